@@ -0,0 +1,167 @@
+// Command greenweb-api runs the carbon-aware HTTP API with the routes of internal/handlers.
+//
+// Configuration is read from the environment (see internal/config), most importantly PORT,
+// ELECTRICITY_MAPS_API_KEY and ADMIN_API_TOKEN. STORAGE_BACKEND selects where impact
+// baselines and reports, optimization rules and webhook registrations are kept (memory,
+// embedded or postgres). CDN_CATALOG_FILES adds private edges to the CDN catalog.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq" // PostgreSQL driver for STORAGE_BACKEND=postgres
+
+	carbonintel "github.com/perschulte/greenweb-api/internal/carbon"
+	"github.com/perschulte/greenweb-api/internal/clientip"
+	"github.com/perschulte/greenweb-api/internal/config"
+	"github.com/perschulte/greenweb-api/internal/edgehealth"
+	"github.com/perschulte/greenweb-api/internal/geolocation"
+	"github.com/perschulte/greenweb-api/internal/handlers"
+	"github.com/perschulte/greenweb-api/internal/impact"
+	"github.com/perschulte/greenweb-api/internal/latency"
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+	"github.com/perschulte/greenweb-api/service"
+)
+
+// version is reported by the health endpoint
+const version = "1.0.0"
+
+func main() {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	if err := run(logger); err != nil {
+		logger.Error("API server stopped", "error", err)
+		os.Exit(1)
+	}
+}
+
+// run starts the API server and returns when it stops. Deferred cleanup such as closing
+// the stores happens on every return path, which os.Exit would skip.
+func run(logger *slog.Logger) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg.IsProduction() {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	stores, err := cfg.Storage.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s storage: %w", cfg.Storage.Backend, err)
+	}
+	defer stores.Close()
+
+	// Catalog files add private PoPs to the built-in providers; the admin API reloads them
+	if len(cfg.CDNCatalog.Files) > 0 {
+		carbon.DefaultCDNCatalog.SetFiles(cfg.CDNCatalog.Files...)
+		if _, err := carbon.DefaultCDNCatalog.Reload(); err != nil {
+			return fmt.Errorf("failed to load CDN catalog: %w", err)
+		}
+	}
+
+	electricityMaps := service.NewElectricityMapsClient(logger)
+	electricityMaps.SetEdgeRankingPolicy(cfg.EdgeHealth.RankingPolicy())
+	// Stored rules adjust every generated profile and are managed through the admin API
+	baseOptimization := service.NewOptimizationService(electricityMaps, logger)
+	baseOptimization.SetRuleStore(stores.Rules)
+	// Analytics adds the application tracking, stats and savings report endpoints
+	optimizationService := service.NewOptimizationAnalyticsService(baseOptimization, nil, logger)
+	clientIPResolver, err := cfg.ClientIP.Resolver()
+	if err != nil {
+		return fmt.Errorf("invalid client IP configuration: %w", err)
+	}
+	clientip.SetDefault(clientIPResolver)
+
+	geoConfig, closeGeo, err := cfg.Geolocation.ServiceConfig(clientIPResolver)
+	if err != nil {
+		return err
+	}
+	defer closeGeo()
+	dualGridService := geolocation.NewDualGridService(geoConfig)
+
+	// Measured RTTs replace the distance model for edge selection where enough samples exist
+	latencyMatrix := latency.NewMatrix(latency.MatrixConfig{
+		WindowSize: cfg.Latency.WindowSize,
+		MaxAge:     cfg.Latency.MaxAge,
+		MinSamples: cfg.Latency.MinSamples,
+	}, nil)
+	if cfg.Latency.MeasurementsFile != "" {
+		samples, err := latency.LoadCSVFile(cfg.Latency.MeasurementsFile)
+		if err == nil {
+			err = latencyMatrix.Add(samples...)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load RTT measurements from %s: %w", cfg.Latency.MeasurementsFile, err)
+		}
+	}
+	electricityMaps.SetRTTModel(latencyMatrix)
+	dualGridService.SetRTTModel(latencyMatrix)
+
+	profileSigner, err := cfg.Security.ProfileSigner()
+	if err != nil {
+		return fmt.Errorf("invalid profile signing key: %w", err)
+	}
+	if cfg.Security.ProfileSigningKey == "" {
+		logger.Warn("PROFILE_SIGNING_KEY is not set, profile tokens use an ephemeral key and stop verifying after a restart")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Edge health probes exclude unreachable edges from recommendations
+	var prober *edgehealth.Prober
+	if len(cfg.EdgeHealth.Probes) > 0 {
+		targets, err := edgehealth.ParseTargets(cfg.EdgeHealth.Probes)
+		if err != nil {
+			return fmt.Errorf("failed to parse edge health probes: %w", err)
+		}
+		prober = edgehealth.NewProber(edgehealth.Config{
+			Interval:         cfg.EdgeHealth.Interval,
+			Timeout:          cfg.EdgeHealth.Timeout,
+			FailureThreshold: cfg.EdgeHealth.FailureThreshold,
+		}, targets, nil, logger)
+		go prober.Run(ctx)
+		electricityMaps.SetEdgeHealthSource(prober)
+	}
+
+	// The image proxy is served when local or remote image sources are configured
+	var imageTranscoder handlers.ImageTranscoder
+	if cfg.Images.Enabled() {
+		imageTranscoder = cfg.Images.Service(logger)
+	}
+
+	// Zone-aware impact results need live and historical grid intensity
+	impactService := impact.NewServiceWithIntensitySource(stores.Impact, carbonintel.NewElectricityMapsAdapter(electricityMaps))
+
+	deps := &handlers.Dependencies{
+		ElectricityMaps:   electricityMaps,
+		Optimization:      optimizationService,
+		ProfileSigner:     profileSigner,
+		ImageTranscoder:   imageTranscoder,
+		CDNCatalog:        carbon.DefaultCDNCatalog,
+		Impact:            impactService,
+		OptimizationRules: baseOptimization,
+		Webhooks:          stores.Webhooks,
+		LatencyMatrix:     latencyMatrix,
+		EdgeProber:        prober,
+		Logger:            logger,
+		Config: &handlers.Config{
+			Version:           version,
+			ServiceName:       "greenweb-api",
+			ElectricityAPIKey: cfg.ElectricityMaps.APIKey,
+			AdminToken:        cfg.Security.AdminToken,
+		},
+	}
+
+	r := gin.New()
+	r.Use(gin.Recovery(), handlers.CORSMiddleware())
+	handlers.RegisterHandlers(r, deps, dualGridService)
+
+	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+	logger.Info("Starting API server", "addr", addr, "storage", cfg.Storage.Backend)
+	return r.Run(addr)
+}
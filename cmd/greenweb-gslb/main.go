@@ -0,0 +1,105 @@
+// Command greenweb-gslb runs the carbon-aware authoritative DNS server that steers each
+// query to the greenest viable edge.
+//
+// Configuration is read from the environment (see internal/config), most importantly
+// GSLB_LISTEN_ADDR and GSLB_CONFIG_FILE. CDN_CATALOG_FILES adds private edges to the catalog,
+// EDGE_HEALTH_PROBES takes edges out of rotation while their health probes fail, and
+// GEOIP_DATABASE_FILES resolves client subnets from local IP databases.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/perschulte/greenweb-api/internal/clientip"
+	"github.com/perschulte/greenweb-api/internal/config"
+	"github.com/perschulte/greenweb-api/internal/edgehealth"
+	"github.com/perschulte/greenweb-api/internal/geolocation"
+	"github.com/perschulte/greenweb-api/internal/gslb"
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+	"github.com/perschulte/greenweb-api/service"
+)
+
+func main() {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Error("Failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+	if cfg.GSLB.ConfigFile == "" {
+		logger.Error("GSLB_CONFIG_FILE must be set")
+		os.Exit(1)
+	}
+
+	if len(cfg.CDNCatalog.Files) > 0 {
+		carbon.DefaultCDNCatalog.SetFiles(cfg.CDNCatalog.Files...)
+		if _, err := carbon.DefaultCDNCatalog.Reload(); err != nil {
+			logger.Error("Failed to load CDN catalog", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	gslbConfig, err := gslb.LoadConfigFile(cfg.GSLB.ConfigFile)
+	if err != nil {
+		logger.Error("Failed to load GSLB configuration", "error", err)
+		os.Exit(1)
+	}
+
+	electricityMaps := service.NewElectricityMapsClient(logger)
+	electricityMaps.SetEdgeRankingPolicy(cfg.EdgeHealth.RankingPolicy())
+	clientIPResolver, err := cfg.ClientIP.Resolver()
+	if err != nil {
+		logger.Error("Invalid client IP configuration", "error", err)
+		os.Exit(1)
+	}
+	clientip.SetDefault(clientIPResolver)
+
+	geoConfig, closeGeo, err := cfg.Geolocation.ServiceConfig(clientIPResolver)
+	if err != nil {
+		logger.Error("Failed to configure geolocation", "error", err)
+		os.Exit(1)
+	}
+	defer closeGeo()
+	geoService := geolocation.NewService(geoConfig)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var health gslb.HealthChecker
+	if len(cfg.EdgeHealth.Probes) > 0 {
+		targets, err := edgehealth.ParseTargets(cfg.EdgeHealth.Probes)
+		if err != nil {
+			logger.Error("Failed to parse edge health probes", "error", err)
+			os.Exit(1)
+		}
+		prober := edgehealth.NewProber(edgehealth.Config{
+			Interval:         cfg.EdgeHealth.Interval,
+			Timeout:          cfg.EdgeHealth.Timeout,
+			FailureThreshold: cfg.EdgeHealth.FailureThreshold,
+		}, targets, nil, logger)
+		go prober.Run(ctx)
+		electricityMaps.SetEdgeHealthSource(prober)
+		health = prober
+	}
+
+	selector := gslb.NewSelector(gslbConfig, electricityMaps, geoService, health, logger)
+	server := gslb.NewServer(gslbConfig, selector, logger)
+
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		cancel()
+		server.Shutdown()
+	}()
+
+	if err := server.ListenAndServe(cfg.GSLB.ListenAddr); err != nil {
+		logger.Error("GSLB DNS server stopped", "error", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,66 @@
+// Command greenweb-proxy runs the carbon-aware HTML rewriting reverse proxy in front of an origin.
+//
+// Configuration is read from the environment (see internal/config), most importantly
+// PROXY_ORIGIN_URL, PROXY_PORT and PROXY_DEFAULT_ZONE. GEOIP_DATABASE_FILES resolves client
+// locations from local IP databases instead of the ipapi.co API.
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/perschulte/greenweb-api/internal/clientip"
+	"github.com/perschulte/greenweb-api/internal/config"
+	"github.com/perschulte/greenweb-api/internal/geolocation"
+	"github.com/perschulte/greenweb-api/internal/proxy"
+	"github.com/perschulte/greenweb-api/service"
+)
+
+func main() {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Error("Failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+	if cfg.Proxy.OriginURL == "" {
+		logger.Error("PROXY_ORIGIN_URL must be set")
+		os.Exit(1)
+	}
+
+	electricityMaps := service.NewElectricityMapsClient(logger)
+	optimizationService := service.NewOptimizationService(electricityMaps, logger)
+	clientIPResolver, err := cfg.ClientIP.Resolver()
+	if err != nil {
+		logger.Error("Invalid client IP configuration", "error", err)
+		os.Exit(1)
+	}
+	clientip.SetDefault(clientIPResolver)
+
+	geoConfig, closeGeo, err := cfg.Geolocation.ServiceConfig(clientIPResolver)
+	if err != nil {
+		logger.Error("Failed to configure geolocation", "error", err)
+		os.Exit(1)
+	}
+	defer closeGeo()
+	geoService := geolocation.NewService(geoConfig)
+
+	handler, err := proxy.New(proxy.Config{
+		OriginURL:   cfg.Proxy.OriginURL,
+		DefaultZone: cfg.Proxy.DefaultZone,
+	}, optimizationService, geoService, logger)
+	if err != nil {
+		logger.Error("Failed to create proxy", "error", err)
+		os.Exit(1)
+	}
+
+	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Proxy.Port)
+	logger.Info("Starting carbon-aware proxy", "addr", addr, "origin", cfg.Proxy.OriginURL)
+	if err := http.ListenAndServe(addr, handler); err != nil {
+		logger.Error("Proxy server stopped", "error", err)
+		os.Exit(1)
+	}
+}
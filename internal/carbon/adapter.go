@@ -17,6 +17,8 @@ type ElectricityMapsAdapter struct {
 	client ElectricityMapsService
 }
 
+var _ carbon.CarbonServiceWithHistory = (*ElectricityMapsAdapter)(nil)
+
 // ElectricityMapsService defines the interface for electricity maps operations
 type ElectricityMapsService interface {
 	GetCarbonIntensity(ctx context.Context, location string) (*service.CarbonIntensity, error)
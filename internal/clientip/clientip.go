@@ -0,0 +1,297 @@
+// Package clientip determines the address of the client behind reverse proxies. Proxy
+// headers are only honored when they arrive from a trusted proxy, so clients cannot
+// spoof their location or rate limit identity.
+package clientip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrInvalidConfig is returned for unparseable proxy addresses or unknown presets
+var ErrInvalidConfig = errors.New("invalid client IP configuration")
+
+// Client IP headers. Forwarded and X-Forwarded-For list every hop and are walked from the
+// nearest proxy; the others carry a single address set by the nearest proxy.
+const (
+	HeaderForwarded      = "Forwarded"
+	HeaderXForwardedFor  = "X-Forwarded-For"
+	HeaderXRealIP        = "X-Real-IP"
+	HeaderCFConnectingIP = "CF-Connecting-IP"
+	HeaderTrueClientIP   = "True-Client-IP"
+	HeaderXClientIP      = "X-Client-IP"
+)
+
+// Proxy presets
+const (
+	PresetPrivate    = "private"    // Proxies on loopback, private and link-local networks
+	PresetCloudflare = "cloudflare" // Cloudflare edge network
+	PresetAWSALB     = "aws-alb"    // AWS Application Load Balancer inside a VPC
+)
+
+// DefaultHeaders are consulted when no headers are configured. Only X-Forwarded-For is
+// appended to by common proxies; a proxy that does not set Forwarded, X-Real-IP or a CDN
+// header passes a client's forged value through, so those must be enabled explicitly or
+// come with the preset of the provider that sets them.
+var DefaultHeaders = []string{HeaderXForwardedFor}
+
+// privateNetworks are loopback, private and link-local ranges
+var privateNetworks = []string{
+	"127.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "169.254.0.0/16",
+	"::1/128", "fc00::/7", "fe80::/10",
+}
+
+// cloudflareNetworks are the published Cloudflare ranges (https://www.cloudflare.com/ips/)
+var cloudflareNetworks = []string{
+	"173.245.48.0/20", "103.21.244.0/22", "103.22.200.0/22", "103.31.4.0/22",
+	"141.101.64.0/18", "108.162.192.0/18", "190.93.240.0/20", "188.114.96.0/20",
+	"197.234.240.0/22", "198.41.128.0/17", "162.158.0.0/15", "104.16.0.0/13",
+	"104.24.0.0/14", "172.64.0.0/13", "131.0.72.0/22",
+	"2400:cb00::/32", "2606:4700::/32", "2803:f800::/32", "2405:b500::/32",
+	"2405:8100::/32", "2a06:98c0::/29", "2c0f:f248::/32",
+}
+
+// preset bundles the proxy networks and headers of a deployment
+type preset struct {
+	networks []string
+	headers  []string
+}
+
+var presets = map[string]preset{
+	PresetPrivate:    {networks: privateNetworks, headers: []string{HeaderXForwardedFor}},
+	PresetCloudflare: {networks: cloudflareNetworks, headers: []string{HeaderCFConnectingIP, HeaderXForwardedFor}},
+	PresetAWSALB:     {networks: privateNetworks, headers: []string{HeaderXForwardedFor}},
+}
+
+// Config describes which proxies are trusted to report the client address
+type Config struct {
+	TrustedProxies []string // Addresses or CIDRs of trusted proxies
+	TrustedHops    int      // Number of proxies in front of the service, trusted whatever their address
+	Presets        []string // Named proxy networks: private, cloudflare, aws-alb
+	Headers        []string // Headers to consult in order; defaults to the presets' headers or DefaultHeaders
+}
+
+// Resolver extracts client addresses from requests according to a Config
+type Resolver struct {
+	networks []netip.Prefix
+	hops     int
+	headers  []string
+}
+
+// New creates a resolver. An empty Config trusts proxies on private networks.
+func New(config Config) (*Resolver, error) {
+	if config.TrustedHops < 0 {
+		return nil, fmt.Errorf("%w: trusted hops cannot be negative", ErrInvalidConfig)
+	}
+	if len(config.TrustedProxies) == 0 && len(config.Presets) == 0 && config.TrustedHops == 0 {
+		config.Presets = []string{PresetPrivate}
+	}
+
+	resolver := &Resolver{hops: config.TrustedHops}
+	networks := config.TrustedProxies
+	var presetHeaders []string
+	for _, name := range config.Presets {
+		p, exists := presets[strings.ToLower(strings.TrimSpace(name))]
+		if !exists {
+			return nil, fmt.Errorf("%w: unknown preset %q", ErrInvalidConfig, name)
+		}
+		networks = append(networks[:len(networks):len(networks)], p.networks...)
+		presetHeaders = append(presetHeaders, p.headers...)
+	}
+
+	for _, network := range networks {
+		network = strings.TrimSpace(network)
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(network)
+			if addrErr != nil {
+				return nil, fmt.Errorf("%w: invalid proxy address %q", ErrInvalidConfig, network)
+			}
+			addr = addr.Unmap()
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		resolver.networks = append(resolver.networks, prefix.Masked())
+	}
+
+	headers := config.Headers
+	if len(headers) == 0 {
+		headers = presetHeaders
+	}
+	if len(headers) == 0 {
+		headers = DefaultHeaders
+	}
+	seen := make(map[string]bool)
+	for _, header := range headers {
+		header = http.CanonicalHeaderKey(strings.TrimSpace(header))
+		if header != "" && !seen[header] {
+			seen[header] = true
+			resolver.headers = append(resolver.headers, header)
+		}
+	}
+
+	return resolver, nil
+}
+
+// trusted reports whether an address hops away from the service is a trusted proxy;
+// the direct peer is hop 0
+func (r *Resolver) trusted(addr netip.Addr, hop int) bool {
+	return hop < r.hops || r.isProxy(addr)
+}
+
+// isProxy reports whether an address is in a trusted proxy network
+func (r *Resolver) isProxy(addr netip.Addr) bool {
+	for _, network := range r.networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the client address of a request. Headers are only honored when the
+// direct peer is a trusted proxy; multi-hop headers are walked from the nearest proxy to
+// the first untrusted address. Without a usable header the peer address is returned.
+func (r *Resolver) ClientIP(req *http.Request) string {
+	peer, remote, ok := peerAddr(req)
+	if !ok {
+		return remote
+	}
+	if !r.trusted(peer, 0) {
+		return peer.String()
+	}
+
+	for _, header := range r.headers {
+		values := req.Header.Values(header)
+		if len(values) == 0 {
+			continue
+		}
+
+		switch header {
+		case HeaderForwarded:
+			if client, found := r.walk(peer, parseForwarded(values)); found {
+				return client
+			}
+		case HeaderXForwardedFor:
+			if client, found := r.walk(peer, splitList(values)); found {
+				return client
+			}
+		default:
+			if addr, ok := parseAddr(values[0]); ok && !r.isProxy(addr) {
+				return addr.String()
+			}
+		}
+	}
+
+	return peer.String()
+}
+
+// TrustedPeer reports whether a request arrived from a trusted proxy, and so whether
+// headers added by that proxy, such as CDN geo headers, can be believed
+func (r *Resolver) TrustedPeer(req *http.Request) bool {
+	peer, _, ok := peerAddr(req)
+	return ok && r.trusted(peer, 0)
+}
+
+// peerAddr parses the address of the direct peer, also returning the raw host
+func peerAddr(req *http.Request) (netip.Addr, string, bool) {
+	remote := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	peer, ok := parseAddr(remote)
+	return peer, remote, ok
+}
+
+// walk follows a hop list from the trusted peer towards the client and returns the first
+// untrusted address. When a hop is not an address (e.g. "unknown"), the proxy that
+// reported it is returned.
+func (r *Resolver) walk(peer netip.Addr, hops []string) (string, bool) {
+	nearest := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseAddr(hops[i])
+		if !ok {
+			return nearest.String(), true
+		}
+		if !r.trusted(addr, len(hops)-i) {
+			return addr.String(), true
+		}
+		nearest = addr
+	}
+	return "", false
+}
+
+// splitList splits comma-separated header values
+func splitList(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// parseForwarded returns the for= nodes of RFC 7239 Forwarded headers, one per hop
+func parseForwarded(values []string) []string {
+	var nodes []string
+	for _, element := range splitList(values) {
+		node := ""
+		for _, pair := range strings.Split(element, ";") {
+			key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+			if found && strings.EqualFold(strings.TrimSpace(key), "for") {
+				node = strings.Trim(strings.TrimSpace(value), `"`)
+			}
+		}
+		nodes = append(nodes, node) // Hops without for= count as unknown
+	}
+	return nodes
+}
+
+// parseAddr parses an address with an optional port, brackets or IPv4-mapped form
+func parseAddr(value string) (netip.Addr, bool) {
+	value = strings.TrimSpace(value)
+	if addr, err := netip.ParseAddr(value); err == nil {
+		return addr.Unmap(), true
+	}
+	if addrPort, err := netip.ParseAddrPort(value); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		if addr, err := netip.ParseAddr(value[1 : len(value)-1]); err == nil {
+			return addr.Unmap(), true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// defaultResolver trusts proxies on private networks until SetDefault is called
+var defaultResolver atomic.Pointer[Resolver]
+
+func init() {
+	resolver, _ := New(Config{})
+	defaultResolver.Store(resolver)
+}
+
+// Default returns the process-wide resolver shared by geolocation and middleware
+func Default() *Resolver {
+	return defaultResolver.Load()
+}
+
+// SetDefault replaces the process-wide resolver; call it during startup
+func SetDefault(resolver *Resolver) {
+	if resolver != nil {
+		defaultResolver.Store(resolver)
+	}
+}
+
+// FromRequest returns the client address of a request using the default resolver
+func FromRequest(req *http.Request) string {
+	return Default().ClientIP(req)
+}
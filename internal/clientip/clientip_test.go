@@ -0,0 +1,190 @@
+package clientip
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolverClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     Config
+		remoteAddr string
+		headers    map[string][]string
+		expected   string
+	}{
+		{
+			name:       "direct client ignores spoofed headers",
+			remoteAddr: "203.0.113.5:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"8.8.8.8"}, "X-Real-IP": {"8.8.4.4"}},
+			expected:   "203.0.113.5",
+		},
+		{
+			name:       "private proxy forwards client",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"8.8.8.8"}},
+			expected:   "8.8.8.8",
+		},
+		{
+			name:       "spoofed entries left of the real client are ignored",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.1.1.1, 203.0.113.9", "10.0.0.3"}},
+			expected:   "203.0.113.9",
+		},
+		{
+			name:       "all hops trusted falls back to peer",
+			remoteAddr: "127.0.0.1:8080",
+			headers:    map[string][]string{"X-Forwarded-For": {"192.168.1.1"}},
+			expected:   "127.0.0.1",
+		},
+		{
+			name:       "client-injected headers are ignored behind a private proxy",
+			remoteAddr: "10.0.0.2:1234",
+			headers: map[string][]string{
+				"Forwarded":        {"for=1.1.1.1"},
+				"X-Real-IP":        {"1.1.1.1"},
+				"CF-Connecting-IP": {"1.1.1.1"},
+				"X-Forwarded-For":  {"1.1.1.1, 203.0.113.9"},
+			},
+			expected: "203.0.113.9",
+		},
+		{
+			name:       "forwarded header takes precedence when configured",
+			config:     Config{Headers: []string{"Forwarded", "X-Forwarded-For"}},
+			remoteAddr: "10.0.0.2:1234",
+			headers: map[string][]string{
+				"Forwarded":       {`for=198.51.100.17;proto=https, for="[2001:db8:cafe::17]:4711"`},
+				"X-Forwarded-For": {"8.8.8.8"},
+			},
+			expected: "2001:db8:cafe::17",
+		},
+		{
+			name:       "forwarded obfuscated node returns the reporting proxy",
+			config:     Config{Headers: []string{"Forwarded"}},
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string][]string{"Forwarded": {"for=_hidden, for=10.0.0.9"}},
+			expected:   "10.0.0.9",
+		},
+		{
+			name:       "trusted CIDR",
+			config:     Config{TrustedProxies: []string{"203.0.113.0/24", "198.51.100.7"}},
+			remoteAddr: "203.0.113.5:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"8.8.8.8, 198.51.100.7"}},
+			expected:   "8.8.8.8",
+		},
+		{
+			name:       "trusted CIDRs replace the private default",
+			config:     Config{TrustedProxies: []string{"203.0.113.0/24"}},
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"8.8.8.8"}},
+			expected:   "10.0.0.2",
+		},
+		{
+			name:       "hop counting",
+			config:     Config{TrustedHops: 2},
+			remoteAddr: "203.0.113.5:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.1.1.1, 8.8.8.8, 198.51.100.20"}},
+			expected:   "8.8.8.8",
+		},
+		{
+			name:       "cloudflare preset uses CF-Connecting-IP",
+			config:     Config{Presets: []string{PresetCloudflare}},
+			remoteAddr: "172.64.1.1:443",
+			headers:    map[string][]string{"CF-Connecting-IP": {"2001:db8::1"}, "X-Forwarded-For": {"8.8.8.8"}},
+			expected:   "2001:db8::1",
+		},
+		{
+			name:       "cloudflare preset rejects other peers",
+			config:     Config{Presets: []string{PresetCloudflare}},
+			remoteAddr: "203.0.113.5:1234",
+			headers:    map[string][]string{"CF-Connecting-IP": {"8.8.8.8"}},
+			expected:   "203.0.113.5",
+		},
+		{
+			name:       "aws alb preset ignores X-Real-IP",
+			config:     Config{Presets: []string{PresetAWSALB}},
+			remoteAddr: "172.31.5.6:1234",
+			headers:    map[string][]string{"X-Real-IP": {"1.1.1.1"}, "X-Forwarded-For": {"8.8.8.8:5123"}},
+			expected:   "8.8.8.8",
+		},
+		{
+			name:       "IPv4-mapped peer",
+			remoteAddr: "[::ffff:10.0.0.2]:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"::ffff:8.8.8.8"}},
+			expected:   "8.8.8.8",
+		},
+		{
+			name:       "unparseable remote address is returned as is",
+			remoteAddr: "pipe",
+			headers:    map[string][]string{"X-Forwarded-For": {"8.8.8.8"}},
+			expected:   "pipe",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver, err := New(tt.config)
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, values := range tt.headers {
+				for _, value := range values {
+					req.Header.Add(key, value)
+				}
+			}
+
+			if got := resolver.ClientIP(req); got != tt.expected {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResolverTrustedPeer(t *testing.T) {
+	resolver, err := New(Config{Presets: []string{PresetCloudflare}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		"172.64.1.1:443":        true,
+		"[2606:4700::1]:443":    true,
+		"203.0.113.5:1234":      false,
+		"10.0.0.2:1234":         false,
+		"not an address at all": false,
+	}
+	for remoteAddr, expected := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+		if got := resolver.TrustedPeer(req); got != expected {
+			t.Errorf("TrustedPeer(%s) = %v, want %v", remoteAddr, got, expected)
+		}
+	}
+}
+
+func TestZeroResolverTrustsNoProxies(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "127.0.0.1:8080"
+	req.Header.Set("X-Forwarded-For", "8.8.8.8")
+
+	if got := (&Resolver{}).ClientIP(req); got != "127.0.0.1" {
+		t.Errorf("ClientIP() = %q, want peer address", got)
+	}
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	configs := []Config{
+		{TrustedProxies: []string{"not-a-network"}},
+		{Presets: []string{"akamai"}},
+		{TrustedHops: -1},
+	}
+	for _, config := range configs {
+		if _, err := New(config); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("New(%+v): expected ErrInvalidConfig, got %v", config, err)
+		}
+	}
+}
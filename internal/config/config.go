@@ -5,6 +5,8 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -12,6 +14,13 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/perschulte/greenweb-api/internal/cache"
+	"github.com/perschulte/greenweb-api/internal/clientip"
+	"github.com/perschulte/greenweb-api/internal/geolocation"
+	"github.com/perschulte/greenweb-api/internal/imaging"
+	"github.com/perschulte/greenweb-api/internal/store"
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+	"github.com/perschulte/greenweb-api/pkg/optimization"
 )
 
 // Config holds all configuration values for the GreenWeb API.
@@ -35,6 +44,33 @@ type Config struct {
 	// Feature flags
 	Features FeatureConfig
 
+	// Reverse proxy settings
+	Proxy ProxyConfig
+
+	// Image proxy settings
+	Images ImagesConfig
+
+	// CDN edge catalog settings
+	CDNCatalog CDNCatalogConfig
+
+	// Carbon-aware DNS load balancer settings
+	GSLB GSLBConfig
+
+	// Measured RTT matrix settings
+	Latency LatencyConfig
+
+	// Edge health probe and carbon data freshness settings
+	EdgeHealth EdgeHealthConfig
+
+	// IP geolocation settings
+	Geolocation GeolocationConfig
+
+	// Client IP extraction behind reverse proxies
+	ClientIP ClientIPConfig
+
+	// Persistence backend for impact data, optimization rules and webhooks
+	Storage StorageConfig
+
 	// Internal state
 	mu sync.RWMutex
 }
@@ -85,7 +121,19 @@ type RateLimitConfig struct {
 
 // SecurityConfig contains security-related configuration.
 type SecurityConfig struct {
-	AllowedOrigins []string // CORS allowed origins
+	AllowedOrigins      []string // CORS allowed origins
+	ProfileSigningKey   string   // Base64 Ed25519 seed for signing profile tokens (ephemeral key if empty)
+	ProfileSigningKeyID string   // Key ID published in the JWKS for profile tokens
+	AdminToken          string   // Bearer token for admin endpoints (admin endpoints disabled if empty)
+}
+
+// ProfileSigner builds the profile token signer from PROFILE_SIGNING_KEY, or with an
+// ephemeral key when none is set; tokens from an ephemeral key do not survive a restart.
+func (c SecurityConfig) ProfileSigner() (*optimization.ProfileSigner, error) {
+	if c.ProfileSigningKey == "" {
+		return optimization.GenerateProfileSigner(c.ProfileSigningKeyID)
+	}
+	return optimization.NewProfileSignerFromSeed(c.ProfileSigningKey, c.ProfileSigningKeyID)
 }
 
 // FeatureConfig contains feature flags.
@@ -93,6 +141,164 @@ type FeatureConfig struct {
 	EnableDemoMode bool // Enable demo mode with mock data
 }
 
+// ProxyConfig contains carbon-aware reverse proxy configuration.
+type ProxyConfig struct {
+	OriginURL   string // Origin server the proxy sits in front of
+	Port        int    // Port the proxy listens on
+	DefaultZone string // Grid zone used when the visitor's zone cannot be resolved
+}
+
+// ImagesConfig contains image transcoding configuration.
+type ImagesConfig struct {
+	LocalRoot          string   // Directory local image paths are resolved against (local sources disabled if empty)
+	AllowedHosts       []string // Hosts remote images may be fetched from
+	AllowAnyPublicHost bool     // Fetch from any public host when AllowedHosts is empty (not allowed in production)
+	MaxSourceBytes     int64    // Maximum size of a source image in bytes
+	MaxSourcePixels    int64    // Maximum width × height of a source image
+}
+
+// Enabled reports whether any image source is configured.
+func (c ImagesConfig) Enabled() bool {
+	return c.LocalRoot != "" || len(c.AllowedHosts) > 0 || c.AllowAnyPublicHost
+}
+
+// Service builds the image transcoding service for this configuration.
+func (c ImagesConfig) Service(logger *slog.Logger) *imaging.Service {
+	return imaging.NewService(imaging.ServiceConfig{
+		LocalRoot:          c.LocalRoot,
+		AllowedHosts:       c.AllowedHosts,
+		AllowAnyPublicHost: c.AllowAnyPublicHost,
+		MaxSourceBytes:     c.MaxSourceBytes,
+		MaxSourcePixels:    c.MaxSourcePixels,
+	}, logger)
+}
+
+// CDNCatalogConfig contains CDN edge catalog configuration.
+type CDNCatalogConfig struct {
+	Files []string // YAML/JSON catalog files merged on top of the built-in providers, in order
+}
+
+// GSLBConfig contains carbon-aware DNS load balancer configuration.
+type GSLBConfig struct {
+	ListenAddr string // UDP/TCP address the authoritative DNS server listens on
+	ConfigFile string // YAML/JSON file with the zone and steered records
+}
+
+// LatencyConfig contains measured RTT matrix configuration.
+type LatencyConfig struct {
+	MeasurementsFile string        // CSV file with RTT samples loaded at startup
+	WindowSize       int           // Samples kept per user/edge pair
+	MaxAge           time.Duration // Samples older than this are ignored
+	MinSamples       int           // Pairs with fewer samples fall back to the distance model
+}
+
+// EdgeHealthConfig contains edge health probe and carbon data freshness configuration.
+type EdgeHealthConfig struct {
+	Probes              []string      // Probe targets as "provider/edge=url"
+	Interval            time.Duration // Time between probe rounds
+	Timeout             time.Duration // Timeout for a single probe
+	FailureThreshold    int           // Consecutive failures before an edge is unhealthy
+	MaxDataAge          time.Duration // Carbon data older than this is stale
+	StaleDataAction     string        // "downrank" or "exclude" edges with stale data
+	SyntheticDataAction string        // "downrank" or "exclude" edges with mock or default data
+}
+
+// RankingPolicy builds the edge ranking policy for stale and synthetic carbon data.
+func (c EdgeHealthConfig) RankingPolicy() carbon.EdgeRankingPolicy {
+	return carbon.EdgeRankingPolicy{
+		MaxDataAge:    c.MaxDataAge,
+		StaleData:     c.StaleDataAction,
+		SyntheticData: c.SyntheticDataAction,
+	}
+}
+
+// GeolocationConfig contains IP geolocation configuration.
+type GeolocationConfig struct {
+	IPDatabaseFiles   []string      // Local IP databases (.mmdb or CSV), consulted before the HTTP API
+	APIFallback       bool          // Query the HTTP API for addresses not in the local databases
+	CacheSize         int           // Maximum cached lookups; negative disables the cache
+	CacheTTL          time.Duration // Lifetime of cached lookups
+	CacheIPv4Prefix   int           // IPv4 addresses share cache entries per prefix length
+	CacheIPv6Prefix   int           // IPv6 addresses share cache entries per prefix length
+	SharedCache       bool          // Share lookups between replicas through Redis
+	ZoneBoundaryFiles []string      // GeoJSON zone boundaries resolving coordinates to sub-national grid zones
+	OverrideName      string        // Query parameter and cookie overriding the detected location
+	AllowOverride     bool          // Honor location overrides from clients
+	TrustCDNHeaders   bool          // Honor CDN geo headers (CF-IPCountry, CloudFront-Viewer-*) from trusted proxies; off by default
+}
+
+// ServiceConfig builds the geolocation service configuration, opening the local IP databases
+// and zone boundaries. The returned close function releases the shared cache connection.
+func (c GeolocationConfig) ServiceConfig(clientIP *clientip.Resolver) (geolocation.ServiceConfig, func() error, error) {
+	config := geolocation.ServiceConfig{
+		APIFallback:       c.APIFallback,
+		ClientIP:          clientIP,
+		OverrideName:      c.OverrideName,
+		DisableOverride:   !c.AllowOverride,
+		DisableCDNHeaders: !c.TrustCDNHeaders,
+		Cache: geolocation.CacheConfig{
+			Size:           c.CacheSize,
+			TTL:            c.CacheTTL,
+			IPv4PrefixBits: c.CacheIPv4Prefix,
+			IPv6PrefixBits: c.CacheIPv6Prefix,
+		},
+	}
+
+	var err error
+	if len(c.IPDatabaseFiles) > 0 {
+		if config.IPDatabase, err = geolocation.OpenIPDatabases(c.IPDatabaseFiles...); err != nil {
+			return geolocation.ServiceConfig{}, nil, fmt.Errorf("failed to load IP database: %w", err)
+		}
+	}
+	if len(c.ZoneBoundaryFiles) > 0 {
+		if config.ZoneBoundaries, err = geolocation.OpenZoneBoundaries(c.ZoneBoundaryFiles...); err != nil {
+			return geolocation.ServiceConfig{}, nil, fmt.Errorf("failed to load grid zone boundaries: %w", err)
+		}
+	}
+
+	closeShared := func() error { return nil }
+	if c.SharedCache {
+		sharedCache := cache.NewFromEnv()
+		config.Cache.Shared = sharedCache
+		closeShared = sharedCache.Close
+	}
+	return config, closeShared, nil
+}
+
+// ClientIPConfig contains the reverse proxy trust model used for geolocation and rate limiting.
+type ClientIPConfig struct {
+	TrustedProxies []string // Proxy addresses or CIDRs whose forwarding headers are honored
+	TrustedHops    int      // Number of proxies in front of the service, trusted whatever their address
+	Presets        []string // Named proxy networks: private, cloudflare, aws-alb
+	Headers        []string // Client IP headers in order of preference
+}
+
+// Resolver builds the client IP resolver for this configuration.
+func (c ClientIPConfig) Resolver() (*clientip.Resolver, error) {
+	return clientip.New(clientip.Config{
+		TrustedProxies: c.TrustedProxies,
+		TrustedHops:    c.TrustedHops,
+		Presets:        c.Presets,
+		Headers:        c.Headers,
+	})
+}
+
+// StorageConfig selects where impact data, optimization rules and webhooks are persisted.
+type StorageConfig struct {
+	Backend     string // memory, embedded or postgres
+	Path        string // Embedded store file; also holds rules and webhooks with postgres
+	PostgresDSN string // PostgreSQL connection string for the postgres backend
+}
+
+// Open opens the configured storage backends.
+func (c StorageConfig) Open() (*store.Stores, error) {
+	return store.Open(store.Config{
+		Backend:     c.Backend,
+		Path:        c.Path,
+		PostgresDSN: c.PostgresDSN,
+	})
+}
+
 // Load creates a new Config instance by loading values from environment variables.
 // It automatically loads .env files if they exist and validates all required fields.
 func Load() (*Config, error) {
@@ -134,11 +340,72 @@ func Load() (*Config, error) {
 			},
 		},
 		Security: SecurityConfig{
-			AllowedOrigins: parseStringSlice(getEnvString("ALLOWED_ORIGINS", "http://localhost:3000,http://localhost:8090")),
+			AllowedOrigins:      parseStringSlice(getEnvString("ALLOWED_ORIGINS", "http://localhost:3000,http://localhost:8090")),
+			ProfileSigningKey:   getEnvString("PROFILE_SIGNING_KEY", ""),
+			ProfileSigningKeyID: getEnvString("PROFILE_SIGNING_KEY_ID", "greenweb-profile-1"),
+			AdminToken:          getEnvString("ADMIN_API_TOKEN", ""),
 		},
 		Features: FeatureConfig{
 			EnableDemoMode: getEnvBool("ENABLE_DEMO_MODE", true),
 		},
+		Proxy: ProxyConfig{
+			OriginURL:   getEnvString("PROXY_ORIGIN_URL", ""),
+			Port:        getEnvInt("PROXY_PORT", 8091),
+			DefaultZone: getEnvString("PROXY_DEFAULT_ZONE", "DE"),
+		},
+		Images: ImagesConfig{
+			LocalRoot:          getEnvString("IMAGE_LOCAL_ROOT", ""),
+			AllowedHosts:       parseStringSlice(getEnvString("IMAGE_ALLOWED_HOSTS", "")),
+			AllowAnyPublicHost: getEnvBool("IMAGE_ALLOW_ANY_PUBLIC_HOST", false),
+			MaxSourceBytes:     int64(getEnvInt("IMAGE_MAX_SOURCE_MB", 20)) * 1024 * 1024,
+			MaxSourcePixels:    int64(getEnvInt("IMAGE_MAX_SOURCE_MEGAPIXELS", 40)) * 1_000_000,
+		},
+		CDNCatalog: CDNCatalogConfig{
+			Files: parseStringSlice(getEnvString("CDN_CATALOG_FILES", "")),
+		},
+		GSLB: GSLBConfig{
+			ListenAddr: getEnvString("GSLB_LISTEN_ADDR", ":8053"),
+			ConfigFile: getEnvString("GSLB_CONFIG_FILE", ""),
+		},
+		Latency: LatencyConfig{
+			MeasurementsFile: getEnvString("LATENCY_MEASUREMENTS_FILE", ""),
+			WindowSize:       getEnvInt("LATENCY_WINDOW_SIZE", 500),
+			MaxAge:           time.Duration(getEnvInt("LATENCY_MAX_AGE_HOURS", 168)) * time.Hour,
+			MinSamples:       getEnvInt("LATENCY_MIN_SAMPLES", 5),
+		},
+		EdgeHealth: EdgeHealthConfig{
+			Probes:              parseStringSlice(getEnvString("EDGE_HEALTH_PROBES", "")),
+			Interval:            time.Duration(getEnvInt("EDGE_HEALTH_INTERVAL_SECONDS", 30)) * time.Second,
+			Timeout:             time.Duration(getEnvInt("EDGE_HEALTH_TIMEOUT_SECONDS", 5)) * time.Second,
+			FailureThreshold:    getEnvInt("EDGE_HEALTH_FAILURE_THRESHOLD", 3),
+			MaxDataAge:          time.Duration(getEnvInt("CARBON_DATA_MAX_AGE_MINUTES", 120)) * time.Minute,
+			StaleDataAction:     getEnvString("CARBON_STALE_DATA_ACTION", "downrank"),
+			SyntheticDataAction: getEnvString("CARBON_SYNTHETIC_DATA_ACTION", "downrank"),
+		},
+		Geolocation: GeolocationConfig{
+			IPDatabaseFiles:   parseStringSlice(getEnvString("GEOIP_DATABASE_FILES", "")),
+			APIFallback:       getEnvBool("GEOIP_API_FALLBACK", false),
+			CacheSize:         getEnvInt("GEOIP_CACHE_SIZE", 10000),
+			CacheTTL:          time.Duration(getEnvInt("GEOIP_CACHE_TTL_MINUTES", 60)) * time.Minute,
+			CacheIPv4Prefix:   getEnvInt("GEOIP_CACHE_IPV4_PREFIX", 24),
+			CacheIPv6Prefix:   getEnvInt("GEOIP_CACHE_IPV6_PREFIX", 48),
+			SharedCache:       getEnvBool("GEOIP_SHARED_CACHE", false),
+			ZoneBoundaryFiles: parseStringSlice(getEnvString("GRID_ZONE_BOUNDARY_FILES", "")),
+			OverrideName:      getEnvString("GEOIP_OVERRIDE_NAME", "greenweb_location"),
+			AllowOverride:     getEnvBool("GEOIP_ALLOW_OVERRIDE", true),
+			TrustCDNHeaders:   getEnvBool("GEOIP_TRUST_CDN_HEADERS", false),
+		},
+		ClientIP: ClientIPConfig{
+			TrustedProxies: parseStringSlice(getEnvString("TRUSTED_PROXIES", "")),
+			TrustedHops:    getEnvInt("TRUSTED_PROXY_HOPS", 0),
+			Presets:        parseStringSlice(getEnvString("TRUSTED_PROXY_PRESETS", "")),
+			Headers:        parseStringSlice(getEnvString("CLIENT_IP_HEADERS", "")),
+		},
+		Storage: StorageConfig{
+			Backend:     getEnvString("STORAGE_BACKEND", store.BackendMemory),
+			Path:        getEnvString("STORAGE_PATH", store.DefaultPath),
+			PostgresDSN: getEnvString("STORAGE_POSTGRES_DSN", ""),
+		},
 	}
 
 	if err := config.Validate(); err != nil {
@@ -172,6 +439,9 @@ func (c *Config) Validate() error {
 		if c.Features.EnableDemoMode {
 			errors = append(errors, "demo mode should not be enabled in production")
 		}
+		if c.Images.AllowAnyPublicHost {
+			errors = append(errors, "IMAGE_ALLOW_ANY_PUBLIC_HOST is not allowed in production; list hosts in IMAGE_ALLOWED_HOSTS")
+		}
 	}
 
 	// Validate Redis configuration
@@ -225,11 +495,68 @@ func (c *Config) Validate() error {
 		errors = append(errors, "rate limit burst size must be positive")
 	}
 
+	// Validate proxy configuration
+	if c.Proxy.OriginURL != "" {
+		if u, err := url.Parse(c.Proxy.OriginURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errors = append(errors, "PROXY_ORIGIN_URL must be an absolute URL")
+		}
+		if c.Proxy.Port <= 0 || c.Proxy.Port > 65535 {
+			errors = append(errors, "proxy port must be between 1 and 65535")
+		}
+	}
+
+	// Validate latency matrix configuration
+	if c.Latency.WindowSize < 0 || c.Latency.MinSamples < 0 || c.Latency.MaxAge < 0 {
+		errors = append(errors, "latency window size, max age and minimum samples cannot be negative")
+	}
+
+	// Validate edge health configuration
+	if len(c.EdgeHealth.Probes) > 0 && (c.EdgeHealth.Interval <= 0 || c.EdgeHealth.Timeout <= 0 || c.EdgeHealth.FailureThreshold <= 0) {
+		errors = append(errors, "edge health interval, timeout and failure threshold must be positive")
+	}
+	for _, action := range []string{c.EdgeHealth.StaleDataAction, c.EdgeHealth.SyntheticDataAction} {
+		if action != "" && action != "downrank" && action != "exclude" {
+			errors = append(errors, "CARBON_STALE_DATA_ACTION and CARBON_SYNTHETIC_DATA_ACTION must be downrank or exclude")
+			break
+		}
+	}
+
+	// Validate geolocation cache configuration
+	if c.Geolocation.CacheTTL < 0 {
+		errors = append(errors, "geolocation cache TTL cannot be negative")
+	}
+	if c.Geolocation.CacheIPv4Prefix < 0 || c.Geolocation.CacheIPv4Prefix > 32 || c.Geolocation.CacheIPv6Prefix < 0 || c.Geolocation.CacheIPv6Prefix > 128 {
+		errors = append(errors, "geolocation cache prefixes must be 0-32 for IPv4 and 0-128 for IPv6")
+	}
+
+	// Validate client IP configuration
+	if _, err := c.ClientIP.Resolver(); err != nil {
+		errors = append(errors, err.Error())
+	}
+
+	// Validate storage configuration
+	switch c.Storage.Backend {
+	case "", store.BackendMemory, store.BackendEmbedded:
+	case store.BackendPostgres:
+		if c.Storage.PostgresDSN == "" {
+			errors = append(errors, "STORAGE_POSTGRES_DSN is required for the postgres storage backend")
+		}
+	default:
+		errors = append(errors, "STORAGE_BACKEND must be memory, embedded or postgres")
+	}
+
 	// Validate CORS origins
 	if len(c.Security.AllowedOrigins) == 0 {
 		errors = append(errors, "at least one allowed origin must be specified")
 	}
 
+	// Validate profile token signing
+	if c.Security.ProfileSigningKey != "" {
+		if _, err := optimization.NewProfileSignerFromSeed(c.Security.ProfileSigningKey, c.Security.ProfileSigningKeyID); err != nil {
+			errors = append(errors, fmt.Sprintf("PROFILE_SIGNING_KEY is invalid: %v", err))
+		}
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("validation errors: %s", strings.Join(errors, "; "))
 	}
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -217,6 +220,36 @@ func TestValidate(t *testing.T) {
 		}
 	})
 
+	t.Run("production allowing any image host", func(t *testing.T) {
+		config := &Config{
+			Server:          ServerConfig{Port: 8080, Env: "production"},
+			ElectricityMaps: ElectricityMapsConfig{APIKey: "key"},
+			Redis:           RedisConfig{URL: "redis://localhost:6379", PoolSize: 10, DialTimeout: time.Second, ReadTimeout: time.Second, WriteTimeout: time.Second},
+			App:             AppConfig{LogLevel: "info", CacheTTL: time.Second, RateLimit: RateLimitConfig{RequestsPerMinute: 100, BurstSize: 10}},
+			Security:        SecurityConfig{AllowedOrigins: []string{"http://localhost:3000"}},
+			Images:          ImagesConfig{AllowAnyPublicHost: true},
+		}
+
+		err := config.Validate()
+		if err == nil || !strings.Contains(err.Error(), "IMAGE_ALLOW_ANY_PUBLIC_HOST is not allowed in production") {
+			t.Errorf("Expected image host validation error, got %v", err)
+		}
+	})
+
+	t.Run("invalid profile signing key", func(t *testing.T) {
+		config := &Config{
+			Server:   ServerConfig{Port: 8080, Env: "development"},
+			Redis:    RedisConfig{URL: "redis://localhost:6379", PoolSize: 10, DialTimeout: time.Second, ReadTimeout: time.Second, WriteTimeout: time.Second},
+			App:      AppConfig{LogLevel: "info", CacheTTL: time.Second, RateLimit: RateLimitConfig{RequestsPerMinute: 100, BurstSize: 10}},
+			Security: SecurityConfig{AllowedOrigins: []string{"http://localhost:3000"}, ProfileSigningKey: "c2hvcnQ=", ProfileSigningKeyID: "key-1"},
+		}
+
+		err := config.Validate()
+		if err == nil || !strings.Contains(err.Error(), "PROFILE_SIGNING_KEY is invalid") {
+			t.Errorf("Expected signing key validation error, got %v", err)
+		}
+	})
+
 	t.Run("invalid log level", func(t *testing.T) {
 		config := &Config{
 			Server: ServerConfig{Port: 8080, Env: "development"},
@@ -233,6 +266,21 @@ func TestValidate(t *testing.T) {
 			t.Errorf("Expected log level validation error, got %v", err)
 		}
 	})
+
+	t.Run("postgres storage without DSN", func(t *testing.T) {
+		config := &Config{
+			Server:   ServerConfig{Port: 8080, Env: "development"},
+			Redis:    RedisConfig{URL: "redis://localhost:6379", PoolSize: 10, DialTimeout: time.Second, ReadTimeout: time.Second, WriteTimeout: time.Second},
+			App:      AppConfig{LogLevel: "info", CacheTTL: time.Second, RateLimit: RateLimitConfig{RequestsPerMinute: 100, BurstSize: 10}},
+			Security: SecurityConfig{AllowedOrigins: []string{"http://localhost:3000"}},
+			Storage:  StorageConfig{Backend: "postgres"},
+		}
+
+		err := config.Validate()
+		if err == nil || !strings.Contains(err.Error(), "STORAGE_POSTGRES_DSN is required") {
+			t.Errorf("Expected storage validation error, got %v", err)
+		}
+	})
 }
 
 func TestString(t *testing.T) {
@@ -314,6 +362,47 @@ func TestHelperMethods(t *testing.T) {
 			t.Errorf("Expected server address %s, got %s", expected, config.GetServerAddress())
 		}
 	})
+
+	t.Run("RankingPolicy", func(t *testing.T) {
+		edgeHealth := EdgeHealthConfig{MaxDataAge: 30 * time.Minute, StaleDataAction: "exclude", SyntheticDataAction: "downrank"}
+		policy := edgeHealth.RankingPolicy()
+		if policy.MaxDataAge != 30*time.Minute || policy.StaleData != "exclude" || policy.SyntheticData != "downrank" {
+			t.Errorf("Expected the edge health settings in the ranking policy, got %+v", policy)
+		}
+	})
+
+	t.Run("Images", func(t *testing.T) {
+		if (ImagesConfig{MaxSourceBytes: 1024}).Enabled() {
+			t.Error("Expected images to be disabled without a source")
+		}
+		images := ImagesConfig{AllowedHosts: []string{"cdn.example.com"}}
+		if !images.Enabled() {
+			t.Error("Expected images to be enabled with an allowed host")
+		}
+		if images.Service(slog.New(slog.NewTextHandler(io.Discard, nil))) == nil {
+			t.Error("Expected an image service")
+		}
+	})
+
+	t.Run("GeolocationServiceConfig", func(t *testing.T) {
+		geolocation := GeolocationConfig{CacheSize: 500, CacheTTL: time.Minute, CacheIPv4Prefix: 24, OverrideName: "location", TrustCDNHeaders: true}
+		serviceConfig, closeGeo, err := geolocation.ServiceConfig(nil)
+		if err != nil {
+			t.Fatalf("ServiceConfig() error = %v", err)
+		}
+		defer closeGeo()
+		if serviceConfig.Cache.Size != 500 || serviceConfig.Cache.TTL != time.Minute || serviceConfig.Cache.IPv4PrefixBits != 24 || serviceConfig.Cache.Shared != nil {
+			t.Errorf("Expected the cache settings without a shared cache, got %+v", serviceConfig.Cache)
+		}
+		if serviceConfig.OverrideName != "location" || !serviceConfig.DisableOverride || serviceConfig.DisableCDNHeaders {
+			t.Errorf("Expected the override and CDN header settings, got %+v", serviceConfig)
+		}
+
+		geolocation.IPDatabaseFiles = []string{filepath.Join(t.TempDir(), "missing.mmdb")}
+		if _, _, err := geolocation.ServiceConfig(nil); err == nil {
+			t.Error("Expected an error for a missing IP database")
+		}
+	})
 }
 
 func TestParseStringSlice(t *testing.T) {
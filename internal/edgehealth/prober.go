@@ -0,0 +1,309 @@
+// Package edgehealth actively probes CDN edges over HTTP and reports their availability
+// to edge selection and the GSLB.
+package edgehealth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+)
+
+// ErrInvalidTarget is returned for probe targets that cannot be parsed
+var ErrInvalidTarget = errors.New("invalid probe target")
+
+// Target is an edge probed at a URL
+type Target struct {
+	Provider string `json:"provider"` // CDN provider key from the catalog
+	EdgeID   string `json:"edge_id"`  // Edge location key from the catalog
+	URL      string `json:"url"`      // URL answering 2xx or 3xx while the edge serves traffic
+}
+
+// Status is the probe state of one edge
+type Status struct {
+	Target
+	carbon.EdgeHealth
+	LastLatencyMs float64 `json:"last_latency_ms"`
+}
+
+// Config holds prober configuration
+type Config struct {
+	Interval         time.Duration // Time between probe rounds
+	Timeout          time.Duration // Timeout for a single probe
+	FailureThreshold int           // Consecutive failures before an edge is unhealthy
+	Window           int           // Probes kept for the availability rate
+}
+
+// result is the outcome of one probe
+type result struct {
+	ok        bool
+	checkedAt time.Time
+	latency   time.Duration
+	err       string
+}
+
+// edgeState holds the rolling probe results of one edge
+type edgeState struct {
+	target              Target
+	results             []result
+	next                int
+	consecutiveFailures int
+}
+
+// Prober periodically probes edge URLs. It implements carbon.EdgeHealthSource and
+// gslb.HealthChecker; edges without a target are reported healthy.
+type Prober struct {
+	config Config
+	client *http.Client
+	logger *slog.Logger
+
+	mu    sync.RWMutex
+	edges map[string]*edgeState // provider/edge -> state
+}
+
+var _ carbon.EdgeHealthSource = (*Prober)(nil)
+
+// NewProber creates a prober for targets. A nil client uses one with the configured timeout.
+func NewProber(config Config, targets []Target, client *http.Client, logger *slog.Logger) *Prober {
+	if config.Interval <= 0 {
+		config.Interval = 30 * time.Second
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 3
+	}
+	if config.Window <= 0 {
+		config.Window = 20
+	}
+	if client == nil {
+		client = &http.Client{
+			Timeout: config.Timeout,
+			// A redirect means the edge answered; don't probe the redirect target
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		}
+	}
+
+	edges := make(map[string]*edgeState, len(targets))
+	for _, target := range targets {
+		edges[key(target.Provider, target.EdgeID)] = &edgeState{target: target}
+	}
+
+	return &Prober{
+		config: config,
+		client: client,
+		logger: logger,
+		edges:  edges,
+	}
+}
+
+// ParseTargets parses targets written as "provider/edge=url"
+func ParseTargets(specs []string) ([]Target, error) {
+	targets := make([]Target, 0, len(specs))
+	for _, spec := range specs {
+		edge, rawURL, found := strings.Cut(spec, "=")
+		provider, edgeID, hasSlash := strings.Cut(strings.TrimSpace(edge), "/")
+		if !found || !hasSlash || provider == "" || edgeID == "" {
+			return nil, fmt.Errorf("%w: %q must be provider/edge=url", ErrInvalidTarget, spec)
+		}
+		rawURL = strings.TrimSpace(rawURL)
+		if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("%w: %q is not an http(s) URL", ErrInvalidTarget, rawURL)
+		}
+		targets = append(targets, Target{
+			Provider: strings.ToLower(provider),
+			EdgeID:   strings.ToLower(edgeID),
+			URL:      rawURL,
+		})
+	}
+	return targets, nil
+}
+
+// Run probes every target each interval until ctx is done
+func (p *Prober) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		p.ProbeAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProbeAll probes every target once, concurrently
+func (p *Prober) ProbeAll(ctx context.Context) {
+	p.mu.RLock()
+	targets := make([]Target, 0, len(p.edges))
+	for _, state := range p.edges {
+		targets = append(targets, state.target)
+	}
+	p.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target Target) {
+			defer wg.Done()
+			p.record(target, p.probe(ctx, target))
+		}(target)
+	}
+	wg.Wait()
+}
+
+// probe issues one GET against a target
+func (p *Prober) probe(ctx context.Context, target Target) result {
+	ctx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	res := result{checkedAt: start}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.URL, nil)
+	if err != nil {
+		res.err = err.Error()
+		return res
+	}
+	req.Header.Set("User-Agent", "GreenWeb-EdgeHealth/1.0")
+
+	resp, err := p.client.Do(req)
+	res.latency = time.Since(start)
+	if err != nil {
+		res.err = err.Error()
+		return res
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		res.err = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+		return res
+	}
+	res.ok = true
+	return res
+}
+
+// record stores a probe result in the target's rolling window
+func (p *Prober) record(target Target, res result) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, exists := p.edges[key(target.Provider, target.EdgeID)]
+	if !exists {
+		return
+	}
+
+	if len(state.results) < p.config.Window {
+		state.results = append(state.results, res)
+	} else {
+		state.results[state.next] = res
+		state.next = (state.next + 1) % p.config.Window
+	}
+
+	wasHealthy := state.consecutiveFailures < p.config.FailureThreshold
+	if res.ok {
+		state.consecutiveFailures = 0
+	} else {
+		state.consecutiveFailures++
+	}
+	if healthy := state.consecutiveFailures < p.config.FailureThreshold; healthy != wasHealthy && p.logger != nil {
+		p.logger.Warn("Edge health changed",
+			"provider", target.Provider,
+			"edge", target.EdgeID,
+			"healthy", healthy,
+			"error", res.err)
+	}
+}
+
+// Status returns the probe state of an edge
+func (p *Prober) Status(provider, edgeID string) (Status, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	state, exists := p.edges[key(provider, edgeID)]
+	if !exists {
+		return Status{}, false
+	}
+	return p.status(state), true
+}
+
+// All returns the probe state of every target, ordered by provider and edge
+func (p *Prober) All() []Status {
+	p.mu.RLock()
+	statuses := make([]Status, 0, len(p.edges))
+	for _, state := range p.edges {
+		statuses = append(statuses, p.status(state))
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Provider != statuses[j].Provider {
+			return statuses[i].Provider < statuses[j].Provider
+		}
+		return statuses[i].EdgeID < statuses[j].EdgeID
+	})
+	return statuses
+}
+
+// status summarizes an edge's rolling window; callers hold the read lock
+func (p *Prober) status(state *edgeState) Status {
+	status := Status{
+		Target: state.target,
+		EdgeHealth: carbon.EdgeHealth{
+			Healthy:             state.consecutiveFailures < p.config.FailureThreshold,
+			Checks:              len(state.results),
+			ConsecutiveFailures: state.consecutiveFailures,
+		},
+	}
+	if len(state.results) == 0 {
+		return status
+	}
+
+	successes := 0
+	var last result
+	for _, res := range state.results {
+		if res.ok {
+			successes++
+		}
+		if res.checkedAt.After(last.checkedAt) {
+			last = res
+		}
+	}
+	status.Availability = float64(successes) * 100 / float64(len(state.results))
+	status.LastChecked = last.checkedAt
+	status.LastLatencyMs = float64(last.latency.Microseconds()) / 1000
+	if !last.ok {
+		status.LastError = last.err
+	}
+	return status
+}
+
+// EdgeHealth implements carbon.EdgeHealthSource
+func (p *Prober) EdgeHealth(provider, edgeID string) (carbon.EdgeHealth, bool) {
+	status, exists := p.Status(provider, edgeID)
+	return status.EdgeHealth, exists
+}
+
+// IsHealthy implements gslb.HealthChecker; edges without a probe target are healthy
+func (p *Prober) IsHealthy(provider, edgeID string) bool {
+	status, exists := p.Status(provider, edgeID)
+	return !exists || status.Healthy
+}
+
+// key identifies an edge across providers
+func key(provider, edgeID string) string {
+	return strings.ToLower(provider) + "/" + strings.ToLower(edgeID)
+}
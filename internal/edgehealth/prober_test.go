@@ -0,0 +1,132 @@
+package edgehealth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		want    []Target
+		wantErr bool
+	}{
+		{
+			name:  "valid targets",
+			specs: []string{"CloudFlare/Frankfurt=https://fra.example.com/healthz", "fastly/paris = http://cdg.example.com"},
+			want: []Target{
+				{Provider: "cloudflare", EdgeID: "frankfurt", URL: "https://fra.example.com/healthz"},
+				{Provider: "fastly", EdgeID: "paris", URL: "http://cdg.example.com"},
+			},
+		},
+		{name: "missing url", specs: []string{"cloudflare/frankfurt"}, wantErr: true},
+		{name: "missing edge", specs: []string{"cloudflare=https://example.com"}, wantErr: true},
+		{name: "non-http url", specs: []string{"cloudflare/frankfurt=ftp://example.com"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTargets(tt.specs)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTarget) {
+					t.Fatalf("expected ErrInvalidTarget, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d targets, got %d", len(tt.want), len(got))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("target %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProberHealthAndAvailability(t *testing.T) {
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	prober := NewProber(Config{Timeout: time.Second, FailureThreshold: 2, Window: 4},
+		[]Target{{Provider: "cloudflare", EdgeID: "frankfurt", URL: server.URL}}, nil, nil)
+	ctx := context.Background()
+
+	if !prober.IsHealthy("cloudflare", "frankfurt") {
+		t.Error("unprobed edge should be healthy")
+	}
+	if !prober.IsHealthy("cloudflare", "paris") {
+		t.Error("edge without a target should be healthy")
+	}
+	if _, exists := prober.EdgeHealth("cloudflare", "paris"); exists {
+		t.Error("edge without a target should not report health")
+	}
+
+	prober.ProbeAll(ctx)
+	prober.ProbeAll(ctx)
+	health, exists := prober.EdgeHealth("CloudFlare", "Frankfurt")
+	if !exists || !health.Healthy || health.Availability != 100 || health.Checks != 2 {
+		t.Fatalf("expected healthy edge at 100%% after 2 checks, got %+v", health)
+	}
+
+	failing.Store(true)
+	prober.ProbeAll(ctx)
+	if !prober.IsHealthy("cloudflare", "frankfurt") {
+		t.Error("edge should stay healthy below the failure threshold")
+	}
+	prober.ProbeAll(ctx)
+	health, _ = prober.EdgeHealth("cloudflare", "frankfurt")
+	if health.Healthy || health.ConsecutiveFailures != 2 {
+		t.Errorf("expected unhealthy edge after 2 failures, got %+v", health)
+	}
+	if health.Availability != 50 {
+		t.Errorf("expected 50%% availability, got %.1f", health.Availability)
+	}
+	if health.LastError != "unexpected status 503" {
+		t.Errorf("unexpected last error %q", health.LastError)
+	}
+
+	// The window holds 4 results; two successes push out the oldest successes
+	failing.Store(false)
+	prober.ProbeAll(ctx)
+	prober.ProbeAll(ctx)
+	health, _ = prober.EdgeHealth("cloudflare", "frankfurt")
+	if !health.Healthy || health.Checks != 4 || health.Availability != 50 || health.LastError != "" {
+		t.Errorf("expected recovered edge at 50%% over 4 checks, got %+v", health)
+	}
+}
+
+func TestProberUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	prober := NewProber(Config{Timeout: time.Second, FailureThreshold: 1},
+		[]Target{{Provider: "fastly", EdgeID: "paris", URL: url}}, nil, nil)
+	prober.ProbeAll(context.Background())
+
+	statuses := prober.All()
+	if len(statuses) != 1 {
+		t.Fatalf("expected 1 status, got %d", len(statuses))
+	}
+	if statuses[0].Healthy || statuses[0].Availability != 0 || statuses[0].LastError == "" {
+		t.Errorf("expected unreachable edge to be unhealthy with an error, got %+v", statuses[0])
+	}
+}
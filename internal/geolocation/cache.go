@@ -0,0 +1,214 @@
+package geolocation
+
+import (
+	"container/list"
+	"context"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sharedCacheKeyPrefix namespaces geolocation entries in a shared cache
+const sharedCacheKeyPrefix = "geolocation:"
+
+// SharedCache is a cache shared between replicas, such as the Redis cache.Cacher
+type SharedCache interface {
+	Get(ctx context.Context, key string, dest interface{}) error
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+}
+
+// CacheConfig holds configuration for the geolocation lookup cache
+type CacheConfig struct {
+	Size           int           // Maximum entries; 0 uses 10000, negative disables the cache
+	TTL            time.Duration // Entry lifetime (default 1 hour)
+	IPv4PrefixBits int           // IPv4 addresses share entries per prefix (default /24)
+	IPv6PrefixBits int           // IPv6 addresses share entries per prefix (default /48)
+	Shared         SharedCache   // Optional cache shared between replicas
+}
+
+// CacheStats reports geolocation cache effectiveness
+type CacheStats struct {
+	Entries    int     `json:"entries"`
+	Capacity   int     `json:"capacity"`
+	Hits       int64   `json:"hits"`
+	SharedHits int64   `json:"shared_hits"`
+	Misses     int64   `json:"misses"`
+	Coalesced  int64   `json:"coalesced"` // Lookups that waited on a concurrent lookup of the same key
+	Evictions  int64   `json:"evictions"`
+	HitRate    float64 `json:"hit_rate"` // Share of lookups answered without resolving (0-1)
+}
+
+// cacheEntry is a cached lookup result
+type cacheEntry struct {
+	key       string
+	value     LocationWithZone
+	expiresAt time.Time
+}
+
+// lookupCache is a bounded LRU cache with per-entry expiry, keyed by network prefix
+type lookupCache struct {
+	config CacheConfig
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // Front is most recently used
+
+	hits, sharedHits, misses, coalesced, evictions atomic.Int64
+}
+
+// newLookupCache creates a cache, or returns nil when disabled
+func newLookupCache(config CacheConfig) *lookupCache {
+	if config.Size < 0 {
+		return nil
+	}
+	if config.Size == 0 {
+		config.Size = 10000
+	}
+	if config.TTL <= 0 {
+		config.TTL = time.Hour
+	}
+	if config.IPv4PrefixBits <= 0 || config.IPv4PrefixBits > 32 {
+		config.IPv4PrefixBits = 24
+	}
+	if config.IPv6PrefixBits <= 0 || config.IPv6PrefixBits > 128 {
+		config.IPv6PrefixBits = 48
+	}
+
+	return &lookupCache{
+		config:  config,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// key returns the cache key for an IP: its network prefix
+func (c *lookupCache) key(ip string) (string, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", false
+	}
+	addr = addr.Unmap()
+
+	bits := c.config.IPv6PrefixBits
+	if addr.Is4() {
+		bits = c.config.IPv4PrefixBits
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return "", false
+	}
+	return prefix.String(), true
+}
+
+// get returns an unexpired entry and marks it recently used
+func (c *lookupCache) get(key string) (LocationWithZone, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, exists := c.entries[key]
+	if !exists {
+		return LocationWithZone{}, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return LocationWithZone{}, false
+	}
+	c.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// set stores an entry, evicting the least recently used entry when full
+func (c *lookupCache) set(key string, value LocationWithZone) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.config.TTL)
+	if element, exists := c.entries[key]; exists {
+		entry := element.Value.(*cacheEntry)
+		entry.value, entry.expiresAt = value, expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.config.Size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+		c.evictions.Add(1)
+	}
+}
+
+// stats returns a snapshot of the cache counters
+func (c *lookupCache) stats() CacheStats {
+	c.mu.Lock()
+	entries := c.order.Len()
+	c.mu.Unlock()
+
+	stats := CacheStats{
+		Entries:    entries,
+		Capacity:   c.config.Size,
+		Hits:       c.hits.Load(),
+		SharedHits: c.sharedHits.Load(),
+		Misses:     c.misses.Load(),
+		Coalesced:  c.coalesced.Load(),
+		Evictions:  c.evictions.Load(),
+	}
+	if total := stats.Hits + stats.SharedHits + stats.Misses + stats.Coalesced; total > 0 {
+		stats.HitRate = float64(stats.Hits+stats.SharedHits+stats.Coalesced) / float64(total)
+	}
+	return stats
+}
+
+// flightTimeout bounds a shared lookup, which no longer follows any single caller's context
+const flightTimeout = 15 * time.Second
+
+// flightCall is an in-flight lookup that concurrent callers wait on
+type flightCall struct {
+	done  chan struct{}
+	value LocationWithZone
+	err   error
+}
+
+// flightGroup coalesces concurrent lookups of the same key into one
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// do runs fn once per key at a time; callers arriving while it runs share its result.
+// fn runs on a context detached from the callers, so one caller giving up neither cancels
+// the lookup nor fails the others; each caller stops waiting when its own ctx is done.
+// The second result reports whether the result was shared.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(context.Context) (LocationWithZone, error)) (LocationWithZone, bool, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	call, shared := g.calls[key]
+	if !shared {
+		call = &flightCall{done: make(chan struct{})}
+		g.calls[key] = call
+		go func() {
+			lookupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), flightTimeout)
+			defer cancel()
+			call.value, call.err = fn(lookupCtx)
+
+			g.mu.Lock()
+			delete(g.calls, key)
+			g.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.value, shared, call.err
+	case <-ctx.Done():
+		return LocationWithZone{}, shared, ctx.Err()
+	}
+}
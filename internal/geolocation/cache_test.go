@@ -0,0 +1,258 @@
+package geolocation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLookupCacheKey(t *testing.T) {
+	cache := newLookupCache(CacheConfig{})
+
+	tests := []struct {
+		ip       string
+		expected string
+	}{
+		{"81.2.69.142", "81.2.69.0/24"},
+		{"81.2.69.1", "81.2.69.0/24"},
+		{"::ffff:81.2.69.1", "81.2.69.0/24"},
+		{"2a02:db8:1:2::1", "2a02:db8:1::/48"},
+	}
+	for _, tt := range tests {
+		key, ok := cache.key(tt.ip)
+		if !ok || key != tt.expected {
+			t.Errorf("key(%s) = %q (%v), want %q", tt.ip, key, ok, tt.expected)
+		}
+	}
+
+	if _, ok := cache.key("not-an-ip"); ok {
+		t.Error("invalid IPs should not produce a key")
+	}
+	if newLookupCache(CacheConfig{Size: -1}) != nil {
+		t.Error("negative size should disable the cache")
+	}
+}
+
+func TestLookupCacheEvictionAndExpiry(t *testing.T) {
+	cache := newLookupCache(CacheConfig{Size: 2, TTL: 50 * time.Millisecond})
+	value := func(city string) LocationWithZone {
+		return LocationWithZone{Location: Location{CountryCode: "DE", City: city}}
+	}
+
+	cache.set("a", value("A"))
+	cache.set("b", value("B"))
+	cache.get("a") // a becomes most recently used
+	cache.set("c", value("C"))
+
+	if _, found := cache.get("b"); found {
+		t.Error("least recently used entry should have been evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, found := cache.get(key); !found {
+			t.Errorf("expected entry %s", key)
+		}
+	}
+	if stats := cache.stats(); stats.Entries != 2 || stats.Evictions != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, found := cache.get("a"); found {
+		t.Error("expired entry should not be returned")
+	}
+}
+
+// fakeSharedCache is an in-memory SharedCache storing JSON like the Redis cache
+type fakeSharedCache struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+func (f *fakeSharedCache) Get(ctx context.Context, key string, dest interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, exists := f.values[key]
+	if !exists {
+		return errors.New("cache miss")
+	}
+	return json.Unmarshal(data, dest)
+}
+
+func (f *fakeSharedCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.values[key] = data
+	return nil
+}
+
+// newCountingAPI serves ipapi.co style responses, blocking until release is closed
+func newCountingAPI(t *testing.T, release chan struct{}) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+		ip := strings.Split(strings.Trim(r.URL.Path, "/"), "/")[0]
+		json.NewEncoder(w).Encode(IpapiResponse{IP: ip, City: "Stockholm", CountryName: "Sweden", CountryCode: "SE", Latitude: 59.33, Longitude: 18.06})
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestServiceCachesAndCoalescesLookups(t *testing.T) {
+	release := make(chan struct{})
+	server, requests := newCountingAPI(t, release)
+	service := NewService(ServiceConfig{APIBaseURL: server.URL, RateLimitRPS: 100})
+
+	// Concurrent lookups in the same /24 share one API request
+	var wg sync.WaitGroup
+	results := make([]LocationWithZone, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = service.GetLocationByIP(context.Background(), "89.160.20."+strconv.Itoa(i+1))
+		}(i)
+	}
+	for requests.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond) // Let the remaining lookups join the in-flight request
+	close(release)
+	wg.Wait()
+
+	for i, result := range results {
+		if result.Location.City != "Stockholm" || result.Location.IP != "89.160.20."+strconv.Itoa(i+1) {
+			t.Errorf("unexpected result %d: %+v", i, result.Location)
+		}
+	}
+
+	result, err := service.GetLocationByIP(context.Background(), "89.160.20.200")
+	if err != nil || result.Location.City != "Stockholm" || result.Location.IP != "89.160.20.200" {
+		t.Errorf("unexpected cached result %+v (%v)", result.Location, err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("expected 1 API request, got %d", requests.Load())
+	}
+
+	stats, enabled := service.CacheStats()
+	if !enabled {
+		t.Fatal("cache should be enabled by default")
+	}
+	if stats.Misses != 1 || stats.Hits+stats.Coalesced != 5 || stats.Entries != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if stats.HitRate < 0.8 {
+		t.Errorf("expected hit rate of at least 0.8, got %.2f", stats.HitRate)
+	}
+}
+
+func TestServiceCoalescedLookupsFollowTheirOwnContext(t *testing.T) {
+	release := make(chan struct{})
+	server, requests := newCountingAPI(t, release)
+	service := NewService(ServiceConfig{APIBaseURL: server.URL, RateLimitRPS: 100})
+
+	// The caller that starts the lookup gives up while another waits on it
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := service.GetLocationByIP(leaderCtx, "89.160.20.1")
+		leaderErr <- err
+	}()
+	for requests.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	type lookup struct {
+		result LocationWithZone
+		err    error
+	}
+	waiter := make(chan lookup, 1)
+	go func() {
+		result, err := service.GetLocationByIP(context.Background(), "89.160.20.2")
+		waiter <- lookup{result, err}
+	}()
+
+	// A waiter whose own context ends stops waiting without ending the lookup
+	impatientCtx, cancelImpatient := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelImpatient()
+	if result, err := service.GetLocationByIP(impatientCtx, "89.160.20.3"); !errors.Is(err, context.DeadlineExceeded) || result.Location != DefaultLocation {
+		t.Errorf("expected the default location and a deadline error, got %+v (%v)", result.Location, err)
+	}
+
+	cancelLeader()
+	select {
+	case err := <-leaderErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the canceled caller to get context.Canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("canceled caller kept waiting for the lookup")
+	}
+
+	close(release)
+	got := <-waiter
+	if got.err != nil || got.result.Location.City != "Stockholm" || got.result.Location.IP != "89.160.20.2" {
+		t.Errorf("expected the waiter to get the lookup result, got %+v (%v)", got.result.Location, got.err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("expected 1 API request, got %d", requests.Load())
+	}
+}
+
+func TestServiceDoesNotCacheFallbacks(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	service := NewService(ServiceConfig{APIBaseURL: server.URL, RateLimitRPS: 100})
+
+	for i := 0; i < 2; i++ {
+		result, err := service.GetLocationByIP(context.Background(), "89.160.20.1")
+		if err != nil || result.Location != DefaultLocation {
+			t.Errorf("expected default location, got %+v (%v)", result.Location, err)
+		}
+	}
+	if requests.Load() != 2 {
+		t.Errorf("failed lookups should be retried, got %d API requests", requests.Load())
+	}
+}
+
+func TestServiceSharedCache(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	server, requests := newCountingAPI(t, release)
+	shared := &fakeSharedCache{values: make(map[string][]byte)}
+
+	first := NewService(ServiceConfig{APIBaseURL: server.URL, RateLimitRPS: 100, Cache: CacheConfig{Shared: shared}})
+	if _, err := first.GetLocationByIP(context.Background(), "89.160.20.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second replica finds the lookup in the shared cache
+	second := NewService(ServiceConfig{APIBaseURL: server.URL, RateLimitRPS: 100, Cache: CacheConfig{Shared: shared}})
+	result, err := second.GetLocationByIP(context.Background(), "89.160.20.9")
+	if err != nil || result.Location.City != "Stockholm" || result.Location.IP != "89.160.20.9" || result.GridZone.Zone != "SE" {
+		t.Errorf("unexpected shared result %+v (%v)", result, err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("expected 1 API request across replicas, got %d", requests.Load())
+	}
+	if stats, _ := second.CacheStats(); stats.SharedHits != 1 || stats.Misses != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
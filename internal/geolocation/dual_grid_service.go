@@ -5,30 +5,49 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/netip"
+	"sort"
 
 	"github.com/perschulte/greenweb-api/pkg/carbon"
 )
 
 // DualGridService provides enhanced geolocation functionality for dual-grid carbon detection
 type DualGridService struct {
-	*Service                     // Embed base Service
-	cdnProviders map[string]bool // Track available CDN providers
+	*Service // Embed base Service
+
+	rttModel carbon.RTTModel
 }
 
 // NewDualGridService creates a new dual-grid geolocation service
 func NewDualGridService(config ServiceConfig) *DualGridService {
-	baseService := NewService(config)
-	
-	// Initialize with available CDN providers
-	cdnProviders := make(map[string]bool)
-	for _, provider := range carbon.GetAllCDNProviders() {
-		cdnProviders[provider] = true
+	return &DualGridService{
+		Service:  NewService(config),
+		rttModel: carbon.DefaultRTTModel,
 	}
+}
 
-	return &DualGridService{
-		Service:      baseService,
-		cdnProviders: cdnProviders,
+// SetRTTModel sets the RTT model used to pick and report edge locations, e.g. a measured
+// RTT matrix. Call it before serving requests.
+func (s *DualGridService) SetRTTModel(model carbon.RTTModel) {
+	s.rttModel = model
+}
+
+// EdgeSelectionUser returns the user for edge selection. The client address is used as the
+// prefix for measured RTT lookups, except for overridden locations, which don't describe
+// the client's network path.
+func (l LocationWithZone) EdgeSelectionUser() carbon.EdgeSelectionUser {
+	user := carbon.EdgeSelectionUser{
+		Latitude:  l.Location.Latitude,
+		Longitude: l.Location.Longitude,
+		GridZone:  l.GridZone.Zone,
+	}
+	if l.Source != LocationSourceOverride {
+		if addr, err := netip.ParseAddr(l.Location.IP); err == nil {
+			addr = addr.Unmap()
+			user.Prefix = netip.PrefixFrom(addr, addr.BitLen()).String()
+		}
 	}
+	return user
 }
 
 // DualLocationResult contains both user and edge location information
@@ -36,7 +55,11 @@ type DualLocationResult struct {
 	UserLocation LocationWithZone     `json:"user_location"`
 	EdgeLocation *EdgeLocationDetails `json:"edge_location,omitempty"`
 	Distance     float64              `json:"distance_km,omitempty"`
-	NetworkHops  int                  `json:"network_hops,omitempty"`
+
+	// Latency between user and edge from the RTT model, measured where available
+	LatencyP50Ms  float64 `json:"latency_p50_ms,omitempty"`
+	LatencyP95Ms  float64 `json:"latency_p95_ms,omitempty"`
+	LatencySource string  `json:"latency_source,omitempty"`
 }
 
 // EdgeLocationDetails provides detailed information about an edge location
@@ -61,26 +84,19 @@ func (s *DualGridService) GetDualLocationFromRequest(ctx context.Context, r *htt
 		UserLocation: userLocation,
 	}
 
-	// If CDN provider is specified, find optimal edge location
-	if cdnProvider != "" && s.cdnProviders[cdnProvider] {
-		edgeDetails, err := s.getOptimalEdgeLocation(userLocation.Location, cdnProvider)
+	// If CDN provider is specified, find the edge with the lowest latency
+	if cdnProvider != "" && s.isSupportedCDNProvider(cdnProvider) {
+		user := userLocation.EdgeSelectionUser()
+		edgeID, edgeDetails, err := s.getOptimalEdgeLocation(user, cdnProvider)
 		if err != nil {
 			log.Printf("Failed to get optimal edge location: %v", err)
 			return result, nil // Return user location only
 		}
 
 		result.EdgeLocation = edgeDetails
-		
-		// Calculate distance and network hops
-		if edgeDetails != nil {
-			result.Distance = carbon.CalculateDistance(
-				userLocation.Location.Latitude,
-				userLocation.Location.Longitude,
-				edgeDetails.LocationInfo.Latitude,
-				edgeDetails.LocationInfo.Longitude,
-			)
-			result.NetworkHops = carbon.EstimateNetworkHops(result.Distance)
-		}
+		result.Distance = carbon.CalculateDistance(user.Latitude, user.Longitude,
+			edgeDetails.LocationInfo.Latitude, edgeDetails.LocationInfo.Longitude)
+		result.LatencyP50Ms, result.LatencyP95Ms, result.LatencySource = carbon.EstimateRTTPercentiles(s.rttModel, user, edgeID, edgeDetails.LocationInfo)
 	}
 
 	return result, nil
@@ -88,7 +104,7 @@ func (s *DualGridService) GetDualLocationFromRequest(ctx context.Context, r *htt
 
 // GetNearestCDNEdge finds the nearest edge location for a given CDN provider
 func (s *DualGridService) GetNearestCDNEdge(userLocation Location, cdnProvider string) (*EdgeLocationDetails, error) {
-	if !s.cdnProviders[cdnProvider] {
+	if !s.isSupportedCDNProvider(cdnProvider) {
 		return nil, fmt.Errorf("CDN provider %s not supported", cdnProvider)
 	}
 
@@ -102,19 +118,9 @@ func (s *DualGridService) GetNearestCDNEdge(userLocation Location, cdnProvider s
 		return nil, fmt.Errorf("no edge locations found for provider %s", cdnProvider)
 	}
 
-	// Map edge location to grid zone
-	gridZone := s.gridMapper.MapToGridZone(Location{
-		Country:     edgeInfo.Country,
-		CountryCode: s.getCountryCodeFromGridZone(edgeInfo.GridZone),
-		Region:      edgeInfo.City,
-		City:        edgeInfo.City,
-		Latitude:    edgeInfo.Latitude,
-		Longitude:   edgeInfo.Longitude,
-	})
-
 	return &EdgeLocationDetails{
 		LocationInfo: *edgeInfo,
-		GridZone:     gridZone,
+		GridZone:     s.edgeGridZone(*edgeInfo),
 		Provider:     cdnProvider,
 		IsOptimal:    distance < 500, // Consider optimal if within 500km
 	}, nil
@@ -122,7 +128,7 @@ func (s *DualGridService) GetNearestCDNEdge(userLocation Location, cdnProvider s
 
 // GetOptimalCDNEdgeForCarbon finds the edge location with the lowest carbon footprint
 func (s *DualGridService) GetOptimalCDNEdgeForCarbon(userLocation Location, cdnProvider string, getCarbonIntensity func(gridZone string) float64) (*EdgeLocationDetails, error) {
-	if !s.cdnProviders[cdnProvider] {
+	if !s.isSupportedCDNProvider(cdnProvider) {
 		return nil, fmt.Errorf("CDN provider %s not supported", cdnProvider)
 	}
 
@@ -169,7 +175,7 @@ func (s *DualGridService) GetOptimalCDNEdgeForCarbon(userLocation Location, cdnP
 
 // GetAllCDNAlternatives returns all available edge locations for a CDN provider, ranked by carbon efficiency
 func (s *DualGridService) GetAllCDNAlternatives(userLocation Location, cdnProvider string, getCarbonIntensity func(gridZone string) float64, maxResults int) ([]EdgeLocationDetails, error) {
-	if !s.cdnProviders[cdnProvider] {
+	if !s.isSupportedCDNProvider(cdnProvider) {
 		return nil, fmt.Errorf("CDN provider %s not supported", cdnProvider)
 	}
 
@@ -254,20 +260,60 @@ func (s *DualGridService) GetAllCDNAlternatives(userLocation Location, cdnProvid
 	return results, nil
 }
 
-// GetSupportedCDNProviders returns a list of supported CDN providers
+// GetSupportedCDNProviders returns a list of supported CDN providers.
+// It reads the live catalog so providers added by a reload are picked up.
 func (s *DualGridService) GetSupportedCDNProviders() []string {
-	providers := make([]string, 0, len(s.cdnProviders))
-	for provider := range s.cdnProviders {
-		providers = append(providers, provider)
+	return carbon.GetAllCDNProviders()
+}
+
+// isSupportedCDNProvider checks the provider against the live CDN catalog
+func (s *DualGridService) isSupportedCDNProvider(cdnProvider string) bool {
+	_, exists := carbon.GetCDNProvider(cdnProvider)
+	return exists
+}
+
+// getOptimalEdgeLocation returns the provider's edge with the lowest p50 RTT for the user,
+// breaking ties by p95 and then edge ID
+func (s *DualGridService) getOptimalEdgeLocation(user carbon.EdgeSelectionUser, cdnProvider string) (string, *EdgeLocationDetails, error) {
+	provider, exists := carbon.GetCDNProvider(cdnProvider)
+	if !exists || len(provider.EdgeLocations) == 0 {
+		return "", nil, fmt.Errorf("no edge locations found for provider %s", cdnProvider)
+	}
+
+	edgeIDs := make([]string, 0, len(provider.EdgeLocations))
+	for edgeID := range provider.EdgeLocations {
+		edgeIDs = append(edgeIDs, edgeID)
+	}
+	sort.Strings(edgeIDs)
+
+	var bestID string
+	bestP50, bestP95 := 0.0, 0.0
+	for _, edgeID := range edgeIDs {
+		p50, p95, _ := carbon.EstimateRTTPercentiles(s.rttModel, user, edgeID, provider.EdgeLocations[edgeID])
+		if bestID == "" || p50 < bestP50 || (p50 == bestP50 && p95 < bestP95) {
+			bestID, bestP50, bestP95 = edgeID, p50, p95
+		}
 	}
-	return providers
+
+	edge := provider.EdgeLocations[bestID]
+	return bestID, &EdgeLocationDetails{
+		LocationInfo: edge,
+		GridZone:     s.edgeGridZone(edge),
+		Provider:     cdnProvider,
+		IsOptimal:    true,
+	}, nil
 }
 
-// getOptimalEdgeLocation is a helper method to find the optimal edge location
-func (s *DualGridService) getOptimalEdgeLocation(userLocation Location, cdnProvider string) (*EdgeLocationDetails, error) {
-	// For now, return the nearest edge location
-	// In production, this would integrate with carbon intensity data
-	return s.GetNearestCDNEdge(userLocation, cdnProvider)
+// edgeGridZone maps an edge location to its grid zone
+func (s *DualGridService) edgeGridZone(edge carbon.EdgeLocationInfo) GridZone {
+	return s.gridMapper.MapToGridZone(Location{
+		Country:     edge.Country,
+		CountryCode: s.getCountryCodeFromGridZone(edge.GridZone),
+		Region:      edge.City,
+		City:        edge.City,
+		Latitude:    edge.Latitude,
+		Longitude:   edge.Longitude,
+	})
 }
 
 // getCountryCodeFromGridZone attempts to extract country code from grid zone
@@ -325,17 +371,9 @@ func ValidateContentType(contentType string) (string, []string) {
 		return "static", errors // Default to static content
 	}
 	
-	validTypes := []string{"static", "api", "video", "dynamic", "ai", "database"}
-	typeValid := false
-	for _, valid := range validTypes {
-		if contentType == valid {
-			typeValid = true
-			break
-		}
-	}
-	
-	if !typeValid {
-		errors = append(errors, fmt.Sprintf("invalid content type: %s. Valid types: %v", contentType, validTypes))
+	// Built-in types plus tenant-registered "tenant/name" types
+	if !carbon.DefaultContentTypes.Has(contentType) {
+		errors = append(errors, fmt.Sprintf("invalid content type: %s. Valid types: %v or a registered tenant/name type", contentType, carbon.DefaultContentTypes.BuiltinNames()))
 	}
 	
 	return contentType, errors
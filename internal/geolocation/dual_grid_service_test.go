@@ -0,0 +1,82 @@
+package geolocation
+
+import (
+	"testing"
+
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+)
+
+// prefixRTTModel reports a measured RTT for one edge when the user is in its prefix
+type prefixRTTModel struct {
+	prefix string
+	edgeID string
+}
+
+func (m prefixRTTModel) EstimateRTT(user carbon.EdgeSelectionUser, edgeID string, edge carbon.EdgeLocationInfo) (float64, string) {
+	if user.Prefix == m.prefix && edgeID == m.edgeID {
+		return 5, carbon.RTTSourceMeasured
+	}
+	return 200, carbon.RTTSourceModel
+}
+
+func TestLocationEdgeSelectionUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		ip     string
+		prefix string
+	}{
+		{"IPv4 client", LocationSourceIPDatabase, "81.2.69.160", "81.2.69.160/32"},
+		{"IPv4-mapped client", LocationSourceCDNHeader, "::ffff:81.2.69.160", "81.2.69.160/32"},
+		{"IPv6 client", LocationSourceIPAPI, "2001:db8::1", "2001:db8::1/128"},
+		{"override", LocationSourceOverride, "81.2.69.160", ""},
+		{"no address", LocationSourceDefault, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location := LocationWithZone{
+				Location: Location{IP: tt.ip, Latitude: 51.5, Longitude: -0.1},
+				GridZone: GridZone{Zone: "GB"},
+				Source:   tt.source,
+			}
+			user := location.EdgeSelectionUser()
+			if user.Prefix != tt.prefix {
+				t.Errorf("Expected prefix %q, got %q", tt.prefix, user.Prefix)
+			}
+			if user.GridZone != "GB" || user.Latitude != 51.5 || user.Longitude != -0.1 {
+				t.Errorf("Expected the location's zone and coordinates, got %+v", user)
+			}
+		})
+	}
+}
+
+func TestOptimalEdgeLocationUsesRTTModel(t *testing.T) {
+	service := NewDualGridService(ServiceConfig{})
+	service.SetRTTModel(prefixRTTModel{prefix: "81.2.69.160/32", edgeID: "atlanta"})
+
+	// Berlin is far from Atlanta, but the measured RTT for the client's prefix wins
+	user := carbon.EdgeSelectionUser{Latitude: 52.52, Longitude: 13.405, GridZone: "DE", Prefix: "81.2.69.160/32"}
+	edgeID, details, err := service.getOptimalEdgeLocation(user, "cloudflare")
+	if err != nil {
+		t.Fatalf("getOptimalEdgeLocation() error = %v", err)
+	}
+	if edgeID != "atlanta" || details.LocationInfo.City != "Atlanta" {
+		t.Errorf("Expected the measured edge atlanta, got %s (%s)", edgeID, details.LocationInfo.City)
+	}
+
+	// Without measurements for the prefix the distance model picks a nearby edge
+	service.SetRTTModel(carbon.DefaultRTTModel)
+	user.Prefix = ""
+	edgeID, _, err = service.getOptimalEdgeLocation(user, "cloudflare")
+	if err != nil {
+		t.Fatalf("getOptimalEdgeLocation() error = %v", err)
+	}
+	if edgeID != "berlin" && edgeID != "frankfurt" {
+		t.Errorf("Expected an edge near Berlin, got %s", edgeID)
+	}
+
+	if _, _, err := service.getOptimalEdgeLocation(user, "unknown"); err == nil {
+		t.Error("Expected an error for an unknown provider")
+	}
+}
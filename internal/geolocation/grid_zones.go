@@ -1,12 +1,14 @@
 package geolocation
 
 import (
+	"fmt"
 	"strings"
 )
 
 // GridZoneMapper maps locations to electricity grid zones
 type GridZoneMapper struct {
-	zones map[string]GridZone
+	zones      map[string]GridZone
+	boundaries *ZoneBoundaries // Optional sub-national zone polygons
 }
 
 // NewGridZoneMapper creates a new grid zone mapper with predefined mappings
@@ -68,6 +70,11 @@ func (g *GridZoneMapper) initializeZones() {
 
 // MapToGridZone maps a location to its corresponding electricity grid zone
 func (g *GridZoneMapper) MapToGridZone(location Location) GridZone {
+	// Sub-national zones from coordinates take precedence
+	if zone, found := g.mapToSubnationalZone(location); found {
+		return zone
+	}
+
 	// First try country code
 	if zone, exists := g.zones[strings.ToUpper(location.CountryCode)]; exists {
 		zone.Region = location.Region
@@ -85,6 +92,53 @@ func (g *GridZoneMapper) MapToGridZone(location Location) GridZone {
 	return DefaultGridZone
 }
 
+// mapToSubnationalZone resolves a location's coordinates against the zone boundaries.
+// Matches in a different country than the location's country code are ignored.
+func (g *GridZoneMapper) mapToSubnationalZone(location Location) (GridZone, bool) {
+	if g.boundaries == nil || (location.Latitude == 0 && location.Longitude == 0) {
+		return GridZone{}, false
+	}
+	code, found := g.boundaries.Lookup(location.Latitude, location.Longitude)
+	if !found {
+		return GridZone{}, false
+	}
+
+	country := strings.SplitN(code, "-", 2)[0]
+	if location.CountryCode != "" && g.normalizeCountryCode(location.CountryCode, location.Country) != country {
+		return GridZone{}, false
+	}
+
+	zone, known := g.zoneForCode(code)
+	if !known {
+		zone = GridZone{Zone: code, Country: location.Country, Description: code + " electricity grid"}
+	}
+	zone.Region = location.Region
+	return zone, true
+}
+
+// zoneForCode builds the grid zone for a zone code such as "SE" or "US-CAL-CISO". The
+// second result is false when the code's country has no grid zone, or when a sub-national
+// code is not one of the zone boundaries.
+func (g *GridZoneMapper) zoneForCode(code string) (GridZone, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	country := strings.SplitN(code, "-", 2)[0]
+	parent, exists := g.zones[country]
+	if !exists {
+		return GridZone{}, false
+	}
+	if code == country {
+		return parent, true
+	}
+	if g.boundaries == nil || !g.boundaries.Contains(code) {
+		return GridZone{}, false
+	}
+	return GridZone{
+		Zone:        code,
+		Country:     parent.Country,
+		Description: fmt.Sprintf("%s (%s)", parent.Description, code),
+	}, true
+}
+
 // normalizeCountryCode handles alternative country code formats
 func (g *GridZoneMapper) normalizeCountryCode(code, country string) string {
 	code = strings.ToUpper(code)
@@ -121,4 +175,10 @@ func (g *GridZoneMapper) GetAvailableZones() []GridZone {
 // AddCustomZone allows adding custom grid zone mappings
 func (g *GridZoneMapper) AddCustomZone(countryCode string, zone GridZone) {
 	g.zones[strings.ToUpper(countryCode)] = zone
-}
\ No newline at end of file
+}
+
+// SetZoneBoundaries enables sub-national zone resolution from coordinates. Call it before
+// the mapper is used concurrently.
+func (g *GridZoneMapper) SetZoneBoundaries(boundaries *ZoneBoundaries) {
+	g.boundaries = boundaries
+}
@@ -0,0 +1,262 @@
+package geolocation
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidIPDatabase is returned for IP database files that cannot be parsed
+var ErrInvalidIPDatabase = errors.New("invalid IP database")
+
+// IPDatabase resolves IP addresses to locations from local data
+type IPDatabase interface {
+	Lookup(ip netip.Addr) (Location, bool)
+}
+
+// RangeDatabase is an in-memory IP database built from CIDR or start/end range rows,
+// answering lookups by longest-prefix match on a radix tree
+type RangeDatabase struct {
+	mu        sync.RWMutex
+	tree      *radixTree
+	locations []Location
+	index     map[Location]int32 // Deduplicates locations shared by many ranges
+}
+
+// NewRangeDatabase creates an empty range database
+func NewRangeDatabase() *RangeDatabase {
+	return &RangeDatabase{
+		tree:  newRadixTree(),
+		index: make(map[Location]int32),
+	}
+}
+
+// Insert maps a prefix to a location. More specific prefixes take precedence on lookup.
+func (db *RangeDatabase) Insert(prefix netip.Prefix, location Location) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.tree.insert(prefix.Masked(), db.intern(location))
+}
+
+// InsertRange maps the inclusive range [start, end] to a location
+func (db *RangeDatabase) InsertRange(start, end netip.Addr, location Location) error {
+	start, end = start.Unmap(), end.Unmap()
+	if start.Is4() != end.Is4() || end.Less(start) {
+		return fmt.Errorf("%w: invalid range %s-%s", ErrInvalidIPDatabase, start, end)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	value := db.intern(location)
+	for _, prefix := range rangeToPrefixes(start, end) {
+		db.tree.insert(prefix, value)
+	}
+	return nil
+}
+
+// intern returns the index of a location, adding it if new; callers hold the lock
+func (db *RangeDatabase) intern(location Location) int32 {
+	if i, exists := db.index[location]; exists {
+		return i
+	}
+	db.locations = append(db.locations, location)
+	i := int32(len(db.locations) - 1)
+	db.index[location] = i
+	return i
+}
+
+// Lookup implements IPDatabase
+func (db *RangeDatabase) Lookup(ip netip.Addr) (Location, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	i, found := db.tree.lookup(ip.Unmap())
+	if !found {
+		return Location{}, false
+	}
+	return db.locations[i], true
+}
+
+// Len returns the number of distinct locations
+func (db *RangeDatabase) Len() int {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return len(db.locations)
+}
+
+// csvColumns lists the accepted header names per location field
+var csvColumns = map[string][]string{
+	"network":      {"network", "cidr", "prefix"},
+	"start":        {"start_ip", "ip_start", "range_start", "first_ip"},
+	"end":          {"end_ip", "ip_end", "range_end", "last_ip"},
+	"country_code": {"country_code", "country_iso_code", "country"},
+	"country":      {"country_name"},
+	"region":       {"region", "region_name", "subdivision_1_name", "stateprov"},
+	"region_code":  {"region_code", "subdivision_1_iso_code"},
+	"city":         {"city", "city_name"},
+	"latitude":     {"latitude", "lat"},
+	"longitude":    {"longitude", "lon", "lng"},
+	"timezone":     {"timezone", "time_zone"},
+}
+
+// LoadCSV adds rows from CSV with a header row. Each row needs either a network column
+// (CIDR) or start_ip and end_ip columns, plus a country code; country_name, region,
+// region_code, city, latitude, longitude and timezone are optional.
+func (db *RangeDatabase) LoadCSV(r io.Reader) (int, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, fmt.Errorf("%w: empty CSV", ErrInvalidIPDatabase)
+		}
+		return 0, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	names := make(map[string]int)
+	for i, name := range header {
+		names[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	columns := make(map[string]int)
+	for field, aliases := range csvColumns {
+		columns[field] = -1
+		for _, alias := range aliases {
+			if i, exists := names[alias]; exists {
+				columns[field] = i
+				break
+			}
+		}
+	}
+
+	hasNetwork := columns["network"] >= 0
+	hasRange := columns["start"] >= 0 && columns["end"] >= 0
+	if (!hasNetwork && !hasRange) || columns["country_code"] < 0 {
+		return 0, fmt.Errorf("%w: CSV header needs network or start_ip/end_ip, and country_code", ErrInvalidIPDatabase)
+	}
+
+	rows := 0
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return rows, fmt.Errorf("failed to read CSV line %d: %w", line, err)
+		}
+
+		field := func(name string) string {
+			if i := columns[name]; i >= 0 && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		location := Location{
+			CountryCode: strings.ToUpper(field("country_code")),
+			Country:     field("country"),
+			Region:      field("region"),
+			RegionCode:  field("region_code"),
+			City:        field("city"),
+			Timezone:    field("timezone"),
+		}
+		if location.CountryCode == "" {
+			continue // Unassigned or anonymous ranges
+		}
+		if location.Country == "" {
+			location.Country = location.CountryCode
+		}
+		if value := field("latitude"); value != "" {
+			if location.Latitude, err = strconv.ParseFloat(value, 64); err != nil {
+				return rows, fmt.Errorf("%w: line %d: invalid latitude %q", ErrInvalidIPDatabase, line, value)
+			}
+		}
+		if value := field("longitude"); value != "" {
+			if location.Longitude, err = strconv.ParseFloat(value, 64); err != nil {
+				return rows, fmt.Errorf("%w: line %d: invalid longitude %q", ErrInvalidIPDatabase, line, value)
+			}
+		}
+
+		if hasNetwork && field("network") != "" {
+			prefix, err := netip.ParsePrefix(field("network"))
+			if err != nil {
+				return rows, fmt.Errorf("%w: line %d: invalid network %q", ErrInvalidIPDatabase, line, field("network"))
+			}
+			db.Insert(prefix, location)
+		} else {
+			start, startErr := netip.ParseAddr(field("start"))
+			end, endErr := netip.ParseAddr(field("end"))
+			if startErr != nil || endErr != nil {
+				return rows, fmt.Errorf("%w: line %d: invalid range %q-%q", ErrInvalidIPDatabase, line, field("start"), field("end"))
+			}
+			if err := db.InsertRange(start, end, location); err != nil {
+				return rows, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		rows++
+	}
+
+	return rows, nil
+}
+
+// chainDatabase tries several databases in order
+type chainDatabase []IPDatabase
+
+// Lookup implements IPDatabase
+func (c chainDatabase) Lookup(ip netip.Addr) (Location, bool) {
+	for _, db := range c {
+		if location, found := db.Lookup(ip); found {
+			return location, true
+		}
+	}
+	return Location{}, false
+}
+
+// OpenIPDatabases loads IP database files: .mmdb files are MaxMind DB format, anything
+// else is CSV. CSV files (e.g. separate IPv4 and IPv6 blocks) are merged into one tree;
+// databases are consulted in the order given.
+func OpenIPDatabases(paths ...string) (IPDatabase, error) {
+	var chain chainDatabase
+	var ranges *RangeDatabase
+
+	for _, path := range paths {
+		if strings.EqualFold(filepath.Ext(path), ".mmdb") {
+			reader, err := OpenMMDB(path)
+			if err != nil {
+				return nil, err
+			}
+			chain = append(chain, reader)
+			continue
+		}
+
+		if ranges == nil {
+			ranges = NewRangeDatabase()
+			chain = append(chain, ranges)
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open IP database: %w", err)
+		}
+		_, err = ranges.LoadCSV(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if len(chain) == 1 {
+		return chain[0], nil
+	}
+	return chain, nil
+}
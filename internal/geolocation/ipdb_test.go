@@ -0,0 +1,184 @@
+package geolocation
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRangeToPrefixes(t *testing.T) {
+	tests := []struct {
+		start, end string
+		expected   []string
+	}{
+		{"10.0.0.0", "10.0.0.255", []string{"10.0.0.0/24"}},
+		{"10.0.0.1", "10.0.0.6", []string{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6/32"}},
+		{"0.0.0.0", "255.255.255.255", []string{"0.0.0.0/0"}},
+		{"2001:db8::", "2001:db8::1:ffff", []string{"2001:db8::/111"}},
+		{"::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", []string{"::/0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.start+"-"+tt.end, func(t *testing.T) {
+			prefixes := rangeToPrefixes(netip.MustParseAddr(tt.start), netip.MustParseAddr(tt.end))
+			got := make([]string, len(prefixes))
+			for i, prefix := range prefixes {
+				got[i] = prefix.String()
+			}
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("rangeToPrefixes() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRangeDatabaseLongestPrefixMatch(t *testing.T) {
+	db := NewRangeDatabase()
+	db.Insert(netip.MustParsePrefix("81.0.0.0/8"), Location{CountryCode: "GB", City: "London"})
+	db.Insert(netip.MustParsePrefix("81.2.69.0/24"), Location{CountryCode: "GB", City: "Manchester"})
+	db.Insert(netip.MustParsePrefix("81.2.69.160/27"), Location{CountryCode: "GB", City: "Leeds"})
+	db.Insert(netip.MustParsePrefix("2a02:db8::/32"), Location{CountryCode: "SE", City: "Stockholm"})
+	if err := db.InsertRange(netip.MustParseAddr("5.10.0.3"), netip.MustParseAddr("5.10.2.200"), Location{CountryCode: "FR", City: "Paris"}); err != nil {
+		t.Fatalf("InsertRange failed: %v", err)
+	}
+
+	tests := []struct {
+		ip       string
+		expected string
+	}{
+		{"81.1.1.1", "London"},
+		{"81.2.69.1", "Manchester"},
+		{"81.2.69.170", "Leeds"},
+		{"81.2.69.192", "Manchester"},
+		{"::ffff:81.2.69.170", "Leeds"},
+		{"2a02:db8:1::1", "Stockholm"},
+		{"5.10.0.3", "Paris"},
+		{"5.10.1.77", "Paris"},
+		{"5.10.2.200", "Paris"},
+		{"5.10.0.2", ""},
+		{"5.10.2.201", ""},
+		{"82.0.0.1", ""},
+		{"2a03::1", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			location, found := db.Lookup(netip.MustParseAddr(tt.ip))
+			if tt.expected == "" {
+				if found {
+					t.Errorf("expected no match, got %s", location.City)
+				}
+				return
+			}
+			if !found || location.City != tt.expected {
+				t.Errorf("Lookup(%s) = %q (found %v), want %q", tt.ip, location.City, found, tt.expected)
+			}
+		})
+	}
+
+	if db.Len() != 5 {
+		t.Errorf("expected 5 distinct locations, got %d", db.Len())
+	}
+}
+
+func TestRangeDatabaseLoadCSV(t *testing.T) {
+	t.Run("networks", func(t *testing.T) {
+		db := NewRangeDatabase()
+		rows, err := db.LoadCSV(strings.NewReader(
+			"network,country_iso_code,country_name,region,region_code,city,latitude,longitude,time_zone\n" +
+				"81.2.69.0/24,gb,United Kingdom,England,ENG,London,51.5142,-0.0931,Europe/London\n" +
+				"2001:218::/32,JP,Japan,,,,35.69,139.69,Asia/Tokyo\n" +
+				"198.51.100.0/24,,,,,,,,\n"))
+		if err != nil {
+			t.Fatalf("LoadCSV failed: %v", err)
+		}
+		if rows != 2 {
+			t.Errorf("expected 2 rows, got %d", rows)
+		}
+
+		location, found := db.Lookup(netip.MustParseAddr("81.2.69.142"))
+		if !found || location.CountryCode != "GB" || location.RegionCode != "ENG" || location.Latitude != 51.5142 || location.Timezone != "Europe/London" {
+			t.Errorf("unexpected location %+v", location)
+		}
+		if _, found := db.Lookup(netip.MustParseAddr("198.51.100.1")); found {
+			t.Error("rows without a country should be skipped")
+		}
+	})
+
+	t.Run("ranges", func(t *testing.T) {
+		db := NewRangeDatabase()
+		_, err := db.LoadCSV(strings.NewReader("ip_start,ip_end,country,city\n1.0.0.0,1.0.0.255,AU,Sydney\n"))
+		if err != nil {
+			t.Fatalf("LoadCSV failed: %v", err)
+		}
+		location, found := db.Lookup(netip.MustParseAddr("1.0.0.1"))
+		if !found || location.City != "Sydney" || location.Country != "AU" {
+			t.Errorf("unexpected location %+v", location)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		inputs := []string{
+			"",
+			"city,latitude\nBerlin,52.5\n",
+			"network,country_code\nnot-a-network,DE\n",
+			"start_ip,end_ip,country_code\n10.0.0.9,10.0.0.1,DE\n",
+			"start_ip,end_ip,country_code\n10.0.0.1,::1,DE\n",
+		}
+		for _, input := range inputs {
+			if _, err := NewRangeDatabase().LoadCSV(strings.NewReader(input)); !errors.Is(err, ErrInvalidIPDatabase) {
+				t.Errorf("LoadCSV(%q): expected ErrInvalidIPDatabase, got %v", input, err)
+			}
+		}
+	})
+}
+
+func TestServiceUsesIPDatabase(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "blocks.csv")
+	if err := os.WriteFile(path, []byte("network,country_code,country_name,city,latitude,longitude\n8.8.8.0/24,US,United States,Mountain View,37.4,-122.1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := OpenIPDatabases(path)
+	if err != nil {
+		t.Fatalf("OpenIPDatabases failed: %v", err)
+	}
+
+	// The API base URL is unreachable; lookups must not need it
+	service := NewService(ServiceConfig{APIBaseURL: "http://127.0.0.1:1", IPDatabase: db})
+
+	result, err := service.GetLocationByIP(context.Background(), "8.8.8.8")
+	if err != nil {
+		t.Fatalf("GetLocationByIP failed: %v", err)
+	}
+	if result.Location.City != "Mountain View" || result.Location.IP != "8.8.8.8" || result.GridZone.Zone != "US" {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	result, err = service.GetLocationByIP(context.Background(), "9.9.9.9")
+	if err != nil || result.Location != DefaultLocation {
+		t.Errorf("addresses outside the database should use the default location without fallback, got %+v (%v)", result, err)
+	}
+
+	if err := service.HealthCheck(context.Background()); err != nil {
+		t.Errorf("database-only service should be healthy: %v", err)
+	}
+}
+
+func BenchmarkRangeDatabaseLookup(b *testing.B) {
+	db := NewRangeDatabase()
+	for i := 0; i < 65536; i++ {
+		prefix := netip.PrefixFrom(netip.AddrFrom4([4]byte{byte(i >> 8), byte(i), 0, 0}), 16)
+		db.Insert(prefix, Location{CountryCode: "DE", City: string(rune('A' + i%26))})
+	}
+	addr := netip.MustParseAddr("123.45.67.89")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		db.Lookup(addr)
+	}
+}
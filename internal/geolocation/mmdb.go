@@ -0,0 +1,426 @@
+package geolocation
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+	"sync"
+)
+
+// mmdbMetadataMarker precedes the metadata map at the end of a MaxMind DB file
+var mmdbMetadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// mmdbDataSectionSeparator is the size of the zero padding between search tree and data section
+const mmdbDataSectionSeparator = 16
+
+// MMDBReader reads City or Country databases in MaxMind DB format (GeoLite2, GeoIP2, DB-IP,
+// IPinfo). The file is read into memory; decoded locations are cached per data record.
+type MMDBReader struct {
+	buffer       []byte
+	data         []byte // Data section
+	nodeCount    uint
+	recordSize   uint
+	ipVersion    uint
+	databaseType string
+	ipv4Start    uint // Node after ::/96 in IPv6 trees
+	ipv4Depth    int
+
+	mu    sync.RWMutex
+	cache map[uint]Location // data offset -> location
+}
+
+// OpenMMDB reads a MaxMind DB file
+func OpenMMDB(path string) (*MMDBReader, error) {
+	buffer, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MaxMind DB: %w", err)
+	}
+	return NewMMDBReader(buffer)
+}
+
+// NewMMDBReader parses a MaxMind DB held in memory
+func NewMMDBReader(buffer []byte) (*MMDBReader, error) {
+	markerAt := bytes.LastIndex(buffer, mmdbMetadataMarker)
+	if markerAt < 0 {
+		return nil, fmt.Errorf("%w: MaxMind DB metadata not found", ErrInvalidIPDatabase)
+	}
+	metadataStart := markerAt + len(mmdbMetadataMarker)
+	metadataDecoder := mmdbDecoder{buffer: buffer[metadataStart:]}
+	value, _, err := metadataDecoder.decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: metadata: %v", ErrInvalidIPDatabase, err)
+	}
+	metadata, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: metadata is not a map", ErrInvalidIPDatabase)
+	}
+
+	reader := &MMDBReader{
+		buffer:     buffer,
+		nodeCount:  uint(asUint(metadata["node_count"])),
+		recordSize: uint(asUint(metadata["record_size"])),
+		ipVersion:  uint(asUint(metadata["ip_version"])),
+		cache:      make(map[uint]Location),
+	}
+	reader.databaseType, _ = metadata["database_type"].(string)
+
+	switch reader.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("%w: unsupported record size %d", ErrInvalidIPDatabase, reader.recordSize)
+	}
+	treeSize := reader.nodeCount * reader.recordSize / 4
+	if treeSize+mmdbDataSectionSeparator > uint(markerAt) {
+		return nil, fmt.Errorf("%w: search tree exceeds file size", ErrInvalidIPDatabase)
+	}
+	reader.data = buffer[treeSize+mmdbDataSectionSeparator : markerAt]
+
+	if reader.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < reader.nodeCount; i++ {
+			node = reader.readNode(node, 0)
+			reader.ipv4Depth = i + 1
+		}
+		reader.ipv4Start = node
+	}
+
+	return reader, nil
+}
+
+// DatabaseType returns the database type from the metadata, e.g. "GeoLite2-City"
+func (r *MMDBReader) DatabaseType() string {
+	return r.databaseType
+}
+
+// Lookup implements IPDatabase
+func (r *MMDBReader) Lookup(ip netip.Addr) (Location, bool) {
+	ip = ip.Unmap()
+	offset, found := r.lookupOffset(ip)
+	if !found {
+		return Location{}, false
+	}
+
+	r.mu.RLock()
+	location, cached := r.cache[offset]
+	r.mu.RUnlock()
+	if cached {
+		return location, true
+	}
+
+	decoder := mmdbDecoder{buffer: r.data}
+	value, _, err := decoder.decode(offset, 0)
+	if err != nil {
+		return Location{}, false
+	}
+	record, _ := value.(map[string]interface{})
+	location = locationFromMMDB(record)
+	if location.CountryCode == "" {
+		return Location{}, false
+	}
+
+	r.mu.Lock()
+	r.cache[offset] = location
+	r.mu.Unlock()
+	return location, true
+}
+
+// lookupOffset walks the search tree and returns the data section offset for ip
+func (r *MMDBReader) lookupOffset(ip netip.Addr) (uint, bool) {
+	var address []byte
+	node := uint(0)
+	if ip.Is4() {
+		if r.ipVersion == 6 {
+			if r.ipv4Depth < 96 {
+				return 0, false
+			}
+			node = r.ipv4Start
+		}
+		b := ip.As4()
+		address = b[:]
+	} else {
+		if r.ipVersion == 4 {
+			return 0, false
+		}
+		b := ip.As16()
+		address = b[:]
+	}
+
+	for i := 0; i < len(address)*8 && node < r.nodeCount; i++ {
+		bit := uint(address[i/8]>>(7-i%8)) & 1
+		node = r.readNode(node, bit)
+	}
+
+	if node <= r.nodeCount {
+		return 0, false // Empty record
+	}
+	resolved := node - r.nodeCount - mmdbDataSectionSeparator
+	if resolved >= uint(len(r.data)) {
+		return 0, false
+	}
+	return resolved, true
+}
+
+// readNode returns the left (bit 0) or right (bit 1) record of a search tree node
+func (r *MMDBReader) readNode(node, bit uint) uint {
+	b := r.buffer
+	switch r.recordSize {
+	case 24:
+		offset := node*6 + bit*3
+		return uint(b[offset])<<16 | uint(b[offset+1])<<8 | uint(b[offset+2])
+	case 28:
+		offset := node * 7
+		if bit == 0 {
+			return uint(b[offset+3]&0xF0)<<20 | uint(b[offset])<<16 | uint(b[offset+1])<<8 | uint(b[offset+2])
+		}
+		return uint(b[offset+3]&0x0F)<<24 | uint(b[offset+4])<<16 | uint(b[offset+5])<<8 | uint(b[offset+6])
+	default:
+		offset := node*8 + bit*4
+		return uint(binary.BigEndian.Uint32(b[offset : offset+4]))
+	}
+}
+
+// locationFromMMDB maps a City or Country record to a Location
+func locationFromMMDB(record map[string]interface{}) Location {
+	child := func(m map[string]interface{}, key string) map[string]interface{} {
+		value, _ := m[key].(map[string]interface{})
+		return value
+	}
+	str := func(m map[string]interface{}, key string) string {
+		value, _ := m[key].(string)
+		return value
+	}
+	name := func(m map[string]interface{}) string {
+		return str(child(m, "names"), "en")
+	}
+
+	country := child(record, "country")
+	if country == nil {
+		country = child(record, "registered_country")
+	}
+	location := Location{
+		CountryCode: str(country, "iso_code"),
+		Country:     name(country),
+		City:        name(child(record, "city")),
+	}
+	if location.Country == "" {
+		location.Country = location.CountryCode
+	}
+
+	if subdivisions, ok := record["subdivisions"].([]interface{}); ok && len(subdivisions) > 0 {
+		if subdivision, ok := subdivisions[0].(map[string]interface{}); ok {
+			location.Region = name(subdivision)
+			location.RegionCode = str(subdivision, "iso_code")
+		}
+	}
+
+	if loc := child(record, "location"); loc != nil {
+		location.Latitude, _ = loc["latitude"].(float64)
+		location.Longitude, _ = loc["longitude"].(float64)
+		location.Timezone = str(loc, "time_zone")
+	}
+
+	return location
+}
+
+// asUint converts a decoded unsigned integer to uint64
+func asUint(value interface{}) uint64 {
+	switch v := value.(type) {
+	case uint64:
+		return v
+	case int64:
+		return uint64(v)
+	}
+	return 0
+}
+
+// MaxMind DB data section types
+const (
+	mmdbExtended = iota
+	mmdbPointer
+	mmdbString
+	mmdbDouble
+	mmdbBytes
+	mmdbUint16
+	mmdbUint32
+	mmdbMap
+	mmdbInt32
+	mmdbUint64
+	mmdbUint128
+	mmdbArray
+	mmdbContainer
+	mmdbEndMarker
+	mmdbBool
+	mmdbFloat
+)
+
+// mmdbMaxDepth bounds nesting to reject malicious or corrupt files
+const mmdbMaxDepth = 32
+
+// mmdbDecoder decodes values of the MaxMind DB data section format
+type mmdbDecoder struct {
+	buffer []byte
+}
+
+// decode decodes the value at offset, returning it and the offset after it
+func (d *mmdbDecoder) decode(offset uint, depth int) (interface{}, uint, error) {
+	if depth > mmdbMaxDepth {
+		return nil, 0, fmt.Errorf("data nested too deeply")
+	}
+
+	typeNum, size, offset, err := d.controlByte(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if typeNum == mmdbPointer {
+		pointer, next, err := d.pointer(size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(pointer, depth+1)
+		return value, next, err
+	}
+
+	if offset+size > uint(len(d.buffer)) && typeNum != mmdbMap && typeNum != mmdbArray && typeNum != mmdbBool {
+		return nil, 0, fmt.Errorf("value at %d exceeds data section", offset)
+	}
+
+	switch typeNum {
+	case mmdbString:
+		return string(d.buffer[offset : offset+size]), offset + size, nil
+	case mmdbBytes:
+		return append([]byte(nil), d.buffer[offset:offset+size]...), offset + size, nil
+	case mmdbDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("invalid double size %d", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(d.buffer[offset : offset+8])), offset + 8, nil
+	case mmdbFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("invalid float size %d", size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(d.buffer[offset : offset+4]))), offset + 4, nil
+	case mmdbUint16, mmdbUint32, mmdbUint64, mmdbUint128:
+		if size > 16 {
+			return nil, 0, fmt.Errorf("invalid integer size %d", size)
+		}
+		var value uint64
+		for _, b := range d.buffer[offset : offset+size] {
+			value = value<<8 | uint64(b) // uint128 values keep their low 64 bits
+		}
+		return value, offset + size, nil
+	case mmdbInt32:
+		if size > 4 {
+			return nil, 0, fmt.Errorf("invalid int32 size %d", size)
+		}
+		var value uint32
+		for _, b := range d.buffer[offset : offset+size] {
+			value = value<<8 | uint32(b)
+		}
+		return int64(int32(value)), offset + size, nil
+	case mmdbBool:
+		return size != 0, offset, nil
+	case mmdbMap:
+		result := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			keyString, ok := key.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("map key at %d is not a string", offset)
+			}
+			value, next, err := d.decode(next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			result[keyString] = value
+			offset = next
+		}
+		return result, offset, nil
+	case mmdbArray:
+		result := make([]interface{}, 0, min(size, 64))
+		for i := uint(0); i < size; i++ {
+			value, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			result = append(result, value)
+			offset = next
+		}
+		return result, offset, nil
+	}
+
+	return nil, 0, fmt.Errorf("unsupported data type %d at %d", typeNum, offset)
+}
+
+// controlByte reads a control byte with its extended type and size bytes
+func (d *mmdbDecoder) controlByte(offset uint) (int, uint, uint, error) {
+	if offset >= uint(len(d.buffer)) {
+		return 0, 0, 0, fmt.Errorf("offset %d exceeds data section", offset)
+	}
+	control := d.buffer[offset]
+	offset++
+
+	typeNum := int(control >> 5)
+	if typeNum == mmdbExtended {
+		if offset >= uint(len(d.buffer)) {
+			return 0, 0, 0, fmt.Errorf("truncated extended type")
+		}
+		typeNum = 7 + int(d.buffer[offset])
+		offset++
+	}
+
+	size := uint(control & 0x1f)
+	if typeNum == mmdbPointer || size < 29 {
+		return typeNum, size, offset, nil
+	}
+
+	extra := size - 28
+	if offset+extra > uint(len(d.buffer)) {
+		return 0, 0, 0, fmt.Errorf("truncated size")
+	}
+	var value uint
+	for _, b := range d.buffer[offset : offset+extra] {
+		value = value<<8 | uint(b)
+	}
+	switch extra {
+	case 1:
+		size = 29 + value
+	case 2:
+		size = 285 + value
+	default:
+		size = 65821 + value
+	}
+	return typeNum, size, offset + extra, nil
+}
+
+// pointer resolves a pointer whose control byte carried size, returning the target and next offset
+func (d *mmdbDecoder) pointer(size, offset uint) (uint, uint, error) {
+	pointerSize := (size >> 3 & 0x3) + 1
+	if offset+pointerSize > uint(len(d.buffer)) {
+		return 0, 0, fmt.Errorf("truncated pointer")
+	}
+
+	var prefix uint
+	if pointerSize != 4 {
+		prefix = size & 0x7
+	}
+	value := prefix
+	for _, b := range d.buffer[offset : offset+pointerSize] {
+		value = value<<8 | uint(b)
+	}
+
+	var bias uint
+	switch pointerSize {
+	case 2:
+		bias = 2048
+	case 3:
+		bias = 526336
+	}
+	return value + bias, offset + pointerSize, nil
+}
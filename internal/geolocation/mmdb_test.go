@@ -0,0 +1,250 @@
+package geolocation
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// testMMDBEntry maps a network to a data record; a pointerTo of n >= 0 stores a pointer to entry n
+type testMMDBEntry struct {
+	network   string
+	record    map[string]interface{}
+	pointerTo int
+}
+
+// buildTestMMDB writes a minimal IPv6 MaxMind DB with the given record size
+func buildTestMMDB(t *testing.T, recordSize int, entries []testMMDBEntry) []byte {
+	t.Helper()
+
+	// Data section
+	var data []byte
+	offsets := make([]int, len(entries))
+	for i, entry := range entries {
+		offsets[i] = len(data)
+		if entry.pointerTo >= 0 {
+			target := offsets[entry.pointerTo]
+			data = append(data, byte(mmdbPointer<<5|target>>8), byte(target))
+			continue
+		}
+		data = append(data, encodeTestMMDB(entry.record)...)
+	}
+
+	// Search tree; records are -1 (empty), a node index, or -(2+entry) for data
+	nodes := [][2]int{{-1, -1}}
+	for i, entry := range entries {
+		prefix := netip.MustParsePrefix(entry.network)
+		key, prefixBits := prefix.Addr().As16(), prefix.Bits()
+		if prefix.Addr().Is4() {
+			key = [16]byte{}
+			copy(key[12:], prefix.Addr().AsSlice())
+			prefixBits += 96
+		}
+
+		node := 0
+		for depth := 0; depth < prefixBits; depth++ {
+			bit := int(key[depth/8]>>(7-depth%8)) & 1
+			if depth == prefixBits-1 {
+				nodes[node][bit] = -(2 + i)
+				break
+			}
+			next := nodes[node][bit]
+			if next < 0 {
+				nodes = append(nodes, [2]int{next, next})
+				next = len(nodes) - 1
+				nodes[node][bit] = next
+			}
+			node = next
+		}
+	}
+
+	nodeCount := len(nodes)
+	resolve := func(record int) uint32 {
+		switch {
+		case record == -1:
+			return uint32(nodeCount)
+		case record < -1:
+			return uint32(nodeCount + mmdbDataSectionSeparator + offsets[-record-2])
+		}
+		return uint32(record)
+	}
+
+	var tree []byte
+	for _, node := range nodes {
+		left, right := resolve(node[0]), resolve(node[1])
+		switch recordSize {
+		case 24:
+			tree = append(tree, byte(left>>16), byte(left>>8), byte(left), byte(right>>16), byte(right>>8), byte(right))
+		case 28:
+			tree = append(tree, byte(left>>16), byte(left>>8), byte(left), byte(left>>24)<<4|byte(right>>24&0x0F), byte(right>>16), byte(right>>8), byte(right))
+		default:
+			tree = binary.BigEndian.AppendUint32(tree, left)
+			tree = binary.BigEndian.AppendUint32(tree, right)
+		}
+	}
+
+	buffer := append(tree, make([]byte, mmdbDataSectionSeparator)...)
+	buffer = append(buffer, data...)
+	buffer = append(buffer, mmdbMetadataMarker...)
+	buffer = append(buffer, encodeTestMMDB(map[string]interface{}{
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(recordSize),
+		"ip_version":                  uint16(6),
+		"database_type":               "Test-City",
+		"binary_format_major_version": uint16(2),
+		"languages":                   []interface{}{"en"},
+	})...)
+	return buffer
+}
+
+// encodeTestMMDB encodes a value in the MaxMind DB data format
+func encodeTestMMDB(value interface{}) []byte {
+	control := func(typeNum, size int) []byte {
+		if typeNum <= 7 {
+			return []byte{byte(typeNum<<5 | size)}
+		}
+		return []byte{byte(size), byte(typeNum - 7)}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return append(control(mmdbString, len(v)), v...)
+	case float64:
+		return binary.BigEndian.AppendUint64(control(mmdbDouble, 8), math.Float64bits(v))
+	case uint16:
+		return binary.BigEndian.AppendUint16(control(mmdbUint16, 2), v)
+	case uint32:
+		return binary.BigEndian.AppendUint32(control(mmdbUint32, 4), v)
+	case bool:
+		if v {
+			return control(mmdbBool, 1)
+		}
+		return control(mmdbBool, 0)
+	case []interface{}:
+		encoded := control(mmdbArray, len(v))
+		for _, item := range v {
+			encoded = append(encoded, encodeTestMMDB(item)...)
+		}
+		return encoded
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		encoded := control(mmdbMap, len(v))
+		for _, key := range keys {
+			encoded = append(encoded, encodeTestMMDB(key)...)
+			encoded = append(encoded, encodeTestMMDB(v[key])...)
+		}
+		return encoded
+	}
+	panic("unsupported test value")
+}
+
+// testCityRecord builds a GeoIP2 City style record
+func testCityRecord(countryCode, country, subdivisionCode, city string, latitude, longitude float64) map[string]interface{} {
+	return map[string]interface{}{
+		"country": map[string]interface{}{
+			"iso_code": countryCode,
+			"names":    map[string]interface{}{"en": country},
+		},
+		"subdivisions": []interface{}{map[string]interface{}{
+			"iso_code": subdivisionCode,
+			"names":    map[string]interface{}{"en": subdivisionCode + " region"},
+		}},
+		"city": map[string]interface{}{
+			"names": map[string]interface{}{"en": city},
+		},
+		"location": map[string]interface{}{
+			"latitude":  latitude,
+			"longitude": longitude,
+			"time_zone": "Europe/Stockholm",
+		},
+		"is_in_european_union": true,
+	}
+}
+
+func TestMMDBReader(t *testing.T) {
+	entries := []testMMDBEntry{
+		{network: "89.160.20.0/24", record: testCityRecord("SE", "Sweden", "E", "Linköping", 58.4167, 15.6167), pointerTo: -1},
+		{network: "89.160.20.128/25", record: testCityRecord("SE", "Sweden", "AB", "Stockholm", 59.33, 18.06), pointerTo: -1},
+		{network: "2001:218::/32", record: testCityRecord("JP", "Japan", "13", "Tokyo", 35.69, 139.69), pointerTo: -1},
+		{network: "2a02:cf40::/29", pointerTo: 1},
+	}
+
+	for _, recordSize := range []int{24, 28, 32} {
+		t.Run("record size "+strconv.Itoa(recordSize), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.mmdb")
+			if err := os.WriteFile(path, buildTestMMDB(t, recordSize, entries), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			db, err := OpenIPDatabases(path)
+			if err != nil {
+				t.Fatalf("OpenIPDatabases failed: %v", err)
+			}
+			reader, ok := db.(*MMDBReader)
+			if !ok {
+				t.Fatalf("expected *MMDBReader, got %T", db)
+			}
+			if reader.DatabaseType() != "Test-City" {
+				t.Errorf("unexpected database type %q", reader.DatabaseType())
+			}
+
+			tests := []struct {
+				ip           string
+				expectedCity string
+			}{
+				{"89.160.20.1", "Linköping"},
+				{"89.160.20.200", "Stockholm"},
+				{"::ffff:89.160.20.200", "Stockholm"},
+				{"2001:218:1::1", "Tokyo"},
+				{"2a02:cf47::1", "Stockholm"},
+				{"89.160.21.1", ""},
+				{"2001:219::1", ""},
+			}
+			for _, tt := range tests {
+				for i := 0; i < 2; i++ { // second lookup is served from the cache
+					location, found := reader.Lookup(netip.MustParseAddr(tt.ip))
+					if tt.expectedCity == "" {
+						if found {
+							t.Errorf("Lookup(%s): expected no match, got %+v", tt.ip, location)
+						}
+						continue
+					}
+					if !found || location.City != tt.expectedCity {
+						t.Errorf("Lookup(%s) = %q (found %v), want %q", tt.ip, location.City, found, tt.expectedCity)
+					}
+				}
+			}
+
+			location, _ := reader.Lookup(netip.MustParseAddr("89.160.20.1"))
+			expected := Location{CountryCode: "SE", Country: "Sweden", Region: "E region", RegionCode: "E", City: "Linköping", Latitude: 58.4167, Longitude: 15.6167, Timezone: "Europe/Stockholm"}
+			if location != expected {
+				t.Errorf("unexpected location %+v", location)
+			}
+		})
+	}
+}
+
+func TestMMDBReaderInvalid(t *testing.T) {
+	inputs := map[string][]byte{
+		"no metadata":         []byte("not a database"),
+		"bad record size":     append(append([]byte{}, mmdbMetadataMarker...), encodeTestMMDB(map[string]interface{}{"node_count": uint32(0), "record_size": uint16(20)})...),
+		"tree exceeds buffer": append(append([]byte{}, mmdbMetadataMarker...), encodeTestMMDB(map[string]interface{}{"node_count": uint32(100), "record_size": uint16(24)})...),
+	}
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewMMDBReader(input); !errors.Is(err, ErrInvalidIPDatabase) {
+				t.Errorf("expected ErrInvalidIPDatabase, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package geolocation
+
+import (
+	"math/bits"
+	"net/netip"
+)
+
+// uint128 is an IPv6 address (IPv4 addresses are IPv4-mapped) as a 128-bit integer
+type uint128 struct {
+	hi, lo uint64
+}
+
+// addrToUint128 converts an address, mapping IPv4 into ::ffff:0:0/96
+func addrToUint128(addr netip.Addr) uint128 {
+	b := addr.As16()
+	var u uint128
+	for i := 0; i < 8; i++ {
+		u.hi = u.hi<<8 | uint64(b[i])
+		u.lo = u.lo<<8 | uint64(b[i+8])
+	}
+	return u
+}
+
+// uint128ToAddr converts back to an address, unmapping IPv4
+func uint128ToAddr(u uint128) netip.Addr {
+	var b [16]byte
+	for i := 7; i >= 0; i-- {
+		b[i] = byte(u.hi)
+		b[i+8] = byte(u.lo)
+		u.hi >>= 8
+		u.lo >>= 8
+	}
+	return netip.AddrFrom16(b).Unmap()
+}
+
+// bit returns bit i (0 is the most significant)
+func (u uint128) bit(i int) int {
+	if i < 64 {
+		return int(u.hi >> (63 - i) & 1)
+	}
+	return int(u.lo >> (127 - i) & 1)
+}
+
+// mask clears all but the first n bits
+func (u uint128) mask(n int) uint128 {
+	switch {
+	case n <= 0:
+		return uint128{}
+	case n < 64:
+		return uint128{hi: u.hi &^ (^uint64(0) >> n)}
+	case n < 128:
+		return uint128{hi: u.hi, lo: u.lo &^ (^uint64(0) >> (n - 64))}
+	}
+	return u
+}
+
+// commonBits returns the length of the common prefix of u and v, at most n
+func (u uint128) commonBits(v uint128, n int) int {
+	common := bits.LeadingZeros64(u.hi ^ v.hi)
+	if common == 64 {
+		common += bits.LeadingZeros64(u.lo ^ v.lo)
+	}
+	return min(common, n)
+}
+
+// add returns u + n
+func (u uint128) add(n uint64) uint128 {
+	lo, carry := bits.Add64(u.lo, n, 0)
+	return uint128{hi: u.hi + carry, lo: lo}
+}
+
+// trailingZeros returns the number of trailing zero bits
+func (u uint128) trailingZeros() int {
+	if u.lo != 0 {
+		return bits.TrailingZeros64(u.lo)
+	}
+	return 64 + bits.TrailingZeros64(u.hi)
+}
+
+// less reports whether u < v
+func (u uint128) less(v uint128) bool {
+	return u.hi < v.hi || (u.hi == v.hi && u.lo < v.lo)
+}
+
+// radixNode is a path-compressed node covering key/bits
+type radixNode struct {
+	key      uint128
+	bits     uint8
+	value    int32    // Index of the stored value, -1 for internal nodes
+	children [2]int32 // Node indexes, 0 for none (the root is never a child)
+}
+
+// radixTree is a path-compressed binary trie for longest-prefix match over IPv4 and IPv6.
+// Nodes live in one slice so large tables stay compact and GC-friendly.
+type radixTree struct {
+	nodes []radixNode
+}
+
+// newRadixTree creates a tree with an empty root covering ::/0
+func newRadixTree() *radixTree {
+	return &radixTree{nodes: []radixNode{{value: -1}}}
+}
+
+// insert stores value for a prefix, replacing any value stored for the same prefix
+func (t *radixTree) insert(prefix netip.Prefix, value int32) {
+	prefixBits := prefix.Bits()
+	if prefix.Addr().Is4() {
+		prefixBits += 96
+	}
+	key := addrToUint128(prefix.Addr()).mask(prefixBits)
+
+	n := 0
+	for {
+		node := &t.nodes[n]
+		if int(node.bits) == prefixBits {
+			node.value = value
+			return
+		}
+
+		branch := key.bit(int(node.bits))
+		child := node.children[branch]
+		if child == 0 {
+			t.nodes = append(t.nodes, radixNode{key: key, bits: uint8(prefixBits), value: value})
+			t.nodes[n].children[branch] = int32(len(t.nodes) - 1)
+			return
+		}
+
+		childNode := t.nodes[child]
+		common := key.commonBits(childNode.key, min(int(childNode.bits), prefixBits))
+		if common == int(childNode.bits) {
+			n = int(child)
+			continue
+		}
+
+		// Split the edge to the child at the common prefix
+		split := radixNode{key: key.mask(common), bits: uint8(common), value: -1}
+		split.children[childNode.key.bit(common)] = child
+		t.nodes = append(t.nodes, split)
+		splitIndex := int32(len(t.nodes) - 1)
+		t.nodes[n].children[branch] = splitIndex
+
+		if common == prefixBits {
+			t.nodes[splitIndex].value = value
+		} else {
+			t.nodes = append(t.nodes, radixNode{key: key, bits: uint8(prefixBits), value: value})
+			t.nodes[splitIndex].children[key.bit(common)] = int32(len(t.nodes) - 1)
+		}
+		return
+	}
+}
+
+// lookup returns the value of the longest prefix containing addr
+func (t *radixTree) lookup(addr netip.Addr) (int32, bool) {
+	key := addrToUint128(addr)
+	best := int32(-1)
+
+	n := int32(0)
+	for {
+		node := &t.nodes[n]
+		if node.bits > 0 && key.commonBits(node.key, int(node.bits)) < int(node.bits) {
+			break
+		}
+		if node.value >= 0 {
+			best = node.value
+		}
+		if node.bits == 128 {
+			break
+		}
+		if n = node.children[key.bit(int(node.bits))]; n == 0 {
+			break
+		}
+	}
+
+	return best, best >= 0
+}
+
+// rangeToPrefixes splits the inclusive range [start, end] into the minimal list of prefixes
+func rangeToPrefixes(start, end netip.Addr) []netip.Prefix {
+	is4 := start.Is4()
+	from, to := addrToUint128(start), addrToUint128(end)
+
+	var prefixes []netip.Prefix
+	for !to.less(from) {
+		// Largest aligned block starting at from that does not pass to
+		size := min(from.trailingZeros(), 128)
+		for size > 0 && to.less(blockEnd(from, size)) {
+			size--
+		}
+
+		prefixBits := 128 - size
+		if is4 {
+			prefixBits -= 96
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(uint128ToAddr(from), prefixBits))
+
+		next := blockEnd(from, size).add(1)
+		if next == (uint128{}) {
+			break // wrapped past the end of the address space
+		}
+		from = next
+	}
+	return prefixes
+}
+
+// blockEnd returns the last address of the block of 2^size addresses starting at from
+func blockEnd(from uint128, size int) uint128 {
+	switch {
+	case size == 0:
+		return from
+	case size < 64:
+		return uint128{hi: from.hi, lo: from.lo | (uint64(1)<<size - 1)}
+	case size < 128:
+		return uint128{hi: from.hi | (uint64(1)<<(size-64) - 1), lo: ^uint64(0)}
+	}
+	return uint128{hi: ^uint64(0), lo: ^uint64(0)}
+}
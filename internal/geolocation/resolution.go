@@ -0,0 +1,202 @@
+package geolocation
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/perschulte/greenweb-api/internal/clientip"
+)
+
+// DefaultOverrideName is the query parameter and cookie that override the detected location
+const DefaultOverrideName = "greenweb_location"
+
+// zoneCodePattern matches country and grid zone codes such as "SE" or "US-CAL-CISO"
+var zoneCodePattern = regexp.MustCompile(`^[A-Za-z]{2}(-[A-Za-z0-9]+)*$`)
+
+// CDNHeaders names the visitor geo headers a CDN adds to requests
+type CDNHeaders struct {
+	Provider    string
+	CountryCode string
+	Country     string
+	Region      string
+	RegionCode  string
+	City        string
+	Latitude    string
+	Longitude   string
+	Timezone    string
+}
+
+// DefaultCDNHeaders covers Cloudflare (CF-IPCountry, plus the other fields with the "Add
+// visitor location headers" managed transform), CloudFront viewer headers and Fastly
+// geo headers set from client.geo in VCL
+var DefaultCDNHeaders = []CDNHeaders{
+	{
+		Provider:    "cloudflare",
+		CountryCode: "CF-IPCountry",
+		Region:      "CF-Region",
+		RegionCode:  "CF-Region-Code",
+		City:        "CF-IPCity",
+		Latitude:    "CF-IPLatitude",
+		Longitude:   "CF-IPLongitude",
+		Timezone:    "CF-Timezone",
+	},
+	{
+		Provider:    "cloudfront",
+		CountryCode: "CloudFront-Viewer-Country",
+		Country:     "CloudFront-Viewer-Country-Name",
+		Region:      "CloudFront-Viewer-Country-Region-Name",
+		RegionCode:  "CloudFront-Viewer-Country-Region",
+		City:        "CloudFront-Viewer-City",
+		Latitude:    "CloudFront-Viewer-Latitude",
+		Longitude:   "CloudFront-Viewer-Longitude",
+		Timezone:    "CloudFront-Viewer-Time-Zone",
+	},
+	{
+		Provider:    "fastly",
+		CountryCode: "Fastly-Geo-Country-Code",
+		Country:     "Fastly-Geo-Country-Name",
+		RegionCode:  "Fastly-Geo-Region",
+		City:        "Fastly-Geo-City",
+		Latitude:    "Fastly-Geo-Latitude",
+		Longitude:   "Fastly-Geo-Longitude",
+	},
+}
+
+// unknownCountryCodes are placeholder codes CDNs send when the country is unknown
+var unknownCountryCodes = map[string]bool{"XX": true, "T1": true, "A1": true, "A2": true, "O1": true, "EU": true, "AP": true}
+
+// GetLocationFromRequest resolves a request's location through the resolution chain: an
+// explicit override, geo headers from a trusted CDN, the client IP lookup (local database,
+// then HTTP API) and finally the default. LocationWithZone.Source records the step used.
+func (s *Service) GetLocationFromRequest(ctx context.Context, r *http.Request) (LocationWithZone, error) {
+	ip := s.clientIP().ClientIP(r)
+
+	if s.overrideName != "" {
+		if result, found := s.locationFromOverride(r); found {
+			result.Location.IP = ip
+			return result, nil
+		}
+	}
+
+	if len(s.cdnHeaders) > 0 && s.clientIP().TrustedPeer(r) {
+		if location, found := locationFromCDNHeaders(r, s.cdnHeaders); found {
+			location.IP = ip
+			return LocationWithZone{
+				Location: location,
+				GridZone: s.gridMapper.MapToGridZone(location),
+				Source:   LocationSourceCDNHeader,
+			}, nil
+		}
+	}
+
+	return s.GetLocationByIP(ctx, ip)
+}
+
+// clientIP returns the resolver used to find client addresses and trusted proxies
+func (s *Service) clientIP() *clientip.Resolver {
+	if s.clientIPResolver != nil {
+		return s.clientIPResolver
+	}
+	return clientip.Default()
+}
+
+// locationFromOverride reads a location override from the query string, then the cookie.
+// Overrides are zone codes ("SE", "US-CAL-CISO") or "latitude,longitude" coordinates;
+// coordinates need zone boundaries to resolve.
+func (s *Service) locationFromOverride(r *http.Request) (LocationWithZone, bool) {
+	value := r.URL.Query().Get(s.overrideName)
+	if value == "" {
+		if cookie, err := r.Cookie(s.overrideName); err == nil {
+			value, _ = url.QueryUnescape(cookie.Value)
+		}
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return LocationWithZone{}, false
+	}
+
+	if latitude, longitude, ok := parseCoordinates(value); ok {
+		location := Location{Latitude: latitude, Longitude: longitude}
+		zone, found := s.gridMapper.mapToSubnationalZone(location)
+		if !found {
+			return LocationWithZone{}, false
+		}
+		location.CountryCode = strings.SplitN(zone.Zone, "-", 2)[0]
+		location.Country = zone.Country
+		return LocationWithZone{Location: location, GridZone: zone, Source: LocationSourceOverride}, true
+	}
+
+	if !zoneCodePattern.MatchString(value) {
+		return LocationWithZone{}, false
+	}
+	zone, found := s.gridMapper.zoneForCode(value)
+	if !found {
+		return LocationWithZone{}, false
+	}
+	location := Location{
+		CountryCode: strings.SplitN(strings.ToUpper(value), "-", 2)[0],
+		Country:     zone.Country,
+	}
+	return LocationWithZone{Location: location, GridZone: zone, Source: LocationSourceOverride}, true
+}
+
+// parseCoordinates parses "latitude,longitude"
+func parseCoordinates(value string) (float64, float64, bool) {
+	latitudeText, longitudeText, found := strings.Cut(value, ",")
+	if !found {
+		return 0, 0, false
+	}
+	latitude, err := strconv.ParseFloat(strings.TrimSpace(latitudeText), 64)
+	if err != nil || latitude < -90 || latitude > 90 {
+		return 0, 0, false
+	}
+	longitude, err := strconv.ParseFloat(strings.TrimSpace(longitudeText), 64)
+	if err != nil || longitude < -180 || longitude > 180 {
+		return 0, 0, false
+	}
+	return latitude, longitude, true
+}
+
+// locationFromCDNHeaders builds a location from the first CDN whose country header is set
+func locationFromCDNHeaders(r *http.Request, sets []CDNHeaders) (Location, bool) {
+	for _, headers := range sets {
+		countryCode := strings.ToUpper(strings.TrimSpace(r.Header.Get(headers.CountryCode)))
+		if len(countryCode) != 2 || unknownCountryCodes[countryCode] {
+			continue
+		}
+
+		header := func(name string) string {
+			if name == "" {
+				return ""
+			}
+			value := strings.TrimSpace(r.Header.Get(name))
+			if unescaped, err := url.QueryUnescape(value); err == nil {
+				value = unescaped // CloudFront URL-encodes non-ASCII city names
+			}
+			return value
+		}
+
+		location := Location{
+			CountryCode: countryCode,
+			Country:     header(headers.Country),
+			Region:      header(headers.Region),
+			RegionCode:  header(headers.RegionCode),
+			City:        header(headers.City),
+			Timezone:    header(headers.Timezone),
+		}
+		if location.Country == "" {
+			location.Country = countryCode
+		}
+		latitude, latErr := strconv.ParseFloat(header(headers.Latitude), 64)
+		longitude, lonErr := strconv.ParseFloat(header(headers.Longitude), 64)
+		if latErr == nil && lonErr == nil && latitude >= -90 && latitude <= 90 && longitude >= -180 && longitude <= 180 {
+			location.Latitude, location.Longitude = latitude, longitude
+		}
+		return location, true
+	}
+	return Location{}, false
+}
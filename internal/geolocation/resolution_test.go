@@ -0,0 +1,162 @@
+package geolocation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/perschulte/greenweb-api/internal/clientip"
+)
+
+func TestGetLocationFromRequestChain(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "blocks.csv")
+	if err := os.WriteFile(path, []byte("network,country_code,country_name,city,latitude,longitude\n8.8.8.0/24,US,United States,Mountain View,37.4,-122.1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := OpenIPDatabases(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	boundaries := NewZoneBoundaries()
+	if _, err := boundaries.LoadGeoJSON(strings.NewReader(testZoneBoundaries)); err != nil {
+		t.Fatal(err)
+	}
+	cloudflare, err := clientip.New(clientip.Config{Presets: []string{clientip.PresetCloudflare}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := NewService(ServiceConfig{
+		APIBaseURL:     "http://127.0.0.1:1",
+		IPDatabase:     db,
+		ZoneBoundaries: boundaries,
+		ClientIP:       cloudflare,
+	})
+
+	tests := []struct {
+		name           string
+		target         string
+		remoteAddr     string
+		headers        map[string]string
+		cookie         string
+		expectedSource string
+		expectedZone   string
+		expectedIP     string
+	}{
+		{
+			name:           "query override with zone code",
+			target:         "/?greenweb_location=us-cal-ciso",
+			remoteAddr:     "8.8.8.8:1234",
+			expectedSource: LocationSourceOverride,
+			expectedZone:   "US-CAL-CISO",
+			expectedIP:     "8.8.8.8",
+		},
+		{
+			name:           "cookie override with coordinates",
+			target:         "/",
+			remoteAddr:     "8.8.8.8:1234",
+			cookie:         "-33.87%2C151.21",
+			expectedSource: LocationSourceOverride,
+			expectedZone:   "AU-NSW",
+			expectedIP:     "8.8.8.8",
+		},
+		{
+			name:           "unknown override falls through",
+			target:         "/?greenweb_location=ZZ",
+			remoteAddr:     "8.8.8.8:1234",
+			expectedSource: LocationSourceIPDatabase,
+			expectedZone:   "US-CAL-CISO",
+			expectedIP:     "8.8.8.8",
+		},
+		{
+			name:           "unknown sub-national zone falls through",
+			target:         "/?greenweb_location=US-FOO",
+			remoteAddr:     "8.8.8.8:1234",
+			expectedSource: LocationSourceIPDatabase,
+			expectedZone:   "US-CAL-CISO",
+			expectedIP:     "8.8.8.8",
+		},
+		{
+			name:           "cloudflare headers from a trusted peer",
+			target:         "/",
+			remoteAddr:     "172.64.1.1:443",
+			headers:        map[string]string{"CF-Connecting-IP": "8.8.8.8", "CF-IPCountry": "SE", "CF-IPCity": "Stockholm"},
+			expectedSource: LocationSourceCDNHeader,
+			expectedZone:   "SE",
+			expectedIP:     "8.8.8.8",
+		},
+		{
+			name:           "cloudfront headers with coordinates",
+			target:         "/",
+			remoteAddr:     "172.64.1.1:443",
+			headers:        map[string]string{"CF-IPCountry": "XX", "CloudFront-Viewer-Country": "US", "CloudFront-Viewer-Latitude": "40.71", "CloudFront-Viewer-Longitude": "-74.00"},
+			expectedSource: LocationSourceCDNHeader,
+			expectedZone:   "US",
+			expectedIP:     "172.64.1.1",
+		},
+		{
+			name:           "spoofed CDN headers from a client are ignored",
+			target:         "/",
+			remoteAddr:     "8.8.8.8:1234",
+			headers:        map[string]string{"CF-IPCountry": "SE"},
+			expectedSource: LocationSourceIPDatabase,
+			expectedZone:   "US-CAL-CISO",
+			expectedIP:     "8.8.8.8",
+		},
+		{
+			name:           "address outside the database uses the default",
+			target:         "/",
+			remoteAddr:     "9.9.9.9:1234",
+			expectedSource: LocationSourceDefault,
+			expectedZone:   "DE",
+			expectedIP:     "0.0.0.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.target, nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: DefaultOverrideName, Value: tt.cookie})
+			}
+
+			result, err := service.GetLocationFromRequest(context.Background(), req)
+			if err != nil {
+				t.Fatalf("GetLocationFromRequest failed: %v", err)
+			}
+			if result.Source != tt.expectedSource || result.GridZone.Zone != tt.expectedZone || result.Location.IP != tt.expectedIP {
+				t.Errorf("got source %q, zone %q, IP %q; want %q, %q, %q",
+					result.Source, result.GridZone.Zone, result.Location.IP, tt.expectedSource, tt.expectedZone, tt.expectedIP)
+			}
+		})
+	}
+}
+
+func TestGetLocationFromRequestDisabledSteps(t *testing.T) {
+	service := NewService(ServiceConfig{
+		IPDatabase:        NewRangeDatabase(),
+		DisableOverride:   true,
+		DisableCDNHeaders: true,
+	})
+
+	req := httptest.NewRequest("GET", "/?greenweb_location=SE", nil)
+	req.RemoteAddr = "10.0.0.2:1234"
+	req.Header.Set("CF-IPCountry", "SE")
+
+	result, err := service.GetLocationFromRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Source != LocationSourceDefault {
+		t.Errorf("expected default source with overrides and CDN headers disabled, got %q", result.Source)
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/netip"
 	"time"
+
+	"github.com/perschulte/greenweb-api/internal/clientip"
 )
 
 // Service provides IP-based geolocation functionality
@@ -15,13 +18,32 @@ type Service struct {
 	gridMapper  *GridZoneMapper
 	apiBaseURL  string
 	rateLimiter chan struct{}
+	ipDatabase  IPDatabase
+	apiEnabled  bool
+	cache       *lookupCache
+	flight      flightGroup
+
+	clientIPResolver *clientip.Resolver
+	overrideName     string
+	cdnHeaders       []CDNHeaders
 }
 
 // ServiceConfig holds configuration for the geolocation service
 type ServiceConfig struct {
-	APIBaseURL    string
-	Timeout       time.Duration
-	RateLimitRPS  int // Requests per second
+	APIBaseURL     string
+	Timeout        time.Duration
+	RateLimitRPS   int             // Requests per second
+	IPDatabase     IPDatabase      // Optional local database; lookups no longer hit the HTTP API
+	APIFallback    bool            // With an IPDatabase, query the HTTP API for addresses the database doesn't cover
+	Cache          CacheConfig     // Lookup cache, enabled by default
+	ZoneBoundaries *ZoneBoundaries // Optional polygons resolving coordinates to sub-national grid zones
+
+	// Location resolution chain for requests
+	ClientIP          *clientip.Resolver // Client address and trusted proxies; nil uses clientip.Default()
+	OverrideName      string             // Query parameter and cookie overriding the location (default greenweb_location)
+	DisableOverride   bool               // Ignore location overrides
+	CDNHeaders        []CDNHeaders       // CDN geo headers honored from trusted proxies; nil uses DefaultCDNHeaders
+	DisableCDNHeaders bool               // Ignore CDN geo headers
 }
 
 // NewService creates a new geolocation service
@@ -36,6 +58,36 @@ func NewService(config ServiceConfig) *Service {
 		config.RateLimitRPS = 2 // Conservative rate limit for free tier
 	}
 
+	service := &Service{
+		client: &http.Client{
+			Timeout: config.Timeout,
+		},
+		gridMapper: NewGridZoneMapper(),
+		apiBaseURL: config.APIBaseURL,
+		ipDatabase: config.IPDatabase,
+		apiEnabled: config.IPDatabase == nil || config.APIFallback,
+		cache:      newLookupCache(config.Cache),
+	}
+	if config.ZoneBoundaries != nil {
+		service.gridMapper.SetZoneBoundaries(config.ZoneBoundaries)
+	}
+	service.clientIPResolver = config.ClientIP
+	if !config.DisableOverride {
+		service.overrideName = config.OverrideName
+		if service.overrideName == "" {
+			service.overrideName = DefaultOverrideName
+		}
+	}
+	if !config.DisableCDNHeaders {
+		service.cdnHeaders = config.CDNHeaders
+		if service.cdnHeaders == nil {
+			service.cdnHeaders = DefaultCDNHeaders
+		}
+	}
+	if !service.apiEnabled {
+		return service
+	}
+
 	// Create rate limiter channel
 	rateLimiter := make(chan struct{}, config.RateLimitRPS)
 	go func() {
@@ -52,14 +104,8 @@ func NewService(config ServiceConfig) *Service {
 		}
 	}()
 
-	return &Service{
-		client: &http.Client{
-			Timeout: config.Timeout,
-		},
-		gridMapper:  NewGridZoneMapper(),
-		apiBaseURL:  config.APIBaseURL,
-		rateLimiter: rateLimiter,
-	}
+	service.rateLimiter = rateLimiter
+	return service
 }
 
 // GetLocationByIP retrieves location information for the given IP address
@@ -67,10 +113,78 @@ func (s *Service) GetLocationByIP(ctx context.Context, ip string) (LocationWithZ
 	// Check for private/local IPs
 	if IsPrivateOrLocalIP(ip) {
 		log.Printf("Private or local IP detected (%s), using default location", ip)
-		return LocationWithZone{
-			Location: DefaultLocation,
-			GridZone: DefaultGridZone,
-		}, nil
+		return s.getDefaultLocationWithZone(), nil
+	}
+
+	if s.cache == nil {
+		result, _, err := s.resolve(ctx, ip)
+		return result, err
+	}
+	key, ok := s.cache.key(ip)
+	if !ok {
+		result, _, err := s.resolve(ctx, ip)
+		return result, err
+	}
+
+	if cached, found := s.cache.get(key); found {
+		s.cache.hits.Add(1)
+		cached.Location.IP = ip
+		return cached, nil
+	}
+
+	// Concurrent lookups of the same network wait for a single resolution
+	result, coalesced, err := s.flight.do(ctx, key, func(ctx context.Context) (LocationWithZone, error) {
+		if shared := s.cache.config.Shared; shared != nil {
+			var cached LocationWithZone
+			if err := shared.Get(ctx, sharedCacheKeyPrefix+key, &cached); err == nil && cached.Location.CountryCode != "" {
+				s.cache.sharedHits.Add(1)
+				s.cache.set(key, cached)
+				return cached, nil
+			}
+		}
+
+		s.cache.misses.Add(1)
+		result, resolved, err := s.resolve(ctx, ip)
+		if resolved {
+			s.cache.set(key, result)
+			if shared := s.cache.config.Shared; shared != nil {
+				if err := shared.Set(ctx, sharedCacheKeyPrefix+key, result, s.cache.config.TTL); err != nil {
+					log.Printf("Failed to share geolocation for %s: %v", key, err)
+				}
+			}
+		}
+		return result, err
+	})
+	if coalesced {
+		s.cache.coalesced.Add(1)
+	}
+	if err != nil && ctx.Err() != nil {
+		return s.getDefaultLocationWithZone(), err
+	}
+	if result.Location != DefaultLocation {
+		result.Location.IP = ip
+	}
+	return result, err
+}
+
+// resolve looks up an IP in the local database, then the HTTP API. The second result is
+// false when the default location was returned instead.
+func (s *Service) resolve(ctx context.Context, ip string) (LocationWithZone, bool, error) {
+	// Local database lookups take microseconds and need no rate limiting
+	if s.ipDatabase != nil {
+		if addr, err := netip.ParseAddr(ip); err == nil {
+			if location, found := s.ipDatabase.Lookup(addr); found {
+				location.IP = ip
+				return LocationWithZone{
+					Location: location,
+					GridZone: s.gridMapper.MapToGridZone(location),
+					Source:   LocationSourceIPDatabase,
+				}, true, nil
+			}
+		}
+		if !s.apiEnabled {
+			return s.getDefaultLocationWithZone(), false, nil
+		}
 	}
 
 	// Rate limiting
@@ -78,17 +192,17 @@ func (s *Service) GetLocationByIP(ctx context.Context, ip string) (LocationWithZ
 	case <-s.rateLimiter:
 		// Proceed with request
 	case <-ctx.Done():
-		return s.getDefaultLocationWithZone(), ctx.Err()
+		return s.getDefaultLocationWithZone(), false, ctx.Err()
 	case <-time.After(2 * time.Second):
 		log.Printf("Rate limit timeout for IP %s, using default location", ip)
-		return s.getDefaultLocationWithZone(), nil
+		return s.getDefaultLocationWithZone(), false, nil
 	}
 
 	// Make API request
 	location, err := s.fetchLocationFromAPI(ctx, ip)
 	if err != nil {
 		log.Printf("Failed to fetch location for IP %s: %v, using default", ip, err)
-		return s.getDefaultLocationWithZone(), nil
+		return s.getDefaultLocationWithZone(), false, nil
 	}
 
 	// Map to grid zone
@@ -97,13 +211,16 @@ func (s *Service) GetLocationByIP(ctx context.Context, ip string) (LocationWithZ
 	return LocationWithZone{
 		Location: location,
 		GridZone: gridZone,
-	}, nil
+		Source:   LocationSourceIPAPI,
+	}, true, nil
 }
 
-// GetLocationFromRequest extracts IP from HTTP request and returns location
-func (s *Service) GetLocationFromRequest(ctx context.Context, r *http.Request) (LocationWithZone, error) {
-	ip := ExtractClientIP(r)
-	return s.GetLocationByIP(ctx, ip)
+// CacheStats returns lookup cache metrics; the second result is false when caching is disabled
+func (s *Service) CacheStats() (CacheStats, bool) {
+	if s.cache == nil {
+		return CacheStats{}, false
+	}
+	return s.cache.stats(), true
 }
 
 // fetchLocationFromAPI makes the actual API call to ipapi.co
@@ -159,6 +276,7 @@ func (s *Service) getDefaultLocationWithZone() LocationWithZone {
 	return LocationWithZone{
 		Location: DefaultLocation,
 		GridZone: DefaultGridZone,
+		Source:   LocationSourceDefault,
 	}
 }
 
@@ -167,8 +285,13 @@ func (s *Service) GetGridZoneMapper() *GridZoneMapper {
 	return s.gridMapper
 }
 
-// HealthCheck verifies the service is working by testing with a known IP
+// HealthCheck verifies the service is working by testing with a known IP.
+// A service answering from a local database only is always healthy.
 func (s *Service) HealthCheck(ctx context.Context) error {
+	if !s.apiEnabled {
+		return nil
+	}
+
 	// Test with Google's public DNS IP
 	testIP := "8.8.8.8"
 	
@@ -21,16 +21,16 @@ func TestExtractClientIP(t *testing.T) {
 			expectedResult: "127.0.0.1", // Private IP should fall back to cleaned RemoteAddr
 		},
 		{
-			name:           "X-Real-IP public",
+			name:           "X-Real-IP not trusted by default",
 			headers:        map[string]string{"X-Real-IP": "8.8.8.8"},
 			remoteAddr:     "127.0.0.1:8080",
-			expectedResult: "8.8.8.8",
+			expectedResult: "127.0.0.1", // Only X-Forwarded-For is honored without configured headers
 		},
 		{
-			name:           "CF-Connecting-IP public",
+			name:           "CF-Connecting-IP needs the cloudflare preset",
 			headers:        map[string]string{"CF-Connecting-IP": "1.1.1.1"},
 			remoteAddr:     "127.0.0.1:8080",
-			expectedResult: "1.1.1.1",
+			expectedResult: "127.0.0.1",
 		},
 		{
 			name:           "No headers",
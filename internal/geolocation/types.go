@@ -25,8 +25,18 @@ type GridZone struct {
 type LocationWithZone struct {
 	Location Location `json:"location"`
 	GridZone GridZone `json:"grid_zone"`
+	Source   string   `json:"source,omitempty"` // How the location was determined, one of the LocationSource constants
 }
 
+// Location sources, in the order the resolution chain tries them
+const (
+	LocationSourceOverride   = "override"    // Explicit query parameter or cookie
+	LocationSourceCDNHeader  = "cdn_header"  // Geo headers added by a trusted CDN
+	LocationSourceIPDatabase = "ip_database" // Local IP database
+	LocationSourceIPAPI      = "ip_api"      // HTTP geolocation API
+	LocationSourceDefault    = "default"     // Fallback location
+)
+
 // Default locations for fallback
 var (
 	DefaultLocation = Location{
@@ -0,0 +1,268 @@
+package geolocation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidZoneBoundaries is returned for zone boundary files that cannot be parsed
+var ErrInvalidZoneBoundaries = errors.New("invalid zone boundaries")
+
+// zoneIndexCellDegrees is the size of a spatial index cell
+const zoneIndexCellDegrees = 1.0
+
+// zoneProperties lists the GeoJSON feature properties accepted as the zone code
+var zoneProperties = []string{"zone", "zoneName", "zone_name", "zone_key"}
+
+// zonePolygon is one polygon of a zone; the first ring is the outer boundary, the rest are holes
+type zonePolygon struct {
+	zone                           string
+	rings                          [][][2]float64 // [longitude, latitude] points
+	minLon, minLat, maxLon, maxLat float64
+	area                           float64 // In square degrees, used to prefer nested zones
+}
+
+// contains reports whether a point lies inside the polygon, using even-odd ray casting
+func (p *zonePolygon) contains(lon, lat float64) bool {
+	if lon < p.minLon || lon > p.maxLon || lat < p.minLat || lat > p.maxLat {
+		return false
+	}
+
+	inside := false
+	for _, ring := range p.rings {
+		for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+			a, b := ring[i], ring[j]
+			if (a[1] > lat) != (b[1] > lat) && lon < (b[0]-a[0])*(lat-a[1])/(b[1]-a[1])+a[0] {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// ZoneBoundaries resolves coordinates to grid zones by point-in-polygon tests against
+// GeoJSON zone boundaries, using a grid index to test only nearby polygons
+type ZoneBoundaries struct {
+	mu       sync.RWMutex
+	polygons []zonePolygon
+	cells    map[int][]int32 // Cell index to the polygons whose bounding box overlaps it
+	zones    map[string]bool
+}
+
+// NewZoneBoundaries creates empty zone boundaries
+func NewZoneBoundaries() *ZoneBoundaries {
+	return &ZoneBoundaries{
+		cells: make(map[int][]int32),
+		zones: make(map[string]bool),
+	}
+}
+
+// OpenZoneBoundaries loads GeoJSON zone boundary files into one index
+func OpenZoneBoundaries(paths ...string) (*ZoneBoundaries, error) {
+	boundaries := NewZoneBoundaries()
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open zone boundaries: %w", err)
+		}
+		_, err = boundaries.LoadGeoJSON(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return boundaries, nil
+}
+
+// geoJSONFeature is a GeoJSON feature with a Polygon or MultiPolygon geometry
+type geoJSONFeature struct {
+	ID         interface{}            `json:"id"`
+	Properties map[string]interface{} `json:"properties"`
+	Geometry   *struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	} `json:"geometry"`
+}
+
+// LoadGeoJSON adds the zones of a GeoJSON FeatureCollection, such as the Electricity Maps
+// world.geojson. The zone code is taken from the zone, zoneName, zone_name or zone_key
+// property, falling back to the feature id. It returns the number of zones loaded.
+func (b *ZoneBoundaries) LoadGeoJSON(r io.Reader) (int, error) {
+	var collection struct {
+		Type     string           `json:"type"`
+		Features []geoJSONFeature `json:"features"`
+	}
+	if err := json.NewDecoder(r).Decode(&collection); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidZoneBoundaries, err)
+	}
+	if collection.Type != "FeatureCollection" {
+		return 0, fmt.Errorf("%w: expected a FeatureCollection, got %q", ErrInvalidZoneBoundaries, collection.Type)
+	}
+
+	var polygons []zonePolygon
+	loaded := 0
+	for i, feature := range collection.Features {
+		zone := featureZone(feature)
+		if zone == "" {
+			return 0, fmt.Errorf("%w: feature %d has no zone property", ErrInvalidZoneBoundaries, i)
+		}
+		if feature.Geometry == nil {
+			continue
+		}
+
+		var rings [][][][]float64
+		switch feature.Geometry.Type {
+		case "Polygon":
+			var polygon [][][]float64
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &polygon); err != nil {
+				return 0, fmt.Errorf("%w: zone %s: %v", ErrInvalidZoneBoundaries, zone, err)
+			}
+			rings = [][][][]float64{polygon}
+		case "MultiPolygon":
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &rings); err != nil {
+				return 0, fmt.Errorf("%w: zone %s: %v", ErrInvalidZoneBoundaries, zone, err)
+			}
+		default:
+			continue // Points and lines have no area
+		}
+
+		for _, polygon := range rings {
+			parsed, err := newZonePolygon(zone, polygon)
+			if err != nil {
+				return 0, err
+			}
+			polygons = append(polygons, parsed)
+		}
+		loaded++
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, polygon := range polygons {
+		b.add(polygon)
+	}
+	return loaded, nil
+}
+
+// featureZone returns the zone code of a feature
+func featureZone(feature geoJSONFeature) string {
+	for _, property := range zoneProperties {
+		if value, ok := feature.Properties[property].(string); ok && value != "" {
+			return strings.ToUpper(strings.TrimSpace(value))
+		}
+	}
+	if id, ok := feature.ID.(string); ok {
+		return strings.ToUpper(strings.TrimSpace(id))
+	}
+	return ""
+}
+
+// newZonePolygon validates GeoJSON polygon coordinates and computes its bounds and area
+func newZonePolygon(zone string, coordinates [][][]float64) (zonePolygon, error) {
+	polygon := zonePolygon{
+		zone:   zone,
+		minLon: math.Inf(1), minLat: math.Inf(1),
+		maxLon: math.Inf(-1), maxLat: math.Inf(-1),
+	}
+	for ringIndex, ring := range coordinates {
+		if len(ring) < 3 {
+			return zonePolygon{}, fmt.Errorf("%w: zone %s has a ring with fewer than 3 points", ErrInvalidZoneBoundaries, zone)
+		}
+		points := make([][2]float64, len(ring))
+		for i, position := range ring {
+			if len(position) < 2 || math.Abs(position[0]) > 180 || math.Abs(position[1]) > 90 {
+				return zonePolygon{}, fmt.Errorf("%w: zone %s has an invalid position %v", ErrInvalidZoneBoundaries, zone, position)
+			}
+			points[i] = [2]float64{position[0], position[1]}
+			if ringIndex == 0 {
+				polygon.minLon, polygon.maxLon = math.Min(polygon.minLon, position[0]), math.Max(polygon.maxLon, position[0])
+				polygon.minLat, polygon.maxLat = math.Min(polygon.minLat, position[1]), math.Max(polygon.maxLat, position[1])
+			}
+		}
+
+		area := ringArea(points)
+		if ringIndex > 0 {
+			area = -area
+		}
+		polygon.area += area
+		polygon.rings = append(polygon.rings, points)
+	}
+	if len(polygon.rings) == 0 {
+		return zonePolygon{}, fmt.Errorf("%w: zone %s has an empty polygon", ErrInvalidZoneBoundaries, zone)
+	}
+	return polygon, nil
+}
+
+// ringArea returns the unsigned shoelace area of a ring in square degrees
+func ringArea(ring [][2]float64) float64 {
+	sum := 0.0
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		sum += ring[j][0]*ring[i][1] - ring[i][0]*ring[j][1]
+	}
+	return math.Abs(sum) / 2
+}
+
+// zoneIndexCell returns the spatial index cell of a point
+func zoneIndexCell(lon, lat float64) (int, int) {
+	col := int(math.Floor((lon + 180) / zoneIndexCellDegrees))
+	row := int(math.Floor((lat + 90) / zoneIndexCellDegrees))
+	maxCol, maxRow := int(360/zoneIndexCellDegrees)-1, int(180/zoneIndexCellDegrees)-1
+	return min(max(col, 0), maxCol), min(max(row, 0), maxRow)
+}
+
+// add indexes a polygon in every cell its bounding box overlaps; callers hold the lock
+func (b *ZoneBoundaries) add(polygon zonePolygon) {
+	i := int32(len(b.polygons))
+	b.polygons = append(b.polygons, polygon)
+	b.zones[polygon.zone] = true
+
+	minCol, minRow := zoneIndexCell(polygon.minLon, polygon.minLat)
+	maxCol, maxRow := zoneIndexCell(polygon.maxLon, polygon.maxLat)
+	stride := int(360 / zoneIndexCellDegrees)
+	for row := minRow; row <= maxRow; row++ {
+		for col := minCol; col <= maxCol; col++ {
+			cell := row*stride + col
+			b.cells[cell] = append(b.cells[cell], i)
+		}
+	}
+}
+
+// Lookup returns the zone containing a point. Where zones overlap, the smallest wins so
+// that sub-national zones take precedence over country outlines.
+func (b *ZoneBoundaries) Lookup(latitude, longitude float64) (string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	col, row := zoneIndexCell(longitude, latitude)
+	var match *zonePolygon
+	for _, i := range b.cells[row*int(360/zoneIndexCellDegrees)+col] {
+		polygon := &b.polygons[i]
+		if (match == nil || polygon.area < match.area) && polygon.contains(longitude, latitude) {
+			match = polygon
+		}
+	}
+	if match == nil {
+		return "", false
+	}
+	return match.zone, true
+}
+
+// Contains reports whether a zone code has a boundary
+func (b *ZoneBoundaries) Contains(zone string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.zones[strings.ToUpper(strings.TrimSpace(zone))]
+}
+
+// Len returns the number of distinct zones
+func (b *ZoneBoundaries) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.zones)
+}
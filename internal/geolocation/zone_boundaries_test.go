@@ -0,0 +1,124 @@
+package geolocation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testZoneBoundaries covers a US outline with nested California and a hole, an
+// Australian MultiPolygon and a zone identified by feature id
+const testZoneBoundaries = `{
+	"type": "FeatureCollection",
+	"features": [
+		{"type": "Feature", "properties": {"zoneName": "US"}, "geometry": {"type": "Polygon", "coordinates": [
+			[[-125, 24], [-66, 24], [-66, 49], [-125, 49], [-125, 24]]
+		]}},
+		{"type": "Feature", "properties": {"zoneName": "US-CAL-CISO"}, "geometry": {"type": "Polygon", "coordinates": [
+			[[-124.5, 32.5], [-114, 32.5], [-120, 42], [-124.5, 42], [-124.5, 32.5]],
+			[[-119.5, 36.5], [-118.5, 36.5], [-118.5, 37.5], [-119.5, 37.5], [-119.5, 36.5]]
+		]}},
+		{"type": "Feature", "properties": {"zone": "au-nsw"}, "geometry": {"type": "MultiPolygon", "coordinates": [
+			[[[141, -37.5], [153.6, -37.5], [153.6, -28.2], [141, -29], [141, -37.5]]],
+			[[[159.0, -31.6], [159.1, -31.6], [159.1, -31.5], [159.0, -31.5], [159.0, -31.6]]]
+		]}},
+		{"type": "Feature", "id": "SE-SE3", "properties": {}, "geometry": {"type": "Polygon", "coordinates": [
+			[[11, 56.5], [19, 56.5], [19, 61], [11, 61], [11, 56.5]]
+		]}},
+		{"type": "Feature", "properties": {"zone": "XX"}, "geometry": {"type": "Point", "coordinates": [0, 0]}}
+	]
+}`
+
+func TestZoneBoundariesLookup(t *testing.T) {
+	boundaries := NewZoneBoundaries()
+	zones, err := boundaries.LoadGeoJSON(strings.NewReader(testZoneBoundaries))
+	if err != nil {
+		t.Fatalf("LoadGeoJSON failed: %v", err)
+	}
+	if zones != 4 || boundaries.Len() != 4 {
+		t.Errorf("expected 4 zones, got %d (%d indexed)", zones, boundaries.Len())
+	}
+
+	tests := []struct {
+		name      string
+		latitude  float64
+		longitude float64
+		expected  string
+	}{
+		{"Los Angeles", 34.05, -118.24, "US-CAL-CISO"},
+		{"San Francisco", 37.77, -122.42, "US-CAL-CISO"},
+		{"California hole", 37.0, -119.0, "US"},
+		{"Nevada beside the diagonal edge", 40.0, -116.0, "US"},
+		{"New York", 40.71, -74.0, "US"},
+		{"Sydney", -33.87, 151.21, "AU-NSW"},
+		{"Lord Howe Island", -31.55, 159.05, "AU-NSW"},
+		{"Stockholm", 59.33, 18.06, "SE-SE3"},
+		{"Atlantic", 40.0, -40.0, ""},
+		{"Null Island", 0, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone, found := boundaries.Lookup(tt.latitude, tt.longitude)
+			if tt.expected == "" {
+				if found {
+					t.Errorf("expected no zone, got %s", zone)
+				}
+				return
+			}
+			if !found || zone != tt.expected {
+				t.Errorf("Lookup(%v, %v) = %q (found %v), want %q", tt.latitude, tt.longitude, zone, found, tt.expected)
+			}
+		})
+	}
+}
+
+func TestZoneBoundariesInvalid(t *testing.T) {
+	inputs := []string{
+		`not json`,
+		`{"type": "Feature"}`,
+		`{"type": "FeatureCollection", "features": [{"properties": {}, "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1]]]}}]}`,
+		`{"type": "FeatureCollection", "features": [{"properties": {"zone": "DE"}, "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0]]]}}]}`,
+		`{"type": "FeatureCollection", "features": [{"properties": {"zone": "DE"}, "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 95]]]}}]}`,
+	}
+	for _, input := range inputs {
+		if _, err := NewZoneBoundaries().LoadGeoJSON(strings.NewReader(input)); !errors.Is(err, ErrInvalidZoneBoundaries) {
+			t.Errorf("LoadGeoJSON(%q): expected ErrInvalidZoneBoundaries, got %v", input, err)
+		}
+	}
+}
+
+func TestGridZoneMapperSubnationalZones(t *testing.T) {
+	boundaries := NewZoneBoundaries()
+	if _, err := boundaries.LoadGeoJSON(strings.NewReader(testZoneBoundaries)); err != nil {
+		t.Fatal(err)
+	}
+	mapper := NewGridZoneMapper()
+	mapper.SetZoneBoundaries(boundaries)
+
+	tests := []struct {
+		name     string
+		location Location
+		expected string
+	}{
+		{"coordinates in a sub-national zone", Location{CountryCode: "US", Country: "United States", Latitude: 34.05, Longitude: -118.24}, "US-CAL-CISO"},
+		{"coordinates without country code", Location{Latitude: -33.87, Longitude: 151.21}, "AU-NSW"},
+		{"country code mismatch uses the country", Location{CountryCode: "CA", Latitude: 40.71, Longitude: -74.0}, "CA"},
+		{"no coordinates uses the country", Location{CountryCode: "AU"}, "AU"},
+		{"outside all zones uses the country", Location{CountryCode: "JP", Latitude: 35.69, Longitude: 139.69}, "JP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone := mapper.MapToGridZone(tt.location)
+			if zone.Zone != tt.expected {
+				t.Errorf("MapToGridZone() = %s, want %s", zone.Zone, tt.expected)
+			}
+		})
+	}
+
+	zone := mapper.MapToGridZone(Location{CountryCode: "US", Latitude: 34.05, Longitude: -118.24, Region: "California"})
+	if zone.Country != "United States" || zone.Region != "California" || zone.Description != "US electricity grid (US-CAL-CISO)" {
+		t.Errorf("unexpected zone details %+v", zone)
+	}
+}
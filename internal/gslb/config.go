@@ -0,0 +1,175 @@
+// Package gslb implements a carbon-aware global server load balancer: an authoritative
+// DNS server that answers each query with the greenest viable edge for the client's location.
+package gslb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the GSLB zone and record configuration
+type Config struct {
+	Zone              string            `json:"zone"`                 // Zone the server is authoritative for, e.g. "gslb.example.com."
+	Nameservers       []string          `json:"nameservers"`          // NS names published at the zone apex
+	TTLSeconds        uint32            `json:"ttl_seconds"`          // TTL of steered answers
+	MaxDataAgeMinutes int               `json:"max_data_age_minutes"` // Carbon data older than this is stale
+	ContentType       string            `json:"content_type"`         // Content type used to weight user and edge intensity
+	CarbonWeight      float64           `json:"carbon_weight"`        // Weight of weighted intensity in the edge score
+	DistanceWeight    float64           `json:"distance_weight"`      // Weight of distance in the edge score
+	MaxDistanceKm     float64           `json:"max_distance_km"`      // Edges farther than this are not viable, 0 for unlimited
+	ECSMaxPrefixIPv4  int               `json:"ecs_max_prefix_ipv4"`  // Longest IPv4 ECS prefix used for lookups
+	ECSMaxPrefixIPv6  int               `json:"ecs_max_prefix_ipv6"`  // Longest IPv6 ECS prefix used for lookups
+	UDPWorkers        int               `json:"udp_workers"`          // Concurrent UDP queries; datagrams beyond the queue are dropped
+	TCPConnections    int               `json:"tcp_connections"`      // Concurrent TCP connections; connections beyond it are closed
+	QueryTimeoutMs    int               `json:"query_timeout_ms"`     // Deadline for answering one query, including edge selection
+	Records           map[string]Record `json:"records"`              // Steered names relative to the zone ("@" for the apex)
+}
+
+// Record is a steered name backed by edge locations of a CDN catalog provider
+type Record struct {
+	Provider  string              `json:"provider"`  // CDN catalog provider the edge IDs belong to
+	Endpoints map[string]Endpoint `json:"endpoints"` // Answers per edge ID
+}
+
+// Endpoint holds the answers for one edge location
+type Endpoint struct {
+	IPv4  []string `json:"ipv4,omitempty"`  // A record addresses
+	IPv6  []string `json:"ipv6,omitempty"`  // AAAA record addresses
+	CNAME string   `json:"cname,omitempty"` // CNAME target, used when no address of the queried family exists
+}
+
+// DefaultConfig returns a configuration with the default tuning values and no records
+func DefaultConfig() Config {
+	return Config{
+		TTLSeconds:        30,
+		MaxDataAgeMinutes: 120,
+		ContentType:       "static",
+		CarbonWeight:      0.6,
+		DistanceWeight:    0.4,
+		ECSMaxPrefixIPv4:  24,
+		ECSMaxPrefixIPv6:  56,
+		UDPWorkers:        64,
+		TCPConnections:    128,
+		QueryTimeoutMs:    2000,
+	}
+}
+
+// LoadConfigFile reads a YAML or JSON GSLB configuration on top of DefaultConfig
+func LoadConfigFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read GSLB config: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		// Decode YAML generically and re-encode as JSON so both formats share one schema
+		var document interface{}
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return Config{}, fmt.Errorf("failed to parse GSLB config: %w", err)
+		}
+		if data, err = json.Marshal(document); err != nil {
+			return Config{}, fmt.Errorf("failed to parse GSLB config: %w", err)
+		}
+	}
+
+	config := DefaultConfig()
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return Config{}, fmt.Errorf("failed to parse GSLB config: %w", err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// Validate checks the configuration and normalizes the zone name
+func (c *Config) Validate() error {
+	if c.Zone == "" {
+		return fmt.Errorf("GSLB zone is required")
+	}
+	c.Zone = canonicalName(c.Zone)
+
+	if c.TTLSeconds == 0 {
+		return fmt.Errorf("GSLB ttl_seconds must be positive")
+	}
+	if c.CarbonWeight < 0 || c.DistanceWeight < 0 || c.CarbonWeight+c.DistanceWeight == 0 {
+		return fmt.Errorf("GSLB carbon_weight and distance_weight must be non-negative and not both zero")
+	}
+	if c.ECSMaxPrefixIPv4 < 0 || c.ECSMaxPrefixIPv4 > 32 || c.ECSMaxPrefixIPv6 < 0 || c.ECSMaxPrefixIPv6 > 128 {
+		return fmt.Errorf("GSLB ECS prefix limits out of range")
+	}
+	if c.UDPWorkers < 0 || c.TCPConnections < 0 || c.QueryTimeoutMs < 0 {
+		return fmt.Errorf("GSLB udp_workers, tcp_connections and query_timeout_ms cannot be negative")
+	}
+	if len(c.Records) == 0 {
+		return fmt.Errorf("GSLB records must not be empty")
+	}
+
+	for name, record := range c.Records {
+		if record.Provider == "" {
+			return fmt.Errorf("GSLB record %s: provider is required", name)
+		}
+		if len(record.Endpoints) == 0 {
+			return fmt.Errorf("GSLB record %s: endpoints must not be empty", name)
+		}
+		for edgeID, endpoint := range record.Endpoints {
+			if len(endpoint.IPv4) == 0 && len(endpoint.IPv6) == 0 && endpoint.CNAME == "" {
+				return fmt.Errorf("GSLB record %s endpoint %s: needs ipv4, ipv6 or cname", name, edgeID)
+			}
+			for _, address := range endpoint.IPv4 {
+				if ip, err := netip.ParseAddr(address); err != nil || !ip.Is4() {
+					return fmt.Errorf("GSLB record %s endpoint %s: invalid IPv4 address %q", name, edgeID, address)
+				}
+			}
+			for _, address := range endpoint.IPv6 {
+				if ip, err := netip.ParseAddr(address); err != nil || !ip.Is6() || ip.Is4In6() {
+					return fmt.Errorf("GSLB record %s endpoint %s: invalid IPv6 address %q", name, edgeID, address)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// QueryTimeout returns the deadline for answering one query, defaulting to two seconds
+func (c Config) QueryTimeout() time.Duration {
+	if c.QueryTimeoutMs <= 0 {
+		return 2 * time.Second
+	}
+	return time.Duration(c.QueryTimeoutMs) * time.Millisecond
+}
+
+// MaxDataAge returns the age after which carbon data is considered stale
+func (c Config) MaxDataAge() time.Duration {
+	return time.Duration(c.MaxDataAgeMinutes) * time.Minute
+}
+
+// fqdn returns the fully qualified name of a record relative to the zone
+func (c Config) fqdn(name string) string {
+	if name == "@" || name == "" {
+		return c.Zone
+	}
+	return canonicalName(name + "." + c.Zone)
+}
+
+// canonicalName lowercases a DNS name and ensures a trailing dot
+func canonicalName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
@@ -0,0 +1,322 @@
+package gslb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/perschulte/greenweb-api/internal/geolocation"
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+)
+
+// IntensityProvider supplies current carbon intensity per grid zone
+type IntensityProvider interface {
+	GetCarbonIntensity(ctx context.Context, location string) (*carbon.CarbonIntensity, error)
+}
+
+// Locator resolves a client address to a location and grid zone
+type Locator interface {
+	GetLocationByIP(ctx context.Context, ip string) (geolocation.LocationWithZone, error)
+}
+
+// HealthChecker reports whether an edge may receive traffic. A nil HealthChecker treats all edges as healthy.
+type HealthChecker interface {
+	IsHealthy(provider, edgeID string) bool
+}
+
+// Selection strategies recorded on a Selection
+const (
+	StrategyCarbon  = "carbon_weighted"
+	StrategyNearest = "latency_nearest"
+)
+
+// Candidate is a scored edge for one query
+type Candidate struct {
+	EdgeID            string  `json:"edge_id"`
+	GridZone          string  `json:"grid_zone"`
+	DistanceKm        float64 `json:"distance_km"`
+	WeightedIntensity float64 `json:"weighted_intensity,omitempty"`
+	Score             float64 `json:"score"`
+	Fresh             bool    `json:"fresh"`
+}
+
+// Selection is the edge chosen for a query
+type Selection struct {
+	EdgeID   string      `json:"edge_id"`
+	Endpoint Endpoint    `json:"endpoint"`
+	Strategy string      `json:"strategy"`
+	Reason   string      `json:"reason"`
+	UserZone string      `json:"user_zone"`
+	Ranked   []Candidate `json:"ranked"`
+}
+
+// intensityEntry is a cached intensity reading for a zone
+type intensityEntry struct {
+	intensity *carbon.CarbonIntensity
+	fetchedAt time.Time
+}
+
+// locationEntry is a cached location for a client subnet
+type locationEntry struct {
+	location  geolocation.LocationWithZone
+	expiresAt time.Time
+}
+
+// Selector picks the greenest viable edge for a client subnet
+type Selector struct {
+	config      Config
+	intensities IntensityProvider
+	locator     Locator
+	health      HealthChecker
+	logger      *slog.Logger
+
+	// refreshInterval bounds how often a zone's intensity is refetched
+	refreshInterval time.Duration
+	// lookupTimeout bounds intensity and location lookups so DNS answers stay fast
+	lookupTimeout time.Duration
+
+	mu          sync.Mutex
+	intensityBy map[string]intensityEntry
+	locationBy  map[string]locationEntry
+}
+
+// NewSelector creates a selector. locator and health may be nil; without a locator every
+// client is treated as being at geolocation.DefaultLocation.
+func NewSelector(config Config, intensities IntensityProvider, locator Locator, health HealthChecker, logger *slog.Logger) *Selector {
+	return &Selector{
+		config:          config,
+		intensities:     intensities,
+		locator:         locator,
+		health:          health,
+		logger:          logger,
+		refreshInterval: 5 * time.Minute,
+		lookupTimeout:   500 * time.Millisecond,
+		intensityBy:     make(map[string]intensityEntry),
+		locationBy:      make(map[string]locationEntry),
+	}
+}
+
+// Select chooses an edge of a record for a client subnet.
+//
+// Healthy edges within MaxDistanceKm are scored by a blend of normalized dual-grid weighted
+// intensity and normalized distance. When the client zone's data or every edge's data is stale
+// or synthetic, the latency-nearest (closest) healthy edge is returned instead.
+func (s *Selector) Select(ctx context.Context, record Record, client netip.Prefix) (*Selection, error) {
+	provider, exists := carbon.GetCDNProvider(record.Provider)
+	if !exists {
+		return nil, fmt.Errorf("CDN provider %s not in catalog", record.Provider)
+	}
+
+	user := s.locate(ctx, client)
+	userIntensity, userFresh := s.intensity(ctx, user.GridZone.Zone)
+
+	var candidates []Candidate
+	for edgeID := range record.Endpoints {
+		edge, exists := provider.EdgeLocations[edgeID]
+		if !exists {
+			s.logger.Warn("GSLB endpoint not in CDN catalog", "provider", record.Provider, "edge", edgeID)
+			continue
+		}
+		if s.health != nil && !s.health.IsHealthy(record.Provider, edgeID) {
+			continue
+		}
+
+		candidate := Candidate{
+			EdgeID:     edgeID,
+			GridZone:   edge.GridZone,
+			DistanceKm: carbon.CalculateDistance(user.Location.Latitude, user.Location.Longitude, edge.Latitude, edge.Longitude),
+		}
+		if edgeIntensity, fresh := s.intensity(ctx, edge.GridZone); fresh && userFresh {
+			candidate.WeightedIntensity, _, _ = carbon.CalculateWeightedIntensity(userIntensity, edgeIntensity, s.config.ContentType)
+			candidate.Fresh = true
+		}
+		candidates = append(candidates, candidate)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no healthy edges for provider %s", record.Provider)
+	}
+
+	candidates = s.viable(candidates)
+
+	selection := &Selection{UserZone: user.GridZone.Zone}
+	fresh := freshCandidates(candidates)
+	if !userFresh || len(fresh) == 0 {
+		sortByDistance(candidates)
+		selection.Strategy = StrategyNearest
+		selection.Reason = "carbon data stale or unavailable"
+		selection.Ranked = candidates
+	} else {
+		scoreCandidates(fresh, s.config.CarbonWeight, s.config.DistanceWeight)
+		selection.Strategy = StrategyCarbon
+		selection.Reason = "lowest weighted intensity and distance score"
+		if len(fresh) < len(candidates) {
+			selection.Reason += fmt.Sprintf(" (%d edges with stale data skipped)", len(candidates)-len(fresh))
+		}
+		selection.Ranked = fresh
+	}
+
+	selection.EdgeID = selection.Ranked[0].EdgeID
+	selection.Endpoint = record.Endpoints[selection.EdgeID]
+	return selection, nil
+}
+
+// viable drops edges beyond MaxDistanceKm, unless that would leave none
+func (s *Selector) viable(candidates []Candidate) []Candidate {
+	if s.config.MaxDistanceKm <= 0 {
+		return candidates
+	}
+
+	var within []Candidate
+	for _, candidate := range candidates {
+		if candidate.DistanceKm <= s.config.MaxDistanceKm {
+			within = append(within, candidate)
+		}
+	}
+	if len(within) == 0 {
+		return candidates
+	}
+	return within
+}
+
+// freshCandidates returns the candidates with fresh carbon data
+func freshCandidates(candidates []Candidate) []Candidate {
+	var fresh []Candidate
+	for _, candidate := range candidates {
+		if candidate.Fresh {
+			fresh = append(fresh, candidate)
+		}
+	}
+	return fresh
+}
+
+// scoreCandidates min-max normalizes intensity and distance, blends them and sorts ascending
+func scoreCandidates(candidates []Candidate, carbonWeight, distanceWeight float64) {
+	minIntensity, maxIntensity := candidates[0].WeightedIntensity, candidates[0].WeightedIntensity
+	minDistance, maxDistance := candidates[0].DistanceKm, candidates[0].DistanceKm
+	for _, candidate := range candidates[1:] {
+		minIntensity = min(minIntensity, candidate.WeightedIntensity)
+		maxIntensity = max(maxIntensity, candidate.WeightedIntensity)
+		minDistance = min(minDistance, candidate.DistanceKm)
+		maxDistance = max(maxDistance, candidate.DistanceKm)
+	}
+
+	total := carbonWeight + distanceWeight
+	for i := range candidates {
+		candidates[i].Score = (carbonWeight*normalize(candidates[i].WeightedIntensity, minIntensity, maxIntensity) +
+			distanceWeight*normalize(candidates[i].DistanceKm, minDistance, maxDistance)) / total
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score < candidates[j].Score
+		}
+		return candidates[i].EdgeID < candidates[j].EdgeID
+	})
+}
+
+// sortByDistance orders candidates nearest first
+func sortByDistance(candidates []Candidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].DistanceKm != candidates[j].DistanceKm {
+			return candidates[i].DistanceKm < candidates[j].DistanceKm
+		}
+		return candidates[i].EdgeID < candidates[j].EdgeID
+	})
+}
+
+// normalize maps value into [0, 1] within [low, high]
+func normalize(value, low, high float64) float64 {
+	if high <= low {
+		return 0
+	}
+	return (value - low) / (high - low)
+}
+
+// locate resolves the client subnet, caching results per subnet
+func (s *Selector) locate(ctx context.Context, client netip.Prefix) geolocation.LocationWithZone {
+	fallback := geolocation.LocationWithZone{
+		Location: geolocation.DefaultLocation,
+		GridZone: geolocation.DefaultGridZone,
+		Source:   geolocation.LocationSourceDefault,
+	}
+	if s.locator == nil || !client.IsValid() {
+		return fallback
+	}
+
+	key := client.Masked().String()
+	s.mu.Lock()
+	entry, exists := s.locationBy[key]
+	s.mu.Unlock()
+	if exists && time.Now().Before(entry.expiresAt) {
+		return entry.location
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, s.lookupTimeout)
+	defer cancel()
+
+	location, err := s.locator.GetLocationByIP(lookupCtx, client.Masked().Addr().String())
+	if err != nil {
+		s.logger.Debug("GSLB client location lookup failed", "subnet", key, "error", err)
+		return fallback
+	}
+
+	s.mu.Lock()
+	// Bound the cache without tracking order; subnets are cheap to look up again
+	if len(s.locationBy) >= 10000 {
+		s.locationBy = make(map[string]locationEntry)
+	}
+	s.locationBy[key] = locationEntry{location: location, expiresAt: time.Now().Add(time.Hour)}
+	s.mu.Unlock()
+
+	return location
+}
+
+// intensity returns a zone's intensity and whether it is fresh, refetching at most every refreshInterval.
+// Synthetic readings (mock or default data) are never fresh, however recently they were fetched.
+func (s *Selector) intensity(ctx context.Context, zone string) (float64, bool) {
+	if zone == "" || s.intensities == nil {
+		return 0, false
+	}
+
+	s.mu.Lock()
+	entry, exists := s.intensityBy[zone]
+	s.mu.Unlock()
+
+	if !exists || time.Since(entry.fetchedAt) > s.refreshInterval {
+		lookupCtx, cancel := context.WithTimeout(ctx, s.lookupTimeout)
+		intensity, err := s.intensities.GetCarbonIntensity(lookupCtx, zone)
+		cancel()
+
+		if err == nil && intensity != nil {
+			entry = intensityEntry{intensity: intensity, fetchedAt: time.Now()}
+			s.mu.Lock()
+			s.intensityBy[zone] = entry
+			s.mu.Unlock()
+			exists = true
+		} else {
+			s.logger.Debug("GSLB intensity lookup failed", "zone", zone, "error", err)
+			if exists {
+				// Keep the old reading but don't retry on every query
+				entry.fetchedAt = time.Now()
+				s.mu.Lock()
+				s.intensityBy[zone] = entry
+				s.mu.Unlock()
+			}
+		}
+	}
+
+	if !exists {
+		return 0, false
+	}
+	if carbon.IsSyntheticSource(entry.intensity.Source) {
+		return entry.intensity.CarbonIntensity, false
+	}
+	if maxAge := s.config.MaxDataAge(); maxAge > 0 && time.Since(entry.intensity.Timestamp) > maxAge {
+		return entry.intensity.CarbonIntensity, false
+	}
+	return entry.intensity.CarbonIntensity, true
+}
@@ -0,0 +1,149 @@
+package gslb
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/perschulte/greenweb-api/internal/geolocation"
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+)
+
+// stubIntensities returns fixed intensities per zone with a fixed timestamp, from live data
+// unless a source is set
+type stubIntensities struct {
+	values    map[string]float64
+	timestamp time.Time
+	source    string
+}
+
+func (s stubIntensities) GetCarbonIntensity(ctx context.Context, zone string) (*carbon.CarbonIntensity, error) {
+	source := s.source
+	if source == "" {
+		source = carbon.DataSourceElectricityMaps
+	}
+	return &carbon.CarbonIntensity{Location: zone, CarbonIntensity: s.values[zone], Timestamp: s.timestamp, Source: source}, nil
+}
+
+// stubLocator places every client in Berlin
+type stubLocator struct{}
+
+func (stubLocator) GetLocationByIP(ctx context.Context, ip string) (geolocation.LocationWithZone, error) {
+	return geolocation.LocationWithZone{
+		Location: geolocation.Location{IP: ip, City: "Berlin", Latitude: 52.52, Longitude: 13.405},
+		GridZone: geolocation.GridZone{Zone: "DE"},
+	}, nil
+}
+
+// stubHealth marks the listed edges as down
+type stubHealth map[string]bool
+
+func (h stubHealth) IsHealthy(provider, edgeID string) bool {
+	return !h[edgeID]
+}
+
+func testConfig() Config {
+	config := DefaultConfig()
+	config.Zone = "gslb.example.com."
+	config.Records = map[string]Record{
+		"www": {
+			Provider: "cloudflare",
+			Endpoints: map[string]Endpoint{
+				"frankfurt": {IPv4: []string{"192.0.2.10"}, IPv6: []string{"2001:db8::10"}},
+				"stockholm": {IPv4: []string{"192.0.2.20"}},
+				"paris":     {CNAME: "paris.edge.example.net"},
+			},
+		},
+	}
+	return config
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestSelectorSelect(t *testing.T) {
+	fresh := stubIntensities{
+		values:    map[string]float64{"DE": 400, "SE": 40, "FR": 60},
+		timestamp: time.Now(),
+	}
+	stale := fresh
+	stale.timestamp = time.Now().Add(-6 * time.Hour)
+	mock := fresh
+	mock.source = carbon.DataSourceMock
+
+	tests := []struct {
+		name             string
+		intensities      IntensityProvider
+		health           HealthChecker
+		expectedEdge     string
+		expectedStrategy string
+	}{
+		{
+			name:             "greenest viable edge wins",
+			intensities:      fresh,
+			expectedEdge:     "stockholm",
+			expectedStrategy: StrategyCarbon,
+		},
+		{
+			name:             "unhealthy edges are excluded",
+			intensities:      fresh,
+			health:           stubHealth{"stockholm": true},
+			expectedEdge:     "paris",
+			expectedStrategy: StrategyCarbon,
+		},
+		{
+			name:             "stale data falls back to nearest",
+			intensities:      stale,
+			expectedEdge:     "frankfurt",
+			expectedStrategy: StrategyNearest,
+		},
+		{
+			name:             "mock data falls back to nearest",
+			intensities:      mock,
+			expectedEdge:     "frankfurt",
+			expectedStrategy: StrategyNearest,
+		},
+	}
+
+	client := netip.MustParsePrefix("81.2.69.0/24")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			selector := NewSelector(config, tt.intensities, stubLocator{}, tt.health, testLogger())
+
+			selection, err := selector.Select(context.Background(), config.Records["www"], client)
+			if err != nil {
+				t.Fatalf("Select() error = %v", err)
+			}
+			if selection.EdgeID != tt.expectedEdge {
+				t.Errorf("Expected edge %s, got %s (ranked %+v)", tt.expectedEdge, selection.EdgeID, selection.Ranked)
+			}
+			if selection.Strategy != tt.expectedStrategy {
+				t.Errorf("Expected strategy %s, got %s", tt.expectedStrategy, selection.Strategy)
+			}
+		})
+	}
+}
+
+func TestSelectorMaxDistance(t *testing.T) {
+	config := testConfig()
+	config.MaxDistanceKm = 600
+
+	intensities := stubIntensities{
+		values:    map[string]float64{"DE": 400, "SE": 40, "FR": 60},
+		timestamp: time.Now(),
+	}
+	selector := NewSelector(config, intensities, stubLocator{}, nil, testLogger())
+
+	selection, err := selector.Select(context.Background(), config.Records["www"], netip.MustParsePrefix("81.2.69.0/24"))
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if selection.EdgeID != "frankfurt" {
+		t.Errorf("Expected only frankfurt to be viable within 600 km, got %s", selection.EdgeID)
+	}
+}
@@ -0,0 +1,698 @@
+package gslb
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// EDNS Client Subnet option code (RFC 7871)
+const optionCodeECS = 8
+
+// Message size limits
+const (
+	maxUDPSize     = 512  // Classic DNS over UDP limit without EDNS
+	maxEDNSUDPSize = 1232 // Advertised and honoured EDNS buffer size (DNS flag day 2020)
+	maxTCPSize     = 65535
+)
+
+// tcpIdleTimeout closes TCP connections that do not deliver a complete query in time
+const tcpIdleTimeout = 10 * time.Second
+
+// defaultUDPWorkers answer UDP queries when Config.UDPWorkers is not set
+const defaultUDPWorkers = 64
+
+// defaultTCPConnections are served concurrently when Config.TCPConnections is not set
+const defaultTCPConnections = 128
+
+// datagram is a UDP query waiting for a worker
+type datagram struct {
+	query []byte
+	addr  net.Addr
+}
+
+// Server is an authoritative DNS server for the GSLB zone, answering over UDP and TCP
+type Server struct {
+	config   Config
+	selector *Selector
+	logger   *slog.Logger
+	records  map[string]Record // Keyed by fully qualified name
+
+	mu        sync.Mutex
+	packet    net.PacketConn
+	listener  net.Listener
+	closed    bool
+	waitGroup sync.WaitGroup
+}
+
+// NewServer creates an authoritative DNS server for a validated configuration
+func NewServer(config Config, selector *Selector, logger *slog.Logger) *Server {
+	records := make(map[string]Record, len(config.Records))
+	for name, record := range config.Records {
+		records[config.fqdn(name)] = record
+	}
+
+	return &Server{
+		config:   config,
+		selector: selector,
+		logger:   logger,
+		records:  records,
+	}
+}
+
+// ListenAndServe serves DNS on addr over both UDP and TCP until Shutdown is called
+func (s *Server) ListenAndServe(addr string) error {
+	packet, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on udp %s: %w", addr, err)
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		packet.Close()
+		return fmt.Errorf("failed to listen on tcp %s: %w", addr, err)
+	}
+
+	return s.Serve(packet, listener)
+}
+
+// Serve answers queries on an existing UDP socket and TCP listener until Shutdown is called
+func (s *Server) Serve(packet net.PacketConn, listener net.Listener) error {
+	s.mu.Lock()
+	s.packet = packet
+	s.listener = listener
+	s.mu.Unlock()
+
+	s.logger.Info("GSLB DNS server listening",
+		"zone", s.config.Zone,
+		"udp", packet.LocalAddr().String(),
+		"tcp", listener.Addr().String(),
+		"records", len(s.records))
+
+	errs := make(chan error, 2)
+	go func() { errs <- s.serveUDP(packet) }()
+	go func() { errs <- s.serveTCP(listener) }()
+
+	err := <-errs
+	s.Shutdown()
+	<-errs
+	s.waitGroup.Wait()
+
+	if s.isClosed() {
+		return nil
+	}
+	return err
+}
+
+// Shutdown closes the listeners; in-flight queries are allowed to finish
+func (s *Server) Shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	if s.packet != nil {
+		s.packet.Close()
+	}
+	if s.listener != nil {
+		s.listener.Close()
+	}
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// serveUDP answers datagram queries on a fixed pool of workers. Datagrams arriving while
+// every worker is busy and the queue is full are dropped; resolvers retry them.
+func (s *Server) serveUDP(packet net.PacketConn) error {
+	workers := s.config.UDPWorkers
+	if workers <= 0 {
+		workers = defaultUDPWorkers
+	}
+
+	queue := make(chan datagram, workers)
+	defer close(queue)
+	for i := 0; i < workers; i++ {
+		s.waitGroup.Add(1)
+		go func() {
+			defer s.waitGroup.Done()
+			for d := range queue {
+				if response := s.handleWithTimeout(d.query, addrIP(d.addr), false); response != nil {
+					packet.WriteTo(response, d.addr)
+				}
+			}
+		}()
+	}
+
+	buf := make([]byte, maxTCPSize)
+	for {
+		n, addr, err := packet.ReadFrom(buf)
+		if err != nil {
+			if s.isClosed() {
+				return nil
+			}
+			return err
+		}
+
+		select {
+		case queue <- datagram{query: append([]byte(nil), buf[:n]...), addr: addr}:
+		default:
+			s.logger.Debug("Dropping DNS query, all UDP workers busy", "client", addr.String())
+		}
+	}
+}
+
+// handleWithTimeout answers a query within the configured query timeout
+func (s *Server) handleWithTimeout(message []byte, client netip.Addr, tcp bool) []byte {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.QueryTimeout())
+	defer cancel()
+	return s.Handle(ctx, message, client, tcp)
+}
+
+// serveTCP accepts connections and answers length-prefixed queries on each. Connections
+// accepted while the connection limit is reached are closed; resolvers retry them.
+func (s *Server) serveTCP(listener net.Listener) error {
+	limit := s.config.TCPConnections
+	if limit <= 0 {
+		limit = defaultTCPConnections
+	}
+	slots := make(chan struct{}, limit)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return nil
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return err
+		}
+
+		select {
+		case slots <- struct{}{}:
+		default:
+			s.logger.Debug("Closing DNS connection, TCP connection limit reached", "client", conn.RemoteAddr().String())
+			conn.Close()
+			continue
+		}
+
+		s.waitGroup.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				s.waitGroup.Done()
+			}()
+			s.serveConn(conn)
+		}()
+	}
+}
+
+// serveConn answers queries on one TCP connection until it goes idle
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	client := addrIP(conn.RemoteAddr())
+
+	for !s.isClosed() {
+		// The whole query, length prefix included, must arrive within the deadline
+		conn.SetReadDeadline(time.Now().Add(tcpIdleTimeout))
+
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return
+		}
+		query := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, query); err != nil {
+			return
+		}
+
+		response := s.handleWithTimeout(query, client, true)
+		if response == nil {
+			return
+		}
+
+		framed := make([]byte, 2+len(response))
+		binary.BigEndian.PutUint16(framed, uint16(len(response)))
+		copy(framed[2:], response)
+		conn.SetWriteDeadline(time.Now().Add(tcpIdleTimeout))
+		if _, err := conn.Write(framed); err != nil {
+			return
+		}
+	}
+}
+
+// query is a parsed DNS query
+type query struct {
+	header   dnsmessage.Header
+	question dnsmessage.Question
+	edns     bool
+	udpSize  int
+	ecs      *clientSubnet
+}
+
+// clientSubnet is a parsed EDNS Client Subnet option
+type clientSubnet struct {
+	family       uint16
+	sourcePrefix int
+	prefix       netip.Prefix
+}
+
+// Handle answers a single wire-format query from client. It returns nil for messages
+// that should be dropped, such as responses or unparseable headers.
+func (s *Server) Handle(ctx context.Context, message []byte, client netip.Addr, tcp bool) []byte {
+	q, rcode, err := parseQuery(message)
+	if err != nil && rcode == 0 {
+		return nil
+	}
+
+	responseHeader := dnsmessage.Header{
+		ID:               q.header.ID,
+		Response:         true,
+		OpCode:           q.header.OpCode,
+		Authoritative:    true,
+		RecursionDesired: q.header.RecursionDesired,
+		RCode:            rcode,
+	}
+
+	maxSize := maxUDPSize
+	if tcp {
+		maxSize = maxTCPSize
+	} else if q.edns {
+		maxSize = min(max(q.udpSize, maxUDPSize), maxEDNSUDPSize)
+	}
+
+	if rcode != dnsmessage.RCodeSuccess {
+		responseHeader.Authoritative = false
+		return s.build(responseHeader, q, nil, nil, maxSize)
+	}
+
+	answers, authorities, rcode, selection := s.resolve(ctx, q, client)
+	responseHeader.RCode = rcode
+	if rcode == dnsmessage.RCodeRefused {
+		responseHeader.Authoritative = false
+	}
+
+	if selection != nil {
+		s.logger.Debug("GSLB answer",
+			"name", q.question.Name.String(),
+			"type", q.question.Type.String(),
+			"edge", selection.EdgeID,
+			"strategy", selection.Strategy,
+			"user_zone", selection.UserZone)
+	}
+
+	return s.build(responseHeader, q, answers, authorities, maxSize)
+}
+
+// answer is a resource record to be written to a response
+type answer struct {
+	header   dnsmessage.ResourceHeader
+	resource dnsmessage.ResourceBody
+}
+
+// resolve produces the answer and authority sections for a query
+func (s *Server) resolve(ctx context.Context, q query, client netip.Addr) ([]answer, []answer, dnsmessage.RCode, *Selection) {
+	name := strings.ToLower(q.question.Name.String())
+	if q.question.Class != dnsmessage.ClassINET && q.question.Class != dnsmessage.ClassANY {
+		return nil, nil, dnsmessage.RCodeRefused, nil
+	}
+	if name != s.config.Zone && !strings.HasSuffix(name, "."+s.config.Zone) {
+		return nil, nil, dnsmessage.RCodeRefused, nil
+	}
+
+	var answers []answer
+	if name == s.config.Zone {
+		switch q.question.Type {
+		case dnsmessage.TypeSOA:
+			answers = append(answers, s.soa())
+		case dnsmessage.TypeNS:
+			for _, nameserver := range s.config.Nameservers {
+				target, err := dnsmessage.NewName(canonicalName(nameserver))
+				if err != nil {
+					continue
+				}
+				answers = append(answers, answer{
+					header:   s.resourceHeader(s.config.Zone, dnsmessage.TypeNS, s.config.TTLSeconds*10),
+					resource: &dnsmessage.NSResource{NS: target},
+				})
+			}
+		}
+	}
+
+	record, exists := s.records[name]
+	if !exists {
+		if len(answers) > 0 || name == s.config.Zone {
+			return answers, negativeAuthority(answers, s.soa()), dnsmessage.RCodeSuccess, nil
+		}
+		return nil, []answer{s.soa()}, dnsmessage.RCodeNameError, nil
+	}
+
+	subnet := clientPrefix(client, q.ecs, s.config)
+	selection, err := s.selector.Select(ctx, record, subnet)
+	if err != nil {
+		s.logger.Warn("GSLB edge selection failed", "name", name, "error", err)
+		return nil, nil, dnsmessage.RCodeServerFailure, nil
+	}
+
+	answers = append(answers, s.endpointAnswers(name, q.question.Type, selection.Endpoint)...)
+	return answers, negativeAuthority(answers, s.soa()), dnsmessage.RCodeSuccess, selection
+}
+
+// endpointAnswers returns the records of an endpoint matching the query type, falling back to its CNAME
+func (s *Server) endpointAnswers(name string, queryType dnsmessage.Type, endpoint Endpoint) []answer {
+	var answers []answer
+
+	switch queryType {
+	case dnsmessage.TypeA, dnsmessage.TypeALL:
+		for _, address := range endpoint.IPv4 {
+			ip := netip.MustParseAddr(address)
+			answers = append(answers, answer{
+				header:   s.resourceHeader(name, dnsmessage.TypeA, s.config.TTLSeconds),
+				resource: &dnsmessage.AResource{A: ip.As4()},
+			})
+		}
+	}
+	switch queryType {
+	case dnsmessage.TypeAAAA, dnsmessage.TypeALL:
+		for _, address := range endpoint.IPv6 {
+			ip := netip.MustParseAddr(address)
+			answers = append(answers, answer{
+				header:   s.resourceHeader(name, dnsmessage.TypeAAAA, s.config.TTLSeconds),
+				resource: &dnsmessage.AAAAResource{AAAA: ip.As16()},
+			})
+		}
+	}
+
+	// A CNAME cannot coexist with other data, so only answer with it when there are no addresses
+	if len(answers) == 0 && endpoint.CNAME != "" {
+		switch queryType {
+		case dnsmessage.TypeA, dnsmessage.TypeAAAA, dnsmessage.TypeCNAME, dnsmessage.TypeALL:
+			if target, err := dnsmessage.NewName(canonicalName(endpoint.CNAME)); err == nil {
+				answers = append(answers, answer{
+					header:   s.resourceHeader(name, dnsmessage.TypeCNAME, s.config.TTLSeconds),
+					resource: &dnsmessage.CNAMEResource{CNAME: target},
+				})
+			}
+		}
+	}
+
+	return answers
+}
+
+// negativeAuthority returns the SOA for NODATA responses and nothing otherwise
+func negativeAuthority(answers []answer, soa answer) []answer {
+	if len(answers) > 0 {
+		return nil
+	}
+	return []answer{soa}
+}
+
+// soa returns the zone's SOA record; its minimum TTL caps negative caching at the answer TTL
+func (s *Server) soa() answer {
+	nameserver := "ns1." + s.config.Zone
+	if len(s.config.Nameservers) > 0 {
+		nameserver = canonicalName(s.config.Nameservers[0])
+	}
+
+	return answer{
+		header: s.resourceHeader(s.config.Zone, dnsmessage.TypeSOA, s.config.TTLSeconds),
+		resource: &dnsmessage.SOAResource{
+			NS:      dnsmessage.MustNewName(nameserver),
+			MBox:    dnsmessage.MustNewName("hostmaster." + s.config.Zone),
+			Serial:  uint32(time.Now().Unix()),
+			Refresh: 3600,
+			Retry:   600,
+			Expire:  86400,
+			MinTTL:  s.config.TTLSeconds,
+		},
+	}
+}
+
+// resourceHeader builds an IN-class header
+func (s *Server) resourceHeader(name string, recordType dnsmessage.Type, ttl uint32) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{
+		Name:  dnsmessage.MustNewName(name),
+		Type:  recordType,
+		Class: dnsmessage.ClassINET,
+		TTL:   ttl,
+	}
+}
+
+// build serializes a response, setting TC and dropping records if it exceeds maxSize
+func (s *Server) build(header dnsmessage.Header, q query, answers, authorities []answer, maxSize int) []byte {
+	response, err := s.serialize(header, q, answers, authorities)
+	if err == nil && len(response) <= maxSize {
+		return response
+	}
+
+	header.Truncated = true
+	response, err = s.serialize(header, q, nil, nil)
+	if err != nil {
+		s.logger.Error("failed to build DNS response", "error", err)
+		return nil
+	}
+	return response
+}
+
+// serialize writes a complete response message
+func (s *Server) serialize(header dnsmessage.Header, q query, answers, authorities []answer) ([]byte, error) {
+	builder := dnsmessage.NewBuilder(make([]byte, 0, 512), header)
+	builder.EnableCompression()
+
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if q.question.Name.Length > 0 {
+		if err := builder.Question(q.question); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := builder.StartAnswers(); err != nil {
+		return nil, err
+	}
+	for _, record := range answers {
+		if err := addResource(&builder, record); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := builder.StartAuthorities(); err != nil {
+		return nil, err
+	}
+	for _, record := range authorities {
+		if err := addResource(&builder, record); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := builder.StartAdditionals(); err != nil {
+		return nil, err
+	}
+	if q.edns {
+		var optHeader dnsmessage.ResourceHeader
+		if err := optHeader.SetEDNS0(maxEDNSUDPSize, header.RCode, false); err != nil {
+			return nil, err
+		}
+		var options []dnsmessage.Option
+		if q.ecs != nil {
+			options = append(options, q.ecs.option(s.config))
+		}
+		if err := builder.OPTResource(optHeader, dnsmessage.OPTResource{Options: options}); err != nil {
+			return nil, err
+		}
+	}
+
+	return builder.Finish()
+}
+
+// addResource writes one record with the builder method for its type
+func addResource(builder *dnsmessage.Builder, record answer) error {
+	switch resource := record.resource.(type) {
+	case *dnsmessage.AResource:
+		return builder.AResource(record.header, *resource)
+	case *dnsmessage.AAAAResource:
+		return builder.AAAAResource(record.header, *resource)
+	case *dnsmessage.CNAMEResource:
+		return builder.CNAMEResource(record.header, *resource)
+	case *dnsmessage.NSResource:
+		return builder.NSResource(record.header, *resource)
+	case *dnsmessage.SOAResource:
+		return builder.SOAResource(record.header, *resource)
+	default:
+		return fmt.Errorf("unsupported resource type %T", resource)
+	}
+}
+
+// parseQuery parses the header, single question and EDNS options of a query.
+// A non-zero RCode with an error means a response should still be sent.
+func parseQuery(message []byte) (query, dnsmessage.RCode, error) {
+	var q query
+	var parser dnsmessage.Parser
+
+	header, err := parser.Start(message)
+	if err != nil {
+		return q, 0, err
+	}
+	q.header = header
+	if header.Response {
+		return q, 0, errors.New("message is a response")
+	}
+	if header.OpCode != 0 {
+		return q, dnsmessage.RCodeNotImplemented, errors.New("unsupported opcode")
+	}
+
+	questions, err := parser.AllQuestions()
+	if err != nil || len(questions) != 1 {
+		return q, dnsmessage.RCodeFormatError, errors.New("expected exactly one question")
+	}
+	q.question = questions[0]
+
+	if err := parser.SkipAllAnswers(); err != nil {
+		return q, dnsmessage.RCodeFormatError, err
+	}
+	if err := parser.SkipAllAuthorities(); err != nil {
+		return q, dnsmessage.RCodeFormatError, err
+	}
+
+	for {
+		additional, err := parser.AdditionalHeader()
+		if errors.Is(err, dnsmessage.ErrSectionDone) {
+			break
+		}
+		if err != nil {
+			return q, dnsmessage.RCodeFormatError, err
+		}
+		if additional.Type != dnsmessage.TypeOPT {
+			if err := parser.SkipAdditional(); err != nil {
+				return q, dnsmessage.RCodeFormatError, err
+			}
+			continue
+		}
+
+		opt, err := parser.OPTResource()
+		if err != nil {
+			return q, dnsmessage.RCodeFormatError, err
+		}
+		q.edns = true
+		q.udpSize = int(additional.Class)
+		for _, option := range opt.Options {
+			if option.Code != optionCodeECS {
+				continue
+			}
+			ecs, err := parseClientSubnet(option.Data)
+			if err != nil {
+				return q, dnsmessage.RCodeFormatError, err
+			}
+			q.ecs = ecs
+		}
+	}
+
+	return q, dnsmessage.RCodeSuccess, nil
+}
+
+// parseClientSubnet decodes an ECS option payload (RFC 7871 section 6)
+func parseClientSubnet(data []byte) (*clientSubnet, error) {
+	if len(data) < 4 {
+		return nil, errors.New("ECS option too short")
+	}
+	family := binary.BigEndian.Uint16(data[0:2])
+	sourcePrefix := int(data[2])
+	address := data[4:]
+
+	var addressLength, maxPrefix int
+	switch family {
+	case 1:
+		addressLength, maxPrefix = 4, 32
+	case 2:
+		addressLength, maxPrefix = 16, 128
+	default:
+		return nil, fmt.Errorf("unsupported ECS family %d", family)
+	}
+	if sourcePrefix > maxPrefix || len(address) != (sourcePrefix+7)/8 {
+		return nil, errors.New("malformed ECS address")
+	}
+
+	padded := make([]byte, addressLength)
+	copy(padded, address)
+	ip, _ := netip.AddrFromSlice(padded)
+	prefix, err := ip.Prefix(sourcePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return &clientSubnet{family: family, sourcePrefix: sourcePrefix, prefix: prefix}, nil
+}
+
+// option encodes the ECS response option. The scope is the prefix length actually used
+// for the lookup, so resolvers can cache the answer for the whole subnet.
+func (c *clientSubnet) option(config Config) dnsmessage.Option {
+	scope := c.scope(config)
+	addressBytes := (c.sourcePrefix + 7) / 8
+	raw := c.prefix.Addr().AsSlice()
+
+	data := make([]byte, 4+addressBytes)
+	binary.BigEndian.PutUint16(data[0:2], c.family)
+	data[2] = byte(c.sourcePrefix)
+	data[3] = byte(scope)
+	copy(data[4:], raw[:addressBytes])
+
+	return dnsmessage.Option{Code: optionCodeECS, Data: data}
+}
+
+// scope is the source prefix capped at the configured maximum for its family
+func (c *clientSubnet) scope(config Config) int {
+	if c.family == 1 {
+		return min(c.sourcePrefix, config.ECSMaxPrefixIPv4)
+	}
+	return min(c.sourcePrefix, config.ECSMaxPrefixIPv6)
+}
+
+// clientPrefix returns the subnet used to locate the client: the ECS subnet if present,
+// otherwise the resolver address, truncated to the configured maximum prefix
+func clientPrefix(resolver netip.Addr, ecs *clientSubnet, config Config) netip.Prefix {
+	if ecs != nil && ecs.sourcePrefix > 0 {
+		prefix, _ := ecs.prefix.Addr().Prefix(ecs.scope(config))
+		return prefix
+	}
+	if !resolver.IsValid() {
+		return netip.Prefix{}
+	}
+
+	resolver = resolver.Unmap()
+	bits := config.ECSMaxPrefixIPv6
+	if resolver.Is4() {
+		bits = config.ECSMaxPrefixIPv4
+	}
+	prefix, _ := resolver.Prefix(bits)
+	return prefix
+}
+
+// addrIP extracts the IP address from a UDP or TCP address
+func addrIP(addr net.Addr) netip.Addr {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		ip, _ := netip.AddrFromSlice(a.IP)
+		return ip.Unmap()
+	case *net.TCPAddr:
+		ip, _ := netip.AddrFromSlice(a.IP)
+		return ip.Unmap()
+	}
+	return netip.Addr{}
+}
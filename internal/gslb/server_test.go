@@ -0,0 +1,278 @@
+package gslb
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+)
+
+// buildQuery builds a wire-format query, optionally with an ECS option
+func buildQuery(t *testing.T, name string, queryType dnsmessage.Type, ecs string) []byte {
+	t.Helper()
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 42, RecursionDesired: true})
+	builder.StartQuestions()
+	builder.Question(dnsmessage.Question{Name: dnsmessage.MustNewName(name), Type: queryType, Class: dnsmessage.ClassINET})
+	builder.StartAdditionals()
+
+	if ecs != "" {
+		prefix := netip.MustParsePrefix(ecs)
+		addressBytes := (prefix.Bits() + 7) / 8
+		data := make([]byte, 4+addressBytes)
+		binary.BigEndian.PutUint16(data, 1)
+		data[2] = byte(prefix.Bits())
+		copy(data[4:], prefix.Addr().AsSlice()[:addressBytes])
+
+		var optHeader dnsmessage.ResourceHeader
+		optHeader.SetEDNS0(1232, dnsmessage.RCodeSuccess, false)
+		builder.OPTResource(optHeader, dnsmessage.OPTResource{Options: []dnsmessage.Option{{Code: optionCodeECS, Data: data}}})
+	}
+
+	message, err := builder.Finish()
+	if err != nil {
+		t.Fatalf("Failed to build query: %v", err)
+	}
+	return message
+}
+
+func newTestServer() *Server {
+	config := testConfig()
+	config.Validate()
+	intensities := stubIntensities{
+		values:    map[string]float64{"DE": 400, "SE": 40, "FR": 60},
+		timestamp: time.Now(),
+	}
+	return NewServer(config, NewSelector(config, intensities, stubLocator{}, nil, testLogger()), testLogger())
+}
+
+func TestServerHandle(t *testing.T) {
+	server := newTestServer()
+
+	tests := []struct {
+		name          string
+		query         string
+		queryType     dnsmessage.Type
+		expectedRCode dnsmessage.RCode
+		expectedType  dnsmessage.Type
+		expectedCount int
+	}{
+		{"steered A", "www.gslb.example.com.", dnsmessage.TypeA, dnsmessage.RCodeSuccess, dnsmessage.TypeA, 1},
+		{"case insensitive", "WWW.gslb.example.com.", dnsmessage.TypeA, dnsmessage.RCodeSuccess, dnsmessage.TypeA, 1},
+		{"no AAAA at chosen edge", "www.gslb.example.com.", dnsmessage.TypeAAAA, dnsmessage.RCodeSuccess, 0, 0},
+		{"apex SOA", "gslb.example.com.", dnsmessage.TypeSOA, dnsmessage.RCodeSuccess, dnsmessage.TypeSOA, 1},
+		{"unknown name", "missing.gslb.example.com.", dnsmessage.TypeA, dnsmessage.RCodeNameError, 0, 0},
+		{"outside zone", "www.example.org.", dnsmessage.TypeA, dnsmessage.RCodeRefused, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := server.Handle(context.Background(), buildQuery(t, tt.query, tt.queryType, ""), netip.MustParseAddr("81.2.69.1"), false)
+
+			var message dnsmessage.Message
+			if err := message.Unpack(response); err != nil {
+				t.Fatalf("Failed to unpack response: %v", err)
+			}
+			if message.Header.ID != 42 || !message.Header.Response {
+				t.Errorf("Unexpected response header %+v", message.Header)
+			}
+			if message.Header.RCode != tt.expectedRCode {
+				t.Errorf("Expected rcode %v, got %v", tt.expectedRCode, message.Header.RCode)
+			}
+			if len(message.Answers) != tt.expectedCount {
+				t.Fatalf("Expected %d answers, got %d", tt.expectedCount, len(message.Answers))
+			}
+			if tt.expectedCount > 0 && message.Answers[0].Header.Type != tt.expectedType {
+				t.Errorf("Expected %v answer, got %v", tt.expectedType, message.Answers[0].Header.Type)
+			}
+		})
+	}
+}
+
+func TestServerHandleECS(t *testing.T) {
+	server := newTestServer()
+
+	response := server.Handle(context.Background(), buildQuery(t, "www.gslb.example.com.", dnsmessage.TypeA, "81.2.69.160/27"), netip.MustParseAddr("192.0.2.53"), false)
+
+	var message dnsmessage.Message
+	if err := message.Unpack(response); err != nil {
+		t.Fatalf("Failed to unpack response: %v", err)
+	}
+
+	a, ok := message.Answers[0].Body.(*dnsmessage.AResource)
+	if !ok || netip.AddrFrom4(a.A).String() != "192.0.2.20" {
+		t.Errorf("Expected stockholm address 192.0.2.20, got %v", message.Answers[0].Body)
+	}
+	if message.Answers[0].Header.TTL != 30 {
+		t.Errorf("Expected TTL 30, got %d", message.Answers[0].Header.TTL)
+	}
+
+	scope := -1
+	for _, additional := range message.Additionals {
+		if opt, ok := additional.Body.(*dnsmessage.OPTResource); ok {
+			for _, option := range opt.Options {
+				if option.Code == optionCodeECS {
+					scope = int(option.Data[3])
+				}
+			}
+		}
+	}
+	if scope != 24 {
+		t.Errorf("Expected ECS scope capped at 24, got %d", scope)
+	}
+}
+
+func TestServerTCP(t *testing.T) {
+	server := newTestServer()
+
+	packet, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(packet, listener) }()
+	defer func() {
+		server.Shutdown()
+		<-done
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	query := buildQuery(t, "www.gslb.example.com.", dnsmessage.TypeCNAME, "")
+	framed := append([]byte{byte(len(query) >> 8), byte(len(query))}, query...)
+	if _, err := conn.Write(framed); err != nil {
+		t.Fatalf("Failed to write query: %v", err)
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		t.Fatalf("Failed to read response length: %v", err)
+	}
+	response := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, response); err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+
+	var message dnsmessage.Message
+	if err := message.Unpack(response); err != nil {
+		t.Fatalf("Failed to unpack response: %v", err)
+	}
+	if message.Header.RCode != dnsmessage.RCodeSuccess || !message.Header.Authoritative {
+		t.Errorf("Expected authoritative NOERROR, got %+v", message.Header)
+	}
+}
+
+// blockingIntensities blocks every lookup until the context is done
+type blockingIntensities struct{}
+
+func (blockingIntensities) GetCarbonIntensity(ctx context.Context, zone string) (*carbon.CarbonIntensity, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestServerUDPQueryTimeout(t *testing.T) {
+	config := testConfig()
+	config.UDPWorkers = 1
+	config.QueryTimeoutMs = 50
+	config.Validate()
+	server := NewServer(config, NewSelector(config, blockingIntensities{}, stubLocator{}, nil, testLogger()), testLogger())
+
+	packet, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(packet, listener) }()
+	defer func() {
+		server.Shutdown()
+		<-done
+	}()
+
+	conn, err := net.Dial("udp", packet.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	// A single worker answers consecutive queries because stalled lookups are cut off
+	query := buildQuery(t, "www.gslb.example.com.", dnsmessage.TypeCNAME, "")
+	for i := 0; i < 3; i++ {
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		if _, err := conn.Write(query); err != nil {
+			t.Fatalf("Failed to write query: %v", err)
+		}
+		response := make([]byte, maxEDNSUDPSize)
+		n, err := conn.Read(response)
+		if err != nil {
+			t.Fatalf("Query %d: failed to read response: %v", i, err)
+		}
+
+		var message dnsmessage.Message
+		if err := message.Unpack(response[:n]); err != nil {
+			t.Fatalf("Failed to unpack response: %v", err)
+		}
+		if message.Header.ID != 42 {
+			t.Errorf("Expected response to query 42, got %d", message.Header.ID)
+		}
+	}
+}
+
+func TestServerTCPConnectionLimit(t *testing.T) {
+	config := testConfig()
+	config.TCPConnections = 1
+	config.Validate()
+	server := NewServer(config, NewSelector(config, stubIntensities{}, stubLocator{}, nil, testLogger()), testLogger())
+
+	packet, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(packet, listener) }()
+	defer func() {
+		server.Shutdown()
+		<-done
+	}()
+
+	// The first connection holds the only slot without sending a query
+	held, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer held.Close()
+
+	rejected, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer rejected.Close()
+	rejected.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err := rejected.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Expected the connection beyond the limit to be closed, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+)
+
+// AdminHandler handles administrative endpoints
+type AdminHandler struct {
+	cdnCatalog *carbon.CDNCatalog
+	logger     *slog.Logger
+	config     *Config
+}
+
+// NewAdminHandler creates a new admin handler with dependencies
+func NewAdminHandler(deps *Dependencies) *AdminHandler {
+	return &AdminHandler{
+		cdnCatalog: deps.CDNCatalog,
+		logger:     deps.Logger,
+		config:     deps.Config,
+	}
+}
+
+// AdminAuthMiddleware requires a matching bearer token on admin endpoints. The
+// Authorization header must use the Bearer scheme; a bare token is rejected.
+func AdminAuthMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, provided, found := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			provided = ""
+		}
+		if token == "" || provided == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			RespondWithError(c, http.StatusUnauthorized,
+				"Admin authentication required",
+				"UNAUTHORIZED",
+				nil)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// HandleGetCDNCatalog returns the status of the loaded CDN edge catalog
+// @Summary Get CDN catalog status
+// @Description Returns the catalog files in use, when the catalog was loaded and how many providers and edge locations it contains
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} carbon.CDNCatalogStatus
+// @Failure 401 {object} map[string]interface{}
+// @Router /v1/admin/cdn-catalog [get]
+func (h *AdminHandler) HandleGetCDNCatalog(c *gin.Context) {
+	const operation = "get_cdn_catalog"
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{})
+
+	status := h.cdnCatalog.Status()
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"providers":      status.Providers,
+		"edge_locations": status.EdgeLocations,
+	})
+
+	c.JSON(http.StatusOK, status)
+}
+
+// HandleReloadCDNCatalog reloads the CDN edge catalog from its configured files
+// @Summary Reload CDN catalog
+// @Description Re-reads and validates the configured catalog files and merges them on top of the built-in providers. The previous catalog stays active if validation fails.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} carbon.CDNCatalogStatus
+// @Failure 401 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/admin/cdn-catalog/reload [post]
+func (h *AdminHandler) HandleReloadCDNCatalog(c *gin.Context) {
+	const operation = "reload_cdn_catalog"
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{})
+
+	status, err := h.cdnCatalog.Reload()
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		code := "CATALOG_RELOAD_ERROR"
+		if errors.Is(err, carbon.ErrInvalidCDNCatalog) {
+			statusCode = http.StatusUnprocessableEntity
+			code = "INVALID_CATALOG"
+		}
+
+		h.logger.Error("failed to reload CDN catalog",
+			"error", err,
+			"operation", operation)
+
+		RespondWithError(c, statusCode,
+			"Failed to reload CDN catalog",
+			code,
+			map[string]string{
+				"reason": err.Error(),
+			})
+
+		LogResponse(h.logger, operation, statusCode, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	h.logger.Info("CDN catalog reloaded",
+		"sources", status.Sources,
+		"providers", status.Providers,
+		"edge_locations", status.EdgeLocations,
+		"operation", operation)
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"providers":      status.Providers,
+		"edge_locations": status.EdgeLocations,
+	})
+
+	c.JSON(http.StatusOK, status)
+}
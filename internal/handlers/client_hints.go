@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+)
+
+// Client hint request headers used for optimization profiles
+const (
+	HeaderSecCHUAMobile             = "Sec-CH-UA-Mobile"
+	HeaderECT                       = "ECT"
+	HeaderDownlink                  = "Downlink"
+	HeaderRTT                       = "RTT"
+	HeaderDeviceMemory              = "Device-Memory"
+	HeaderSaveData                  = "Save-Data"
+	HeaderSecCHPrefersReducedMotion = "Sec-CH-Prefers-Reduced-Motion"
+)
+
+// acceptCHHeaders lists the client hints requested from browsers via Accept-CH.
+// Save-Data is sent by browsers without opt-in and is therefore only listed in Vary.
+var acceptCHHeaders = []string{
+	HeaderSecCHUAMobile,
+	HeaderECT,
+	HeaderDownlink,
+	HeaderRTT,
+	HeaderDeviceMemory,
+	HeaderSecCHPrefersReducedMotion,
+}
+
+// varyHeaders lists the client hints that take a handful of values, so shared caches
+// can keep one entry per value without fragmenting.
+var varyHeaders = []string{
+	HeaderSecCHUAMobile,
+	HeaderECT,
+	HeaderSaveData,
+	HeaderSecCHPrefersReducedMotion,
+}
+
+// unbucketedHintHeaders lists the client hints with continuous values. Varying on them
+// would give every client its own cache entry, so responses to requests carrying them
+// are kept out of shared caches instead.
+var unbucketedHintHeaders = []string{
+	HeaderDownlink,
+	HeaderRTT,
+	HeaderDeviceMemory,
+}
+
+// SetClientHintsHeaders advertises the client hints we use and tells caches how to store
+// responses that depend on them
+func SetClientHintsHeaders(c *gin.Context) {
+	c.Header("Accept-CH", strings.Join(acceptCHHeaders, ", "))
+	c.Writer.Header().Add("Vary", strings.Join(varyHeaders, ", "))
+	if hasUnbucketedHints(c.Request) {
+		c.Header("Cache-Control", "private")
+	}
+}
+
+// clientHintsCacheControl returns the Cache-Control value for a response that depends on
+// client hints and may be cached for maxAge seconds
+func clientHintsCacheControl(r *http.Request, maxAge int) string {
+	scope := "public"
+	if hasUnbucketedHints(r) {
+		scope = "private"
+	}
+	return scope + ", max-age=" + strconv.Itoa(maxAge)
+}
+
+// hasUnbucketedHints reports whether the request carries a continuous-valued client hint
+func hasUnbucketedHints(r *http.Request) bool {
+	for _, header := range unbucketedHintHeaders {
+		if r.Header.Get(header) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseClientHints extracts client hints from request headers.
+// It returns nil if the request carries none of the supported hints.
+func ParseClientHints(r *http.Request) *optimization.ClientHints {
+	hints := &optimization.ClientHints{}
+	found := false
+
+	if value := r.Header.Get(HeaderSecCHUAMobile); value != "" {
+		// Structured header boolean: ?1 or ?0
+		hints.Mobile = strings.TrimSpace(value) == "?1"
+		found = true
+	}
+
+	if value := strings.ToLower(strings.TrimSpace(r.Header.Get(HeaderECT))); value != "" {
+		switch value {
+		case "slow-2g", "2g", "3g", "4g":
+			hints.EffectiveConnectionType = value
+			found = true
+		}
+	}
+
+	if value := r.Header.Get(HeaderDownlink); value != "" {
+		if downlink, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && downlink >= 0 {
+			hints.Downlink = downlink
+			found = true
+		}
+	}
+
+	if value := r.Header.Get(HeaderRTT); value != "" {
+		if rtt, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && rtt >= 0 {
+			hints.RTT = rtt
+			found = true
+		}
+	}
+
+	if value := r.Header.Get(HeaderDeviceMemory); value != "" {
+		if memory, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && memory > 0 {
+			hints.DeviceMemory = memory
+			found = true
+		}
+	}
+
+	if value := r.Header.Get(HeaderSaveData); value != "" {
+		hints.SaveData = strings.EqualFold(strings.TrimSpace(value), "on")
+		found = true
+	}
+
+	if value := r.Header.Get(HeaderSecCHPrefersReducedMotion); value != "" {
+		hints.PrefersReducedMotion = strings.EqualFold(strings.Trim(strings.TrimSpace(value), `"`), "reduce")
+		found = true
+	}
+
+	if !found {
+		return nil
+	}
+	return hints
+}
+
+// clientHintsCacheKey returns a cache key fragment for the hints that affect profile generation
+func clientHintsCacheKey(hints *optimization.ClientHints) string {
+	if hints == nil {
+		return "nohints"
+	}
+
+	network := "fast"
+	if hints.IsSlowNetwork() {
+		network = "slow"
+	} else if hints.IsConstrainedNetwork() {
+		network = "constrained"
+	}
+
+	return fmt.Sprintf("m%t:n%s:d%t:s%t:r%t",
+		hints.Mobile, network, hints.IsLowEndDevice(), hints.SaveData, hints.PrefersReducedMotion)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+)
+
+func TestParseClientHints(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected *optimization.ClientHints
+	}{
+		{"no hints", nil, nil},
+		{"unsupported ECT only", map[string]string{"ECT": "5g"}, nil},
+		{"2G with Save-Data", map[string]string{"ECT": "2G", "Save-Data": "on"},
+			&optimization.ClientHints{EffectiveConnectionType: "2g", SaveData: true}},
+		{"fiber", map[string]string{"ECT": "4g", "Downlink": "250", "RTT": "5", "Device-Memory": "8"},
+			&optimization.ClientHints{EffectiveConnectionType: "4g", Downlink: 250, RTT: 5, DeviceMemory: 8}},
+		{"mobile with reduced motion", map[string]string{"Sec-CH-UA-Mobile": "?1", "Sec-CH-Prefers-Reduced-Motion": `"reduce"`},
+			&optimization.ClientHints{Mobile: true, PrefersReducedMotion: true}},
+		{"invalid numbers are ignored", map[string]string{"Downlink": "-1", "RTT": "fast", "Device-Memory": "0", "Sec-CH-UA-Mobile": "?0"},
+			&optimization.ClientHints{}},
+		{"Save-Data off", map[string]string{"Save-Data": "off"}, &optimization.ClientHints{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/optimization", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+
+			hints := ParseClientHints(req)
+			if tt.expected == nil {
+				if hints != nil {
+					t.Errorf("Expected no hints, got %+v", hints)
+				}
+				return
+			}
+			if hints == nil || *hints != *tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, hints)
+			}
+		})
+	}
+}
+
+func TestSetClientHintsHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		headers      map[string]string
+		cacheControl string
+	}{
+		{"bucketed hints stay shareable", map[string]string{"ECT": "2g", "Save-Data": "on", "Sec-CH-UA-Mobile": "?1"}, "public, max-age=60"},
+		{"downlink keeps the response private", map[string]string{"ECT": "4g", "Downlink": "9.5"}, "private, max-age=60"},
+		{"device memory keeps the response private", map[string]string{"Device-Memory": "4"}, "private, max-age=60"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/api/v1/optimization", nil)
+			for name, value := range tt.headers {
+				c.Request.Header.Set(name, value)
+			}
+
+			SetClientHintsHeaders(c)
+
+			vary := w.Header().Get("Vary")
+			for _, header := range []string{"Downlink", "RTT", "Device-Memory"} {
+				if strings.Contains(vary, header) {
+					t.Errorf("Expected no Vary on %s, got %q", header, vary)
+				}
+			}
+			for _, header := range []string{"ECT", "Save-Data", "Sec-CH-UA-Mobile"} {
+				if !strings.Contains(vary, header) {
+					t.Errorf("Expected Vary on %s, got %q", header, vary)
+				}
+			}
+			if cacheControl := clientHintsCacheControl(c.Request, 60); cacheControl != tt.cacheControl {
+				t.Errorf("Expected %q, got %q", tt.cacheControl, cacheControl)
+			}
+			if private := w.Header().Get("Cache-Control") == "private"; private != strings.HasPrefix(tt.cacheControl, "private") {
+				t.Errorf("Expected private responses only for continuous hints, got Cache-Control %q", w.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
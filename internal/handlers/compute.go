@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+)
+
+// ComputeHandler handles carbon-aware placement of deferrable compute workloads
+type ComputeHandler struct {
+	electricityService ElectricityMapsService
+	logger             *slog.Logger
+	config             *Config
+}
+
+// NewComputeHandler creates a new compute handler with dependencies
+func NewComputeHandler(deps *Dependencies) *ComputeHandler {
+	return &ComputeHandler{
+		electricityService: deps.ElectricityMaps,
+		logger:             deps.Logger,
+		config:             deps.Config,
+	}
+}
+
+// HandleRecommendComputeRegion recommends a cloud region and start time for a batch job
+// @Summary Recommend a region and start time for deferrable compute
+// @Description Evaluates every AWS, GCP and Azure region meeting the residency and provider constraints at every hourly start time that finishes by the deadline, and returns the placement with the lowest expected emissions compared to running in the default region now
+// @Tags compute
+// @Accept json
+// @Produce json
+// @Param workload body carbon.ComputeWorkload true "Compute workload"
+// @Success 200 {object} carbon.ComputePlacementResult
+// @Failure 400 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/compute/placement [post]
+func (h *ComputeHandler) HandleRecommendComputeRegion(c *gin.Context) {
+	const operation = "recommend_compute_region"
+
+	var workload carbon.ComputeWorkload
+	if err := c.ShouldBindJSON(&workload); err != nil {
+		RespondWithValidationErrors(c, []ValidationError{{
+			Field:   "body",
+			Message: "invalid JSON body: " + err.Error(),
+		}})
+		return
+	}
+
+	var allErrors []ValidationError
+
+	if workload.EnergyKWh <= 0 {
+		allErrors = append(allErrors, ValidationError{Field: "energy_kwh", Message: "energy_kwh must be positive"})
+	}
+	if workload.DurationHours <= 0 || workload.DurationHours > carbon.MaxComputeHorizon.Hours() {
+		allErrors = append(allErrors, ValidationError{Field: "duration_hours", Message: "duration_hours must be positive and at most 168"})
+	}
+	if workload.Deadline.IsZero() {
+		allErrors = append(allErrors, ValidationError{Field: "deadline", Message: "deadline is required"})
+	} else if !workload.Deadline.After(time.Now()) {
+		allErrors = append(allErrors, ValidationError{Field: "deadline", Message: "deadline must be in the future"})
+	}
+	for _, provider := range workload.Providers {
+		if _, exists := carbon.CloudRegionProviders[strings.ToLower(strings.TrimSpace(provider))]; !exists {
+			allErrors = append(allErrors, ValidationError{Field: "providers", Message: "provider must be one of: aws, gcp, azure", Value: provider})
+		}
+	}
+	if workload.DefaultRegion != "" {
+		if _, err := carbon.FindCloudRegion(workload.DefaultRegion); err != nil {
+			allErrors = append(allErrors, ValidationError{Field: "default_region", Message: err.Error(), Value: workload.DefaultRegion})
+		}
+	}
+
+	if len(allErrors) > 0 {
+		RespondWithValidationErrors(c, allErrors)
+		return
+	}
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"energy_kwh":     workload.EnergyKWh,
+		"duration_hours": workload.DurationHours,
+		"deadline":       workload.Deadline,
+		"residency":      workload.Residency,
+		"providers":      workload.Providers,
+	})
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	result, err := h.electricityService.RecommendComputeRegion(ctx, workload)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		code := "COMPUTE_PLACEMENT_ERROR"
+		if errors.Is(err, carbon.ErrNoPlacement) {
+			statusCode = http.StatusUnprocessableEntity
+			code = "NO_PLACEMENT"
+		}
+
+		h.logger.Error("failed to recommend compute region",
+			"error", err,
+			"operation", operation)
+
+		RespondWithError(c, statusCode,
+			"Failed to recommend compute region",
+			code,
+			map[string]string{
+				"reason": err.Error(),
+			})
+
+		LogResponse(h.logger, operation, statusCode, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"best_region":     result.Best.Provider + "/" + result.Best.Region,
+		"best_start":      result.Best.Start,
+		"savings_percent": result.SavingsPercent,
+	})
+
+	c.JSON(http.StatusOK, result)
+}
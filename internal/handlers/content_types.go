@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+)
+
+// ContentTypeHandler handles built-in and tenant-registered content types
+type ContentTypeHandler struct {
+	registry *carbon.ContentTypeRegistry
+	logger   *slog.Logger
+	config   *Config
+}
+
+// NewContentTypeHandler creates a new content type handler with dependencies
+func NewContentTypeHandler(deps *Dependencies) *ContentTypeHandler {
+	return &ContentTypeHandler{
+		registry: carbon.DefaultContentTypes,
+		logger:   deps.Logger,
+		config:   deps.Config,
+	}
+}
+
+// ContentTypesResponse lists the content types available to a tenant
+type ContentTypesResponse struct {
+	Builtin map[string]carbon.ContentTypeWeights `json:"builtin"`
+	Tenant  []carbon.TenantContentType           `json:"tenant"`
+}
+
+// HandleListContentTypes lists built-in content types and a tenant's custom types
+// @Summary List content types
+// @Description Returns the built-in content types with their transmission/computation weights and typical request profile, plus custom types registered by the given tenant (none if omitted)
+// @Tags content-types
+// @Produce json
+// @Param tenant query string false "Tenant" example("acme")
+// @Success 200 {object} ContentTypesResponse
+// @Router /v1/content-types [get]
+func (h *ContentTypeHandler) HandleListContentTypes(c *gin.Context) {
+	const operation = "list_content_types"
+
+	tenant := c.Query("tenant")
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"tenant": tenant,
+	})
+
+	response := ContentTypesResponse{
+		Builtin: make(map[string]carbon.ContentTypeWeights),
+		Tenant:  h.registry.TenantTypes(tenant),
+	}
+	for _, name := range h.registry.BuiltinNames() {
+		response.Builtin[name], _ = h.registry.Lookup(name)
+	}
+	if response.Tenant == nil {
+		response.Tenant = []carbon.TenantContentType{}
+	}
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"builtin": len(response.Builtin),
+		"tenant":  len(response.Tenant),
+	})
+
+	c.JSON(http.StatusOK, response)
+}
+
+// HandleRegisterContentType registers or replaces a tenant's custom content type
+// @Summary Register a tenant content type
+// @Description Registers a custom content type referenced as "tenant/name" in content_type parameters. Weights must sum to 100; the request profile is used for per-request emissions.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param tenant path string true "Tenant" example("acme")
+// @Param name path string true "Content type name" example("thumbnails")
+// @Param weights body carbon.ContentTypeWeights true "Weights and request profile"
+// @Success 200 {object} carbon.TenantContentType
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /v1/admin/content-types/{tenant}/{name} [put]
+func (h *ContentTypeHandler) HandleRegisterContentType(c *gin.Context) {
+	const operation = "register_content_type"
+
+	tenant, name := c.Param("tenant"), c.Param("name")
+
+	var weights carbon.ContentTypeWeights
+	if err := c.ShouldBindJSON(&weights); err != nil {
+		RespondWithValidationErrors(c, []ValidationError{{
+			Field:   "body",
+			Message: "invalid JSON body: " + err.Error(),
+		}})
+		return
+	}
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"tenant": tenant,
+		"name":   name,
+	})
+
+	if err := h.registry.Register(tenant, name, weights); err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, carbon.ErrInvalidContentType) {
+			statusCode = http.StatusBadRequest
+		}
+
+		RespondWithError(c, statusCode,
+			"Failed to register content type",
+			"INVALID_CONTENT_TYPE",
+			map[string]string{
+				"reason": err.Error(),
+			})
+
+		LogResponse(h.logger, operation, statusCode, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	h.logger.Info("content type registered",
+		"tenant", tenant,
+		"name", name,
+		"operation", operation)
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"content_type": carbon.QualifiedContentType(tenant, name),
+	})
+
+	c.JSON(http.StatusOK, carbon.TenantContentType{
+		Tenant:      tenant,
+		Name:        name,
+		ContentType: carbon.QualifiedContentType(tenant, name),
+		Weights:     weights,
+	})
+}
+
+// HandleDeleteContentType removes a tenant's custom content type
+// @Summary Delete a tenant content type
+// @Tags admin
+// @Security BearerAuth
+// @Param tenant path string true "Tenant" example("acme")
+// @Param name path string true "Content type name" example("thumbnails")
+// @Success 204
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/admin/content-types/{tenant}/{name} [delete]
+func (h *ContentTypeHandler) HandleDeleteContentType(c *gin.Context) {
+	const operation = "delete_content_type"
+
+	tenant, name := c.Param("tenant"), c.Param("name")
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"tenant": tenant,
+		"name":   name,
+	})
+
+	if !h.registry.Unregister(tenant, name) {
+		RespondWithError(c, http.StatusNotFound,
+			"Content type not found",
+			"CONTENT_TYPE_NOT_FOUND",
+			map[string]string{
+				"content_type": carbon.QualifiedContentType(tenant, name),
+			})
+
+		LogResponse(h.logger, operation, http.StatusNotFound, map[string]interface{}{})
+		return
+	}
+
+	LogResponse(h.logger, operation, http.StatusNoContent, map[string]interface{}{})
+
+	c.Status(http.StatusNoContent)
+}
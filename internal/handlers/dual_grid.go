@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -60,6 +61,7 @@ func (h *DualGridHandler) HandleGetDualGridCarbonIntensity(c *gin.Context) {
 
 	// If user location not provided, detect from IP
 	var userLocation string
+	var selectionUser *carbon.EdgeSelectionUser
 	if userLocationParam == "" {
 		// Detect user location from IP
 		dualLocation, err := h.geolocationService.GetDualLocationFromRequest(c.Request.Context(), c.Request, cdnProviderParam)
@@ -67,6 +69,8 @@ func (h *DualGridHandler) HandleGetDualGridCarbonIntensity(c *gin.Context) {
 			h.logger.Error("Failed to detect user location", "error", err)
 			userLocation = "Berlin" // Default fallback
 		} else {
+			user := dualLocation.UserLocation.EdgeSelectionUser()
+			selectionUser = &user
 			userLocation = dualLocation.UserLocation.Location.City
 			if userLocation == "" {
 				userLocation = dualLocation.UserLocation.Location.Country
@@ -127,11 +131,18 @@ func (h *DualGridHandler) HandleGetDualGridCarbonIntensity(c *gin.Context) {
 	// Create context with timeout
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
 	defer cancel()
+	if selectionUser != nil {
+		ctx = carbon.WithEdgeSelectionUser(ctx, *selectionUser)
+	}
 
 	// Try to get from cache first
 	var cacheKey string
 	if h.cacheService != nil {
 		cacheKey = "dual_grid:" + userLocation + ":" + edgeLocation + ":" + contentType
+		if selectionUser != nil {
+			// Latency is estimated for the client's own prefix
+			cacheKey += ":" + selectionUser.Prefix
+		}
 		if cached, found := h.cacheService.Get(cacheKey); found {
 			h.logger.Info("serving dual grid data from cache",
 				"user_location", userLocation,
@@ -233,12 +244,15 @@ func (h *DualGridHandler) HandleGetOptimalEdgeLocation(c *gin.Context) {
 
 	// If user location not provided, detect from IP
 	var userLocation string
+	var selectionUser *carbon.EdgeSelectionUser
 	if userLocationParam == "" {
 		dualLocation, err := h.geolocationService.GetDualLocationFromRequest(c.Request.Context(), c.Request, cdnProviderParam)
 		if err != nil {
 			h.logger.Error("Failed to detect user location", "error", err)
 			userLocation = "Berlin"
 		} else {
+			user := dualLocation.UserLocation.EdgeSelectionUser()
+			selectionUser = &user
 			userLocation = dualLocation.UserLocation.Location.City
 		}
 	} else {
@@ -281,6 +295,9 @@ func (h *DualGridHandler) HandleGetOptimalEdgeLocation(c *gin.Context) {
 	// Create context with timeout
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
+	if selectionUser != nil {
+		ctx = carbon.WithEdgeSelectionUser(ctx, *selectionUser)
+	}
 
 	// Get optimal edge location
 	optimalEdge, err := h.electricityService.GetOptimalEdgeLocation(ctx, userLocation, cdnProvider, contentType)
@@ -320,7 +337,7 @@ func (h *DualGridHandler) HandleGetOptimalEdgeLocation(c *gin.Context) {
 
 // HandleGetCDNAlternatives returns alternative edge locations with better carbon characteristics
 // @Summary Get CDN alternatives
-// @Description Returns alternative edge locations for a CDN provider ranked by carbon efficiency
+// @Description Returns alternative edge locations for a CDN provider ranked by carbon efficiency. Edges whose grid data is stale or synthetic (mock fallback) rank behind edges with live data and carry a reason; edges failing health probes are excluded.
 // @Tags dual-grid
 // @Accept json
 // @Produce json
@@ -329,6 +346,7 @@ func (h *DualGridHandler) HandleGetOptimalEdgeLocation(c *gin.Context) {
 // @Param cdn_provider query string true "CDN provider"
 // @Param content_type query string false "Content type" default(static)
 // @Param max_results query int false "Maximum number of alternatives to return" default(5)
+// @Param include_excluded query bool false "Append greener edges excluded for failing health probes or untrusted carbon data, with the reason" default(false)
 // @Success 200 {array} carbon.EdgeAlternative
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
@@ -342,6 +360,7 @@ func (h *DualGridHandler) HandleGetCDNAlternatives(c *gin.Context) {
 	cdnProviderParam := c.DefaultQuery("cdn_provider", "")
 	contentTypeParam := c.DefaultQuery("content_type", "static")
 	maxResultsParam := c.DefaultQuery("max_results", "5")
+	includeExcluded := c.Query("include_excluded") == "true"
 
 	// Validate parameters
 	var allErrors []ValidationError
@@ -362,12 +381,15 @@ func (h *DualGridHandler) HandleGetCDNAlternatives(c *gin.Context) {
 
 	// If user location not provided, detect from IP
 	var userLocation string
+	var selectionUser *carbon.EdgeSelectionUser
 	if userLocationParam == "" {
 		dualLocation, err := h.geolocationService.GetDualLocationFromRequest(c.Request.Context(), c.Request, cdnProviderParam)
 		if err != nil {
 			h.logger.Error("Failed to detect user location", "error", err)
 			userLocation = "Berlin"
 		} else {
+			user := dualLocation.UserLocation.EdgeSelectionUser()
+			selectionUser = &user
 			userLocation = dualLocation.UserLocation.Location.City
 		}
 	} else {
@@ -409,9 +431,12 @@ func (h *DualGridHandler) HandleGetCDNAlternatives(c *gin.Context) {
 	// Create context with timeout
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
 	defer cancel()
+	if selectionUser != nil {
+		ctx = carbon.WithEdgeSelectionUser(ctx, *selectionUser)
+	}
 
 	// Get CDN alternatives
-	alternatives, err := h.electricityService.GetCDNAlternatives(ctx, userLocation, currentEdgeParam, cdnProvider, contentType, maxResults)
+	result, err := h.electricityService.EvaluateCDNAlternatives(ctx, userLocation, currentEdgeParam, cdnProvider, contentType, maxResults)
 	if err != nil {
 		h.logger.Error("failed to get CDN alternatives",
 			"error", err,
@@ -431,18 +456,25 @@ func (h *DualGridHandler) HandleGetCDNAlternatives(c *gin.Context) {
 		return
 	}
 
+	alternatives := result.Alternatives
+	if includeExcluded {
+		alternatives = append(alternatives, result.Excluded...)
+	}
+
 	// Log successful response
 	h.logger.Info("CDN alternatives retrieved",
 		"user_location", userLocation,
 		"current_edge", currentEdgeParam,
 		"cdn_provider", cdnProvider,
-		"alternatives_count", len(alternatives),
+		"alternatives_count", len(result.Alternatives),
+		"excluded_count", len(result.Excluded),
 		"operation", operation)
 
 	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
 		"user_location":      userLocation,
 		"current_edge":       currentEdgeParam,
-		"alternatives_count": len(alternatives),
+		"alternatives_count": len(result.Alternatives),
+		"excluded_count":     len(result.Excluded),
 	})
 
 	c.JSON(http.StatusOK, alternatives)
@@ -491,4 +523,298 @@ func (h *DualGridHandler) HandleGetSupportedCDNProviders(c *gin.Context) {
 	})
 
 	c.JSON(http.StatusOK, response)
-}
\ No newline at end of file
+}
+
+// GeolocationCacheResponse reports geolocation lookup cache metrics
+type GeolocationCacheResponse struct {
+	Enabled bool                    `json:"enabled"`
+	Stats   *geolocation.CacheStats `json:"stats,omitempty"`
+}
+
+// HandleGetGeolocationCacheStats returns hit-rate metrics for the geolocation lookup cache
+// @Summary Get geolocation cache statistics
+// @Description Returns entry counts and hit, shared hit, miss, coalesced and eviction counters for the in-process geolocation lookup cache
+// @Tags dual-grid
+// @Accept json
+// @Produce json
+// @Success 200 {object} GeolocationCacheResponse
+// @Router /v1/dual-grid/geolocation-cache [get]
+func (h *DualGridHandler) HandleGetGeolocationCacheStats(c *gin.Context) {
+	const operation = "get_geolocation_cache_stats"
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{})
+
+	response := GeolocationCacheResponse{}
+	if stats, enabled := h.geolocationService.CacheStats(); enabled {
+		response.Enabled = true
+		response.Stats = &stats
+	}
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"enabled": response.Enabled,
+	})
+
+	c.JSON(http.StatusOK, response)
+}
+
+// HandleSelectEdgeMultiObjective selects an edge by latency SLO, egress price and carbon intensity
+// @Summary Multi-objective edge selection
+// @Description Evaluates every edge of a CDN provider on RTT (model or measured), egress price and dual-grid weighted carbon intensity. Returns the Pareto-optimal edges within the latency SLO, the point chosen with the supplied weights and how it trades off against each single-objective optimum. The user location is detected from the request IP when not supplied.
+// @Tags dual-grid
+// @Accept json
+// @Produce json
+// @Param request body carbon.EdgeSelectionRequest true "Edge selection request"
+// @Success 200 {object} carbon.EdgeSelectionResult
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/dual-grid/edge-selection [post]
+func (h *DualGridHandler) HandleSelectEdgeMultiObjective(c *gin.Context) {
+	const operation = "select_edge_multi_objective"
+
+	var req carbon.EdgeSelectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondWithValidationErrors(c, []ValidationError{{
+			Field:   "body",
+			Message: "invalid JSON body: " + err.Error(),
+		}})
+		return
+	}
+
+	var allErrors []ValidationError
+
+	if req.CDNProvider == "" {
+		allErrors = append(allErrors, ValidationError{Field: "cdn_provider", Message: "cdn_provider is required"})
+	}
+	_, cdnProviderErrors := geolocation.ValidateCDNProvider(req.CDNProvider)
+	for _, err := range cdnProviderErrors {
+		allErrors = append(allErrors, ValidationError{Field: "cdn_provider", Message: err})
+	}
+
+	if req.ContentType == "" {
+		req.ContentType = "static"
+	}
+	_, contentTypeErrors := geolocation.ValidateContentType(req.ContentType)
+	for _, err := range contentTypeErrors {
+		allErrors = append(allErrors, ValidationError{Field: "content_type", Message: err})
+	}
+
+	if req.LatencySLOMs < 0 {
+		allErrors = append(allErrors, ValidationError{Field: "latency_slo_ms", Message: "latency_slo_ms must not be negative"})
+	}
+	if req.Weights.Latency < 0 || req.Weights.Cost < 0 || req.Weights.Carbon < 0 {
+		allErrors = append(allErrors, ValidationError{Field: "weights", Message: "weights must not be negative"})
+	}
+	if req.User.Latitude < -90 || req.User.Latitude > 90 || req.User.Longitude < -180 || req.User.Longitude > 180 {
+		allErrors = append(allErrors, ValidationError{Field: "user", Message: "user coordinates out of range"})
+	}
+	for edgeID, price := range req.EgressPricePerGB {
+		if price < 0 {
+			allErrors = append(allErrors, ValidationError{Field: "egress_price_per_gb", Message: "prices must not be negative", Value: edgeID})
+		}
+	}
+
+	if len(allErrors) > 0 {
+		RespondWithValidationErrors(c, allErrors)
+		return
+	}
+
+	h.resolveEdgeSelectionUser(c, &req.User)
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"cdn_provider":   req.CDNProvider,
+		"user_zone":      req.User.GridZone,
+		"latency_slo_ms": req.LatencySLOMs,
+		"weights":        req.Weights,
+	})
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	result, err := h.electricityService.SelectEdgeMultiObjective(ctx, req)
+	if err != nil {
+		h.logger.Error("failed to select edge",
+			"error", err,
+			"cdn_provider", req.CDNProvider,
+			"operation", operation)
+
+		RespondWithError(c, http.StatusInternalServerError,
+			"Failed to select edge location",
+			"EDGE_SELECTION_ERROR",
+			map[string]string{
+				"cdn_provider": req.CDNProvider,
+			})
+		return
+	}
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"cdn_provider":  req.CDNProvider,
+		"chosen":        result.Chosen.EdgeID,
+		"pareto_front":  len(result.ParetoFront),
+		"slo_satisfied": result.SLOSatisfied,
+	})
+
+	c.JSON(http.StatusOK, result)
+}
+
+// resolveEdgeSelectionUser detects the user from the request IP when no location was supplied
+func (h *DualGridHandler) resolveEdgeSelectionUser(c *gin.Context, user *carbon.EdgeSelectionUser) {
+	if user.Latitude == 0 && user.Longitude == 0 && user.GridZone == "" {
+		userLocation, err := h.geolocationService.GetLocationFromRequest(c.Request.Context(), c.Request)
+		if err != nil {
+			h.logger.Error("Failed to detect user location", "error", err)
+			userLocation = geolocation.LocationWithZone{Location: geolocation.DefaultLocation, GridZone: geolocation.DefaultGridZone, Source: geolocation.LocationSourceDefault}
+		}
+		*user = userLocation.EdgeSelectionUser()
+	}
+	if user.GridZone == "" {
+		user.GridZone = geolocation.DefaultGridZone.Zone
+	}
+}
+
+// TrafficPlanHTTPRequest is a traffic plan request with load balancer export options
+type TrafficPlanHTTPRequest struct {
+	carbon.TrafficPlanRequest
+
+	// Export controls pool naming and edge addresses for load balancer formats
+	Export carbon.TrafficPlanExportOptions `json:"export"`
+}
+
+// HandlePlanTrafficShift computes a day-ahead traffic-shifting plan
+// @Summary Plan hourly CDN traffic weights
+// @Description Builds an hourly weight table that shifts a user population's traffic to the edges with the lowest forecast dual-grid intensity, within each edge's capacity share and the p95 latency SLO. Returns JSON, or weighted-pool config snippets per hour for nginx, HAProxy, Envoy or Route 53 when format is set.
+// @Tags dual-grid
+// @Accept json
+// @Produce json
+// @Produce plain
+// @Param format query string false "Output format: json, nginx, haproxy, envoy, route53" default(json)
+// @Param request body TrafficPlanHTTPRequest true "Traffic plan request"
+// @Success 200 {object} carbon.TrafficPlan
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/dual-grid/traffic-plan [post]
+func (h *DualGridHandler) HandlePlanTrafficShift(c *gin.Context) {
+	const operation = "plan_traffic_shift"
+
+	var req TrafficPlanHTTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondWithValidationErrors(c, []ValidationError{{
+			Field:   "body",
+			Message: "invalid JSON body: " + err.Error(),
+		}})
+		return
+	}
+
+	var allErrors []ValidationError
+
+	format := strings.ToLower(c.DefaultQuery("format", carbon.TrafficPlanFormatJSON))
+	validFormat := false
+	for _, supported := range carbon.TrafficPlanFormats {
+		validFormat = validFormat || format == supported
+	}
+	if !validFormat {
+		allErrors = append(allErrors, ValidationError{
+			Field:   "format",
+			Message: "format must be one of: " + strings.Join(carbon.TrafficPlanFormats, ", "),
+			Value:   format,
+		})
+	}
+
+	if req.CDNProvider == "" {
+		allErrors = append(allErrors, ValidationError{Field: "cdn_provider", Message: "cdn_provider is required"})
+	}
+	_, cdnProviderErrors := geolocation.ValidateCDNProvider(req.CDNProvider)
+	for _, err := range cdnProviderErrors {
+		allErrors = append(allErrors, ValidationError{Field: "cdn_provider", Message: err})
+	}
+
+	if req.ContentType == "" {
+		req.ContentType = "static"
+	}
+	_, contentTypeErrors := geolocation.ValidateContentType(req.ContentType)
+	for _, err := range contentTypeErrors {
+		allErrors = append(allErrors, ValidationError{Field: "content_type", Message: err})
+	}
+
+	if req.LatencySLOMs < 0 {
+		allErrors = append(allErrors, ValidationError{Field: "latency_slo_ms", Message: "latency_slo_ms must not be negative"})
+	}
+	if req.Hours < 0 || req.Hours > 168 {
+		allErrors = append(allErrors, ValidationError{Field: "hours", Message: "hours must be between 1 and 168", Value: strconv.Itoa(req.Hours)})
+	}
+	if req.MinSharePercent < 0 || req.MinSharePercent > 100 {
+		allErrors = append(allErrors, ValidationError{Field: "min_share_percent", Message: "min_share_percent must be between 0 and 100"})
+	}
+	for capacity, share := range req.CapacityShares {
+		if share <= 0 || share > 1 {
+			allErrors = append(allErrors, ValidationError{Field: "capacity_shares", Message: "capacity shares must be in (0, 1]", Value: capacity})
+		}
+	}
+	if req.User.Latitude < -90 || req.User.Latitude > 90 || req.User.Longitude < -180 || req.User.Longitude > 180 {
+		allErrors = append(allErrors, ValidationError{Field: "user", Message: "user coordinates out of range"})
+	}
+
+	if len(allErrors) > 0 {
+		RespondWithValidationErrors(c, allErrors)
+		return
+	}
+
+	h.resolveEdgeSelectionUser(c, &req.User)
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"cdn_provider":   req.CDNProvider,
+		"user_zone":      req.User.GridZone,
+		"latency_slo_ms": req.LatencySLOMs,
+		"hours":          req.Hours,
+		"format":         format,
+	})
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	plan, err := h.electricityService.PlanTrafficShift(ctx, req.TrafficPlanRequest)
+	if err != nil {
+		h.logger.Error("failed to plan traffic shift",
+			"error", err,
+			"cdn_provider", req.CDNProvider,
+			"operation", operation)
+
+		RespondWithError(c, http.StatusInternalServerError,
+			"Failed to plan traffic shift",
+			"TRAFFIC_PLAN_ERROR",
+			map[string]string{
+				"cdn_provider": req.CDNProvider,
+				"reason":       err.Error(),
+			})
+		return
+	}
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"cdn_provider":      req.CDNProvider,
+		"hours":             len(plan.Hours),
+		"reduction_percent": plan.ReductionPercent,
+		"slo_satisfied":     plan.SLOSatisfied,
+	})
+
+	if format == carbon.TrafficPlanFormatJSON && req.Export.At.IsZero() {
+		c.JSON(http.StatusOK, plan)
+		return
+	}
+
+	body, err := carbon.ExportTrafficPlan(plan, format, req.Export)
+	if err != nil {
+		RespondWithError(c, http.StatusBadRequest,
+			"Failed to export traffic plan",
+			"TRAFFIC_PLAN_EXPORT_ERROR",
+			map[string]string{
+				"format": format,
+				"reason": err.Error(),
+			})
+		return
+	}
+
+	contentType := "text/plain; charset=utf-8"
+	if format == carbon.TrafficPlanFormatJSON || format == carbon.TrafficPlanFormatRoute53 {
+		contentType = "application/json; charset=utf-8"
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
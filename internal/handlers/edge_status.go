@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/perschulte/greenweb-api/internal/edgehealth"
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+)
+
+// EdgeStatusHandler reports grid zone data freshness and edge health probe results
+type EdgeStatusHandler struct {
+	electricityService ElectricityMapsService
+	prober             *edgehealth.Prober
+	logger             *slog.Logger
+}
+
+// NewEdgeStatusHandler creates a new edge status handler with dependencies
+func NewEdgeStatusHandler(deps *Dependencies) *EdgeStatusHandler {
+	return &EdgeStatusHandler{
+		electricityService: deps.ElectricityMaps,
+		prober:             deps.EdgeProber,
+		logger:             deps.Logger,
+	}
+}
+
+// EdgeStatusResponse lists carbon data freshness per grid zone and health per probed edge
+type EdgeStatusResponse struct {
+	Zones  []carbon.DataFreshness `json:"zones"`
+	Probes []edgehealth.Status    `json:"probes"`
+}
+
+// HandleGetEdgeStatus returns data freshness and edge health
+// @Summary Get edge data freshness and health
+// @Description Returns the source and age of the latest carbon reading per grid zone, and active health probe results per edge when probes are configured
+// @Tags dual-grid
+// @Produce json
+// @Param provider query string false "Only probes of this CDN provider" example("cloudflare")
+// @Success 200 {object} EdgeStatusResponse
+// @Router /v1/edges/status [get]
+func (h *EdgeStatusHandler) HandleGetEdgeStatus(c *gin.Context) {
+	const operation = "get_edge_status"
+
+	provider := strings.ToLower(c.Query("provider"))
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"provider": provider,
+	})
+
+	response := EdgeStatusResponse{
+		Zones:  h.electricityService.DataFreshness(),
+		Probes: []edgehealth.Status{},
+	}
+	if h.prober != nil {
+		for _, status := range h.prober.All() {
+			if provider == "" || status.Provider == provider {
+				response.Probes = append(response.Probes, status)
+			}
+		}
+	}
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"zones":  len(response.Zones),
+		"probes": len(response.Probes),
+	})
+
+	c.JSON(http.StatusOK, response)
+}
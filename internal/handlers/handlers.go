@@ -8,10 +8,15 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/perschulte/greenweb-api/internal/geolocation"
+	"github.com/perschulte/greenweb-api/internal/edgehealth"
+	"github.com/perschulte/greenweb-api/internal/impact"
+	"github.com/perschulte/greenweb-api/internal/latency"
 	"github.com/perschulte/greenweb-api/pkg/carbon"
+	"github.com/perschulte/greenweb-api/pkg/optimization"
 )
 
 // ElectricityMapsService defines the interface for electricity maps operations
@@ -23,11 +28,25 @@ type ElectricityMapsService interface {
 	GetDualGridCarbonIntensity(ctx context.Context, userLocation, edgeLocation, contentType string) (*carbon.DualGridCarbonIntensity, error)
 	GetOptimalEdgeLocation(ctx context.Context, userLocation, cdnProvider, contentType string) (*carbon.EdgeAlternative, error)
 	GetCDNAlternatives(ctx context.Context, userLocation, currentEdgeLocation, cdnProvider, contentType string, maxAlternatives int) ([]carbon.EdgeAlternative, error)
+	EvaluateCDNAlternatives(ctx context.Context, userLocation, currentEdgeLocation, cdnProvider, contentType string, maxAlternatives int) (*carbon.CDNAlternatives, error)
+	DataFreshness() []carbon.DataFreshness
+	SelectEdgeMultiObjective(ctx context.Context, req carbon.EdgeSelectionRequest) (*carbon.EdgeSelectionResult, error)
+	PlanTrafficShift(ctx context.Context, req carbon.TrafficPlanRequest) (*carbon.TrafficPlan, error)
+	// Compute placement
+	RecommendComputeRegion(ctx context.Context, workload carbon.ComputeWorkload) (*carbon.ComputePlacementResult, error)
 }
 
 // OptimizationService defines the interface for optimization operations
 type OptimizationService interface {
-	// TODO: Define optimization methods when implemented
+	GetOptimizationProfile(ctx context.Context, req optimization.OptimizationRequest) (*optimization.OptimizationResponse, error)
+}
+
+// OptimizationAnalyticsService defines the interface for optimization analytics operations.
+// An OptimizationService that also implements it enables the analytics endpoints.
+type OptimizationAnalyticsService interface {
+	TrackOptimizationApplication(ctx context.Context, profile *optimization.OptimizationProfile, metadata map[string]interface{}) error
+	GetOptimizationStats(ctx context.Context, location string, timeRange optimization.TimeRange) (*optimization.OptimizationStats, error)
+	GetEnergySavingsReport(ctx context.Context, location string, timeRange optimization.TimeRange) (*optimization.EnergySavingsReport, error)
 }
 
 // CarbonIntelligenceService defines the interface for carbon intelligence operations
@@ -49,6 +68,7 @@ type Config struct {
 	Version          string
 	ServiceName      string
 	ElectricityAPIKey string
+	AdminToken       string // Bearer token for admin endpoints, admin endpoints are disabled if empty
 }
 
 // Dependencies holds all services that handlers depend on
@@ -57,6 +77,14 @@ type Dependencies struct {
 	Optimization          OptimizationService
 	CarbonIntelligence    CarbonIntelligenceService // Optional, can be nil for fallback
 	Cache                 CacheService // Optional, can be nil
+	ProfileSigner         *optimization.ProfileSigner // Optional, enables signed profile tokens
+	ImageTranscoder       ImageTranscoder // Optional, enables the image proxy endpoint
+	CDNCatalog            *carbon.CDNCatalog // Optional, enables the CDN catalog admin endpoints
+	LatencyMatrix         *latency.Matrix // Optional, enables measured RTT ingestion and the latency matrix
+	EdgeProber            *edgehealth.Prober // Optional, adds edge health probe results to the edge status endpoint
+	Impact                *impact.Service // Optional, enables the impact audit endpoints
+	OptimizationRules     OptimizationRulesService // Optional, enables the optimization rule admin endpoints
+	Webhooks              carbon.CarbonWebhookService // Optional, enables the webhook registration admin endpoints
 	Logger                *slog.Logger
 	Config                *Config
 }
@@ -280,6 +308,77 @@ func ValidateDays(daysParam string, defaultDays int, maxDays int) (int, []Valida
 	return days, errors
 }
 
+// ValidateTimeRange validates start and end parameters (RFC3339 or YYYY-MM-DD).
+// Missing values default to the last defaultDays days ending now.
+func ValidateTimeRange(startParam, endParam string, defaultDays int, maxDays int) (optimization.TimeRange, []ValidationError) {
+	var errors []ValidationError
+	
+	end := time.Now()
+	if endParam != "" {
+		parsed, err := parseTimeParam(endParam)
+		if err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "end",
+				Message: "end parameter must be an RFC3339 timestamp or YYYY-MM-DD date",
+				Value:   endParam,
+			})
+		} else {
+			end = parsed
+			if len(endParam) == len("2006-01-02") {
+				// Date-only end values include the whole day
+				end = end.Add(24*time.Hour - time.Nanosecond)
+			}
+		}
+	}
+	
+	start := end.AddDate(0, 0, -defaultDays)
+	if startParam != "" {
+		parsed, err := parseTimeParam(startParam)
+		if err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "start",
+				Message: "start parameter must be an RFC3339 timestamp or YYYY-MM-DD date",
+				Value:   startParam,
+			})
+		} else {
+			start = parsed
+		}
+	}
+	
+	if len(errors) > 0 {
+		return optimization.TimeRange{}, errors
+	}
+	
+	if !start.Before(end) {
+		errors = append(errors, ValidationError{
+			Field:   "start",
+			Message: "start must be before end",
+			Value:   startParam,
+		})
+		return optimization.TimeRange{}, errors
+	}
+	
+	if end.Sub(start) > time.Duration(maxDays)*24*time.Hour {
+		errors = append(errors, ValidationError{
+			Field:   "start",
+			Message: "time range exceeds maximum allowed span",
+			Value:   startParam,
+		})
+		return optimization.TimeRange{}, errors
+	}
+	
+	return optimization.TimeRange{Start: start, End: end}, errors
+}
+
+// parseTimeParam parses an RFC3339 timestamp or a YYYY-MM-DD date in UTC
+func parseTimeParam(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
 // LogRequest logs incoming requests with relevant details
 func LogRequest(logger *slog.Logger, c *gin.Context, operation string, params map[string]interface{}) {
 	logger.Info("handling request",
@@ -313,8 +412,44 @@ func RegisterHandlers(r *gin.Engine, deps *Dependencies, dualGridGeoService *geo
 	// Create handler instances
 	healthHandler := NewHealthHandler(deps)
 	carbonHandler := NewCarbonSimpleHandler(deps)
+	computeHandler := NewComputeHandler(deps)
 	demoHandler := NewDemoHandler(deps)
 	
+	// Create optimization handler if optimization service is provided
+	var optimizationHandler *OptimizationHandler
+	if deps.Optimization != nil {
+		optimizationHandler = NewOptimizationHandler(deps)
+	}
+	
+	// Create image handler if both optimization and transcoding services are provided
+	var imageHandler *ImageHandler
+	if deps.Optimization != nil && deps.ImageTranscoder != nil {
+		imageHandler = NewImageHandler(deps)
+	}
+	
+	// Create admin handler if an admin token and something to administer are configured
+	var adminHandler *AdminHandler
+	if deps.Config != nil && deps.Config.AdminToken != "" && deps.CDNCatalog != nil {
+		adminHandler = NewAdminHandler(deps)
+	}
+	
+	contentTypeHandler := NewContentTypeHandler(deps)
+	rulesHandler := NewRulesHandler(deps)
+	
+	// Create latency handler if a measured RTT matrix is provided
+	var latencyHandler *LatencyHandler
+	if deps.LatencyMatrix != nil {
+		latencyHandler = NewLatencyHandler(deps)
+	}
+	
+	// Create impact handler if an impact service is provided
+	var impactHandler *ImpactHandler
+	if deps.Impact != nil {
+		impactHandler = NewImpactHandler(deps)
+	}
+	
+	edgeStatusHandler := NewEdgeStatusHandler(deps)
+	
 	// Create dual-grid handler if geolocation service is provided
 	var dualGridHandler *DualGridHandler
 	if dualGridGeoService != nil {
@@ -323,6 +458,9 @@ func RegisterHandlers(r *gin.Engine, deps *Dependencies, dualGridGeoService *geo
 	
 	// Register routes
 	r.GET("/health", healthHandler.HandleHealthCheck)
+	if optimizationHandler != nil && optimizationHandler.profileSigner != nil {
+		r.GET("/.well-known/jwks.json", optimizationHandler.HandleGetJWKS)
+	}
 	
 	// API v1 routes
 	v1 := r.Group("/api/v1")
@@ -331,6 +469,33 @@ func RegisterHandlers(r *gin.Engine, deps *Dependencies, dualGridGeoService *geo
 		v1.GET("/carbon-intensity", carbonHandler.HandleGetCarbonIntensity)
 		v1.GET("/green-hours", carbonHandler.HandleGetGreenHours)
 		
+		// Content type endpoints
+		v1.GET("/content-types", contentTypeHandler.HandleListContentTypes)
+		
+		// Compute placement endpoint
+		v1.POST("/compute/placement", computeHandler.HandleRecommendComputeRegion)
+		
+		// Optimization endpoints (if available)
+		if optimizationHandler != nil {
+			optimizationGroup := v1.Group("/optimization")
+			{
+				optimizationGroup.GET("", optimizationHandler.HandleGetOptimizationProfile)
+				if optimizationHandler.analyticsService != nil {
+					optimizationGroup.POST("/applications", optimizationHandler.HandleTrackOptimizationApplication)
+					optimizationGroup.GET("/stats", optimizationHandler.HandleGetOptimizationStats)
+					optimizationGroup.GET("/savings-report", optimizationHandler.HandleGetEnergySavingsReport)
+				}
+				if optimizationHandler.profileSigner != nil {
+					optimizationGroup.GET("/token", optimizationHandler.HandleIssueProfileToken)
+					optimizationGroup.GET("/jwks", optimizationHandler.HandleGetJWKS)
+				}
+			}
+		}
+		
+		// Image proxy endpoint (if available)
+		if imageHandler != nil {
+			v1.GET("/images", imageHandler.HandleGetImage)
+		}
 		
 		// Dual-grid endpoints (if available)
 		if dualGridHandler != nil {
@@ -340,6 +505,61 @@ func RegisterHandlers(r *gin.Engine, deps *Dependencies, dualGridGeoService *geo
 				dualGrid.GET("/optimal-edge", dualGridHandler.HandleGetOptimalEdgeLocation)
 				dualGrid.GET("/cdn-alternatives", dualGridHandler.HandleGetCDNAlternatives)
 				dualGrid.GET("/cdn-providers", dualGridHandler.HandleGetSupportedCDNProviders)
+				dualGrid.GET("/geolocation-cache", dualGridHandler.HandleGetGeolocationCacheStats)
+				dualGrid.POST("/edge-selection", dualGridHandler.HandleSelectEdgeMultiObjective)
+				dualGrid.POST("/traffic-plan", dualGridHandler.HandlePlanTrafficShift)
+			}
+		}
+		
+		// Edge carbon data freshness and health probe status
+		v1.GET("/edges/status", edgeStatusHandler.HandleGetEdgeStatus)
+		
+		// Latency matrix endpoints (if available); ingestion requires the admin token
+		if latencyHandler != nil {
+			latencyGroup := v1.Group("/latency")
+			{
+				latencyGroup.GET("/matrix", latencyHandler.HandleGetLatencyMatrix)
+				if deps.Config != nil && deps.Config.AdminToken != "" {
+					latencyGroup.POST("/measurements", AdminAuthMiddleware(deps.Config.AdminToken), latencyHandler.HandleIngestMeasurements)
+				}
+			}
+		}
+		
+		// Impact audit endpoints (if available); Lighthouse imports store baselines and
+		// require the admin token
+		if impactHandler != nil {
+			impactGroup := v1.Group("/impact")
+			{
+				impactGroup.POST("/har-audit", impactHandler.HandleAuditHAR)
+				impactGroup.GET("/trends", impactHandler.HandleGetPageTrend)
+				if deps.Config != nil && deps.Config.AdminToken != "" {
+					impactGroup.POST("/lighthouse", AdminAuthMiddleware(deps.Config.AdminToken), impactHandler.HandleImportLighthouse)
+				}
+			}
+		}
+		
+		// Admin endpoints (if configured)
+		if deps.Config != nil && deps.Config.AdminToken != "" {
+			admin := v1.Group("/admin", AdminAuthMiddleware(deps.Config.AdminToken))
+			{
+				if adminHandler != nil {
+					admin.GET("/cdn-catalog", adminHandler.HandleGetCDNCatalog)
+					admin.POST("/cdn-catalog/reload", adminHandler.HandleReloadCDNCatalog)
+				}
+				admin.PUT("/content-types/:tenant/:name", contentTypeHandler.HandleRegisterContentType)
+				admin.DELETE("/content-types/:tenant/:name", contentTypeHandler.HandleDeleteContentType)
+				if deps.OptimizationRules != nil {
+					admin.GET("/optimization-rules", rulesHandler.HandleListRules)
+					admin.POST("/optimization-rules", rulesHandler.HandleCreateRule)
+					admin.GET("/optimization-rules/:id", rulesHandler.HandleGetRule)
+					admin.PUT("/optimization-rules/:id", rulesHandler.HandleUpdateRule)
+					admin.DELETE("/optimization-rules/:id", rulesHandler.HandleDeleteRule)
+				}
+				if deps.Webhooks != nil {
+					admin.GET("/webhooks", rulesHandler.HandleListWebhooks)
+					admin.POST("/webhooks", rulesHandler.HandleRegisterWebhook)
+					admin.DELETE("/webhooks/:id", rulesHandler.HandleUnregisterWebhook)
+				}
 			}
 		}
 	}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/perschulte/greenweb-api/internal/store"
+	"github.com/perschulte/greenweb-api/service"
+)
+
+// routeSet registers the handlers for deps and returns the mounted "METHOD path" pairs
+func routeSet(deps *Dependencies) map[string]bool {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	RegisterHandlers(r, deps, nil)
+
+	routes := make(map[string]bool)
+	for _, route := range r.Routes() {
+		routes[route.Method+" "+route.Path] = true
+	}
+	return routes
+}
+
+func TestRegisterHandlersOptimizationAnalytics(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	plain := service.NewOptimizationService(&service.MockElectricityMapsClient{}, logger)
+	analyticsRoutes := []string{
+		"POST /api/v1/optimization/applications",
+		"GET /api/v1/optimization/stats",
+		"GET /api/v1/optimization/savings-report",
+	}
+
+	tests := []struct {
+		name         string
+		optimization OptimizationService
+		mounted      bool
+	}{
+		{"analytics service", service.NewOptimizationAnalyticsService(plain, nil, logger), true},
+		{"plain service", plain, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes := routeSet(&Dependencies{
+				Optimization: tt.optimization,
+				Logger:       logger,
+				Config:       &Config{ServiceName: "test"},
+			})
+			if !routes["GET /api/v1/optimization"] {
+				t.Fatal("Expected the optimization profile route to be mounted")
+			}
+			for _, route := range analyticsRoutes {
+				if routes[route] != tt.mounted {
+					t.Errorf("Expected %s mounted: %v, got %v", route, tt.mounted, routes[route])
+				}
+			}
+		})
+	}
+}
+
+func TestRegisterHandlersRulesAndWebhooks(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	rules, _ := store.NewRuleStore(nil)
+	webhooks, _ := store.NewWebhookStore(nil)
+	optimizationService := service.NewOptimizationService(&service.MockElectricityMapsClient{}, logger)
+	optimizationService.SetRuleStore(rules)
+	adminRoutes := []string{
+		"GET /api/v1/admin/optimization-rules",
+		"POST /api/v1/admin/optimization-rules",
+		"PUT /api/v1/admin/optimization-rules/:id",
+		"DELETE /api/v1/admin/optimization-rules/:id",
+		"POST /api/v1/admin/webhooks",
+		"DELETE /api/v1/admin/webhooks/:id",
+	}
+
+	tests := []struct {
+		name       string
+		adminToken string
+		withStores bool
+		mounted    bool
+	}{
+		{"stores with admin token", "secret", true, true},
+		{"no admin token", "", true, false},
+		{"no stores", "secret", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := &Dependencies{
+				Optimization: optimizationService,
+				Logger:       logger,
+				Config:       &Config{ServiceName: "test", AdminToken: tt.adminToken},
+			}
+			if tt.withStores {
+				deps.OptimizationRules = optimizationService
+				deps.Webhooks = webhooks
+			}
+			routes := routeSet(deps)
+			for _, route := range adminRoutes {
+				if routes[route] != tt.mounted {
+					t.Errorf("Expected %s mounted: %v, got %v", route, tt.mounted, routes[route])
+				}
+			}
+		})
+	}
+
+	t.Run("invalid rules and webhooks are rejected", func(t *testing.T) {
+		r := gin.New()
+		RegisterHandlers(r, &Dependencies{
+			Optimization:      optimizationService,
+			OptimizationRules: optimizationService,
+			Webhooks:          webhooks,
+			Logger:            logger,
+			Config:            &Config{ServiceName: "test", AdminToken: "secret"},
+		}, nil)
+
+		requests := []struct {
+			method, path, body string
+			statusCode         int
+		}{
+			{"POST", "/api/v1/admin/optimization-rules", `{"name":"no conditions","actions":[{"type":"disable_feature","target":"chat"}]}`, http.StatusBadRequest},
+			{"GET", "/api/v1/admin/optimization-rules/missing", "", http.StatusNotFound},
+			{"POST", "/api/v1/admin/webhooks", `{"url":"ftp://example.com","events":["intensity_change"]}`, http.StatusBadRequest},
+			{"POST", "/api/v1/admin/webhooks", `{"url":"https://example.com/hook","events":["intensity_change"]}`, http.StatusCreated},
+		}
+		for _, req := range requests {
+			httpReq := httptest.NewRequest(req.method, req.path, strings.NewReader(req.body))
+			httpReq.Header.Set("Authorization", "Bearer secret")
+			httpReq.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httpReq)
+			if w.Code != req.statusCode {
+				t.Errorf("Expected %d for %s %s, got %d: %s", req.statusCode, req.method, req.path, w.Code, w.Body.String())
+			}
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/perschulte/greenweb-api/internal/imaging"
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+)
+
+// ImageTranscoder defines the interface for carbon-adaptive image transcoding
+type ImageTranscoder interface {
+	Transcode(ctx context.Context, source string, profile *optimization.OptimizationProfile, format string) (*imaging.Result, error)
+}
+
+// ImageHandler handles the carbon-adaptive image proxy endpoint
+type ImageHandler struct {
+	optimizationService OptimizationService
+	transcoder          ImageTranscoder
+	logger              *slog.Logger
+	config              *Config
+}
+
+// NewImageHandler creates a new image handler with dependencies
+func NewImageHandler(deps *Dependencies) *ImageHandler {
+	return &ImageHandler{
+		optimizationService: deps.Optimization,
+		transcoder:          deps.ImageTranscoder,
+		logger:              deps.Logger,
+		config:              deps.Config,
+	}
+}
+
+// HandleGetImage re-encodes an image for the current optimization profile of a location
+// @Summary Carbon-adaptive image proxy
+// @Description Fetches an image from a URL or local path and re-encodes it to the quality, dimensions and size limit of the current optimization profile
+// @Tags images
+// @Produce image/jpeg,image/png
+// @Param src query string true "Source image URL (http/https) or path below the configured local root"
+// @Param location query string false "Location" default(Berlin)
+// @Param format query string false "Output format (auto, jpeg, png)" default(auto)
+// @Success 200 {file} binary
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+// @Router /v1/images [get]
+func (h *ImageHandler) HandleGetImage(c *gin.Context) {
+	const operation = "get_image"
+
+	// Responses depend on client hints, so advertise them and vary caches on them
+	SetClientHintsHeaders(c)
+
+	sourceParam := strings.TrimSpace(c.Query("src"))
+	locationParam := c.DefaultQuery("location", "Berlin")
+	formatParam := strings.ToLower(c.DefaultQuery("format", imaging.FormatAuto))
+
+	var allErrors []ValidationError
+	if sourceParam == "" {
+		allErrors = append(allErrors, ValidationError{
+			Field:   "src",
+			Message: "src parameter is required",
+		})
+	} else if len(sourceParam) > 2048 {
+		allErrors = append(allErrors, ValidationError{
+			Field:   "src",
+			Message: "src parameter too long (max 2048 characters)",
+		})
+	}
+
+	location, locationErrors := ValidateLocation(locationParam)
+	allErrors = append(allErrors, locationErrors...)
+
+	switch formatParam {
+	case imaging.FormatAuto, imaging.FormatJPEG, imaging.FormatPNG:
+	case "jpg":
+		formatParam = imaging.FormatJPEG
+	default:
+		allErrors = append(allErrors, ValidationError{
+			Field:   "format",
+			Message: "format must be one of: auto, jpeg, png",
+			Value:   formatParam,
+		})
+	}
+
+	if len(allErrors) > 0 {
+		RespondWithValidationErrors(c, allErrors)
+		LogResponse(h.logger, operation, http.StatusBadRequest, map[string]interface{}{
+			"src":      sourceParam,
+			"location": locationParam,
+			"errors":   allErrors,
+		})
+		return
+	}
+
+	hints := ParseClientHints(c.Request)
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"src":      sourceParam,
+		"location": location,
+		"format":   formatParam,
+	})
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	response, err := h.optimizationService.GetOptimizationProfile(ctx, optimization.OptimizationRequest{
+		Location:    location,
+		ClientHints: hints,
+	})
+	if err != nil {
+		h.logger.Error("failed to get optimization profile for image",
+			"error", err,
+			"location", location,
+			"operation", operation)
+
+		RespondWithError(c, http.StatusInternalServerError,
+			"Failed to generate optimization profile",
+			"OPTIMIZATION_ERROR",
+			map[string]string{
+				"location": location,
+			})
+
+		LogResponse(h.logger, operation, http.StatusInternalServerError, map[string]interface{}{
+			"location": location,
+			"error":    err.Error(),
+		})
+		return
+	}
+	profile := response.Optimization
+
+	result, err := h.transcoder.Transcode(ctx, sourceParam, profile, formatParam)
+	if err != nil {
+		statusCode := http.StatusBadGateway
+		code := "TRANSCODE_ERROR"
+		switch {
+		case errors.Is(err, imaging.ErrSourceNotAllowed):
+			statusCode = http.StatusForbidden
+			code = "SOURCE_NOT_ALLOWED"
+		case errors.Is(err, imaging.ErrSourceTooLarge):
+			statusCode = http.StatusRequestEntityTooLarge
+			code = "SOURCE_TOO_LARGE"
+		}
+
+		h.logger.Error("failed to transcode image",
+			"error", err,
+			"src", sourceParam,
+			"operation", operation)
+
+		RespondWithError(c, statusCode,
+			"Failed to transcode image",
+			code,
+			map[string]string{
+				"src": sourceParam,
+			})
+
+		LogResponse(h.logger, operation, statusCode, map[string]interface{}{
+			"src":   sourceParam,
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Cache downstream until the profile expires
+	if maxAge := int(time.Until(profile.ValidUntil).Seconds()); maxAge > 0 {
+		c.Header("Cache-Control", clientHintsCacheControl(c.Request, maxAge))
+	}
+	c.Header("X-GreenWeb-Mode", string(result.Mode))
+	c.Header("X-GreenWeb-Original-Bytes", strconv.Itoa(result.OriginalBytes))
+	if result.Cached {
+		c.Header("X-Cache", "HIT")
+	} else {
+		c.Header("X-Cache", "MISS")
+	}
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"src":            sourceParam,
+		"mode":           result.Mode,
+		"original_bytes": result.OriginalBytes,
+		"output_bytes":   len(result.Data),
+		"cached":         result.Cached,
+	})
+
+	c.Data(http.StatusOK, result.ContentType, result.Data)
+}
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/perschulte/greenweb-api/internal/impact"
+)
+
+// maxHARUploadBytes bounds the size of a HAR upload
+const maxHARUploadBytes = 50 << 20
+
+// maxLighthouseUploadBytes bounds the size of a Lighthouse report upload
+const maxLighthouseUploadBytes = 20 << 20
+
+// ImpactHandler handles impact measurement endpoints
+type ImpactHandler struct {
+	impactService *impact.Service
+	logger        *slog.Logger
+	config        *Config
+}
+
+// NewImpactHandler creates a new impact handler with dependencies
+func NewImpactHandler(deps *Dependencies) *ImpactHandler {
+	return &ImpactHandler{
+		impactService: deps.Impact,
+		logger:        deps.Logger,
+		config:        deps.Config,
+	}
+}
+
+// HandleAuditHAR audits the page weight recorded in a HAR file
+// @Summary Audit page weight from a HAR file
+// @Description Accepts a HAR file as the request body or as the "file" field of a multipart form. Returns bytes by MIME type, first- versus third-party and cache hits, the heaviest resources, the baseline measurement (not stored), the page load impact and the savings each optimization mode would bring.
+// @Tags impact
+// @Accept json
+// @Accept mpfd
+// @Produce json
+// @Param device_type query string false "Device type: smartphone, laptop or desktop" example("smartphone")
+// @Param connection_type query string false "Connection type" example("mobile_4g")
+// @Param region query string false "Region for grid intensity" example("EU")
+// @Param methodology query string false "Emissions model: greenweb, swd or onebyte" example("swd")
+// @Param top query int false "Number of heaviest resources to return (1-100)" example(10)
+// @Success 200 {object} impact.HARAudit
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/impact/har-audit [post]
+func (h *ImpactHandler) HandleAuditHAR(c *gin.Context) {
+	const operation = "audit_har"
+
+	params := impact.HARAuditParams{
+		DeviceType:     c.Query("device_type"),
+		ConnectionType: c.Query("connection_type"),
+		Region:         c.Query("region"),
+		Methodology:    impact.CalculationMethodology(strings.ToLower(c.Query("methodology"))),
+	}
+
+	var validationErrors []ValidationError
+	if top := c.Query("top"); top != "" {
+		value, err := strconv.Atoi(top)
+		if err != nil || value < 1 || value > 100 {
+			validationErrors = append(validationErrors, ValidationError{
+				Field:   "top",
+				Message: "top must be an integer between 1 and 100",
+				Value:   top,
+			})
+		}
+		params.TopResources = value
+	}
+	switch params.Methodology {
+	case "", impact.MethodologyGreenWeb, impact.MethodologySWD, impact.MethodologyOneByte:
+	default:
+		validationErrors = append(validationErrors, ValidationError{
+			Field:   "methodology",
+			Message: "methodology must be one of: greenweb, swd, onebyte",
+			Value:   string(params.Methodology),
+		})
+	}
+	if len(validationErrors) > 0 {
+		RespondWithValidationErrors(c, validationErrors)
+		return
+	}
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"content_type": c.ContentType(),
+		"region":       params.Region,
+		"methodology":  params.Methodology,
+	})
+
+	body, closeBody, err := uploadedFile(c, maxHARUploadBytes)
+	if err != nil {
+		RespondWithError(c, http.StatusBadRequest,
+			"Missing HAR file",
+			"INVALID_HAR",
+			map[string]string{
+				"reason": err.Error(),
+			})
+
+		LogResponse(h.logger, operation, http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	defer closeBody()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	audit, err := h.impactService.AuditHAR(ctx, body, params)
+	if err != nil {
+		statusCode, code, message := http.StatusInternalServerError, "AUDIT_FAILED", "Failed to audit HAR file"
+		if errors.Is(err, impact.ErrInvalidHAR) {
+			statusCode, code, message = http.StatusBadRequest, "INVALID_HAR", "Invalid HAR file"
+		}
+		RespondWithError(c, statusCode, message, code, map[string]string{
+			"reason": err.Error(),
+		})
+
+		LogResponse(h.logger, operation, statusCode, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"url":               audit.URL,
+		"requests":          audit.Breakdown.Requests,
+		"transferred_bytes": audit.Breakdown.TransferredBytes,
+	})
+
+	c.JSON(http.StatusOK, audit)
+}
+
+// HandleImportLighthouse imports a Lighthouse or PageSpeed Insights JSON report
+// @Summary Import a Lighthouse report
+// @Description Accepts a Lighthouse JSON report (or a PageSpeed Insights response) as the request body or as the "file" field of a multipart form. The resource-summary or network-requests audit is stored as a baseline measurement for the page and compared with the median of the page's previous baselines for the same device, connection and region. Regressions list increases in page weight or estimated CO2 beyond the thresholds.
+// @Tags impact
+// @Accept json
+// @Accept mpfd
+// @Produce json
+// @Param deploy query string false "Deploy identifier, e.g. a commit SHA" example("3f2c1a9")
+// @Param device_type query string false "Device type, defaults from the report's form factor" example("smartphone")
+// @Param connection_type query string false "Connection type, defaults from the report's form factor" example("mobile_4g")
+// @Param region query string false "Region for grid intensity" example("EU")
+// @Param weight_threshold query number false "Page weight increase in percent that raises an alert (default 10)" example(10)
+// @Param co2_threshold query number false "Emissions increase in percent that raises an alert (default 10)" example(10)
+// @Security BearerAuth
+// @Success 200 {object} impact.LighthouseImport
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/impact/lighthouse [post]
+func (h *ImpactHandler) HandleImportLighthouse(c *gin.Context) {
+	const operation = "import_lighthouse"
+
+	thresholds, validationErrors := ValidateRegressionThresholds(c.Query("weight_threshold"), c.Query("co2_threshold"))
+	if len(validationErrors) > 0 {
+		RespondWithValidationErrors(c, validationErrors)
+		return
+	}
+	params := impact.LighthouseImportParams{
+		DeviceType:     c.Query("device_type"),
+		ConnectionType: c.Query("connection_type"),
+		Region:         c.Query("region"),
+		Deploy:         c.Query("deploy"),
+		Thresholds:     thresholds,
+	}
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"content_type": c.ContentType(),
+		"deploy":       params.Deploy,
+		"region":       params.Region,
+	})
+
+	body, closeBody, err := uploadedFile(c, maxLighthouseUploadBytes)
+	if err != nil {
+		RespondWithError(c, http.StatusBadRequest,
+			"Missing Lighthouse report",
+			"INVALID_LIGHTHOUSE_REPORT",
+			map[string]string{
+				"reason": err.Error(),
+			})
+
+		LogResponse(h.logger, operation, http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	defer closeBody()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	result, err := h.impactService.ImportLighthouse(ctx, body, params)
+	if err != nil {
+		statusCode, code, message := http.StatusInternalServerError, "IMPORT_FAILED", "Failed to import Lighthouse report"
+		if errors.Is(err, impact.ErrInvalidLighthouseReport) {
+			statusCode, code, message = http.StatusBadRequest, "INVALID_LIGHTHOUSE_REPORT", "Invalid Lighthouse report"
+		}
+		RespondWithError(c, statusCode, message, code, map[string]string{
+			"reason": err.Error(),
+		})
+
+		LogResponse(h.logger, operation, statusCode, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if len(result.Regressions) > 0 {
+		h.logger.Warn("page weight regression detected",
+			"url", result.Baseline.URL,
+			"deploy", result.Baseline.Deploy,
+			"regressions", len(result.Regressions),
+		)
+	}
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"url":         result.Baseline.URL,
+		"baseline_id": result.Baseline.ID,
+		"regressions": len(result.Regressions),
+	})
+
+	c.JSON(http.StatusOK, result)
+}
+
+// HandleGetPageTrend returns a page's weight and emissions trend
+// @Summary Get page emissions trend
+// @Description Returns the page's stored baselines over the last days with the change from first to latest, the least-squares emissions slope per day and the regressions raised
+// @Tags impact
+// @Produce json
+// @Param url query string true "Page URL" example("https://example.com/")
+// @Param days query int false "Number of days (1-365)" example(30)
+// @Param device_type query string false "Only include baselines for this device type" example("smartphone")
+// @Param weight_threshold query number false "Page weight increase in percent that raises an alert (default 10)" example(10)
+// @Param co2_threshold query number false "Emissions increase in percent that raises an alert (default 10)" example(10)
+// @Success 200 {object} impact.PageTrend
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/impact/trends [get]
+func (h *ImpactHandler) HandleGetPageTrend(c *gin.Context) {
+	const operation = "get_page_trend"
+
+	url, validationErrors := ValidateURL(c.Query("url"))
+	if url == "" && len(validationErrors) == 0 {
+		validationErrors = append(validationErrors, ValidationError{
+			Field:   "url",
+			Message: "url parameter is required",
+		})
+	}
+	days, dayErrors := ValidateDays(c.Query("days"), 30, 365)
+	thresholds, thresholdErrors := ValidateRegressionThresholds(c.Query("weight_threshold"), c.Query("co2_threshold"))
+	validationErrors = append(append(validationErrors, dayErrors...), thresholdErrors...)
+	if len(validationErrors) > 0 {
+		RespondWithValidationErrors(c, validationErrors)
+		return
+	}
+	deviceType := c.Query("device_type")
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"url":         url,
+		"days":        days,
+		"device_type": deviceType,
+	})
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	end := time.Now()
+	period := impact.ReportPeriod{Start: end.AddDate(0, 0, -days), End: end, Days: days}
+	trend, err := h.impactService.GetPageTrend(ctx, url, deviceType, period, thresholds)
+	if err != nil {
+		RespondWithError(c, http.StatusInternalServerError,
+			"Failed to get page trend",
+			"TREND_FAILED",
+			map[string]string{
+				"url":    url,
+				"reason": err.Error(),
+			})
+
+		LogResponse(h.logger, operation, http.StatusInternalServerError, map[string]interface{}{
+			"url":   url,
+			"error": err.Error(),
+		})
+		return
+	}
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"url":         url,
+		"points":      len(trend.Points),
+		"regressions": len(trend.Regressions),
+	})
+
+	c.JSON(http.StatusOK, trend)
+}
+
+// ValidateRegressionThresholds validates the page weight and emissions alert thresholds.
+// Missing values use the impact package defaults.
+func ValidateRegressionThresholds(weightParam, co2Param string) (impact.RegressionThresholds, []ValidationError) {
+	var thresholds impact.RegressionThresholds
+	var errors []ValidationError
+
+	for _, threshold := range []struct {
+		field string
+		param string
+		value *float64
+	}{
+		{"weight_threshold", weightParam, &thresholds.PageWeightPercent},
+		{"co2_threshold", co2Param, &thresholds.EmissionsPercent},
+	} {
+		if threshold.param == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(threshold.param, 64)
+		if err != nil || value <= 0 || value > 1000 {
+			errors = append(errors, ValidationError{
+				Field:   threshold.field,
+				Message: threshold.field + " must be a percentage between 0 and 1000",
+				Value:   threshold.param,
+			})
+			continue
+		}
+		*threshold.value = value
+	}
+
+	return thresholds, errors
+}
+
+// uploadedFile returns the request body, or the "file" field of a multipart form
+func uploadedFile(c *gin.Context, maxBytes int64) (io.Reader, func(), error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+	if c.ContentType() != "multipart/form-data" {
+		return c.Request.Body, func() {}, nil
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return nil, nil, err
+	}
+	upload, err := file.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	return upload, func() { upload.Close() }, nil
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/perschulte/greenweb-api/internal/latency"
+)
+
+// maxLatencyUploadBytes bounds the size of a measurement upload
+const maxLatencyUploadBytes = 10 << 20
+
+// LatencyHandler handles RTT measurement ingestion and the measured latency matrix
+type LatencyHandler struct {
+	matrix *latency.Matrix
+	logger *slog.Logger
+	config *Config
+}
+
+// NewLatencyHandler creates a new latency handler with dependencies
+func NewLatencyHandler(deps *Dependencies) *LatencyHandler {
+	return &LatencyHandler{
+		matrix: deps.LatencyMatrix,
+		logger: deps.Logger,
+		config: deps.Config,
+	}
+}
+
+// LatencyIngestResponse reports the result of a measurement upload
+type LatencyIngestResponse struct {
+	Accepted int `json:"accepted" example:"250"`
+}
+
+// LatencyMatrixResponse lists rolling RTT percentiles per user key and edge
+type LatencyMatrixResponse struct {
+	Pairs []latency.Percentiles `json:"pairs"`
+}
+
+// HandleIngestMeasurements ingests RUM or synthetic RTT measurements
+// @Summary Ingest RTT measurements
+// @Description Accepts a JSON array of samples or CSV (Content-Type text/csv) with user key, edge_id, rtt_ms and optional timestamp columns. User keys are grid zones or network prefixes. The whole batch is rejected if any sample is invalid.
+// @Tags latency
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Security BearerAuth
+// @Param samples body []latency.Sample true "RTT samples"
+// @Success 202 {object} LatencyIngestResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /v1/latency/measurements [post]
+func (h *LatencyHandler) HandleIngestMeasurements(c *gin.Context) {
+	const operation = "ingest_latency_measurements"
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"content_type": c.ContentType(),
+	})
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxLatencyUploadBytes)
+
+	var samples []latency.Sample
+	var err error
+	if strings.Contains(c.ContentType(), "csv") {
+		samples, err = latency.ParseCSV(c.Request.Body)
+	} else {
+		err = c.ShouldBindJSON(&samples)
+	}
+	if err == nil && len(samples) == 0 {
+		err = errors.New("no samples in request")
+	}
+	if err == nil {
+		err = h.matrix.Add(samples...)
+	}
+	if err != nil {
+		RespondWithError(c, http.StatusBadRequest,
+			"Invalid RTT measurements",
+			"INVALID_MEASUREMENTS",
+			map[string]string{
+				"reason": err.Error(),
+			})
+
+		LogResponse(h.logger, operation, http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	LogResponse(h.logger, operation, http.StatusAccepted, map[string]interface{}{
+		"accepted": len(samples),
+	})
+
+	c.JSON(http.StatusAccepted, LatencyIngestResponse{Accepted: len(samples)})
+}
+
+// HandleGetLatencyMatrix returns rolling RTT percentiles
+// @Summary Get measured latency matrix
+// @Description Returns p50/p95 RTT per user key and edge over the rolling window, optionally filtered by user key and edge ID
+// @Tags latency
+// @Produce json
+// @Param user query string false "User key (grid zone or prefix)" example("DE")
+// @Param edge query string false "Edge location ID" example("frankfurt")
+// @Success 200 {object} LatencyMatrixResponse
+// @Router /v1/latency/matrix [get]
+func (h *LatencyHandler) HandleGetLatencyMatrix(c *gin.Context) {
+	const operation = "get_latency_matrix"
+
+	user := c.Query("user")
+	edge := strings.ToLower(c.Query("edge"))
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"user": user,
+		"edge": edge,
+	})
+
+	pairs := []latency.Percentiles{}
+	for _, pair := range h.matrix.All(user) {
+		if edge == "" || pair.EdgeID == edge {
+			pairs = append(pairs, pair)
+		}
+	}
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"pairs": len(pairs),
+	})
+
+	c.JSON(http.StatusOK, LatencyMatrixResponse{Pairs: pairs})
+}
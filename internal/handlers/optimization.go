@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+)
+
+// OptimizationHandler handles optimization profile and analytics endpoints
+type OptimizationHandler struct {
+	optimizationService OptimizationService
+	analyticsService    OptimizationAnalyticsService // nil when the service has no analytics support
+	profileSigner       *optimization.ProfileSigner  // nil when signed profile tokens are disabled
+	cacheService        CacheService
+	logger              *slog.Logger
+	config              *Config
+}
+
+// TrackOptimizationRequest is the request body for recording an applied optimization profile
+type TrackOptimizationRequest struct {
+	Profile  *optimization.OptimizationProfile `json:"profile"`
+	Metadata map[string]interface{}            `json:"metadata,omitempty"`
+}
+
+// NewOptimizationHandler creates a new optimization handler with dependencies
+func NewOptimizationHandler(deps *Dependencies) *OptimizationHandler {
+	handler := &OptimizationHandler{
+		optimizationService: deps.Optimization,
+		profileSigner:       deps.ProfileSigner,
+		cacheService:        deps.Cache,
+		logger:              deps.Logger,
+		config:              deps.Config,
+	}
+
+	if analytics, ok := deps.Optimization.(OptimizationAnalyticsService); ok {
+		handler.analyticsService = analytics
+	}
+
+	return handler
+}
+
+// HandleGetOptimizationProfile generates an optimization profile for a location
+// @Summary Get optimization profile
+// @Description Generates a carbon-aware optimization profile for the given location and URL
+// @Tags optimization
+// @Produce json
+// @Param location query string false "Location" default(Berlin)
+// @Param url query string false "Website URL for URL-specific optimizations"
+// @Param Sec-CH-UA-Mobile header string false "Mobile client hint (?1 or ?0)"
+// @Param ECT header string false "Effective connection type (slow-2g, 2g, 3g, 4g)"
+// @Param Downlink header number false "Downlink bandwidth in Mbps"
+// @Param RTT header integer false "Round trip time in milliseconds"
+// @Param Device-Memory header number false "Device memory in GiB"
+// @Param Save-Data header string false "Save-Data preference (on)"
+// @Param Sec-CH-Prefers-Reduced-Motion header string false "Reduced motion preference (reduce)"
+// @Success 200 {object} optimization.OptimizationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/optimization [get]
+func (h *OptimizationHandler) HandleGetOptimizationProfile(c *gin.Context) {
+	const operation = "get_optimization_profile"
+
+	// Responses depend on client hints, so advertise them and vary caches on them
+	SetClientHintsHeaders(c)
+
+	req, ok := h.parseProfileQuery(c, operation)
+	if !ok {
+		return
+	}
+	location := req.Location
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	// Try to get from cache first if cache service is available
+	var cacheKey string
+	if h.cacheService != nil {
+		cacheKey = "optimization:" + location + ":" + req.URL + ":" + clientHintsCacheKey(req.ClientHints)
+		if cached, found := h.cacheService.Get(cacheKey); found {
+			LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+				"location": location,
+				"source":   "cache",
+			})
+			c.JSON(http.StatusOK, cached)
+			return
+		}
+	}
+
+	response, err := h.optimizationService.GetOptimizationProfile(ctx, req)
+	if err != nil {
+		h.logger.Error("failed to generate optimization profile",
+			"error", err,
+			"location", location,
+			"operation", operation)
+
+		RespondWithError(c, http.StatusInternalServerError,
+			"Failed to generate optimization profile",
+			"OPTIMIZATION_ERROR",
+			map[string]string{
+				"location": location,
+			})
+
+		LogResponse(h.logger, operation, http.StatusInternalServerError, map[string]interface{}{
+			"location": location,
+			"error":    err.Error(),
+		})
+		return
+	}
+
+	if h.cacheService != nil && cacheKey != "" {
+		h.cacheService.Set(cacheKey, response, 300) // Cache for 5 minutes
+	}
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"location": location,
+		"mode":     response.Optimization.Mode,
+	})
+
+	c.JSON(http.StatusOK, response)
+}
+
+// HandleTrackOptimizationApplication records that a client applied an optimization profile
+// @Summary Track optimization application
+// @Description Records an applied optimization profile for analytics and savings reporting
+// @Tags optimization
+// @Accept json
+// @Produce json
+// @Param request body TrackOptimizationRequest true "Applied profile and metadata"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/optimization/applications [post]
+func (h *OptimizationHandler) HandleTrackOptimizationApplication(c *gin.Context) {
+	const operation = "track_optimization_application"
+
+	var req TrackOptimizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondWithValidationErrors(c, []ValidationError{{
+			Field:   "body",
+			Message: "request body must be valid JSON",
+		}})
+		LogResponse(h.logger, operation, http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if req.Profile == nil || !req.Profile.Mode.IsValid() {
+		RespondWithValidationErrors(c, []ValidationError{{
+			Field:   "profile.mode",
+			Message: "profile with a valid mode (full, normal, eco, critical) is required",
+		}})
+		LogResponse(h.logger, operation, http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid profile",
+		})
+		return
+	}
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"mode":     req.Profile.Mode,
+		"location": req.Profile.Metadata.Location,
+	})
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.analyticsService.TrackOptimizationApplication(ctx, req.Profile, req.Metadata); err != nil {
+		h.logger.Error("failed to track optimization application",
+			"error", err,
+			"operation", operation)
+
+		RespondWithError(c, http.StatusInternalServerError,
+			"Failed to track optimization application",
+			"TRACKING_ERROR",
+			nil)
+
+		LogResponse(h.logger, operation, http.StatusInternalServerError, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	LogResponse(h.logger, operation, http.StatusCreated, map[string]interface{}{
+		"mode": req.Profile.Mode,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{"status": "tracked"})
+}
+
+// HandleGetOptimizationStats returns aggregated optimization statistics
+// @Summary Get optimization statistics
+// @Description Returns mode distribution, most disabled features and location breakdown for tracked optimizations
+// @Tags optimization
+// @Produce json
+// @Param location query string false "Filter by location"
+// @Param start query string false "Start of time range (RFC3339 or YYYY-MM-DD), defaults to 7 days before end"
+// @Param end query string false "End of time range (RFC3339 or YYYY-MM-DD), defaults to now"
+// @Success 200 {object} optimization.OptimizationStats
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/optimization/stats [get]
+func (h *OptimizationHandler) HandleGetOptimizationStats(c *gin.Context) {
+	const operation = "get_optimization_stats"
+
+	location, timeRange, ok := h.parseAnalyticsQuery(c, operation)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	stats, err := h.analyticsService.GetOptimizationStats(ctx, location, timeRange)
+	if err != nil {
+		h.logger.Error("failed to get optimization stats",
+			"error", err,
+			"location", location,
+			"operation", operation)
+
+		RespondWithError(c, http.StatusInternalServerError,
+			"Failed to get optimization statistics",
+			"ANALYTICS_ERROR",
+			map[string]string{
+				"location": location,
+			})
+
+		LogResponse(h.logger, operation, http.StatusInternalServerError, map[string]interface{}{
+			"location": location,
+			"error":    err.Error(),
+		})
+		return
+	}
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"location":            location,
+		"total_optimizations": stats.TotalOptimizations,
+	})
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// HandleGetEnergySavingsReport returns an energy savings report for tracked optimizations
+// @Summary Get energy savings report
+// @Description Returns total, per-mode, per-feature and daily energy and carbon savings for tracked optimizations
+// @Tags optimization
+// @Produce json
+// @Param location query string false "Filter by location"
+// @Param start query string false "Start of time range (RFC3339 or YYYY-MM-DD), defaults to 7 days before end"
+// @Param end query string false "End of time range (RFC3339 or YYYY-MM-DD), defaults to now"
+// @Success 200 {object} optimization.EnergySavingsReport
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/optimization/savings-report [get]
+func (h *OptimizationHandler) HandleGetEnergySavingsReport(c *gin.Context) {
+	const operation = "get_energy_savings_report"
+
+	location, timeRange, ok := h.parseAnalyticsQuery(c, operation)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	report, err := h.analyticsService.GetEnergySavingsReport(ctx, location, timeRange)
+	if err != nil {
+		h.logger.Error("failed to get energy savings report",
+			"error", err,
+			"location", location,
+			"operation", operation)
+
+		RespondWithError(c, http.StatusInternalServerError,
+			"Failed to generate energy savings report",
+			"ANALYTICS_ERROR",
+			map[string]string{
+				"location": location,
+			})
+
+		LogResponse(h.logger, operation, http.StatusInternalServerError, map[string]interface{}{
+			"location": location,
+			"error":    err.Error(),
+		})
+		return
+	}
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"location":           location,
+		"total_carbon_saved": report.TotalCarbonSaved,
+	})
+
+	c.JSON(http.StatusOK, report)
+}
+
+// parseProfileQuery validates location and URL parameters and reads client hints into an
+// optimization request. It writes the validation response and returns false when the query is invalid.
+func (h *OptimizationHandler) parseProfileQuery(c *gin.Context, operation string) (optimization.OptimizationRequest, bool) {
+	locationParam := c.DefaultQuery("location", "Berlin")
+	urlParam := c.Query("url")
+
+	location, locationErrors := ValidateLocation(locationParam)
+	url, urlErrors := ValidateURL(urlParam)
+
+	var allErrors []ValidationError
+	allErrors = append(allErrors, locationErrors...)
+	allErrors = append(allErrors, urlErrors...)
+
+	if len(allErrors) > 0 {
+		RespondWithValidationErrors(c, allErrors)
+		LogResponse(h.logger, operation, http.StatusBadRequest, map[string]interface{}{
+			"location": locationParam,
+			"url":      urlParam,
+			"errors":   allErrors,
+		})
+		return optimization.OptimizationRequest{}, false
+	}
+
+	hints := ParseClientHints(c.Request)
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"location":     location,
+		"url":          url,
+		"client_hints": hints,
+	})
+
+	req := optimization.OptimizationRequest{
+		Location:    location,
+		URL:         url,
+		UserAgent:   c.GetHeader("User-Agent"),
+		ClientHints: hints,
+	}
+	if hints != nil {
+		if hints.Mobile {
+			req.DeviceType = "mobile"
+		}
+		req.BandwidthLimit = hints.Downlink
+	}
+
+	return req, true
+}
+
+// parseAnalyticsQuery validates the optional location and the time range of analytics requests.
+// It writes the validation response and returns false when the query is invalid.
+func (h *OptimizationHandler) parseAnalyticsQuery(c *gin.Context, operation string) (string, optimization.TimeRange, bool) {
+	locationParam := c.Query("location")
+	startParam := c.Query("start")
+	endParam := c.Query("end")
+
+	var allErrors []ValidationError
+
+	// Location is optional for analytics; empty means all locations
+	var location string
+	if locationParam != "" {
+		var locationErrors []ValidationError
+		location, locationErrors = ValidateLocation(locationParam)
+		allErrors = append(allErrors, locationErrors...)
+	}
+
+	timeRange, timeErrors := ValidateTimeRange(startParam, endParam, 7, 366)
+	allErrors = append(allErrors, timeErrors...)
+
+	if len(allErrors) > 0 {
+		RespondWithValidationErrors(c, allErrors)
+		LogResponse(h.logger, operation, http.StatusBadRequest, map[string]interface{}{
+			"location": locationParam,
+			"start":    startParam,
+			"end":      endParam,
+			"errors":   allErrors,
+		})
+		return "", optimization.TimeRange{}, false
+	}
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"location": location,
+		"start":    timeRange.Start,
+		"end":      timeRange.End,
+	})
+
+	return location, timeRange, true
+}
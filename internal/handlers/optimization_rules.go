@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/perschulte/greenweb-api/internal/store"
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+	"github.com/perschulte/greenweb-api/service"
+)
+
+// OptimizationRulesService defines the rule management operations of an
+// optimization.OptimizationServiceWithRules
+type OptimizationRulesService interface {
+	CreateRule(ctx context.Context, rule *optimization.OptimizationRule) (*optimization.OptimizationRule, error)
+	UpdateRule(ctx context.Context, ruleID string, rule *optimization.OptimizationRule) (*optimization.OptimizationRule, error)
+	DeleteRule(ctx context.Context, ruleID string) error
+	GetRule(ctx context.Context, ruleID string) (*optimization.OptimizationRule, error)
+	ListRules(ctx context.Context, tags []string) ([]*optimization.OptimizationRule, error)
+}
+
+// RulesHandler handles the optimization rule and webhook registration admin endpoints
+type RulesHandler struct {
+	rules    OptimizationRulesService
+	webhooks carbon.CarbonWebhookService
+	logger   *slog.Logger
+}
+
+// NewRulesHandler creates a new rules handler with dependencies
+func NewRulesHandler(deps *Dependencies) *RulesHandler {
+	return &RulesHandler{
+		rules:    deps.OptimizationRules,
+		webhooks: deps.Webhooks,
+		logger:   deps.Logger,
+	}
+}
+
+// WebhookRegistrationRequest registers a webhook for carbon intensity notifications
+type WebhookRegistrationRequest struct {
+	URL      string   `json:"url" binding:"required" example:"https://example.com/hooks/carbon"`
+	Location string   `json:"location" example:"DE"`
+	Events   []string `json:"events" binding:"required" example:"green_hour_start,intensity_change"`
+}
+
+// respondWithStoreError maps rule and webhook store errors to HTTP responses
+func (h *RulesHandler) respondWithStoreError(c *gin.Context, operation, message string, err error) {
+	statusCode := http.StatusInternalServerError
+	code := "STORAGE_ERROR"
+	switch {
+	case errors.Is(err, service.ErrInvalidRule):
+		statusCode, code = http.StatusBadRequest, "INVALID_RULE"
+	case errors.Is(err, store.ErrInvalidWebhook):
+		statusCode, code = http.StatusBadRequest, "INVALID_WEBHOOK"
+	case errors.Is(err, store.ErrNotFound):
+		statusCode, code = http.StatusNotFound, "NOT_FOUND"
+	case errors.Is(err, store.ErrExists):
+		statusCode, code = http.StatusConflict, "ALREADY_EXISTS"
+	default:
+		h.logger.Error(message, "error", err, "operation", operation)
+	}
+
+	RespondWithError(c, statusCode, message, code, map[string]string{
+		"reason": err.Error(),
+	})
+
+	LogResponse(h.logger, operation, statusCode, map[string]interface{}{
+		"error": err.Error(),
+	})
+}
+
+// bindRule decodes an optimization rule from the request body
+func bindRule(c *gin.Context) (*optimization.OptimizationRule, bool) {
+	var rule optimization.OptimizationRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		RespondWithValidationErrors(c, []ValidationError{{
+			Field:   "body",
+			Message: "invalid JSON body: " + err.Error(),
+		}})
+		return nil, false
+	}
+	return &rule, true
+}
+
+// HandleListRules lists optimization rules
+// @Summary List optimization rules
+// @Description Returns the optimization rules, highest priority first, optionally only those carrying one of the given tags
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param tags query string false "Comma-separated tags" example("video,autoplay")
+// @Success 200 {array} optimization.OptimizationRule
+// @Failure 401 {object} map[string]interface{}
+// @Router /v1/admin/optimization-rules [get]
+func (h *RulesHandler) HandleListRules(c *gin.Context) {
+	const operation = "list_optimization_rules"
+
+	var tags []string
+	for _, tag := range strings.Split(c.Query("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"tags": tags,
+	})
+
+	rules, err := h.rules.ListRules(c.Request.Context(), tags)
+	if err != nil {
+		h.respondWithStoreError(c, operation, "Failed to list optimization rules", err)
+		return
+	}
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"rules": len(rules),
+	})
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// HandleCreateRule creates an optimization rule
+// @Summary Create an optimization rule
+// @Description Stores a rule that is applied to every generated optimization profile whose carbon intensity, location or request matches its conditions. Supported actions are disable_feature, enable_feature, set_image_quality and set_video_quality.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param rule body optimization.OptimizationRule true "Rule"
+// @Success 201 {object} optimization.OptimizationRule
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /v1/admin/optimization-rules [post]
+func (h *RulesHandler) HandleCreateRule(c *gin.Context) {
+	const operation = "create_optimization_rule"
+
+	rule, ok := bindRule(c)
+	if !ok {
+		return
+	}
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"rule_id": rule.ID,
+	})
+
+	created, err := h.rules.CreateRule(c.Request.Context(), rule)
+	if err != nil {
+		h.respondWithStoreError(c, operation, "Failed to create optimization rule", err)
+		return
+	}
+
+	h.logger.Info("optimization rule created",
+		"rule_id", created.ID,
+		"operation", operation)
+
+	LogResponse(h.logger, operation, http.StatusCreated, map[string]interface{}{
+		"rule_id": created.ID,
+	})
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// HandleGetRule returns an optimization rule
+// @Summary Get an optimization rule
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Rule ID" example("high_carbon_video")
+// @Success 200 {object} optimization.OptimizationRule
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/admin/optimization-rules/{id} [get]
+func (h *RulesHandler) HandleGetRule(c *gin.Context) {
+	const operation = "get_optimization_rule"
+
+	ruleID := c.Param("id")
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"rule_id": ruleID,
+	})
+
+	rule, err := h.rules.GetRule(c.Request.Context(), ruleID)
+	if err != nil {
+		h.respondWithStoreError(c, operation, "Failed to get optimization rule", err)
+		return
+	}
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"rule_id": ruleID,
+	})
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// HandleUpdateRule replaces an optimization rule
+// @Summary Update an optimization rule
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Rule ID" example("high_carbon_video")
+// @Param rule body optimization.OptimizationRule true "Rule"
+// @Success 200 {object} optimization.OptimizationRule
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/admin/optimization-rules/{id} [put]
+func (h *RulesHandler) HandleUpdateRule(c *gin.Context) {
+	const operation = "update_optimization_rule"
+
+	ruleID := c.Param("id")
+	rule, ok := bindRule(c)
+	if !ok {
+		return
+	}
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"rule_id": ruleID,
+	})
+
+	updated, err := h.rules.UpdateRule(c.Request.Context(), ruleID, rule)
+	if err != nil {
+		h.respondWithStoreError(c, operation, "Failed to update optimization rule", err)
+		return
+	}
+
+	h.logger.Info("optimization rule updated",
+		"rule_id", ruleID,
+		"operation", operation)
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"rule_id": ruleID,
+	})
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// HandleDeleteRule removes an optimization rule
+// @Summary Delete an optimization rule
+// @Tags admin
+// @Security BearerAuth
+// @Param id path string true "Rule ID" example("high_carbon_video")
+// @Success 204
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/admin/optimization-rules/{id} [delete]
+func (h *RulesHandler) HandleDeleteRule(c *gin.Context) {
+	const operation = "delete_optimization_rule"
+
+	ruleID := c.Param("id")
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"rule_id": ruleID,
+	})
+
+	if err := h.rules.DeleteRule(c.Request.Context(), ruleID); err != nil {
+		h.respondWithStoreError(c, operation, "Failed to delete optimization rule", err)
+		return
+	}
+
+	LogResponse(h.logger, operation, http.StatusNoContent, map[string]interface{}{})
+
+	c.Status(http.StatusNoContent)
+}
+
+// HandleListWebhooks lists webhook registrations
+// @Summary List webhook registrations
+// @Description Returns the carbon intensity webhook registrations for a location, or all of them, oldest first
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param location query string false "Location" example("DE")
+// @Success 200 {array} carbon.WebhookRegistration
+// @Failure 401 {object} map[string]interface{}
+// @Router /v1/admin/webhooks [get]
+func (h *RulesHandler) HandleListWebhooks(c *gin.Context) {
+	const operation = "list_webhooks"
+
+	location := c.Query("location")
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"location": location,
+	})
+
+	webhooks, err := h.webhooks.ListWebhooks(c.Request.Context(), location)
+	if err != nil {
+		h.respondWithStoreError(c, operation, "Failed to list webhooks", err)
+		return
+	}
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"webhooks": len(webhooks),
+	})
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// HandleRegisterWebhook registers a webhook
+// @Summary Register a webhook
+// @Description Registers an http(s) URL for carbon intensity notifications about a location
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param webhook body WebhookRegistrationRequest true "Registration"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /v1/admin/webhooks [post]
+func (h *RulesHandler) HandleRegisterWebhook(c *gin.Context) {
+	const operation = "register_webhook"
+
+	var req WebhookRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondWithValidationErrors(c, []ValidationError{{
+			Field:   "body",
+			Message: "invalid JSON body: " + err.Error(),
+		}})
+		return
+	}
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"location": req.Location,
+		"events":   req.Events,
+	})
+
+	webhookID, err := h.webhooks.RegisterWebhook(c.Request.Context(), req.URL, req.Location, req.Events)
+	if err != nil {
+		h.respondWithStoreError(c, operation, "Failed to register webhook", err)
+		return
+	}
+
+	h.logger.Info("webhook registered",
+		"webhook_id", webhookID,
+		"location", req.Location,
+		"operation", operation)
+
+	LogResponse(h.logger, operation, http.StatusCreated, map[string]interface{}{
+		"webhook_id": webhookID,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{"id": webhookID})
+}
+
+// HandleUnregisterWebhook removes a webhook registration
+// @Summary Unregister a webhook
+// @Tags admin
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Success 204
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/admin/webhooks/{id} [delete]
+func (h *RulesHandler) HandleUnregisterWebhook(c *gin.Context) {
+	const operation = "unregister_webhook"
+
+	webhookID := c.Param("id")
+
+	LogRequest(h.logger, c, operation, map[string]interface{}{
+		"webhook_id": webhookID,
+	})
+
+	if err := h.webhooks.UnregisterWebhook(c.Request.Context(), webhookID); err != nil {
+		h.respondWithStoreError(c, operation, "Failed to unregister webhook", err)
+		return
+	}
+
+	LogResponse(h.logger, operation, http.StatusNoContent, map[string]interface{}{})
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+)
+
+// ProfileTokenResponse is the response for a signed optimization profile token
+type ProfileTokenResponse struct {
+	Token     string                        `json:"token"`
+	TokenType string                        `json:"token_type"`
+	KeyID     string                        `json:"key_id"`
+	Zone      string                        `json:"zone"`
+	Mode      optimization.OptimizationMode `json:"mode"`
+	ExpiresAt time.Time                     `json:"expires_at"`
+}
+
+// HandleIssueProfileToken issues a signed token carrying the optimization profile for a location
+// @Summary Issue signed optimization profile token
+// @Description Generates an optimization profile and returns it as a compact Ed25519 JWS that edge workers can verify offline until it expires
+// @Tags optimization
+// @Produce json
+// @Param location query string false "Location" default(Berlin)
+// @Param url query string false "Website URL for URL-specific optimizations"
+// @Success 200 {object} ProfileTokenResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/optimization/token [get]
+func (h *OptimizationHandler) HandleIssueProfileToken(c *gin.Context) {
+	const operation = "issue_profile_token"
+
+	SetClientHintsHeaders(c)
+
+	req, ok := h.parseProfileQuery(c, operation)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	response, err := h.optimizationService.GetOptimizationProfile(ctx, req)
+	if err != nil {
+		h.logger.Error("failed to generate optimization profile for token",
+			"error", err,
+			"location", req.Location,
+			"operation", operation)
+
+		RespondWithError(c, http.StatusInternalServerError,
+			"Failed to generate optimization profile",
+			"OPTIMIZATION_ERROR",
+			map[string]string{
+				"location": req.Location,
+			})
+
+		LogResponse(h.logger, operation, http.StatusInternalServerError, map[string]interface{}{
+			"location": req.Location,
+			"error":    err.Error(),
+		})
+		return
+	}
+
+	// Prefer the grid zone reported by the carbon data source
+	zone := req.Location
+	if response.CarbonIntensity != nil && response.CarbonIntensity.GridZone != "" {
+		zone = response.CarbonIntensity.GridZone
+	}
+
+	token, claims, err := h.profileSigner.Sign(response.Optimization, zone)
+	if err != nil {
+		h.logger.Error("failed to sign optimization profile",
+			"error", err,
+			"location", req.Location,
+			"operation", operation)
+
+		RespondWithError(c, http.StatusInternalServerError,
+			"Failed to sign optimization profile",
+			"SIGNING_ERROR",
+			map[string]string{
+				"location": req.Location,
+			})
+
+		LogResponse(h.logger, operation, http.StatusInternalServerError, map[string]interface{}{
+			"location": req.Location,
+			"error":    err.Error(),
+		})
+		return
+	}
+
+	expiresAt := time.Unix(claims.ExpiresAt, 0).UTC()
+	if maxAge := int(time.Until(expiresAt).Seconds()); maxAge > 0 {
+		c.Header("Cache-Control", clientHintsCacheControl(c.Request, maxAge))
+	}
+
+	LogResponse(h.logger, operation, http.StatusOK, map[string]interface{}{
+		"location":   req.Location,
+		"zone":       zone,
+		"mode":       response.Optimization.Mode,
+		"expires_at": expiresAt,
+	})
+
+	c.JSON(http.StatusOK, ProfileTokenResponse{
+		Token:     token,
+		TokenType: "JWS",
+		KeyID:     h.profileSigner.KeyID(),
+		Zone:      zone,
+		Mode:      response.Optimization.Mode,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// HandleGetJWKS publishes the public keys used to sign optimization profile tokens
+// @Summary Get profile token signing keys
+// @Description Returns the JSON Web Key Set for verifying signed optimization profile tokens
+// @Tags optimization
+// @Produce json
+// @Success 200 {object} optimization.JSONWebKeySet
+// @Router /v1/optimization/jwks [get]
+func (h *OptimizationHandler) HandleGetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.JSON(http.StatusOK, h.profileSigner.JWKS())
+}
@@ -0,0 +1,357 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+)
+
+var (
+	// ErrSourceNotAllowed is returned when a source URL or path is outside the configured allow lists
+	ErrSourceNotAllowed = errors.New("image source not allowed")
+
+	// ErrSourceTooLarge is returned when a source image exceeds MaxSourceBytes or MaxSourcePixels
+	ErrSourceTooLarge = errors.New("image source too large")
+)
+
+// maxRedirects is the number of redirects followed when fetching a remote source
+const maxRedirects = 5
+
+// carrierGradeNAT is the shared address space (RFC 6598), which net.IP does not classify as private
+var carrierGradeNAT = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// ServiceConfig holds configuration for the image transcoding service
+type ServiceConfig struct {
+	LocalRoot          string        // Directory local paths are resolved against; local sources are disabled if empty
+	AllowedHosts       []string      // Hosts remote sources may be fetched from
+	AllowAnyPublicHost bool          // Fetch from any public host when AllowedHosts is empty; remote sources are disabled otherwise
+	MaxSourceBytes     int64         // Maximum size of a source image
+	MaxSourcePixels    int64         // Maximum width × height of a source image, checked before decoding
+	FetchTimeout       time.Duration // Timeout for fetching remote sources
+	CacheSize          int           // Maximum number of cached outputs
+	CacheTTL           time.Duration // Maximum lifetime of a cached output
+}
+
+// Result is a transcoded image
+type Result struct {
+	Data          []byte
+	ContentType   string
+	Format        string
+	Width         int
+	Height        int
+	OriginalBytes int
+	Mode          optimization.OptimizationMode
+	Cached        bool
+}
+
+// cacheEntry is a cached transcoding result
+type cacheEntry struct {
+	result    *Result
+	expiresAt time.Time
+}
+
+// Service fetches source images and transcodes them for optimization profiles
+type Service struct {
+	config ServiceConfig
+	client *http.Client
+	logger *slog.Logger
+
+	cache      map[string]*cacheEntry
+	cacheOrder []string
+	cacheMutex sync.Mutex
+}
+
+// NewService creates a new image transcoding service
+func NewService(config ServiceConfig, logger *slog.Logger) *Service {
+	if config.MaxSourceBytes <= 0 {
+		config.MaxSourceBytes = 20 * 1024 * 1024
+	}
+	if config.MaxSourcePixels <= 0 {
+		config.MaxSourcePixels = 40_000_000
+	}
+	if config.FetchTimeout <= 0 {
+		config.FetchTimeout = 10 * time.Second
+	}
+	if config.CacheSize <= 0 {
+		config.CacheSize = 256
+	}
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = time.Hour
+	}
+
+	service := &Service{
+		config: config,
+		logger: logger,
+		cache:  make(map[string]*cacheEntry),
+	}
+	service.client = service.newClient()
+	return service
+}
+
+// newClient returns an HTTP client that only connects to public addresses. The address is
+// checked after DNS resolution, so allowed host names cannot be pointed at internal services,
+// and every redirect target is checked against the allow list again.
+func (s *Service) newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: s.config.FetchTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("%w: %s resolves to a non-public address", ErrSourceNotAllowed, host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // A proxy would connect on our behalf and bypass the address check
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   s.config.FetchTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			if (req.URL.Scheme != "http" && req.URL.Scheme != "https") || !s.isHostAllowed(req.URL.Hostname()) {
+				return fmt.Errorf("%w: redirect to %s", ErrSourceNotAllowed, req.URL.Host)
+			}
+			return nil
+		},
+	}
+}
+
+// Transcode fetches a source (http(s) URL or local path) and re-encodes it for the profile.
+// Results are cached per source, profile mode and derived options.
+func (s *Service) Transcode(ctx context.Context, source string, profile *optimization.OptimizationProfile, format string) (*Result, error) {
+	options := OptionsFromProfile(profile)
+	if format != "" {
+		options.Format = format
+	}
+
+	var mode optimization.OptimizationMode
+	if profile != nil {
+		mode = profile.Mode
+	}
+
+	cacheKey := fmt.Sprintf("%s|%s|q%d|%dx%d|%d|%s",
+		mode, source, options.Quality, options.MaxWidth, options.MaxHeight, options.MaxBytes, options.Format)
+	if cached := s.getCached(cacheKey); cached != nil {
+		return cached, nil
+	}
+
+	data, err := s.fetch(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the dimensions first, a small compressed file can decode to gigabytes of pixels
+	sourceConfig, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if int64(sourceConfig.Width)*int64(sourceConfig.Height) > s.config.MaxSourcePixels {
+		return nil, fmt.Errorf("%w: %dx%d exceeds %d pixels", ErrSourceTooLarge, sourceConfig.Width, sourceConfig.Height, s.config.MaxSourcePixels)
+	}
+
+	img, sourceFormat, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	encoded, outputFormat, err := Transcode(img, sourceFormat, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// Never serve something larger than the original in the same format
+	if outputFormat == sourceFormat && len(encoded) >= len(data) && img.Bounds().Dx() <= options.MaxWidth && img.Bounds().Dy() <= options.MaxHeight {
+		encoded = data
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcoded image: %w", err)
+	}
+
+	result := &Result{
+		Data:          encoded,
+		ContentType:   "image/" + outputFormat,
+		Format:        outputFormat,
+		Width:         config.Width,
+		Height:        config.Height,
+		OriginalBytes: len(data),
+		Mode:          mode,
+	}
+
+	s.setCached(cacheKey, result)
+
+	s.logger.Info("Transcoded image",
+		"source", source,
+		"mode", mode,
+		"format", outputFormat,
+		"original_bytes", len(data),
+		"output_bytes", len(encoded),
+		"width", config.Width,
+		"height", config.Height)
+
+	return result, nil
+}
+
+// fetch loads source bytes from a remote URL or the local root
+func (s *Service) fetch(ctx context.Context, source string) ([]byte, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return s.fetchRemote(ctx, source)
+	}
+	return s.fetchLocal(source)
+}
+
+// fetchRemote downloads a source image over HTTP(S)
+func (s *Service) fetchRemote(ctx context.Context, source string) ([]byte, error) {
+	sourceURL, err := url.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source URL: %w", err)
+	}
+	if !s.isHostAllowed(sourceURL.Hostname()) {
+		return nil, ErrSourceNotAllowed
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch source image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("source returned status %d", resp.StatusCode)
+	}
+
+	return readLimited(resp.Body, s.config.MaxSourceBytes)
+}
+
+// fetchLocal reads a source image from below the configured local root
+func (s *Service) fetchLocal(source string) ([]byte, error) {
+	if s.config.LocalRoot == "" {
+		return nil, ErrSourceNotAllowed
+	}
+
+	root, err := filepath.Abs(s.config.LocalRoot)
+	if err != nil {
+		return nil, fmt.Errorf("invalid local root: %w", err)
+	}
+	path := filepath.Join(root, filepath.Clean("/"+source))
+	if path != root && !strings.HasPrefix(path, root+string(filepath.Separator)) {
+		return nil, ErrSourceNotAllowed
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open source image: %w", err)
+	}
+	defer file.Close()
+
+	return readLimited(file, s.config.MaxSourceBytes)
+}
+
+// isHostAllowed checks a remote host against the allow list, or rejects local and
+// private addresses when any public host is allowed. The resolved address is checked
+// again when connecting.
+func (s *Service) isHostAllowed(host string) bool {
+	host = strings.ToLower(host)
+	if len(s.config.AllowedHosts) > 0 {
+		for _, allowed := range s.config.AllowedHosts {
+			if host == strings.ToLower(allowed) {
+				return true
+			}
+		}
+		return false
+	}
+	if !s.config.AllowAnyPublicHost {
+		return false
+	}
+
+	if host == "" || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return isPublicIP(ip)
+	}
+	return true
+}
+
+// isPublicIP reports whether ip is a globally routable unicast address
+func isPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || carrierGradeNAT.Contains(ip))
+}
+
+// readLimited reads at most limit bytes and fails if the reader holds more
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source image: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, ErrSourceTooLarge
+	}
+	return data, nil
+}
+
+// getCached returns a cached result if present and not expired
+func (s *Service) getCached(key string) *Result {
+	s.cacheMutex.Lock()
+	defer s.cacheMutex.Unlock()
+
+	entry, exists := s.cache[key]
+	if !exists || time.Now().After(entry.expiresAt) {
+		return nil
+	}
+
+	result := *entry.result
+	result.Cached = true
+	return &result
+}
+
+// setCached stores a result, evicting the oldest entries when the cache is full
+func (s *Service) setCached(key string, result *Result) {
+	s.cacheMutex.Lock()
+	defer s.cacheMutex.Unlock()
+
+	if _, exists := s.cache[key]; !exists {
+		s.cacheOrder = append(s.cacheOrder, key)
+	}
+	s.cache[key] = &cacheEntry{result: result, expiresAt: time.Now().Add(s.config.CacheTTL)}
+
+	for len(s.cacheOrder) > s.config.CacheSize {
+		oldest := s.cacheOrder[0]
+		s.cacheOrder = s.cacheOrder[1:]
+		delete(s.cache, oldest)
+	}
+}
@@ -0,0 +1,104 @@
+package imaging
+
+import (
+	"context"
+	"errors"
+	"image/png"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsHostAllowed(t *testing.T) {
+	allowList := NewService(ServiceConfig{AllowedHosts: []string{"cdn.example.com"}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	anyHost := NewService(ServiceConfig{AllowAnyPublicHost: true}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	disabled := NewService(ServiceConfig{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		name    string
+		service *Service
+		host    string
+		allowed bool
+	}{
+		{"listed host", allowList, "CDN.example.com", true},
+		{"unlisted host", allowList, "images.example.com", false},
+		{"no allow list", disabled, "cdn.example.com", false},
+		{"public host", anyHost, "images.example.com", true},
+		{"public address", anyHost, "93.184.216.34", true},
+		{"localhost", anyHost, "localhost", false},
+		{"loopback", anyHost, "127.0.0.1", false},
+		{"private", anyHost, "10.1.2.3", false},
+		{"link-local metadata", anyHost, "169.254.169.254", false},
+		{"carrier-grade NAT", anyHost, "100.64.1.1", false},
+		{"unspecified", anyHost, "0.0.0.0", false},
+		{"IPv6 unique local", anyHost, "fd00::1", false},
+		{"IPv4-mapped loopback", anyHost, "::ffff:127.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.service.isHostAllowed(tt.host); got != tt.allowed {
+				t.Errorf("isHostAllowed(%q) = %v, want %v", tt.host, got, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestFetchRemoteRejectsPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://internal.example.com/secret", http.StatusFound)
+			return
+		}
+		w.Write([]byte("secret"))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	// An allowed name that resolves to loopback is rejected when connecting
+	service := NewService(ServiceConfig{AllowedHosts: []string{"localhost"}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if _, err := service.fetchRemote(context.Background(), "http://localhost:"+port+"/image.png"); !errors.Is(err, ErrSourceNotAllowed) {
+		t.Errorf("Expected ErrSourceNotAllowed for a loopback address, got %v", err)
+	}
+
+	// Redirects are checked against the allow list before they are followed
+	request, _ := http.NewRequest(http.MethodGet, "http://internal.example.com/secret", nil)
+	if err := service.client.CheckRedirect(request, []*http.Request{{}}); !errors.Is(err, ErrSourceNotAllowed) {
+		t.Errorf("Expected ErrSourceNotAllowed for a redirect off the allow list, got %v", err)
+	}
+	request, _ = http.NewRequest(http.MethodGet, "file:///etc/passwd", nil)
+	if err := service.client.CheckRedirect(request, []*http.Request{{}}); !errors.Is(err, ErrSourceNotAllowed) {
+		t.Errorf("Expected ErrSourceNotAllowed for a non-HTTP redirect, got %v", err)
+	}
+}
+
+func TestTranscodeRejectsTooManyPixels(t *testing.T) {
+	root := t.TempDir()
+	file, err := os.Create(filepath.Join(root, "large.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(file, noisyImage(300, 200)); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	service := NewService(ServiceConfig{LocalRoot: root, MaxSourcePixels: 50000}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if _, err := service.Transcode(context.Background(), "large.png", nil, FormatPNG); !errors.Is(err, ErrSourceTooLarge) {
+		t.Errorf("Expected ErrSourceTooLarge for 60000 pixels, got %v", err)
+	}
+
+	service = NewService(ServiceConfig{LocalRoot: root, MaxSourcePixels: 60000}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	result, err := service.Transcode(context.Background(), "large.png", nil, FormatPNG)
+	if err != nil {
+		t.Fatalf("Transcode() error = %v", err)
+	}
+	if result.Width != 300 || result.Height != 200 {
+		t.Errorf("Expected 300x200 output, got %dx%d", result.Width, result.Height)
+	}
+}
@@ -0,0 +1,253 @@
+// Package imaging re-encodes images to the quality and dimensions of an optimization profile
+// using only the Go standard library.
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+
+	_ "image/gif" // Register GIF decoding for sources
+
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+)
+
+// Output formats supported by the transcoder
+const (
+	FormatAuto = "auto"
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+)
+
+// minJPEGQuality is the lowest quality used when shrinking an image to fit MaxBytes
+const minJPEGQuality = 30
+
+// minDimension stops downscaling once either side would drop below this many pixels
+const minDimension = 64
+
+// Options controls how an image is re-encoded
+type Options struct {
+	Quality   int    // JPEG quality (1-100)
+	MaxWidth  int    // Maximum output width in pixels, 0 for unlimited
+	MaxHeight int    // Maximum output height in pixels, 0 for unlimited
+	MaxBytes  int64  // Maximum encoded size in bytes, 0 for unlimited
+	Format    string // Output format: auto, jpeg or png
+}
+
+// imageQualityDefaults maps profile image quality to JPEG quality and maximum dimensions
+var imageQualityDefaults = map[optimization.ImageQuality]Options{
+	optimization.ImageQualityHigh:   {Quality: 85, MaxWidth: 3840, MaxHeight: 2160},
+	optimization.ImageQualityMedium: {Quality: 75, MaxWidth: 1920, MaxHeight: 1080},
+	optimization.ImageQualityLow:    {Quality: 60, MaxWidth: 1280, MaxHeight: 720},
+}
+
+// OptionsFromProfile derives transcoding options from a profile. The stricter of ImageQuality
+// and AdvancedImageOptimization wins, and ResourceLimits.MaxImageSize bounds the output size.
+func OptionsFromProfile(profile *optimization.OptimizationProfile) Options {
+	options := imageQualityDefaults[optimization.ImageQualityHigh]
+	options.Format = FormatAuto
+	if profile == nil {
+		return options
+	}
+
+	if defaults, exists := imageQualityDefaults[profile.ImageQuality]; exists {
+		options.Quality = defaults.Quality
+		options.MaxWidth = defaults.MaxWidth
+		options.MaxHeight = defaults.MaxHeight
+	}
+
+	advanced := profile.HighImpactOptimizations.ImageOptimization
+	if advanced.CompressionQuality > 0 && advanced.CompressionQuality < options.Quality {
+		options.Quality = advanced.CompressionQuality
+	}
+	if advanced.MaxImageDimensions.MaxWidth > 0 && advanced.MaxImageDimensions.MaxWidth < options.MaxWidth {
+		options.MaxWidth = advanced.MaxImageDimensions.MaxWidth
+	}
+	if advanced.MaxImageDimensions.MaxHeight > 0 && advanced.MaxImageDimensions.MaxHeight < options.MaxHeight {
+		options.MaxHeight = advanced.MaxImageDimensions.MaxHeight
+	}
+
+	options.MaxBytes = profile.ResourceLimits.MaxImageSize
+
+	return options
+}
+
+// Transcode resizes and re-encodes an image. It returns the encoded bytes and the output format.
+// When MaxBytes is set, JPEG quality is lowered and the image is downscaled until it fits.
+func Transcode(src image.Image, sourceFormat string, options Options) ([]byte, string, error) {
+	if options.Quality <= 0 || options.Quality > 100 {
+		options.Quality = 85
+	}
+
+	format := options.Format
+	if format == "" || format == FormatAuto {
+		format = FormatJPEG
+		if sourceFormat == "png" || sourceFormat == "gif" {
+			if !isOpaque(src) {
+				format = FormatPNG
+			}
+		}
+	}
+	if format != FormatJPEG && format != FormatPNG {
+		return nil, "", fmt.Errorf("unsupported output format: %s", format)
+	}
+
+	img := fit(src, options.MaxWidth, options.MaxHeight)
+	quality := options.Quality
+
+	for {
+		encoded, err := encode(img, format, quality)
+		if err != nil {
+			return nil, "", err
+		}
+		if options.MaxBytes <= 0 || int64(len(encoded)) <= options.MaxBytes {
+			return encoded, format, nil
+		}
+
+		// Over the size budget: reduce quality first, then dimensions
+		if format == FormatJPEG && quality > minJPEGQuality {
+			quality -= 10
+			if quality < minJPEGQuality {
+				quality = minJPEGQuality
+			}
+			continue
+		}
+
+		bounds := img.Bounds()
+		width, height := bounds.Dx()*3/4, bounds.Dy()*3/4
+		if width < minDimension || height < minDimension {
+			// Cannot shrink further; return the smallest result we have
+			return encoded, format, nil
+		}
+		img = resize(img, width, height)
+	}
+}
+
+// encode writes an image in the given format
+func encode(img image.Image, format string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+
+	switch format {
+	case FormatPNG:
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		err = encoder.Encode(&buf, img)
+	default:
+		err = jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: quality})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", format, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// fit scales an image down to fit within maxWidth x maxHeight, preserving aspect ratio
+func fit(img image.Image, maxWidth, maxHeight int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	scale := 1.0
+	if maxWidth > 0 && width > maxWidth {
+		scale = float64(maxWidth) / float64(width)
+	}
+	if maxHeight > 0 && height > maxHeight {
+		if s := float64(maxHeight) / float64(height); s < scale {
+			scale = s
+		}
+	}
+	if scale >= 1.0 {
+		return img
+	}
+
+	newWidth := int(float64(width)*scale + 0.5)
+	newHeight := int(float64(height)*scale + 0.5)
+	if newWidth < 1 {
+		newWidth = 1
+	}
+	if newHeight < 1 {
+		newHeight = 1
+	}
+
+	return resize(img, newWidth, newHeight)
+}
+
+// resize downsamples an image with an area-averaging (box) filter
+func resize(src image.Image, width, height int) *image.NRGBA {
+	bounds := src.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*srcHeight/height
+		y1 := bounds.Min.Y + (y+1)*srcHeight/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*srcWidth/width
+			x1 := bounds.Min.X + (x+1)*srcWidth/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			// Average premultiplied values so transparent pixels don't bleed colour
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r += uint64(pr)
+					g += uint64(pg)
+					b += uint64(pb)
+					a += uint64(pa)
+					n++
+				}
+			}
+
+			pixel := color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			}
+			dst.Set(x, y, pixel)
+		}
+	}
+
+	return dst
+}
+
+// flatten composites an image with transparency onto white for JPEG output
+func flatten(img image.Image) image.Image {
+	if isOpaque(img) {
+		return img
+	}
+
+	bounds := img.Bounds()
+	dst := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			// Premultiplied colour over white: c + (1 - a) * white
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r + (0xffff - a)),
+				G: uint16(g + (0xffff - a)),
+				B: uint16(b + (0xffff - a)),
+				A: 0xffff,
+			})
+		}
+	}
+
+	return dst
+}
+
+// isOpaque reports whether every pixel of the image is fully opaque
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return true
+}
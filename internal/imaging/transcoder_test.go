@@ -0,0 +1,115 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"testing"
+
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+)
+
+// noisyImage returns an opaque image that compresses poorly
+func noisyImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	rng := rand.New(rand.NewSource(1))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), 255})
+		}
+	}
+	return img
+}
+
+func TestOptionsFromProfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  *optimization.OptimizationProfile
+		expected Options
+	}{
+		{
+			name:     "nil profile",
+			profile:  nil,
+			expected: Options{Quality: 85, MaxWidth: 3840, MaxHeight: 2160, Format: FormatAuto},
+		},
+		{
+			name: "low quality with size limit",
+			profile: &optimization.OptimizationProfile{
+				ImageQuality:   optimization.ImageQualityLow,
+				ResourceLimits: optimization.ResourceLimits{MaxImageSize: 100000},
+			},
+			expected: Options{Quality: 60, MaxWidth: 1280, MaxHeight: 720, MaxBytes: 100000, Format: FormatAuto},
+		},
+		{
+			name: "advanced options are stricter",
+			profile: &optimization.OptimizationProfile{
+				ImageQuality: optimization.ImageQualityMedium,
+				HighImpactOptimizations: optimization.HighImpactOptimizations{
+					ImageOptimization: optimization.AdvancedImageOptimization{
+						CompressionQuality: 50,
+						MaxImageDimensions: optimization.ImageDimensions{MaxWidth: 800, MaxHeight: 2000},
+					},
+				},
+			},
+			expected: Options{Quality: 50, MaxWidth: 800, MaxHeight: 1080, Format: FormatAuto},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := OptionsFromProfile(tt.profile)
+			if options != tt.expected {
+				t.Errorf("OptionsFromProfile() = %+v, want %+v", options, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTranscodeResizes(t *testing.T) {
+	encoded, format, err := Transcode(noisyImage(400, 200), "jpeg", Options{Quality: 75, MaxWidth: 100, MaxHeight: 100})
+	if err != nil {
+		t.Fatalf("Transcode() error = %v", err)
+	}
+	if format != FormatJPEG {
+		t.Errorf("Expected jpeg output, got %s", format)
+	}
+
+	config, err := jpeg.DecodeConfig(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatalf("Failed to decode output: %v", err)
+	}
+	if config.Width != 100 || config.Height != 50 {
+		t.Errorf("Expected 100x50 output, got %dx%d", config.Width, config.Height)
+	}
+}
+
+func TestTranscodeHonorsMaxBytes(t *testing.T) {
+	const maxBytes = 20000
+
+	encoded, _, err := Transcode(noisyImage(512, 512), "jpeg", Options{Quality: 90, MaxBytes: maxBytes})
+	if err != nil {
+		t.Fatalf("Transcode() error = %v", err)
+	}
+	if len(encoded) > maxBytes {
+		t.Errorf("Expected output of at most %d bytes, got %d", maxBytes, len(encoded))
+	}
+}
+
+func TestTranscodeKeepsTransparency(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	img.Set(0, 0, color.NRGBA{255, 0, 0, 128})
+
+	encoded, format, err := Transcode(img, "png", Options{Quality: 75})
+	if err != nil {
+		t.Fatalf("Transcode() error = %v", err)
+	}
+	if format != FormatPNG {
+		t.Errorf("Expected png output for transparent source, got %s", format)
+	}
+	if _, err := png.Decode(bytes.NewReader(encoded)); err != nil {
+		t.Errorf("Failed to decode output: %v", err)
+	}
+}
@@ -1,14 +1,21 @@
 package impact
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"time"
+
+	"github.com/perschulte/greenweb-api/pkg/carbon"
 )
 
 // Calculator provides science-based CO2 impact calculations
 type Calculator struct {
-	factors EmissionFactors
+	factors   EmissionFactors
+	intensity carbon.CarbonServiceWithHistory // Live and historical intensity for requests with a zone
+
+	// fallbackGridScale samples the SWD and 1byte published grid averages, 0 means unscaled
+	fallbackGridScale float64
 }
 
 // NewCalculator creates a new impact calculator with default emission factors
@@ -25,8 +32,23 @@ func NewCalculatorWithFactors(factors EmissionFactors) *Calculator {
 	}
 }
 
+// NewCalculatorWithIntensitySource creates a calculator that adds time-specific results
+// from a zone's live or historical grid intensity
+func NewCalculatorWithIntensitySource(factors EmissionFactors, source carbon.CarbonServiceWithHistory) *Calculator {
+	return &Calculator{
+		factors:   factors,
+		intensity: source,
+	}
+}
+
 // Calculate performs impact calculation based on the request
 func (c *Calculator) Calculate(req *CalculationRequest) (*ImpactResult, error) {
+	return c.CalculateWithContext(context.Background(), req)
+}
+
+// CalculateWithContext performs impact calculation, using ctx to fetch grid intensity
+// for requests with a zone
+func (c *Calculator) CalculateWithContext(ctx context.Context, req *CalculationRequest) (*ImpactResult, error) {
 	if err := c.validateRequest(req); err != nil {
 		return nil, err
 	}
@@ -34,26 +56,7 @@ func (c *Calculator) Calculate(req *CalculationRequest) (*ImpactResult, error) {
 	// Set defaults
 	c.setDefaults(req)
 
-	var result *ImpactResult
-	var err error
-
-	switch req.Type {
-	case ImpactTypeVideoStreaming:
-		result, err = c.calculateVideoStreaming(req)
-	case ImpactTypeImageLoading:
-		result, err = c.calculateImageLoading(req)
-	case ImpactTypeJavaScript:
-		result, err = c.calculateJavaScript(req)
-	case ImpactTypeAIInference:
-		result, err = c.calculateAIInference(req)
-	case ImpactTypePageLoad:
-		result, err = c.calculatePageLoad(req)
-	case ImpactTypeDataTransfer:
-		result, err = c.calculateDataTransfer(req)
-	default:
-		return nil, fmt.Errorf("unsupported impact type: %s", req.Type)
-	}
-
+	result, err := c.calculateModel(req)
 	if err != nil {
 		return nil, err
 	}
@@ -69,24 +72,59 @@ func (c *Calculator) Calculate(req *CalculationRequest) (*ImpactResult, error) {
 	// Add methodology and warnings
 	c.addMethodologyInfo(result, req)
 
+	// Propagate emission factor uncertainty if requested
+	if req.MonteCarloSamples > 0 {
+		c.addUncertainty(result, req)
+	}
+	if result.Uncertainty == nil {
+		result.Methodology += fixedConfidenceIntervalNote
+	}
+
+	// Recalculate with the zone's intensity at the requested time
+	c.addTimeSpecificResult(ctx, result, req)
+
 	result.CalculatedAt = time.Now()
 
 	return result, nil
 }
 
+// calculateModel runs the request's methodology without confidence intervals,
+// rebound effects or methodology info
+func (c *Calculator) calculateModel(req *CalculationRequest) (*ImpactResult, error) {
+	switch req.Methodology {
+	case MethodologySWD:
+		return c.calculateSWD(req)
+	case MethodologyOneByte:
+		return c.calculateOneByte(req)
+	default:
+		return c.calculateGreenWeb(req)
+	}
+}
+
+// calculateGreenWeb dispatches to the activity models of the greenweb methodology
+func (c *Calculator) calculateGreenWeb(req *CalculationRequest) (*ImpactResult, error) {
+	switch req.Type {
+	case ImpactTypeVideoStreaming:
+		return c.calculateVideoStreaming(req)
+	case ImpactTypeImageLoading:
+		return c.calculateImageLoading(req)
+	case ImpactTypeJavaScript:
+		return c.calculateJavaScript(req)
+	case ImpactTypeAIInference:
+		return c.calculateAIInference(req)
+	case ImpactTypePageLoad:
+		return c.calculatePageLoad(req)
+	case ImpactTypeDataTransfer:
+		return c.calculateDataTransfer(req)
+	default:
+		return nil, fmt.Errorf("unsupported impact type: %s", req.Type)
+	}
+}
+
 // calculateVideoStreaming calculates impact of video streaming
 // Based on: IEA (2020), Carbon Trust (2021), Shift Project (2023 revised)
 func (c *Calculator) calculateVideoStreaming(req *CalculationRequest) (*ImpactResult, error) {
-	// Video bitrates in Mbps
-	bitrates := map[string]float64{
-		"360p":  1.0,
-		"480p":  2.5,
-		"720p":  5.0,
-		"1080p": 8.0,
-		"4k":    25.0,
-	}
-
-	bitrate := bitrates[req.VideoQuality]
+	bitrate := videoBitrates[req.VideoQuality]
 	hours := req.Duration / 3600.0
 
 	// Data transferred in GB
@@ -417,6 +455,10 @@ func (c *Calculator) calculateDataTransfer(req *CalculationRequest) (*ImpactResu
 }
 
 // applyConfidenceIntervals adds conservative confidence intervals
+// fixedConfidenceIntervalNote ends the methodology of results whose bounds are the fixed
+// interval rather than Monte Carlo percentiles
+const fixedConfidenceIntervalNote = " Includes ±25% confidence interval."
+
 func (c *Calculator) applyConfidenceIntervals(result *ImpactResult) {
 	// Conservative approach: ±25% uncertainty
 	result.ConfidenceInterval = 25.0
@@ -451,7 +493,14 @@ func (c *Calculator) calculateReboundEffects(result *ImpactResult, req *Calculat
 
 // addMethodologyInfo adds methodology explanation and data sources
 func (c *Calculator) addMethodologyInfo(result *ImpactResult, req *CalculationRequest) {
-	result.Methodology = "Conservative calculation based on device energy consumption, network transmission, and data center operations. Includes ±25% confidence interval."
+	result.Model = req.Methodology
+	if req.Methodology != MethodologyGreenWeb {
+		c.addModelMethodologyInfo(result, req)
+		return
+	}
+
+	result.ModelVersion = GreenWebModelVersion
+	result.Methodology = "Conservative calculation based on device energy consumption, network transmission, and data center operations."
 	
 	result.DataSources = []string{
 		"IEA (2023) - Electricity grid carbon intensity",
@@ -486,6 +535,30 @@ func (c *Calculator) validateRequest(req *CalculationRequest) error {
 		return fmt.Errorf("impact type is required")
 	}
 
+	switch req.Methodology {
+	case "", MethodologyGreenWeb, MethodologySWD, MethodologyOneByte:
+	default:
+		return fmt.Errorf("unsupported methodology: %s", req.Methodology)
+	}
+	if !req.Timestamp.IsZero() && req.Zone == "" {
+		return fmt.Errorf("zone is required with a timestamp")
+	}
+	if req.Timestamp.After(time.Now().Add(currentIntensityWindow)) {
+		return fmt.Errorf("timestamp cannot be in the future")
+	}
+	if req.MonteCarloSamples != 0 && (req.MonteCarloSamples < minMonteCarloSamples || req.MonteCarloSamples > maxMonteCarloSamples) {
+		return fmt.Errorf("monte carlo samples must be between %d and %d", minMonteCarloSamples, maxMonteCarloSamples)
+	}
+	for name, ratio := range map[string]*float64{
+		"green hosting ratio":          &req.GreenHostingRatio,
+		"returning visitor ratio":      req.ReturningVisitorRatio,
+		"returning visitor data ratio": req.ReturningVisitorDataRatio,
+	} {
+		if ratio != nil && (*ratio < 0 || *ratio > 1) {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+	}
+
 	switch req.Type {
 	case ImpactTypeVideoStreaming:
 		if req.Duration <= 0 {
@@ -522,6 +595,9 @@ func (c *Calculator) setDefaults(req *CalculationRequest) {
 	if req.Region == "" {
 		req.Region = "global"
 	}
+	if req.Methodology == "" {
+		req.Methodology = MethodologyGreenWeb
+	}
 }
 
 // Helper function for max
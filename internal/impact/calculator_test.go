@@ -1,6 +1,7 @@
 package impact
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -276,6 +277,81 @@ func TestCalculator_OptimizationLevels(t *testing.T) {
 	}
 }
 
+func TestCalculator_AlternateMethodologies(t *testing.T) {
+	calculator := NewCalculator()
+	fullGreen := 1.0
+	noReturning := 0.0
+
+	tests := []struct {
+		name            string
+		req             CalculationRequest
+		expected        float64
+		expectedVersion string
+	}{
+		{
+			name:            "swd with default visitor ratios",
+			req:             CalculationRequest{Type: ImpactTypeDataTransfer, DataSize: 1000, Methodology: MethodologySWD},
+			expected:        0.81 * (0.75 + 0.25*0.02) * 442,
+			expectedVersion: SWDModelVersion,
+		},
+		{
+			name: "swd with green hosting and first-time visitors only",
+			req: CalculationRequest{Type: ImpactTypeDataTransfer, DataSize: 1000, Methodology: MethodologySWD,
+				GreenHostingRatio: fullGreen, ReturningVisitorRatio: &noReturning},
+			expected:        0.81*0.85*442 + 0.81*0.15*50,
+			expectedVersion: SWDModelVersion,
+		},
+		{
+			name:            "onebyte over wifi",
+			req:             CalculationRequest{Type: ImpactTypeDataTransfer, DataSize: 1000, Methodology: MethodologyOneByte, ConnectionType: "wifi"},
+			expected:        1.52*475 + 0.72*519,
+			expectedVersion: OneByteModelVersion,
+		},
+		{
+			name:            "onebyte with green hosting in a region",
+			req:             CalculationRequest{Type: ImpactTypeDataTransfer, DataSize: 1000, Methodology: MethodologyOneByte, ConnectionType: "ethernet", Region: "FR", GreenHostingRatio: fullGreen},
+			expected:        4.29 * 85,
+			expectedVersion: OneByteModelVersion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := calculator.Calculate(&tt.req)
+			if err != nil {
+				t.Fatalf("Calculate failed: %v", err)
+			}
+			if abs(result.BaselineEmissions-tt.expected) > 0.01 {
+				t.Errorf("Expected baseline %.2f g, got %.2f g", tt.expected, result.BaselineEmissions)
+			}
+			if result.Model != tt.req.Methodology || result.ModelVersion != tt.expectedVersion {
+				t.Errorf("Expected model %s %s, got %s %s", tt.req.Methodology, tt.expectedVersion, result.Model, result.ModelVersion)
+			}
+			if !strings.Contains(result.Methodology, tt.expectedVersion) {
+				t.Errorf("Expected methodology to name the model version, got %q", result.Methodology)
+			}
+		})
+	}
+
+	result, err := calculator.Calculate(&CalculationRequest{Type: ImpactTypePageLoad})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Model != MethodologyGreenWeb || result.ModelVersion != GreenWebModelVersion {
+		t.Errorf("Expected greenweb model by default, got %s %s", result.Model, result.ModelVersion)
+	}
+
+	invalid := []CalculationRequest{
+		{Type: ImpactTypePageLoad, Methodology: "co2js"},
+		{Type: ImpactTypePageLoad, Methodology: MethodologySWD, GreenHostingRatio: 1.5},
+	}
+	for _, req := range invalid {
+		if _, err := calculator.Calculate(&req); err == nil {
+			t.Errorf("Expected validation error for %+v", req)
+		}
+	}
+}
+
 // Helper function for absolute value
 func abs(x float64) float64 {
 	if x < 0 {
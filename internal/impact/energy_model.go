@@ -0,0 +1,93 @@
+package impact
+
+import (
+	"strings"
+
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+)
+
+// ServerEnergyModelName identifies ServerEnergyModel results
+const ServerEnergyModelName = "greenweb-server-v1"
+
+// euGridZones are grid zone prefixes that use the EU data center PUE
+var euGridZones = map[string]bool{
+	"AT": true, "BE": true, "BG": true, "CY": true, "CZ": true, "DE": true, "DK": true,
+	"EE": true, "ES": true, "FI": true, "FR": true, "GR": true, "HR": true, "HU": true,
+	"IE": true, "IT": true, "LT": true, "LU": true, "LV": true, "MT": true, "NL": true,
+	"PL": true, "PT": true, "RO": true, "SE": true, "SI": true, "SK": true,
+}
+
+// ServerEnergyModel estimates per-request emissions from bytes transferred and server CPU time.
+//
+// Transmission uses the NetworkTransmission factor (g CO2/GB) for the connection type, scaled
+// by the user's grid intensity relative to the global average the factors assume. Computation
+// converts CPU-seconds to energy at WattsPerCPU, applies the edge region's DataCenterPUE and
+// multiplies by the edge's grid intensity.
+type ServerEnergyModel struct {
+	factors        EmissionFactors
+	connectionType string
+	wattsPerCPU    float64
+}
+
+// NewServerEnergyModel creates a server energy model with a fixed-broadband connection and 15 W per CPU
+func NewServerEnergyModel(factors EmissionFactors) *ServerEnergyModel {
+	return &ServerEnergyModel{
+		factors:        factors,
+		connectionType: "fixed_broad",
+		wattsPerCPU:    15.0,
+	}
+}
+
+// WithConnectionType returns a copy of the model using another NetworkTransmission factor
+func (m *ServerEnergyModel) WithConnectionType(connectionType string) *ServerEnergyModel {
+	copied := *m
+	copied.connectionType = connectionType
+	return &copied
+}
+
+// EstimateRequestEmissions implements carbon.EnergyModel
+func (m *ServerEnergyModel) EstimateRequestEmissions(profile carbon.RequestProfile, userZone, edgeZone string, userIntensity, edgeIntensity float64) carbon.RequestEmissions {
+	networkFactor := m.factors.NetworkTransmission[m.connectionType]
+	if reference := m.factors.GridCarbonIntensity["global"]; reference > 0 {
+		networkFactor *= userIntensity / reference
+	}
+	transmission := float64(profile.Bytes) / 1e9 * networkFactor
+
+	pue := m.pue(edgeZone)
+	serverEnergy := profile.CPUSeconds * m.wattsPerCPU / 3600.0 / 1000.0 * pue
+	computation := serverEnergy * edgeIntensity
+
+	return carbon.RequestEmissions{
+		Profile:           profile,
+		TransmissionGrams: transmission,
+		ComputationGrams:  computation,
+		TotalGrams:        transmission + computation,
+		ServerEnergyKWh:   serverEnergy,
+		PUE:               pue,
+		NetworkGramsPerGB: networkFactor,
+		Model:             ServerEnergyModelName,
+	}
+}
+
+// pue returns the data center PUE for a grid zone's region, falling back to the global average
+func (m *ServerEnergyModel) pue(gridZone string) float64 {
+	country, _, _ := strings.Cut(strings.ToUpper(gridZone), "-")
+
+	region := "global"
+	switch {
+	case euGridZones[country]:
+		region = "EU"
+	case country == "US":
+		region = "US"
+	}
+
+	if pue, exists := m.factors.DataCenterPUE[region]; exists && pue > 0 {
+		return pue
+	}
+	if pue, exists := m.factors.DataCenterPUE["global"]; exists && pue > 0 {
+		return pue
+	}
+	return 1.0
+}
+
+var _ carbon.EnergyModel = (*ServerEnergyModel)(nil)
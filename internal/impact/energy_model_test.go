@@ -0,0 +1,55 @@
+package impact
+
+import (
+	"math"
+	"testing"
+
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+)
+
+func TestServerEnergyModel_EstimateRequestEmissions(t *testing.T) {
+	model := NewServerEnergyModel(DefaultEmissionFactors)
+
+	profile := carbon.RequestProfile{Bytes: 1e9, CPUSeconds: 3600}
+	emissions := model.EstimateRequestEmissions(profile, "DE", "SE", 475, 40)
+
+	// At the global average intensity the network factor is used unscaled
+	if math.Abs(emissions.TransmissionGrams-3.2) > 1e-9 {
+		t.Errorf("Expected 3.2 g transmission, got %f", emissions.TransmissionGrams)
+	}
+
+	// 15 W for one hour at EU PUE 1.6 and 40 g/kWh
+	expectedEnergy := 0.015 * 1.6
+	if math.Abs(emissions.ServerEnergyKWh-expectedEnergy) > 1e-9 {
+		t.Errorf("Expected %f kWh, got %f", expectedEnergy, emissions.ServerEnergyKWh)
+	}
+	if math.Abs(emissions.ComputationGrams-expectedEnergy*40) > 1e-9 {
+		t.Errorf("Expected %f g computation, got %f", expectedEnergy*40, emissions.ComputationGrams)
+	}
+	if emissions.TotalGrams != emissions.TransmissionGrams+emissions.ComputationGrams {
+		t.Errorf("Expected total to be the sum of components")
+	}
+}
+
+func TestServerEnergyModel_ScalesWithGrids(t *testing.T) {
+	model := NewServerEnergyModel(DefaultEmissionFactors)
+	profile := carbon.RequestProfile{Bytes: 500000, CPUSeconds: 0.01}
+
+	clean := model.EstimateRequestEmissions(profile, "FR", "SE", 50, 40)
+	dirty := model.EstimateRequestEmissions(profile, "PL", "US-MIDA", 700, 450)
+
+	if dirty.TransmissionGrams <= clean.TransmissionGrams {
+		t.Errorf("Expected transmission to grow with user intensity")
+	}
+	if dirty.ComputationGrams <= clean.ComputationGrams {
+		t.Errorf("Expected computation to grow with edge intensity")
+	}
+	if dirty.PUE != 1.8 || clean.PUE != 1.6 {
+		t.Errorf("Expected US PUE 1.8 and EU PUE 1.6, got %f and %f", dirty.PUE, clean.PUE)
+	}
+
+	mobile := model.WithConnectionType("mobile_4g").EstimateRequestEmissions(profile, "FR", "SE", 50, 40)
+	if mobile.TransmissionGrams <= clean.TransmissionGrams {
+		t.Errorf("Expected mobile transmission to exceed fixed broadband")
+	}
+}
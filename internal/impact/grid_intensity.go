@@ -0,0 +1,138 @@
+package impact
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+)
+
+// currentIntensityWindow is how close to now a timestamp must be to use the current reading
+const currentIntensityWindow = 15 * time.Minute
+
+// historicalIntensityWindow is how far from the timestamp historical readings are searched
+const historicalIntensityWindow = time.Hour
+
+// zoneRegionPrefix marks the synthetic region that carries a zone's intensity through the
+// activity models
+const zoneRegionPrefix = "zone:"
+
+// intensityAt returns the zone's reading closest to at: the current reading for recent
+// timestamps, otherwise the nearest historical reading within an hour
+func (c *Calculator) intensityAt(ctx context.Context, zone string, at time.Time) (*carbon.CarbonIntensity, error) {
+	now := time.Now()
+	if at.IsZero() || now.Sub(at) <= currentIntensityWindow {
+		return c.intensity.GetCarbonIntensity(ctx, zone)
+	}
+
+	readings, err := c.intensity.GetHistoricalCarbonIntensity(ctx, zone, at.Add(-historicalIntensityWindow), at.Add(historicalIntensityWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	var closest *carbon.CarbonIntensity
+	for i := range readings {
+		if closest == nil || absDuration(readings[i].Timestamp.Sub(at)) < absDuration(closest.Timestamp.Sub(at)) {
+			closest = &readings[i]
+		}
+	}
+	if closest == nil {
+		return nil, fmt.Errorf("no intensity readings for %s around %s", zone, at.Format(time.RFC3339))
+	}
+	return closest, nil
+}
+
+// averageGridIntensity returns the static grid intensity the average-factor result used
+func (c *Calculator) averageGridIntensity(req *CalculationRequest) float64 {
+	switch req.Methodology {
+	case MethodologySWD:
+		return c.gridIntensityOr(req.Region, swdGridIntensity)
+	case MethodologyOneByte:
+		return c.gridIntensityOr(req.Region, oneByteNetworkGrid)
+	default:
+		return c.factors.GridCarbonIntensity[req.Region]
+	}
+}
+
+// calculateTimeSpecific repeats the calculation with the zone's intensity at the
+// requested time in place of the regional average
+func (c *Calculator) calculateTimeSpecific(ctx context.Context, req *CalculationRequest, average *ImpactResult) (*TimeSpecificImpact, error) {
+	reading, err := c.intensityAt(ctx, req.Zone, req.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	region := zoneRegionPrefix + req.Zone
+	factors := c.factors
+	factors.GridCarbonIntensity = make(map[string]float64, len(c.factors.GridCarbonIntensity)+1)
+	for key, value := range c.factors.GridCarbonIntensity {
+		factors.GridCarbonIntensity[key] = value
+	}
+	factors.GridCarbonIntensity[region] = reading.CarbonIntensity
+
+	live := *req
+	live.Region = region
+	live.Zone = ""
+	live.Timestamp = time.Time{}
+	result, err := NewCalculatorWithFactors(factors).CalculateWithContext(ctx, &live)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := req.Timestamp
+	if timestamp.IsZero() {
+		timestamp = reading.Timestamp
+	}
+	timeSpecific := &TimeSpecificImpact{
+		Zone:                 req.Zone,
+		Timestamp:            timestamp,
+		GridIntensity:        reading.CarbonIntensity,
+		AverageGridIntensity: c.averageGridIntensity(req),
+		IntensityTimestamp:   reading.Timestamp,
+		IntensitySource:      reading.Source,
+		BaselineEmissions:    result.BaselineEmissions,
+		OptimizedEmissions:   result.OptimizedEmissions,
+		Savings:              result.Savings,
+		NetSavings:           result.NetSavings,
+		LowerBound:           result.LowerBound,
+		UpperBound:           result.UpperBound,
+	}
+	if average.OptimizedEmissions > 0 {
+		timeSpecific.DifferencePercentage = (result.OptimizedEmissions - average.OptimizedEmissions) / average.OptimizedEmissions * 100
+	}
+	return timeSpecific, nil
+}
+
+// addTimeSpecificResult attaches the time-specific result, or a warning when no
+// intensity is available; the average-factor result stands either way
+func (c *Calculator) addTimeSpecificResult(ctx context.Context, result *ImpactResult, req *CalculationRequest) {
+	if req.Zone == "" {
+		return
+	}
+	if c.intensity == nil {
+		result.Warnings = append(result.Warnings,
+			fmt.Sprintf("No grid intensity source configured; %s is estimated with the %s average", req.Zone, req.Region))
+		return
+	}
+
+	timeSpecific, err := c.calculateTimeSpecific(ctx, req, result)
+	if err != nil {
+		result.Warnings = append(result.Warnings,
+			fmt.Sprintf("Grid intensity for %s unavailable (%v); only the %s average result is reported", req.Zone, err, req.Region))
+		return
+	}
+	if carbon.IsSyntheticSource(timeSpecific.IntensitySource) {
+		result.Warnings = append(result.Warnings,
+			fmt.Sprintf("Grid intensity for %s comes from synthetic %q data, not the measured grid", req.Zone, timeSpecific.IntensitySource))
+	}
+	result.TimeSpecific = timeSpecific
+}
+
+// absDuration returns the absolute value of a duration
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
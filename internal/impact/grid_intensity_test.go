@@ -0,0 +1,135 @@
+package impact
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+)
+
+// fakeIntensitySource returns a fixed current reading and hourly historical readings
+type fakeIntensitySource struct {
+	current    float64
+	historical func(at time.Time) float64
+	err        error
+}
+
+func (f *fakeIntensitySource) GetCarbonIntensity(ctx context.Context, location string) (*carbon.CarbonIntensity, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &carbon.CarbonIntensity{Location: location, CarbonIntensity: f.current, Timestamp: time.Now(), Source: "electricity_maps"}, nil
+}
+
+func (f *fakeIntensitySource) GetGreenHoursForecast(ctx context.Context, location string, hours int) (*carbon.GreenHoursForecast, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeIntensitySource) IsHealthy(ctx context.Context) bool { return f.err == nil }
+
+func (f *fakeIntensitySource) GetSupportedLocations(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeIntensitySource) GetHistoricalCarbonIntensity(ctx context.Context, location string, start, end time.Time) ([]carbon.CarbonIntensity, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	var readings []carbon.CarbonIntensity
+	for at := start.Truncate(time.Hour); !at.After(end); at = at.Add(time.Hour) {
+		readings = append(readings, carbon.CarbonIntensity{Location: location, CarbonIntensity: f.historical(at), Timestamp: at, Source: "mock_historical"})
+	}
+	return readings, nil
+}
+
+func (f *fakeIntensitySource) GetAverageCarbonIntensity(ctx context.Context, location string, start, end time.Time) (float64, error) {
+	return f.current, f.err
+}
+
+func TestCalculator_TimeSpecificIntensity(t *testing.T) {
+	at := time.Now().Add(-48 * time.Hour).Truncate(time.Hour).Add(20 * time.Minute)
+	source := &fakeIntensitySource{
+		current: 590, // Twice the EU average
+		historical: func(reading time.Time) float64 {
+			if reading.Equal(at.Truncate(time.Hour)) {
+				return 59 // Nearest reading to the timestamp
+			}
+			return 1000
+		},
+	}
+	calculator := NewCalculatorWithIntensitySource(DefaultEmissionFactors, source)
+
+	t.Run("current intensity", func(t *testing.T) {
+		req := &CalculationRequest{Type: ImpactTypePageLoad, Region: "EU", Zone: "DE"}
+		result, err := calculator.Calculate(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.TimeSpecific == nil {
+			t.Fatalf("expected a time-specific result, warnings: %v", result.Warnings)
+		}
+		ts := result.TimeSpecific
+		if ts.GridIntensity != 590 || ts.AverageGridIntensity != 295 {
+			t.Errorf("unexpected intensities: %+v", ts)
+		}
+		// Device and data center energy scale with grid intensity; network factors are per GB
+		components := result.Components
+		expected := 2*(components.DeviceEmissions+components.DataCenterEmissions) + components.NetworkEmissions
+		if abs(ts.BaselineEmissions-expected) > 1e-9 || ts.DifferencePercentage <= 0 {
+			t.Errorf("expected baseline %f at twice the intensity, got %f (%.2f%%)", expected, ts.BaselineEmissions, ts.DifferencePercentage)
+		}
+	})
+
+	t.Run("historical intensity with swd", func(t *testing.T) {
+		req := &CalculationRequest{Type: ImpactTypeDataTransfer, DataSize: 1000, Methodology: MethodologySWD, Zone: "SE", Timestamp: at}
+		result, err := calculator.Calculate(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts := result.TimeSpecific
+		if ts == nil || ts.GridIntensity != 59 || !ts.Timestamp.Equal(at) {
+			t.Fatalf("expected the nearest historical reading, got %+v", ts)
+		}
+		if expected := 0.81 * (0.75 + 0.25*0.02) * 59; abs(ts.BaselineEmissions-expected) > 0.01 {
+			t.Errorf("expected %.2f g at historical intensity, got %.2f g", expected, ts.BaselineEmissions)
+		}
+		if !containsWarning(result.Warnings, "synthetic") {
+			t.Errorf("expected a synthetic data warning, got %v", result.Warnings)
+		}
+	})
+
+	t.Run("unavailable intensity keeps the average result", func(t *testing.T) {
+		failing := NewCalculatorWithIntensitySource(DefaultEmissionFactors, &fakeIntensitySource{err: errors.New("upstream down")})
+		result, err := failing.Calculate(&CalculationRequest{Type: ImpactTypePageLoad, Zone: "DE"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.TimeSpecific != nil || !containsWarning(result.Warnings, "upstream down") {
+			t.Errorf("expected only a warning, got %+v, %v", result.TimeSpecific, result.Warnings)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		invalid := []CalculationRequest{
+			{Type: ImpactTypePageLoad, Timestamp: at},
+			{Type: ImpactTypePageLoad, Zone: "DE", Timestamp: time.Now().Add(24 * time.Hour)},
+		}
+		for _, req := range invalid {
+			if _, err := calculator.Calculate(&req); err == nil {
+				t.Errorf("expected validation error for %+v", req)
+			}
+		}
+	})
+}
+
+func containsWarning(warnings []string, substring string) bool {
+	for _, warning := range warnings {
+		if strings.Contains(warning, substring) {
+			return true
+		}
+	}
+	return false
+}
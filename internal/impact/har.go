@@ -0,0 +1,596 @@
+package impact
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+	"golang.org/x/net/publicsuffix"
+)
+
+// ErrInvalidHAR is returned when an uploaded HAR file cannot be audited
+var ErrInvalidHAR = errors.New("invalid HAR file")
+
+// defaultHeaviestResources is the number of ranked resources returned when unset
+const defaultHeaviestResources = 10
+
+// bytesPerMB converts bytes to the decimal megabytes used by the calculator
+const bytesPerMB = 1_000_000
+
+// Resource types in the HAR breakdown
+const (
+	ResourceTypeDocument   = "document"
+	ResourceTypeScript     = "script"
+	ResourceTypeStylesheet = "stylesheet"
+	ResourceTypeImage      = "image"
+	ResourceTypeVideo      = "video"
+	ResourceTypeFont       = "font"
+	ResourceTypeOther      = "other"
+)
+
+// harFile is the subset of the HAR 1.2 format used by the audit
+type harFile struct {
+	Log struct {
+		Pages []struct {
+			ID          string `json:"id"`
+			Title       string `json:"title"`
+			PageTimings struct {
+				OnLoad float64 `json:"onLoad"` // ms, -1 if unknown
+			} `json:"pageTimings"`
+		} `json:"pages"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+// harEntry is a single request in a HAR file. _transferSize and _fromCache are
+// Chromium extensions; other browsers only report header and body sizes.
+type harEntry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+	Time            float64   `json:"time"` // ms
+	Request         struct {
+		URL string `json:"url"`
+	} `json:"request"`
+	Response struct {
+		Status       int    `json:"status"`
+		HeadersSize  int64  `json:"headersSize"`
+		BodySize     int64  `json:"bodySize"`
+		TransferSize *int64 `json:"_transferSize"`
+		Content      struct {
+			Size     int64  `json:"size"`
+			MimeType string `json:"mimeType"`
+		} `json:"content"`
+	} `json:"response"`
+	FromCache string `json:"_fromCache"`
+}
+
+// transferred returns the bytes sent over the network for the entry
+func (e *harEntry) transferred() int64 {
+	if e.Response.TransferSize != nil && *e.Response.TransferSize >= 0 {
+		return *e.Response.TransferSize
+	}
+	var size int64
+	if e.Response.HeadersSize > 0 {
+		size += e.Response.HeadersSize
+	}
+	if e.Response.BodySize > 0 {
+		size += e.Response.BodySize
+	}
+	return size
+}
+
+// cached reports whether the entry was served from the browser cache or revalidated
+func (e *harEntry) cached() bool {
+	if e.Response.Status == 304 || e.FromCache != "" {
+		return true
+	}
+	return e.Response.Status == 200 && e.transferred() == 0 && e.Response.Content.Size > 0
+}
+
+// HARAuditParams describes the conditions a HAR file is evaluated under
+type HARAuditParams struct {
+	DeviceType     string
+	ConnectionType string
+	Region         string
+	Methodology    CalculationMethodology
+
+	// TopResources limits the ranked resource list, 10 if zero
+	TopResources int
+}
+
+// HARAudit is the page-weight audit of a HAR file
+type HARAudit struct {
+	// URL of the audited page
+	URL string `json:"url"`
+
+	// Baseline measured for the page; audits do not store it
+	Baseline *BaselineMeasurement `json:"baseline"`
+
+	// Impact of loading the page as recorded
+	Impact *ImpactResult `json:"impact"`
+
+	// Breakdown of transferred bytes
+	Breakdown HARBreakdown `json:"breakdown"`
+
+	// HeaviestResources ranked by transferred bytes
+	HeaviestResources []HARResource `json:"heaviest_resources"`
+
+	// ModeSavings estimates the savings of each optimization mode
+	ModeSavings []ModeSavings `json:"mode_savings"`
+
+	// Warnings about the HAR file or the estimates
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// HARBreakdown summarizes transferred bytes by resource type, party and cache status
+type HARBreakdown struct {
+	// Requests in the HAR file
+	Requests int `json:"requests"`
+
+	// TransferredBytes over the network
+	TransferredBytes int64 `json:"transferred_bytes"`
+
+	// ContentBytes after decompression, including cached resources
+	ContentBytes int64 `json:"content_bytes"`
+
+	// ByType breaks transferred bytes down by resource type
+	ByType map[string]ResourceBreakdown `json:"by_type"`
+
+	// ByMIMEType breaks transferred bytes down by MIME type
+	ByMIMEType map[string]ResourceBreakdown `json:"by_mime_type"`
+
+	// FirstParty resources share the page's registrable domain
+	FirstParty PartyBreakdown `json:"first_party"`
+
+	// ThirdParty resources are served from other domains
+	ThirdParty PartyBreakdown `json:"third_party"`
+
+	// CacheHits served from the browser cache or revalidated with 304
+	CacheHits int `json:"cache_hits"`
+
+	// CacheHitRatio of requests
+	CacheHitRatio float64 `json:"cache_hit_ratio"`
+
+	// CachedBytes not transferred thanks to cache hits
+	CachedBytes int64 `json:"cached_bytes"`
+}
+
+// ResourceBreakdown counts requests and transferred bytes
+type ResourceBreakdown struct {
+	Requests   int     `json:"requests"`
+	Bytes      int64   `json:"bytes"`
+	Percentage float64 `json:"percentage"`
+}
+
+// PartyBreakdown counts requests and transferred bytes for first or third parties
+type PartyBreakdown struct {
+	ResourceBreakdown
+	Domains []string `json:"domains"`
+}
+
+// HARResource is a single resource from the HAR file
+type HARResource struct {
+	URL        string  `json:"url"`
+	Domain     string  `json:"domain"`
+	MIMEType   string  `json:"mime_type"`
+	Type       string  `json:"type"`
+	Bytes      int64   `json:"bytes"`
+	Percentage float64 `json:"percentage"`
+	ThirdParty bool    `json:"third_party"`
+	Cached     bool    `json:"cached"`
+
+	// Emissions is the resource's share of the data-driven emissions in g CO2e
+	Emissions float64 `json:"emissions"`
+}
+
+// ModeSavings estimates page weight and emissions under an optimization mode
+type ModeSavings struct {
+	Mode optimization.OptimizationMode `json:"mode"`
+
+	// DataTransferred in MB under the mode
+	DataTransferred float64 `json:"data_transferred"`
+
+	// BytesSaved compared with the recorded page
+	BytesSaved int64 `json:"bytes_saved"`
+
+	// Emissions per page load in g CO2e under the mode
+	Emissions float64 `json:"emissions"`
+
+	// Savings per page load in g CO2e
+	Savings float64 `json:"savings"`
+
+	// SavingsPercentage of the recorded page's emissions
+	SavingsPercentage float64 `json:"savings_percentage"`
+
+	// Reductions applied by resource type, as a share of bytes
+	Reductions map[string]float64 `json:"reductions"`
+}
+
+// modeReduction is the share of bytes an optimization mode removes by resource
+// type. Third-party reductions cover deferred analytics and features the mode disables.
+type modeReduction struct {
+	byType     map[string]float64
+	thirdParty float64
+}
+
+// imageQualityReductions are the byte savings of recompressing images at each quality
+var imageQualityReductions = map[optimization.ImageQuality]float64{
+	optimization.ImageQualityHigh:   0,
+	optimization.ImageQualityMedium: 0.3,
+	optimization.ImageQualityLow:    0.6,
+}
+
+// videoReduction returns the byte savings of capping video at quality, relative to 1080p
+func videoReduction(quality optimization.VideoQuality) float64 {
+	return 1 - videoBitrates[string(quality)]/videoBitrates["1080p"]
+}
+
+// modeReductions mirror the image, video, feature and minification settings the
+// optimization service applies in each mode
+var modeReductions = map[optimization.OptimizationMode]modeReduction{
+	optimization.ModeFull: {
+		byType: map[string]float64{
+			ResourceTypeImage:      imageQualityReductions[optimization.ImageQualityHigh],
+			ResourceTypeVideo:      videoReduction(optimization.VideoQuality1080p),
+			ResourceTypeScript:     0.1, // Minification
+			ResourceTypeStylesheet: 0.1,
+		},
+	},
+	optimization.ModeNormal: {
+		byType: map[string]float64{
+			ResourceTypeImage:      imageQualityReductions[optimization.ImageQualityMedium],
+			ResourceTypeVideo:      videoReduction(optimization.VideoQuality720p),
+			ResourceTypeScript:     0.15,
+			ResourceTypeStylesheet: 0.15,
+			ResourceTypeDocument:   0.05, // HTML minification
+		},
+	},
+	optimization.ModeEco: {
+		byType: map[string]float64{
+			ResourceTypeImage:      imageQualityReductions[optimization.ImageQualityLow],
+			ResourceTypeVideo:      videoReduction(optimization.VideoQuality480p),
+			ResourceTypeScript:     0.25,
+			ResourceTypeStylesheet: 0.2,
+			ResourceTypeDocument:   0.05,
+			ResourceTypeFont:       1, // System fonts
+		},
+		thirdParty: 0.5,
+	},
+	optimization.ModeCritical: {
+		byType: map[string]float64{
+			ResourceTypeImage:      imageQualityReductions[optimization.ImageQualityLow],
+			ResourceTypeVideo:      1, // Video streaming disabled
+			ResourceTypeScript:     0.4,
+			ResourceTypeStylesheet: 0.3,
+			ResourceTypeDocument:   0.05,
+			ResourceTypeFont:       1,
+		},
+		thirdParty: 0.8,
+	},
+}
+
+// auditModes is the order modes are reported in
+var auditModes = []optimization.OptimizationMode{
+	optimization.ModeFull,
+	optimization.ModeNormal,
+	optimization.ModeEco,
+	optimization.ModeCritical,
+}
+
+// resourceType classifies a MIME type
+func resourceType(mimeType string) string {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return ResourceTypeImage
+	case strings.HasPrefix(mimeType, "video/"), strings.HasPrefix(mimeType, "audio/"),
+		mimeType == "application/vnd.apple.mpegurl", mimeType == "application/dash+xml":
+		return ResourceTypeVideo
+	case strings.HasPrefix(mimeType, "font/"), strings.Contains(mimeType, "font"):
+		return ResourceTypeFont
+	case strings.Contains(mimeType, "javascript"), strings.Contains(mimeType, "ecmascript"), mimeType == "application/wasm":
+		return ResourceTypeScript
+	case mimeType == "text/css":
+		return ResourceTypeStylesheet
+	case mimeType == "text/html", mimeType == "application/xhtml+xml":
+		return ResourceTypeDocument
+	default:
+		return ResourceTypeOther
+	}
+}
+
+// siteOf returns the registrable domain of a URL, or its host when there is none
+func siteOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	host := parsed.Hostname()
+	if site, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return site
+	}
+	return host
+}
+
+// parseHAR decodes a HAR file, skipping entries that are not HTTP(S) requests
+func parseHAR(r io.Reader) (*harFile, error) {
+	var har harFile
+	if err := json.NewDecoder(r).Decode(&har); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHAR, err)
+	}
+
+	entries := har.Log.Entries[:0]
+	for _, entry := range har.Log.Entries {
+		if strings.HasPrefix(entry.Request.URL, "http://") || strings.HasPrefix(entry.Request.URL, "https://") {
+			entries = append(entries, entry)
+		}
+	}
+	har.Log.Entries = entries
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: no HTTP requests recorded", ErrInvalidHAR)
+	}
+	return &har, nil
+}
+
+// pageURL returns the URL of the page: the first HTML document, otherwise the first request
+func pageURL(har *harFile) string {
+	for _, entry := range har.Log.Entries {
+		if resourceType(normalizeMIMEType(entry.Response.Content.MimeType)) == ResourceTypeDocument {
+			return entry.Request.URL
+		}
+	}
+	return har.Log.Entries[0].Request.URL
+}
+
+// loadTime returns the page load time in seconds from the page timings, otherwise
+// from the span of the recorded requests
+func loadTime(har *harFile) float64 {
+	if len(har.Log.Pages) > 0 && har.Log.Pages[0].PageTimings.OnLoad > 0 {
+		return har.Log.Pages[0].PageTimings.OnLoad / 1000.0
+	}
+
+	var start, end time.Time
+	for _, entry := range har.Log.Entries {
+		if entry.StartedDateTime.IsZero() {
+			continue
+		}
+		finished := entry.StartedDateTime.Add(time.Duration(entry.Time * float64(time.Millisecond)))
+		if start.IsZero() || entry.StartedDateTime.Before(start) {
+			start = entry.StartedDateTime
+		}
+		if finished.After(end) {
+			end = finished
+		}
+	}
+	return end.Sub(start).Seconds()
+}
+
+// normalizeMIMEType strips parameters from a MIME type
+func normalizeMIMEType(mimeType string) string {
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// analyzeHAR builds the byte breakdown and resource list of a HAR file
+func analyzeHAR(har *harFile, page string) (HARBreakdown, []HARResource) {
+	pageSite := siteOf(page)
+	breakdown := HARBreakdown{
+		Requests:   len(har.Log.Entries),
+		ByType:     make(map[string]ResourceBreakdown),
+		ByMIMEType: make(map[string]ResourceBreakdown),
+		FirstParty: PartyBreakdown{Domains: []string{}},
+		ThirdParty: PartyBreakdown{Domains: []string{}},
+	}
+	resources := make([]HARResource, 0, len(har.Log.Entries))
+	domains := map[bool]map[string]bool{false: {}, true: {}}
+
+	for i := range har.Log.Entries {
+		entry := &har.Log.Entries[i]
+		mimeType := normalizeMIMEType(entry.Response.Content.MimeType)
+		if mimeType == "" {
+			mimeType = "unknown"
+		}
+		site := siteOf(entry.Request.URL)
+		resource := HARResource{
+			URL:        entry.Request.URL,
+			Domain:     site,
+			MIMEType:   mimeType,
+			Type:       resourceType(mimeType),
+			Bytes:      entry.transferred(),
+			ThirdParty: site != pageSite,
+			Cached:     entry.cached(),
+		}
+		resources = append(resources, resource)
+
+		breakdown.TransferredBytes += resource.Bytes
+		if entry.Response.Content.Size > 0 {
+			breakdown.ContentBytes += entry.Response.Content.Size
+		}
+		if resource.Cached {
+			breakdown.CacheHits++
+			if cachedBytes := entry.Response.Content.Size - entry.Response.BodySize; cachedBytes > 0 {
+				breakdown.CachedBytes += cachedBytes
+			}
+		}
+
+		addResource(breakdown.ByType, resource.Type, resource.Bytes)
+		addResource(breakdown.ByMIMEType, mimeType, resource.Bytes)
+		party := &breakdown.FirstParty
+		if resource.ThirdParty {
+			party = &breakdown.ThirdParty
+		}
+		party.Requests++
+		party.Bytes += resource.Bytes
+		if !domains[resource.ThirdParty][site] {
+			domains[resource.ThirdParty][site] = true
+			party.Domains = append(party.Domains, site)
+		}
+	}
+
+	breakdown.CacheHitRatio = float64(breakdown.CacheHits) / float64(breakdown.Requests)
+	if breakdown.TransferredBytes > 0 {
+		total := float64(breakdown.TransferredBytes)
+		for _, counts := range []map[string]ResourceBreakdown{breakdown.ByType, breakdown.ByMIMEType} {
+			for key, entry := range counts {
+				entry.Percentage = float64(entry.Bytes) / total * 100
+				counts[key] = entry
+			}
+		}
+		breakdown.FirstParty.Percentage = float64(breakdown.FirstParty.Bytes) / total * 100
+		breakdown.ThirdParty.Percentage = float64(breakdown.ThirdParty.Bytes) / total * 100
+		for i := range resources {
+			resources[i].Percentage = float64(resources[i].Bytes) / total * 100
+		}
+	}
+	sort.Strings(breakdown.FirstParty.Domains)
+	sort.Strings(breakdown.ThirdParty.Domains)
+
+	return breakdown, resources
+}
+
+// addResource adds a request to a breakdown entry
+func addResource(counts map[string]ResourceBreakdown, key string, bytes int64) {
+	entry := counts[key]
+	entry.Requests++
+	entry.Bytes += bytes
+	counts[key] = entry
+}
+
+// AuditHAR audits the page weight recorded in a HAR file, stores it as a baseline
+// and estimates the savings of each optimization mode
+func (s *Service) AuditHAR(ctx context.Context, r io.Reader, params HARAuditParams) (*HARAudit, error) {
+	har, err := parseHAR(r)
+	if err != nil {
+		return nil, err
+	}
+
+	page := pageURL(har)
+	breakdown, resources := analyzeHAR(har, page)
+	if breakdown.TransferredBytes == 0 {
+		return nil, fmt.Errorf("%w: no transferred bytes recorded", ErrInvalidHAR)
+	}
+
+	audit := &HARAudit{URL: page, Breakdown: breakdown}
+	seconds := loadTime(har)
+	if seconds <= 0 {
+		seconds = 5.0 // Calculator default
+		audit.Warnings = append(audit.Warnings, "No page timings recorded; assuming a 5 second load time")
+	}
+	if har.Log.Entries[0].Response.TransferSize == nil {
+		audit.Warnings = append(audit.Warnings, "HAR file has no transfer sizes; using header and body sizes, which may include cached responses")
+	}
+	if len(har.Log.Pages) > 1 {
+		audit.Warnings = append(audit.Warnings,
+			fmt.Sprintf("HAR file contains %d pages; all requests are audited as a single page load", len(har.Log.Pages)))
+	}
+
+	dataMB := float64(breakdown.TransferredBytes) / bytesPerMB
+	req := &CalculationRequest{
+		Type:           ImpactTypePageLoad,
+		Duration:       seconds,
+		DataSize:       dataMB,
+		DeviceType:     params.DeviceType,
+		ConnectionType: params.ConnectionType,
+		Region:         params.Region,
+		Methodology:    params.Methodology,
+	}
+	audit.Impact, err = s.CalculateImpact(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resourceCount := make(map[string]int, len(breakdown.ByType))
+	for resource, counts := range breakdown.ByType {
+		resourceCount[resource] = counts.Requests
+	}
+	// The audit endpoint is public; storing its baselines would let anyone skew a page's
+	// trend and regression alerts, so only authenticated imports persist
+	audit.Baseline = s.newBaseline(page, BaselineParams{
+		DataTransferred: dataMB,
+		JavaScriptSize:  float64(breakdown.ByType[ResourceTypeScript].Bytes) / 1000.0,
+		ImageSize:       float64(breakdown.ByType[ResourceTypeImage].Bytes) / bytesPerMB,
+		VideoSize:       float64(breakdown.ByType[ResourceTypeVideo].Bytes) / bytesPerMB,
+		LoadTime:        seconds,
+		ResourceCount:   resourceCount,
+		DeviceType:      req.DeviceType,
+		ConnectionType:  req.ConnectionType,
+		Region:          req.Region,
+	})
+
+	// Data-driven emissions are spread over resources by transferred bytes
+	components := audit.Impact.Components
+	dataEmissions := components.NetworkEmissions + components.DataCenterEmissions
+	if req.Methodology == MethodologySWD {
+		dataEmissions = audit.Impact.BaselineEmissions
+	}
+	for i := range resources {
+		resources[i].Emissions = dataEmissions * resources[i].Percentage / 100
+	}
+
+	sort.SliceStable(resources, func(i, j int) bool { return resources[i].Bytes > resources[j].Bytes })
+	top := params.TopResources
+	if top <= 0 {
+		top = defaultHeaviestResources
+	}
+	if top > len(resources) {
+		top = len(resources)
+	}
+	audit.HeaviestResources = resources[:top]
+
+	audit.ModeSavings, err = s.modeSavings(ctx, req, resources, audit.Impact.BaselineEmissions)
+	if err != nil {
+		return nil, err
+	}
+	return audit, nil
+}
+
+// modeSavings recalculates the page load with each mode's byte reductions applied
+func (s *Service) modeSavings(ctx context.Context, req *CalculationRequest, resources []HARResource, baseline float64) ([]ModeSavings, error) {
+	savings := make([]ModeSavings, 0, len(auditModes))
+	for _, mode := range auditModes {
+		reduction := modeReductions[mode]
+		var saved float64
+		for _, resource := range resources {
+			share := reduction.byType[resource.Type]
+			if resource.ThirdParty && reduction.thirdParty > share {
+				share = reduction.thirdParty
+			}
+			saved += float64(resource.Bytes) * share
+		}
+
+		optimized := *req
+		// A zero data size means "unknown" to the calculator, so keep at least a byte
+		optimized.DataSize = math.Max(req.DataSize-saved/bytesPerMB, 1.0/bytesPerMB)
+		result, err := s.calculator.CalculateWithContext(ctx, &optimized)
+		if err != nil {
+			return nil, fmt.Errorf("calculation failed for %s mode: %w", mode, err)
+		}
+
+		reductions := make(map[string]float64, len(reduction.byType)+1)
+		for resource, share := range reduction.byType {
+			reductions[resource] = share
+		}
+		if reduction.thirdParty > 0 {
+			reductions["third_party"] = reduction.thirdParty
+		}
+		modeSaving := ModeSavings{
+			Mode:            mode,
+			DataTransferred: optimized.DataSize,
+			BytesSaved:      int64(saved),
+			Emissions:       result.BaselineEmissions,
+			Savings:         baseline - result.BaselineEmissions,
+			Reductions:      reductions,
+		}
+		if baseline > 0 {
+			modeSaving.SavingsPercentage = modeSaving.Savings / baseline * 100
+		}
+		savings = append(savings, modeSaving)
+	}
+	return savings, nil
+}
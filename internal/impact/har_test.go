@@ -0,0 +1,116 @@
+package impact
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+)
+
+// testHAR records a page with a first-party document, image and script, a
+// third-party analytics script and a revalidated stylesheet
+const testHAR = `{
+  "log": {
+    "pages": [{"id": "page_1", "title": "Shop", "pageTimings": {"onLoad": 2500}}],
+    "entries": [
+      {"request": {"url": "https://www.example.co.uk/"},
+       "response": {"status": 200, "headersSize": 400, "bodySize": 50000, "_transferSize": 50400,
+                    "content": {"size": 200000, "mimeType": "text/html; charset=utf-8"}}},
+      {"request": {"url": "https://static.example.co.uk/hero.jpg"},
+       "response": {"status": 200, "headersSize": 300, "bodySize": 1200000, "_transferSize": 1200300,
+                    "content": {"size": 1200000, "mimeType": "image/jpeg"}}},
+      {"request": {"url": "https://static.example.co.uk/app.js"},
+       "response": {"status": 200, "headersSize": 300, "bodySize": 300000, "_transferSize": 300300,
+                    "content": {"size": 900000, "mimeType": "application/javascript"}}},
+      {"request": {"url": "https://www.google-analytics.com/analytics.js"},
+       "response": {"status": 200, "headersSize": 200, "bodySize": 49800, "_transferSize": 50000,
+                    "content": {"size": 150000, "mimeType": "text/javascript"}}},
+      {"request": {"url": "https://static.example.co.uk/site.css"},
+       "response": {"status": 304, "headersSize": 250, "bodySize": 0, "_transferSize": 250,
+                    "content": {"size": 80000, "mimeType": "text/css"}}},
+      {"request": {"url": "data:image/png;base64,AAAA"},
+       "response": {"status": 200, "content": {"size": 4, "mimeType": "image/png"}}}
+    ]
+  }
+}`
+
+func TestAuditHAR(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	service := NewService(storage)
+
+	audit, err := service.AuditHAR(ctx, strings.NewReader(testHAR), HARAuditParams{Region: "EU", TopResources: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if audit.URL != "https://www.example.co.uk/" {
+		t.Errorf("expected the HTML document as page URL, got %s", audit.URL)
+	}
+	breakdown := audit.Breakdown
+	if breakdown.Requests != 5 || breakdown.TransferredBytes != 1601250 {
+		t.Errorf("unexpected totals: %d requests, %d bytes", breakdown.Requests, breakdown.TransferredBytes)
+	}
+	if scripts := breakdown.ByType[ResourceTypeScript]; scripts.Requests != 2 || scripts.Bytes != 350300 {
+		t.Errorf("unexpected script breakdown: %+v", scripts)
+	}
+	if html := breakdown.ByMIMEType["text/html"]; html.Bytes != 50400 {
+		t.Errorf("expected MIME parameters to be stripped, got %+v", breakdown.ByMIMEType)
+	}
+	if breakdown.ThirdParty.Requests != 1 || len(breakdown.ThirdParty.Domains) != 1 || breakdown.ThirdParty.Domains[0] != "google-analytics.com" {
+		t.Errorf("unexpected third-party breakdown: %+v", breakdown.ThirdParty)
+	}
+	if breakdown.FirstParty.Requests != 4 || breakdown.FirstParty.Domains[0] != "example.co.uk" {
+		t.Errorf("expected subdomains of the page to be first party: %+v", breakdown.FirstParty)
+	}
+	if breakdown.CacheHits != 1 || breakdown.CachedBytes != 80000 {
+		t.Errorf("expected the 304 to count as a cache hit: %+v", breakdown)
+	}
+
+	if len(audit.HeaviestResources) != 3 || !strings.HasSuffix(audit.HeaviestResources[0].URL, "hero.jpg") ||
+		audit.HeaviestResources[0].Bytes < audit.HeaviestResources[1].Bytes {
+		t.Errorf("expected resources ranked by bytes, got %+v", audit.HeaviestResources)
+	}
+
+	if _, err := storage.GetBaseline(ctx, audit.Baseline.ID); err == nil {
+		t.Error("expected the audit not to store its baseline")
+	}
+	baseline := audit.Baseline
+	if baseline.LoadTime != 2.5 || baseline.JavaScriptSize != 350.3 || baseline.ResourceCount[ResourceTypeImage] != 1 {
+		t.Errorf("unexpected baseline: %+v", baseline)
+	}
+	if audit.Impact == nil || audit.Impact.BaselineEmissions <= 0 {
+		t.Fatalf("expected an impact result, got %+v", audit.Impact)
+	}
+
+	if len(audit.ModeSavings) != 4 {
+		t.Fatalf("expected savings for every mode, got %d", len(audit.ModeSavings))
+	}
+	previous := -1.0
+	for _, savings := range audit.ModeSavings {
+		if savings.Savings < previous {
+			t.Errorf("expected savings to grow with stricter modes, %s saves %.4f g after %.4f g", savings.Mode, savings.Savings, previous)
+		}
+		previous = savings.Savings
+	}
+	// Eco recompresses images at low quality and halves third-party bytes
+	eco := audit.ModeSavings[2]
+	if eco.Mode != optimization.ModeEco || eco.BytesSaved < 1200300*6/10+50000/2 {
+		t.Errorf("unexpected eco savings: %+v", eco)
+	}
+}
+
+func TestAuditHARInvalid(t *testing.T) {
+	service := NewService(NewMemoryStorage())
+	for name, har := range map[string]string{
+		"malformed":   `{"log": `,
+		"no requests": `{"log": {"entries": []}}`,
+		"no bytes":    `{"log": {"entries": [{"request": {"url": "https://example.com/"}, "response": {"status": 200}}]}}`,
+	} {
+		if _, err := service.AuditHAR(context.Background(), strings.NewReader(har), HARAuditParams{}); !errors.Is(err, ErrInvalidHAR) {
+			t.Errorf("%s: expected ErrInvalidHAR, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package impact
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrInvalidLighthouseReport is returned when a Lighthouse report cannot be imported
+var ErrInvalidLighthouseReport = errors.New("invalid Lighthouse report")
+
+// SourceLighthouse marks baselines imported from Lighthouse reports
+const SourceLighthouse = "lighthouse"
+
+// lighthouseDefaultLoadTime is used when a report has no load metrics, matching the
+// calculator's page load default
+const lighthouseDefaultLoadTime = 5.0
+
+// lighthouseReport is the subset of a Lighthouse result (LHR) used by the importer.
+// PageSpeed Insights responses wrap it in lighthouseResult.
+type lighthouseReport struct {
+	LighthouseVersion string    `json:"lighthouseVersion"`
+	RequestedURL      string    `json:"requestedUrl"`
+	FinalURL          string    `json:"finalUrl"`          // Lighthouse 9 and earlier
+	FinalDisplayedURL string    `json:"finalDisplayedUrl"` // Lighthouse 10 and later
+	FetchTime         time.Time `json:"fetchTime"`
+	ConfigSettings    struct {
+		FormFactor string `json:"formFactor"`
+	} `json:"configSettings"`
+	Audits map[string]lighthouseAudit `json:"audits"`
+}
+
+// lighthouseAudit is a single audit; details items vary by audit
+type lighthouseAudit struct {
+	NumericValue *float64 `json:"numericValue"`
+	Details      struct {
+		Items json.RawMessage `json:"items"`
+	} `json:"details"`
+}
+
+// lighthouseResourceSummary is an item of the resource-summary audit
+type lighthouseResourceSummary struct {
+	ResourceType string  `json:"resourceType"`
+	RequestCount int     `json:"requestCount"`
+	TransferSize float64 `json:"transferSize"`
+}
+
+// lighthouseNetworkRequest is an item of the network-requests audit
+type lighthouseNetworkRequest struct {
+	URL          string  `json:"url"`
+	ResourceType string  `json:"resourceType"`
+	MimeType     string  `json:"mimeType"`
+	TransferSize float64 `json:"transferSize"`
+}
+
+// lighthouseMetrics is the item of the metrics audit
+type lighthouseMetrics struct {
+	ObservedLoad float64 `json:"observedLoad"` // ms
+}
+
+// LighthouseImportParams describes how a Lighthouse report is imported
+type LighthouseImportParams struct {
+	// DeviceType and ConnectionType default from the report's form factor
+	DeviceType     string
+	ConnectionType string
+	Region         string
+
+	// Deploy identifies the release measured, e.g. a commit SHA
+	Deploy string
+
+	// Thresholds for regression alerts; zero values use DefaultRegressionThresholds
+	Thresholds RegressionThresholds
+}
+
+// LighthouseImport is the result of importing a Lighthouse report
+type LighthouseImport struct {
+	// Baseline stored for the report
+	Baseline *BaselineMeasurement `json:"baseline"`
+
+	// Reference the baseline was compared with, nil for the first import of a page
+	Reference *RegressionReference `json:"reference,omitempty"`
+
+	// Regressions beyond the thresholds
+	Regressions []RegressionAlert `json:"regressions"`
+
+	// Warnings about the report
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// decodeLighthouseItems decodes an audit's details items, reporting whether the audit exists
+func decodeLighthouseItems(report *lighthouseReport, audit string, items interface{}) (bool, error) {
+	result, exists := report.Audits[audit]
+	if !exists || len(result.Details.Items) == 0 {
+		return false, nil
+	}
+	if err := json.Unmarshal(result.Details.Items, items); err != nil {
+		return false, fmt.Errorf("%w: %s audit: %v", ErrInvalidLighthouseReport, audit, err)
+	}
+	return true, nil
+}
+
+// parseLighthouseReport decodes a Lighthouse or PageSpeed Insights JSON report
+func parseLighthouseReport(r io.Reader) (*lighthouseReport, error) {
+	var raw struct {
+		lighthouseReport
+		LighthouseResult *lighthouseReport `json:"lighthouseResult"`
+	}
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLighthouseReport, err)
+	}
+
+	report := &raw.lighthouseReport
+	if raw.LighthouseResult != nil {
+		report = raw.LighthouseResult
+	}
+	if report.URL() == "" {
+		return nil, fmt.Errorf("%w: no page URL", ErrInvalidLighthouseReport)
+	}
+	if len(report.Audits) == 0 {
+		return nil, fmt.Errorf("%w: no audits", ErrInvalidLighthouseReport)
+	}
+	return report, nil
+}
+
+// URL returns the audited page URL, preferring the URL after redirects
+func (r *lighthouseReport) URL() string {
+	switch {
+	case r.FinalDisplayedURL != "":
+		return r.FinalDisplayedURL
+	case r.FinalURL != "":
+		return r.FinalURL
+	default:
+		return r.RequestedURL
+	}
+}
+
+// resourceSummary returns transferred bytes and request counts by Lighthouse resource
+// type, from the resource-summary audit or else summed from network-requests
+func (r *lighthouseReport) resourceSummary() (map[string]lighthouseResourceSummary, error) {
+	summary := make(map[string]lighthouseResourceSummary)
+
+	var items []lighthouseResourceSummary
+	found, err := decodeLighthouseItems(r, "resource-summary", &items)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		for _, item := range items {
+			summary[item.ResourceType] = item
+		}
+		return summary, nil
+	}
+
+	var requests []lighthouseNetworkRequest
+	found, err = decodeLighthouseItems(r, "network-requests", &requests)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: neither resource-summary nor network-requests audit present", ErrInvalidLighthouseReport)
+	}
+
+	pageSite := siteOf(r.URL())
+	add := func(resourceType string, transferSize float64) {
+		item := summary[resourceType]
+		item.ResourceType = resourceType
+		item.RequestCount++
+		item.TransferSize += transferSize
+		summary[resourceType] = item
+	}
+	for _, request := range requests {
+		add("total", request.TransferSize)
+		add(lighthouseResourceType(request), request.TransferSize)
+		if siteOf(request.URL) != pageSite {
+			add("third-party", request.TransferSize)
+		}
+	}
+	return summary, nil
+}
+
+// lighthouseResourceType maps a network request to the resource-summary types
+func lighthouseResourceType(request lighthouseNetworkRequest) string {
+	switch request.ResourceType {
+	case "Document":
+		return "document"
+	case "Script":
+		return "script"
+	case "Stylesheet":
+		return "stylesheet"
+	case "Image":
+		return "image"
+	case "Media":
+		return "media"
+	case "Font":
+		return "font"
+	}
+	// Requests without a resource type are classified by MIME type like HAR entries
+	switch resourceType(normalizeMIMEType(request.MimeType)) {
+	case ResourceTypeVideo:
+		return "media"
+	case ResourceTypeOther:
+		return "other"
+	default:
+		return resourceType(normalizeMIMEType(request.MimeType))
+	}
+}
+
+// loadTime returns the observed load time in seconds, falling back to time to interactive
+func (r *lighthouseReport) loadTime() (float64, error) {
+	var metrics []lighthouseMetrics
+	found, err := decodeLighthouseItems(r, "metrics", &metrics)
+	if err != nil {
+		return 0, err
+	}
+	if found && len(metrics) > 0 && metrics[0].ObservedLoad > 0 {
+		return metrics[0].ObservedLoad / 1000.0, nil
+	}
+	if interactive, exists := r.Audits["interactive"]; exists && interactive.NumericValue != nil && *interactive.NumericValue > 0 {
+		return *interactive.NumericValue / 1000.0, nil
+	}
+	return 0, nil
+}
+
+// lighthouseBaselineID derives a stable ID so re-importing a report replaces its baseline
+func lighthouseBaselineID(report *lighthouseReport) string {
+	sum := sha256.Sum256([]byte(report.URL() + "|" + report.FetchTime.UTC().Format(time.RFC3339Nano) + "|" + report.ConfigSettings.FormFactor))
+	return "lh_" + hex.EncodeToString(sum[:8])
+}
+
+// ImportLighthouse maps a Lighthouse report's resource summary into a baseline
+// measurement, compares it with the page's recent history and stores it
+func (s *Service) ImportLighthouse(ctx context.Context, r io.Reader, params LighthouseImportParams) (*LighthouseImport, error) {
+	report, err := parseLighthouseReport(r)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := report.resourceSummary()
+	if err != nil {
+		return nil, err
+	}
+	if summary["total"].TransferSize <= 0 {
+		return nil, fmt.Errorf("%w: no transferred bytes recorded", ErrInvalidLighthouseReport)
+	}
+
+	result := &LighthouseImport{Regressions: []RegressionAlert{}}
+	seconds, err := report.loadTime()
+	if err != nil {
+		return nil, err
+	}
+	if seconds <= 0 {
+		seconds = lighthouseDefaultLoadTime
+		result.Warnings = append(result.Warnings, "No load metrics in the report; assuming a 5 second load time")
+	}
+	measuredAt := report.FetchTime.UTC()
+	if measuredAt.IsZero() {
+		measuredAt = time.Now().UTC()
+		result.Warnings = append(result.Warnings, "No fetch time in the report; using the import time")
+	}
+
+	// Lighthouse emulates a mid-range phone on slow 4G unless run with the desktop preset
+	params.DeviceType, params.ConnectionType = lighthouseDeviceDefaults(report.ConfigSettings.FormFactor, params.DeviceType, params.ConnectionType)
+
+	resourceCount := make(map[string]int, len(summary))
+	for resourceType, item := range summary {
+		if resourceType != "total" {
+			resourceCount[resourceType] = item.RequestCount
+		}
+	}
+	baselineParams := BaselineParams{
+		DataTransferred: summary["total"].TransferSize / bytesPerMB,
+		JavaScriptSize:  summary["script"].TransferSize / 1000.0,
+		ImageSize:       summary["image"].TransferSize / bytesPerMB,
+		VideoSize:       summary["media"].TransferSize / bytesPerMB,
+		LoadTime:        seconds,
+		ResourceCount:   resourceCount,
+		DeviceType:      params.DeviceType,
+		ConnectionType:  params.ConnectionType,
+		Region:          params.Region,
+	}
+	baseline := &BaselineMeasurement{
+		ID:                lighthouseBaselineID(report),
+		URL:               report.URL(),
+		PageLoadEmissions: s.calculatePageLoadBaseline(baselineParams),
+		DataTransferred:   baselineParams.DataTransferred,
+		JavaScriptSize:    baselineParams.JavaScriptSize,
+		ImageSize:         baselineParams.ImageSize,
+		VideoSize:         baselineParams.VideoSize,
+		LoadTime:          seconds,
+		ResourceCount:     resourceCount,
+		MeasuredAt:        measuredAt,
+		DeviceType:        params.DeviceType,
+		ConnectionType:    params.ConnectionType,
+		Region:            params.Region,
+		Source:            SourceLighthouse,
+		Deploy:            params.Deploy,
+	}
+	if baseline.PageLoadEmissions <= 0 {
+		return nil, fmt.Errorf("failed to estimate page load emissions for %s", baseline.URL)
+	}
+	baseline.EstimatedHourlyEmissions = baseline.PageLoadEmissions * (3600.0 / baseline.LoadTime) * 0.1 // Same 10% active time as MeasureBaseline
+	result.Baseline = baseline
+
+	history, err := s.storage.ListBaselines(ctx, baseline.URL, ReportPeriod{Start: measuredAt.Add(-regressionHistoryWindow), End: measuredAt})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load baseline history: %w", err)
+	}
+	result.Reference, result.Regressions = detectRegressions(baseline, comparableBaselines(baseline, history), params.Thresholds)
+
+	if err := s.storage.SaveBaseline(ctx, baseline); err != nil {
+		return nil, fmt.Errorf("failed to save baseline: %w", err)
+	}
+	return result, nil
+}
+
+// lighthouseDeviceDefaults fills in device and connection types for the report's form factor
+func lighthouseDeviceDefaults(formFactor, deviceType, connectionType string) (string, string) {
+	desktop := strings.EqualFold(formFactor, "desktop")
+	if deviceType == "" {
+		deviceType = "smartphone"
+		if desktop {
+			deviceType = "desktop"
+		}
+	}
+	if connectionType == "" {
+		connectionType = "mobile_4g"
+		if desktop {
+			connectionType = "fixed_broad"
+		}
+	}
+	return deviceType, connectionType
+}
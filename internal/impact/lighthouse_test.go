@@ -0,0 +1,148 @@
+package impact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lighthouseJSON builds a minimal Lighthouse 10 report with a resource summary
+func lighthouseJSON(fetchTime time.Time, imageBytes int) string {
+	return fmt.Sprintf(`{
+  "lighthouseVersion": "11.4.0",
+  "requestedUrl": "https://example.com",
+  "finalDisplayedUrl": "https://example.com/",
+  "fetchTime": %q,
+  "configSettings": {"formFactor": "mobile"},
+  "audits": {
+    "resource-summary": {"details": {"items": [
+      {"resourceType": "total", "label": "Total", "requestCount": 12, "transferSize": %d},
+      {"resourceType": "script", "label": "Script", "requestCount": 5, "transferSize": 400000},
+      {"resourceType": "image", "label": "Image", "requestCount": 6, "transferSize": %d},
+      {"resourceType": "document", "label": "Document", "requestCount": 1, "transferSize": 100000},
+      {"resourceType": "third-party", "label": "Third-party", "requestCount": 3, "transferSize": 150000}
+    ]}},
+    "metrics": {"details": {"items": [{"observedLoad": 3200}]}}
+  }
+}`, fetchTime.Format(time.RFC3339), 500000+imageBytes, imageBytes)
+}
+
+func TestImportLighthouse(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	service := NewService(storage)
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	imports := make([]*LighthouseImport, 0, 4)
+	for day, imageBytes := range []int{1000000, 1050000, 950000, 2000000} {
+		result, err := service.ImportLighthouse(ctx, strings.NewReader(lighthouseJSON(start.AddDate(0, 0, day), imageBytes)),
+			LighthouseImportParams{Region: "EU", Deploy: fmt.Sprintf("deploy-%d", day)})
+		if err != nil {
+			t.Fatalf("import %d failed: %v", day, err)
+		}
+		imports = append(imports, result)
+	}
+
+	first := imports[0]
+	if first.Reference != nil || len(first.Regressions) != 0 {
+		t.Errorf("expected no reference for the first import, got %+v", first)
+	}
+	baseline := first.Baseline
+	if baseline.URL != "https://example.com/" || baseline.DataTransferred != 1.5 || baseline.JavaScriptSize != 400 ||
+		baseline.ImageSize != 1 || baseline.LoadTime != 3.2 || baseline.ResourceCount["third-party"] != 3 {
+		t.Errorf("unexpected baseline: %+v", baseline)
+	}
+	if baseline.DeviceType != "smartphone" || baseline.ConnectionType != "mobile_4g" || !baseline.MeasuredAt.Equal(start) {
+		t.Errorf("expected mobile defaults and the fetch time, got %+v", baseline)
+	}
+
+	// Small changes stay within the 10% default threshold
+	if len(imports[1].Regressions) != 0 || len(imports[2].Regressions) != 0 {
+		t.Errorf("unexpected regressions: %+v, %+v", imports[1].Regressions, imports[2].Regressions)
+	}
+	last := imports[3]
+	if last.Reference == nil || last.Reference.Baselines != 3 || last.Reference.LatestDeploy != "deploy-2" {
+		t.Fatalf("expected the three earlier imports as reference, got %+v", last.Reference)
+	}
+	if len(last.Regressions) != 2 || last.Regressions[0].Metric != RegressionMetricPageWeight || last.Regressions[0].Deploy != "deploy-3" {
+		t.Errorf("expected page weight and emissions regressions, got %+v", last.Regressions)
+	}
+
+	// Re-importing a report replaces its baseline instead of comparing with itself
+	again, err := service.ImportLighthouse(ctx, strings.NewReader(lighthouseJSON(start.AddDate(0, 0, 3), 2000000)), LighthouseImportParams{Region: "EU"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Baseline.ID != last.Baseline.ID || again.Reference.Baselines != 3 {
+		t.Errorf("expected an idempotent re-import, got %+v", again)
+	}
+
+	trend, err := service.GetPageTrend(ctx, "https://example.com/", "", ReportPeriod{Start: start, End: start.AddDate(0, 0, 7)}, RegressionThresholds{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trend.Points) != 4 || !trend.Points[3].Regression || trend.Points[1].Regression {
+		t.Errorf("unexpected trend points: %+v", trend.Points)
+	}
+	if len(trend.Regressions) != 2 || trend.EmissionsSlope <= 0 || trend.DataTransferredChange <= 0 {
+		t.Errorf("unexpected trend: %+v", trend)
+	}
+
+	// Desktop runs are trended separately and never compared with mobile runs
+	desktop := strings.Replace(lighthouseJSON(start.AddDate(0, 0, 4), 4000000), `"mobile"`, `"desktop"`, 1)
+	result, err := service.ImportLighthouse(ctx, strings.NewReader(desktop), LighthouseImportParams{Region: "EU"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Reference != nil || result.Baseline.DeviceType != "desktop" {
+		t.Errorf("expected desktop run without a mobile reference, got %+v", result)
+	}
+	trend, _ = service.GetPageTrend(ctx, "https://example.com/", "smartphone", ReportPeriod{Start: start, End: start.AddDate(0, 0, 7)}, RegressionThresholds{})
+	if len(trend.Points) != 4 {
+		t.Errorf("expected the device filter to drop the desktop run, got %d points", len(trend.Points))
+	}
+}
+
+func TestImportPageSpeedInsightsNetworkRequests(t *testing.T) {
+	report := `{"lighthouseResult": {
+  "finalUrl": "https://www.example.com/shop",
+  "fetchTime": "2024-03-01T12:00:00.000Z",
+  "configSettings": {"formFactor": "desktop"},
+  "audits": {
+    "network-requests": {"details": {"items": [
+      {"url": "https://www.example.com/shop", "resourceType": "Document", "mimeType": "text/html", "transferSize": 60000},
+      {"url": "https://cdn.example.com/app.js", "resourceType": "Script", "mimeType": "application/javascript", "transferSize": 240000},
+      {"url": "https://video.example.net/intro.mp4", "resourceType": "Media", "mimeType": "video/mp4", "transferSize": 3000000}
+    ]}},
+    "interactive": {"numericValue": 4100}
+  }
+}}`
+	result, err := NewService(NewMemoryStorage()).ImportLighthouse(context.Background(), strings.NewReader(report), LighthouseImportParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline := result.Baseline
+	if baseline.URL != "https://www.example.com/shop" || baseline.DataTransferred != 3.3 || baseline.VideoSize != 3 || baseline.LoadTime != 4.1 {
+		t.Errorf("unexpected baseline: %+v", baseline)
+	}
+	if baseline.ResourceCount["third-party"] != 1 || baseline.ResourceCount["script"] != 1 || baseline.DeviceType != "desktop" {
+		t.Errorf("unexpected resource counts: %+v", baseline.ResourceCount)
+	}
+}
+
+func TestImportLighthouseInvalid(t *testing.T) {
+	service := NewService(NewMemoryStorage())
+	for name, report := range map[string]string{
+		"malformed": `{"audits": `,
+		"no url":    `{"audits": {"metrics": {}}}`,
+		"no audits": `{"requestedUrl": "https://example.com"}`,
+		"no sizes":  `{"requestedUrl": "https://example.com", "audits": {"metrics": {}}}`,
+	} {
+		if _, err := service.ImportLighthouse(context.Background(), strings.NewReader(report), LighthouseImportParams{}); !errors.Is(err, ErrInvalidLighthouseReport) {
+			t.Errorf("%s: expected ErrInvalidLighthouseReport, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package impact
+
+import (
+	"fmt"
+	"math"
+)
+
+// CalculationMethodology selects the emissions model used by Calculator
+type CalculationMethodology string
+
+const (
+	// MethodologyGreenWeb models device power draw, network transmission and data center
+	// energy per activity with regional factors
+	MethodologyGreenWeb CalculationMethodology = "greenweb"
+
+	// MethodologySWD is the Sustainable Web Design model: energy per GB transferred, split
+	// across device, network, data center and production segments
+	MethodologySWD CalculationMethodology = "swd"
+
+	// MethodologyOneByte is The Shift Project's 1byte model: data center and network
+	// energy per byte transferred
+	MethodologyOneByte CalculationMethodology = "onebyte"
+)
+
+// Model versions recorded in results
+const (
+	GreenWebModelVersion = "1.0"
+	SWDModelVersion      = "3.0.0"
+	OneByteModelVersion  = "2018"
+)
+
+// Sustainable Web Design v3 factors, as published at sustainablewebdesign.org and used
+// by CO2.js
+const (
+	swdKWhPerGB                  = 0.81
+	swdDeviceShare               = 0.52
+	swdNetworkShare              = 0.14
+	swdDataCenterShare           = 0.15
+	swdProductionShare           = 0.19
+	swdGridIntensity             = 442.0 // g CO2e/kWh, global average
+	swdRenewableIntensity        = 50.0  // g CO2e/kWh, renewable data center energy
+	swdReturningVisitorRatio     = 0.25
+	swdReturningVisitorDataRatio = 0.02
+)
+
+// 1byte factors from The Shift Project (2018), as used by CO2.js
+const (
+	oneByteDataCenterKWhPerGB = 0.72
+	oneByteWiredKWhPerGB      = 4.29
+	oneByteWiFiKWhPerGB       = 1.52
+	oneByteMobileKWhPerGB     = 8.84
+	oneByteDataCenterGrid     = 519.0 // g CO2e/kWh, data center grid intensity
+	oneByteNetworkGrid        = 475.0 // g CO2e/kWh, network grid intensity
+)
+
+// videoBitrates in Mbps by quality
+var videoBitrates = map[string]float64{
+	"360p":  1.0,
+	"480p":  2.5,
+	"720p":  5.0,
+	"1080p": 8.0,
+	"4k":    25.0,
+}
+
+// dataReductionLimits are the maximum data savings from optimization by activity, the
+// same limits the greenweb model applies
+var dataReductionLimits = map[ImpactType]float64{
+	ImpactTypeVideoStreaming: 0.4, // Adaptive bitrate
+	ImpactTypeImageLoading:   0.7, // WebP/AVIF
+	ImpactTypeJavaScript:     0.5, // Code splitting, tree shaking
+	ImpactTypeAIInference:    0.8, // Caching repeated queries
+	ImpactTypePageLoad:       0.6,
+	ImpactTypeDataTransfer:   0.5, // Compression
+}
+
+// transferredGB estimates the data transferred by an activity, with the greenweb
+// model's defaults for missing sizes
+func transferredGB(req *CalculationRequest) (float64, error) {
+	switch req.Type {
+	case ImpactTypeVideoStreaming:
+		return videoBitrates[req.VideoQuality] * req.Duration / 8.0 / 1000.0, nil
+	case ImpactTypeImageLoading:
+		if req.DataSize > 0 {
+			return req.DataSize / 1000.0, nil
+		}
+		return 0.5 * float64(req.ImageCount) / 1000.0, nil // 0.5 MB per unoptimized image
+	case ImpactTypeJavaScript:
+		if req.DataSize > 0 {
+			return req.DataSize / 1000.0, nil
+		}
+		return 0.002, nil // 2 MB bundle
+	case ImpactTypeAIInference:
+		inferences := 1.0
+		if req.Duration > 0 {
+			inferences = req.Duration / 2.0
+		}
+		return 0.001 * inferences, nil // ~1 MB per inference
+	case ImpactTypePageLoad:
+		if req.DataSize > 0 {
+			return req.DataSize / 1000.0, nil
+		}
+		return 0.0022, nil // 2.2 MB average page (HTTP Archive 2023)
+	case ImpactTypeDataTransfer:
+		return req.DataSize / 1000.0, nil
+	default:
+		return 0, fmt.Errorf("unsupported impact type: %s", req.Type)
+	}
+}
+
+// dataReduction returns the share of data saved at the request's optimization level
+func dataReduction(req *CalculationRequest) float64 {
+	limit := dataReductionLimits[req.Type]
+	return math.Min(req.OptimizationLevel*limit/100.0, limit)
+}
+
+// gridIntensityOr returns the region's grid intensity, or fallback for the global region
+// so each model keeps its own published average. Monte Carlo samples scale the fallback.
+func (c *Calculator) gridIntensityOr(region string, fallback float64) float64 {
+	if c.fallbackGridScale != 0 {
+		fallback *= c.fallbackGridScale
+	}
+	if region == "global" {
+		return fallback
+	}
+	if intensity, exists := c.factors.GridCarbonIntensity[region]; exists {
+		return intensity
+	}
+	return fallback
+}
+
+// calculateSWD applies the Sustainable Web Design model to the data transferred.
+// Returning visitors only reload part of the data; green hosting lowers the data
+// center segment to the renewable intensity.
+func (c *Calculator) calculateSWD(req *CalculationRequest) (*ImpactResult, error) {
+	returning := swdReturningVisitorRatio
+	if req.ReturningVisitorRatio != nil {
+		returning = *req.ReturningVisitorRatio
+	}
+	reloaded := swdReturningVisitorDataRatio
+	if req.ReturningVisitorDataRatio != nil {
+		reloaded = *req.ReturningVisitorDataRatio
+	}
+
+	gridIntensity := c.gridIntensityOr(req.Region, swdGridIntensity)
+	dcIntensity := req.GreenHostingRatio*swdRenewableIntensity + (1-req.GreenHostingRatio)*gridIntensity
+
+	emissions := func(dataGB float64) EmissionComponents {
+		energy := dataGB * swdKWhPerGB * ((1 - returning) + returning*reloaded) // kWh
+		return EmissionComponents{
+			DeviceEmissions:     energy * swdDeviceShare * gridIntensity,
+			NetworkEmissions:    energy * swdNetworkShare * gridIntensity,
+			DataCenterEmissions: energy * swdDataCenterShare * dcIntensity,
+			ProductionEmissions: energy * swdProductionShare * gridIntensity,
+		}
+	}
+
+	dataGB, err := transferredGB(req)
+	if err != nil {
+		return nil, err
+	}
+	components := emissions(dataGB)
+	optimized := emissions(dataGB * (1 - dataReduction(req)))
+
+	return newModelResult(components, total(optimized))
+}
+
+// calculateOneByte applies the 1byte model: data center and network energy per byte,
+// with the network factor for the connection type. Device energy is not modelled.
+func (c *Calculator) calculateOneByte(req *CalculationRequest) (*ImpactResult, error) {
+	networkKWhPerGB := (oneByteWiredKWhPerGB + oneByteWiFiKWhPerGB + oneByteMobileKWhPerGB) / 3
+	switch req.ConnectionType {
+	case "wifi":
+		networkKWhPerGB = oneByteWiFiKWhPerGB
+	case "ethernet", "fixed_broad":
+		networkKWhPerGB = oneByteWiredKWhPerGB
+	case "mobile_3g", "mobile_4g", "mobile_5g":
+		networkKWhPerGB = oneByteMobileKWhPerGB
+	}
+
+	networkIntensity := c.gridIntensityOr(req.Region, oneByteNetworkGrid)
+	dcIntensity := (1 - req.GreenHostingRatio) * c.gridIntensityOr(req.Region, oneByteDataCenterGrid)
+
+	emissions := func(dataGB float64) EmissionComponents {
+		return EmissionComponents{
+			NetworkEmissions:    dataGB * networkKWhPerGB * networkIntensity,
+			DataCenterEmissions: dataGB * oneByteDataCenterKWhPerGB * dcIntensity,
+		}
+	}
+
+	dataGB, err := transferredGB(req)
+	if err != nil {
+		return nil, err
+	}
+	components := emissions(dataGB)
+	optimized := emissions(dataGB * (1 - dataReduction(req)))
+
+	return newModelResult(components, total(optimized))
+}
+
+// total sums emission components
+func total(components EmissionComponents) float64 {
+	return components.DeviceEmissions + components.NetworkEmissions + components.DataCenterEmissions + components.ProductionEmissions
+}
+
+// newModelResult builds a result from baseline components and optimized emissions
+func newModelResult(components EmissionComponents, optimized float64) (*ImpactResult, error) {
+	baseline := total(components)
+	if baseline <= 0 {
+		return nil, fmt.Errorf("no data transferred to estimate emissions from")
+	}
+
+	components.DevicePercentage = (components.DeviceEmissions / baseline) * 100
+	components.NetworkPercentage = (components.NetworkEmissions / baseline) * 100
+	components.DataCenterPercentage = (components.DataCenterEmissions / baseline) * 100
+	components.ProductionPercentage = (components.ProductionEmissions / baseline) * 100
+
+	return &ImpactResult{
+		BaselineEmissions:  baseline,
+		OptimizedEmissions: optimized,
+		Savings:            baseline - optimized,
+		SavingsPercentage:  ((baseline - optimized) / baseline) * 100,
+		Components:         components,
+		NetSavings:         baseline - optimized,
+	}, nil
+}
+
+// addModelMethodologyInfo describes the SWD and 1byte models and their data sources
+func (c *Calculator) addModelMethodologyInfo(result *ImpactResult, req *CalculationRequest) {
+	switch req.Methodology {
+	case MethodologySWD:
+		result.ModelVersion = SWDModelVersion
+		returning := swdReturningVisitorRatio
+		if req.ReturningVisitorRatio != nil {
+			returning = *req.ReturningVisitorRatio
+		}
+		reloaded := swdReturningVisitorDataRatio
+		if req.ReturningVisitorDataRatio != nil {
+			reloaded = *req.ReturningVisitorDataRatio
+		}
+		result.Methodology = fmt.Sprintf("Sustainable Web Design model v%s: %.2f kWh/GB split %.0f%% device, %.0f%% network, "+
+			"%.0f%% data center and %.0f%% production; %.0f%% returning visitors reloading %.0f%% of data; "+
+			"%.0f%% green hosting at %.0f g CO2e/kWh.",
+			SWDModelVersion, swdKWhPerGB, swdDeviceShare*100, swdNetworkShare*100, swdDataCenterShare*100,
+			swdProductionShare*100, returning*100, reloaded*100, req.GreenHostingRatio*100, swdRenewableIntensity)
+		result.DataSources = []string{
+			"Sustainable Web Design (2022) - v3 model, sustainablewebdesign.org",
+			"The Green Web Foundation - CO2.js reference implementation",
+			"Ember (2022) - Global average grid intensity",
+		}
+	case MethodologyOneByte:
+		result.ModelVersion = OneByteModelVersion
+		result.Methodology = fmt.Sprintf("1byte model (%s): %.2f kWh/GB in data centers plus network energy per GB "+
+			"for the connection type; %.0f%% green hosting. Device energy is not included.",
+			OneByteModelVersion, oneByteDataCenterKWhPerGB, req.GreenHostingRatio*100)
+		result.DataSources = []string{
+			"The Shift Project (2018) - Lean ICT 1byte model",
+			"The Green Web Foundation - CO2.js reference implementation",
+		}
+		result.Warnings = append(result.Warnings,
+			"The 1byte model excludes end-user device energy, which is often the largest share of web emissions")
+	}
+
+	if req.Type == ImpactTypeAIInference || req.Type == ImpactTypeJavaScript {
+		result.Warnings = append(result.Warnings,
+			"Data-transfer models do not capture compute-heavy activities; use the greenweb methodology for server or device processing")
+	}
+}
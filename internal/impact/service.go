@@ -6,6 +6,8 @@ import (
 	"math/rand"
 	"sync"
 	"time"
+
+	"github.com/perschulte/greenweb-api/pkg/carbon"
 )
 
 // Service provides impact calculation and tracking functionality
@@ -20,6 +22,7 @@ type Service struct {
 type Storage interface {
 	SaveBaseline(ctx context.Context, baseline *BaselineMeasurement) error
 	GetBaseline(ctx context.Context, id string) (*BaselineMeasurement, error)
+	ListBaselines(ctx context.Context, url string, period ReportPeriod) ([]*BaselineMeasurement, error)
 	SaveSession(ctx context.Context, session *SessionMetrics) error
 	GetSession(ctx context.Context, sessionID string) (*SessionMetrics, error)
 	SaveReport(ctx context.Context, report *ImpactReport) error
@@ -46,9 +49,19 @@ func NewService(storage Storage) *Service {
 	}
 }
 
+// NewServiceWithIntensitySource creates an impact service whose calculations add
+// time-specific results from live and historical grid intensity
+func NewServiceWithIntensitySource(storage Storage, source carbon.CarbonServiceWithHistory) *Service {
+	return &Service{
+		calculator: NewCalculatorWithIntensitySource(DefaultEmissionFactors, source),
+		storage:    storage,
+		metrics:    NewMetricsCollector(),
+	}
+}
+
 // CalculateImpact calculates the carbon impact for a given request
 func (s *Service) CalculateImpact(ctx context.Context, req *CalculationRequest) (*ImpactResult, error) {
-	result, err := s.calculator.Calculate(req)
+	result, err := s.calculator.CalculateWithContext(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("calculation failed: %w", err)
 	}
@@ -61,6 +74,16 @@ func (s *Service) CalculateImpact(ctx context.Context, req *CalculationRequest)
 
 // MeasureBaseline measures and stores baseline carbon footprint for a URL
 func (s *Service) MeasureBaseline(ctx context.Context, url string, params BaselineParams) (*BaselineMeasurement, error) {
+	baseline := s.newBaseline(url, params)
+	if err := s.storage.SaveBaseline(ctx, baseline); err != nil {
+		return nil, fmt.Errorf("failed to save baseline: %w", err)
+	}
+
+	return baseline, nil
+}
+
+// newBaseline computes a baseline measurement without storing it
+func (s *Service) newBaseline(url string, params BaselineParams) *BaselineMeasurement {
 	// Simulate measurement (in real implementation, would use browser automation)
 	baseline := &BaselineMeasurement{
 		ID:               generateID(),
@@ -81,11 +104,7 @@ func (s *Service) MeasureBaseline(ctx context.Context, url string, params Baseli
 	// Calculate hourly emissions estimate
 	baseline.EstimatedHourlyEmissions = baseline.PageLoadEmissions * (3600.0 / baseline.LoadTime) * 0.1 // Assume 10% active time
 
-	if err := s.storage.SaveBaseline(ctx, baseline); err != nil {
-		return nil, fmt.Errorf("failed to save baseline: %w", err)
-	}
-
-	return baseline, nil
+	return baseline
 }
 
 // BaselineParams contains parameters for baseline measurement
@@ -3,6 +3,7 @@ package impact
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 )
@@ -47,6 +48,25 @@ func (ms *MemoryStorage) GetBaseline(ctx context.Context, id string) (*BaselineM
 	return baseline, nil
 }
 
+// ListBaselines returns the baselines of a URL measured within the period, bounds
+// included, oldest first
+func (ms *MemoryStorage) ListBaselines(ctx context.Context, url string, period ReportPeriod) ([]*BaselineMeasurement, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var baselines []*BaselineMeasurement
+	for _, baseline := range ms.baselines {
+		if baseline.URL == url && !baseline.MeasuredAt.Before(period.Start) && !baseline.MeasuredAt.After(period.End) {
+			baselines = append(baselines, baseline)
+		}
+	}
+	sort.Slice(baselines, func(i, j int) bool {
+		return baselines[i].MeasuredAt.Before(baselines[j].MeasuredAt)
+	})
+
+	return baselines, nil
+}
+
 // SaveSession saves session metrics
 func (ms *MemoryStorage) SaveSession(ctx context.Context, session *SessionMetrics) error {
 	ms.mu.Lock()
@@ -129,77 +149,6 @@ func (ms *MemoryStorage) GetAggregatedMetrics(ctx context.Context, period Report
 	return metrics, nil
 }
 
-// PostgreSQLStorage provides a PostgreSQL implementation (skeleton for future use)
-type PostgreSQLStorage struct {
-	// db *sql.DB
-	// In a real implementation, this would contain database connection
-}
-
-// NewPostgreSQLStorage creates a new PostgreSQL storage instance
-func NewPostgreSQLStorage(connectionString string) (*PostgreSQLStorage, error) {
-	// In a real implementation, you would:
-	// 1. Connect to PostgreSQL
-	// 2. Run migrations to create tables
-	// 3. Return configured storage
-	return &PostgreSQLStorage{}, fmt.Errorf("PostgreSQL storage not implemented in this demo")
-}
-
-// Example table schemas for PostgreSQL implementation:
-const (
-	createBaselinesTableSQL = `
-	CREATE TABLE IF NOT EXISTS baselines (
-		id VARCHAR(255) PRIMARY KEY,
-		url TEXT NOT NULL,
-		page_load_emissions DECIMAL(10,3) NOT NULL,
-		data_transferred DECIMAL(10,3) NOT NULL,
-		javascript_size DECIMAL(10,3) NOT NULL,
-		image_size DECIMAL(10,3) NOT NULL,
-		video_size DECIMAL(10,3) NOT NULL,
-		load_time DECIMAL(10,3) NOT NULL,
-		resource_count JSONB,
-		estimated_hourly_emissions DECIMAL(10,3) NOT NULL,
-		measured_at TIMESTAMP NOT NULL,
-		device_type VARCHAR(50) NOT NULL,
-		connection_type VARCHAR(50) NOT NULL,
-		region VARCHAR(50) NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	createSessionsTableSQL = `
-	CREATE TABLE IF NOT EXISTS sessions (
-		session_id VARCHAR(255) PRIMARY KEY,
-		start_time TIMESTAMP NOT NULL,
-		duration DECIMAL(10,3) NOT NULL,
-		total_emissions DECIMAL(10,3) NOT NULL,
-		emissions_by_activity JSONB,
-		optimizations_applied INTEGER NOT NULL DEFAULT 0,
-		estimated_savings DECIMAL(10,3) NOT NULL DEFAULT 0,
-		device_type VARCHAR(50) NOT NULL,
-		region VARCHAR(50) NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	createReportsTableSQL = `
-	CREATE TABLE IF NOT EXISTS reports (
-		id VARCHAR(255) PRIMARY KEY,
-		period_start TIMESTAMP NOT NULL,
-		period_end TIMESTAMP NOT NULL,
-		period_days INTEGER NOT NULL,
-		total_savings DECIMAL(10,3) NOT NULL,
-		baseline_total DECIMAL(10,3) NOT NULL,
-		optimized_total DECIMAL(10,3) NOT NULL,
-		savings_by_type JSONB,
-		optimization_events INTEGER NOT NULL,
-		average_optimization_level DECIMAL(5,2) NOT NULL,
-		equivalent_to JSONB,
-		confidence_score DECIMAL(5,2) NOT NULL,
-		methodology TEXT,
-		recommendations JSONB,
-		generated_at TIMESTAMP NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);`
-)
-
 // MockStorageWithData provides pre-populated mock data for testing
 func NewMockStorageWithData() *MemoryStorage {
 	storage := NewMemoryStorage()
@@ -0,0 +1,545 @@
+package impact
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// postgresDrivers are the database/sql driver names tried, in order, when opening a
+// connection string; the binary must import one of them (pgx stdlib or lib/pq)
+var postgresDrivers = []string{"pgx", "postgres"}
+
+// migrationLockID serializes migrations across replicas with a transaction-level advisory lock
+const migrationLockID = 72640144
+
+// migration is a versioned schema change
+type migration struct {
+	version     int
+	description string
+	statements  []string
+}
+
+// postgresMigrations are applied in order; never edit an applied migration, add a new one
+var postgresMigrations = []migration{
+	{
+		version:     1,
+		description: "create baselines, sessions and reports",
+		statements:  []string{createBaselinesTableSQL, createSessionsTableSQL, createReportsTableSQL},
+	},
+	{
+		version:     2,
+		description: "index aggregation periods",
+		statements: []string{
+			`CREATE INDEX IF NOT EXISTS baselines_measured_at_idx ON baselines (measured_at)`,
+			`CREATE INDEX IF NOT EXISTS sessions_start_time_idx ON sessions (start_time)`,
+			`CREATE INDEX IF NOT EXISTS reports_generated_at_idx ON reports (generated_at)`,
+		},
+	},
+	{
+		version:     3,
+		description: "track baseline sources and deploys per URL",
+		statements: []string{
+			`ALTER TABLE baselines ADD COLUMN IF NOT EXISTS source VARCHAR(50) NOT NULL DEFAULT ''`,
+			`ALTER TABLE baselines ADD COLUMN IF NOT EXISTS deploy VARCHAR(255) NOT NULL DEFAULT ''`,
+			`CREATE INDEX IF NOT EXISTS baselines_url_measured_at_idx ON baselines (url, measured_at)`,
+		},
+	},
+	{
+		// DECIMAL(10,3) rounded sub-milligram emissions to zero, so results differed from the
+		// memory and embedded stores for the same data
+		version:     4,
+		description: "store emissions and sizes at full precision",
+		statements: []string{
+			`ALTER TABLE baselines
+				ALTER COLUMN page_load_emissions TYPE DOUBLE PRECISION,
+				ALTER COLUMN data_transferred TYPE DOUBLE PRECISION,
+				ALTER COLUMN javascript_size TYPE DOUBLE PRECISION,
+				ALTER COLUMN image_size TYPE DOUBLE PRECISION,
+				ALTER COLUMN video_size TYPE DOUBLE PRECISION,
+				ALTER COLUMN estimated_hourly_emissions TYPE DOUBLE PRECISION`,
+			`ALTER TABLE sessions
+				ALTER COLUMN total_emissions TYPE DOUBLE PRECISION,
+				ALTER COLUMN estimated_savings TYPE DOUBLE PRECISION`,
+			`ALTER TABLE reports
+				ALTER COLUMN total_savings TYPE DOUBLE PRECISION,
+				ALTER COLUMN baseline_total TYPE DOUBLE PRECISION,
+				ALTER COLUMN optimized_total TYPE DOUBLE PRECISION`,
+		},
+	},
+}
+
+// Table schemas; timestamps are stored in UTC
+const (
+	createMigrationsTableSQL = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	createBaselinesTableSQL = `
+	CREATE TABLE IF NOT EXISTS baselines (
+		id VARCHAR(255) PRIMARY KEY,
+		url TEXT NOT NULL,
+		page_load_emissions DECIMAL(10,3) NOT NULL,
+		data_transferred DECIMAL(10,3) NOT NULL,
+		javascript_size DECIMAL(10,3) NOT NULL,
+		image_size DECIMAL(10,3) NOT NULL,
+		video_size DECIMAL(10,3) NOT NULL,
+		load_time DECIMAL(10,3) NOT NULL,
+		resource_count JSONB,
+		estimated_hourly_emissions DECIMAL(10,3) NOT NULL,
+		measured_at TIMESTAMP NOT NULL,
+		device_type VARCHAR(50) NOT NULL,
+		connection_type VARCHAR(50) NOT NULL,
+		region VARCHAR(50) NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	createSessionsTableSQL = `
+	CREATE TABLE IF NOT EXISTS sessions (
+		session_id VARCHAR(255) PRIMARY KEY,
+		start_time TIMESTAMP NOT NULL,
+		duration DECIMAL(10,3) NOT NULL,
+		total_emissions DECIMAL(10,3) NOT NULL,
+		emissions_by_activity JSONB,
+		optimizations_applied INTEGER NOT NULL DEFAULT 0,
+		estimated_savings DECIMAL(10,3) NOT NULL DEFAULT 0,
+		device_type VARCHAR(50) NOT NULL,
+		region VARCHAR(50) NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	createReportsTableSQL = `
+	CREATE TABLE IF NOT EXISTS reports (
+		id VARCHAR(255) PRIMARY KEY,
+		period_start TIMESTAMP NOT NULL,
+		period_end TIMESTAMP NOT NULL,
+		period_days INTEGER NOT NULL,
+		total_savings DECIMAL(10,3) NOT NULL,
+		baseline_total DECIMAL(10,3) NOT NULL,
+		optimized_total DECIMAL(10,3) NOT NULL,
+		savings_by_type JSONB,
+		optimization_events INTEGER NOT NULL,
+		average_optimization_level DECIMAL(5,2) NOT NULL,
+		equivalent_to JSONB,
+		confidence_score DECIMAL(5,2) NOT NULL,
+		methodology TEXT,
+		recommendations JSONB,
+		generated_at TIMESTAMP NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+)
+
+// PostgreSQLStorage implements Storage on PostgreSQL through database/sql
+type PostgreSQLStorage struct {
+	db *sql.DB
+}
+
+// NewPostgreSQLStorage connects to PostgreSQL and applies pending migrations. A driver
+// registered as "pgx" or "postgres" must be linked into the binary.
+func NewPostgreSQLStorage(connectionString string) (*PostgreSQLStorage, error) {
+	driver := ""
+	for _, name := range postgresDrivers {
+		for _, registered := range sql.Drivers() {
+			if registered == name && driver == "" {
+				driver = name
+			}
+		}
+	}
+	if driver == "" {
+		return nil, fmt.Errorf("no PostgreSQL driver registered; import github.com/jackc/pgx/v5/stdlib or github.com/lib/pq")
+	}
+
+	db, err := sql.Open(driver, connectionString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PostgreSQL connection: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+
+	storage, err := NewPostgreSQLStorageWithDB(ctx, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return storage, nil
+}
+
+// NewPostgreSQLStorageWithDB uses an existing connection pool and applies pending migrations
+func NewPostgreSQLStorageWithDB(ctx context.Context, db *sql.DB) (*PostgreSQLStorage, error) {
+	storage := &PostgreSQLStorage{db: db}
+	if err := storage.Migrate(ctx); err != nil {
+		return nil, err
+	}
+	return storage, nil
+}
+
+// Migrate applies pending schema migrations in a single transaction
+func (ps *PostgreSQLStorage) Migrate(ctx context.Context) error {
+	tx, err := ps.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, createMigrationsTableSQL); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	applied := make(map[int]bool)
+	rows, err := tx.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		applied[version] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	for _, m := range postgresMigrations {
+		if applied[m.version] {
+			continue
+		}
+		for _, statement := range m.statements {
+			if _, err := tx.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.description, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, description) VALUES ($1, $2)`, m.version, m.description); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migrations: %w", err)
+	}
+	return nil
+}
+
+// Close closes the connection pool
+func (ps *PostgreSQLStorage) Close() error {
+	return ps.db.Close()
+}
+
+// SaveBaseline saves a baseline measurement, replacing one with the same ID
+func (ps *PostgreSQLStorage) SaveBaseline(ctx context.Context, baseline *BaselineMeasurement) error {
+	resourceCount, err := marshalJSONB(baseline.ResourceCount)
+	if err != nil {
+		return err
+	}
+
+	_, err = ps.db.ExecContext(ctx, `
+		INSERT INTO baselines (id, url, page_load_emissions, data_transferred, javascript_size, image_size,
+			video_size, load_time, resource_count, estimated_hourly_emissions, measured_at, device_type,
+			connection_type, region, source, deploy)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		ON CONFLICT (id) DO UPDATE SET
+			url = EXCLUDED.url,
+			page_load_emissions = EXCLUDED.page_load_emissions,
+			data_transferred = EXCLUDED.data_transferred,
+			javascript_size = EXCLUDED.javascript_size,
+			image_size = EXCLUDED.image_size,
+			video_size = EXCLUDED.video_size,
+			load_time = EXCLUDED.load_time,
+			resource_count = EXCLUDED.resource_count,
+			estimated_hourly_emissions = EXCLUDED.estimated_hourly_emissions,
+			measured_at = EXCLUDED.measured_at,
+			device_type = EXCLUDED.device_type,
+			connection_type = EXCLUDED.connection_type,
+			region = EXCLUDED.region,
+			source = EXCLUDED.source,
+			deploy = EXCLUDED.deploy`,
+		baseline.ID, baseline.URL, baseline.PageLoadEmissions, baseline.DataTransferred, baseline.JavaScriptSize,
+		baseline.ImageSize, baseline.VideoSize, baseline.LoadTime, resourceCount, baseline.EstimatedHourlyEmissions,
+		baseline.MeasuredAt.UTC(), baseline.DeviceType, baseline.ConnectionType, baseline.Region, baseline.Source,
+		baseline.Deploy)
+	if err != nil {
+		return fmt.Errorf("failed to save baseline %s: %w", baseline.ID, err)
+	}
+	return nil
+}
+
+// baselineColumns are the baseline columns read by scanBaseline
+const baselineColumns = `id, url, page_load_emissions, data_transferred, javascript_size, image_size, video_size,
+	load_time, resource_count, estimated_hourly_emissions, measured_at, device_type, connection_type, region,
+	source, deploy`
+
+// scanBaseline reads a row of baselineColumns
+func scanBaseline(row interface{ Scan(...interface{}) error }) (*BaselineMeasurement, error) {
+	baseline := &BaselineMeasurement{}
+	var resourceCount []byte
+
+	err := row.Scan(
+		&baseline.ID, &baseline.URL, &baseline.PageLoadEmissions, &baseline.DataTransferred, &baseline.JavaScriptSize,
+		&baseline.ImageSize, &baseline.VideoSize, &baseline.LoadTime, &resourceCount, &baseline.EstimatedHourlyEmissions,
+		&baseline.MeasuredAt, &baseline.DeviceType, &baseline.ConnectionType, &baseline.Region, &baseline.Source,
+		&baseline.Deploy)
+	if err != nil {
+		return nil, err
+	}
+
+	baseline.MeasuredAt = baseline.MeasuredAt.UTC()
+	if err := unmarshalJSONB(resourceCount, &baseline.ResourceCount); err != nil {
+		return nil, err
+	}
+	return baseline, nil
+}
+
+// GetBaseline retrieves a baseline measurement by ID
+func (ps *PostgreSQLStorage) GetBaseline(ctx context.Context, id string) (*BaselineMeasurement, error) {
+	baseline, err := scanBaseline(ps.db.QueryRowContext(ctx,
+		`SELECT `+baselineColumns+` FROM baselines WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("baseline with ID %s not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get baseline %s: %w", id, err)
+	}
+	return baseline, nil
+}
+
+// ListBaselines returns the baselines of a URL measured within the period, bounds
+// included, oldest first
+func (ps *PostgreSQLStorage) ListBaselines(ctx context.Context, url string, period ReportPeriod) ([]*BaselineMeasurement, error) {
+	rows, err := ps.db.QueryContext(ctx, `
+		SELECT `+baselineColumns+` FROM baselines
+		WHERE url = $1 AND measured_at >= $2 AND measured_at <= $3
+		ORDER BY measured_at`, url, period.Start.UTC(), period.End.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list baselines for %s: %w", url, err)
+	}
+	defer rows.Close()
+
+	var baselines []*BaselineMeasurement
+	for rows.Next() {
+		baseline, err := scanBaseline(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list baselines for %s: %w", url, err)
+		}
+		baselines = append(baselines, baseline)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list baselines for %s: %w", url, err)
+	}
+	return baselines, nil
+}
+
+// SaveSession saves session metrics, replacing a session with the same ID
+func (ps *PostgreSQLStorage) SaveSession(ctx context.Context, session *SessionMetrics) error {
+	emissionsByActivity, err := marshalJSONB(session.EmissionsByActivity)
+	if err != nil {
+		return err
+	}
+
+	_, err = ps.db.ExecContext(ctx, `
+		INSERT INTO sessions (session_id, start_time, duration, total_emissions, emissions_by_activity,
+			optimizations_applied, estimated_savings, device_type, region)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (session_id) DO UPDATE SET
+			start_time = EXCLUDED.start_time,
+			duration = EXCLUDED.duration,
+			total_emissions = EXCLUDED.total_emissions,
+			emissions_by_activity = EXCLUDED.emissions_by_activity,
+			optimizations_applied = EXCLUDED.optimizations_applied,
+			estimated_savings = EXCLUDED.estimated_savings,
+			device_type = EXCLUDED.device_type,
+			region = EXCLUDED.region`,
+		session.SessionID, session.StartTime.UTC(), session.Duration, session.TotalEmissions, emissionsByActivity,
+		session.OptimizationsApplied, session.EstimatedSavings, session.DeviceType, session.Region)
+	if err != nil {
+		return fmt.Errorf("failed to save session %s: %w", session.SessionID, err)
+	}
+	return nil
+}
+
+// GetSession retrieves session metrics by session ID
+func (ps *PostgreSQLStorage) GetSession(ctx context.Context, sessionID string) (*SessionMetrics, error) {
+	session := &SessionMetrics{}
+	var emissionsByActivity []byte
+
+	err := ps.db.QueryRowContext(ctx, `
+		SELECT session_id, start_time, duration, total_emissions, emissions_by_activity,
+			optimizations_applied, estimated_savings, device_type, region
+		FROM sessions WHERE session_id = $1`, sessionID).Scan(
+		&session.SessionID, &session.StartTime, &session.Duration, &session.TotalEmissions, &emissionsByActivity,
+		&session.OptimizationsApplied, &session.EstimatedSavings, &session.DeviceType, &session.Region)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("session with ID %s not found", sessionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session %s: %w", sessionID, err)
+	}
+
+	session.StartTime = session.StartTime.UTC()
+	if err := unmarshalJSONB(emissionsByActivity, &session.EmissionsByActivity); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// SaveReport saves an impact report, replacing a report with the same ID
+func (ps *PostgreSQLStorage) SaveReport(ctx context.Context, report *ImpactReport) error {
+	savingsByType, err := marshalJSONB(report.SavingsByType)
+	if err != nil {
+		return err
+	}
+	equivalentTo, err := marshalJSONB(report.EquivalentTo)
+	if err != nil {
+		return err
+	}
+	recommendations, err := marshalJSONB(report.Recommendations)
+	if err != nil {
+		return err
+	}
+
+	_, err = ps.db.ExecContext(ctx, `
+		INSERT INTO reports (id, period_start, period_end, period_days, total_savings, baseline_total,
+			optimized_total, savings_by_type, optimization_events, average_optimization_level, equivalent_to,
+			confidence_score, methodology, recommendations, generated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (id) DO UPDATE SET
+			period_start = EXCLUDED.period_start,
+			period_end = EXCLUDED.period_end,
+			period_days = EXCLUDED.period_days,
+			total_savings = EXCLUDED.total_savings,
+			baseline_total = EXCLUDED.baseline_total,
+			optimized_total = EXCLUDED.optimized_total,
+			savings_by_type = EXCLUDED.savings_by_type,
+			optimization_events = EXCLUDED.optimization_events,
+			average_optimization_level = EXCLUDED.average_optimization_level,
+			equivalent_to = EXCLUDED.equivalent_to,
+			confidence_score = EXCLUDED.confidence_score,
+			methodology = EXCLUDED.methodology,
+			recommendations = EXCLUDED.recommendations,
+			generated_at = EXCLUDED.generated_at`,
+		report.ID, report.Period.Start.UTC(), report.Period.End.UTC(), report.Period.Days, report.TotalSavings,
+		report.BaselineTotal, report.OptimizedTotal, savingsByType, report.OptimizationEvents,
+		report.AverageOptimizationLevel, equivalentTo, report.ConfidenceScore, report.Methodology, recommendations,
+		report.GeneratedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save report %s: %w", report.ID, err)
+	}
+	return nil
+}
+
+// GetReport retrieves an impact report by ID
+func (ps *PostgreSQLStorage) GetReport(ctx context.Context, id string) (*ImpactReport, error) {
+	report := &ImpactReport{}
+	var savingsByType, equivalentTo, recommendations []byte
+	var methodology sql.NullString
+
+	err := ps.db.QueryRowContext(ctx, `
+		SELECT id, period_start, period_end, period_days, total_savings, baseline_total, optimized_total,
+			savings_by_type, optimization_events, average_optimization_level, equivalent_to, confidence_score,
+			methodology, recommendations, generated_at
+		FROM reports WHERE id = $1`, id).Scan(
+		&report.ID, &report.Period.Start, &report.Period.End, &report.Period.Days, &report.TotalSavings,
+		&report.BaselineTotal, &report.OptimizedTotal, &savingsByType, &report.OptimizationEvents,
+		&report.AverageOptimizationLevel, &equivalentTo, &report.ConfidenceScore, &methodology, &recommendations,
+		&report.GeneratedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("report with ID %s not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report %s: %w", id, err)
+	}
+
+	report.Period.Start = report.Period.Start.UTC()
+	report.Period.End = report.Period.End.UTC()
+	report.GeneratedAt = report.GeneratedAt.UTC()
+	report.Methodology = methodology.String
+	for _, field := range []struct {
+		data []byte
+		dest interface{}
+	}{
+		{savingsByType, &report.SavingsByType},
+		{equivalentTo, &report.EquivalentTo},
+		{recommendations, &report.Recommendations},
+	} {
+		if err := unmarshalJSONB(field.data, field.dest); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// aggregatedMetricsSQL mirrors MemoryStorage.GetAggregatedMetrics: baselines measured within
+// the period are extrapolated over its length ($3 hours), sessions started within it are
+// summed, and without baselines the optimized total is taken as 70% of the baseline
+const aggregatedMetricsSQL = `
+	WITH baseline AS (
+		SELECT COALESCE(SUM(estimated_hourly_emissions), 0)::double precision AS hourly
+		FROM baselines
+		WHERE measured_at > $1 AND measured_at < $2
+	), session AS (
+		SELECT COUNT(*) AS session_count,
+			COALESCE(SUM(total_emissions), 0)::double precision AS optimized,
+			COALESCE(SUM(estimated_savings), 0)::double precision AS savings,
+			COALESCE(SUM(optimizations_applied), 0) AS optimizations
+		FROM sessions
+		WHERE start_time > $1 AND start_time < $2
+	)
+	SELECT
+		CASE WHEN baseline.hourly = 0 AND session.session_count > 0 THEN session.optimized / 0.7
+			ELSE baseline.hourly * $3 END,
+		session.optimized, session.savings, session.session_count, session.optimizations
+	FROM baseline, session`
+
+// GetAggregatedMetrics calculates aggregated metrics for a period in SQL
+func (ps *PostgreSQLStorage) GetAggregatedMetrics(ctx context.Context, period ReportPeriod) (*AggregatedMetrics, error) {
+	metrics := &AggregatedMetrics{Period: period}
+	hours := period.End.Sub(period.Start).Hours()
+
+	err := ps.db.QueryRowContext(ctx, aggregatedMetricsSQL, period.Start.UTC(), period.End.UTC(), hours).Scan(
+		&metrics.TotalBaseline, &metrics.TotalOptimized, &metrics.TotalSavings,
+		&metrics.SessionCount, &metrics.OptimizationCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate metrics: %w", err)
+	}
+	return metrics, nil
+}
+
+// marshalJSONB encodes a value for a JSONB column; nil maps and slices are stored as NULL
+func marshalJSONB(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JSON column: %w", err)
+	}
+	if string(data) == "null" {
+		return nil, nil
+	}
+	return string(data), nil
+}
+
+// unmarshalJSONB decodes a JSONB column, leaving dest untouched for NULL
+func unmarshalJSONB(data []byte, dest interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		return fmt.Errorf("failed to decode JSON column: %w", err)
+	}
+	return nil
+}
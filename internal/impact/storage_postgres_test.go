@@ -0,0 +1,458 @@
+package impact
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq" // Driver for the round trip against IMPACT_POSTGRES_DSN
+)
+
+// fakeDB is a containerless stand-in for PostgreSQL. It records statements with their
+// bound arguments, keeps track of applied schema migrations, stores inserted rows by
+// their first argument and serves them back to SELECT ... WHERE <key> = $1 in insert
+// argument order, which matches the column order the storage reads. Other queries
+// return the row scripted for them, or no rows.
+type fakeDB struct {
+	mu         sync.Mutex
+	statements []string
+	args       [][]driver.Value
+	versions   []int64
+	tables     map[string][][]driver.Value
+	scripted   map[string][]driver.Value
+}
+
+var (
+	fakeInsertPattern = regexp.MustCompile(`^\s*INSERT INTO (\w+)`)
+	fakeSelectPattern = regexp.MustCompile(`FROM (\w+)\s+WHERE (\w+) = \$1`)
+)
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return nil, errors.New("use the connector") }
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return c, nil }
+func (c *fakeConn) Commit() error                       { return nil }
+func (c *fakeConn) Rollback() error                     { return nil }
+
+// record appends a statement and its bound arguments; callers hold the lock
+func (f *fakeDB) record(query string, named []driver.NamedValue) []driver.Value {
+	args := make([]driver.Value, len(named))
+	for i, arg := range named {
+		args[i] = arg.Value
+	}
+	f.statements = append(f.statements, query)
+	f.args = append(f.args, args)
+	return args
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, named []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	args := c.db.record(query, named)
+	if strings.HasPrefix(query, "INSERT INTO schema_migrations") {
+		c.db.versions = append(c.db.versions, args[0].(int64))
+	} else if match := fakeInsertPattern.FindStringSubmatch(query); match != nil {
+		if c.db.tables == nil {
+			c.db.tables = make(map[string][][]driver.Value)
+		}
+		rows := c.db.tables[match[1]]
+		for i, row := range rows {
+			if row[0] == args[0] {
+				rows = append(rows[:i], rows[i+1:]...) // ON CONFLICT (key) DO UPDATE
+				break
+			}
+		}
+		c.db.tables[match[1]] = append(rows, args)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, named []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	args := c.db.record(query, named)
+	rows := &fakeRows{}
+	switch match := fakeSelectPattern.FindStringSubmatch(query); {
+	case query == "SELECT version FROM schema_migrations":
+		for _, version := range c.db.versions {
+			rows.values = append(rows.values, []driver.Value{version})
+		}
+	case match != nil && match[2] == "url":
+		// ListBaselines: url = $1 AND measured_at between $2 and $3
+		for _, row := range c.db.tables[match[1]] {
+			measuredAt := row[10].(time.Time)
+			if row[1] == args[0] && !measuredAt.Before(args[1].(time.Time)) && !measuredAt.After(args[2].(time.Time)) {
+				rows.values = append(rows.values, row)
+			}
+		}
+	case match != nil:
+		for _, row := range c.db.tables[match[1]] {
+			if row[0] == args[0] {
+				rows.values = append(rows.values, row)
+			}
+		}
+	default:
+		if row, exists := c.db.scripted[query]; exists {
+			rows.values = append(rows.values, row)
+		}
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	values [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.values) == 0 {
+		return []string{"version"}
+	}
+	columns := make([]string, len(r.values[0]))
+	for i := range columns {
+		columns[i] = fmt.Sprintf("column%d", i)
+	}
+	return columns
+}
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// lastArgs returns the bound arguments of the latest statement starting with prefix
+func (f *fakeDB) lastArgs(prefix string) []driver.Value {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := len(f.statements) - 1; i >= 0; i-- {
+		if strings.HasPrefix(strings.TrimSpace(f.statements[i]), prefix) {
+			return f.args[i]
+		}
+	}
+	return nil
+}
+
+func (f *fakeDB) count(prefix string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	count := 0
+	for _, statement := range f.statements {
+		if strings.HasPrefix(strings.TrimSpace(statement), prefix) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestPostgreSQLStorageMigrations(t *testing.T) {
+	fake := &fakeDB{}
+	db := sql.OpenDB(fake)
+	defer db.Close()
+
+	ctx := context.Background()
+	storage, err := NewPostgreSQLStorageWithDB(ctx, db)
+	if err != nil {
+		t.Fatalf("NewPostgreSQLStorageWithDB failed: %v", err)
+	}
+
+	if got := len(fake.versions); got != len(postgresMigrations) {
+		t.Fatalf("expected %d migrations to be recorded, got %d", len(postgresMigrations), got)
+	}
+	if got := fake.count("CREATE TABLE IF NOT EXISTS baselines"); got != 1 {
+		t.Errorf("expected baselines table to be created once, got %d", got)
+	}
+	if got := fake.count("SELECT pg_advisory_xact_lock"); got != 1 {
+		t.Errorf("expected migrations to take the advisory lock, got %d", got)
+	}
+	if got := fake.count("ALTER TABLE sessions"); got != 1 {
+		t.Errorf("expected session emissions to be widened to double precision once, got %d", got)
+	}
+
+	// A second run finds every version applied and changes nothing
+	if err := storage.Migrate(ctx); err != nil {
+		t.Fatalf("second Migrate failed: %v", err)
+	}
+	if got := len(fake.versions); got != len(postgresMigrations) {
+		t.Errorf("expected migrations to be idempotent, got %d recorded versions", got)
+	}
+	if got := fake.count("CREATE TABLE IF NOT EXISTS baselines"); got != 1 {
+		t.Errorf("expected applied migrations to be skipped, baselines created %d times", got)
+	}
+}
+
+func TestPostgreSQLStorageNotFound(t *testing.T) {
+	db := sql.OpenDB(&fakeDB{})
+	defer db.Close()
+
+	storage := &PostgreSQLStorage{db: db}
+	ctx := context.Background()
+
+	if _, err := storage.GetBaseline(ctx, "missing"); err == nil || err.Error() != "baseline with ID missing not found" {
+		t.Errorf("GetBaseline: unexpected error %v", err)
+	}
+	if _, err := storage.GetSession(ctx, "missing"); err == nil || err.Error() != "session with ID missing not found" {
+		t.Errorf("GetSession: unexpected error %v", err)
+	}
+	if _, err := storage.GetReport(ctx, "missing"); err == nil || err.Error() != "report with ID missing not found" {
+		t.Errorf("GetReport: unexpected error %v", err)
+	}
+}
+
+func TestPostgreSQLStorageBindsAndScans(t *testing.T) {
+	fake := &fakeDB{}
+	db := sql.OpenDB(fake)
+	defer db.Close()
+
+	storage := &PostgreSQLStorage{db: db}
+	ctx := context.Background()
+	measuredAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+
+	baseline := &BaselineMeasurement{
+		ID:                       "baseline-1",
+		URL:                      "https://example.com/",
+		PageLoadEmissions:        1.5,
+		DataTransferred:          2.25,
+		ResourceCount:            map[string]int{"image": 12},
+		EstimatedHourlyEmissions: 10,
+		MeasuredAt:               measuredAt,
+		DeviceType:               "smartphone",
+		ConnectionType:           "mobile_4g",
+		Region:                   "EU",
+		Source:                   SourceLighthouse,
+		Deploy:                   "abc123",
+	}
+	if err := storage.SaveBaseline(ctx, baseline); err != nil {
+		t.Fatal(err)
+	}
+
+	// Timestamps are bound in UTC and maps as JSON text for the JSONB column
+	args := fake.lastArgs("INSERT INTO baselines")
+	if len(args) != 16 || args[0] != "baseline-1" || args[8] != `{"image":12}` || args[15] != "abc123" {
+		t.Fatalf("unexpected baseline arguments: %v", args)
+	}
+	if boundAt, ok := args[10].(time.Time); !ok || boundAt.Location() != time.UTC || !boundAt.Equal(measuredAt) {
+		t.Errorf("expected measured_at bound in UTC, got %v", args[10])
+	}
+
+	got, err := storage.GetBaseline(ctx, "baseline-1")
+	if err != nil {
+		t.Fatalf("GetBaseline failed: %v", err)
+	}
+	if got.ResourceCount["image"] != 12 || got.Deploy != "abc123" || got.Source != SourceLighthouse || !got.MeasuredAt.Equal(measuredAt) {
+		t.Errorf("baseline did not round trip: %+v", got)
+	}
+
+	later := *baseline
+	later.ID, later.MeasuredAt, later.ResourceCount = "baseline-2", measuredAt.Add(48*time.Hour), nil
+	if err := storage.SaveBaseline(ctx, &later); err != nil {
+		t.Fatal(err)
+	}
+	if args := fake.lastArgs("INSERT INTO baselines"); args[8] != nil {
+		t.Errorf("expected a nil map to be stored as NULL, got %v", args[8])
+	}
+	history, err := storage.ListBaselines(ctx, baseline.URL, ReportPeriod{Start: measuredAt, End: measuredAt.Add(24 * time.Hour)})
+	if err != nil {
+		t.Fatalf("ListBaselines failed: %v", err)
+	}
+	if len(history) != 1 || history[0].ID != "baseline-1" {
+		t.Errorf("expected only the baseline within the period, got %+v", history)
+	}
+
+	session := &SessionMetrics{
+		SessionID:           "session-1",
+		StartTime:           measuredAt,
+		Duration:            90,
+		TotalEmissions:      4,
+		EmissionsByActivity: map[string]float64{"video": 3.5},
+		DeviceType:          "laptop",
+	}
+	if err := storage.SaveSession(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	gotSession, err := storage.GetSession(ctx, "session-1")
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if gotSession.EmissionsByActivity["video"] != 3.5 || gotSession.Duration != 90 || gotSession.StartTime.Location() != time.UTC {
+		t.Errorf("session did not round trip: %+v", gotSession)
+	}
+
+	report := &ImpactReport{
+		ID:              "report-1",
+		Period:          ReportPeriod{Start: measuredAt.Add(-24 * time.Hour), End: measuredAt, Days: 1},
+		TotalSavings:    1.5,
+		SavingsByType:   map[ImpactType]float64{ImpactTypeVideoStreaming: 1.5},
+		EquivalentTo:    []Equivalence{{Type: "driving", Value: 0.01, Unit: "km"}},
+		Recommendations: []string{"Lazy load images"},
+		GeneratedAt:     measuredAt,
+	}
+	if err := storage.SaveReport(ctx, report); err != nil {
+		t.Fatal(err)
+	}
+	gotReport, err := storage.GetReport(ctx, "report-1")
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	if gotReport.SavingsByType[ImpactTypeVideoStreaming] != 1.5 || len(gotReport.EquivalentTo) != 1 ||
+		gotReport.EquivalentTo[0].Unit != "km" || gotReport.Recommendations[0] != "Lazy load images" || gotReport.Methodology != "" {
+		t.Errorf("report did not round trip: %+v", gotReport)
+	}
+
+	// A corrupt JSONB column is reported instead of silently dropped
+	fake.tables["sessions"][0][4] = "{not json"
+	if _, err := storage.GetSession(ctx, "session-1"); err == nil || !strings.Contains(err.Error(), "decode JSON column") {
+		t.Errorf("expected a JSON decode error, got %v", err)
+	}
+}
+
+func TestPostgreSQLStorageAggregatedMetrics(t *testing.T) {
+	fake := &fakeDB{scripted: map[string][]driver.Value{
+		aggregatedMetricsSQL: {240.0, 100.0, 40.0, int64(3), int64(7)},
+	}}
+	db := sql.OpenDB(fake)
+	defer db.Close()
+
+	storage := &PostgreSQLStorage{db: db}
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.FixedZone("CET", 3600))
+	period := ReportPeriod{Start: start, End: start.Add(48 * time.Hour)}
+
+	metrics, err := storage.GetAggregatedMetrics(context.Background(), period)
+	if err != nil {
+		t.Fatalf("GetAggregatedMetrics failed: %v", err)
+	}
+	if metrics.TotalBaseline != 240 || metrics.TotalSavings != 40 || metrics.SessionCount != 3 || metrics.OptimizationCount != 7 {
+		t.Errorf("unexpected metrics: %+v", metrics)
+	}
+
+	args := fake.lastArgs("WITH baseline AS")
+	if len(args) != 3 || args[2] != 48.0 {
+		t.Fatalf("expected the period length in hours as $3, got %v", args)
+	}
+	if boundStart, ok := args[0].(time.Time); !ok || boundStart.Location() != time.UTC || !boundStart.Equal(start) {
+		t.Errorf("expected the period start bound in UTC, got %v", args[0])
+	}
+}
+
+// TestPostgreSQLStorageRoundTrip runs against a real database when IMPACT_POSTGRES_DSN is set
+func TestPostgreSQLStorageRoundTrip(t *testing.T) {
+	dsn := os.Getenv("IMPACT_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("IMPACT_POSTGRES_DSN not set")
+	}
+
+	storage, err := NewPostgreSQLStorage(dsn)
+	if err != nil {
+		t.Skipf("PostgreSQL not available for testing: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	suffix := now.Format("20060102150405.000000000")
+
+	baseline := &BaselineMeasurement{
+		ID:                       "baseline-" + suffix,
+		URL:                      "https://example.com",
+		PageLoadEmissions:        0.0004,
+		DataTransferred:          2.25,
+		ResourceCount:            map[string]int{"images": 12},
+		EstimatedHourlyEmissions: 10,
+		MeasuredAt:               now,
+		DeviceType:               "laptop",
+		ConnectionType:           "wifi",
+		Region:                   "EU",
+		Source:                   "lighthouse",
+		Deploy:                   "abc123",
+	}
+	if err := storage.SaveBaseline(ctx, baseline); err != nil {
+		t.Fatalf("SaveBaseline failed: %v", err)
+	}
+	gotBaseline, err := storage.GetBaseline(ctx, baseline.ID)
+	if err != nil {
+		t.Fatalf("GetBaseline failed: %v", err)
+	}
+	if gotBaseline.PageLoadEmissions != 0.0004 {
+		t.Errorf("expected sub-milligram emissions to round trip, got %v", gotBaseline.PageLoadEmissions)
+	}
+	if gotBaseline.ResourceCount["images"] != 12 || !gotBaseline.MeasuredAt.Equal(now) {
+		t.Errorf("baseline did not round trip: %+v", gotBaseline)
+	}
+	history, err := storage.ListBaselines(ctx, baseline.URL, ReportPeriod{Start: now, End: now})
+	if err != nil {
+		t.Fatalf("ListBaselines failed: %v", err)
+	}
+	if len(history) == 0 || history[len(history)-1].Deploy != "abc123" {
+		t.Errorf("baseline not listed for its URL: %+v", history)
+	}
+
+	session := &SessionMetrics{
+		SessionID:            "session-" + suffix,
+		StartTime:            now,
+		TotalEmissions:       4,
+		EmissionsByActivity:  map[string]float64{"video": 3.5},
+		OptimizationsApplied: 2,
+		EstimatedSavings:     1.5,
+		DeviceType:           "laptop",
+		Region:               "EU",
+	}
+	if err := storage.SaveSession(ctx, session); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+
+	report := &ImpactReport{
+		ID:              "report-" + suffix,
+		Period:          ReportPeriod{Start: now.Add(-24 * time.Hour), End: now, Days: 1},
+		TotalSavings:    1.5,
+		SavingsByType:   map[ImpactType]float64{ImpactTypeVideoStreaming: 1.5},
+		EquivalentTo:    []Equivalence{{Type: "driving", Value: 0.01, Unit: "km"}},
+		Recommendations: []string{"Lazy load images"},
+		GeneratedAt:     now,
+	}
+	if err := storage.SaveReport(ctx, report); err != nil {
+		t.Fatalf("SaveReport failed: %v", err)
+	}
+	gotReport, err := storage.GetReport(ctx, report.ID)
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	if gotReport.SavingsByType[ImpactTypeVideoStreaming] != 1.5 || len(gotReport.Recommendations) != 1 {
+		t.Errorf("report did not round trip: %+v", gotReport)
+	}
+
+	// Narrow the period around this run so rows from other runs are excluded
+	period := ReportPeriod{Start: now.Add(-time.Second), End: now.Add(time.Second)}
+	metrics, err := storage.GetAggregatedMetrics(ctx, period)
+	if err != nil {
+		t.Fatalf("GetAggregatedMetrics failed: %v", err)
+	}
+	if metrics.SessionCount < 1 || metrics.OptimizationCount < 2 || metrics.TotalSavings < 1.5 {
+		t.Errorf("unexpected aggregated metrics: %+v", metrics)
+	}
+	if metrics.TotalBaseline < 10*period.End.Sub(period.Start).Hours() {
+		t.Errorf("expected baseline extrapolated over the period, got %f", metrics.TotalBaseline)
+	}
+}
@@ -0,0 +1,248 @@
+package impact
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// regressionHistoryWindow is how far back previous baselines are compared with a new one
+const regressionHistoryWindow = 90 * 24 * time.Hour
+
+// regressionWindow is the number of previous baselines whose median is the reference,
+// so a single noisy run neither hides nor raises a regression
+const regressionWindow = 5
+
+// Regression metrics
+const (
+	RegressionMetricPageWeight = "page_weight"
+	RegressionMetricEmissions  = "emissions"
+)
+
+// RegressionThresholds are the increases over the reference that raise an alert
+type RegressionThresholds struct {
+	// PageWeightPercent increase in data transferred
+	PageWeightPercent float64 `json:"page_weight_percent"`
+
+	// EmissionsPercent increase in page load emissions
+	EmissionsPercent float64 `json:"emissions_percent"`
+}
+
+// DefaultRegressionThresholds alert on increases of more than 10%
+var DefaultRegressionThresholds = RegressionThresholds{
+	PageWeightPercent: 10,
+	EmissionsPercent:  10,
+}
+
+// withDefaults replaces unset thresholds with the defaults
+func (t RegressionThresholds) withDefaults() RegressionThresholds {
+	if t.PageWeightPercent <= 0 {
+		t.PageWeightPercent = DefaultRegressionThresholds.PageWeightPercent
+	}
+	if t.EmissionsPercent <= 0 {
+		t.EmissionsPercent = DefaultRegressionThresholds.EmissionsPercent
+	}
+	return t
+}
+
+// RegressionReference summarizes the previous baselines a measurement is compared with
+type RegressionReference struct {
+	// Baselines the reference is the median of
+	Baselines int `json:"baselines"`
+
+	// DataTransferred median in MB
+	DataTransferred float64 `json:"data_transferred"`
+
+	// PageLoadEmissions median in g CO2e
+	PageLoadEmissions float64 `json:"page_load_emissions"`
+
+	// LatestBaselineID and LatestDeploy identify the most recent previous baseline
+	LatestBaselineID string `json:"latest_baseline_id"`
+	LatestDeploy     string `json:"latest_deploy,omitempty"`
+}
+
+// RegressionAlert reports a measurement that exceeds its reference by more than the threshold
+type RegressionAlert struct {
+	URL        string    `json:"url"`
+	BaselineID string    `json:"baseline_id"`
+	Deploy     string    `json:"deploy,omitempty"`
+	MeasuredAt time.Time `json:"measured_at"`
+
+	// Metric is page_weight (MB) or emissions (g CO2e)
+	Metric        string  `json:"metric"`
+	Reference     float64 `json:"reference"`
+	Current       float64 `json:"current"`
+	ChangePercent float64 `json:"change_percent"`
+	Threshold     float64 `json:"threshold"`
+	Message       string  `json:"message"`
+}
+
+// TrendPoint is a single baseline in a page's trend
+type TrendPoint struct {
+	BaselineID        string    `json:"baseline_id"`
+	MeasuredAt        time.Time `json:"measured_at"`
+	Deploy            string    `json:"deploy,omitempty"`
+	Source            string    `json:"source,omitempty"`
+	DeviceType        string    `json:"device_type"`
+	DataTransferred   float64   `json:"data_transferred"`
+	PageLoadEmissions float64   `json:"page_load_emissions"`
+	Regression        bool      `json:"regression"`
+}
+
+// PageTrend shows how a page's weight and emissions developed over a period
+type PageTrend struct {
+	URL    string       `json:"url"`
+	Period ReportPeriod `json:"period"`
+	Points []TrendPoint `json:"points"`
+
+	// DataTransferredChange and EmissionsChange from the first to the latest point, in percent
+	DataTransferredChange float64 `json:"data_transferred_change"`
+	EmissionsChange       float64 `json:"emissions_change"`
+
+	// EmissionsSlope is the least-squares trend of page load emissions in g CO2e per day
+	EmissionsSlope float64 `json:"emissions_slope"`
+
+	// Regressions raised over the period
+	Regressions []RegressionAlert `json:"regressions"`
+}
+
+// comparableBaselines returns the baselines measured before baseline under the same
+// device, connection and region, oldest first
+func comparableBaselines(baseline *BaselineMeasurement, history []*BaselineMeasurement) []*BaselineMeasurement {
+	var comparable []*BaselineMeasurement
+	for _, previous := range history {
+		if previous.ID == baseline.ID || previous.MeasuredAt.After(baseline.MeasuredAt) {
+			continue
+		}
+		if previous.DeviceType == baseline.DeviceType && previous.ConnectionType == baseline.ConnectionType && previous.Region == baseline.Region {
+			comparable = append(comparable, previous)
+		}
+	}
+	return comparable
+}
+
+// detectRegressions compares a baseline with the median of the latest previous baselines
+func detectRegressions(baseline *BaselineMeasurement, previous []*BaselineMeasurement, thresholds RegressionThresholds) (*RegressionReference, []RegressionAlert) {
+	alerts := []RegressionAlert{}
+	if len(previous) == 0 {
+		return nil, alerts
+	}
+	if len(previous) > regressionWindow {
+		previous = previous[len(previous)-regressionWindow:]
+	}
+	thresholds = thresholds.withDefaults()
+
+	weights := make([]float64, len(previous))
+	emissions := make([]float64, len(previous))
+	for i, measurement := range previous {
+		weights[i] = measurement.DataTransferred
+		emissions[i] = measurement.PageLoadEmissions
+	}
+	latest := previous[len(previous)-1]
+	reference := &RegressionReference{
+		Baselines:         len(previous),
+		DataTransferred:   median(weights),
+		PageLoadEmissions: median(emissions),
+		LatestBaselineID:  latest.ID,
+		LatestDeploy:      latest.Deploy,
+	}
+
+	check := func(metric, unit string, referenceValue, current, threshold float64) {
+		if referenceValue <= 0 {
+			return
+		}
+		change := (current - referenceValue) / referenceValue * 100
+		if change <= threshold {
+			return
+		}
+		alerts = append(alerts, RegressionAlert{
+			URL:           baseline.URL,
+			BaselineID:    baseline.ID,
+			Deploy:        baseline.Deploy,
+			MeasuredAt:    baseline.MeasuredAt,
+			Metric:        metric,
+			Reference:     referenceValue,
+			Current:       current,
+			ChangePercent: change,
+			Threshold:     threshold,
+			Message: fmt.Sprintf("%s increased %.1f%% from %.3f to %.3f %s, above the %.0f%% threshold",
+				metric, change, referenceValue, current, unit, threshold),
+		})
+	}
+	check(RegressionMetricPageWeight, "MB", reference.DataTransferred, baseline.DataTransferred, thresholds.PageWeightPercent)
+	check(RegressionMetricEmissions, "g CO2e", reference.PageLoadEmissions, baseline.PageLoadEmissions, thresholds.EmissionsPercent)
+
+	return reference, alerts
+}
+
+// GetPageTrend returns a page's baselines over the period with regressions marked.
+// An empty deviceType includes every device; regressions only compare like with like.
+func (s *Service) GetPageTrend(ctx context.Context, url, deviceType string, period ReportPeriod, thresholds RegressionThresholds) (*PageTrend, error) {
+	// Load earlier baselines too, so the first points in the period have a reference
+	history, err := s.storage.ListBaselines(ctx, url, ReportPeriod{Start: period.Start.Add(-regressionHistoryWindow), End: period.End})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load baseline history: %w", err)
+	}
+	sort.SliceStable(history, func(i, j int) bool { return history[i].MeasuredAt.Before(history[j].MeasuredAt) })
+
+	trend := &PageTrend{URL: url, Period: period, Points: []TrendPoint{}, Regressions: []RegressionAlert{}}
+	for i, baseline := range history {
+		if baseline.MeasuredAt.Before(period.Start) || (deviceType != "" && baseline.DeviceType != deviceType) {
+			continue
+		}
+		_, alerts := detectRegressions(baseline, comparableBaselines(baseline, history[:i]), thresholds)
+		trend.Regressions = append(trend.Regressions, alerts...)
+		trend.Points = append(trend.Points, TrendPoint{
+			BaselineID:        baseline.ID,
+			MeasuredAt:        baseline.MeasuredAt,
+			Deploy:            baseline.Deploy,
+			Source:            baseline.Source,
+			DeviceType:        baseline.DeviceType,
+			DataTransferred:   baseline.DataTransferred,
+			PageLoadEmissions: baseline.PageLoadEmissions,
+			Regression:        len(alerts) > 0,
+		})
+	}
+
+	if len(trend.Points) > 1 {
+		first, last := trend.Points[0], trend.Points[len(trend.Points)-1]
+		if first.DataTransferred > 0 {
+			trend.DataTransferredChange = (last.DataTransferred - first.DataTransferred) / first.DataTransferred * 100
+		}
+		if first.PageLoadEmissions > 0 {
+			trend.EmissionsChange = (last.PageLoadEmissions - first.PageLoadEmissions) / first.PageLoadEmissions * 100
+		}
+		trend.EmissionsSlope = emissionsSlope(trend.Points)
+	}
+	return trend, nil
+}
+
+// emissionsSlope fits a least-squares line through page load emissions over time, in g per day
+func emissionsSlope(points []TrendPoint) float64 {
+	origin := points[0].MeasuredAt
+	var sumX, sumY, sumXY, sumXX float64
+	for _, point := range points {
+		x := point.MeasuredAt.Sub(origin).Hours() / 24
+		sumX += x
+		sumY += point.PageLoadEmissions
+		sumXY += x * point.PageLoadEmissions
+		sumXX += x * x
+	}
+	n := float64(len(points))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
+
+// median returns the median of values; values is reordered
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	middle := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[middle-1] + values[middle]) / 2
+	}
+	return values[middle]
+}
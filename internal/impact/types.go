@@ -20,6 +20,11 @@ type EmissionFactors struct {
 
 	// DataCenterPUE (Power Usage Effectiveness) by region
 	DataCenterPUE map[string]float64
+
+	// Uncertainty holds factor distributions for Monte Carlo sampling, keyed by factor
+	// group (e.g. "grid_carbon_intensity") or by group and key ("grid_carbon_intensity/FR").
+	// Factors without a distribution are treated as exact.
+	Uncertainty map[string]FactorDistribution
 }
 
 // DefaultEmissionFactors provides conservative emission factors based on 2023 data
@@ -77,6 +82,18 @@ var DefaultEmissionFactors = EmissionFactors{
 		"US":     1.8,  // US average
 		"global": 1.67, // Global average
 	},
+	Uncertainty: map[string]FactorDistribution{
+		// Annual averages hide hourly variation and location- versus market-based accounting
+		FactorGridCarbonIntensity: {Type: DistributionNormal, StdDev: 0.15},
+		// Low-carbon grids vary most in relative terms
+		FactorGridCarbonIntensity + "/FR": {Type: DistributionNormal, StdDev: 0.3},
+		// Published network intensity estimates span more than a factor of four (Aslan et al. 2018)
+		FactorNetworkTransmission: {Type: DistributionUniform, Min: 0.5, Max: 2.0},
+		// Power draw varies with hardware generation, screen brightness and workload
+		FactorDeviceConsumption: {Type: DistributionNormal, StdDev: 0.25},
+		// Uptime Institute survey range around the reported averages
+		FactorDataCenterPUE: {Type: DistributionUniform, Min: 0.9, Max: 1.2},
+	},
 }
 
 // ImpactType represents different types of carbon impact measurements
@@ -122,6 +139,32 @@ type CalculationRequest struct {
 
 	// Include rebound effects in calculation
 	IncludeReboundEffects bool `json:"include_rebound_effects,omitempty"`
+
+	// Methodology selects the emissions model (greenweb, swd, onebyte); defaults to greenweb
+	Methodology CalculationMethodology `json:"methodology,omitempty" validate:"omitempty,oneof=greenweb swd onebyte"`
+
+	// GreenHostingRatio (0-1) is the share of data center energy from renewables (swd, onebyte)
+	GreenHostingRatio float64 `json:"green_hosting_ratio,omitempty" validate:"min=0,max=1"`
+
+	// ReturningVisitorRatio (0-1) is the share of returning visitors (swd); defaults to 0.25
+	ReturningVisitorRatio *float64 `json:"returning_visitor_ratio,omitempty" validate:"omitempty,min=0,max=1"`
+
+	// ReturningVisitorDataRatio (0-1) is the share of data reloaded by returning visitors (swd); defaults to 0.02
+	ReturningVisitorDataRatio *float64 `json:"returning_visitor_data_ratio,omitempty" validate:"omitempty,min=0,max=1"`
+
+	// Zone is the grid zone (e.g. "DE", "US-CAL-CISO") whose live or historical intensity
+	// produces a time-specific result alongside the average-factor result
+	Zone string `json:"zone,omitempty"`
+
+	// Timestamp of the activity for historical intensity; defaults to now. Requires Zone.
+	Timestamp time.Time `json:"timestamp,omitempty"`
+
+	// MonteCarloSamples samples the emission factor distributions to report percentiles
+	// and a sensitivity ranking; 0 keeps the fixed confidence interval
+	MonteCarloSamples int `json:"monte_carlo_samples,omitempty" validate:"omitempty,min=100,max=100000"`
+
+	// MonteCarloSeed makes sampling reproducible
+	MonteCarloSeed int64 `json:"monte_carlo_seed,omitempty"`
 }
 
 // ImpactResult represents the calculated carbon impact with confidence intervals
@@ -159,16 +202,71 @@ type ImpactResult struct {
 	// Methodology explanation
 	Methodology string `json:"methodology"`
 
+	// Model is the emissions model used (greenweb, swd, onebyte)
+	Model CalculationMethodology `json:"model"`
+
+	// ModelVersion of the emissions model
+	ModelVersion string `json:"model_version"`
+
 	// DataSources used for calculation
 	DataSources []string `json:"data_sources"`
 
 	// Warnings about limitations or assumptions
 	Warnings []string `json:"warnings,omitempty"`
 
+	// Uncertainty from Monte Carlo sampling of the emission factors, if requested
+	Uncertainty *UncertaintyResult `json:"uncertainty,omitempty"`
+
+	// TimeSpecific recalculates the result with the zone's grid intensity at the requested time
+	TimeSpecific *TimeSpecificImpact `json:"time_specific,omitempty"`
+
 	// CalculatedAt timestamp
 	CalculatedAt time.Time `json:"calculated_at"`
 }
 
+// TimeSpecificImpact is an impact result using a grid zone's actual intensity at a point
+// in time instead of the static regional average
+type TimeSpecificImpact struct {
+	// Zone the intensity was read for
+	Zone string `json:"zone"`
+
+	// Timestamp requested
+	Timestamp time.Time `json:"timestamp"`
+
+	// GridIntensity in g CO2/kWh at the requested time
+	GridIntensity float64 `json:"grid_intensity"`
+
+	// AverageGridIntensity in g CO2/kWh used by the average-factor result
+	AverageGridIntensity float64 `json:"average_grid_intensity"`
+
+	// IntensityTimestamp of the reading used
+	IntensityTimestamp time.Time `json:"intensity_timestamp"`
+
+	// IntensitySource of the reading (e.g. "electricity_maps", "mock")
+	IntensitySource string `json:"intensity_source,omitempty"`
+
+	// BaselineEmissions in grams CO2e
+	BaselineEmissions float64 `json:"baseline_emissions"`
+
+	// OptimizedEmissions in grams CO2e
+	OptimizedEmissions float64 `json:"optimized_emissions"`
+
+	// Savings in grams CO2e
+	Savings float64 `json:"savings"`
+
+	// NetSavings after accounting for rebound effects
+	NetSavings float64 `json:"net_savings"`
+
+	// LowerBound of emissions estimate
+	LowerBound float64 `json:"lower_bound"`
+
+	// UpperBound of emissions estimate
+	UpperBound float64 `json:"upper_bound"`
+
+	// DifferencePercentage of optimized emissions relative to the average-factor result
+	DifferencePercentage float64 `json:"difference_percentage"`
+}
+
 // EmissionComponents breaks down emissions by source
 type EmissionComponents struct {
 	// DeviceEmissions from client device energy use
@@ -180,10 +278,14 @@ type EmissionComponents struct {
 	// DataCenterEmissions from server processing
 	DataCenterEmissions float64 `json:"datacenter_emissions"`
 
+	// ProductionEmissions from manufacturing hardware (swd only)
+	ProductionEmissions float64 `json:"production_emissions,omitempty"`
+
 	// Percentages for each component
 	DevicePercentage     float64 `json:"device_percentage"`
 	NetworkPercentage    float64 `json:"network_percentage"`
 	DataCenterPercentage float64 `json:"datacenter_percentage"`
+	ProductionPercentage float64 `json:"production_percentage,omitempty"`
 }
 
 // BaselineMeasurement represents measured baseline carbon footprint
@@ -229,6 +331,12 @@ type BaselineMeasurement struct {
 
 	// Region of measurement
 	Region string `json:"region"`
+
+	// Source of the measurement, e.g. "lighthouse"; empty for manual measurements
+	Source string `json:"source,omitempty"`
+
+	// Deploy identifies the release measured, e.g. a commit SHA from CI
+	Deploy string `json:"deploy,omitempty"`
 }
 
 // ImpactReport represents a comprehensive impact report
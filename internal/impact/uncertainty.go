@@ -0,0 +1,317 @@
+package impact
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Monte Carlo sample limits; fewer samples make the 5th and 95th percentiles unstable
+const (
+	minMonteCarloSamples = 100
+	maxMonteCarloSamples = 100000
+)
+
+// Emission factor groups that carry distributions
+const (
+	FactorGridCarbonIntensity = "grid_carbon_intensity"
+	FactorNetworkTransmission = "network_transmission"
+	FactorDeviceConsumption   = "device_consumption"
+	FactorDataCenterPUE       = "data_center_pue"
+)
+
+// factorGroups is the order factor groups are sampled in, which keeps seeded runs stable
+var factorGroups = []string{
+	FactorGridCarbonIntensity,
+	FactorNetworkTransmission,
+	FactorDeviceConsumption,
+	FactorDataCenterPUE,
+}
+
+// DistributionType is the shape of a factor distribution
+type DistributionType string
+
+const (
+	// DistributionNormal varies a factor by a relative standard deviation
+	DistributionNormal DistributionType = "normal"
+
+	// DistributionUniform varies a factor evenly between relative bounds
+	DistributionUniform DistributionType = "uniform"
+)
+
+// FactorDistribution describes the uncertainty of an emission factor relative to its value
+type FactorDistribution struct {
+	Type DistributionType `json:"type"`
+
+	// StdDev of a normal distribution as a share of the value, e.g. 0.15 for ±15%
+	StdDev float64 `json:"std_dev,omitempty"`
+
+	// Min and Max of a uniform distribution as multiples of the value, e.g. 0.5 and 2
+	Min float64 `json:"min,omitempty"`
+	Max float64 `json:"max,omitempty"`
+}
+
+// multiplier maps a standard normal draw to a multiple of the factor value. Factors of a
+// group share one draw, so the same draw gives each key's distribution the same quantile.
+func (d FactorDistribution) multiplier(z float64) float64 {
+	switch d.Type {
+	case DistributionNormal:
+		return math.Max(0, 1+d.StdDev*z)
+	case DistributionUniform:
+		quantile := 0.5 * math.Erfc(-z/math.Sqrt2)
+		return d.Min + (d.Max-d.Min)*quantile
+	default:
+		return 1
+	}
+}
+
+// distribution returns the distribution of a factor, preferring one for the key over
+// the group's
+func (f EmissionFactors) distribution(group, key string) (FactorDistribution, bool) {
+	if distribution, exists := f.Uncertainty[group+"/"+key]; exists {
+		return distribution, true
+	}
+	distribution, exists := f.Uncertainty[group]
+	return distribution, exists
+}
+
+// Percentiles summarizes a sampled quantity in g CO2e
+type Percentiles struct {
+	P5     float64 `json:"p5"`
+	P50    float64 `json:"p50"`
+	P95    float64 `json:"p95"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"std_dev"`
+}
+
+// SensitivityEntry ranks how much a factor group drives the uncertainty of baseline emissions
+type SensitivityEntry struct {
+	// Input is the factor group
+	Input string `json:"input"`
+
+	// Correlation is the rank correlation between the input's draws and baseline emissions
+	Correlation float64 `json:"correlation"`
+
+	// Contribution is the input's share of the explained variance, in percent
+	Contribution float64 `json:"contribution"`
+}
+
+// UncertaintyResult reports the spread of a result under Monte Carlo sampling
+type UncertaintyResult struct {
+	Samples int   `json:"samples"`
+	Seed    int64 `json:"seed"`
+
+	Baseline  Percentiles `json:"baseline"`
+	Optimized Percentiles `json:"optimized"`
+	Savings   Percentiles `json:"savings"`
+
+	// NetSavings after rebound effects, sampled when rebound effects are included
+	NetSavings *Percentiles `json:"net_savings,omitempty"`
+
+	// Sensitivity ranks factor groups by their contribution, largest first
+	Sensitivity []SensitivityEntry `json:"sensitivity"`
+}
+
+// sampleMap scales every value of a factor map by its distribution's multiplier for z
+func sampleMap(factors EmissionFactors, group string, values map[string]float64, z float64) map[string]float64 {
+	sampled := make(map[string]float64, len(values))
+	for key, value := range values {
+		if distribution, exists := factors.distribution(group, key); exists {
+			value *= distribution.multiplier(z)
+		}
+		sampled[key] = value
+	}
+	return sampled
+}
+
+// sampledCalculator returns a calculator whose factors are drawn from their distributions
+func (c *Calculator) sampledCalculator(draws map[string]float64) *Calculator {
+	factors := c.factors
+	factors.GridCarbonIntensity = sampleMap(c.factors, FactorGridCarbonIntensity, c.factors.GridCarbonIntensity, draws[FactorGridCarbonIntensity])
+	factors.NetworkTransmission = sampleMap(c.factors, FactorNetworkTransmission, c.factors.NetworkTransmission, draws[FactorNetworkTransmission])
+	factors.DataCenterPUE = sampleMap(c.factors, FactorDataCenterPUE, c.factors.DataCenterPUE, draws[FactorDataCenterPUE])
+	factors.DeviceConsumption = make(map[string]map[string]float64, len(c.factors.DeviceConsumption))
+	for device, activities := range c.factors.DeviceConsumption {
+		scale := 1.0
+		if distribution, exists := c.factors.distribution(FactorDeviceConsumption, device); exists {
+			scale = distribution.multiplier(draws[FactorDeviceConsumption])
+		}
+		sampled := make(map[string]float64, len(activities))
+		for activity, power := range activities {
+			sampled[activity] = power * scale
+		}
+		factors.DeviceConsumption[device] = sampled
+	}
+
+	sampled := &Calculator{factors: factors}
+	if distribution, exists := c.factors.distribution(FactorGridCarbonIntensity, "global"); exists {
+		sampled.fallbackGridScale = math.Max(distribution.multiplier(draws[FactorGridCarbonIntensity]), math.SmallestNonzeroFloat64)
+	}
+	return sampled
+}
+
+// addUncertainty runs seeded Monte Carlo sampling of the emission factors and replaces
+// the fixed confidence interval with the sampled 90% interval
+func (c *Calculator) addUncertainty(result *ImpactResult, req *CalculationRequest) {
+	rng := rand.New(rand.NewSource(req.MonteCarloSeed))
+	baselines := make([]float64, 0, req.MonteCarloSamples)
+	optimized := make([]float64, 0, req.MonteCarloSamples)
+	savings := make([]float64, 0, req.MonteCarloSamples)
+	netSavings := make([]float64, 0, req.MonteCarloSamples)
+	draws := make(map[string][]float64, len(factorGroups))
+
+	sample := make(map[string]float64, len(factorGroups))
+	for i := 0; i < req.MonteCarloSamples; i++ {
+		for _, group := range factorGroups {
+			sample[group] = rng.NormFloat64()
+		}
+		sampledReq := *req
+		sampled, err := c.sampledCalculator(sample).calculateModel(&sampledReq)
+		if err != nil {
+			continue // A sample can only fail where the point estimate would have
+		}
+		baselines = append(baselines, sampled.BaselineEmissions)
+		optimized = append(optimized, sampled.OptimizedEmissions)
+		savings = append(savings, sampled.Savings)
+		if req.IncludeReboundEffects {
+			c.calculateReboundEffects(sampled, &sampledReq)
+			netSavings = append(netSavings, sampled.NetSavings)
+		}
+		for _, group := range factorGroups {
+			draws[group] = append(draws[group], sample[group])
+		}
+	}
+	if len(baselines) < minMonteCarloSamples {
+		result.Warnings = append(result.Warnings,
+			fmt.Sprintf("Monte Carlo sampling produced only %d valid samples; keeping the ±%.0f%% confidence interval", len(baselines), result.ConfidenceInterval))
+		return
+	}
+
+	uncertainty := &UncertaintyResult{
+		Samples:     len(baselines),
+		Seed:        req.MonteCarloSeed,
+		Baseline:    percentiles(baselines),
+		Optimized:   percentiles(optimized),
+		Savings:     percentiles(savings),
+		Sensitivity: sensitivity(draws, baselines),
+	}
+	if req.IncludeReboundEffects {
+		net := percentiles(netSavings)
+		uncertainty.NetSavings = &net
+	}
+	result.Uncertainty = uncertainty
+	result.LowerBound = uncertainty.Optimized.P5
+	result.UpperBound = uncertainty.Optimized.P95
+	if uncertainty.Optimized.P50 > 0 {
+		result.ConfidenceInterval = (uncertainty.Optimized.P95 - uncertainty.Optimized.P5) / 2 / uncertainty.Optimized.P50 * 100
+	}
+
+	result.Methodology += fmt.Sprintf(" Monte Carlo: %d samples (seed %d) of the emission factor distributions; bounds are the 5th and 95th percentiles.",
+		uncertainty.Samples, uncertainty.Seed)
+	if req.Methodology != MethodologyGreenWeb {
+		result.Warnings = append(result.Warnings,
+			fmt.Sprintf("The %s model's energy per GB is fixed; only grid intensity is sampled", req.Methodology))
+	}
+	if len(c.factors.Uncertainty) == 0 {
+		result.Warnings = append(result.Warnings,
+			"No emission factor distributions configured; Monte Carlo sampling shows no uncertainty")
+	}
+}
+
+// percentiles summarizes samples
+func percentiles(samples []float64) Percentiles {
+	samples = append([]float64(nil), samples...)
+	sort.Float64s(samples)
+
+	var sum float64
+	for _, sample := range samples {
+		sum += sample
+	}
+	mean := sum / float64(len(samples))
+	var variance float64
+	for _, sample := range samples {
+		variance += (sample - mean) * (sample - mean)
+	}
+
+	return Percentiles{
+		P5:     quantile(samples, 0.05),
+		P50:    quantile(samples, 0.5),
+		P95:    quantile(samples, 0.95),
+		Mean:   mean,
+		StdDev: math.Sqrt(variance / float64(len(samples))),
+	}
+}
+
+// quantile interpolates linearly between the closest ranks of sorted samples
+func quantile(sorted []float64, q float64) float64 {
+	position := q * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+}
+
+// sensitivity ranks factor groups by the squared rank correlation between their draws
+// and the output. Rank correlation captures monotonic effects of any distribution shape.
+func sensitivity(draws map[string][]float64, output []float64) []SensitivityEntry {
+	outputRanks := ranks(output)
+	entries := make([]SensitivityEntry, 0, len(factorGroups))
+	var total float64
+	for _, group := range factorGroups {
+		correlation := pearson(ranks(draws[group]), outputRanks)
+		entries = append(entries, SensitivityEntry{Input: group, Correlation: correlation})
+		total += correlation * correlation
+	}
+	for i := range entries {
+		if total > 0 {
+			entries[i].Contribution = entries[i].Correlation * entries[i].Correlation / total * 100
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Contribution > entries[j].Contribution })
+	return entries
+}
+
+// ranks returns the rank of each value, averaging ties
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return values[order[i]] < values[order[j]] })
+
+	result := make([]float64, len(values))
+	for start := 0; start < len(order); {
+		end := start
+		for end+1 < len(order) && values[order[end+1]] == values[order[start]] {
+			end++
+		}
+		rank := float64(start+end) / 2
+		for i := start; i <= end; i++ {
+			result[order[i]] = rank
+		}
+		start = end + 1
+	}
+	return result
+}
+
+// pearson returns the correlation coefficient of x and y, 0 if either is constant
+func pearson(x, y []float64) float64 {
+	n := float64(len(x))
+	var sumX, sumY float64
+	for i := range x {
+		sumX += x[i]
+		sumY += y[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var covariance, varianceX, varianceY float64
+	for i := range x {
+		covariance += (x[i] - meanX) * (y[i] - meanY)
+		varianceX += (x[i] - meanX) * (x[i] - meanX)
+		varianceY += (y[i] - meanY) * (y[i] - meanY)
+	}
+	if varianceX == 0 || varianceY == 0 {
+		return 0
+	}
+	return covariance / math.Sqrt(varianceX*varianceY)
+}
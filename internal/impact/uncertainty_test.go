@@ -0,0 +1,165 @@
+package impact
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCalculator_MonteCarloUncertainty(t *testing.T) {
+	calculator := NewCalculator()
+	request := func(seed int64) *CalculationRequest {
+		return &CalculationRequest{
+			Type:              ImpactTypeVideoStreaming,
+			Duration:          3600,
+			VideoQuality:      "1080p",
+			DeviceType:        "laptop",
+			Region:            "EU",
+			OptimizationLevel: 50,
+			MonteCarloSamples: 2000,
+			MonteCarloSeed:    seed,
+		}
+	}
+
+	result, err := calculator.Calculate(request(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uncertainty := result.Uncertainty
+	if uncertainty == nil || uncertainty.Samples != 2000 || uncertainty.Seed != 7 {
+		t.Fatalf("expected a sampled uncertainty result, got %+v", uncertainty)
+	}
+	for name, p := range map[string]Percentiles{"baseline": uncertainty.Baseline, "optimized": uncertainty.Optimized, "savings": uncertainty.Savings} {
+		if !(p.P5 < p.P50 && p.P50 < p.P95) || p.StdDev <= 0 {
+			t.Errorf("%s: expected ordered percentiles with spread, got %+v", name, p)
+		}
+	}
+	// The point estimate uses the central factor values, so it lies within the sampled interval
+	if result.BaselineEmissions < uncertainty.Baseline.P5 || result.BaselineEmissions > uncertainty.Baseline.P95 {
+		t.Errorf("point estimate %.3f outside sampled interval %+v", result.BaselineEmissions, uncertainty.Baseline)
+	}
+	if result.LowerBound != uncertainty.Optimized.P5 || result.UpperBound != uncertainty.Optimized.P95 || result.ConfidenceInterval == 25.0 {
+		t.Errorf("expected bounds from the sampled interval, got %.3f-%.3f (±%.1f%%)", result.LowerBound, result.UpperBound, result.ConfidenceInterval)
+	}
+	if strings.Contains(result.Methodology, "±25%") || !strings.Contains(result.Methodology, "Monte Carlo") {
+		t.Errorf("expected the methodology to describe the sampled interval only, got %q", result.Methodology)
+	}
+	if uncertainty.NetSavings != nil {
+		t.Errorf("expected no net savings percentiles without rebound effects, got %+v", uncertainty.NetSavings)
+	}
+
+	if len(uncertainty.Sensitivity) != len(factorGroups) {
+		t.Fatalf("expected every factor group ranked, got %+v", uncertainty.Sensitivity)
+	}
+	// 1080p streaming moves several GB an hour, so the wide network range dominates
+	if top := uncertainty.Sensitivity[0]; top.Input != FactorNetworkTransmission || top.Correlation <= 0 {
+		t.Errorf("unexpected top input %+v: %+v", top, uncertainty.Sensitivity)
+	}
+	var total float64
+	for i, entry := range uncertainty.Sensitivity {
+		total += entry.Contribution
+		if i > 0 && entry.Contribution > uncertainty.Sensitivity[i-1].Contribution {
+			t.Errorf("sensitivity not ranked: %+v", uncertainty.Sensitivity)
+		}
+	}
+	if abs(total-100) > 1e-6 {
+		t.Errorf("expected contributions to sum to 100%%, got %.3f", total)
+	}
+
+	again, _ := calculator.Calculate(request(7))
+	if !reflect.DeepEqual(again.Uncertainty, uncertainty) {
+		t.Error("expected the same seed to reproduce the result")
+	}
+	other, _ := calculator.Calculate(request(8))
+	if reflect.DeepEqual(other.Uncertainty.Baseline, uncertainty.Baseline) {
+		t.Error("expected a different seed to draw different samples")
+	}
+}
+
+func TestCalculator_MonteCarloReboundEffects(t *testing.T) {
+	calculator := NewCalculator()
+	request := &CalculationRequest{
+		Type:                  ImpactTypeVideoStreaming,
+		Duration:              3600,
+		VideoQuality:          "1080p",
+		DeviceType:            "laptop",
+		Region:                "EU",
+		OptimizationLevel:     50,
+		IncludeReboundEffects: true,
+		MonteCarloSamples:     1000,
+		MonteCarloSeed:        7,
+	}
+
+	result, err := calculator.Calculate(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	net := result.Uncertainty.NetSavings
+	if net == nil {
+		t.Fatal("expected net savings percentiles with rebound effects")
+	}
+	// Video streaming rebounds 30% of the savings in every sample
+	if abs(net.P50-result.Uncertainty.Savings.P50*0.7) > 1e-9 {
+		t.Errorf("expected net savings after a 30%% rebound, got p50 %.3f for savings p50 %.3f", net.P50, result.Uncertainty.Savings.P50)
+	}
+	if result.NetSavings < net.P5 || result.NetSavings > net.P95 {
+		t.Errorf("point estimate %.3f outside sampled net savings %+v", result.NetSavings, net)
+	}
+
+	request.MonteCarloSamples = 0
+	fixed, err := calculator.Calculate(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(fixed.Methodology, "Includes ±25% confidence interval.") {
+		t.Errorf("expected the fixed interval in the methodology without sampling, got %q", fixed.Methodology)
+	}
+}
+
+func TestCalculator_MonteCarloSensitivity(t *testing.T) {
+	// Only the network factor is uncertain, so it explains all of the spread
+	factors := DefaultEmissionFactors
+	factors.Uncertainty = map[string]FactorDistribution{
+		FactorNetworkTransmission: {Type: DistributionUniform, Min: 0.5, Max: 2},
+	}
+	result, err := NewCalculatorWithFactors(factors).Calculate(&CalculationRequest{
+		Type:              ImpactTypeDataTransfer,
+		DataSize:          1000,
+		ConnectionType:    "mobile_4g",
+		MonteCarloSamples: 500,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	top := result.Uncertainty.Sensitivity[0]
+	if top.Input != FactorNetworkTransmission || top.Contribution < 99 || top.Correlation <= 0 {
+		t.Errorf("expected network transmission to drive the uncertainty, got %+v", result.Uncertainty.Sensitivity)
+	}
+	if p5 := result.Uncertainty.Baseline.P5; p5 >= result.BaselineEmissions {
+		t.Errorf("expected the uniform range to reach below the point estimate, p5 %.3f", p5)
+	}
+}
+
+func TestCalculator_MonteCarloModels(t *testing.T) {
+	// SWD uses its own global grid average, which is sampled as well
+	result, err := NewCalculator().Calculate(&CalculationRequest{
+		Type:              ImpactTypePageLoad,
+		Methodology:       MethodologySWD,
+		MonteCarloSamples: 500,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Uncertainty.Baseline.StdDev <= 0 || result.Uncertainty.Sensitivity[0].Input != FactorGridCarbonIntensity {
+		t.Errorf("expected grid intensity to drive SWD uncertainty, got %+v", result.Uncertainty)
+	}
+	if !containsWarning(result.Warnings, "only grid intensity is sampled") {
+		t.Errorf("expected a warning about fixed model factors, got %v", result.Warnings)
+	}
+
+	for _, samples := range []int{-1, 10, maxMonteCarloSamples + 1} {
+		if _, err := NewCalculator().Calculate(&CalculationRequest{Type: ImpactTypePageLoad, MonteCarloSamples: samples}); err == nil {
+			t.Errorf("expected %d samples to be rejected", samples)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package latency
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// userKeyColumns are the accepted header names for the user key column
+var userKeyColumns = []string{"user_key", "user", "user_zone", "zone", "prefix"}
+
+// ParseCSV reads RTT samples from CSV with a header row. Required columns are a user key
+// (user_key, user, user_zone, zone or prefix), edge_id and rtt_ms; timestamp (RFC 3339 or
+// Unix seconds) is optional.
+func ParseCSV(r io.Reader) ([]Sample, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: empty CSV", ErrInvalidSample)
+		}
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	userColumn := -1
+	for _, name := range userKeyColumns {
+		if i, exists := columns[name]; exists {
+			userColumn = i
+			break
+		}
+	}
+	edgeColumn, hasEdge := columns["edge_id"]
+	rttColumn, hasRTT := columns["rtt_ms"]
+	if userColumn < 0 || !hasEdge || !hasRTT {
+		return nil, fmt.Errorf("%w: CSV header needs a user key column, edge_id and rtt_ms", ErrInvalidSample)
+	}
+	timestampColumn, hasTimestamp := columns["timestamp"]
+
+	var samples []Sample
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV line %d: %w", line, err)
+		}
+
+		field := func(i int) string {
+			if i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		rtt, err := strconv.ParseFloat(field(rttColumn), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: invalid rtt_ms %q", ErrInvalidSample, line, field(rttColumn))
+		}
+
+		sample := Sample{
+			UserKey: field(userColumn),
+			EdgeID:  field(edgeColumn),
+			RTTMs:   rtt,
+		}
+		if hasTimestamp && field(timestampColumn) != "" {
+			if sample.Timestamp, err = parseTimestamp(field(timestampColumn)); err != nil {
+				return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidSample, line, err)
+			}
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, nil
+}
+
+// LoadCSVFile reads RTT samples from a CSV file
+func LoadCSVFile(path string) ([]Sample, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open RTT measurements: %w", err)
+	}
+	defer file.Close()
+
+	return ParseCSV(file)
+}
+
+// parseTimestamp accepts RFC 3339 or Unix seconds
+func parseTimestamp(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	timestamp, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+	}
+	return timestamp, nil
+}
@@ -0,0 +1,309 @@
+// Package latency stores measured RTTs between user populations and edge locations and
+// serves rolling percentiles to edge selection in place of distance-based estimates.
+package latency
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+)
+
+// ErrInvalidSample is returned for measurements that cannot be stored
+var ErrInvalidSample = errors.New("invalid RTT sample")
+
+// Sample is a single RTT measurement from a user population to an edge
+type Sample struct {
+	UserKey   string    `json:"user_key"`            // Grid zone (e.g. "DE") or network prefix (e.g. "81.2.69.0/24")
+	EdgeID    string    `json:"edge_id"`             // Edge location key from the CDN catalog
+	RTTMs     float64   `json:"rtt_ms"`              // Round-trip time in milliseconds
+	Timestamp time.Time `json:"timestamp,omitempty"` // Measurement time, defaults to ingestion time
+}
+
+// Percentiles summarizes the samples in the rolling window for one user/edge pair
+type Percentiles struct {
+	UserKey string    `json:"user_key"`
+	EdgeID  string    `json:"edge_id"`
+	P50     float64   `json:"p50_ms"`
+	P95     float64   `json:"p95_ms"`
+	Min     float64   `json:"min_ms"`
+	Max     float64   `json:"max_ms"`
+	Count   int       `json:"count"`
+	Latest  time.Time `json:"latest"`
+}
+
+// MatrixConfig holds configuration for the RTT matrix
+type MatrixConfig struct {
+	WindowSize int           // Samples kept per user/edge pair
+	MaxAge     time.Duration // Samples older than this are ignored
+	MinSamples int           // Pairs with fewer samples fall back to the model
+}
+
+// series is a ring buffer of samples for one user/edge pair
+type series struct {
+	samples []Sample
+	next    int
+}
+
+// Matrix is a thread-safe rolling RTT matrix keyed by user key and edge ID.
+// It implements carbon.RTTPercentileModel, falling back to Fallback for unmeasured pairs.
+type Matrix struct {
+	config   MatrixConfig
+	fallback carbon.RTTModel
+
+	mu         sync.RWMutex
+	series     map[string]map[string]*series // user key -> edge ID -> samples
+	prefixes   map[netip.Prefix]bool         // Prefix user keys, indexed for longest-prefix matching
+	prefixBits []int                         // Distinct lengths of the prefix user keys, longest first
+}
+
+var _ carbon.RTTPercentileModel = (*Matrix)(nil)
+
+// NewMatrix creates an RTT matrix. A nil fallback uses carbon.DefaultRTTModel.
+func NewMatrix(config MatrixConfig, fallback carbon.RTTModel) *Matrix {
+	if config.WindowSize <= 0 {
+		config.WindowSize = 500
+	}
+	if config.MaxAge <= 0 {
+		config.MaxAge = 7 * 24 * time.Hour
+	}
+	if config.MinSamples <= 0 {
+		config.MinSamples = 5
+	}
+	if fallback == nil {
+		fallback = carbon.DefaultRTTModel
+	}
+
+	return &Matrix{
+		config:   config,
+		fallback: fallback,
+		series:   make(map[string]map[string]*series),
+		prefixes: make(map[netip.Prefix]bool),
+	}
+}
+
+// Add stores samples. Samples are validated first; none are stored if any is invalid.
+func (m *Matrix) Add(samples ...Sample) error {
+	now := time.Now()
+	normalized := make([]Sample, len(samples))
+	for i, sample := range samples {
+		userKey, err := NormalizeUserKey(sample.UserKey)
+		if err != nil {
+			return fmt.Errorf("sample %d: %w", i+1, err)
+		}
+		if sample.EdgeID == "" {
+			return fmt.Errorf("sample %d: %w: edge_id is required", i+1, ErrInvalidSample)
+		}
+		if sample.RTTMs <= 0 || sample.RTTMs > 60000 || math.IsNaN(sample.RTTMs) {
+			return fmt.Errorf("sample %d: %w: rtt_ms must be between 0 and 60000", i+1, ErrInvalidSample)
+		}
+		if sample.Timestamp.IsZero() {
+			sample.Timestamp = now
+		}
+		sample.UserKey = userKey
+		sample.EdgeID = strings.ToLower(strings.TrimSpace(sample.EdgeID))
+		normalized[i] = sample
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, sample := range normalized {
+		edges, exists := m.series[sample.UserKey]
+		if !exists {
+			edges = make(map[string]*series)
+			m.series[sample.UserKey] = edges
+			m.indexPrefix(sample.UserKey)
+		}
+		s, exists := edges[sample.EdgeID]
+		if !exists {
+			s = &series{}
+			edges[sample.EdgeID] = s
+		}
+
+		if len(s.samples) < m.config.WindowSize {
+			s.samples = append(s.samples, sample)
+		} else {
+			s.samples[s.next] = sample
+			s.next = (s.next + 1) % m.config.WindowSize
+		}
+	}
+
+	return nil
+}
+
+// Percentiles returns rolling percentiles for a user key and edge ID
+func (m *Matrix) Percentiles(userKey, edgeID string) (Percentiles, bool) {
+	userKey, err := NormalizeUserKey(userKey)
+	if err != nil {
+		return Percentiles{}, false
+	}
+	edgeID = strings.ToLower(strings.TrimSpace(edgeID))
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, exists := m.series[userKey][edgeID]
+	if !exists {
+		return Percentiles{}, false
+	}
+	return m.summarize(userKey, edgeID, s)
+}
+
+// All returns percentiles for every pair with samples in the window, optionally filtered by user key
+func (m *Matrix) All(userKey string) []Percentiles {
+	if userKey != "" {
+		if normalized, err := NormalizeUserKey(userKey); err == nil {
+			userKey = normalized
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var results []Percentiles
+	for key, edges := range m.series {
+		if userKey != "" && key != userKey {
+			continue
+		}
+		for edgeID, s := range edges {
+			if p, ok := m.summarize(key, edgeID, s); ok {
+				results = append(results, p)
+			}
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].UserKey != results[j].UserKey {
+			return results[i].UserKey < results[j].UserKey
+		}
+		return results[i].EdgeID < results[j].EdgeID
+	})
+	return results
+}
+
+// summarize computes percentiles over the samples within MaxAge; callers hold the read lock
+func (m *Matrix) summarize(userKey, edgeID string, s *series) (Percentiles, bool) {
+	cutoff := time.Now().Add(-m.config.MaxAge)
+
+	values := make([]float64, 0, len(s.samples))
+	var latest time.Time
+	for _, sample := range s.samples {
+		if sample.Timestamp.Before(cutoff) {
+			continue
+		}
+		values = append(values, sample.RTTMs)
+		if sample.Timestamp.After(latest) {
+			latest = sample.Timestamp
+		}
+	}
+	if len(values) == 0 {
+		return Percentiles{}, false
+	}
+
+	sort.Float64s(values)
+	return Percentiles{
+		UserKey: userKey,
+		EdgeID:  edgeID,
+		P50:     percentile(values, 50),
+		P95:     percentile(values, 95),
+		Min:     values[0],
+		Max:     values[len(values)-1],
+		Count:   len(values),
+		Latest:  latest,
+	}, true
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// EstimateRTT implements carbon.RTTModel with the measured p50
+func (m *Matrix) EstimateRTT(user carbon.EdgeSelectionUser, edgeID string, edge carbon.EdgeLocationInfo) (float64, string) {
+	p50, _, source := m.EstimateRTTPercentiles(user, edgeID, edge)
+	return p50, source
+}
+
+// EstimateRTTPercentiles implements carbon.RTTPercentileModel. The user's prefix is tried
+// first (longest stored prefix containing it), then the grid zone; pairs with fewer than
+// MinSamples fall back to the model.
+func (m *Matrix) EstimateRTTPercentiles(user carbon.EdgeSelectionUser, edgeID string, edge carbon.EdgeLocationInfo) (float64, float64, string) {
+	for _, key := range m.candidateKeys(user) {
+		if p, ok := m.Percentiles(key, edgeID); ok && p.Count >= m.config.MinSamples {
+			return p.P50, p.P95, carbon.RTTSourceMeasured
+		}
+	}
+	return carbon.EstimateRTTPercentiles(m.fallback, user, edgeID, edge)
+}
+
+// indexPrefix records a new user key for longest-prefix matching if it is a prefix;
+// callers hold the write lock
+func (m *Matrix) indexPrefix(userKey string) {
+	prefix, err := netip.ParsePrefix(userKey)
+	if err != nil {
+		return
+	}
+	m.prefixes[prefix] = true
+
+	i := sort.Search(len(m.prefixBits), func(i int) bool { return m.prefixBits[i] <= prefix.Bits() })
+	if i < len(m.prefixBits) && m.prefixBits[i] == prefix.Bits() {
+		return
+	}
+	m.prefixBits = append(m.prefixBits, 0)
+	copy(m.prefixBits[i+1:], m.prefixBits[i:])
+	m.prefixBits[i] = prefix.Bits()
+}
+
+// candidateKeys lists the stored user keys matching a user, most specific first. The
+// longest stored prefix is found by masking the user's address to each stored length.
+func (m *Matrix) candidateKeys(user carbon.EdgeSelectionUser) []string {
+	var keys []string
+
+	if user.Prefix != "" {
+		if prefix, err := netip.ParsePrefix(user.Prefix); err == nil {
+			m.mu.RLock()
+			for _, bits := range m.prefixBits {
+				if bits > prefix.Bits() {
+					continue
+				}
+				if stored, err := prefix.Addr().Prefix(bits); err == nil && m.prefixes[stored] {
+					keys = append(keys, stored.String())
+					break
+				}
+			}
+			m.mu.RUnlock()
+		}
+	}
+
+	if user.GridZone != "" {
+		keys = append(keys, user.GridZone)
+	}
+	return keys
+}
+
+// NormalizeUserKey canonicalizes a user key: prefixes are masked, zones are uppercased
+func NormalizeUserKey(key string) (string, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", fmt.Errorf("%w: user_key is required", ErrInvalidSample)
+	}
+	if strings.Contains(key, "/") {
+		prefix, err := netip.ParsePrefix(key)
+		if err != nil {
+			return "", fmt.Errorf("%w: invalid prefix %q", ErrInvalidSample, key)
+		}
+		return prefix.Masked().String(), nil
+	}
+	return strings.ToUpper(key), nil
+}
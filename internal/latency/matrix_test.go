@@ -0,0 +1,158 @@
+package latency
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+)
+
+func TestMatrixPercentiles(t *testing.T) {
+	matrix := NewMatrix(MatrixConfig{WindowSize: 100, MinSamples: 1}, nil)
+
+	var samples []Sample
+	for i := 1; i <= 20; i++ {
+		samples = append(samples, Sample{UserKey: "de", EdgeID: "Frankfurt", RTTMs: float64(i)})
+	}
+	if err := matrix.Add(samples...); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	p, ok := matrix.Percentiles("DE", "frankfurt")
+	if !ok {
+		t.Fatal("expected percentiles for DE/frankfurt")
+	}
+	if p.P50 != 10 || p.P95 != 19 || p.Min != 1 || p.Max != 20 || p.Count != 20 {
+		t.Errorf("unexpected percentiles: %+v", p)
+	}
+}
+
+func TestMatrixWindowAndMaxAge(t *testing.T) {
+	matrix := NewMatrix(MatrixConfig{WindowSize: 3, MaxAge: time.Hour, MinSamples: 1}, nil)
+
+	old := time.Now().Add(-2 * time.Hour)
+	if err := matrix.Add(Sample{UserKey: "FR", EdgeID: "paris", RTTMs: 500, Timestamp: old}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if _, ok := matrix.Percentiles("FR", "paris"); ok {
+		t.Error("expected samples older than MaxAge to be ignored")
+	}
+
+	for _, rtt := range []float64{10, 20, 30, 40} {
+		if err := matrix.Add(Sample{UserKey: "FR", EdgeID: "paris", RTTMs: rtt}); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	p, _ := matrix.Percentiles("FR", "paris")
+	if p.Count != 3 || p.Min != 20 {
+		t.Errorf("expected window of the 3 newest samples, got %+v", p)
+	}
+}
+
+func TestMatrixAddRejectsInvalidSamples(t *testing.T) {
+	matrix := NewMatrix(MatrixConfig{}, nil)
+
+	tests := []struct {
+		name   string
+		sample Sample
+	}{
+		{"missing user key", Sample{EdgeID: "paris", RTTMs: 10}},
+		{"missing edge", Sample{UserKey: "FR", RTTMs: 10}},
+		{"zero rtt", Sample{UserKey: "FR", EdgeID: "paris"}},
+		{"bad prefix", Sample{UserKey: "10.0.0.0/99", EdgeID: "paris", RTTMs: 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid := Sample{UserKey: "DE", EdgeID: "frankfurt", RTTMs: 10}
+			if err := matrix.Add(valid, tt.sample); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	if len(matrix.All("")) != 0 {
+		t.Error("expected no samples stored when a batch is rejected")
+	}
+}
+
+func TestMatrixEstimateRTTPercentiles(t *testing.T) {
+	matrix := NewMatrix(MatrixConfig{MinSamples: 2}, carbon.DistanceRTTModel{BaseMs: 10, KmPerMs: 20})
+
+	add := func(userKey string, rtts ...float64) {
+		for _, rtt := range rtts {
+			if err := matrix.Add(Sample{UserKey: userKey, EdgeID: "frankfurt", RTTMs: rtt}); err != nil {
+				t.Fatalf("Add failed: %v", err)
+			}
+		}
+	}
+	add("DE", 40, 40)
+	add("81.2.0.0/16", 25, 25)
+	add("81.2.69.0/24", 12, 12)
+	add("2001:db8::/48", 8, 8)
+	add("FR", 90) // below MinSamples
+
+	edge := carbon.EdgeLocationInfo{Latitude: 50.11, Longitude: 8.68}
+	user := carbon.EdgeSelectionUser{Latitude: 52.52, Longitude: 13.405, GridZone: "DE"}
+
+	tests := []struct {
+		name       string
+		user       carbon.EdgeSelectionUser
+		wantP50    float64
+		wantSource string
+	}{
+		{"most specific prefix", carbon.EdgeSelectionUser{GridZone: "DE", Prefix: "81.2.69.142/32"}, 12, carbon.RTTSourceMeasured},
+		{"covering prefix", carbon.EdgeSelectionUser{GridZone: "DE", Prefix: "81.2.1.0/24"}, 25, carbon.RTTSourceMeasured},
+		{"IPv6 prefix", carbon.EdgeSelectionUser{GridZone: "DE", Prefix: "2001:db8::1/128"}, 8, carbon.RTTSourceMeasured},
+		{"user prefix broader than stored", carbon.EdgeSelectionUser{GridZone: "DE", Prefix: "81.2.0.0/15"}, 40, carbon.RTTSourceMeasured},
+		{"zone", user, 40, carbon.RTTSourceMeasured},
+		{"too few samples", carbon.EdgeSelectionUser{Latitude: 52.52, Longitude: 13.405, GridZone: "FR"}, 0, carbon.RTTSourceModel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p50, p95, source := matrix.EstimateRTTPercentiles(tt.user, "frankfurt", edge)
+			if source != tt.wantSource {
+				t.Fatalf("source = %s, want %s", source, tt.wantSource)
+			}
+			if tt.wantSource == carbon.RTTSourceMeasured && p50 != tt.wantP50 {
+				t.Errorf("p50 = %v, want %v", p50, tt.wantP50)
+			}
+			if p95 < p50 {
+				t.Errorf("p95 %v below p50 %v", p95, p50)
+			}
+		})
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	input := "user_zone,edge_id,rtt_ms,timestamp\n" +
+		"DE,frankfurt,23.5,2024-01-15T10:00:00Z\n" +
+		"81.2.69.0/24,paris,31,1705312800\n" +
+		"SE,stockholm,12,\n"
+
+	samples, err := ParseCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseCSV failed: %v", err)
+	}
+	if len(samples) != 3 {
+		t.Fatalf("expected 3 samples, got %d", len(samples))
+	}
+	if samples[0].UserKey != "DE" || samples[0].RTTMs != 23.5 || samples[0].Timestamp.IsZero() {
+		t.Errorf("unexpected first sample: %+v", samples[0])
+	}
+	if !samples[1].Timestamp.Equal(time.Unix(1705312800, 0)) {
+		t.Errorf("unexpected Unix timestamp: %v", samples[1].Timestamp)
+	}
+	if !samples[2].Timestamp.IsZero() {
+		t.Errorf("expected empty timestamp, got %v", samples[2].Timestamp)
+	}
+
+	if _, err := ParseCSV(strings.NewReader("zone,rtt_ms\nDE,10\n")); err == nil {
+		t.Error("expected error for missing edge_id column")
+	}
+	if _, err := ParseCSV(strings.NewReader("zone,edge_id,rtt_ms\nDE,frankfurt,fast\n")); err == nil {
+		t.Error("expected error for non-numeric rtt_ms")
+	}
+}
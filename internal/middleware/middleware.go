@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/perschulte/greenweb-api/internal/clientip"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -41,7 +42,8 @@ type RateLimitConfig struct {
 	RedisKeyPrefix     string                  `json:"redis_key_prefix"`
 	SkipSuccessful     bool                    `json:"skip_successful"`
 	SkipClientErrors   bool                    `json:"skip_client_errors"`
-	TrustedProxies     []string                `json:"trusted_proxies"`
+	TrustedProxies     []string                `json:"trusted_proxies"` // Proxy addresses or CIDRs whose forwarding headers are honored
+	ClientIP           *clientip.Resolver      `json:"-"`               // Overrides TrustedProxies; defaults to clientip.Default()
 	KeyGenerator       func(*gin.Context) string
 	ErrorHandler       func(*gin.Context, error)
 	LimitReachedHandler func(*gin.Context, time.Duration)
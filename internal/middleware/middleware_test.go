@@ -235,6 +235,42 @@ func TestRateLimitWithRedis(t *testing.T) {
 	assert.Equal(t, 2, passes)
 }
 
+func TestRateLimitTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	config := RateLimitConfig{
+		Enabled:        true,
+		DefaultRPS:     1,
+		DefaultBurst:   1,
+		WindowSize:     time.Minute,
+		TrustedProxies: []string{"10.0.0.0/8"},
+	}
+
+	r := gin.New()
+	r.Use(NewRateLimit(config, nil))
+	r.GET("/test", func(c *gin.Context) {
+		c.JSON(200, gin.H{"ok": true})
+	})
+
+	request := func(remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// A direct client cannot escape its limit by rotating X-Forwarded-For
+	assert.Equal(t, 200, request("203.0.113.5:1234", "198.51.100.1"))
+	assert.Equal(t, 429, request("203.0.113.5:1234", "198.51.100.2"))
+
+	// Clients behind a trusted proxy are limited individually
+	assert.Equal(t, 200, request("10.0.0.1:1234", "198.51.100.3"))
+	assert.Equal(t, 200, request("10.0.0.1:1234", "198.51.100.4"))
+	assert.Equal(t, 429, request("10.0.0.1:1234", "198.51.100.4"))
+}
+
 func TestLoggingMiddleware(t *testing.T) {
 	var buf bytes.Buffer
 	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
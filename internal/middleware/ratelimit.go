@@ -3,7 +3,7 @@ package middleware
 import (
 	"context"
 	"fmt"
-	"net"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/perschulte/greenweb-api/internal/clientip"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -55,8 +56,9 @@ func NewRateLimit(config RateLimitConfig, redisClient *redis.Client) gin.Handler
 	}
 
 	// Set default key generator if not provided
+	config.ClientIP = config.clientIPResolver()
 	if config.KeyGenerator == nil {
-		config.KeyGenerator = defaultKeyGenerator
+		config.KeyGenerator = defaultKeyGenerator(config.ClientIP)
 	}
 
 	// Set default error handler if not provided
@@ -166,33 +168,34 @@ func matchesPattern(path, pattern string) bool {
 }
 
 // defaultKeyGenerator generates a rate limit key based on IP address
-func defaultKeyGenerator(c *gin.Context) string {
-	ip := getClientIP(c)
-	return fmt.Sprintf("ratelimit:%s:%s", c.Request.URL.Path, ip)
+func defaultKeyGenerator(resolver *clientip.Resolver) func(*gin.Context) string {
+	return func(c *gin.Context) string {
+		ip := resolver.ClientIP(c.Request)
+		return fmt.Sprintf("ratelimit:%s:%s", c.Request.URL.Path, ip)
+	}
 }
 
-// getClientIP extracts the client IP address from the request
-func getClientIP(c *gin.Context) string {
-	// Check X-Forwarded-For header
-	xForwardedFor := c.GetHeader("X-Forwarded-For")
-	if xForwardedFor != "" {
-		ips := strings.Split(xForwardedFor, ",")
-		return strings.TrimSpace(ips[0])
+// clientIPResolver returns the resolver for rate limit keys: ClientIP if set, otherwise one
+// trusting TrustedProxies, falling back to the shared default resolver
+func (config RateLimitConfig) clientIPResolver() *clientip.Resolver {
+	if config.ClientIP != nil {
+		return config.ClientIP
 	}
-	
-	// Check X-Real-IP header
-	xRealIP := c.GetHeader("X-Real-IP")
-	if xRealIP != "" {
-		return xRealIP
+	if len(config.TrustedProxies) == 0 {
+		return clientip.Default()
 	}
-	
-	// Fall back to RemoteAddr
-	ip, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+	resolver, err := clientip.New(clientip.Config{TrustedProxies: config.TrustedProxies})
 	if err != nil {
-		return c.Request.RemoteAddr
+		// Never fall back to trusting spoofable headers
+		slog.Warn("invalid rate limit trusted proxies, trusting none", "error", err)
+		return &clientip.Resolver{}
 	}
-	
-	return ip
+	return resolver
+}
+
+// getClientIP extracts the client IP address from the request using the shared resolver
+func getClientIP(c *gin.Context) string {
+	return clientip.FromRequest(c.Request)
 }
 
 // setRateLimitHeaders sets standard rate limit headers
@@ -373,10 +376,11 @@ func PerUserRateLimit(config RateLimitConfig, redisClient *redis.Client, userIDH
 
 // PerAPIKeyRateLimit creates a rate limiter that limits per API key
 func PerAPIKeyRateLimit(config RateLimitConfig, redisClient *redis.Client, apiKeyHeader string) gin.HandlerFunc {
+	resolver := config.clientIPResolver()
 	config.KeyGenerator = func(c *gin.Context) string {
 		apiKey := c.GetHeader(apiKeyHeader)
 		if apiKey == "" {
-			apiKey = getClientIP(c)
+			apiKey = resolver.ClientIP(c.Request)
 		}
 		return fmt.Sprintf("apikey_ratelimit:%s:%s", c.Request.URL.Path, apiKey)
 	}
@@ -0,0 +1,297 @@
+package proxy
+
+import (
+	"compress/gzip"
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/perschulte/greenweb-api/internal/geolocation"
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+)
+
+// ProfileProvider generates optimization profiles for a location
+type ProfileProvider interface {
+	GetOptimizationProfile(ctx context.Context, req optimization.OptimizationRequest) (*optimization.OptimizationResponse, error)
+}
+
+// LocationResolver resolves the visitor's location and grid zone from a request
+type LocationResolver interface {
+	GetLocationFromRequest(ctx context.Context, r *http.Request) (geolocation.LocationWithZone, error)
+}
+
+// Config holds reverse proxy configuration
+type Config struct {
+	OriginURL      string        // Origin server to proxy to
+	DefaultZone    string        // Grid zone used when the visitor's zone cannot be resolved
+	ProfileTimeout time.Duration // Timeout for profile lookups before serving the page unmodified
+	CacheSize      int           // Grid zones whose profiles are cached; defaults to 256
+	CacheTTL       time.Duration // Longest a profile is reused, even if still valid; defaults to 5 minutes
+}
+
+// Proxy is a carbon-aware reverse proxy that rewrites HTML from the origin
+type Proxy struct {
+	origin    *url.URL
+	proxy     *httputil.ReverseProxy
+	profiles  ProfileProvider
+	locations LocationResolver
+	config    Config
+	logger    *slog.Logger
+
+	cache *profileCache
+}
+
+// profileContextKey is the context key carrying the visitor's profile to ModifyResponse
+type profileContextKey struct{}
+
+// New creates a new reverse proxy in front of config.OriginURL.
+// A nil locations resolver makes every visitor use config.DefaultZone.
+func New(config Config, profiles ProfileProvider, locations LocationResolver, logger *slog.Logger) (*Proxy, error) {
+	origin, err := url.Parse(config.OriginURL)
+	if err != nil || origin.Scheme == "" || origin.Host == "" {
+		return nil, fmt.Errorf("invalid origin URL %q", config.OriginURL)
+	}
+	if profiles == nil {
+		return nil, fmt.Errorf("profile provider is required")
+	}
+	if config.DefaultZone == "" {
+		config.DefaultZone = geolocation.DefaultGridZone.Zone
+	}
+	if config.ProfileTimeout <= 0 {
+		config.ProfileTimeout = 2 * time.Second
+	}
+	if config.CacheSize <= 0 {
+		config.CacheSize = 256
+	}
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = 5 * time.Minute
+	}
+
+	p := &Proxy{
+		origin:    origin,
+		profiles:  profiles,
+		locations: locations,
+		config:    config,
+		logger:    logger,
+		cache:     newProfileCache(config.CacheSize, config.CacheTTL),
+	}
+
+	p.proxy = httputil.NewSingleHostReverseProxy(origin)
+	director := p.proxy.Director
+	p.proxy.Director = func(r *http.Request) {
+		director(r)
+		r.Host = origin.Host
+		// Only accept encodings we can decode for rewriting; without the header an
+		// origin may pick any encoding, so identity is requested explicitly
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			r.Header.Set("Accept-Encoding", "gzip, identity")
+		} else {
+			r.Header.Set("Accept-Encoding", "identity")
+		}
+	}
+	p.proxy.ModifyResponse = p.modifyResponse
+
+	return p, nil
+}
+
+// ServeHTTP resolves the visitor's profile and proxies the request to the origin
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if profile := p.profileForRequest(r); profile != nil {
+		r = r.WithContext(context.WithValue(r.Context(), profileContextKey{}, profile))
+	}
+	p.proxy.ServeHTTP(w, r)
+}
+
+// profileForRequest returns the cached or freshly generated profile for the visitor's zone
+func (p *Proxy) profileForRequest(r *http.Request) *optimization.OptimizationProfile {
+	ctx, cancel := context.WithTimeout(r.Context(), p.config.ProfileTimeout)
+	defer cancel()
+
+	zone := p.config.DefaultZone
+	if p.locations != nil {
+		if location, err := p.locations.GetLocationFromRequest(ctx, r); err == nil && location.GridZone.Zone != "" {
+			zone = location.GridZone.Zone
+		}
+	}
+
+	if profile, exists := p.cache.get(zone); exists {
+		return profile
+	}
+
+	response, err := p.profiles.GetOptimizationProfile(ctx, optimization.OptimizationRequest{Location: zone})
+	if err != nil || response.Optimization == nil {
+		p.logger.Warn("Failed to get optimization profile, serving origin unmodified", "zone", zone, "error", err)
+		return nil
+	}
+
+	p.cache.set(zone, response.Optimization)
+
+	return response.Optimization
+}
+
+// modifyResponse rewrites HTML responses according to the visitor's profile
+func (p *Proxy) modifyResponse(resp *http.Response) error {
+	profile, ok := resp.Request.Context().Value(profileContextKey{}).(*optimization.OptimizationProfile)
+	if !ok {
+		return nil
+	}
+
+	resp.Header.Set("X-GreenWeb-Mode", string(profile.Mode))
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" || resp.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	rules := RulesFromProfile(profile)
+	if rules.IsNoop() {
+		return nil
+	}
+
+	body := resp.Body
+	switch strings.ToLower(resp.Header.Get("Content-Encoding")) {
+	case "":
+	case "gzip":
+		gzipReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to decode gzip response: %w", err)
+		}
+		body = struct {
+			io.Reader
+			io.Closer
+		}{gzipReader, resp.Body}
+		resp.Header.Del("Content-Encoding")
+	default:
+		// The origin ignored Accept-Encoding; pass through untouched
+		p.logger.Warn("Skipping HTML rewrite of response with unsupported encoding",
+			"path", resp.Request.URL.Path,
+			"content_encoding", resp.Header.Get("Content-Encoding"))
+		return nil
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		defer body.Close()
+		writer.CloseWithError(Rewrite(writer, body, rules))
+	}()
+
+	resp.Body = reader
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+	resp.Header.Del("ETag")
+	// The rewrite depends on the visitor's grid zone, so shared caches must not store it
+	resp.Header.Set("Cache-Control", privateCacheControl(resp.Header.Get("Cache-Control")))
+
+	p.logger.Debug("Rewriting HTML response",
+		"path", resp.Request.URL.Path,
+		"mode", profile.Mode,
+		"strip_autoplay", rules.StripAutoplay,
+		"lazy_load", rules.LazyLoad,
+		"video_quality", rules.VideoQuality,
+		"dropped_features", len(rules.DroppedFeatures))
+
+	return nil
+}
+
+// privateCacheControl restricts a Cache-Control value to private caches, dropping the
+// directives that only apply to shared caches
+func privateCacheControl(value string) string {
+	directives := []string{"private"}
+	for _, directive := range strings.Split(value, ",") {
+		directive = strings.TrimSpace(directive)
+		name := strings.ToLower(strings.SplitN(directive, "=", 2)[0])
+		switch name {
+		case "", "public", "private", "s-maxage", "proxy-revalidate":
+			continue
+		}
+		directives = append(directives, directive)
+	}
+	return strings.Join(directives, ", ")
+}
+
+// profileCacheEntry is a cached profile for one grid zone
+type profileCacheEntry struct {
+	zone      string
+	profile   *optimization.OptimizationProfile
+	expiresAt time.Time
+}
+
+// profileCache is a bounded LRU cache of profiles by grid zone. Entries expire after the
+// cache TTL or when the profile stops being valid, whichever comes first.
+type profileCache struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // Front is most recently used
+}
+
+func newProfileCache(size int, ttl time.Duration) *profileCache {
+	return &profileCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get returns an unexpired profile and marks it recently used
+func (c *profileCache) get(zone string) (*optimization.OptimizationProfile, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, exists := c.entries[zone]
+	if !exists {
+		return nil, false
+	}
+	entry := element.Value.(*profileCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, zone)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.profile, true
+}
+
+// set stores a profile, evicting the least recently used zone when full
+func (c *profileCache) set(zone string, profile *optimization.OptimizationProfile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+	if profile.ValidUntil.Before(expiresAt) {
+		expiresAt = profile.ValidUntil
+	}
+
+	if element, exists := c.entries[zone]; exists {
+		entry := element.Value.(*profileCacheEntry)
+		entry.profile, entry.expiresAt = profile, expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[zone] = c.order.PushFront(&profileCacheEntry{zone: zone, profile: profile, expiresAt: expiresAt})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*profileCacheEntry).zone)
+	}
+}
+
+// len returns the number of cached zones, expired or not
+func (c *profileCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,168 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/perschulte/greenweb-api/internal/geolocation"
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+)
+
+// staticProfiles returns the same profile for every location
+type staticProfiles struct {
+	profile *optimization.OptimizationProfile
+}
+
+func (s staticProfiles) GetOptimizationProfile(ctx context.Context, req optimization.OptimizationRequest) (*optimization.OptimizationResponse, error) {
+	return &optimization.OptimizationResponse{Optimization: s.profile}, nil
+}
+
+func TestProxyRewrite(t *testing.T) {
+	const page = `<video src="a.mp4" autoplay></video>`
+	var acceptEncoding string
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "public, max-age=600, s-maxage=3600")
+		if r.URL.Path == "/brotli" {
+			w.Header().Set("Content-Encoding", "br")
+		}
+		io.WriteString(w, page)
+	}))
+	defer origin.Close()
+
+	profile := &optimization.OptimizationProfile{
+		Mode:            optimization.ModeEco,
+		DisableFeatures: []string{"video_autoplay"},
+		ValidUntil:      time.Now().Add(time.Hour),
+	}
+	proxy, err := New(Config{OriginURL: origin.URL}, staticProfiles{profile}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	t.Run("rewritten responses are private", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("Accept-Encoding", "br, gzip")
+		recorder := httptest.NewRecorder()
+		proxy.ServeHTTP(recorder, request)
+
+		if acceptEncoding != "gzip, identity" {
+			t.Errorf("Expected upstream Accept-Encoding %q, got %q", "gzip, identity", acceptEncoding)
+		}
+		if strings.Contains(recorder.Body.String(), "autoplay") {
+			t.Errorf("Expected autoplay to be stripped, got %q", recorder.Body.String())
+		}
+		if got := recorder.Header().Get("Cache-Control"); got != "private, max-age=600" {
+			t.Errorf("Expected Cache-Control %q, got %q", "private, max-age=600", got)
+		}
+	})
+
+	t.Run("identity is requested without gzip", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("Accept-Encoding", "br")
+		proxy.ServeHTTP(httptest.NewRecorder(), request)
+
+		if acceptEncoding != "identity" {
+			t.Errorf("Expected upstream Accept-Encoding %q, got %q", "identity", acceptEncoding)
+		}
+	})
+
+	t.Run("unsupported encodings pass through", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		proxy.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/brotli", nil))
+
+		if recorder.Body.String() != page {
+			t.Errorf("Expected the origin body unchanged, got %q", recorder.Body.String())
+		}
+		if got := recorder.Header().Get("Cache-Control"); got != "public, max-age=600, s-maxage=3600" {
+			t.Errorf("Expected the origin Cache-Control, got %q", got)
+		}
+	})
+}
+
+// countingProfiles returns a profile valid for an hour and counts the lookups
+type countingProfiles struct {
+	lookups atomic.Int64
+}
+
+func (c *countingProfiles) GetOptimizationProfile(ctx context.Context, req optimization.OptimizationRequest) (*optimization.OptimizationResponse, error) {
+	c.lookups.Add(1)
+	return &optimization.OptimizationResponse{Optimization: &optimization.OptimizationProfile{
+		Mode:       optimization.ModeNormal,
+		ValidUntil: time.Now().Add(time.Hour),
+	}}, nil
+}
+
+// headerZones resolves the visitor's grid zone from the X-Zone request header
+type headerZones struct{}
+
+func (headerZones) GetLocationFromRequest(ctx context.Context, r *http.Request) (geolocation.LocationWithZone, error) {
+	return geolocation.LocationWithZone{GridZone: geolocation.GridZone{Zone: r.Header.Get("X-Zone")}}, nil
+}
+
+func TestProxyProfileCache(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer origin.Close()
+
+	profiles := &countingProfiles{}
+	proxy, err := New(Config{OriginURL: origin.URL, CacheSize: 2, CacheTTL: 50 * time.Millisecond},
+		profiles, headerZones{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	serve := func(zone string) {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("X-Zone", zone)
+		proxy.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	serve("DE")
+	serve("DE")
+	if got := profiles.lookups.Load(); got != 1 {
+		t.Errorf("Expected the cached profile to be reused, got %d lookups", got)
+	}
+
+	serve("FR")
+	serve("SE")
+	if got := proxy.cache.len(); got != 2 {
+		t.Errorf("Expected the cache bounded at 2 zones, got %d", got)
+	}
+	serve("DE")
+	if got := profiles.lookups.Load(); got != 4 {
+		t.Errorf("Expected the least recently used zone to be evicted, got %d lookups", got)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	serve("DE")
+	if got := profiles.lookups.Load(); got != 5 {
+		t.Errorf("Expected the profile to expire after the cache TTL, got %d lookups", got)
+	}
+}
+
+func TestPrivateCacheControl(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"", "private"},
+		{"public, max-age=600", "private, max-age=600"},
+		{"max-age=60, s-maxage=600, proxy-revalidate", "private, max-age=60"},
+		{"private, no-cache", "private, no-cache"},
+		{"no-store", "private, no-store"},
+	}
+
+	for _, tt := range tests {
+		if got := privateCacheControl(tt.value); got != tt.expected {
+			t.Errorf("privateCacheControl(%q) = %q, want %q", tt.value, got, tt.expected)
+		}
+	}
+}
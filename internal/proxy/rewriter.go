@@ -0,0 +1,286 @@
+// Package proxy provides a carbon-aware reverse proxy that rewrites HTML responses
+// according to the current optimization profile for the visitor's grid zone.
+package proxy
+
+import (
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+	"golang.org/x/net/html"
+)
+
+// RewriteRules describes the HTML transformations applied to a response
+type RewriteRules struct {
+	StripAutoplay   bool                      // Remove autoplay from video and audio elements
+	LazyLoad        bool                      // Add loading="lazy" to images and iframes
+	VideoQuality    optimization.VideoQuality // Maximum quality for video sources, empty to keep sources
+	VideoPreload    string                    // preload value for video elements, empty to keep
+	DroppedFeatures []string                  // Features whose scripts are removed
+	RemoveComments  bool                      // Strip HTML comments
+}
+
+// RulesFromProfile derives rewrite rules from a profile's UI and content optimizations
+func RulesFromProfile(profile *optimization.OptimizationProfile) RewriteRules {
+	if profile == nil {
+		return RewriteRules{}
+	}
+
+	videoOpt := profile.HighImpactOptimizations.VideoStreamingOptimization
+	rules := RewriteRules{
+		StripAutoplay: profile.UIOptimizations.ReduceAnimations ||
+			profile.UIOptimizations.DisableTransitions ||
+			videoOpt.AutoplayDisabled ||
+			profile.IsFeatureDisabled("video_autoplay"),
+		LazyLoad:       profile.UIOptimizations.LazyLoadImages,
+		RemoveComments: profile.ContentOptimizations.RemoveComments || profile.ContentOptimizations.MinifyHTML,
+	}
+
+	if profile.ContentOptimizations.CompressVideos || videoOpt.Enabled {
+		rules.VideoQuality = profile.VideoQuality
+		if videoOpt.MaxQuality != "" && videoQualityRank(videoOpt.MaxQuality) < videoQualityRank(rules.VideoQuality) {
+			rules.VideoQuality = videoOpt.MaxQuality
+		}
+		rules.VideoPreload = videoOpt.PreloadStrategy
+	}
+
+	if profile.UIOptimizations.MinimizeJavaScript || profile.ContentOptimizations.MinifyJavaScript {
+		rules.DroppedFeatures = append(rules.DroppedFeatures, profile.DisableFeatures...)
+	}
+
+	return rules
+}
+
+// IsNoop returns true if the rules would not change any document
+func (r RewriteRules) IsNoop() bool {
+	return !r.StripAutoplay && !r.LazyLoad && r.VideoQuality == "" && r.VideoPreload == "" &&
+		len(r.DroppedFeatures) == 0 && !r.RemoveComments
+}
+
+// videoQualityPattern matches quality markers commonly embedded in video URLs
+var videoQualityPattern = regexp.MustCompile(`(?i)(2160p|4k|1080p|720p|480p|360p)`)
+
+// videoQualityRank orders video qualities; unknown values rank highest so they are never used as a cap
+func videoQualityRank(quality optimization.VideoQuality) int {
+	switch strings.ToLower(string(quality)) {
+	case "360p":
+		return 0
+	case "480p":
+		return 1
+	case "720p":
+		return 2
+	case "1080p":
+		return 3
+	case "4k", "2160p":
+		return 4
+	default:
+		return 5
+	}
+}
+
+// Rewrite streams HTML from r to w, applying the rules. Tokens that are not modified
+// are written byte-for-byte so inline scripts and styles are preserved exactly.
+func Rewrite(w io.Writer, r io.Reader, rules RewriteRules) error {
+	tokenizer := html.NewTokenizer(r)
+	droppingScript := false
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			if tokenizer.Err() == io.EOF {
+				return nil
+			}
+			return tokenizer.Err()
+		}
+
+		raw := tokenizer.Raw()
+
+		if droppingScript {
+			if tokenType == html.EndTagToken {
+				if name, _ := tokenizer.TagName(); string(name) == "script" {
+					droppingScript = false
+				}
+			}
+			continue
+		}
+
+		switch tokenType {
+		case html.CommentToken:
+			if rules.RemoveComments && !isConditionalComment(raw) {
+				continue
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			// Copy the raw bytes before Token() is called; they are only valid until the next call
+			raw = append([]byte(nil), raw...)
+			token := tokenizer.Token()
+			if token.Data == "script" && rules.dropsScript(token) {
+				droppingScript = tokenType == html.StartTagToken
+				continue
+			}
+			if rules.rewriteTag(&token) {
+				if _, err := io.WriteString(w, token.String()); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if _, err := w.Write(raw); err != nil {
+			return err
+		}
+	}
+}
+
+// rewriteTag applies attribute rules to a start tag and reports whether it changed
+func (r RewriteRules) rewriteTag(token *html.Token) bool {
+	changed := false
+
+	switch token.Data {
+	case "video", "audio":
+		if r.StripAutoplay && removeAttr(token, "autoplay") {
+			changed = true
+		}
+		if token.Data == "video" {
+			if r.VideoPreload != "" && setAttr(token, "preload", r.VideoPreload) {
+				changed = true
+			}
+			if r.VideoQuality != "" && r.swapVideoQuality(token, "src") {
+				changed = true
+			}
+		}
+
+	case "source":
+		if r.VideoQuality != "" && r.swapVideoQuality(token, "src") {
+			changed = true
+		}
+
+	case "img", "iframe":
+		if r.LazyLoad && !hasAttr(token, "loading") {
+			token.Attr = append(token.Attr, html.Attribute{Key: "loading", Val: "lazy"})
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// swapVideoQuality rewrites quality markers above the target quality in a URL attribute
+func (r RewriteRules) swapVideoQuality(token *html.Token, key string) bool {
+	target := videoQualityRank(r.VideoQuality)
+	changed := false
+
+	for i, attr := range token.Attr {
+		if attr.Key != key {
+			continue
+		}
+		rewritten := videoQualityPattern.ReplaceAllStringFunc(attr.Val, func(match string) string {
+			if videoQualityRank(optimization.VideoQuality(match)) > target {
+				return string(r.VideoQuality)
+			}
+			return match
+		})
+		if rewritten != attr.Val {
+			token.Attr[i].Val = rewritten
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// dropsScript returns true if a script element belongs to a disabled feature, either
+// through a data-feature attribute or a src path with a segment named after the feature
+// ("/js/live-chat.js" or "/live_chat/init.js" for live_chat, but not "/js/live-chatter.js")
+func (r RewriteRules) dropsScript(token html.Token) bool {
+	if len(r.DroppedFeatures) == 0 {
+		return false
+	}
+
+	feature := strings.ToLower(getAttr(token, "data-feature"))
+	segments := srcSegments(getAttr(token, "src"))
+
+	for _, disabled := range r.DroppedFeatures {
+		disabled = strings.ToLower(disabled)
+		hyphenated := strings.ReplaceAll(disabled, "_", "-")
+		if feature != "" && (feature == disabled || feature == hyphenated) {
+			return true
+		}
+		for _, segment := range segments {
+			if segment == disabled || segment == hyphenated {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// srcSegments returns the lower-cased path segments of a script URL, with file names cut
+// at their first dot so "live-chat.min.js" yields "live-chat"
+func srcSegments(src string) []string {
+	if src == "" {
+		return nil
+	}
+	parsed, err := url.Parse(src)
+	if err != nil {
+		return nil
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(strings.ToLower(parsed.Path), "/") {
+		if name, _, _ := strings.Cut(segment, "."); name != "" {
+			segments = append(segments, name)
+		}
+	}
+	return segments
+}
+
+// isConditionalComment reports whether a comment is an IE conditional comment, which must be kept
+func isConditionalComment(raw []byte) bool {
+	return strings.HasPrefix(string(raw), "<!--[if")
+}
+
+func hasAttr(token *html.Token, key string) bool {
+	for _, attr := range token.Attr {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func getAttr(token html.Token, key string) string {
+	for _, attr := range token.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func setAttr(token *html.Token, key, value string) bool {
+	for i, attr := range token.Attr {
+		if attr.Key == key {
+			if attr.Val == value {
+				return false
+			}
+			token.Attr[i].Val = value
+			return true
+		}
+	}
+	token.Attr = append(token.Attr, html.Attribute{Key: key, Val: value})
+	return true
+}
+
+func removeAttr(token *html.Token, key string) bool {
+	for i, attr := range token.Attr {
+		if attr.Key == key {
+			token.Attr = append(token.Attr[:i], token.Attr[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+)
+
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    RewriteRules
+		input    string
+		expected string
+	}{
+		{
+			name:     "strip autoplay",
+			rules:    RewriteRules{StripAutoplay: true},
+			input:    `<video autoplay muted src="a.mp4"></video>`,
+			expected: `<video muted="" src="a.mp4"></video>`,
+		},
+		{
+			name:     "lazy load images",
+			rules:    RewriteRules{LazyLoad: true},
+			input:    `<img src="a.jpg"><img src="b.jpg" loading="eager">`,
+			expected: `<img src="a.jpg" loading="lazy"><img src="b.jpg" loading="eager">`,
+		},
+		{
+			name:     "swap video quality",
+			rules:    RewriteRules{VideoQuality: optimization.VideoQuality480p},
+			input:    `<source src="/v/clip_1080p.mp4"><source src="/v/clip_360p.mp4">`,
+			expected: `<source src="/v/clip_480p.mp4"><source src="/v/clip_360p.mp4">`,
+		},
+		{
+			name:     "drop feature scripts",
+			rules:    RewriteRules{DroppedFeatures: []string{"live_chat"}},
+			input:    `<script src="/js/live-chat.js"></script><script data-feature="live_chat">init()</script><script>keep("<b>")</script>`,
+			expected: `<script>keep("<b>")</script>`,
+		},
+		{
+			name:     "feature scripts match whole path segments",
+			rules:    RewriteRules{DroppedFeatures: []string{"chat"}},
+			input:    `<script src="/js/chatter-lib.js"></script><script src="https://cdn.example.com/chat/widget.min.js?v=2"></script><script src="/js/chat.min.js"></script>`,
+			expected: `<script src="/js/chatter-lib.js"></script>`,
+		},
+		{
+			name:     "remove comments",
+			rules:    RewriteRules{RemoveComments: true},
+			input:    `<p>a<!-- note --></p><!--[if IE]><p>ie</p><![endif]-->`,
+			expected: `<p>a</p><!--[if IE]><p>ie</p><![endif]-->`,
+		},
+		{
+			name:     "noop leaves markup untouched",
+			rules:    RewriteRules{},
+			input:    `<VIDEO AUTOPLAY><img src=a.jpg></VIDEO>`,
+			expected: `<VIDEO AUTOPLAY><img src=a.jpg></VIDEO>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			if err := Rewrite(&out, strings.NewReader(tt.input), tt.rules); err != nil {
+				t.Fatalf("Rewrite() error = %v", err)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("Rewrite() = %q, want %q", out.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestRulesFromProfile(t *testing.T) {
+	profile := &optimization.OptimizationProfile{
+		Mode:            optimization.ModeEco,
+		DisableFeatures: []string{"video_autoplay", "live_chat"},
+		VideoQuality:    optimization.VideoQuality480p,
+		UIOptimizations: optimization.UIOptimizations{
+			LazyLoadImages:     true,
+			MinimizeJavaScript: true,
+		},
+		ContentOptimizations: optimization.ContentOptimizations{
+			CompressVideos: true,
+		},
+	}
+
+	rules := RulesFromProfile(profile)
+	if !rules.StripAutoplay {
+		t.Error("Expected autoplay to be stripped when video_autoplay is disabled")
+	}
+	if !rules.LazyLoad {
+		t.Error("Expected lazy loading from UIOptimizations.LazyLoadImages")
+	}
+	if rules.VideoQuality != optimization.VideoQuality480p {
+		t.Errorf("Expected video quality 480p, got %s", rules.VideoQuality)
+	}
+	if len(rules.DroppedFeatures) != 2 {
+		t.Errorf("Expected 2 dropped features, got %d", len(rules.DroppedFeatures))
+	}
+
+	if !RulesFromProfile(&optimization.OptimizationProfile{Mode: optimization.ModeFull}).IsNoop() {
+		t.Error("Expected empty profile to produce no-op rules")
+	}
+}
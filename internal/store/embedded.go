@@ -0,0 +1,264 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ErrClosed is returned by operations on a closed DB
+var ErrClosed = errors.New("embedded store is closed")
+
+// ErrCorrupt is returned when a record before the end of the log cannot be read. Only a
+// torn final line is discarded; anything else is left for an operator to inspect.
+var ErrCorrupt = errors.New("embedded store is corrupt")
+
+// record is one line of the append-only log; a nil Value deletes the key
+type record struct {
+	Bucket string          `json:"b"`
+	Key    string          `json:"k"`
+	Value  json.RawMessage `json:"v,omitempty"`
+}
+
+// DB is an embedded key/value store kept in a single file. Every write is appended to the
+// file and synced before it returns; the log is compacted on open and whenever obsolete
+// records outnumber live ones. A torn final line from a crash is discarded on open.
+type DB struct {
+	path    string
+	file    *os.File
+	buckets map[string]map[string]json.RawMessage
+	records int // Records in the log, live or obsolete
+	mu      sync.RWMutex
+}
+
+// compactThreshold is the minimum number of obsolete records before the log is rewritten
+const compactThreshold = 1000
+
+// OpenDB opens or creates the embedded store at path
+func OpenDB(path string) (*DB, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create store directory: %w", err)
+		}
+	}
+
+	db := &DB{path: path, buckets: make(map[string]map[string]json.RawMessage)}
+	if err := db.load(); err != nil {
+		return nil, err
+	}
+	if err := db.compact(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// load replays the log into memory
+func (db *DB) load() error {
+	file, err := os.Open(db.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	line, tornLine := 0, 0
+	for scanner.Scan() {
+		line++
+		if tornLine > 0 {
+			// Compacting would drop every record after the bad one
+			return fmt.Errorf("%w: %s line %d cannot be read", ErrCorrupt, db.path, tornLine)
+		}
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			tornLine = line // Tolerated only as a torn write at the end of the log
+			continue
+		}
+		db.apply(r)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read store: %w", err)
+	}
+	return nil
+}
+
+// apply updates the in-memory state with a record
+func (db *DB) apply(r record) {
+	db.records++
+	if r.Value == nil {
+		delete(db.buckets[r.Bucket], r.Key)
+		return
+	}
+	bucket, exists := db.buckets[r.Bucket]
+	if !exists {
+		bucket = make(map[string]json.RawMessage)
+		db.buckets[r.Bucket] = bucket
+	}
+	bucket[r.Key] = r.Value
+}
+
+// live counts the current keys across buckets
+func (db *DB) live() int {
+	count := 0
+	for _, bucket := range db.buckets {
+		count += len(bucket)
+	}
+	return count
+}
+
+// compact rewrites the log with only live records and reopens it for appending. The new
+// log is synced and renamed over the old one, so a crash leaves one of them intact.
+func (db *DB) compact() error {
+	tmpPath := db.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to compact store: %w", err)
+	}
+
+	writer := bufio.NewWriter(tmp)
+	records := 0
+	bucketNames := make([]string, 0, len(db.buckets))
+	for name := range db.buckets {
+		bucketNames = append(bucketNames, name)
+	}
+	sort.Strings(bucketNames)
+	for _, name := range bucketNames {
+		for key, value := range db.buckets[name] {
+			line, err := json.Marshal(record{Bucket: name, Key: key, Value: value})
+			if err == nil {
+				_, err = writer.Write(append(line, '\n'))
+			}
+			if err != nil {
+				tmp.Close()
+				return fmt.Errorf("failed to compact store: %w", err)
+			}
+			records++
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to compact store: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to compact store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to compact store: %w", err)
+	}
+	if err := os.Rename(tmpPath, db.path); err != nil {
+		return fmt.Errorf("failed to compact store: %w", err)
+	}
+
+	if db.file != nil {
+		db.file.Close()
+	}
+	db.file, err = os.OpenFile(db.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open store for writing: %w", err)
+	}
+	db.records = records
+	return nil
+}
+
+// write appends a record to the log, syncs it and applies it
+func (db *DB) write(r record) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.file == nil {
+		return ErrClosed
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s/%s: %w", r.Bucket, r.Key, err)
+	}
+	info, err := db.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to write %s/%s: %w", r.Bucket, r.Key, err)
+	}
+	if _, err := db.file.Write(append(line, '\n')); err != nil {
+		db.file.Truncate(info.Size()) // Drop a partial line so later records stay readable
+		return fmt.Errorf("failed to write %s/%s: %w", r.Bucket, r.Key, err)
+	}
+	if err := db.file.Sync(); err != nil {
+		db.file.Truncate(info.Size())
+		return fmt.Errorf("failed to sync %s/%s: %w", r.Bucket, r.Key, err)
+	}
+	db.apply(r)
+
+	if obsolete := db.records - db.live(); obsolete > compactThreshold && obsolete > db.live() {
+		return db.compact()
+	}
+	return nil
+}
+
+// Put stores value as JSON under bucket and key
+func (db *DB) Put(bucket, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s/%s: %w", bucket, key, err)
+	}
+	return db.write(record{Bucket: bucket, Key: key, Value: data})
+}
+
+// Delete removes a key; deleting a missing key is not an error
+func (db *DB) Delete(bucket, key string) error {
+	return db.write(record{Bucket: bucket, Key: key})
+}
+
+// Get decodes the value under bucket and key into dest, reporting whether it exists
+func (db *DB) Get(bucket, key string, dest interface{}) (bool, error) {
+	db.mu.RLock()
+	value, exists := db.buckets[bucket][key]
+	db.mu.RUnlock()
+
+	if !exists {
+		return false, nil
+	}
+	if err := json.Unmarshal(value, dest); err != nil {
+		return true, fmt.Errorf("failed to decode %s/%s: %w", bucket, key, err)
+	}
+	return true, nil
+}
+
+// ForEach calls fn with every key and raw JSON value in a bucket, in key order
+func (db *DB) ForEach(bucket string, fn func(key string, value []byte) error) error {
+	db.mu.RLock()
+	keys := make([]string, 0, len(db.buckets[bucket]))
+	values := make(map[string]json.RawMessage, len(db.buckets[bucket]))
+	for key, value := range db.buckets[bucket] {
+		keys = append(keys, key)
+		values[key] = value
+	}
+	db.mu.RUnlock()
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := fn(key, values[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close flushes and closes the store
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.file == nil {
+		return nil
+	}
+	err := db.file.Close()
+	db.file = nil
+	return err
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/perschulte/greenweb-api/internal/impact"
+)
+
+// Impact buckets
+const (
+	bucketBaselines = "impact_baselines"
+	bucketSessions  = "impact_sessions"
+	bucketReports   = "impact_reports"
+)
+
+// ImpactStorage implements impact.Storage on an embedded DB. Records are written through
+// to the file and served from an in-memory index, so aggregation matches MemoryStorage.
+type ImpactStorage struct {
+	db     *DB
+	memory *impact.MemoryStorage
+}
+
+// NewImpactStorage loads the impact records of db
+func NewImpactStorage(db *DB) (*ImpactStorage, error) {
+	storage := &ImpactStorage{db: db, memory: impact.NewMemoryStorage()}
+	ctx := context.Background()
+
+	err := db.ForEach(bucketBaselines, func(key string, value []byte) error {
+		var baseline impact.BaselineMeasurement
+		if err := json.Unmarshal(value, &baseline); err != nil {
+			return fmt.Errorf("failed to decode baseline %s: %w", key, err)
+		}
+		return storage.memory.SaveBaseline(ctx, &baseline)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.ForEach(bucketSessions, func(key string, value []byte) error {
+		var session impact.SessionMetrics
+		if err := json.Unmarshal(value, &session); err != nil {
+			return fmt.Errorf("failed to decode session %s: %w", key, err)
+		}
+		return storage.memory.SaveSession(ctx, &session)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.ForEach(bucketReports, func(key string, value []byte) error {
+		var report impact.ImpactReport
+		if err := json.Unmarshal(value, &report); err != nil {
+			return fmt.Errorf("failed to decode report %s: %w", key, err)
+		}
+		return storage.memory.SaveReport(ctx, &report)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return storage, nil
+}
+
+// SaveBaseline saves a baseline measurement
+func (s *ImpactStorage) SaveBaseline(ctx context.Context, baseline *impact.BaselineMeasurement) error {
+	if err := s.db.Put(bucketBaselines, baseline.ID, baseline); err != nil {
+		return err
+	}
+	return s.memory.SaveBaseline(ctx, baseline)
+}
+
+// GetBaseline retrieves a baseline measurement by ID
+func (s *ImpactStorage) GetBaseline(ctx context.Context, id string) (*impact.BaselineMeasurement, error) {
+	return s.memory.GetBaseline(ctx, id)
+}
+
+// ListBaselines returns the baselines of a URL measured within the period, oldest first
+func (s *ImpactStorage) ListBaselines(ctx context.Context, url string, period impact.ReportPeriod) ([]*impact.BaselineMeasurement, error) {
+	return s.memory.ListBaselines(ctx, url, period)
+}
+
+// SaveSession saves session metrics
+func (s *ImpactStorage) SaveSession(ctx context.Context, session *impact.SessionMetrics) error {
+	if err := s.db.Put(bucketSessions, session.SessionID, session); err != nil {
+		return err
+	}
+	return s.memory.SaveSession(ctx, session)
+}
+
+// GetSession retrieves session metrics by session ID
+func (s *ImpactStorage) GetSession(ctx context.Context, sessionID string) (*impact.SessionMetrics, error) {
+	return s.memory.GetSession(ctx, sessionID)
+}
+
+// SaveReport saves an impact report
+func (s *ImpactStorage) SaveReport(ctx context.Context, report *impact.ImpactReport) error {
+	if err := s.db.Put(bucketReports, report.ID, report); err != nil {
+		return err
+	}
+	return s.memory.SaveReport(ctx, report)
+}
+
+// GetReport retrieves an impact report by ID
+func (s *ImpactStorage) GetReport(ctx context.Context, id string) (*impact.ImpactReport, error) {
+	return s.memory.GetReport(ctx, id)
+}
+
+// GetAggregatedMetrics calculates aggregated metrics for a period
+func (s *ImpactStorage) GetAggregatedMetrics(ctx context.Context, period impact.ReportPeriod) (*impact.AggregatedMetrics, error) {
+	return s.memory.GetAggregatedMetrics(ctx, period)
+}
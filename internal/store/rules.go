@@ -0,0 +1,186 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+)
+
+const bucketRules = "optimization_rules"
+
+// RuleStore keeps optimization rules, persisting them to an embedded DB when one is set.
+// It provides the rule management half of optimization.OptimizationServiceWithRules.
+type RuleStore struct {
+	db    *DB
+	rules map[string]*optimization.OptimizationRule
+	mu    sync.RWMutex
+}
+
+// NewRuleStore loads the rules of db; a nil db keeps rules in memory only
+func NewRuleStore(db *DB) (*RuleStore, error) {
+	rs := &RuleStore{db: db, rules: make(map[string]*optimization.OptimizationRule)}
+	if db == nil {
+		return rs, nil
+	}
+
+	err := db.ForEach(bucketRules, func(key string, value []byte) error {
+		var rule optimization.OptimizationRule
+		if err := json.Unmarshal(value, &rule); err != nil {
+			return fmt.Errorf("failed to decode rule %s: %w", key, err)
+		}
+		rs.rules[key] = &rule
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// save persists and indexes a rule; callers hold the lock
+func (rs *RuleStore) save(rule *optimization.OptimizationRule) error {
+	if rs.db != nil {
+		if err := rs.db.Put(bucketRules, rule.ID, rule); err != nil {
+			return err
+		}
+	}
+	rs.rules[rule.ID] = rule
+	return nil
+}
+
+// CreateRule stores a new rule, assigning an ID when none is set
+func (rs *RuleStore) CreateRule(ctx context.Context, rule *optimization.OptimizationRule) (*optimization.OptimizationRule, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	created := *rule
+	if created.ID == "" {
+		created.ID = fmt.Sprintf("rule_%d", time.Now().UnixNano())
+	}
+	if _, exists := rs.rules[created.ID]; exists {
+		return nil, fmt.Errorf("%w: rule %s", ErrExists, created.ID)
+	}
+	now := time.Now().UTC()
+	created.CreatedAt = now
+	created.UpdatedAt = now
+
+	if err := rs.save(&created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UpdateRule replaces an existing rule, keeping its ID and creation time
+func (rs *RuleStore) UpdateRule(ctx context.Context, ruleID string, rule *optimization.OptimizationRule) (*optimization.OptimizationRule, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	existing, exists := rs.rules[ruleID]
+	if !exists {
+		return nil, fmt.Errorf("%w: rule %s", ErrNotFound, ruleID)
+	}
+
+	updated := *rule
+	updated.ID = ruleID
+	updated.CreatedAt = existing.CreatedAt
+	updated.UpdatedAt = time.Now().UTC()
+
+	if err := rs.save(&updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteRule removes a rule
+func (rs *RuleStore) DeleteRule(ctx context.Context, ruleID string) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if _, exists := rs.rules[ruleID]; !exists {
+		return fmt.Errorf("%w: rule %s", ErrNotFound, ruleID)
+	}
+	if rs.db != nil {
+		if err := rs.db.Delete(bucketRules, ruleID); err != nil {
+			return err
+		}
+	}
+	delete(rs.rules, ruleID)
+	return nil
+}
+
+// GetRule retrieves a rule by ID
+func (rs *RuleStore) GetRule(ctx context.Context, ruleID string) (*optimization.OptimizationRule, error) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	rule, exists := rs.rules[ruleID]
+	if !exists {
+		return nil, fmt.Errorf("%w: rule %s", ErrNotFound, ruleID)
+	}
+	copied := *rule
+	return &copied, nil
+}
+
+// ListRules returns rules carrying any of the tags, or all rules without tags, highest
+// priority first
+func (rs *RuleStore) ListRules(ctx context.Context, tags []string) ([]*optimization.OptimizationRule, error) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		wanted[tag] = true
+	}
+
+	rules := make([]*optimization.OptimizationRule, 0, len(rs.rules))
+	for _, rule := range rs.rules {
+		if len(wanted) > 0 && !hasAnyTag(rule.Tags, wanted) {
+			continue
+		}
+		copied := *rule
+		rules = append(rules, &copied)
+	}
+	sortRules(rules)
+	return rules, nil
+}
+
+// EvaluateRules returns the enabled rules whose conditions match, highest priority first
+func (rs *RuleStore) EvaluateRules(ctx context.Context, evalContext *optimization.OptimizationContext) ([]*optimization.OptimizationRule, error) {
+	rules, err := rs.ListRules(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := rules[:0]
+	for _, rule := range rules {
+		if rule.Evaluate(evalContext) {
+			matched = append(matched, rule)
+		}
+	}
+	return matched, nil
+}
+
+// hasAnyTag reports whether tags contains one of wanted
+func hasAnyTag(tags []string, wanted map[string]bool) bool {
+	for _, tag := range tags {
+		if wanted[tag] {
+			return true
+		}
+	}
+	return false
+}
+
+// sortRules orders rules by descending priority, then ID
+func sortRules(rules []*optimization.OptimizationRule) {
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
+		}
+		return rules[i].ID < rules[j].ID
+	})
+}
@@ -0,0 +1,109 @@
+// Package store selects and opens the persistence backends for impact measurements,
+// optimization rules and webhook registrations. The embedded backend keeps all state in
+// one local file, so a single node runs with durable state and no database server.
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/perschulte/greenweb-api/internal/impact"
+)
+
+var (
+	// ErrNotFound is returned for unknown rule and webhook IDs
+	ErrNotFound = errors.New("not found")
+
+	// ErrExists is returned when creating a rule whose ID is taken
+	ErrExists = errors.New("already exists")
+
+	// ErrInvalidWebhook is returned when registering a webhook with a bad URL or no events
+	ErrInvalidWebhook = errors.New("invalid webhook")
+
+	// ErrUnknownBackend is returned for an unsupported Config.Backend
+	ErrUnknownBackend = errors.New("unknown storage backend")
+)
+
+// Storage backends
+const (
+	BackendMemory   = "memory"   // State is lost on restart
+	BackendEmbedded = "embedded" // State is kept in a local file
+	BackendPostgres = "postgres" // Impact data in PostgreSQL; rules and webhooks in the local file
+)
+
+// DefaultPath is the embedded store file used when Config.Path is empty
+const DefaultPath = "./data/greenweb.db"
+
+// Config selects a storage backend
+type Config struct {
+	Backend     string // memory, embedded or postgres; empty means memory
+	Path        string // Embedded store file
+	PostgresDSN string // PostgreSQL connection string for the postgres backend
+}
+
+// Stores are the opened persistence backends. cmd/greenweb-api serves Impact through the
+// impact endpoints, applies Rules to optimization profiles via
+// service.OptimizationService.SetRuleStore and manages Rules and Webhooks through the
+// admin endpoints. Webhooks only records registrations; nothing delivers notifications yet.
+type Stores struct {
+	Impact   impact.Storage
+	Rules    *RuleStore
+	Webhooks *WebhookStore
+
+	db       *DB
+	postgres *impact.PostgreSQLStorage
+}
+
+// Open opens the backends selected by config
+func Open(config Config) (*Stores, error) {
+	path := config.Path
+	if path == "" {
+		path = DefaultPath
+	}
+
+	stores := &Stores{}
+	switch config.Backend {
+	case "", BackendMemory:
+		stores.Impact = impact.NewMemoryStorage()
+	case BackendEmbedded, BackendPostgres:
+		db, err := OpenDB(path)
+		if err != nil {
+			return nil, err
+		}
+		stores.db = db
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownBackend, config.Backend)
+	}
+
+	var err error
+	switch config.Backend {
+	case BackendEmbedded:
+		stores.Impact, err = NewImpactStorage(stores.db)
+	case BackendPostgres:
+		stores.postgres, err = impact.NewPostgreSQLStorage(config.PostgresDSN)
+		stores.Impact = stores.postgres
+	}
+	if err == nil {
+		stores.Rules, err = NewRuleStore(stores.db)
+	}
+	if err == nil {
+		stores.Webhooks, err = NewWebhookStore(stores.db)
+	}
+	if err != nil {
+		stores.Close()
+		return nil, err
+	}
+	return stores, nil
+}
+
+// Close releases the backends
+func (s *Stores) Close() error {
+	var errs []error
+	if s.postgres != nil {
+		errs = append(errs, s.postgres.Close())
+	}
+	if s.db != nil {
+		errs = append(errs, s.db.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,208 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/perschulte/greenweb-api/internal/impact"
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+)
+
+func TestEmbeddedStoresSurviveRestart(t *testing.T) {
+	ctx := context.Background()
+	config := Config{Backend: BackendEmbedded, Path: filepath.Join(t.TempDir(), "state", "greenweb.db")}
+	now := time.Now().UTC().Truncate(time.Second)
+
+	stores, err := Open(config)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err := stores.Impact.SaveBaseline(ctx, &impact.BaselineMeasurement{ID: "b1", EstimatedHourlyEmissions: 2, MeasuredAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := stores.Impact.SaveSession(ctx, &impact.SessionMetrics{SessionID: "s1", StartTime: now, TotalEmissions: 3, OptimizationsApplied: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := stores.Impact.SaveReport(ctx, &impact.ImpactReport{ID: "r1", Recommendations: []string{"Compress images"}}); err != nil {
+		t.Fatal(err)
+	}
+	rule, err := stores.Rules.CreateRule(ctx, &optimization.OptimizationRule{ID: "high_carbon_video", Name: "Video", Enabled: true, Priority: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhookID, err := stores.Webhooks.RegisterWebhook(ctx, "https://example.com/hook", "DE", []string{"green_hour_start"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stores.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := Open(config)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer reopened.Close()
+
+	if baseline, err := reopened.Impact.GetBaseline(ctx, "b1"); err != nil || !baseline.MeasuredAt.Equal(now) {
+		t.Errorf("baseline not restored: %+v, %v", baseline, err)
+	}
+	if report, err := reopened.Impact.GetReport(ctx, "r1"); err != nil || len(report.Recommendations) != 1 {
+		t.Errorf("report not restored: %+v, %v", report, err)
+	}
+	metrics, err := reopened.Impact.GetAggregatedMetrics(ctx, impact.ReportPeriod{Start: now.Add(-time.Hour), End: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics.SessionCount != 1 || metrics.TotalBaseline != 4 {
+		t.Errorf("unexpected aggregated metrics after restart: %+v", metrics)
+	}
+	if got, err := reopened.Rules.GetRule(ctx, rule.ID); err != nil || got.Priority != 10 || !got.CreatedAt.Equal(rule.CreatedAt) {
+		t.Errorf("rule not restored: %+v, %v", got, err)
+	}
+	webhooks, err := reopened.Webhooks.ListWebhooks(ctx, "DE")
+	if err != nil || len(webhooks) != 1 || webhooks[0].ID != webhookID {
+		t.Errorf("webhook not restored: %+v, %v", webhooks, err)
+	}
+}
+
+func TestEmbeddedDeletesAndCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "greenweb.db")
+	db, err := OpenDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < compactThreshold+10; i++ {
+		if err := db.Put("counters", "hits", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Put("counters", "removed", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete("counters", "removed"); err != nil {
+		t.Fatal(err)
+	}
+	if db.records > compactThreshold {
+		t.Errorf("expected the log to be compacted, %d records", db.records)
+	}
+	db.Close()
+
+	// A torn final line is discarded on open
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"b":"counters","k":"hits","v":`)
+	file.Close()
+
+	db, err = OpenDB(path)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer db.Close()
+
+	var hits int
+	if found, err := db.Get("counters", "hits", &hits); err != nil || !found || hits != compactThreshold+9 {
+		t.Errorf("expected hits %d, got %d (found %v, err %v)", compactThreshold+9, hits, found, err)
+	}
+	if found, _ := db.Get("counters", "removed", &hits); found {
+		t.Error("expected deleted key to stay deleted")
+	}
+}
+
+func TestEmbeddedRejectsCorruptionBeforeTheEnd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "greenweb.db")
+	original := "{\"b\":\"counters\",\"k\":\"a\",\"v\":1}\n{\"b\":\"coun\n{\"b\":\"counters\",\"k\":\"b\",\"v\":2}\n"
+	if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenDB(path); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+	// The log is left untouched instead of being compacted without the later records
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != original {
+		t.Errorf("expected the corrupt log to be kept as is, got %q (%v)", data, err)
+	}
+}
+
+func TestRuleStore(t *testing.T) {
+	ctx := context.Background()
+	rules, err := NewRuleStore(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	high := &optimization.OptimizationRule{
+		ID:         "high_carbon",
+		Enabled:    true,
+		Priority:   100,
+		Tags:       []string{"video"},
+		Conditions: []optimization.RuleCondition{{Type: "carbon_intensity", Operator: "gt", Value: 300.0}},
+	}
+	low := &optimization.OptimizationRule{ID: "always", Enabled: true, Priority: 1, Tags: []string{"images"}}
+	for _, rule := range []*optimization.OptimizationRule{low, high} {
+		if _, err := rules.CreateRule(ctx, rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := rules.CreateRule(ctx, low); !errors.Is(err, ErrExists) {
+		t.Errorf("expected ErrExists for a duplicate rule, got %v", err)
+	}
+
+	tagged, _ := rules.ListRules(ctx, []string{"video"})
+	if len(tagged) != 1 || tagged[0].ID != "high_carbon" {
+		t.Errorf("unexpected tag filter result: %+v", tagged)
+	}
+
+	evalContext := &optimization.OptimizationContext{CarbonIntensity: &carbon.CarbonIntensity{CarbonIntensity: 450}}
+	matched, err := rules.EvaluateRules(ctx, evalContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matched) != 2 || matched[0].ID != "high_carbon" {
+		t.Errorf("expected both rules by priority, got %+v", matched)
+	}
+
+	if _, err := rules.UpdateRule(ctx, "missing", low); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := rules.DeleteRule(ctx, "always"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rules.GetRule(ctx, "always"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected deleted rule to be gone, got %v", err)
+	}
+}
+
+func TestWebhookStoreValidation(t *testing.T) {
+	webhooks, err := NewWebhookStore(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if _, err := webhooks.RegisterWebhook(ctx, "ftp://example.com", "DE", []string{"intensity_change"}); err == nil {
+		t.Error("expected non-HTTP URL to be rejected")
+	}
+	if _, err := webhooks.RegisterWebhook(ctx, "https://example.com", "DE", nil); err == nil {
+		t.Error("expected registration without events to be rejected")
+	}
+	if err := webhooks.UnregisterWebhook(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestOpenUnknownBackend(t *testing.T) {
+	if _, err := Open(Config{Backend: "sqlite"}); !errors.Is(err, ErrUnknownBackend) {
+		t.Errorf("expected ErrUnknownBackend, got %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+)
+
+const bucketWebhooks = "webhooks"
+
+// WebhookStore keeps carbon intensity webhook registrations, persisting them to an
+// embedded DB when one is set. It implements carbon.CarbonWebhookService.
+type WebhookStore struct {
+	db       *DB
+	webhooks map[string]*carbon.WebhookRegistration
+	mu       sync.RWMutex
+}
+
+// NewWebhookStore loads the registrations of db; a nil db keeps them in memory only
+func NewWebhookStore(db *DB) (*WebhookStore, error) {
+	ws := &WebhookStore{db: db, webhooks: make(map[string]*carbon.WebhookRegistration)}
+	if db == nil {
+		return ws, nil
+	}
+
+	err := db.ForEach(bucketWebhooks, func(key string, value []byte) error {
+		var webhook carbon.WebhookRegistration
+		if err := json.Unmarshal(value, &webhook); err != nil {
+			return fmt.Errorf("failed to decode webhook %s: %w", key, err)
+		}
+		ws.webhooks[key] = &webhook
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
+
+// save persists and indexes a registration; callers hold the lock
+func (ws *WebhookStore) save(webhook *carbon.WebhookRegistration) error {
+	if ws.db != nil {
+		if err := ws.db.Put(bucketWebhooks, webhook.ID, webhook); err != nil {
+			return err
+		}
+	}
+	ws.webhooks[webhook.ID] = webhook
+	return nil
+}
+
+// RegisterWebhook registers an http(s) URL for notifications about a location
+func (ws *WebhookStore) RegisterWebhook(ctx context.Context, webhookURL, location string, events []string) (string, error) {
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("%w: URL %q must be an absolute http(s) URL", ErrInvalidWebhook, webhookURL)
+	}
+	if len(events) == 0 {
+		return "", fmt.Errorf("%w: at least one event must be specified", ErrInvalidWebhook)
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	webhook := &carbon.WebhookRegistration{
+		ID:        fmt.Sprintf("webhook_%d", time.Now().UnixNano()),
+		URL:       webhookURL,
+		Location:  location,
+		Events:    append([]string(nil), events...),
+		CreatedAt: time.Now().UTC(),
+		Active:    true,
+	}
+	if err := ws.save(webhook); err != nil {
+		return "", err
+	}
+	return webhook.ID, nil
+}
+
+// UnregisterWebhook removes a registration
+func (ws *WebhookStore) UnregisterWebhook(ctx context.Context, webhookID string) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if _, exists := ws.webhooks[webhookID]; !exists {
+		return fmt.Errorf("%w: webhook %s", ErrNotFound, webhookID)
+	}
+	if ws.db != nil {
+		if err := ws.db.Delete(bucketWebhooks, webhookID); err != nil {
+			return err
+		}
+	}
+	delete(ws.webhooks, webhookID)
+	return nil
+}
+
+// ListWebhooks returns the registrations for a location, or all of them for an empty
+// location, oldest first
+func (ws *WebhookStore) ListWebhooks(ctx context.Context, location string) ([]carbon.WebhookRegistration, error) {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	webhooks := make([]carbon.WebhookRegistration, 0, len(ws.webhooks))
+	for _, webhook := range ws.webhooks {
+		if location == "" || webhook.Location == location {
+			webhooks = append(webhooks, *webhook)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		if !webhooks[i].CreatedAt.Equal(webhooks[j].CreatedAt) {
+			return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+		}
+		return webhooks[i].ID < webhooks[j].ID
+	})
+	return webhooks, nil
+}
+
+// MarkTriggered records a delivery to a registration
+func (ws *WebhookStore) MarkTriggered(ctx context.Context, webhookID string, at time.Time) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	existing, exists := ws.webhooks[webhookID]
+	if !exists {
+		return fmt.Errorf("%w: webhook %s", ErrNotFound, webhookID)
+	}
+	updated := *existing
+	triggered := at.UTC()
+	updated.LastTriggered = &triggered
+	return ws.save(&updated)
+}
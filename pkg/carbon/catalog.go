@@ -0,0 +1,451 @@
+package carbon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CDNCatalogVersion is the catalog file schema version understood by this package.
+const CDNCatalogVersion = 1
+
+// ErrInvalidCDNCatalog is returned when a catalog file fails schema validation.
+var ErrInvalidCDNCatalog = errors.New("invalid CDN catalog")
+
+// CDNCatalogFile is the on-disk format of a CDN edge catalog, in YAML or JSON.
+//
+// Files are merged on top of the built-in MajorCDNProviders in the order given:
+// providers not yet known are added, known providers have their non-empty fields
+// overridden and their edge locations added or replaced by key. A provider with
+// Replace set discards the previous definition entirely, and an edge location
+// with Remove set deletes that edge.
+//
+// Example (YAML):
+//
+//	version: 1
+//	providers:
+//	  cloudflare:
+//	    edge_locations:
+//	      berlin:
+//	        city: Berlin
+//	        country: Germany
+//	        grid_zone: DE
+//	        latitude: 52.52
+//	        longitude: 13.405
+//	        tier: 2
+//	        capacity: medium
+//	  acme-private:
+//	    name: ACME Private PoPs
+//	    default_edge_selection: carbon_aware
+//	    carbon_aware_routing: true
+//	    edge_locations:
+//	      oslo-1:
+//	        city: Oslo
+//	        country: Norway
+//	        grid_zone: NO-NO1
+//	        latitude: 59.9139
+//	        longitude: 10.7522
+//	        tier: 1
+//	        capacity: low
+//	        renewable_commitment: true
+type CDNCatalogFile struct {
+	// Version is the schema version, currently 1
+	Version int `json:"version" example:"1"`
+
+	// Providers maps provider keys to their (partial) definitions
+	Providers map[string]CDNCatalogProvider `json:"providers"`
+}
+
+// CDNCatalogProvider is a provider entry in a catalog file.
+type CDNCatalogProvider struct {
+	// Name is the display name; required for providers not already in the catalog
+	Name string `json:"name,omitempty" example:"ACME Private PoPs"`
+
+	// DefaultEdgeSelection is the default edge selection strategy
+	DefaultEdgeSelection string `json:"default_edge_selection,omitempty" example:"geo_nearest"`
+
+	// CarbonAwareRouting overrides carbon-aware routing support when set
+	CarbonAwareRouting *bool `json:"carbon_aware_routing,omitempty" example:"true"`
+
+	// Replace discards any previous definition of this provider instead of merging
+	Replace bool `json:"replace,omitempty" example:"false"`
+
+	// EdgeLocations maps edge location keys to their definitions
+	EdgeLocations map[string]CDNCatalogEdge `json:"edge_locations,omitempty"`
+}
+
+// CDNCatalogEdge is an edge location entry in a catalog file.
+type CDNCatalogEdge struct {
+	EdgeLocationInfo
+
+	// Remove deletes this edge location from the merged catalog
+	Remove bool `json:"remove,omitempty" example:"false"`
+}
+
+// CDNCatalogStatus describes the currently loaded catalog.
+type CDNCatalogStatus struct {
+	// Sources lists the catalog files merged on top of the built-in providers
+	Sources []string `json:"sources"`
+
+	// LoadedAt is when the catalog was last (re)loaded
+	LoadedAt time.Time `json:"loaded_at"`
+
+	// Providers is the number of providers in the catalog
+	Providers int `json:"providers" example:"5"`
+
+	// EdgeLocations is the total number of edge locations across all providers
+	EdgeLocations int `json:"edge_locations" example:"80"`
+}
+
+// Validation constraints for catalog entries
+var (
+	catalogKeyPattern       = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+	validCapacities         = map[string]bool{"high": true, "medium": true, "low": true}
+	validEdgeSelectionModes = map[string]bool{"geo_nearest": true, "performance_based": true, "carbon_aware": true, "round_robin": true}
+)
+
+// CDNCatalog is a thread-safe, reloadable registry of CDN providers and their edge locations.
+type CDNCatalog struct {
+	base     map[string]CDNProvider
+	files    []string
+	mu       sync.RWMutex
+	current  map[string]CDNProvider
+	sources  []string
+	loadedAt time.Time
+}
+
+// DefaultCDNCatalog is the catalog used by GetCDNProvider and the other package-level helpers.
+// It starts out with MajorCDNProviders; call SetFiles and Reload to merge catalog files.
+var DefaultCDNCatalog = NewCDNCatalog(MajorCDNProviders)
+
+// NewCDNCatalog creates a catalog with the given built-in providers and optional catalog files.
+// The files are not read until Reload is called.
+func NewCDNCatalog(base map[string]CDNProvider, files ...string) *CDNCatalog {
+	return &CDNCatalog{
+		base:     cloneProviders(base),
+		files:    append([]string(nil), files...),
+		current:  cloneProviders(base),
+		loadedAt: time.Now(),
+	}
+}
+
+// SetFiles sets the catalog files merged on the next Reload.
+func (c *CDNCatalog) SetFiles(files ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files = append([]string(nil), files...)
+}
+
+// Reload rebuilds the catalog from the built-in providers and the configured files.
+// The previous catalog stays active if any file cannot be read or fails validation.
+func (c *CDNCatalog) Reload() (*CDNCatalogStatus, error) {
+	c.mu.RLock()
+	files := append([]string(nil), c.files...)
+	c.mu.RUnlock()
+
+	merged := cloneProviders(c.base)
+	for _, path := range files {
+		catalogFile, err := LoadCDNCatalogFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := MergeCDNCatalog(merged, catalogFile); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	c.mu.Lock()
+	c.current = merged
+	c.sources = files
+	c.loadedAt = time.Now()
+	c.mu.Unlock()
+
+	return c.Status(), nil
+}
+
+// Status returns a summary of the currently loaded catalog.
+func (c *CDNCatalog) Status() *CDNCatalogStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	status := &CDNCatalogStatus{
+		Sources:   append([]string{}, c.sources...),
+		LoadedAt:  c.loadedAt,
+		Providers: len(c.current),
+	}
+	for _, provider := range c.current {
+		status.EdgeLocations += len(provider.EdgeLocations)
+	}
+	return status
+}
+
+// Provider returns a copy of a provider's configuration.
+func (c *CDNCatalog) Provider(name string) (*CDNProvider, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	provider, exists := c.current[name]
+	if !exists {
+		return nil, false
+	}
+	copied := cloneProvider(provider)
+	return &copied, true
+}
+
+// ProviderNames returns the sorted names of all providers in the catalog.
+func (c *CDNCatalog) ProviderNames() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.current))
+	for name := range c.current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadCDNCatalogFile reads and validates a catalog file. The format is chosen by
+// extension: .yaml and .yml are parsed as YAML, anything else as JSON.
+func LoadCDNCatalogFile(path string) (*CDNCatalogFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CDN catalog: %w", err)
+	}
+
+	format := "json"
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		format = "yaml"
+	}
+
+	catalogFile, err := ParseCDNCatalog(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return catalogFile, nil
+}
+
+// ParseCDNCatalog parses and validates a catalog document in "yaml" or "json" format.
+// Unknown fields are rejected so that typos don't silently drop settings.
+func ParseCDNCatalog(data []byte, format string) (*CDNCatalogFile, error) {
+	if format == "yaml" {
+		// Decode YAML generically and re-encode as JSON so both formats share one schema
+		var document interface{}
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCDNCatalog, err)
+		}
+		converted, err := json.Marshal(document)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCDNCatalog, err)
+		}
+		data = converted
+	}
+
+	// encoding/json keeps the last of duplicate keys; an edge defined twice is a mistake
+	if path, err := duplicateKey(json.NewDecoder(bytes.NewReader(data)), ""); err == nil && path != "" {
+		return nil, fmt.Errorf("%w: %s: defined more than once", ErrInvalidCDNCatalog, path)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var catalogFile CDNCatalogFile
+	if err := decoder.Decode(&catalogFile); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCDNCatalog, err)
+	}
+
+	if err := ValidateCDNCatalog(&catalogFile); err != nil {
+		return nil, err
+	}
+	return &catalogFile, nil
+}
+
+// duplicateKey returns the path of the first object key defined twice in the JSON value
+// read from decoder, or "" when all keys are unique
+func duplicateKey(decoder *json.Decoder, path string) (string, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return "", err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return "", nil
+	}
+
+	switch delim {
+	case '{':
+		seen := make(map[string]bool)
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return "", err
+			}
+			key, _ := token.(string)
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			if seen[key] {
+				return keyPath, nil
+			}
+			seen[key] = true
+			if duplicate, err := duplicateKey(decoder, keyPath); err != nil || duplicate != "" {
+				return duplicate, err
+			}
+		}
+	case '[':
+		for i := 0; decoder.More(); i++ {
+			if duplicate, err := duplicateKey(decoder, fmt.Sprintf("%s[%d]", path, i)); err != nil || duplicate != "" {
+				return duplicate, err
+			}
+		}
+	}
+
+	// Consume the closing delimiter
+	_, err = decoder.Token()
+	return "", err
+}
+
+// ValidateCDNCatalog checks a catalog document against the schema and returns all problems found.
+func ValidateCDNCatalog(catalogFile *CDNCatalogFile) error {
+	var problems []string
+
+	if catalogFile.Version != CDNCatalogVersion {
+		problems = append(problems, fmt.Sprintf("version must be %d, got %d", CDNCatalogVersion, catalogFile.Version))
+	}
+	if len(catalogFile.Providers) == 0 {
+		problems = append(problems, "providers must not be empty")
+	}
+
+	for key, provider := range catalogFile.Providers {
+		prefix := "providers." + key
+		if !catalogKeyPattern.MatchString(key) {
+			problems = append(problems, prefix+": key must be lowercase letters, digits, '.', '_' or '-'")
+		}
+		if provider.Replace && provider.Name == "" {
+			problems = append(problems, prefix+".name: required when replace is set")
+		}
+		if provider.DefaultEdgeSelection != "" && !validEdgeSelectionModes[provider.DefaultEdgeSelection] {
+			problems = append(problems, fmt.Sprintf("%s.default_edge_selection: unknown strategy %q", prefix, provider.DefaultEdgeSelection))
+		}
+
+		for edgeKey, edge := range provider.EdgeLocations {
+			edgePrefix := prefix + ".edge_locations." + edgeKey
+			if !catalogKeyPattern.MatchString(edgeKey) {
+				problems = append(problems, edgePrefix+": key must be lowercase letters, digits, '.', '_' or '-'")
+			}
+			if edge.Remove {
+				continue
+			}
+			problems = append(problems, validateEdgeLocation(edgePrefix, edge.EdgeLocationInfo)...)
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%w: %s", ErrInvalidCDNCatalog, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateEdgeLocation checks the fields of a single edge location
+func validateEdgeLocation(prefix string, edge EdgeLocationInfo) []string {
+	var problems []string
+
+	if edge.City == "" {
+		problems = append(problems, prefix+".city: required")
+	}
+	if edge.Country == "" {
+		problems = append(problems, prefix+".country: required")
+	}
+	if edge.GridZone == "" {
+		problems = append(problems, prefix+".grid_zone: required")
+	}
+	// Omitted coordinates decode as 0, 0, which would skew every distance and RTT estimate
+	if edge.Latitude == 0 && edge.Longitude == 0 {
+		problems = append(problems, prefix+".latitude, longitude: required, 0, 0 is not an edge location")
+	}
+	if edge.Latitude < -90 || edge.Latitude > 90 {
+		problems = append(problems, fmt.Sprintf("%s.latitude: %v out of range [-90, 90]", prefix, edge.Latitude))
+	}
+	if edge.Longitude < -180 || edge.Longitude > 180 {
+		problems = append(problems, fmt.Sprintf("%s.longitude: %v out of range [-180, 180]", prefix, edge.Longitude))
+	}
+	if edge.Tier < 1 || edge.Tier > 3 {
+		problems = append(problems, fmt.Sprintf("%s.tier: must be 1, 2 or 3, got %d", prefix, edge.Tier))
+	}
+	if !validCapacities[edge.Capacity] {
+		problems = append(problems, fmt.Sprintf("%s.capacity: must be high, medium or low, got %q", prefix, edge.Capacity))
+	}
+
+	return problems
+}
+
+// MergeCDNCatalog merges a validated catalog document into providers in place.
+func MergeCDNCatalog(providers map[string]CDNProvider, catalogFile *CDNCatalogFile) error {
+	for key, entry := range catalogFile.Providers {
+		provider, exists := providers[key]
+		if !exists || entry.Replace {
+			if entry.Name == "" {
+				return fmt.Errorf("%w: providers.%s.name: required for new providers", ErrInvalidCDNCatalog, key)
+			}
+			provider = CDNProvider{
+				Name:                 entry.Name,
+				EdgeLocations:        make(map[string]EdgeLocationInfo),
+				DefaultEdgeSelection: "geo_nearest",
+			}
+		}
+
+		if entry.Name != "" {
+			provider.Name = entry.Name
+		}
+		if entry.DefaultEdgeSelection != "" {
+			provider.DefaultEdgeSelection = entry.DefaultEdgeSelection
+		}
+		if entry.CarbonAwareRouting != nil {
+			provider.CarbonAwareRouting = *entry.CarbonAwareRouting
+		}
+
+		for edgeKey, edge := range entry.EdgeLocations {
+			if edge.Remove {
+				delete(provider.EdgeLocations, edgeKey)
+				continue
+			}
+			provider.EdgeLocations[edgeKey] = edge.EdgeLocationInfo
+		}
+
+		providers[key] = provider
+	}
+
+	return nil
+}
+
+// cloneProviders deep-copies a provider map so catalogs never share edge maps
+func cloneProviders(providers map[string]CDNProvider) map[string]CDNProvider {
+	cloned := make(map[string]CDNProvider, len(providers))
+	for name, provider := range providers {
+		cloned[name] = cloneProvider(provider)
+	}
+	return cloned
+}
+
+// cloneProvider deep-copies a single provider
+func cloneProvider(provider CDNProvider) CDNProvider {
+	edges := make(map[string]EdgeLocationInfo, len(provider.EdgeLocations))
+	for key, edge := range provider.EdgeLocations {
+		edges[key] = edge
+	}
+	provider.EdgeLocations = edges
+	return provider
+}
@@ -0,0 +1,234 @@
+package carbon
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testCatalogYAML = `version: 1
+providers:
+  acme:
+    name: ACME
+    edge_locations:
+      oslo-1:
+        city: Oslo
+        country: Norway
+        grid_zone: NO-NO1
+        latitude: 59.9139
+        longitude: 10.7522
+        tier: 1
+        capacity: low
+`
+
+// testEdgeJSON is a valid edge location in JSON
+const testEdgeJSON = `{"city": "Oslo", "country": "Norway", "grid_zone": "NO-NO1", "latitude": 59.9, "longitude": 10.7, "tier": 1, "capacity": "low"}`
+
+func TestParseCDNCatalog(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		data    string
+		problem string // Expected in the error; empty for a valid catalog
+	}{
+		{"valid YAML", "yaml", testCatalogYAML, ""},
+		{"valid JSON", "json", `{"version": 1, "providers": {"acme": {"name": "ACME", "edge_locations": {"oslo-1": ` + testEdgeJSON + `}}}}`, ""},
+		{"duplicate edge ID in JSON", "json", `{"version": 1, "providers": {"acme": {"name": "ACME", "edge_locations": {"oslo-1": ` + testEdgeJSON + `, "oslo-1": ` + testEdgeJSON + `}}}}`,
+			"providers.acme.edge_locations.oslo-1: defined more than once"},
+		{"duplicate provider in JSON", "json", `{"version": 1, "providers": {"acme": {"name": "A"}, "acme": {"name": "B"}}}`,
+			"providers.acme: defined more than once"},
+		{"duplicate edge ID in YAML", "yaml", testCatalogYAML + "      oslo-1:\n        city: Oslo\n", "already defined"},
+		{"latitude out of range", "json", `{"version": 1, "providers": {"acme": {"name": "ACME", "edge_locations": {"oslo-1": ` + strings.Replace(testEdgeJSON, "59.9", "91", 1) + `}}}}`,
+			"providers.acme.edge_locations.oslo-1.latitude: 91 out of range"},
+		{"longitude out of range", "json", `{"version": 1, "providers": {"acme": {"name": "ACME", "edge_locations": {"oslo-1": ` + strings.Replace(testEdgeJSON, "10.7", "-180.5", 1) + `}}}}`,
+			"providers.acme.edge_locations.oslo-1.longitude: -180.5 out of range"},
+		{"missing coordinates", "json", `{"version": 1, "providers": {"acme": {"name": "ACME", "edge_locations": {"oslo-1": {"city": "Oslo", "country": "Norway", "grid_zone": "NO-NO1", "tier": 1, "capacity": "low"}}}}}`,
+			"providers.acme.edge_locations.oslo-1.latitude, longitude: required"},
+		{"missing fields", "json", `{"version": 1, "providers": {"acme": {"name": "ACME", "edge_locations": {"oslo-1": {"latitude": 1}}}}}`,
+			"providers.acme.edge_locations.oslo-1.city: required"},
+		{"removed edge skips validation", "json", `{"version": 1, "providers": {"acme": {"edge_locations": {"oslo-1": {"remove": true}}}}}`, ""},
+		{"unknown field", "json", `{"version": 1, "providers": {"acme": {"name": "ACME", "region": "eu"}}}`, "unknown field"},
+		{"wrong version", "json", `{"version": 2, "providers": {"acme": {"name": "ACME"}}}`, "version must be 1"},
+		{"no providers", "json", `{"version": 1}`, "providers must not be empty"},
+		{"uppercase key", "json", `{"version": 1, "providers": {"ACME": {"name": "ACME"}}}`, "providers.ACME: key must be lowercase"},
+		{"replace without name", "json", `{"version": 1, "providers": {"acme": {"replace": true}}}`, "providers.acme.name: required when replace is set"},
+		{"unknown strategy", "json", `{"version": 1, "providers": {"acme": {"name": "ACME", "default_edge_selection": "random"}}}`, "unknown strategy"},
+		{"malformed JSON", "json", `{"version": 1,`, "unexpected EOF"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalogFile, err := ParseCDNCatalog([]byte(tt.data), tt.format)
+			if tt.problem == "" {
+				if err != nil {
+					t.Fatalf("ParseCDNCatalog() error = %v", err)
+				}
+				if len(catalogFile.Providers) != 1 {
+					t.Errorf("Expected 1 provider, got %d", len(catalogFile.Providers))
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidCDNCatalog) {
+				t.Fatalf("Expected ErrInvalidCDNCatalog, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.problem) {
+				t.Errorf("Expected error to contain %q, got %v", tt.problem, err)
+			}
+		})
+	}
+}
+
+func TestValidateCDNCatalogReportsAllProblems(t *testing.T) {
+	catalogFile := &CDNCatalogFile{
+		Version: 1,
+		Providers: map[string]CDNCatalogProvider{
+			"acme": {EdgeLocations: map[string]CDNCatalogEdge{
+				"a": {EdgeLocationInfo: EdgeLocationInfo{City: "A", Country: "X", GridZone: "X", Latitude: -91, Longitude: 0, Tier: 1, Capacity: "low"}},
+				"b": {EdgeLocationInfo: EdgeLocationInfo{City: "B", Country: "X", GridZone: "X", Latitude: 0, Longitude: 0, Tier: 4, Capacity: "huge"}},
+			}},
+		},
+	}
+
+	err := ValidateCDNCatalog(catalogFile)
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	expected := []string{
+		"providers.acme.edge_locations.a.latitude: -91 out of range",
+		"providers.acme.edge_locations.b.capacity: must be high, medium or low",
+		"providers.acme.edge_locations.b.tier: must be 1, 2 or 3",
+	}
+	message := err.Error()
+	last := -1
+	for _, problem := range expected {
+		index := strings.Index(message, problem)
+		if index < 0 {
+			t.Errorf("Expected %q in %v", problem, err)
+			continue
+		}
+		if index < last {
+			t.Errorf("Expected problems sorted, got %v", err)
+		}
+		last = index
+	}
+}
+
+func TestMergeCDNCatalog(t *testing.T) {
+	oslo := EdgeLocationInfo{City: "Oslo", Country: "Norway", GridZone: "NO-NO1", Latitude: 59.9, Longitude: 10.7, Tier: 1, Capacity: "low"}
+	base := map[string]CDNProvider{
+		"acme": {
+			Name:                 "ACME",
+			DefaultEdgeSelection: "geo_nearest",
+			EdgeLocations: map[string]EdgeLocationInfo{
+				"oslo-1":   oslo,
+				"bergen-1": {City: "Bergen", Country: "Norway", GridZone: "NO-NO5", Tier: 2, Capacity: "low"},
+			},
+		},
+	}
+
+	t.Run("overrides by key", func(t *testing.T) {
+		providers := cloneProviders(base)
+		enabled := true
+		moved := oslo
+		moved.Capacity = "high"
+		err := MergeCDNCatalog(providers, &CDNCatalogFile{Version: 1, Providers: map[string]CDNCatalogProvider{
+			"acme": {
+				DefaultEdgeSelection: "carbon_aware",
+				CarbonAwareRouting:   &enabled,
+				EdgeLocations: map[string]CDNCatalogEdge{
+					"oslo-1":   {EdgeLocationInfo: moved},
+					"bergen-1": {Remove: true},
+					"tromso-1": {EdgeLocationInfo: EdgeLocationInfo{City: "Tromsø", Country: "Norway", GridZone: "NO-NO4", Tier: 3, Capacity: "low"}},
+				},
+			},
+		}})
+		if err != nil {
+			t.Fatalf("MergeCDNCatalog() error = %v", err)
+		}
+
+		acme := providers["acme"]
+		if acme.Name != "ACME" {
+			t.Errorf("Expected the name to be kept when not overridden, got %q", acme.Name)
+		}
+		if acme.DefaultEdgeSelection != "carbon_aware" || !acme.CarbonAwareRouting {
+			t.Errorf("Expected overridden strategy and routing, got %q and %v", acme.DefaultEdgeSelection, acme.CarbonAwareRouting)
+		}
+		if acme.EdgeLocations["oslo-1"].Capacity != "high" {
+			t.Errorf("Expected the catalog edge to replace the built-in one, got %+v", acme.EdgeLocations["oslo-1"])
+		}
+		if _, exists := acme.EdgeLocations["bergen-1"]; exists {
+			t.Error("Expected bergen-1 to be removed")
+		}
+		if _, exists := acme.EdgeLocations["tromso-1"]; !exists || len(acme.EdgeLocations) != 2 {
+			t.Errorf("Expected oslo-1 and tromso-1, got %v", acme.EdgeLocations)
+		}
+		if base["acme"].EdgeLocations["oslo-1"].Capacity != "low" || len(base["acme"].EdgeLocations) != 2 {
+			t.Error("Expected the base providers to be unchanged")
+		}
+	})
+
+	t.Run("replace discards previous edges", func(t *testing.T) {
+		providers := cloneProviders(base)
+		err := MergeCDNCatalog(providers, &CDNCatalogFile{Version: 1, Providers: map[string]CDNCatalogProvider{
+			"acme": {Name: "ACME v2", Replace: true, EdgeLocations: map[string]CDNCatalogEdge{"oslo-2": {EdgeLocationInfo: oslo}}},
+		}})
+		if err != nil {
+			t.Fatalf("MergeCDNCatalog() error = %v", err)
+		}
+		acme := providers["acme"]
+		if acme.Name != "ACME v2" || acme.DefaultEdgeSelection != "geo_nearest" || len(acme.EdgeLocations) != 1 {
+			t.Errorf("Expected only the replacement definition, got %+v", acme)
+		}
+	})
+
+	t.Run("new providers need a name", func(t *testing.T) {
+		providers := cloneProviders(base)
+		err := MergeCDNCatalog(providers, &CDNCatalogFile{Version: 1, Providers: map[string]CDNCatalogProvider{
+			"other": {EdgeLocations: map[string]CDNCatalogEdge{"oslo-1": {EdgeLocationInfo: oslo}}},
+		}})
+		if !errors.Is(err, ErrInvalidCDNCatalog) {
+			t.Errorf("Expected ErrInvalidCDNCatalog, got %v", err)
+		}
+	})
+}
+
+func TestCDNCatalogReload(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	first := write("first.yaml", testCatalogYAML)
+	second := write("second.json", `{"version": 1, "providers": {"acme": {"name": "ACME Override", "edge_locations": {"oslo-1": `+strings.Replace(testEdgeJSON, `"low"`, `"high"`, 1)+`}}}}`)
+	invalid := write("invalid.json", `{"version": 1, "providers": {"acme": {"name": "ACME", "edge_locations": {"oslo-1": {"city": "Oslo"}}}}}`)
+
+	catalog := NewCDNCatalog(map[string]CDNProvider{}, first, second)
+	status, err := catalog.Reload()
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if status.Providers != 1 || status.EdgeLocations != 1 || len(status.Sources) != 2 {
+		t.Errorf("Expected 1 provider with 1 edge from 2 sources, got %+v", status)
+	}
+
+	// Later files take precedence
+	acme, _ := catalog.Provider("acme")
+	if acme.Name != "ACME Override" || acme.EdgeLocations["oslo-1"].Capacity != "high" {
+		t.Errorf("Expected the second file to override the first, got %+v", acme)
+	}
+
+	// A failing reload keeps the previous catalog
+	catalog.SetFiles(first, invalid)
+	if _, err := catalog.Reload(); !errors.Is(err, ErrInvalidCDNCatalog) {
+		t.Fatalf("Expected ErrInvalidCDNCatalog, got %v", err)
+	}
+	if acme, _ := catalog.Provider("acme"); acme.Name != "ACME Override" {
+		t.Errorf("Expected the previous catalog to stay active, got %q", acme.Name)
+	}
+}
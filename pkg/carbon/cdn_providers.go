@@ -4,7 +4,8 @@
 // CloudFlare, AWS CloudFront, Google Cloud CDN, and Azure CDN.
 package carbon
 
-// MajorCDNProviders contains configuration for major CDN providers.
+// MajorCDNProviders contains the built-in configuration for major CDN providers.
+// Catalog files loaded through DefaultCDNCatalog are merged on top of it.
 var MajorCDNProviders = map[string]CDNProvider{
 	"cloudflare": {
 		Name: "CloudFlare",
@@ -370,25 +371,17 @@ var MajorCDNProviders = map[string]CDNProvider{
 
 // GetCDNProvider returns the configuration for a specific CDN provider.
 func GetCDNProvider(providerName string) (*CDNProvider, bool) {
-	provider, exists := MajorCDNProviders[providerName]
-	if !exists {
-		return nil, false
-	}
-	return &provider, true
+	return DefaultCDNCatalog.Provider(providerName)
 }
 
 // GetAllCDNProviders returns a list of all configured CDN provider names.
 func GetAllCDNProviders() []string {
-	providers := make([]string, 0, len(MajorCDNProviders))
-	for name := range MajorCDNProviders {
-		providers = append(providers, name)
-	}
-	return providers
+	return DefaultCDNCatalog.ProviderNames()
 }
 
 // FindNearestEdgeLocation finds the nearest edge location for a given provider and user location.
 func FindNearestEdgeLocation(providerName string, userLat, userLon float64) (*EdgeLocationInfo, float64) {
-	provider, exists := MajorCDNProviders[providerName]
+	provider, exists := GetCDNProvider(providerName)
 	if !exists {
 		return nil, 0
 	}
@@ -410,7 +403,7 @@ func FindNearestEdgeLocation(providerName string, userLat, userLon float64) (*Ed
 
 // GetRenewableEdgeLocations returns all edge locations with renewable energy commitments for a provider.
 func GetRenewableEdgeLocations(providerName string) []EdgeLocationInfo {
-	provider, exists := MajorCDNProviders[providerName]
+	provider, exists := GetCDNProvider(providerName)
 	if !exists {
 		return nil
 	}
@@ -0,0 +1,346 @@
+package carbon
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNoPlacement is returned when no region and start time satisfy a compute workload's constraints.
+var ErrNoPlacement = errors.New("no region and start time satisfy the workload constraints")
+
+// CloudRegionProviders maps cloud provider aliases to the catalog providers whose edge
+// locations are cloud regions.
+var CloudRegionProviders = map[string]string{
+	"aws":   "aws-cloudfront",
+	"gcp":   "google-cloud",
+	"azure": "azure",
+}
+
+// DefaultComputeRegion is the baseline region used when a workload names none.
+const DefaultComputeRegion = "aws/us-east-1"
+
+// MaxComputeHorizon bounds how far ahead a compute workload may be scheduled.
+const MaxComputeHorizon = 7 * 24 * time.Hour
+
+// euMemberStates lists EU member states by the country names used in the CDN catalog.
+var euMemberStates = map[string]bool{
+	"austria": true, "belgium": true, "bulgaria": true, "croatia": true, "cyprus": true,
+	"czech republic": true, "czechia": true, "denmark": true, "estonia": true, "finland": true,
+	"france": true, "germany": true, "greece": true, "hungary": true, "ireland": true,
+	"italy": true, "latvia": true, "lithuania": true, "luxembourg": true, "malta": true,
+	"netherlands": true, "poland": true, "portugal": true, "romania": true, "slovakia": true,
+	"slovenia": true, "spain": true, "sweden": true,
+}
+
+// eeaNonEUStates lists EEA members outside the EU.
+var eeaNonEUStates = map[string]bool{
+	"iceland": true, "liechtenstein": true, "norway": true,
+}
+
+// ComputeWorkload describes a deferrable batch compute job.
+type ComputeWorkload struct {
+	// EnergyKWh is the estimated energy use of the job in kWh
+	EnergyKWh float64 `json:"energy_kwh" example:"120"`
+
+	// DurationHours is how long the job runs
+	DurationHours float64 `json:"duration_hours" example:"4"`
+
+	// Deadline is when the job must have finished
+	Deadline time.Time `json:"deadline" example:"2024-01-17T06:00:00Z"`
+
+	// EarliestStart is the earliest the job may start (default: now)
+	EarliestStart time.Time `json:"earliest_start,omitempty" example:"2024-01-16T00:00:00Z"`
+
+	// Residency restricts regions to jurisdictions: "EU", "EEA", country names or grid zone prefixes
+	Residency []string `json:"residency,omitempty" example:"EU"`
+
+	// Providers restricts regions to cloud providers ("aws", "gcp", "azure"); empty allows all
+	Providers []string `json:"providers,omitempty" example:"aws,gcp"`
+
+	// DefaultRegion is the baseline "provider/region" the job would otherwise run in now
+	DefaultRegion string `json:"default_region,omitempty" example:"aws/eu-central-1"`
+}
+
+// ComputePlacement is a region and start time for a workload with its expected emissions.
+type ComputePlacement struct {
+	// Provider is the cloud provider alias
+	Provider string `json:"provider" example:"gcp"`
+
+	// Region is the provider's region ID
+	Region string `json:"region" example:"europe-north1"`
+
+	// City of the region
+	City string `json:"city" example:"Finland"`
+
+	// Country of the region
+	Country string `json:"country" example:"Finland"`
+
+	// GridZone of the region
+	GridZone string `json:"grid_zone" example:"FI"`
+
+	// Start is when the job starts
+	Start time.Time `json:"start" example:"2024-01-16T02:00:00Z"`
+
+	// End is when the job finishes
+	End time.Time `json:"end" example:"2024-01-16T06:00:00Z"`
+
+	// AverageIntensity is the expected mean grid intensity during the run in g CO2/kWh
+	AverageIntensity float64 `json:"average_intensity" example:"62.4"`
+
+	// EmissionsGrams is the expected emissions of the run in g CO2
+	EmissionsGrams float64 `json:"emissions_grams" example:"7488"`
+
+	// EstimatedHours is how much of the run has no forecast and is priced at an estimate
+	// such as the zone's current intensity
+	EstimatedHours float64 `json:"estimated_hours" example:"1.5"`
+}
+
+// ComputePlacementResult is the recommended placement for a workload compared to the baseline.
+type ComputePlacementResult struct {
+	// Best is the placement with the lowest expected emissions
+	Best ComputePlacement `json:"best"`
+
+	// Baseline runs the job in the default region now
+	Baseline ComputePlacement `json:"baseline"`
+
+	// SavingsGrams is the emissions avoided by Best compared to Baseline
+	SavingsGrams float64 `json:"savings_grams" example:"41212"`
+
+	// SavingsPercent is SavingsGrams relative to Baseline
+	SavingsPercent float64 `json:"savings_percent" example:"84.6"`
+
+	// Alternatives lists the best start time in each other eligible region, lowest emissions first
+	Alternatives []ComputePlacement `json:"alternatives,omitempty"`
+
+	// RegionsEvaluated is the number of regions meeting the residency and provider constraints
+	RegionsEvaluated int `json:"regions_evaluated" example:"9"`
+}
+
+// CloudRegion is a region of a cloud provider.
+type CloudRegion struct {
+	Provider string
+	Region   string
+	Location EdgeLocationInfo
+}
+
+// ListCloudRegions returns the regions of the given cloud provider aliases (all when empty)
+// that satisfy the residency constraints, sorted by provider and region.
+func ListCloudRegions(providers, residency []string) ([]CloudRegion, error) {
+	if len(providers) == 0 {
+		for alias := range CloudRegionProviders {
+			providers = append(providers, alias)
+		}
+	}
+
+	var regions []CloudRegion
+	for _, alias := range providers {
+		alias = strings.ToLower(strings.TrimSpace(alias))
+		catalogName, exists := CloudRegionProviders[alias]
+		if !exists {
+			return nil, fmt.Errorf("unsupported cloud provider %q", alias)
+		}
+		provider, exists := GetCDNProvider(catalogName)
+		if !exists {
+			continue
+		}
+		for regionID, location := range provider.EdgeLocations {
+			if MatchesResidency(location, residency) {
+				regions = append(regions, CloudRegion{Provider: alias, Region: regionID, Location: location})
+			}
+		}
+	}
+
+	sort.Slice(regions, func(i, j int) bool {
+		if regions[i].Provider != regions[j].Provider {
+			return regions[i].Provider < regions[j].Provider
+		}
+		return regions[i].Region < regions[j].Region
+	})
+	return regions, nil
+}
+
+// FindCloudRegion looks up a "provider/region" reference such as "aws/eu-central-1".
+func FindCloudRegion(ref string) (CloudRegion, error) {
+	alias, regionID, found := strings.Cut(strings.ToLower(strings.TrimSpace(ref)), "/")
+	if !found {
+		return CloudRegion{}, fmt.Errorf("region %q must be provider/region", ref)
+	}
+	catalogName, exists := CloudRegionProviders[alias]
+	if !exists {
+		return CloudRegion{}, fmt.Errorf("unsupported cloud provider %q", alias)
+	}
+	provider, exists := GetCDNProvider(catalogName)
+	if !exists {
+		return CloudRegion{}, fmt.Errorf("cloud provider %q not in catalog", alias)
+	}
+	location, exists := provider.EdgeLocations[regionID]
+	if !exists {
+		return CloudRegion{}, fmt.Errorf("region %q not found for %s", regionID, alias)
+	}
+	return CloudRegion{Provider: alias, Region: regionID, Location: location}, nil
+}
+
+// MatchesResidency reports whether a location satisfies any of the residency constraints.
+// No constraints match everything.
+func MatchesResidency(location EdgeLocationInfo, residency []string) bool {
+	if len(residency) == 0 {
+		return true
+	}
+
+	country := strings.ToLower(location.Country)
+	zone := strings.ToUpper(location.GridZone)
+	for _, constraint := range residency {
+		constraint = strings.TrimSpace(constraint)
+		switch strings.ToUpper(constraint) {
+		case "EU":
+			if euMemberStates[country] {
+				return true
+			}
+		case "EEA":
+			if euMemberStates[country] || eeaNonEUStates[country] {
+				return true
+			}
+		default:
+			upper := strings.ToUpper(constraint)
+			if strings.EqualFold(constraint, location.Country) || zone == upper || strings.HasPrefix(zone, upper+"-") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// EstimateRunEmissions returns the mean intensity and emissions of running a job of energyKWh
+// spread evenly over [start, start+duration), and how many of the run's hours have no forecast.
+func EstimateRunEmissions(gridZone string, start time.Time, duration time.Duration, energyKWh float64,
+	intensityAt HourlyIntensity) (float64, float64, float64) {
+
+	if duration <= 0 {
+		intensity, _ := intensityAt(gridZone, start.Truncate(time.Hour))
+		return intensity, intensity * energyKWh, 0
+	}
+
+	end := start.Add(duration)
+	var weighted, estimated float64
+	for slot := start; slot.Before(end); {
+		next := slot.Truncate(time.Hour).Add(time.Hour)
+		if next.After(end) {
+			next = end
+		}
+		intensity, forecast := intensityAt(gridZone, slot.Truncate(time.Hour))
+		weighted += intensity * next.Sub(slot).Hours()
+		if !forecast {
+			estimated += next.Sub(slot).Hours()
+		}
+		slot = next
+	}
+
+	average := weighted / duration.Hours()
+	return average, average * energyKWh, estimated
+}
+
+// RecommendComputePlacement evaluates every eligible region at every hourly start time that
+// finishes by the deadline and returns the placement with the lowest expected emissions,
+// compared to running in the default region at the earliest start.
+func RecommendComputePlacement(workload ComputeWorkload, now time.Time, intensityAt HourlyIntensity) (*ComputePlacementResult, error) {
+
+	if workload.EnergyKWh <= 0 {
+		return nil, fmt.Errorf("energy_kwh must be positive")
+	}
+	if workload.DurationHours < 0 {
+		return nil, fmt.Errorf("duration_hours must not be negative")
+	}
+	if workload.DefaultRegion == "" {
+		workload.DefaultRegion = DefaultComputeRegion
+	}
+
+	earliest := workload.EarliestStart
+	if earliest.Before(now) {
+		earliest = now
+	}
+	duration := time.Duration(workload.DurationHours * float64(time.Hour))
+	latestStart := workload.Deadline.Add(-duration)
+	if latestStart.Before(earliest) {
+		return nil, fmt.Errorf("%w: the job cannot finish before the deadline", ErrNoPlacement)
+	}
+	if latestStart.Sub(now) > MaxComputeHorizon {
+		latestStart = now.Add(MaxComputeHorizon)
+	}
+
+	baselineRegion, err := FindCloudRegion(workload.DefaultRegion)
+	if err != nil {
+		return nil, err
+	}
+	regions, err := ListCloudRegions(workload.Providers, workload.Residency)
+	if err != nil {
+		return nil, err
+	}
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("%w: no region meets the residency and provider constraints", ErrNoPlacement)
+	}
+
+	// Start at the earliest time, then at each following full hour
+	starts := []time.Time{earliest}
+	for start := earliest.Truncate(time.Hour).Add(time.Hour); !start.After(latestStart); start = start.Add(time.Hour) {
+		starts = append(starts, start)
+	}
+
+	place := func(region CloudRegion, start time.Time) ComputePlacement {
+		average, emissions, estimated := EstimateRunEmissions(region.Location.GridZone, start, duration, workload.EnergyKWh, intensityAt)
+		return ComputePlacement{
+			Provider:         region.Provider,
+			Region:           region.Region,
+			City:             region.Location.City,
+			Country:          region.Location.Country,
+			GridZone:         region.Location.GridZone,
+			Start:            start,
+			End:              start.Add(duration),
+			AverageIntensity: math.Round(average*10) / 10,
+			EmissionsGrams:   math.Round(emissions*10) / 10,
+			EstimatedHours:   math.Round(estimated*10) / 10,
+		}
+	}
+
+	result := &ComputePlacementResult{
+		Baseline:         place(baselineRegion, earliest),
+		RegionsEvaluated: len(regions),
+	}
+
+	// Best start time per region; ties go to the earlier start
+	bestPerRegion := make([]ComputePlacement, 0, len(regions))
+	for _, region := range regions {
+		best := place(region, starts[0])
+		for _, start := range starts[1:] {
+			if candidate := place(region, start); candidate.EmissionsGrams < best.EmissionsGrams {
+				best = candidate
+			}
+		}
+		bestPerRegion = append(bestPerRegion, best)
+	}
+	sort.SliceStable(bestPerRegion, func(i, j int) bool {
+		if bestPerRegion[i].EmissionsGrams != bestPerRegion[j].EmissionsGrams {
+			return bestPerRegion[i].EmissionsGrams < bestPerRegion[j].EmissionsGrams
+		}
+		return bestPerRegion[i].Start.Before(bestPerRegion[j].Start)
+	})
+
+	result.Best = bestPerRegion[0]
+	if len(bestPerRegion) > 1 {
+		alternatives := bestPerRegion[1:]
+		if len(alternatives) > 10 {
+			alternatives = alternatives[:10]
+		}
+		result.Alternatives = alternatives
+	}
+
+	result.SavingsGrams = math.Round((result.Baseline.EmissionsGrams-result.Best.EmissionsGrams)*10) / 10
+	if result.Baseline.EmissionsGrams > 0 {
+		result.SavingsPercent = math.Round(result.SavingsGrams/result.Baseline.EmissionsGrams*1000) / 10
+	}
+
+	return result, nil
+}
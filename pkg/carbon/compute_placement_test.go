@@ -0,0 +1,228 @@
+package carbon
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMatchesResidency(t *testing.T) {
+	germany := EdgeLocationInfo{Country: "Germany", GridZone: "DE"}
+	norway := EdgeLocationInfo{Country: "Norway", GridZone: "NO"}
+	switzerland := EdgeLocationInfo{Country: "Switzerland", GridZone: "CH"}
+	california := EdgeLocationInfo{Country: "USA", GridZone: "US-CAL-CISO"}
+
+	tests := []struct {
+		name      string
+		location  EdgeLocationInfo
+		residency []string
+		expected  bool
+	}{
+		{"no constraints", california, nil, true},
+		{"EU member", germany, []string{"EU"}, true},
+		{"EEA outside the EU", norway, []string{"EU"}, false},
+		{"EEA member", norway, []string{"eea"}, true},
+		{"outside the EEA", switzerland, []string{"EEA"}, false},
+		{"country name", germany, []string{"germany"}, true},
+		{"grid zone", switzerland, []string{"CH"}, true},
+		{"grid zone prefix", california, []string{"US"}, true},
+		{"longer grid zone prefix", california, []string{"US-CAL"}, true},
+		{"partial zone segment", california, []string{"US-CA"}, false},
+		{"any constraint", switzerland, []string{"EU", "CH"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchesResidency(tt.location, tt.residency); got != tt.expected {
+				t.Errorf("MatchesResidency(%s, %v) = %v, want %v", tt.location.GridZone, tt.residency, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestListCloudRegions(t *testing.T) {
+	regions, err := ListCloudRegions([]string{"aws"}, []string{"EU"})
+	if err != nil {
+		t.Fatalf("ListCloudRegions() error = %v", err)
+	}
+	var ids []string
+	for _, region := range regions {
+		if region.Provider != "aws" {
+			t.Errorf("Expected only aws regions, got %s/%s", region.Provider, region.Region)
+		}
+		ids = append(ids, region.Region)
+	}
+	expected := []string{"eu-central-1", "eu-north-1", "eu-west-1", "eu-west-3"}
+	if len(ids) != len(expected) {
+		t.Fatalf("Expected EU regions %v, got %v", expected, ids)
+	}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Errorf("Expected EU regions %v, got %v", expected, ids)
+			break
+		}
+	}
+
+	if _, err := ListCloudRegions([]string{"oracle"}, nil); err == nil {
+		t.Error("Expected an error for an unsupported cloud provider")
+	}
+}
+
+func TestEstimateRunEmissions(t *testing.T) {
+	base := time.Date(2024, 1, 16, 10, 0, 0, 0, time.UTC)
+	intensityAt := func(gridZone string, hour time.Time) (float64, bool) {
+		switch hour {
+		case base:
+			return 100, true
+		case base.Add(time.Hour):
+			return 200, true
+		}
+		return 400, false
+	}
+
+	// 10:30-12:30 spends half an hour at 100, an hour at 200 and half an hour at 400
+	average, emissions, estimated := EstimateRunEmissions("DE", base.Add(30*time.Minute), 2*time.Hour, 10, intensityAt)
+	if average != 225 {
+		t.Errorf("Expected time-weighted average 225, got %v", average)
+	}
+	if emissions != 2250 {
+		t.Errorf("Expected emissions 2250, got %v", emissions)
+	}
+	if estimated != 0.5 {
+		t.Errorf("Expected 0.5 estimated hours, got %v", estimated)
+	}
+
+	// A zero-length job uses the intensity of the hour it starts in
+	average, emissions, estimated = EstimateRunEmissions("DE", base.Add(75*time.Minute), 0, 10, intensityAt)
+	if average != 200 || emissions != 2000 || estimated != 0 {
+		t.Errorf("Expected 200 g/kWh and 2000 g for an instant job, got %v and %v (%v estimated)", average, emissions, estimated)
+	}
+}
+
+func TestRecommendComputePlacement(t *testing.T) {
+	now := time.Date(2024, 1, 16, 0, 15, 0, 0, time.UTC)
+	greenHour := time.Date(2024, 1, 16, 3, 0, 0, 0, time.UTC)
+
+	// Sweden is green at 03:00 only; every other zone and hour is 300, Germany 400
+	intensityAt := func(gridZone string, hour time.Time) (float64, bool) {
+		switch {
+		case gridZone == "SE" && hour.Equal(greenHour):
+			return 20, true
+		case gridZone == "DE":
+			return 400, false
+		}
+		return 300, false
+	}
+
+	t.Run("picks the greenest region and start", func(t *testing.T) {
+		workload := ComputeWorkload{
+			EnergyKWh:     100,
+			DurationHours: 1,
+			Deadline:      now.Add(12 * time.Hour),
+			Residency:     []string{"EU"},
+			Providers:     []string{"aws"},
+			DefaultRegion: "aws/eu-central-1",
+		}
+		result, err := RecommendComputePlacement(workload, now, intensityAt)
+		if err != nil {
+			t.Fatalf("RecommendComputePlacement() error = %v", err)
+		}
+
+		if result.Best.Region != "eu-north-1" || !result.Best.Start.Equal(greenHour) {
+			t.Errorf("Expected eu-north-1 at %s, got %s at %s", greenHour, result.Best.Region, result.Best.Start)
+		}
+		if result.Best.EmissionsGrams != 2000 || result.Best.EstimatedHours != 0 {
+			t.Errorf("Expected 2000 g fully forecast, got %v g with %v estimated hours", result.Best.EmissionsGrams, result.Best.EstimatedHours)
+		}
+		if result.Baseline.Region != "eu-central-1" || !result.Baseline.Start.Equal(now) || result.Baseline.EmissionsGrams != 40000 {
+			t.Errorf("Expected the baseline in eu-central-1 now at 40000 g, got %+v", result.Baseline)
+		}
+		if result.Baseline.EstimatedHours != 1 {
+			t.Errorf("Expected the baseline's hour to be estimated, got %v", result.Baseline.EstimatedHours)
+		}
+		if result.SavingsGrams != 38000 || result.SavingsPercent != 95 {
+			t.Errorf("Expected 38000 g (95%%) savings, got %v g (%v%%)", result.SavingsGrams, result.SavingsPercent)
+		}
+		if result.RegionsEvaluated != 4 || len(result.Alternatives) != 3 {
+			t.Errorf("Expected 4 regions with 3 alternatives, got %d and %d", result.RegionsEvaluated, len(result.Alternatives))
+		}
+	})
+
+	t.Run("clamps the search to the horizon", func(t *testing.T) {
+		var latest time.Time
+		recording := func(gridZone string, hour time.Time) (float64, bool) {
+			if hour.After(latest) {
+				latest = hour
+			}
+			return 300, false
+		}
+		workload := ComputeWorkload{
+			EnergyKWh:     1,
+			DurationHours: 1,
+			Deadline:      now.Add(30 * 24 * time.Hour),
+			Providers:     []string{"aws"},
+		}
+		if _, err := RecommendComputePlacement(workload, now, recording); err != nil {
+			t.Fatalf("RecommendComputePlacement() error = %v", err)
+		}
+		if limit := now.Add(MaxComputeHorizon); latest.After(limit) {
+			t.Errorf("Expected no hour after the %s horizon, got %s", limit, latest)
+		}
+	})
+
+	t.Run("constraints", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			workload ComputeWorkload
+			noPlace  bool
+		}{
+			{"cannot finish before the deadline", ComputeWorkload{EnergyKWh: 1, DurationHours: 4, Deadline: now.Add(3 * time.Hour)}, true},
+			{"earliest start after the deadline", ComputeWorkload{EnergyKWh: 1, Deadline: now.Add(time.Hour), EarliestStart: now.Add(2 * time.Hour)}, true},
+			{"no region in the jurisdiction", ComputeWorkload{EnergyKWh: 1, Deadline: now.Add(time.Hour), Residency: []string{"Antarctica"}}, true},
+			{"no energy", ComputeWorkload{Deadline: now.Add(time.Hour)}, false},
+			{"unknown default region", ComputeWorkload{EnergyKWh: 1, Deadline: now.Add(time.Hour), DefaultRegion: "aws/mars-1"}, false},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := RecommendComputePlacement(tt.workload, now, intensityAt)
+				if err == nil {
+					t.Fatal("Expected an error")
+				}
+				if got := errors.Is(err, ErrNoPlacement); got != tt.noPlace {
+					t.Errorf("errors.Is(%v, ErrNoPlacement) = %v, want %v", err, got, tt.noPlace)
+				}
+			})
+		}
+	})
+}
+
+func TestForecastIntensityAt(t *testing.T) {
+	start := time.Date(2024, 1, 16, 2, 0, 0, 0, time.UTC)
+	forecast := &GreenHoursForecast{GreenHours: []GreenHour{
+		{Start: start, End: start.Add(2 * time.Hour), CarbonIntensity: 80},
+	}}
+
+	tests := []struct {
+		name      string
+		forecast  *GreenHoursForecast
+		at        time.Time
+		intensity float64
+		covered   bool
+	}{
+		{"inside a window", forecast, start.Add(90 * time.Minute), 80, true},
+		{"at the window end", forecast, start.Add(2 * time.Hour), 250, false},
+		{"before the window", forecast, start.Add(-time.Minute), 250, false},
+		{"no forecast", nil, start, 250, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			intensity, covered := ForecastIntensityAt(tt.forecast, tt.at, 250)
+			if math.Abs(intensity-tt.intensity) > 1e-9 || covered != tt.covered {
+				t.Errorf("ForecastIntensityAt() = %v, %v; want %v, %v", intensity, covered, tt.intensity, tt.covered)
+			}
+		})
+	}
+}
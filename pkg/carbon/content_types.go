@@ -0,0 +1,167 @@
+package carbon
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidContentType is returned when registering a content type with an invalid name or weights.
+var ErrInvalidContentType = errors.New("invalid content type")
+
+// contentTypeNamePattern restricts content type and tenant names
+var contentTypeNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// ContentTypeRegistry resolves content type names to weights. Built-in types are global;
+// tenants register custom types in their own namespace, referenced as "tenant/name".
+type ContentTypeRegistry struct {
+	mu      sync.RWMutex
+	builtin map[string]ContentTypeWeights
+	tenants map[string]map[string]ContentTypeWeights
+}
+
+// TenantContentType is a custom content type registered by a tenant.
+type TenantContentType struct {
+	// Tenant owning the content type
+	Tenant string `json:"tenant" example:"acme"`
+
+	// Name of the content type within the tenant
+	Name string `json:"name" example:"thumbnails"`
+
+	// ContentType is the qualified name used in requests ("tenant/name")
+	ContentType string `json:"content_type" example:"acme/thumbnails"`
+
+	// Weights of the content type
+	Weights ContentTypeWeights `json:"weights"`
+}
+
+// DefaultContentTypes is the registry used by CalculateWeightedIntensity.
+var DefaultContentTypes = NewContentTypeRegistry(PredefinedContentWeights)
+
+// NewContentTypeRegistry creates a registry with the given built-in content types.
+func NewContentTypeRegistry(builtin map[string]ContentTypeWeights) *ContentTypeRegistry {
+	copied := make(map[string]ContentTypeWeights, len(builtin))
+	for name, weights := range builtin {
+		copied[name] = weights
+	}
+	return &ContentTypeRegistry{
+		builtin: copied,
+		tenants: make(map[string]map[string]ContentTypeWeights),
+	}
+}
+
+// QualifiedContentType returns the name a tenant's content type is referenced by.
+func QualifiedContentType(tenant, name string) string {
+	return tenant + "/" + name
+}
+
+// Lookup resolves a built-in ("static") or tenant ("acme/thumbnails") content type.
+func (r *ContentTypeRegistry) Lookup(contentType string) (ContentTypeWeights, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if tenant, name, qualified := strings.Cut(contentType, "/"); qualified {
+		weights, exists := r.tenants[tenant][name]
+		return weights, exists
+	}
+	weights, exists := r.builtin[contentType]
+	return weights, exists
+}
+
+// Has reports whether a content type is registered.
+func (r *ContentTypeRegistry) Has(contentType string) bool {
+	_, exists := r.Lookup(contentType)
+	return exists
+}
+
+// Register adds or replaces a tenant's custom content type.
+func (r *ContentTypeRegistry) Register(tenant, name string, weights ContentTypeWeights) error {
+	if !contentTypeNamePattern.MatchString(tenant) {
+		return fmt.Errorf("%w: tenant %q must match %s", ErrInvalidContentType, tenant, contentTypeNamePattern)
+	}
+	if !contentTypeNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: name %q must match %s", ErrInvalidContentType, name, contentTypeNamePattern)
+	}
+	if err := ValidateContentTypeWeights(weights); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.tenants[tenant] == nil {
+		r.tenants[tenant] = make(map[string]ContentTypeWeights)
+	}
+	r.tenants[tenant][name] = weights
+	return nil
+}
+
+// Unregister removes a tenant's custom content type and reports whether it existed.
+func (r *ContentTypeRegistry) Unregister(tenant, name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tenants[tenant][name]; !exists {
+		return false
+	}
+	delete(r.tenants[tenant], name)
+	if len(r.tenants[tenant]) == 0 {
+		delete(r.tenants, tenant)
+	}
+	return true
+}
+
+// BuiltinNames returns the built-in content type names, sorted.
+func (r *ContentTypeRegistry) BuiltinNames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.builtin))
+	for name := range r.builtin {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TenantTypes returns a tenant's custom content types sorted by name. An empty tenant has
+// no custom types, so one tenant's definitions are never listed to another.
+func (r *ContentTypeRegistry) TenantTypes(tenant string) []TenantContentType {
+	if tenant == "" {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var types []TenantContentType
+	for name, weights := range r.tenants[tenant] {
+		types = append(types, TenantContentType{
+			Tenant:      tenant,
+			Name:        name,
+			ContentType: QualifiedContentType(tenant, name),
+			Weights:     weights,
+		})
+	}
+
+	sort.Slice(types, func(i, j int) bool { return types[i].ContentType < types[j].ContentType })
+	return types
+}
+
+// ValidateContentTypeWeights checks that weights are non-negative, sum to 100 and the request profile is non-negative.
+func ValidateContentTypeWeights(weights ContentTypeWeights) error {
+	if weights.TransmissionWeight < 0 || weights.ComputationWeight < 0 {
+		return fmt.Errorf("%w: weights must not be negative", ErrInvalidContentType)
+	}
+	if math.Abs(weights.TransmissionWeight+weights.ComputationWeight-100) > 0.01 {
+		return fmt.Errorf("%w: transmission_weight and computation_weight must sum to 100", ErrInvalidContentType)
+	}
+	if weights.BytesPerRequest < 0 || weights.CPUSecondsPerRequest < 0 {
+		return fmt.Errorf("%w: bytes_per_request and cpu_seconds_per_request must not be negative", ErrInvalidContentType)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package carbon
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestContentTypeRegistry(t *testing.T) {
+	registry := NewContentTypeRegistry(map[string]ContentTypeWeights{
+		"static": {TransmissionWeight: 90, ComputationWeight: 10},
+	})
+	thumbnails := ContentTypeWeights{TransmissionWeight: 80, ComputationWeight: 20, BytesPerRequest: 40000}
+
+	if err := registry.Register("acme", "thumbnails", thumbnails); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := registry.Register("globex", "reports", ContentTypeWeights{TransmissionWeight: 30, ComputationWeight: 70}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	t.Run("lookup", func(t *testing.T) {
+		tests := []struct {
+			contentType  string
+			exists       bool
+			transmission float64
+		}{
+			{"static", true, 90},
+			{"acme/thumbnails", true, 80},
+			{"thumbnails", false, 0},
+			{"globex/thumbnails", false, 0},
+			{"acme/static", false, 0},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.contentType, func(t *testing.T) {
+				weights, exists := registry.Lookup(tt.contentType)
+				if exists != tt.exists || weights.TransmissionWeight != tt.transmission {
+					t.Errorf("Expected %v with transmission %v, got %v with %v", tt.exists, tt.transmission, exists, weights.TransmissionWeight)
+				}
+				if registry.Has(tt.contentType) != tt.exists {
+					t.Errorf("Expected Has() = %v", tt.exists)
+				}
+			})
+		}
+	})
+
+	t.Run("invalid names", func(t *testing.T) {
+		tests := []struct{ tenant, name string }{
+			{"", "thumbnails"},
+			{"Acme", "thumbnails"},
+			{"acme", "a/b"},
+			{"acme", "-leading-dash"},
+		}
+
+		for _, tt := range tests {
+			if err := registry.Register(tt.tenant, tt.name, thumbnails); !errors.Is(err, ErrInvalidContentType) {
+				t.Errorf("Expected ErrInvalidContentType for %q/%q, got %v", tt.tenant, tt.name, err)
+			}
+		}
+	})
+
+	t.Run("tenant types", func(t *testing.T) {
+		types := registry.TenantTypes("acme")
+		if len(types) != 1 || types[0].ContentType != "acme/thumbnails" || types[0].Weights != thumbnails {
+			t.Errorf("Expected only acme/thumbnails, got %+v", types)
+		}
+		if types := registry.TenantTypes(""); types != nil {
+			t.Errorf("Expected no tenant types without a tenant, got %+v", types)
+		}
+		if types := registry.TenantTypes("initech"); types != nil {
+			t.Errorf("Expected no types for an unknown tenant, got %+v", types)
+		}
+	})
+
+	t.Run("unregister", func(t *testing.T) {
+		if registry.Unregister("globex", "thumbnails") {
+			t.Error("Expected unregistering another tenant's type to fail")
+		}
+		if !registry.Unregister("acme", "thumbnails") {
+			t.Fatal("Expected acme/thumbnails to be unregistered")
+		}
+		if registry.Has("acme/thumbnails") || registry.Unregister("acme", "thumbnails") {
+			t.Error("Expected acme/thumbnails to be gone")
+		}
+		if !registry.Has("globex/reports") || !registry.Has("static") {
+			t.Error("Expected other types to be kept")
+		}
+	})
+}
+
+func TestValidateContentTypeWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights ContentTypeWeights
+		valid   bool
+	}{
+		{"sums to 100", ContentTypeWeights{TransmissionWeight: 60, ComputationWeight: 40}, true},
+		{"sums to 100 within tolerance", ContentTypeWeights{TransmissionWeight: 33.333, ComputationWeight: 66.667}, true},
+		{"all transmission", ContentTypeWeights{TransmissionWeight: 100}, true},
+		{"sums below 100", ContentTypeWeights{TransmissionWeight: 60, ComputationWeight: 30}, false},
+		{"sums above 100", ContentTypeWeights{TransmissionWeight: 60, ComputationWeight: 50}, false},
+		{"negative weight", ContentTypeWeights{TransmissionWeight: 120, ComputationWeight: -20}, false},
+		{"negative bytes", ContentTypeWeights{TransmissionWeight: 50, ComputationWeight: 50, BytesPerRequest: -1}, false},
+		{"negative CPU", ContentTypeWeights{TransmissionWeight: 50, ComputationWeight: 50, CPUSecondsPerRequest: -0.1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateContentTypeWeights(tt.weights)
+			if tt.valid && err != nil {
+				t.Errorf("Expected valid weights, got %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidContentType) {
+				t.Errorf("Expected ErrInvalidContentType, got %v", err)
+			}
+		})
+	}
+}
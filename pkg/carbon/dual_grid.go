@@ -40,6 +40,21 @@ type DualGridCarbonIntensity struct {
 	// NetworkHops estimates the number of network hops between locations
 	NetworkHops int `json:"network_hops,omitempty" example:"8"`
 
+	// LatencyP50Ms is the median RTT between user and edge in milliseconds
+	LatencyP50Ms float64 `json:"latency_p50_ms,omitempty" example:"24.5"`
+
+	// LatencyP95Ms is the 95th percentile RTT between user and edge in milliseconds
+	LatencyP95Ms float64 `json:"latency_p95_ms,omitempty" example:"61.0"`
+
+	// LatencySource indicates whether latency is "measured" or estimated by a "model"
+	LatencySource string `json:"latency_source,omitempty" example:"measured"`
+
+	// GramsPerRequest is the absolute emissions of one request of this content type in g CO2
+	GramsPerRequest float64 `json:"grams_per_request,omitempty" example:"0.042"`
+
+	// Emissions breaks GramsPerRequest down into transmission and server computation
+	Emissions *RequestEmissions `json:"emissions,omitempty"`
+
 	// Recommendation provides optimization guidance based on dual-grid analysis
 	Recommendation DualGridRecommendation `json:"recommendation"`
 
@@ -85,11 +100,38 @@ type EdgeAlternative struct {
 	// Distance from user in kilometers
 	Distance float64 `json:"distance_km" example:"650.2"`
 
-	// EstimatedLatency in milliseconds
+	// EstimatedLatency in milliseconds (measured p50 where available)
 	EstimatedLatency int `json:"estimated_latency_ms" example:"45"`
 
+	// LatencyP95 is the 95th percentile latency in milliseconds
+	LatencyP95 int `json:"latency_p95_ms,omitempty" example:"80"`
+
+	// LatencySource indicates whether latency is "measured" or estimated by a "model"
+	LatencySource string `json:"latency_source,omitempty" example:"measured"`
+
 	// AvailabilityScore indicates the likelihood this edge can serve the content (0-100)
 	AvailabilityScore float64 `json:"availability_score" example:"95.5"`
+
+	// AvailabilitySource indicates whether AvailabilityScore comes from health "probe" results or a "tier_estimate"
+	AvailabilitySource string `json:"availability_source,omitempty" example:"probe"`
+
+	// EdgeID is the edge location key in the CDN catalog
+	EdgeID string `json:"edge_id,omitempty" example:"frankfurt"`
+
+	// DataSource is the source of the edge zone's carbon data ("electricity_maps", "mock" or "default")
+	DataSource string `json:"data_source,omitempty" example:"electricity_maps"`
+
+	// DataAgeMinutes is the age of the edge zone's carbon data
+	DataAgeMinutes float64 `json:"data_age_minutes" example:"12.5"`
+
+	// Downranked is true when stale or synthetic carbon data moved this edge behind edges with trustworthy data
+	Downranked bool `json:"downranked,omitempty" example:"false"`
+
+	// Excluded is true when the edge must not be recommended, e.g. because its health probe is failing
+	Excluded bool `json:"excluded,omitempty" example:"false"`
+
+	// Reason explains why the edge was down-ranked or excluded
+	Reason string `json:"reason,omitempty" example:"carbon data for zone SE is synthetic (mock)"`
 }
 
 // TimeBasedStrategy provides time-based optimization recommendations.
@@ -149,49 +191,71 @@ type EdgeLocationInfo struct {
 // ContentTypeWeights defines carbon weight distributions for different content types.
 type ContentTypeWeights struct {
 	// TransmissionWeight is the percentage of carbon footprint from transmission
-	TransmissionWeight float64
+	TransmissionWeight float64 `json:"transmission_weight" example:"80"`
 
 	// ComputationWeight is the percentage of carbon footprint from computation
-	ComputationWeight float64
+	ComputationWeight float64 `json:"computation_weight" example:"20"`
+
+	// BytesPerRequest is the typical response size, used for absolute emissions
+	BytesPerRequest int64 `json:"bytes_per_request,omitempty" example:"500000"`
+
+	// CPUSecondsPerRequest is the typical server CPU time per request, used for absolute emissions
+	CPUSecondsPerRequest float64 `json:"cpu_seconds_per_request,omitempty" example:"0.002"`
 }
 
 // PredefinedContentWeights provides standard weight distributions for common content types.
 var PredefinedContentWeights = map[string]ContentTypeWeights{
 	"static": {
-		TransmissionWeight: 80, // Static content: mostly transmission
-		ComputationWeight:  20,
+		TransmissionWeight:   80, // Static content: mostly transmission
+		ComputationWeight:    20,
+		BytesPerRequest:      500_000,
+		CPUSecondsPerRequest: 0.002,
 	},
 	"api": {
-		TransmissionWeight: 40, // API calls: balanced
-		ComputationWeight:  60,
+		TransmissionWeight:   40, // API calls: balanced
+		ComputationWeight:    60,
+		BytesPerRequest:      20_000,
+		CPUSecondsPerRequest: 0.05,
 	},
 	"video": {
-		TransmissionWeight: 60, // Video: significant transmission, some transcoding
-		ComputationWeight:  40,
+		TransmissionWeight:   60, // Video: significant transmission, some transcoding
+		ComputationWeight:    40,
+		BytesPerRequest:      5_000_000, // One segment of a few seconds
+		CPUSecondsPerRequest: 0.01,
 	},
 	"dynamic": {
-		TransmissionWeight: 30, // Dynamic content: mostly computation
-		ComputationWeight:  70,
+		TransmissionWeight:   30, // Dynamic content: mostly computation
+		ComputationWeight:    70,
+		BytesPerRequest:      150_000,
+		CPUSecondsPerRequest: 0.1,
 	},
 	"ai": {
-		TransmissionWeight: 20, // AI/ML: heavy computation
-		ComputationWeight:  80,
+		TransmissionWeight:   20, // AI/ML: heavy computation
+		ComputationWeight:    80,
+		BytesPerRequest:      10_000,
+		CPUSecondsPerRequest: 2.0,
 	},
 	"database": {
-		TransmissionWeight: 25, // Database queries: computation heavy
-		ComputationWeight:  75,
+		TransmissionWeight:   25, // Database queries: computation heavy
+		ComputationWeight:    75,
+		BytesPerRequest:      50_000,
+		CPUSecondsPerRequest: 0.2,
 	},
 }
 
+// DefaultContentWeights is used for content types that are not registered.
+var DefaultContentWeights = ContentTypeWeights{
+	TransmissionWeight: 50,
+	ComputationWeight:  50,
+}
+
 // CalculateWeightedIntensity computes the weighted carbon intensity based on location data and content type.
+// Content types are resolved through DefaultContentTypes, so tenant-registered types ("tenant/name") apply.
 func CalculateWeightedIntensity(userIntensity, edgeIntensity float64, contentType string) (float64, float64, float64) {
-	weights, exists := PredefinedContentWeights[contentType]
+	weights, exists := DefaultContentTypes.Lookup(contentType)
 	if !exists {
 		// Default to balanced weights
-		weights = ContentTypeWeights{
-			TransmissionWeight: 50,
-			ComputationWeight:  50,
-		}
+		weights = DefaultContentWeights
 	}
 
 	// Calculate weighted intensity
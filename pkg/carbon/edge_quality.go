@@ -0,0 +1,215 @@
+package carbon
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Carbon intensity data sources recorded in CarbonIntensity.Source
+const (
+	DataSourceElectricityMaps = "electricity_maps"
+	DataSourceMock            = "mock"
+	DataSourceDefault         = "default"
+)
+
+// Actions applied to edges whose carbon data is stale or synthetic
+const (
+	DataActionDownrank = "downrank"
+	DataActionExclude  = "exclude"
+)
+
+// Availability score sources recorded in EdgeAlternative.AvailabilitySource
+const (
+	AvailabilitySourceProbe = "probe"
+	AvailabilitySourceTier  = "tier_estimate"
+)
+
+// DefaultMaxDataAge is the age after which carbon data is considered stale
+const DefaultMaxDataAge = 2 * time.Hour
+
+// DataFreshness describes where a grid zone's carbon data came from and how old it is.
+type DataFreshness struct {
+	// Zone is the grid zone
+	Zone string `json:"zone" example:"SE"`
+
+	// Source of the reading ("electricity_maps", "mock" or "default" when no reading was available)
+	Source string `json:"source" example:"electricity_maps"`
+
+	// DataTimestamp is when the reading was taken
+	DataTimestamp time.Time `json:"data_timestamp,omitempty" example:"2024-01-15T14:00:00Z"`
+
+	// FetchedAt is when the service last fetched the reading
+	FetchedAt time.Time `json:"fetched_at,omitempty" example:"2024-01-15T14:25:00Z"`
+
+	// AgeMinutes is the age of the reading at assessment time
+	AgeMinutes float64 `json:"age_minutes" example:"25"`
+
+	// Stale is true when the reading is older than the maximum data age or undated
+	Stale bool `json:"stale" example:"false"`
+
+	// Synthetic is true for mock or default readings that do not reflect the real grid
+	Synthetic bool `json:"synthetic" example:"false"`
+}
+
+// CDNAlternatives is the outcome of evaluating a provider's edges as alternatives to the current edge.
+type CDNAlternatives struct {
+	// Alternatives ranked by data quality, then carbon intensity
+	Alternatives []EdgeAlternative `json:"alternatives"`
+
+	// Excluded lists greener edges that were not recommended, with the reason
+	Excluded []EdgeAlternative `json:"excluded,omitempty"`
+}
+
+// EdgeHealth is the result of active health probes against an edge.
+type EdgeHealth struct {
+	// Healthy is false once consecutive probe failures reach the failure threshold
+	Healthy bool `json:"healthy" example:"true"`
+
+	// Availability is the probe success rate over the rolling window (0-100)
+	Availability float64 `json:"availability" example:"98.5"`
+
+	// Checks is the number of probes in the rolling window
+	Checks int `json:"checks" example:"20"`
+
+	// ConsecutiveFailures is the number of failed probes since the last success
+	ConsecutiveFailures int `json:"consecutive_failures" example:"0"`
+
+	// LastChecked is when the edge was last probed
+	LastChecked time.Time `json:"last_checked" example:"2024-01-15T14:29:30Z"`
+
+	// LastError describes the most recent failure
+	LastError string `json:"last_error,omitempty" example:"unexpected status 503"`
+}
+
+// EdgeHealthSource reports probe results per edge. The second result is false for edges
+// that are not probed.
+type EdgeHealthSource interface {
+	EdgeHealth(provider, edgeID string) (EdgeHealth, bool)
+}
+
+// EdgeRankingPolicy decides how stale data, synthetic data and failing probes affect edge ranking.
+// Unhealthy edges are always excluded.
+type EdgeRankingPolicy struct {
+	// MaxDataAge is the age after which carbon data is stale (default DefaultMaxDataAge)
+	MaxDataAge time.Duration
+
+	// StaleData is DataActionDownrank (default) or DataActionExclude
+	StaleData string
+
+	// SyntheticData is DataActionDownrank (default) or DataActionExclude
+	SyntheticData string
+}
+
+// DefaultEdgeRankingPolicy down-ranks edges with stale or synthetic data.
+var DefaultEdgeRankingPolicy = EdgeRankingPolicy{
+	MaxDataAge:    DefaultMaxDataAge,
+	StaleData:     DataActionDownrank,
+	SyntheticData: DataActionDownrank,
+}
+
+// EdgeQuality is the outcome of assessing an edge under an EdgeRankingPolicy.
+type EdgeQuality struct {
+	// Penalty counts down-ranking issues; edges with fewer issues rank first
+	Penalty int
+
+	// Excluded edges must not be recommended
+	Excluded bool
+
+	// Reasons explains each issue
+	Reasons []string
+}
+
+// Reason joins the quality reasons into one sentence
+func (q EdgeQuality) Reason() string {
+	return strings.Join(q.Reasons, "; ")
+}
+
+// IsSyntheticSource reports whether a data source does not reflect the real grid
+func IsSyntheticSource(source string) bool {
+	switch source {
+	case "", DataSourceMock, DataSourceDefault:
+		return true
+	}
+	return strings.HasPrefix(source, DataSourceMock+"_") // e.g. "mock_historical"
+}
+
+// assessFreshness describes a zone's reading at now; readings taken before cutoff are stale.
+// A nil reading yields a stale, synthetic "default" entry.
+func assessFreshness(zone string, intensity *CarbonIntensity, fetchedAt, cutoff, now time.Time) DataFreshness {
+	freshness := DataFreshness{Zone: zone, Source: DataSourceDefault, FetchedAt: fetchedAt, Stale: true, Synthetic: true}
+	if intensity == nil {
+		return freshness
+	}
+
+	if intensity.Source != "" {
+		freshness.Source = intensity.Source
+	}
+	freshness.Synthetic = IsSyntheticSource(intensity.Source)
+	freshness.DataTimestamp = intensity.Timestamp
+	if !intensity.Timestamp.IsZero() {
+		freshness.AgeMinutes = math.Round(math.Max(now.Sub(intensity.Timestamp).Minutes(), 0)*10) / 10
+		freshness.Stale = intensity.Timestamp.Before(cutoff)
+	}
+	return freshness
+}
+
+// Freshness describes a zone's reading at now under the policy's maximum data age
+func (p EdgeRankingPolicy) Freshness(zone string, intensity *CarbonIntensity, fetchedAt, now time.Time) DataFreshness {
+	return assessFreshness(zone, intensity, fetchedAt, now.Add(-p.maxDataAge()), now)
+}
+
+// maxDataAge returns MaxDataAge or DefaultMaxDataAge
+func (p EdgeRankingPolicy) maxDataAge() time.Duration {
+	if p.MaxDataAge <= 0 {
+		return DefaultMaxDataAge
+	}
+	return p.MaxDataAge
+}
+
+// Assess applies the policy to an edge's carbon data freshness and, if probed, its health.
+func (p EdgeRankingPolicy) Assess(freshness DataFreshness, health *EdgeHealth) EdgeQuality {
+	var quality EdgeQuality
+
+	apply := func(action, reason string) {
+		if action == DataActionExclude {
+			quality.Excluded = true
+		} else {
+			quality.Penalty++
+		}
+		quality.Reasons = append(quality.Reasons, reason)
+	}
+
+	if health != nil && !health.Healthy {
+		quality.Excluded = true
+		reason := fmt.Sprintf("health probe failing (%d consecutive failures)", health.ConsecutiveFailures)
+		if health.LastError != "" {
+			reason = fmt.Sprintf("health probe failing (%d consecutive failures: %s)", health.ConsecutiveFailures, health.LastError)
+		}
+		quality.Reasons = append(quality.Reasons, reason)
+	}
+
+	if freshness.Synthetic {
+		apply(p.SyntheticData, fmt.Sprintf("carbon data for zone %s is synthetic (%s)", freshness.Zone, freshness.Source))
+	}
+	if freshness.Stale && !freshness.Synthetic {
+		if freshness.DataTimestamp.IsZero() {
+			apply(p.StaleData, fmt.Sprintf("carbon data for zone %s has no timestamp", freshness.Zone))
+		} else {
+			apply(p.StaleData, fmt.Sprintf("carbon data for zone %s is %.0f minutes old (max %.0f)",
+				freshness.Zone, freshness.AgeMinutes, p.maxDataAge().Minutes()))
+		}
+	}
+
+	return quality
+}
+
+// EdgeAvailability returns the availability score of an edge: the probe success rate when
+// probed, otherwise an estimate from the edge tier.
+func EdgeAvailability(edge EdgeLocationInfo, health *EdgeHealth) (float64, string) {
+	if health != nil && health.Checks > 0 {
+		return health.Availability, AvailabilitySourceProbe
+	}
+	return float64(100 - edge.Tier*5), AvailabilitySourceTier // Lower tier = higher score
+}
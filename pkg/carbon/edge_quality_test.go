@@ -0,0 +1,98 @@
+package carbon
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEdgeRankingPolicyFreshness(t *testing.T) {
+	now := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+	policy := EdgeRankingPolicy{MaxDataAge: time.Hour}
+
+	tests := []struct {
+		name      string
+		intensity *CarbonIntensity
+		source    string
+		stale     bool
+		synthetic bool
+		age       float64
+	}{
+		{"fresh live reading", &CarbonIntensity{Source: DataSourceElectricityMaps, Timestamp: now.Add(-30 * time.Minute)}, DataSourceElectricityMaps, false, false, 30},
+		{"old live reading", &CarbonIntensity{Source: DataSourceElectricityMaps, Timestamp: now.Add(-90 * time.Minute)}, DataSourceElectricityMaps, true, false, 90},
+		{"undated live reading", &CarbonIntensity{Source: DataSourceElectricityMaps}, DataSourceElectricityMaps, true, false, 0},
+		{"fresh mock reading", &CarbonIntensity{Source: DataSourceMock, Timestamp: now}, DataSourceMock, false, true, 0},
+		{"mock historical reading", &CarbonIntensity{Source: "mock_historical", Timestamp: now}, "mock_historical", false, true, 0},
+		{"reading without source", &CarbonIntensity{Timestamp: now}, DataSourceDefault, false, true, 0},
+		{"no reading", nil, DataSourceDefault, true, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			freshness := policy.Freshness("SE", tt.intensity, now, now)
+			if freshness.Zone != "SE" || freshness.Source != tt.source {
+				t.Errorf("Expected zone SE from %s, got %s from %s", tt.source, freshness.Zone, freshness.Source)
+			}
+			if freshness.Stale != tt.stale || freshness.Synthetic != tt.synthetic {
+				t.Errorf("Expected stale %v and synthetic %v, got %v and %v", tt.stale, tt.synthetic, freshness.Stale, freshness.Synthetic)
+			}
+			if freshness.AgeMinutes != tt.age {
+				t.Errorf("Expected %v minutes old, got %v", tt.age, freshness.AgeMinutes)
+			}
+		})
+	}
+
+	// The default maximum age applies when none is set
+	old := &CarbonIntensity{Source: DataSourceElectricityMaps, Timestamp: now.Add(-90 * time.Minute)}
+	if freshness := (EdgeRankingPolicy{}).Freshness("SE", old, now, now); freshness.Stale {
+		t.Errorf("Expected 90 minutes to be fresh under the default %s, got stale", DefaultMaxDataAge)
+	}
+}
+
+func TestEdgeRankingPolicyAssess(t *testing.T) {
+	fresh := DataFreshness{Zone: "SE", Source: DataSourceElectricityMaps, DataTimestamp: time.Now(), AgeMinutes: 5}
+	stale := DataFreshness{Zone: "NO-NO1", Source: DataSourceElectricityMaps, DataTimestamp: time.Now(), AgeMinutes: 300, Stale: true}
+	undated := DataFreshness{Zone: "FI", Source: DataSourceElectricityMaps, Stale: true}
+	synthetic := DataFreshness{Zone: "IS", Source: DataSourceMock, Synthetic: true}
+	staleSynthetic := DataFreshness{Zone: "IS", Source: DataSourceDefault, Stale: true, Synthetic: true}
+	healthy := &EdgeHealth{Healthy: true, Availability: 100, Checks: 10}
+	unhealthy := &EdgeHealth{Healthy: false, ConsecutiveFailures: 3, LastError: "unexpected status 503"}
+	exclude := EdgeRankingPolicy{StaleData: DataActionExclude, SyntheticData: DataActionExclude}
+
+	tests := []struct {
+		name      string
+		policy    EdgeRankingPolicy
+		freshness DataFreshness
+		health    *EdgeHealth
+		penalty   int
+		excluded  bool
+		reason    string
+	}{
+		{"fresh and healthy", DefaultEdgeRankingPolicy, fresh, healthy, 0, false, ""},
+		{"fresh and not probed", DefaultEdgeRankingPolicy, fresh, nil, 0, false, ""},
+		{"stale is downranked", DefaultEdgeRankingPolicy, stale, nil, 1, false, "carbon data for zone NO-NO1 is 300 minutes old (max 120)"},
+		{"undated is downranked", DefaultEdgeRankingPolicy, undated, nil, 1, false, "carbon data for zone FI has no timestamp"},
+		{"synthetic is downranked", DefaultEdgeRankingPolicy, synthetic, nil, 1, false, "carbon data for zone IS is synthetic (mock)"},
+		{"stale synthetic counts once", DefaultEdgeRankingPolicy, staleSynthetic, nil, 1, false, "is synthetic (default)"},
+		{"stale is excluded", exclude, stale, nil, 0, true, "300 minutes old"},
+		{"synthetic is excluded", exclude, synthetic, nil, 0, true, "is synthetic"},
+		{"unhealthy is always excluded", DefaultEdgeRankingPolicy, fresh, unhealthy, 0, true,
+			"health probe failing (3 consecutive failures: unexpected status 503)"},
+		{"unhealthy and synthetic", DefaultEdgeRankingPolicy, synthetic, unhealthy, 1, true, "health probe failing (3 consecutive failures: unexpected status 503); carbon data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quality := tt.policy.Assess(tt.freshness, tt.health)
+			if quality.Penalty != tt.penalty || quality.Excluded != tt.excluded {
+				t.Errorf("Expected penalty %d and excluded %v, got %d and %v", tt.penalty, tt.excluded, quality.Penalty, quality.Excluded)
+			}
+			if tt.reason == "" && len(quality.Reasons) > 0 {
+				t.Errorf("Expected no reasons, got %v", quality.Reasons)
+			}
+			if !strings.Contains(quality.Reason(), tt.reason) {
+				t.Errorf("Expected reason containing %q, got %q", tt.reason, quality.Reason())
+			}
+		})
+	}
+}
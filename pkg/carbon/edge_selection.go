@@ -0,0 +1,452 @@
+package carbon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ErrNoEdgeCandidates is returned when a multi-objective selection has no edges to choose from.
+var ErrNoEdgeCandidates = errors.New("no edge candidates")
+
+// RTT sources reported on EdgeObjectives
+const (
+	RTTSourceModel    = "model"
+	RTTSourceMeasured = "measured"
+)
+
+// EdgeSelectionUser identifies the user population an edge is selected for.
+type EdgeSelectionUser struct {
+	// Latitude of the user
+	Latitude float64 `json:"latitude" example:"52.52"`
+
+	// Longitude of the user
+	Longitude float64 `json:"longitude" example:"13.405"`
+
+	// GridZone is the user's electricity grid zone
+	GridZone string `json:"grid_zone" example:"DE"`
+
+	// Prefix is the user's network prefix, used to look up measured RTTs
+	Prefix string `json:"prefix,omitempty" example:"81.2.69.0/24"`
+}
+
+// edgeSelectionUserKey is the context key of the user set by WithEdgeSelectionUser
+type edgeSelectionUserKey struct{}
+
+// WithEdgeSelectionUser returns a context carrying the user an edge is selected for, so
+// services can use the requester's coordinates and network prefix for latency estimates.
+func WithEdgeSelectionUser(ctx context.Context, user EdgeSelectionUser) context.Context {
+	return context.WithValue(ctx, edgeSelectionUserKey{}, user)
+}
+
+// EdgeSelectionUserFromContext returns the user set by WithEdgeSelectionUser, if any.
+func EdgeSelectionUserFromContext(ctx context.Context) (EdgeSelectionUser, bool) {
+	user, ok := ctx.Value(edgeSelectionUserKey{}).(EdgeSelectionUser)
+	return user, ok
+}
+
+// RTTModel estimates the round-trip time between a user and an edge location.
+type RTTModel interface {
+	// EstimateRTT returns the RTT in milliseconds and the source of the estimate
+	EstimateRTT(user EdgeSelectionUser, edgeID string, edge EdgeLocationInfo) (float64, string)
+}
+
+// RTTPercentileModel is an RTTModel that can also report tail latency, typically backed by
+// measurements. Edge selection uses p50 as the latency objective and checks SLOs against p95.
+type RTTPercentileModel interface {
+	RTTModel
+
+	// EstimateRTTPercentiles returns p50 and p95 RTT in milliseconds and the source of the estimate
+	EstimateRTTPercentiles(user EdgeSelectionUser, edgeID string, edge EdgeLocationInfo) (float64, float64, string)
+}
+
+// EstimateRTTPercentiles returns p50 and p95 RTT from a model. Models without percentile
+// support report their single estimate for both.
+func EstimateRTTPercentiles(model RTTModel, user EdgeSelectionUser, edgeID string, edge EdgeLocationInfo) (float64, float64, string) {
+	if percentiles, ok := model.(RTTPercentileModel); ok {
+		return percentiles.EstimateRTTPercentiles(user, edgeID, edge)
+	}
+	rtt, source := model.EstimateRTT(user, edgeID, edge)
+	return rtt, rtt, source
+}
+
+// DistanceRTTModel estimates RTT from great-circle distance: BaseMs + distance / KmPerMs.
+type DistanceRTTModel struct {
+	// BaseMs is the fixed RTT overhead in milliseconds (last mile, processing)
+	BaseMs float64 `json:"base_ms" example:"10"`
+
+	// KmPerMs is the distance covered per millisecond of RTT
+	KmPerMs float64 `json:"km_per_ms" example:"20"`
+}
+
+// DefaultRTTModel matches the latency estimate used for EdgeAlternative.EstimatedLatency.
+var DefaultRTTModel = DistanceRTTModel{BaseMs: 10, KmPerMs: 20}
+
+// EstimateRTT implements RTTModel.
+func (m DistanceRTTModel) EstimateRTT(user EdgeSelectionUser, edgeID string, edge EdgeLocationInfo) (float64, string) {
+	kmPerMs := m.KmPerMs
+	if kmPerMs <= 0 {
+		kmPerMs = DefaultRTTModel.KmPerMs
+	}
+	distance := CalculateDistance(user.Latitude, user.Longitude, edge.Latitude, edge.Longitude)
+	return m.BaseMs + distance/kmPerMs, RTTSourceModel
+}
+
+// RTTTable is a measured RTT table in milliseconds keyed by edge ID, with a model fallback
+// for edges that have no measurement.
+type RTTTable struct {
+	// Measurements maps edge IDs to measured RTT in milliseconds
+	Measurements map[string]float64 `json:"measurements"`
+
+	// Fallback estimates RTT for unmeasured edges; DefaultRTTModel if nil
+	Fallback RTTModel `json:"-"`
+}
+
+// EstimateRTT implements RTTModel.
+func (t RTTTable) EstimateRTT(user EdgeSelectionUser, edgeID string, edge EdgeLocationInfo) (float64, string) {
+	if rtt, exists := t.Measurements[edgeID]; exists && rtt > 0 {
+		return rtt, RTTSourceMeasured
+	}
+	if t.Fallback != nil {
+		return t.Fallback.EstimateRTT(user, edgeID, edge)
+	}
+	return DefaultRTTModel.EstimateRTT(user, edgeID, edge)
+}
+
+// EstimateRTTPercentiles implements RTTPercentileModel, deferring to the fallback's percentiles
+// for unmeasured edges.
+func (t RTTTable) EstimateRTTPercentiles(user EdgeSelectionUser, edgeID string, edge EdgeLocationInfo) (float64, float64, string) {
+	if rtt, exists := t.Measurements[edgeID]; exists && rtt > 0 {
+		return rtt, rtt, RTTSourceMeasured
+	}
+	if t.Fallback != nil {
+		return EstimateRTTPercentiles(t.Fallback, user, edgeID, edge)
+	}
+	return EstimateRTTPercentiles(DefaultRTTModel, user, edgeID, edge)
+}
+
+// ObjectiveWeights weights latency, egress cost and carbon when picking a point on the Pareto front.
+// Weights are normalized to sum to 1; all zero means equal weights.
+type ObjectiveWeights struct {
+	// Latency weight
+	Latency float64 `json:"latency" example:"0.3"`
+
+	// Cost weight
+	Cost float64 `json:"cost" example:"0.2"`
+
+	// Carbon weight
+	Carbon float64 `json:"carbon" example:"0.5"`
+}
+
+// Normalized returns the weights scaled to sum to 1.
+func (w ObjectiveWeights) Normalized() ObjectiveWeights {
+	latency, cost, carbonWeight := math.Max(w.Latency, 0), math.Max(w.Cost, 0), math.Max(w.Carbon, 0)
+	total := latency + cost + carbonWeight
+	if total == 0 {
+		return ObjectiveWeights{Latency: 1.0 / 3, Cost: 1.0 / 3, Carbon: 1.0 / 3}
+	}
+	return ObjectiveWeights{Latency: latency / total, Cost: cost / total, Carbon: carbonWeight / total}
+}
+
+// EdgeSelectionRequest describes a multi-objective edge selection.
+type EdgeSelectionRequest struct {
+	// User is the user population to serve
+	User EdgeSelectionUser `json:"user"`
+
+	// CDNProvider is the catalog provider whose edges are considered
+	CDNProvider string `json:"cdn_provider" example:"cloudflare"`
+
+	// ContentType weights user and edge intensity (see PredefinedContentWeights)
+	ContentType string `json:"content_type" example:"static"`
+
+	// LatencySLOMs is the maximum acceptable p95 RTT in milliseconds, 0 for none
+	LatencySLOMs float64 `json:"latency_slo_ms" example:"50"`
+
+	// Weights selects the chosen point on the Pareto front
+	Weights ObjectiveWeights `json:"weights"`
+
+	// EgressPricePerGB maps edge IDs to egress price in USD per GB
+	EgressPricePerGB map[string]float64 `json:"egress_price_per_gb,omitempty"`
+
+	// DefaultEgressPricePerGB applies to edges without an explicit price
+	DefaultEgressPricePerGB float64 `json:"default_egress_price_per_gb" example:"0.08"`
+
+	// RTTModel overrides the distance-based RTT model parameters
+	RTTModel *DistanceRTTModel `json:"rtt_model,omitempty"`
+
+	// MeasuredRTTMs maps edge IDs to measured RTT in milliseconds for this user
+	MeasuredRTTMs map[string]float64 `json:"measured_rtt_ms,omitempty"`
+}
+
+// EdgeObjective holds the objective values of one edge.
+type EdgeObjective struct {
+	// EdgeID is the catalog key of the edge
+	EdgeID string `json:"edge_id" example:"frankfurt"`
+
+	// City of the edge
+	City string `json:"city" example:"Frankfurt"`
+
+	// GridZone of the edge
+	GridZone string `json:"grid_zone" example:"DE"`
+
+	// RTTMs is the estimated or measured median RTT in milliseconds
+	RTTMs float64 `json:"rtt_ms" example:"18.5"`
+
+	// RTTP95Ms is the 95th percentile RTT in milliseconds, checked against the SLO
+	RTTP95Ms float64 `json:"rtt_p95_ms" example:"42.0"`
+
+	// RTTSource is "model" or "measured"
+	RTTSource string `json:"rtt_source" example:"model"`
+
+	// EgressPricePerGB is the egress price in USD per GB
+	EgressPricePerGB float64 `json:"egress_price_per_gb" example:"0.02"`
+
+	// CarbonIntensity is the dual-grid weighted intensity in g CO2/kWh
+	CarbonIntensity float64 `json:"carbon_intensity" example:"180.2"`
+
+	// MeetsSLO indicates the p95 RTT is within the latency SLO
+	MeetsSLO bool `json:"meets_slo" example:"true"`
+
+	// CarbonDataMissing is true when the edge's grid intensity could not be fetched and
+	// CarbonIntensity rests on a default value
+	CarbonDataMissing bool `json:"carbon_data_missing,omitempty" example:"false"`
+
+	// Score is the weighted, normalized score on the Pareto front (lower is better)
+	Score float64 `json:"score,omitempty" example:"0.31"`
+}
+
+// ObjectiveTradeOff compares the chosen edge with the best edge for a single objective.
+type ObjectiveTradeOff struct {
+	// Objective is "latency", "cost" or "carbon"
+	Objective string `json:"objective" example:"latency"`
+
+	// BestEdgeID is the edge that is best for this objective alone
+	BestEdgeID string `json:"best_edge_id" example:"frankfurt"`
+
+	// BestValue is that edge's value for the objective
+	BestValue float64 `json:"best_value" example:"18.5"`
+
+	// ChosenValue is the chosen edge's value for the objective
+	ChosenValue float64 `json:"chosen_value" example:"42.0"`
+
+	// Delta is ChosenValue - BestValue
+	Delta float64 `json:"delta" example:"23.5"`
+
+	// DeltaPercent is Delta relative to BestValue
+	DeltaPercent float64 `json:"delta_percent" example:"127.0"`
+}
+
+// EdgeSelectionResult is the outcome of a multi-objective edge selection.
+type EdgeSelectionResult struct {
+	// ParetoFront holds the non-dominated edges that meet the SLO, best score first
+	ParetoFront []EdgeObjective `json:"pareto_front"`
+
+	// Chosen is the front point with the lowest weighted score
+	Chosen *EdgeObjective `json:"chosen"`
+
+	// TradeOffs compares Chosen with each single-objective optimum
+	TradeOffs []ObjectiveTradeOff `json:"trade_offs"`
+
+	// Weights are the normalized weights used to pick Chosen
+	Weights ObjectiveWeights `json:"weights"`
+
+	// SLOSatisfied is false when no edge met the SLO; the front then ignores the SLO
+	SLOSatisfied bool `json:"slo_satisfied" example:"true"`
+
+	// Dominated lists feasible edges that some front edge beats in at least one objective
+	// without being worse in any
+	Dominated []EdgeObjective `json:"dominated,omitempty"`
+
+	// ExcludedBySLO lists edges whose RTT exceeds the SLO
+	ExcludedBySLO []EdgeObjective `json:"excluded_by_slo,omitempty"`
+
+	// ExcludedNoCarbonData lists edges whose grid intensity could not be fetched. They are
+	// only considered when no edge has carbon data.
+	ExcludedNoCarbonData []EdgeObjective `json:"excluded_no_carbon_data,omitempty"`
+}
+
+// EvaluateEdgeObjectives computes RTT, egress price and weighted carbon intensity for each edge.
+// getIntensity returns false when a zone's intensity is unavailable and the value is a default;
+// those edges are marked CarbonDataMissing.
+func EvaluateEdgeObjectives(req EdgeSelectionRequest, edges map[string]EdgeLocationInfo, rtt RTTModel,
+	userIntensity float64, getIntensity func(gridZone string) (float64, bool)) []EdgeObjective {
+
+	objectives := make([]EdgeObjective, 0, len(edges))
+	for edgeID, edge := range edges {
+		rttMs, rttP95Ms, source := EstimateRTTPercentiles(rtt, req.User, edgeID, edge)
+
+		price, exists := req.EgressPricePerGB[edgeID]
+		if !exists {
+			price = req.DefaultEgressPricePerGB
+		}
+
+		edgeIntensity, available := getIntensity(edge.GridZone)
+		weighted, _, _ := CalculateWeightedIntensity(userIntensity, edgeIntensity, req.ContentType)
+
+		objectives = append(objectives, EdgeObjective{
+			EdgeID:            edgeID,
+			City:              edge.City,
+			GridZone:          edge.GridZone,
+			RTTMs:             rttMs,
+			RTTP95Ms:          rttP95Ms,
+			RTTSource:         source,
+			EgressPricePerGB:  price,
+			CarbonIntensity:   weighted,
+			MeetsSLO:          req.LatencySLOMs <= 0 || rttP95Ms <= req.LatencySLOMs,
+			CarbonDataMissing: !available,
+		})
+	}
+
+	// Map iteration order is random; keep results deterministic
+	sort.Slice(objectives, func(i, j int) bool { return objectives[i].EdgeID < objectives[j].EdgeID })
+	return objectives
+}
+
+// SelectParetoEdge returns the Pareto-optimal edges among those meeting the latency SLO and
+// picks the one with the lowest weighted sum of min-max normalized objectives. If no edge meets
+// the SLO, all edges are considered and SLOSatisfied is false. Edges missing carbon data are
+// left out unless no edge has any, so a default intensity never competes with real readings.
+func SelectParetoEdge(objectives []EdgeObjective, weights ObjectiveWeights) (*EdgeSelectionResult, error) {
+	if len(objectives) == 0 {
+		return nil, ErrNoEdgeCandidates
+	}
+
+	result := &EdgeSelectionResult{
+		Weights:      weights.Normalized(),
+		SLOSatisfied: true,
+	}
+
+	var withData []EdgeObjective
+	for _, objective := range objectives {
+		if objective.CarbonDataMissing {
+			result.ExcludedNoCarbonData = append(result.ExcludedNoCarbonData, objective)
+		} else {
+			withData = append(withData, objective)
+		}
+	}
+	if len(withData) > 0 {
+		objectives = withData
+	} else {
+		result.ExcludedNoCarbonData = nil
+	}
+
+	var feasible []EdgeObjective
+	for _, objective := range objectives {
+		if objective.MeetsSLO {
+			feasible = append(feasible, objective)
+		} else {
+			result.ExcludedBySLO = append(result.ExcludedBySLO, objective)
+		}
+	}
+	if len(feasible) == 0 {
+		feasible = append([]EdgeObjective(nil), objectives...)
+		result.ExcludedBySLO = nil
+		result.SLOSatisfied = false
+	}
+
+	for i, candidate := range feasible {
+		dominated := false
+		for j, other := range feasible {
+			if i != j && dominates(other, candidate) {
+				dominated = true
+				break
+			}
+		}
+		if dominated {
+			result.Dominated = append(result.Dominated, candidate)
+		} else {
+			result.ParetoFront = append(result.ParetoFront, candidate)
+		}
+	}
+
+	// Normalize over the whole feasible set so scores reflect the real spread of options
+	latencyRange := objectiveRange(feasible, func(o EdgeObjective) float64 { return o.RTTMs })
+	costRange := objectiveRange(feasible, func(o EdgeObjective) float64 { return o.EgressPricePerGB })
+	carbonRange := objectiveRange(feasible, func(o EdgeObjective) float64 { return o.CarbonIntensity })
+
+	for i := range result.ParetoFront {
+		point := &result.ParetoFront[i]
+		point.Score = result.Weights.Latency*latencyRange.normalize(point.RTTMs) +
+			result.Weights.Cost*costRange.normalize(point.EgressPricePerGB) +
+			result.Weights.Carbon*carbonRange.normalize(point.CarbonIntensity)
+	}
+	sort.SliceStable(result.ParetoFront, func(i, j int) bool {
+		if result.ParetoFront[i].Score != result.ParetoFront[j].Score {
+			return result.ParetoFront[i].Score < result.ParetoFront[j].Score
+		}
+		return result.ParetoFront[i].EdgeID < result.ParetoFront[j].EdgeID
+	})
+
+	chosen := result.ParetoFront[0]
+	result.Chosen = &chosen
+	result.TradeOffs = []ObjectiveTradeOff{
+		tradeOff("latency", feasible, chosen, func(o EdgeObjective) float64 { return o.RTTMs }),
+		tradeOff("cost", feasible, chosen, func(o EdgeObjective) float64 { return o.EgressPricePerGB }),
+		tradeOff("carbon", feasible, chosen, func(o EdgeObjective) float64 { return o.CarbonIntensity }),
+	}
+
+	return result, nil
+}
+
+// dominates reports whether a is no worse than b in every objective and better in at least one
+func dominates(a, b EdgeObjective) bool {
+	if a.RTTMs > b.RTTMs || a.EgressPricePerGB > b.EgressPricePerGB || a.CarbonIntensity > b.CarbonIntensity {
+		return false
+	}
+	return a.RTTMs < b.RTTMs || a.EgressPricePerGB < b.EgressPricePerGB || a.CarbonIntensity < b.CarbonIntensity
+}
+
+// valueRange is the min and max of an objective across candidates
+type valueRange struct {
+	low, high float64
+}
+
+// normalize maps a value into [0, 1]; a flat range maps to 0
+func (r valueRange) normalize(value float64) float64 {
+	if r.high <= r.low {
+		return 0
+	}
+	return (value - r.low) / (r.high - r.low)
+}
+
+func objectiveRange(objectives []EdgeObjective, value func(EdgeObjective) float64) valueRange {
+	r := valueRange{low: value(objectives[0]), high: value(objectives[0])}
+	for _, objective := range objectives[1:] {
+		r.low = math.Min(r.low, value(objective))
+		r.high = math.Max(r.high, value(objective))
+	}
+	return r
+}
+
+// tradeOff compares the chosen edge with the single-objective optimum
+func tradeOff(name string, objectives []EdgeObjective, chosen EdgeObjective, value func(EdgeObjective) float64) ObjectiveTradeOff {
+	best := objectives[0]
+	for _, objective := range objectives[1:] {
+		if value(objective) < value(best) || (value(objective) == value(best) && objective.EdgeID < best.EdgeID) {
+			best = objective
+		}
+	}
+
+	t := ObjectiveTradeOff{
+		Objective:   name,
+		BestEdgeID:  best.EdgeID,
+		BestValue:   value(best),
+		ChosenValue: value(chosen),
+	}
+	t.Delta = t.ChosenValue - t.BestValue
+	if t.BestValue != 0 {
+		t.DeltaPercent = math.Round(t.Delta/t.BestValue*1000) / 10
+	}
+	return t
+}
+
+// String provides a human-readable summary of the selection.
+func (r *EdgeSelectionResult) String() string {
+	if r.Chosen == nil {
+		return "Edge selection: no candidates"
+	}
+	return fmt.Sprintf("Edge selection: %s (%.0f ms, $%.3f/GB, %.1f g CO2/kWh) from %d Pareto-optimal edges",
+		r.Chosen.EdgeID, r.Chosen.RTTMs, r.Chosen.EgressPricePerGB, r.Chosen.CarbonIntensity, len(r.ParetoFront))
+}
@@ -0,0 +1,198 @@
+package carbon
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func edgeIDs(objectives []EdgeObjective) []string {
+	ids := make([]string, 0, len(objectives))
+	for _, objective := range objectives {
+		ids = append(ids, objective.EdgeID)
+	}
+	return ids
+}
+
+func sameIDs(got []EdgeObjective, expected ...string) bool {
+	ids := edgeIDs(got)
+	if len(ids) != len(expected) {
+		return false
+	}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDominates(t *testing.T) {
+	base := EdgeObjective{RTTMs: 20, EgressPricePerGB: 0.05, CarbonIntensity: 200}
+
+	tests := []struct {
+		name     string
+		a        EdgeObjective
+		expected bool
+	}{
+		{"better in every objective", EdgeObjective{RTTMs: 10, EgressPricePerGB: 0.01, CarbonIntensity: 100}, true},
+		{"better in one, equal elsewhere", EdgeObjective{RTTMs: 20, EgressPricePerGB: 0.05, CarbonIntensity: 199}, true},
+		{"identical", base, false},
+		{"trade-off", EdgeObjective{RTTMs: 10, EgressPricePerGB: 0.05, CarbonIntensity: 250}, false},
+		{"worse in one", EdgeObjective{RTTMs: 21, EgressPricePerGB: 0.05, CarbonIntensity: 200}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dominates(tt.a, base); got != tt.expected {
+				t.Errorf("dominates() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSelectParetoEdge(t *testing.T) {
+	// fast trades carbon for latency, green the reverse; slow is beaten by green everywhere
+	objectives := []EdgeObjective{
+		{EdgeID: "fast", RTTMs: 10, EgressPricePerGB: 0.05, CarbonIntensity: 400, MeetsSLO: true},
+		{EdgeID: "green", RTTMs: 30, EgressPricePerGB: 0.05, CarbonIntensity: 50, MeetsSLO: true},
+		{EdgeID: "slow", RTTMs: 40, EgressPricePerGB: 0.05, CarbonIntensity: 60, MeetsSLO: true},
+		{EdgeID: "far", RTTMs: 120, EgressPricePerGB: 0.01, CarbonIntensity: 20, MeetsSLO: false},
+	}
+
+	t.Run("weights pick the front point", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			weights ObjectiveWeights
+			chosen  string
+		}{
+			{"latency", ObjectiveWeights{Latency: 1}, "fast"},
+			{"carbon", ObjectiveWeights{Carbon: 1}, "green"},
+			{"equal by default", ObjectiveWeights{}, "green"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				result, err := SelectParetoEdge(objectives, tt.weights)
+				if err != nil {
+					t.Fatalf("SelectParetoEdge() error = %v", err)
+				}
+				if result.Chosen.EdgeID != tt.chosen {
+					t.Errorf("Expected %s, got %s", tt.chosen, result.Chosen.EdgeID)
+				}
+				if result.ParetoFront[0].EdgeID != tt.chosen {
+					t.Errorf("Expected the front sorted by score, got %v", edgeIDs(result.ParetoFront))
+				}
+				if !result.SLOSatisfied || !sameIDs(result.ExcludedBySLO, "far") {
+					t.Errorf("Expected far excluded by the SLO, got %v", edgeIDs(result.ExcludedBySLO))
+				}
+				if !sameIDs(result.Dominated, "slow") || len(result.ParetoFront) != 2 {
+					t.Errorf("Expected front [fast green] and slow dominated, got %v and %v",
+						edgeIDs(result.ParetoFront), edgeIDs(result.Dominated))
+				}
+			})
+		}
+	})
+
+	t.Run("trade-offs against single-objective optima", func(t *testing.T) {
+		result, err := SelectParetoEdge(objectives, ObjectiveWeights{Carbon: 1})
+		if err != nil {
+			t.Fatalf("SelectParetoEdge() error = %v", err)
+		}
+		latency := result.TradeOffs[0]
+		if latency.Objective != "latency" || latency.BestEdgeID != "fast" || latency.Delta != 20 || latency.DeltaPercent != 200 {
+			t.Errorf("Expected green 20 ms (200%%) slower than fast, got %+v", latency)
+		}
+		if carbonTradeOff := result.TradeOffs[2]; carbonTradeOff.BestEdgeID != "green" || carbonTradeOff.Delta != 0 {
+			t.Errorf("Expected green to be the carbon optimum, got %+v", carbonTradeOff)
+		}
+	})
+
+	t.Run("no edge meets the SLO", func(t *testing.T) {
+		result, err := SelectParetoEdge([]EdgeObjective{
+			{EdgeID: "a", RTTMs: 90, CarbonIntensity: 100},
+			{EdgeID: "b", RTTMs: 80, CarbonIntensity: 300},
+		}, ObjectiveWeights{Latency: 1})
+		if err != nil {
+			t.Fatalf("SelectParetoEdge() error = %v", err)
+		}
+		if result.SLOSatisfied || result.ExcludedBySLO != nil || result.Chosen.EdgeID != "b" {
+			t.Errorf("Expected b chosen ignoring the SLO, got %s (satisfied %v)", result.Chosen.EdgeID, result.SLOSatisfied)
+		}
+	})
+
+	t.Run("edges missing carbon data", func(t *testing.T) {
+		withMissing := append([]EdgeObjective{
+			// A default intensity must not put this edge on the front
+			{EdgeID: "unknown", RTTMs: 5, EgressPricePerGB: 0.01, CarbonIntensity: 10, MeetsSLO: true, CarbonDataMissing: true},
+		}, objectives...)
+		result, err := SelectParetoEdge(withMissing, ObjectiveWeights{})
+		if err != nil {
+			t.Fatalf("SelectParetoEdge() error = %v", err)
+		}
+		if !sameIDs(result.ExcludedNoCarbonData, "unknown") {
+			t.Errorf("Expected unknown excluded for missing data, got %v", edgeIDs(result.ExcludedNoCarbonData))
+		}
+		for _, point := range result.ParetoFront {
+			if point.CarbonDataMissing {
+				t.Errorf("Expected no front point without carbon data, got %s", point.EdgeID)
+			}
+		}
+
+		// Without any carbon data every edge is still considered
+		result, err = SelectParetoEdge([]EdgeObjective{
+			{EdgeID: "a", RTTMs: 10, CarbonIntensity: 300, MeetsSLO: true, CarbonDataMissing: true},
+			{EdgeID: "b", RTTMs: 20, CarbonIntensity: 300, MeetsSLO: true, CarbonDataMissing: true},
+		}, ObjectiveWeights{})
+		if err != nil {
+			t.Fatalf("SelectParetoEdge() error = %v", err)
+		}
+		if result.ExcludedNoCarbonData != nil || result.Chosen.EdgeID != "a" {
+			t.Errorf("Expected a chosen from edges without data, got %s (excluded %v)", result.Chosen.EdgeID, edgeIDs(result.ExcludedNoCarbonData))
+		}
+	})
+
+	t.Run("no candidates", func(t *testing.T) {
+		if _, err := SelectParetoEdge(nil, ObjectiveWeights{}); !errors.Is(err, ErrNoEdgeCandidates) {
+			t.Errorf("Expected ErrNoEdgeCandidates, got %v", err)
+		}
+	})
+}
+
+func TestEvaluateEdgeObjectives(t *testing.T) {
+	edges := map[string]EdgeLocationInfo{
+		"oslo":   {City: "Oslo", GridZone: "NO-NO1", Latitude: 59.91, Longitude: 10.75},
+		"warsaw": {City: "Warsaw", GridZone: "PL", Latitude: 52.23, Longitude: 21.01},
+	}
+	req := EdgeSelectionRequest{
+		User:                    EdgeSelectionUser{Latitude: 52.52, Longitude: 13.405, GridZone: "DE"},
+		LatencySLOMs:            1000,
+		EgressPricePerGB:        map[string]float64{"oslo": 0.02},
+		DefaultEgressPricePerGB: 0.08,
+	}
+	getIntensity := func(gridZone string) (float64, bool) {
+		if gridZone == "PL" {
+			return 300, false
+		}
+		return 30, true
+	}
+
+	objectives := EvaluateEdgeObjectives(req, edges, DefaultRTTModel, 400, getIntensity)
+	if !sameIDs(objectives, "oslo", "warsaw") {
+		t.Fatalf("Expected objectives sorted by edge ID, got %v", edgeIDs(objectives))
+	}
+	oslo, warsaw := objectives[0], objectives[1]
+	if oslo.EgressPricePerGB != 0.02 || warsaw.EgressPricePerGB != 0.08 {
+		t.Errorf("Expected explicit and default prices, got %v and %v", oslo.EgressPricePerGB, warsaw.EgressPricePerGB)
+	}
+	if oslo.CarbonDataMissing || !warsaw.CarbonDataMissing {
+		t.Errorf("Expected only warsaw to miss carbon data, got %v and %v", oslo.CarbonDataMissing, warsaw.CarbonDataMissing)
+	}
+	expected, _, _ := CalculateWeightedIntensity(400, 30, "")
+	if math.Abs(oslo.CarbonIntensity-expected) > 1e-9 {
+		t.Errorf("Expected weighted intensity %v, got %v", expected, oslo.CarbonIntensity)
+	}
+	if !oslo.MeetsSLO || oslo.RTTMs <= 0 || oslo.RTTP95Ms < oslo.RTTMs {
+		t.Errorf("Expected a positive RTT within the SLO, got %+v", oslo)
+	}
+}
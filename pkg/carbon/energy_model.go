@@ -0,0 +1,52 @@
+package carbon
+
+// RequestProfile describes the resources one request consumes.
+type RequestProfile struct {
+	// Bytes transferred to the user
+	Bytes int64 `json:"bytes" example:"500000"`
+
+	// CPUSeconds of server time spent on the request
+	CPUSeconds float64 `json:"cpu_seconds" example:"0.002"`
+}
+
+// ProfileForContentType returns the typical request profile of a registered content type.
+func ProfileForContentType(contentType string) RequestProfile {
+	weights, exists := DefaultContentTypes.Lookup(contentType)
+	if !exists {
+		weights = DefaultContentWeights
+	}
+	return RequestProfile{Bytes: weights.BytesPerRequest, CPUSeconds: weights.CPUSecondsPerRequest}
+}
+
+// RequestEmissions is the absolute carbon footprint of one request.
+type RequestEmissions struct {
+	// Profile is the request profile the emissions were computed for
+	Profile RequestProfile `json:"profile"`
+
+	// TransmissionGrams is the network transmission footprint, attributed to the user's grid
+	TransmissionGrams float64 `json:"transmission_grams" example:"0.0021"`
+
+	// ComputationGrams is the server footprint including data center overhead, attributed to the edge's grid
+	ComputationGrams float64 `json:"computation_grams" example:"0.0004"`
+
+	// TotalGrams is the sum of transmission and computation
+	TotalGrams float64 `json:"total_grams" example:"0.0025"`
+
+	// ServerEnergyKWh is the server energy including PUE overhead
+	ServerEnergyKWh float64 `json:"server_energy_kwh" example:"0.0000011"`
+
+	// PUE is the data center power usage effectiveness applied
+	PUE float64 `json:"pue" example:"1.6"`
+
+	// NetworkGramsPerGB is the network emission factor applied, scaled to the user's grid
+	NetworkGramsPerGB float64 `json:"network_grams_per_gb" example:"4.1"`
+
+	// Model identifies the energy model
+	Model string `json:"model" example:"greenweb-server-v1"`
+}
+
+// EnergyModel estimates the absolute emissions of a request served from an edge to a user.
+type EnergyModel interface {
+	// EstimateRequestEmissions returns the emissions of one request given both grid zones and intensities
+	EstimateRequestEmissions(profile RequestProfile, userZone, edgeZone string, userIntensity, edgeIntensity float64) RequestEmissions
+}
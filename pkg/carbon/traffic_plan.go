@@ -0,0 +1,411 @@
+package carbon
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultCapacityShares caps the share of a user population's traffic an edge may carry,
+// by EdgeLocationInfo.Capacity. Unknown capacities use the "low" share.
+var DefaultCapacityShares = map[string]float64{
+	"high":   0.5,
+	"medium": 0.3,
+	"low":    0.15,
+}
+
+// TrafficPlanRequest describes a day-ahead traffic-shifting plan for one user population.
+type TrafficPlanRequest struct {
+	// User is the population whose traffic is distributed
+	User EdgeSelectionUser `json:"user"`
+
+	// CDNProvider whose edges are candidates
+	CDNProvider string `json:"cdn_provider" example:"cloudflare"`
+
+	// ContentType weights user and edge intensity (see PredefinedContentWeights)
+	ContentType string `json:"content_type" example:"static"`
+
+	// LatencySLOMs is the maximum acceptable p95 RTT in milliseconds, 0 for none
+	LatencySLOMs float64 `json:"latency_slo_ms" example:"50"`
+
+	// Start is the first hour of the plan, truncated to the hour (default: next full hour)
+	Start time.Time `json:"start,omitempty" example:"2024-01-16T00:00:00Z"`
+
+	// Hours is the number of hourly slots (default 24, max 168)
+	Hours int `json:"hours,omitempty" example:"24"`
+
+	// Edges restricts the candidates to these edge IDs (duplicates are ignored); empty means all
+	// edges of the provider
+	Edges []string `json:"edges,omitempty"`
+
+	// CapacityShares overrides DefaultCapacityShares per capacity class
+	CapacityShares map[string]float64 `json:"capacity_shares,omitempty"`
+
+	// MinSharePercent keeps at least this share on every eligible edge so caches stay warm
+	MinSharePercent float64 `json:"min_share_percent,omitempty" example:"2"`
+}
+
+// TrafficPlanEdge is a candidate edge of a traffic plan.
+type TrafficPlanEdge struct {
+	// EdgeID is the edge location key in the CDN catalog
+	EdgeID string `json:"edge_id" example:"stockholm"`
+
+	// City of the edge
+	City string `json:"city" example:"Stockholm"`
+
+	// GridZone of the edge
+	GridZone string `json:"grid_zone" example:"SE"`
+
+	// Capacity class of the edge
+	Capacity string `json:"capacity" example:"high"`
+
+	// MaxSharePercent is the capacity limit on the edge's share of traffic
+	MaxSharePercent float64 `json:"max_share_percent" example:"50"`
+
+	// RTTP95Ms is the 95th percentile RTT from the user population
+	RTTP95Ms float64 `json:"rtt_p95_ms" example:"38.5"`
+
+	// RTTSource is "model" or "measured"
+	RTTSource string `json:"rtt_source" example:"model"`
+
+	// ExcludedReason is set for edges that receive no traffic in any hour
+	ExcludedReason string `json:"excluded_reason,omitempty" example:"p95 RTT exceeds latency SLO"`
+}
+
+// TrafficPlanHour holds the edge weights for one hour.
+type TrafficPlanHour struct {
+	// Start of the hour
+	Start time.Time `json:"start" example:"2024-01-16T03:00:00Z"`
+
+	// End of the hour
+	End time.Time `json:"end" example:"2024-01-16T04:00:00Z"`
+
+	// Weights is the percentage of traffic per edge ID, summing to 100
+	Weights map[string]int `json:"weights"`
+
+	// EdgeIntensity is the forecast dual-grid weighted intensity per edge ID in g CO2/kWh
+	EdgeIntensity map[string]float64 `json:"edge_intensity"`
+
+	// PlannedIntensity is the traffic-weighted intensity under the plan
+	PlannedIntensity float64 `json:"planned_intensity" example:"112.4"`
+
+	// BaselineIntensity is the traffic-weighted intensity of a static capacity-proportional split
+	BaselineIntensity float64 `json:"baseline_intensity" example:"168.9"`
+
+	// EstimatedZones lists the grid zones without a forecast for this hour, planned at an
+	// estimate such as their current intensity
+	EstimatedZones []string `json:"estimated_zones,omitempty" example:"DE"`
+}
+
+// TrafficPlan is an hourly weight table for shifting traffic between edges.
+type TrafficPlan struct {
+	// Provider is the CDN provider name
+	Provider string `json:"provider" example:"CloudFlare"`
+
+	// UserZone is the grid zone of the user population
+	UserZone string `json:"user_zone" example:"DE"`
+
+	// ContentType used to weight intensities
+	ContentType string `json:"content_type" example:"static"`
+
+	// LatencySLOMs is the p95 latency SLO applied
+	LatencySLOMs float64 `json:"latency_slo_ms" example:"50"`
+
+	// SLOSatisfied is false when no edge met the SLO; the plan then ignores the SLO
+	SLOSatisfied bool `json:"slo_satisfied" example:"true"`
+
+	// CapacityRelaxed is true when the eligible edges' capacity limits summed to less than
+	// 100% and were scaled up proportionally
+	CapacityRelaxed bool `json:"capacity_relaxed" example:"false"`
+
+	// Edges lists every candidate edge with its limits, sorted by edge ID
+	Edges []TrafficPlanEdge `json:"edges"`
+
+	// Hours is the hourly weight table
+	Hours []TrafficPlanHour `json:"hours"`
+
+	// EstimatedHours counts the hours in which at least one zone had no forecast
+	EstimatedHours int `json:"estimated_hours" example:"18"`
+
+	// AveragePlannedIntensity is the mean planned intensity over all hours
+	AveragePlannedIntensity float64 `json:"average_planned_intensity" example:"121.7"`
+
+	// AverageBaselineIntensity is the mean baseline intensity over all hours
+	AverageBaselineIntensity float64 `json:"average_baseline_intensity" example:"170.3"`
+
+	// ReductionPercent is the planned reduction relative to the baseline
+	ReductionPercent float64 `json:"reduction_percent" example:"28.5"`
+
+	// GeneratedAt is when the plan was computed
+	GeneratedAt time.Time `json:"generated_at" example:"2024-01-15T18:05:00Z"`
+}
+
+// HourlyIntensity returns a zone's intensity for the hour starting at hour, and whether a
+// forecast covered that hour. Uncovered hours carry an estimate, such as the current intensity.
+type HourlyIntensity func(gridZone string, hour time.Time) (float64, bool)
+
+// ForecastIntensityAt returns the forecast intensity for the hour containing at, or fallback
+// and false when the forecast has no window covering it. Green-hours forecasts only list
+// low-carbon windows, so the fallback is typically the zone's current intensity.
+func ForecastIntensityAt(forecast *GreenHoursForecast, at time.Time, fallback float64) (float64, bool) {
+	if forecast == nil {
+		return fallback, false
+	}
+	for _, hour := range forecast.GreenHours {
+		end := hour.End
+		if end.IsZero() {
+			end = hour.Start.Add(hour.GetDuration())
+		}
+		if !at.Before(hour.Start) && at.Before(end) {
+			return hour.CarbonIntensity, true
+		}
+	}
+	return fallback, false
+}
+
+// PlanTrafficShift builds an hourly weight table that minimizes the dual-grid weighted
+// intensity of a user population's traffic.
+//
+// Edges whose p95 RTT exceeds the latency SLO are excluded (unless none meet it). Each hour,
+// every eligible edge first receives MinSharePercent, then the remaining traffic fills edges
+// greenest first up to their capacity share. Zones whose intensity for an hour is not
+// forecast are listed in the hour's EstimatedZones.
+func PlanTrafficShift(req TrafficPlanRequest, provider *CDNProvider, rtt RTTModel, intensityAt HourlyIntensity) (*TrafficPlan, error) {
+
+	if req.Hours <= 0 {
+		req.Hours = 24
+	}
+	if req.Hours > 168 {
+		return nil, fmt.Errorf("hours must not exceed 168")
+	}
+	if req.Start.IsZero() {
+		req.Start = time.Now().Truncate(time.Hour).Add(time.Hour)
+	}
+	req.Start = req.Start.Truncate(time.Hour)
+
+	shares := make(map[string]float64, len(DefaultCapacityShares))
+	for capacity, share := range DefaultCapacityShares {
+		shares[capacity] = share
+	}
+	for capacity, share := range req.CapacityShares {
+		if share <= 0 || share > 1 {
+			return nil, fmt.Errorf("capacity share for %s must be in (0, 1]", capacity)
+		}
+		shares[strings.ToLower(capacity)] = share
+	}
+
+	// Listing an edge twice must not double its capacity; copy so the caller's slice is not reordered
+	var edgeIDs []string
+	if len(req.Edges) > 0 {
+		listed := make(map[string]bool, len(req.Edges))
+		for _, edgeID := range req.Edges {
+			if !listed[edgeID] {
+				listed[edgeID] = true
+				edgeIDs = append(edgeIDs, edgeID)
+			}
+		}
+	} else {
+		for edgeID := range provider.EdgeLocations {
+			edgeIDs = append(edgeIDs, edgeID)
+		}
+	}
+	sort.Strings(edgeIDs)
+
+	plan := &TrafficPlan{
+		Provider:     provider.Name,
+		UserZone:     req.User.GridZone,
+		ContentType:  req.ContentType,
+		LatencySLOMs: req.LatencySLOMs,
+		SLOSatisfied: true,
+		GeneratedAt:  time.Now(),
+	}
+
+	for _, edgeID := range edgeIDs {
+		edge, exists := provider.EdgeLocations[edgeID]
+		if !exists {
+			return nil, fmt.Errorf("edge %s not found for provider %s", edgeID, provider.Name)
+		}
+		_, p95, source := EstimateRTTPercentiles(rtt, req.User, edgeID, edge)
+
+		share, exists := shares[strings.ToLower(edge.Capacity)]
+		if !exists {
+			share = shares["low"]
+		}
+
+		planEdge := TrafficPlanEdge{
+			EdgeID:          edgeID,
+			City:            edge.City,
+			GridZone:        edge.GridZone,
+			Capacity:        edge.Capacity,
+			MaxSharePercent: share * 100,
+			RTTP95Ms:        p95,
+			RTTSource:       source,
+		}
+		if req.LatencySLOMs > 0 && p95 > req.LatencySLOMs {
+			planEdge.ExcludedReason = "p95 RTT exceeds latency SLO"
+		}
+		plan.Edges = append(plan.Edges, planEdge)
+	}
+	if len(plan.Edges) == 0 {
+		return nil, ErrNoEdgeCandidates
+	}
+
+	eligible := make([]int, 0, len(plan.Edges))
+	for i, edge := range plan.Edges {
+		if edge.ExcludedReason == "" {
+			eligible = append(eligible, i)
+		}
+	}
+	if len(eligible) == 0 {
+		plan.SLOSatisfied = false
+		for i := range plan.Edges {
+			plan.Edges[i].ExcludedReason = ""
+			eligible = append(eligible, i)
+		}
+	}
+
+	// Capacity limits of the eligible edges must cover all traffic
+	caps := make([]float64, len(eligible))
+	var totalCap float64
+	for i, index := range eligible {
+		caps[i] = plan.Edges[index].MaxSharePercent / 100
+		totalCap += caps[i]
+	}
+	if totalCap < 1 {
+		plan.CapacityRelaxed = true
+		for i, index := range eligible {
+			caps[i] /= totalCap
+			plan.Edges[index].MaxSharePercent = caps[i] * 100
+		}
+		totalCap = 1
+	}
+
+	minShare := req.MinSharePercent / 100
+	if minShare < 0 || minShare*float64(len(eligible)) > 1 {
+		return nil, fmt.Errorf("min_share_percent must be between 0 and %.1f for %d edges", 100/float64(len(eligible)), len(eligible))
+	}
+
+	used := make(map[string]bool)
+	var plannedSum, baselineSum float64
+	for slot := 0; slot < req.Hours; slot++ {
+		start := req.Start.Add(time.Duration(slot) * time.Hour)
+		hour := TrafficPlanHour{
+			Start:         start,
+			End:           start.Add(time.Hour),
+			EdgeIntensity: make(map[string]float64, len(eligible)),
+		}
+		estimated := make(map[string]bool)
+		lookup := func(zone string) float64 {
+			intensity, forecast := intensityAt(zone, start)
+			if !forecast && !estimated[zone] {
+				estimated[zone] = true
+				hour.EstimatedZones = append(hour.EstimatedZones, zone)
+			}
+			return intensity
+		}
+
+		userIntensity := lookup(req.User.GridZone)
+		intensities := make([]float64, len(eligible))
+		for i, index := range eligible {
+			edge := plan.Edges[index]
+			intensities[i], _, _ = CalculateWeightedIntensity(userIntensity, lookup(edge.GridZone), req.ContentType)
+			hour.EdgeIntensity[edge.EdgeID] = math.Round(intensities[i]*10) / 10
+		}
+		sort.Strings(hour.EstimatedZones)
+		if len(hour.EstimatedZones) > 0 {
+			plan.EstimatedHours++
+		}
+
+		// Greedy fill is optimal for a linear objective with per-edge bounds
+		fractions := make([]float64, len(eligible))
+		remaining := 1.0
+		for i := range fractions {
+			fractions[i] = math.Min(minShare, caps[i])
+			remaining -= fractions[i]
+		}
+		order := make([]int, len(eligible))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool { return intensities[order[a]] < intensities[order[b]] })
+		for _, i := range order {
+			if remaining <= 0 {
+				break
+			}
+			add := math.Min(caps[i]-fractions[i], remaining)
+			fractions[i] += add
+			remaining -= add
+		}
+
+		ids := make([]string, len(eligible))
+		for i, index := range eligible {
+			ids[i] = plan.Edges[index].EdgeID
+			hour.PlannedIntensity += fractions[i] * intensities[i]
+			hour.BaselineIntensity += caps[i] / totalCap * intensities[i]
+		}
+		hour.Weights = roundPercentages(ids, fractions)
+		for edgeID, weight := range hour.Weights {
+			if weight > 0 {
+				used[edgeID] = true
+			}
+		}
+
+		hour.PlannedIntensity = math.Round(hour.PlannedIntensity*10) / 10
+		hour.BaselineIntensity = math.Round(hour.BaselineIntensity*10) / 10
+		plannedSum += hour.PlannedIntensity
+		baselineSum += hour.BaselineIntensity
+		plan.Hours = append(plan.Hours, hour)
+	}
+
+	for i := range plan.Edges {
+		if plan.Edges[i].ExcludedReason == "" && !used[plan.Edges[i].EdgeID] {
+			plan.Edges[i].ExcludedReason = "never among the greenest edges within capacity"
+		}
+	}
+
+	plan.AveragePlannedIntensity = math.Round(plannedSum/float64(req.Hours)*10) / 10
+	plan.AverageBaselineIntensity = math.Round(baselineSum/float64(req.Hours)*10) / 10
+	if plan.AverageBaselineIntensity > 0 {
+		plan.ReductionPercent = math.Round((1-plan.AveragePlannedIntensity/plan.AverageBaselineIntensity)*1000) / 10
+	}
+
+	return plan, nil
+}
+
+// WeightsAt returns the plan's weights for the hour containing at, or nil outside the plan.
+func (p *TrafficPlan) WeightsAt(at time.Time) map[string]int {
+	for _, hour := range p.Hours {
+		if !at.Before(hour.Start) && at.Before(hour.End) {
+			return hour.Weights
+		}
+	}
+	return nil
+}
+
+// roundPercentages converts fractions summing to 1 into integer percentages summing to 100
+// using the largest remainder method.
+func roundPercentages(ids []string, fractions []float64) map[string]int {
+	weights := make(map[string]int, len(ids))
+	remainders := make([]float64, len(ids))
+	total := 0
+	for i, id := range ids {
+		exact := fractions[i] * 100
+		weights[id] = int(math.Floor(exact + 1e-9))
+		remainders[i] = exact - float64(weights[id])
+		total += weights[id]
+	}
+
+	order := make([]int, len(ids))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+	for i := 0; total < 100 && i < len(order); i++ {
+		weights[ids[order[i]]]++
+		total++
+	}
+
+	return weights
+}
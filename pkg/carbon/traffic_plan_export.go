@@ -0,0 +1,202 @@
+package carbon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Traffic plan export formats
+const (
+	TrafficPlanFormatJSON    = "json"
+	TrafficPlanFormatNginx   = "nginx"
+	TrafficPlanFormatHAProxy = "haproxy"
+	TrafficPlanFormatEnvoy   = "envoy"
+	TrafficPlanFormatRoute53 = "route53"
+)
+
+// TrafficPlanFormats lists the supported export formats
+var TrafficPlanFormats = []string{
+	TrafficPlanFormatJSON,
+	TrafficPlanFormatNginx,
+	TrafficPlanFormatHAProxy,
+	TrafficPlanFormatEnvoy,
+	TrafficPlanFormatRoute53,
+}
+
+// TrafficPlanExportOptions controls how a traffic plan is rendered as load balancer config.
+type TrafficPlanExportOptions struct {
+	// Pool is the upstream, backend, cluster or record name (default "greenweb_edges")
+	Pool string `json:"pool,omitempty" example:"static_assets"`
+
+	// Targets maps edge IDs to addresses (host:port, or hostnames for Route 53); defaults to the edge ID
+	Targets map[string]string `json:"targets,omitempty"`
+
+	// At limits the export to the hour containing this time; zero exports every hour
+	At time.Time `json:"at,omitempty" example:"2024-01-16T03:30:00Z"`
+}
+
+// ExportTrafficPlan renders a traffic plan in one of TrafficPlanFormats. Load balancer formats
+// emit one snippet per hour, each preceded by a comment (or JSON field) with its time window,
+// so a scheduler can apply the snippet for the current hour.
+func ExportTrafficPlan(plan *TrafficPlan, format string, options TrafficPlanExportOptions) ([]byte, error) {
+	if options.Pool == "" {
+		options.Pool = "greenweb_edges"
+	}
+
+	hours := plan.Hours
+	if !options.At.IsZero() {
+		hours = nil
+		for _, hour := range plan.Hours {
+			if !options.At.Before(hour.Start) && options.At.Before(hour.End) {
+				hours = []TrafficPlanHour{hour}
+			}
+		}
+		if hours == nil {
+			return nil, fmt.Errorf("%s is outside the plan", options.At.Format(time.RFC3339))
+		}
+	}
+
+	target := func(edgeID string) string {
+		if address, exists := options.Targets[edgeID]; exists && address != "" {
+			return address
+		}
+		return edgeID
+	}
+
+	switch strings.ToLower(format) {
+	case TrafficPlanFormatJSON, "":
+		if !options.At.IsZero() {
+			filtered := *plan
+			filtered.Hours = hours
+			return json.MarshalIndent(&filtered, "", "  ")
+		}
+		return json.MarshalIndent(plan, "", "  ")
+
+	case TrafficPlanFormatNginx:
+		var b strings.Builder
+		for _, hour := range hours {
+			writeHourComment(&b, plan, hour)
+			fmt.Fprintf(&b, "upstream %s {\n", options.Pool)
+			for _, edgeID := range sortedEdgeIDs(hour.Weights) {
+				if weight := hour.Weights[edgeID]; weight > 0 {
+					fmt.Fprintf(&b, "    server %s weight=%d;\n", target(edgeID), weight)
+				} else {
+					fmt.Fprintf(&b, "    server %s down;\n", target(edgeID))
+				}
+			}
+			b.WriteString("}\n\n")
+		}
+		return []byte(b.String()), nil
+
+	case TrafficPlanFormatHAProxy:
+		var b strings.Builder
+		for _, hour := range hours {
+			writeHourComment(&b, plan, hour)
+			fmt.Fprintf(&b, "backend %s\n    balance roundrobin\n", options.Pool)
+			for _, edgeID := range sortedEdgeIDs(hour.Weights) {
+				fmt.Fprintf(&b, "    server %s %s weight %d check\n", edgeID, target(edgeID), hour.Weights[edgeID])
+			}
+			b.WriteString("\n")
+		}
+		return []byte(b.String()), nil
+
+	case TrafficPlanFormatEnvoy:
+		var b strings.Builder
+		for _, hour := range hours {
+			writeHourComment(&b, plan, hour)
+			fmt.Fprintf(&b, "cluster_name: %s\nendpoints:\n- lb_endpoints:\n", options.Pool)
+			for _, edgeID := range sortedEdgeIDs(hour.Weights) {
+				// Envoy requires weights of at least 1; drained edges are left out
+				weight := hour.Weights[edgeID]
+				if weight == 0 {
+					continue
+				}
+				host, port := splitHostPort(target(edgeID))
+				fmt.Fprintf(&b, "  - endpoint:\n      address:\n        socket_address: { address: %s, port_value: %s }\n    load_balancing_weight: %d\n", host, port, weight)
+			}
+			b.WriteString("---\n")
+		}
+		return []byte(b.String()), nil
+
+	case TrafficPlanFormatRoute53:
+		type resourceRecord struct {
+			Value string `json:"Value"`
+		}
+		type recordSet struct {
+			Name            string           `json:"Name"`
+			Type            string           `json:"Type"`
+			SetIdentifier   string           `json:"SetIdentifier"`
+			Weight          int              `json:"Weight"`
+			TTL             int              `json:"TTL"`
+			ResourceRecords []resourceRecord `json:"ResourceRecords"`
+		}
+		type change struct {
+			Action            string    `json:"Action"`
+			ResourceRecordSet recordSet `json:"ResourceRecordSet"`
+		}
+		type changeBatch struct {
+			Comment string   `json:"Comment"`
+			Changes []change `json:"Changes"`
+		}
+
+		batches := make([]changeBatch, 0, len(hours))
+		for _, hour := range hours {
+			batch := changeBatch{Comment: hourComment(plan, hour)}
+			for _, edgeID := range sortedEdgeIDs(hour.Weights) {
+				batch.Changes = append(batch.Changes, change{
+					Action: "UPSERT",
+					ResourceRecordSet: recordSet{
+						Name:            options.Pool,
+						Type:            "CNAME",
+						SetIdentifier:   edgeID,
+						Weight:          hour.Weights[edgeID],
+						TTL:             60,
+						ResourceRecords: []resourceRecord{{Value: target(edgeID)}},
+					},
+				})
+			}
+			batches = append(batches, batch)
+		}
+		if len(batches) == 1 {
+			return json.MarshalIndent(batches[0], "", "  ")
+		}
+		return json.MarshalIndent(batches, "", "  ")
+	}
+
+	return nil, fmt.Errorf("unsupported traffic plan format %q (supported: %s)", format, strings.Join(TrafficPlanFormats, ", "))
+}
+
+// hourComment describes the time window and intensity of a plan hour
+func hourComment(plan *TrafficPlan, hour TrafficPlanHour) string {
+	return fmt.Sprintf("GreenWeb traffic plan %s users via %s, %s to %s, planned %.1f g/kWh (baseline %.1f)",
+		plan.UserZone, plan.Provider,
+		hour.Start.UTC().Format(time.RFC3339), hour.End.UTC().Format(time.RFC3339),
+		hour.PlannedIntensity, hour.BaselineIntensity)
+}
+
+// writeHourComment writes hourComment as a # comment line
+func writeHourComment(b *strings.Builder, plan *TrafficPlan, hour TrafficPlanHour) {
+	fmt.Fprintf(b, "# %s\n", hourComment(plan, hour))
+}
+
+// sortedEdgeIDs returns the edge IDs of a weight map in a stable order
+func sortedEdgeIDs(weights map[string]int) []string {
+	ids := make([]string, 0, len(weights))
+	for edgeID := range weights {
+		ids = append(ids, edgeID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// splitHostPort splits host:port, defaulting the port to 443
+func splitHostPort(address string) (string, string) {
+	if host, port, err := net.SplitHostPort(address); err == nil {
+		return host, port
+	}
+	return strings.Trim(address, "[]"), "443"
+}
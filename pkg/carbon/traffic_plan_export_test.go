@@ -0,0 +1,115 @@
+package carbon
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testExportPlan() *TrafficPlan {
+	start := time.Date(2024, 1, 16, 3, 0, 0, 0, time.UTC)
+	return &TrafficPlan{
+		Provider: "TestCDN",
+		UserZone: "DE",
+		Hours: []TrafficPlanHour{
+			{Start: start, End: start.Add(time.Hour), Weights: map[string]int{"green": 100, "dirty": 0}, PlannedIntensity: 20, BaselineIntensity: 210},
+			{Start: start.Add(time.Hour), End: start.Add(2 * time.Hour), Weights: map[string]int{"green": 60, "dirty": 40}, PlannedIntensity: 172, BaselineIntensity: 210},
+		},
+	}
+}
+
+func TestExportTrafficPlan(t *testing.T) {
+	options := TrafficPlanExportOptions{
+		Pool:    "assets",
+		Targets: map[string]string{"green": "10.0.0.1:8443", "dirty": "[2001:db8::1]:443"},
+	}
+
+	tests := []struct {
+		name     string
+		format   string
+		contains []string
+		excludes []string
+	}{
+		{"nginx", TrafficPlanFormatNginx,
+			[]string{"# GreenWeb traffic plan DE users via TestCDN, 2024-01-16T03:00:00Z to 2024-01-16T04:00:00Z", "upstream assets {",
+				"server 10.0.0.1:8443 weight=100;", "server [2001:db8::1]:443 down;", "server [2001:db8::1]:443 weight=40;"},
+			nil},
+		{"haproxy", TrafficPlanFormatHAProxy,
+			[]string{"backend assets\n    balance roundrobin", "server dirty [2001:db8::1]:443 weight 0 check", "server green 10.0.0.1:8443 weight 60 check"},
+			nil},
+		{"envoy", "ENVOY",
+			[]string{"cluster_name: assets", "socket_address: { address: 10.0.0.1, port_value: 8443 }", "socket_address: { address: 2001:db8::1, port_value: 443 }\n    load_balancing_weight: 40"},
+			[]string{"load_balancing_weight: 0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := ExportTrafficPlan(testExportPlan(), tt.format, options)
+			if err != nil {
+				t.Fatalf("ExportTrafficPlan() error = %v", err)
+			}
+			for _, expected := range tt.contains {
+				if !strings.Contains(string(body), expected) {
+					t.Errorf("Expected %q in:\n%s", expected, body)
+				}
+			}
+			for _, unexpected := range tt.excludes {
+				if strings.Contains(string(body), unexpected) {
+					t.Errorf("Expected no %q in:\n%s", unexpected, body)
+				}
+			}
+		})
+	}
+}
+
+func TestExportTrafficPlanAt(t *testing.T) {
+	plan := testExportPlan()
+	at := plan.Hours[1].Start.Add(30 * time.Minute)
+
+	body, err := ExportTrafficPlan(plan, TrafficPlanFormatJSON, TrafficPlanExportOptions{At: at})
+	if err != nil {
+		t.Fatalf("ExportTrafficPlan() error = %v", err)
+	}
+	var exported TrafficPlan
+	if err := json.Unmarshal(body, &exported); err != nil {
+		t.Fatalf("Expected a JSON plan, got %v", err)
+	}
+	if len(exported.Hours) != 1 || exported.Hours[0].Weights["dirty"] != 40 {
+		t.Errorf("Expected only the second hour, got %+v", exported.Hours)
+	}
+	if len(plan.Hours) != 2 {
+		t.Error("Expected the plan itself to keep every hour")
+	}
+
+	// Route 53 emits a single change batch for one hour, with the default pool name
+	body, err = ExportTrafficPlan(plan, TrafficPlanFormatRoute53, TrafficPlanExportOptions{At: at})
+	if err != nil {
+		t.Fatalf("ExportTrafficPlan() error = %v", err)
+	}
+	var batch struct {
+		Changes []struct {
+			ResourceRecordSet struct {
+				Name          string
+				SetIdentifier string
+				Weight        int
+			}
+		}
+	}
+	if err := json.Unmarshal(body, &batch); err != nil {
+		t.Fatalf("Expected one change batch, got %v", err)
+	}
+	if len(batch.Changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %d", len(batch.Changes))
+	}
+	if record := batch.Changes[0].ResourceRecordSet; record.Name != "greenweb_edges" || record.SetIdentifier != "dirty" || record.Weight != 40 {
+		t.Errorf("Expected dirty at weight 40 in greenweb_edges, got %+v", record)
+	}
+
+	if _, err := ExportTrafficPlan(plan, TrafficPlanFormatNginx, TrafficPlanExportOptions{At: at.Add(24 * time.Hour)}); err == nil {
+		t.Error("Expected an error for a time outside the plan")
+	}
+	if _, err := ExportTrafficPlan(plan, "caddy", TrafficPlanExportOptions{}); err == nil || !strings.Contains(err.Error(), "unsupported traffic plan format") {
+		t.Errorf("Expected an unsupported format error, got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package carbon
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTrafficProvider has a green, a medium and a dirty edge for users in Germany
+var testTrafficProvider = &CDNProvider{
+	Name: "TestCDN",
+	EdgeLocations: map[string]EdgeLocationInfo{
+		"green": {City: "Stockholm", GridZone: "SE", Capacity: "high"},
+		"mid":   {City: "Paris", GridZone: "FR", Capacity: "low"},
+		"dirty": {City: "Frankfurt", GridZone: "DE", Capacity: "high"},
+	},
+}
+
+var testTrafficRTT = RTTTable{Measurements: map[string]float64{"green": 30, "mid": 20, "dirty": 10}}
+
+// testTrafficIntensity forecasts SE and DE; FR is never forecast
+func testTrafficIntensity(gridZone string, hour time.Time) (float64, bool) {
+	switch gridZone {
+	case "SE":
+		return 20, true
+	case "DE":
+		return 400, true
+	}
+	return 100, false
+}
+
+func TestPlanTrafficShift(t *testing.T) {
+	start := time.Date(2024, 1, 16, 0, 30, 0, 0, time.UTC)
+
+	plan, err := PlanTrafficShift(TrafficPlanRequest{
+		User:  EdgeSelectionUser{GridZone: "DE"},
+		Start: start,
+		Hours: 3,
+	}, testTrafficProvider, testTrafficRTT, testTrafficIntensity)
+	if err != nil {
+		t.Fatalf("PlanTrafficShift() error = %v", err)
+	}
+
+	if len(plan.Hours) != 3 || !plan.Hours[0].Start.Equal(start.Truncate(time.Hour)) {
+		t.Fatalf("Expected 3 hours from the start of the hour, got %d from %s", len(plan.Hours), plan.Hours[0].Start)
+	}
+	if plan.CapacityRelaxed || !plan.SLOSatisfied {
+		t.Errorf("Expected no relaxed capacity and a satisfied SLO, got %v and %v", plan.CapacityRelaxed, plan.SLOSatisfied)
+	}
+
+	// Greenest first up to capacity: green 50%, mid 15%, the rest on dirty
+	hour := plan.Hours[0]
+	expected := map[string]int{"green": 50, "mid": 15, "dirty": 35}
+	for edgeID, weight := range expected {
+		if hour.Weights[edgeID] != weight {
+			t.Errorf("Expected %s at %d%%, got %d%%", edgeID, weight, hour.Weights[edgeID])
+		}
+	}
+	if hour.PlannedIntensity >= hour.BaselineIntensity || plan.ReductionPercent <= 0 {
+		t.Errorf("Expected the plan to beat the baseline, got %v vs %v (%v%%)", hour.PlannedIntensity, hour.BaselineIntensity, plan.ReductionPercent)
+	}
+	if len(hour.EstimatedZones) != 1 || hour.EstimatedZones[0] != "FR" || plan.EstimatedHours != 3 {
+		t.Errorf("Expected FR estimated in all 3 hours, got %v and %d", hour.EstimatedZones, plan.EstimatedHours)
+	}
+	if weights := plan.WeightsAt(start.Add(2 * time.Hour)); weights == nil || weights["green"] != 50 {
+		t.Errorf("Expected the weights of the last hour, got %v", weights)
+	}
+	if weights := plan.WeightsAt(start.Add(4 * time.Hour)); weights != nil {
+		t.Errorf("Expected no weights outside the plan, got %v", weights)
+	}
+}
+
+func TestPlanTrafficShiftEdges(t *testing.T) {
+	start := time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)
+
+	t.Run("duplicate edge IDs", func(t *testing.T) {
+		edges := []string{"mid", "green", "green"}
+		plan, err := PlanTrafficShift(TrafficPlanRequest{
+			User:  EdgeSelectionUser{GridZone: "DE"},
+			Start: start,
+			Hours: 1,
+			Edges: edges,
+		}, testTrafficProvider, testTrafficRTT, testTrafficIntensity)
+		if err != nil {
+			t.Fatalf("PlanTrafficShift() error = %v", err)
+		}
+		if len(plan.Edges) != 2 {
+			t.Fatalf("Expected 2 distinct edges, got %d", len(plan.Edges))
+		}
+		// 50% + 15% must be scaled up, not covered by counting green twice
+		if !plan.CapacityRelaxed || plan.Hours[0].Weights["green"] != 77 || plan.Hours[0].Weights["mid"] != 23 {
+			t.Errorf("Expected relaxed capacity with green 77%% and mid 23%%, got %v", plan.Hours[0].Weights)
+		}
+		if edges[0] != "mid" || edges[1] != "green" {
+			t.Errorf("Expected the request's edges to be left in order, got %v", edges)
+		}
+	})
+
+	t.Run("latency SLO", func(t *testing.T) {
+		plan, err := PlanTrafficShift(TrafficPlanRequest{
+			User:         EdgeSelectionUser{GridZone: "DE"},
+			Start:        start,
+			Hours:        1,
+			LatencySLOMs: 25,
+		}, testTrafficProvider, testTrafficRTT, testTrafficIntensity)
+		if err != nil {
+			t.Fatalf("PlanTrafficShift() error = %v", err)
+		}
+		for _, edge := range plan.Edges {
+			if excluded := edge.ExcludedReason != ""; excluded != (edge.EdgeID == "green") {
+				t.Errorf("Expected only green excluded by the SLO, got %s excluded: %q", edge.EdgeID, edge.ExcludedReason)
+			}
+		}
+		if weight, exists := plan.Hours[0].Weights["green"]; exists {
+			t.Errorf("Expected no weight for green, got %d", weight)
+		}
+
+		plan, err = PlanTrafficShift(TrafficPlanRequest{
+			User:         EdgeSelectionUser{GridZone: "DE"},
+			Start:        start,
+			Hours:        1,
+			LatencySLOMs: 5,
+		}, testTrafficProvider, testTrafficRTT, testTrafficIntensity)
+		if err != nil {
+			t.Fatalf("PlanTrafficShift() error = %v", err)
+		}
+		if plan.SLOSatisfied || plan.Hours[0].Weights["green"] != 50 {
+			t.Errorf("Expected the SLO to be ignored when no edge meets it, got %v and %v", plan.SLOSatisfied, plan.Hours[0].Weights)
+		}
+	})
+
+	t.Run("minimum share", func(t *testing.T) {
+		plan, err := PlanTrafficShift(TrafficPlanRequest{
+			User:            EdgeSelectionUser{GridZone: "DE"},
+			Start:           start,
+			Hours:           1,
+			Edges:           []string{"green", "dirty"},
+			MinSharePercent: 10,
+		}, testTrafficProvider, testTrafficRTT, testTrafficIntensity)
+		if err != nil {
+			t.Fatalf("PlanTrafficShift() error = %v", err)
+		}
+		if plan.Hours[0].Weights["green"] != 50 || plan.Hours[0].Weights["dirty"] != 50 {
+			t.Errorf("Expected green and dirty at capacity, got %v", plan.Hours[0].Weights)
+		}
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			req     TrafficPlanRequest
+			problem string
+		}{
+			{"too many hours", TrafficPlanRequest{Hours: 169}, "hours must not exceed 168"},
+			{"unknown edge", TrafficPlanRequest{Edges: []string{"mars"}}, "edge mars not found"},
+			{"capacity share out of range", TrafficPlanRequest{CapacityShares: map[string]float64{"high": 1.5}}, "capacity share for high"},
+			{"minimum share too large", TrafficPlanRequest{MinSharePercent: 40}, "min_share_percent must be between 0 and 33.3"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := PlanTrafficShift(tt.req, testTrafficProvider, testTrafficRTT, testTrafficIntensity)
+				if err == nil || !strings.Contains(err.Error(), tt.problem) {
+					t.Errorf("Expected error containing %q, got %v", tt.problem, err)
+				}
+			})
+		}
+	})
+}
+
+func TestRoundPercentages(t *testing.T) {
+	weights := roundPercentages([]string{"a", "b", "c"}, []float64{1.0 / 3, 1.0 / 3, 1.0 / 3})
+	total := 0
+	for _, weight := range weights {
+		total += weight
+	}
+	if total != 100 || weights["a"] != 34 || weights["b"] != 33 || weights["c"] != 33 {
+		t.Errorf("Expected 34/33/33, got %v", weights)
+	}
+}
@@ -0,0 +1,292 @@
+package optimization
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ProfileTokenAlgorithm is the JWS algorithm used for signed profile tokens.
+const ProfileTokenAlgorithm = "EdDSA"
+
+// ProfileTokenHeader is the HTTP header edge workers use to forward a signed profile token.
+const ProfileTokenHeader = "X-GreenWeb-Profile-Token"
+
+// ProfileTokenCookie is the cookie name edge workers may use to persist a signed profile token.
+const ProfileTokenCookie = "greenweb_profile"
+
+var (
+	// ErrInvalidProfileToken is returned when a token is malformed or its signature does not verify.
+	ErrInvalidProfileToken = errors.New("invalid profile token")
+
+	// ErrProfileTokenExpired is returned when a token's profile has passed ValidUntil.
+	ErrProfileTokenExpired = errors.New("profile token expired")
+
+	// ErrUnknownProfileKey is returned when a token references a key ID the verifier does not know.
+	ErrUnknownProfileKey = errors.New("unknown profile token key")
+)
+
+// ProfileTokenClaims is the payload of a signed optimization profile token.
+type ProfileTokenClaims struct {
+	// Zone is the grid zone the profile was generated for
+	Zone string `json:"zone" example:"DE"`
+
+	// Profile is the signed optimization profile
+	Profile *OptimizationProfile `json:"profile"`
+
+	// IssuedAt is the Unix time the token was issued
+	IssuedAt int64 `json:"iat" example:"1705329000"`
+
+	// ExpiresAt is the Unix time the token expires, equal to the profile's ValidUntil
+	ExpiresAt int64 `json:"exp" example:"1705329900"`
+}
+
+// IsExpired returns true if the token or the profile it carries has expired.
+func (c *ProfileTokenClaims) IsExpired() bool {
+	if c.Profile == nil || c.Profile.IsExpired() {
+		return true
+	}
+	return time.Now().Unix() >= c.ExpiresAt
+}
+
+// JSONWebKey is a public Ed25519 key in JWK format (RFC 8037).
+type JSONWebKey struct {
+	// KeyType is always "OKP" for Ed25519 keys
+	KeyType string `json:"kty" example:"OKP"`
+
+	// Curve is always "Ed25519"
+	Curve string `json:"crv" example:"Ed25519"`
+
+	// X is the base64url-encoded public key
+	X string `json:"x" example:"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"`
+
+	// KeyID identifies the key in token headers
+	KeyID string `json:"kid" example:"greenweb-2024-01"`
+
+	// Use is always "sig"
+	Use string `json:"use" example:"sig"`
+
+	// Algorithm is always "EdDSA"
+	Algorithm string `json:"alg" example:"EdDSA"`
+}
+
+// JSONWebKeySet is a set of public keys published for token verification.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// tokenHeader is the protected JWS header of a profile token.
+type tokenHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// ProfileSigner issues compact Ed25519 JWS tokens for optimization profiles.
+type ProfileSigner struct {
+	privateKey ed25519.PrivateKey
+	keyID      string
+}
+
+// NewProfileSigner creates a signer from an Ed25519 private key and the key ID published in the JWKS.
+func NewProfileSigner(privateKey ed25519.PrivateKey, keyID string) (*ProfileSigner, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid Ed25519 private key size: %d", len(privateKey))
+	}
+	if keyID == "" {
+		return nil, errors.New("key ID is required")
+	}
+	return &ProfileSigner{privateKey: privateKey, keyID: keyID}, nil
+}
+
+// NewProfileSignerFromSeed creates a signer from a base64-encoded 32-byte Ed25519 seed.
+func NewProfileSignerFromSeed(encodedSeed, keyID string) (*ProfileSigner, error) {
+	seed, err := base64.StdEncoding.DecodeString(encodedSeed)
+	if err != nil {
+		seed, err = base64.RawURLEncoding.DecodeString(encodedSeed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode signing key seed: %w", err)
+		}
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid Ed25519 seed size: %d", len(seed))
+	}
+	return NewProfileSigner(ed25519.NewKeyFromSeed(seed), keyID)
+}
+
+// GenerateProfileSigner creates a signer with a freshly generated key.
+// Tokens signed by it cannot be verified after a restart, so it is intended for development.
+func GenerateProfileSigner(keyID string) (*ProfileSigner, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	return NewProfileSigner(privateKey, keyID)
+}
+
+// KeyID returns the ID of the signing key.
+func (s *ProfileSigner) KeyID() string {
+	return s.keyID
+}
+
+// PublicKey returns the JWK of the signing key's public half.
+func (s *ProfileSigner) PublicKey() JSONWebKey {
+	return JSONWebKey{
+		KeyType:   "OKP",
+		Curve:     "Ed25519",
+		X:         base64.RawURLEncoding.EncodeToString(s.privateKey.Public().(ed25519.PublicKey)),
+		KeyID:     s.keyID,
+		Use:       "sig",
+		Algorithm: ProfileTokenAlgorithm,
+	}
+}
+
+// JWKS returns the key set that verifiers need to validate tokens from this signer.
+func (s *ProfileSigner) JWKS() JSONWebKeySet {
+	return JSONWebKeySet{Keys: []JSONWebKey{s.PublicKey()}}
+}
+
+// Sign issues a compact JWS token for the profile and zone. The token expires at the profile's ValidUntil.
+func (s *ProfileSigner) Sign(profile *OptimizationProfile, zone string) (string, *ProfileTokenClaims, error) {
+	if profile == nil {
+		return "", nil, errors.New("profile is required")
+	}
+	if profile.ValidUntil.IsZero() || profile.IsExpired() {
+		return "", nil, errors.New("profile must have a future ValidUntil")
+	}
+
+	claims := &ProfileTokenClaims{
+		Zone:      zone,
+		Profile:   profile,
+		IssuedAt:  time.Now().Unix(),
+		ExpiresAt: profile.ValidUntil.Unix(),
+	}
+
+	header, err := json.Marshal(tokenHeader{Algorithm: ProfileTokenAlgorithm, Type: "JWT", KeyID: s.keyID})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode token header: %w", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode token claims: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature := ed25519.Sign(s.privateKey, []byte(signingInput))
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), claims, nil
+}
+
+// ProfileVerifier validates signed profile tokens offline against a set of trusted public keys.
+type ProfileVerifier struct {
+	keys map[string]ed25519.PublicKey
+}
+
+// NewProfileVerifier creates a verifier trusting the Ed25519 keys in the key set.
+func NewProfileVerifier(keySet JSONWebKeySet) (*ProfileVerifier, error) {
+	verifier := &ProfileVerifier{keys: make(map[string]ed25519.PublicKey)}
+	for _, key := range keySet.Keys {
+		if key.KeyType != "OKP" || key.Curve != "Ed25519" {
+			continue
+		}
+		publicKey, err := base64.RawURLEncoding.DecodeString(key.X)
+		if err != nil || len(publicKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid public key %q", key.KeyID)
+		}
+		verifier.keys[key.KeyID] = ed25519.PublicKey(publicKey)
+	}
+	if len(verifier.keys) == 0 {
+		return nil, errors.New("key set contains no Ed25519 keys")
+	}
+	return verifier, nil
+}
+
+// NewProfileVerifierFromJWKS creates a verifier from a JSON-encoded key set, e.g. the body of the JWKS endpoint.
+func NewProfileVerifierFromJWKS(data []byte) (*ProfileVerifier, error) {
+	var keySet JSONWebKeySet
+	if err := json.Unmarshal(data, &keySet); err != nil {
+		return nil, fmt.Errorf("failed to decode key set: %w", err)
+	}
+	return NewProfileVerifier(keySet)
+}
+
+// Verify checks the token signature and expiry and returns its claims.
+// Expired tokens return the decoded claims together with ErrProfileTokenExpired.
+func (v *ProfileVerifier) Verify(token string) (*ProfileTokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidProfileToken
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidProfileToken
+	}
+	var header tokenHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil || header.Algorithm != ProfileTokenAlgorithm {
+		return nil, ErrInvalidProfileToken
+	}
+
+	publicKey, exists := v.keys[header.KeyID]
+	if !exists {
+		return nil, ErrUnknownProfileKey
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !ed25519.Verify(publicKey, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrInvalidProfileToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidProfileToken
+	}
+	var claims ProfileTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Profile == nil {
+		return nil, ErrInvalidProfileToken
+	}
+
+	if claims.IsExpired() {
+		return &claims, ErrProfileTokenExpired
+	}
+
+	return &claims, nil
+}
+
+// profileContextKey is the context key for verified profile token claims.
+type profileContextKey struct{}
+
+// ProfileClaimsFromContext returns the verified claims stored by ProfileVerifier.Middleware, if any.
+func ProfileClaimsFromContext(ctx context.Context) (*ProfileTokenClaims, bool) {
+	claims, ok := ctx.Value(profileContextKey{}).(*ProfileTokenClaims)
+	return claims, ok
+}
+
+// Middleware verifies a profile token from ProfileTokenHeader or ProfileTokenCookie and stores
+// the claims in the request context. Requests without a valid, unexpired token pass through
+// unchanged so the edge can fall back to its default behaviour or refresh the token.
+func (v *ProfileVerifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(ProfileTokenHeader)
+		if token == "" {
+			if cookie, err := r.Cookie(ProfileTokenCookie); err == nil {
+				token = cookie.Value
+			}
+		}
+
+		if token != "" {
+			if claims, err := v.Verify(token); err == nil {
+				r = r.WithContext(context.WithValue(r.Context(), profileContextKey{}, claims))
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
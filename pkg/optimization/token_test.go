@@ -0,0 +1,245 @@
+package optimization
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSeed is a fixed Ed25519 seed so tokens are reproducible across signers
+var testSeed = base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+
+func newTestSigner(t *testing.T, keyID string) *ProfileSigner {
+	t.Helper()
+	signer, err := NewProfileSignerFromSeed(testSeed, keyID)
+	if err != nil {
+		t.Fatalf("NewProfileSignerFromSeed() error = %v", err)
+	}
+	return signer
+}
+
+func newTestVerifier(t *testing.T, signers ...*ProfileSigner) *ProfileVerifier {
+	t.Helper()
+	var keySet JSONWebKeySet
+	for _, signer := range signers {
+		keySet.Keys = append(keySet.Keys, signer.PublicKey())
+	}
+	verifier, err := NewProfileVerifier(keySet)
+	if err != nil {
+		t.Fatalf("NewProfileVerifier() error = %v", err)
+	}
+	return verifier
+}
+
+// signRaw signs arbitrary header and claims, bypassing the checks in Sign
+func signRaw(t *testing.T, signer *ProfileSigner, header tokenHeader, claims ProfileTokenClaims) string {
+	t.Helper()
+	headerJSON, _ := json.Marshal(header)
+	claimsJSON, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(signer.privateKey, []byte(signingInput)))
+}
+
+func testProfile(validFor time.Duration) *OptimizationProfile {
+	return &OptimizationProfile{Mode: ModeEco, ValidUntil: time.Now().Add(validFor)}
+}
+
+func TestProfileSignerFromSeed(t *testing.T) {
+	seed := []byte("0123456789abcdef0123456789abcdef")
+
+	tests := []struct {
+		name    string
+		seed    string
+		keyID   string
+		wantErr bool
+	}{
+		{"standard base64", base64.StdEncoding.EncodeToString(seed), "key-1", false},
+		{"raw URL base64", base64.RawURLEncoding.EncodeToString(seed), "key-1", false},
+		{"short seed", base64.StdEncoding.EncodeToString(seed[:16]), "key-1", true},
+		{"not base64", "not base64!", "key-1", true},
+		{"missing key ID", base64.StdEncoding.EncodeToString(seed), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewProfileSignerFromSeed(tt.seed, tt.keyID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewProfileSignerFromSeed() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && signer.PublicKey().X != newTestSigner(t, "key-1").PublicKey().X {
+				t.Error("Expected the same public key from both seed encodings")
+			}
+		})
+	}
+}
+
+func TestProfileSignAndVerify(t *testing.T) {
+	signer := newTestSigner(t, "key-1")
+	other, err := GenerateProfileSigner("key-2")
+	if err != nil {
+		t.Fatalf("GenerateProfileSigner() error = %v", err)
+	}
+	verifier := newTestVerifier(t, signer)
+
+	valid, _, err := signer.Sign(testProfile(time.Hour), "DE")
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	parts := strings.Split(valid, ".")
+
+	// Same key ID, different key
+	impostor, err := GenerateProfileSigner("key-1")
+	if err != nil {
+		t.Fatalf("GenerateProfileSigner() error = %v", err)
+	}
+	forged, _, _ := impostor.Sign(testProfile(time.Hour), "DE")
+	fromOther, _, _ := other.Sign(testProfile(time.Hour), "DE")
+
+	tamperedClaims, _ := json.Marshal(ProfileTokenClaims{Zone: "SE", Profile: testProfile(time.Hour), ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString(tamperedClaims) + "." + parts[2]
+
+	expiredProfile := &OptimizationProfile{Mode: ModeEco, ValidUntil: time.Now().Add(-time.Minute)}
+	expired := signRaw(t, signer, tokenHeader{Algorithm: ProfileTokenAlgorithm, Type: "JWT", KeyID: "key-1"},
+		ProfileTokenClaims{Zone: "DE", Profile: expiredProfile, ExpiresAt: expiredProfile.ValidUntil.Unix()})
+	pastExpiry := signRaw(t, signer, tokenHeader{Algorithm: ProfileTokenAlgorithm, Type: "JWT", KeyID: "key-1"},
+		ProfileTokenClaims{Zone: "DE", Profile: testProfile(time.Hour), ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+	wrongAlgorithm := signRaw(t, signer, tokenHeader{Algorithm: "none", Type: "JWT", KeyID: "key-1"},
+		ProfileTokenClaims{Zone: "DE", Profile: testProfile(time.Hour), ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	noProfile := signRaw(t, signer, tokenHeader{Algorithm: ProfileTokenAlgorithm, Type: "JWT", KeyID: "key-1"},
+		ProfileTokenClaims{Zone: "DE", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+
+	tests := []struct {
+		name       string
+		token      string
+		wantErr    error
+		wantClaims bool
+	}{
+		{"valid", valid, nil, true},
+		{"unknown key ID", fromOther, ErrUnknownProfileKey, false},
+		{"wrong key for key ID", forged, ErrInvalidProfileToken, false},
+		{"tampered payload", tampered, ErrInvalidProfileToken, false},
+		{"truncated signature", valid[:len(valid)-4], ErrInvalidProfileToken, false},
+		{"two parts", parts[0] + "." + parts[1], ErrInvalidProfileToken, false},
+		{"unsupported algorithm", wrongAlgorithm, ErrInvalidProfileToken, false},
+		{"missing profile", noProfile, ErrInvalidProfileToken, false},
+		{"expired profile", expired, ErrProfileTokenExpired, true},
+		{"past expiry", pastExpiry, ErrProfileTokenExpired, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if (claims != nil) != tt.wantClaims {
+				t.Fatalf("Verify() claims = %+v, want claims %v", claims, tt.wantClaims)
+			}
+			if tt.wantErr == nil && (claims.Zone != "DE" || claims.Profile.Mode != ModeEco) {
+				t.Errorf("Expected DE eco claims, got %s %s", claims.Zone, claims.Profile.Mode)
+			}
+		})
+	}
+
+	// Both keys verify once the verifier trusts both
+	if _, err := newTestVerifier(t, signer, other).Verify(fromOther); err != nil {
+		t.Errorf("Expected a token from a trusted second key to verify, got %v", err)
+	}
+}
+
+func TestProfileSignRejectsInvalidProfiles(t *testing.T) {
+	signer := newTestSigner(t, "key-1")
+
+	tests := []struct {
+		name    string
+		profile *OptimizationProfile
+	}{
+		{"nil profile", nil},
+		{"no expiry", &OptimizationProfile{Mode: ModeEco}},
+		{"expired", testProfile(-time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := signer.Sign(tt.profile, "DE"); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestProfileVerifierFromJWKS(t *testing.T) {
+	signer := newTestSigner(t, "key-1")
+	data, _ := json.Marshal(signer.JWKS())
+
+	verifier, err := NewProfileVerifierFromJWKS(data)
+	if err != nil {
+		t.Fatalf("NewProfileVerifierFromJWKS() error = %v", err)
+	}
+	token, _, _ := signer.Sign(testProfile(time.Hour), "DE")
+	if _, err := verifier.Verify(token); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	if _, err := NewProfileVerifierFromJWKS([]byte(`{"keys":[{"kty":"RSA","kid":"rsa"}]}`)); err == nil {
+		t.Error("Expected an error for a key set without Ed25519 keys")
+	}
+	if _, err := NewProfileVerifierFromJWKS([]byte(`{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"short","x":"AAAA"}]}`)); err == nil {
+		t.Error("Expected an error for a malformed public key")
+	}
+}
+
+func TestProfileVerifierMiddleware(t *testing.T) {
+	signer := newTestSigner(t, "key-1")
+	verifier := newTestVerifier(t, signer)
+
+	valid, _, _ := signer.Sign(testProfile(time.Hour), "DE")
+	expired := signRaw(t, signer, tokenHeader{Algorithm: ProfileTokenAlgorithm, Type: "JWT", KeyID: "key-1"},
+		ProfileTokenClaims{Zone: "DE", Profile: testProfile(time.Hour), ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+
+	tests := []struct {
+		name       string
+		header     string
+		cookie     string
+		wantClaims bool
+	}{
+		{"header", valid, "", true},
+		{"cookie", "", valid, true},
+		{"header takes precedence", valid, "garbage", true},
+		{"invalid token passes through", "garbage", "", false},
+		{"expired token passes through", expired, "", false},
+		{"no token", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var called, gotClaims bool
+			handler := verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				_, gotClaims = ProfileClaimsFromContext(r.Context())
+			}))
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				request.Header.Set(ProfileTokenHeader, tt.header)
+			}
+			if tt.cookie != "" {
+				request.AddCookie(&http.Cookie{Name: ProfileTokenCookie, Value: tt.cookie})
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), request)
+
+			if !called {
+				t.Fatal("Expected the request to reach the next handler")
+			}
+			if gotClaims != tt.wantClaims {
+				t.Errorf("Expected claims in context: %v, got %v", tt.wantClaims, gotClaims)
+			}
+		})
+	}
+}
@@ -331,6 +331,9 @@ type OptimizationRequest struct {
 
 	// Preferences contains user preferences for optimization
 	Preferences OptimizationPreferences `json:"preferences,omitempty"`
+
+	// ClientHints contains device and network hints reported by the client (optional)
+	ClientHints *ClientHints `json:"client_hints,omitempty"`
 }
 
 // OptimizationPreferences contains user preferences for optimization.
@@ -351,6 +354,60 @@ type OptimizationPreferences struct {
 	DisallowedFeatures []string `json:"disallowed_features,omitempty" example:"user_authentication,payment_processing"`
 }
 
+// ClientHints contains device, network and user preference hints sent by the client,
+// typically via HTTP Client Hints (Sec-CH-UA-Mobile, ECT, Downlink, RTT, Device-Memory,
+// Save-Data and Sec-CH-Prefers-Reduced-Motion).
+type ClientHints struct {
+	// Mobile indicates the client is a mobile device (Sec-CH-UA-Mobile)
+	Mobile bool `json:"mobile" example:"true"`
+
+	// EffectiveConnectionType is the effective connection type (ECT: slow-2g, 2g, 3g, 4g)
+	EffectiveConnectionType string `json:"effective_connection_type,omitempty" validate:"omitempty,oneof=slow-2g 2g 3g 4g" example:"3g"`
+
+	// Downlink is the approximate downlink bandwidth in Mbps (Downlink)
+	Downlink float64 `json:"downlink,omitempty" validate:"min=0" example:"1.5"`
+
+	// RTT is the approximate round trip time in milliseconds (RTT)
+	RTT int `json:"rtt,omitempty" validate:"min=0" example:"300"`
+
+	// DeviceMemory is the approximate device memory in GiB (Device-Memory)
+	DeviceMemory float64 `json:"device_memory,omitempty" validate:"min=0" example:"2"`
+
+	// SaveData indicates the user has requested reduced data usage (Save-Data)
+	SaveData bool `json:"save_data" example:"false"`
+
+	// PrefersReducedMotion indicates the user prefers reduced motion (Sec-CH-Prefers-Reduced-Motion)
+	PrefersReducedMotion bool `json:"prefers_reduced_motion" example:"false"`
+}
+
+// IsSlowNetwork returns true if the hints indicate a 2G-class connection.
+func (h *ClientHints) IsSlowNetwork() bool {
+	if h == nil {
+		return false
+	}
+	switch h.EffectiveConnectionType {
+	case "slow-2g", "2g":
+		return true
+	}
+	return (h.Downlink > 0 && h.Downlink < 0.5) || h.RTT >= 1000
+}
+
+// IsConstrainedNetwork returns true if the hints indicate a 3G-class or slower connection.
+func (h *ClientHints) IsConstrainedNetwork() bool {
+	if h == nil {
+		return false
+	}
+	if h.IsSlowNetwork() || h.EffectiveConnectionType == "3g" {
+		return true
+	}
+	return (h.Downlink > 0 && h.Downlink < 2) || h.RTT >= 400
+}
+
+// IsLowEndDevice returns true if the hints indicate a device with 2 GiB of memory or less.
+func (h *ClientHints) IsLowEndDevice() bool {
+	return h != nil && h.DeviceMemory > 0 && h.DeviceMemory <= 2
+}
+
 // OptimizationResponse includes both carbon intensity and optimization profile.
 type OptimizationResponse struct {
 	// CarbonIntensity is the current carbon intensity data
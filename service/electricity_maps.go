@@ -5,20 +5,42 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
 	"os"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/perschulte/greenweb-api/internal/impact"
 	"github.com/perschulte/greenweb-api/pkg/carbon"
 )
 
 // ElectricityMapsClient handles integration with Electricity Maps API
 type ElectricityMapsClient struct {
-	apiKey     string
-	httpClient *http.Client
-	baseURL    string
-	logger     *slog.Logger
+	apiKey      string
+	httpClient  *http.Client
+	baseURL     string
+	logger      *slog.Logger
+	rttModel    carbon.RTTModel
+	energyModel carbon.EnergyModel
+
+	// mu guards the edge ranking settings and the latest readings
+	mu sync.Mutex
+
+	// Edge ranking by data freshness and probe health
+	healthSource  carbon.EdgeHealthSource
+	rankingPolicy carbon.EdgeRankingPolicy
+
+	readings map[string]zoneReading // grid zone -> latest reading
+}
+
+// zoneReading is the latest carbon intensity reading fetched for a grid zone
+type zoneReading struct {
+	intensity *carbon.CarbonIntensity
+	fetchedAt time.Time
 }
 
 // NewElectricityMapsClient creates a new Electricity Maps API client
@@ -31,7 +53,11 @@ func NewElectricityMapsClient(logger *slog.Logger) *ElectricityMapsClient {
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
-		logger: logger,
+		logger:        logger,
+		rttModel:      carbon.DefaultRTTModel,
+		energyModel:   impact.NewServerEnergyModel(impact.DefaultEmissionFactors),
+		rankingPolicy: carbon.DefaultEdgeRankingPolicy,
+		readings:      make(map[string]zoneReading),
 	}
 	
 	if apiKey == "" {
@@ -68,8 +94,18 @@ type GreenHour = carbon.GreenHour
 // New code should use github.com/perschulte/greenweb-api/pkg/carbon.GreenHoursForecast
 type GreenHoursForecast = carbon.GreenHoursForecast
 
-// GetCarbonIntensity fetches current carbon intensity for a location
+// GetCarbonIntensity fetches current carbon intensity for a location and records the
+// reading's source and age for its grid zone
 func (c *ElectricityMapsClient) GetCarbonIntensity(ctx context.Context, location string) (*carbon.CarbonIntensity, error) {
+	intensity, err := c.fetchCarbonIntensity(ctx, location)
+	if err == nil && intensity != nil {
+		c.recordReading(location, intensity)
+	}
+	return intensity, err
+}
+
+// fetchCarbonIntensity fetches current carbon intensity, falling back to mock data
+func (c *ElectricityMapsClient) fetchCarbonIntensity(ctx context.Context, location string) (*carbon.CarbonIntensity, error) {
 	// If no API key, fall back to mock data
 	if c.apiKey == "" {
 		c.logger.Info("Using mock data due to missing API key", "location", location)
@@ -77,7 +113,7 @@ func (c *ElectricityMapsClient) GetCarbonIntensity(ctx context.Context, location
 	}
 
 	// Try to map location to country code or zone
-	countryCode := c.mapLocationToCountryCode(location)
+	countryCode := c.gridZone(location)
 	
 	url := fmt.Sprintf("%s/latest?countryCode=%s", c.baseURL, countryCode)
 	
@@ -185,6 +221,17 @@ func (c *ElectricityMapsClient) GetDualGridCarbonIntensity(ctx context.Context,
 		Timestamp:          time.Now(),
 	}
 
+	// Add distance and latency when the edge is a known CDN edge location
+	userZone, edgeZone := c.gridZone(userLocation), c.gridZone(edgeLocation)
+	if edgeID, edge, found := findCatalogEdge(edgeLocation); found {
+		user := c.selectionUser(ctx, userLocation)
+		dual.Distance = carbon.CalculateDistance(user.Latitude, user.Longitude, edge.Latitude, edge.Longitude)
+		dual.NetworkHops = carbon.EstimateNetworkHops(dual.Distance)
+		dual.LatencyP50Ms, dual.LatencyP95Ms, dual.LatencySource = carbon.EstimateRTTPercentiles(c.rttModel, user, edgeID, edge)
+		edgeZone = edge.GridZone
+	}
+	c.attachRequestEmissions(dual, userZone, edgeZone)
+
 	// Generate recommendations
 	dual.Recommendation = carbon.GenerateDualGridRecommendation(dual, []carbon.EdgeAlternative{})
 
@@ -199,7 +246,9 @@ func (c *ElectricityMapsClient) GetDualGridCarbonIntensity(ctx context.Context,
 	return dual, nil
 }
 
-// GetOptimalEdgeLocation finds the best edge location from a CDN provider for minimal carbon impact
+// GetOptimalEdgeLocation finds the optimal edge location for a CDN provider. Edges excluded
+// by the ranking policy are skipped, and edges with stale or synthetic carbon data are only
+// chosen when no edge has trustworthy data.
 func (c *ElectricityMapsClient) GetOptimalEdgeLocation(ctx context.Context, userLocation, cdnProvider, contentType string) (*carbon.EdgeAlternative, error) {
 	// Get CDN provider configuration
 	provider, exists := carbon.GetCDNProvider(cdnProvider)
@@ -207,24 +256,51 @@ func (c *ElectricityMapsClient) GetOptimalEdgeLocation(ctx context.Context, user
 		return nil, fmt.Errorf("CDN provider %s not supported", cdnProvider)
 	}
 
-	// Parse user location to get coordinates
-	// For simplicity, use the default location coordinates if not found
-	userLat, userLon := 52.5200, 13.4050 // Default to Berlin
+	user := c.selectionUser(ctx, userLocation)
+	userLat, userLon := user.Latitude, user.Longitude
 
-	// Function to get intensity for a grid zone
+	getReading := c.zoneReadingLookup(ctx)
 	getIntensity := func(gridZone string) float64 {
-		intensity, err := c.GetCarbonIntensity(ctx, gridZone)
-		if err != nil {
-			c.logger.Warn("Failed to get intensity for grid zone", "zone", gridZone, "error", err)
-			return 300 // Default high value
+		if reading := getReading(gridZone); reading != nil {
+			return reading.CarbonIntensity
 		}
-		return intensity.CarbonIntensity
+		return 300 // Default high value
 	}
 
-	// Convert edge locations to slice
-	edges := make([]carbon.EdgeLocationInfo, 0, len(provider.EdgeLocations))
-	for _, edge := range provider.EdgeLocations {
-		edges = append(edges, edge)
+	// Assess every edge, keeping IDs for latency lookups
+	edgeIDs := make([]string, 0, len(provider.EdgeLocations))
+	for edgeID := range provider.EdgeLocations {
+		edgeIDs = append(edgeIDs, edgeID)
+	}
+	sort.Strings(edgeIDs)
+
+	qualities := make(map[string]carbon.EdgeQuality, len(edgeIDs))
+	minPenalty := -1
+	var excluded []string
+	for _, edgeID := range edgeIDs {
+		edge := provider.EdgeLocations[edgeID]
+		quality := c.assessEdge(cdnProvider, edgeID, edge, getReading(edge.GridZone))
+		qualities[edgeID] = quality
+		if quality.Excluded {
+			excluded = append(excluded, fmt.Sprintf("%s: %s", edgeID, quality.Reason()))
+			continue
+		}
+		if minPenalty < 0 || quality.Penalty < minPenalty {
+			minPenalty = quality.Penalty
+		}
+	}
+	if minPenalty < 0 {
+		return nil, fmt.Errorf("no suitable edge location found, all edges excluded (%s)", strings.Join(excluded, "; "))
+	}
+
+	// Only the edges with the most trustworthy data compete
+	candidateIDs := make([]string, 0, len(edgeIDs))
+	edges := make([]carbon.EdgeLocationInfo, 0, len(edgeIDs))
+	for _, edgeID := range edgeIDs {
+		if quality := qualities[edgeID]; !quality.Excluded && quality.Penalty == minPenalty {
+			candidateIDs = append(candidateIDs, edgeID)
+			edges = append(edges, provider.EdgeLocations[edgeID])
+		}
 	}
 
 	// Get optimal edge location
@@ -232,23 +308,49 @@ func (c *ElectricityMapsClient) GetOptimalEdgeLocation(ctx context.Context, user
 	if optimalEdge == nil {
 		return nil, fmt.Errorf("no suitable edge location found")
 	}
+	var optimalID string
+	for i := range edges {
+		if &edges[i] == optimalEdge {
+			optimalID = candidateIDs[i]
+		}
+	}
 
-	// Calculate distance and estimated latency
+	// Calculate distance and latency, measured where available
 	distance := carbon.CalculateDistance(userLat, userLon, optimalEdge.Latitude, optimalEdge.Longitude)
-	estimatedLatency := int(distance/20) + 10 // Rough estimate: 20km per ms + 10ms base
+	p50, p95, source := carbon.EstimateRTTPercentiles(c.rttModel, user, optimalID, *optimalEdge)
+
+	optimal := &carbon.EdgeAlternative{
+		Location:         optimalEdge.City,
+		Provider:         provider.Name,
+		CarbonIntensity:  getIntensity(optimalEdge.GridZone),
+		Distance:         distance,
+		EstimatedLatency: int(math.Round(p50)),
+		LatencyP95:       int(math.Round(p95)),
+		LatencySource:    source,
+	}
+	c.annotateEdge(optimal, cdnProvider, optimalID, *optimalEdge, getReading(optimalEdge.GridZone))
+	if len(excluded) > 0 {
+		c.logger.Info("Edges excluded from optimal edge selection",
+			"cdn_provider", cdnProvider,
+			"excluded", excluded)
+	}
 
-	return &carbon.EdgeAlternative{
-		Location:          optimalEdge.City,
-		Provider:          provider.Name,
-		CarbonIntensity:   getIntensity(optimalEdge.GridZone),
-		Distance:          distance,
-		EstimatedLatency:  estimatedLatency,
-		AvailabilityScore: 95.0, // Assume high availability for tier 1 locations
-	}, nil
+	return optimal, nil
 }
 
 // GetCDNAlternatives returns alternative edge locations with lower carbon intensity
 func (c *ElectricityMapsClient) GetCDNAlternatives(ctx context.Context, userLocation, currentEdgeLocation, cdnProvider, contentType string, maxAlternatives int) ([]carbon.EdgeAlternative, error) {
+	result, err := c.EvaluateCDNAlternatives(ctx, userLocation, currentEdgeLocation, cdnProvider, contentType, maxAlternatives)
+	if err != nil {
+		return nil, err
+	}
+	return result.Alternatives, nil
+}
+
+// EvaluateCDNAlternatives returns alternative edge locations with lower carbon intensity, plus
+// the greener edges that were excluded by the ranking policy. Alternatives with stale or
+// synthetic carbon data rank behind those with trustworthy data.
+func (c *ElectricityMapsClient) EvaluateCDNAlternatives(ctx context.Context, userLocation, currentEdgeLocation, cdnProvider, contentType string, maxAlternatives int) (*carbon.CDNAlternatives, error) {
 	provider, exists := carbon.GetCDNProvider(cdnProvider)
 	if !exists {
 		return nil, fmt.Errorf("CDN provider %s not supported", cdnProvider)
@@ -260,60 +362,370 @@ func (c *ElectricityMapsClient) GetCDNAlternatives(ctx context.Context, userLoca
 		currentIntensity = &carbon.CarbonIntensity{CarbonIntensity: 300} // Default high value
 	}
 
-	// Default user coordinates (Berlin)
-	userLat, userLon := 52.5200, 13.4050
+	user := c.selectionUser(ctx, userLocation)
+	userLat, userLon := user.Latitude, user.Longitude
 
-	var alternatives []carbon.EdgeAlternative
+	getReading := c.zoneReadingLookup(ctx)
 
-	// Function to get intensity for a grid zone
-	getIntensity := func(gridZone string) float64 {
-		intensity, err := c.GetCarbonIntensity(ctx, gridZone)
-		if err != nil {
-			return 300 // Default high value
-		}
-		return intensity.CarbonIntensity
+	edgeIDs := make([]string, 0, len(provider.EdgeLocations))
+	for edgeID := range provider.EdgeLocations {
+		edgeIDs = append(edgeIDs, edgeID)
 	}
+	sort.Strings(edgeIDs)
+
+	result := &carbon.CDNAlternatives{Alternatives: []carbon.EdgeAlternative{}}
+	penalties := make(map[string]int)
 
 	// Evaluate all edge locations
-	for edgeName, edge := range provider.EdgeLocations {
+	for _, edgeName := range edgeIDs {
+		edge := provider.EdgeLocations[edgeName]
+
 		// Skip current edge location
 		if edgeName == currentEdgeLocation || edge.City == currentEdgeLocation {
 			continue
 		}
 
-		intensity := getIntensity(edge.GridZone)
-		
+		reading := getReading(edge.GridZone)
+		intensity := 300.0 // Default high value
+		if reading != nil {
+			intensity = reading.CarbonIntensity
+		}
+
 		// Only include if significantly better (at least 20% improvement)
 		if intensity < currentIntensity.CarbonIntensity*0.8 {
 			distance := carbon.CalculateDistance(userLat, userLon, edge.Latitude, edge.Longitude)
-			estimatedLatency := int(distance/20) + 10
-
-			alternatives = append(alternatives, carbon.EdgeAlternative{
-				Location:          edge.City,
-				Provider:          provider.Name,
-				CarbonIntensity:   intensity,
-				Distance:          distance,
-				EstimatedLatency:  estimatedLatency,
-				AvailabilityScore: float64(100 - edge.Tier*5), // Lower tier = higher score
-			})
+			p50, p95, source := carbon.EstimateRTTPercentiles(c.rttModel, user, edgeName, edge)
+
+			alternative := carbon.EdgeAlternative{
+				Location:         edge.City,
+				Provider:         provider.Name,
+				CarbonIntensity:  intensity,
+				Distance:         distance,
+				EstimatedLatency: int(math.Round(p50)),
+				LatencyP95:       int(math.Round(p95)),
+				LatencySource:    source,
+			}
+			quality := c.annotateEdge(&alternative, cdnProvider, edgeName, edge, reading)
+			if alternative.Excluded {
+				result.Excluded = append(result.Excluded, alternative)
+				continue
+			}
+			penalties[edgeName] = quality.Penalty
+			result.Alternatives = append(result.Alternatives, alternative)
+		}
+	}
+
+	// Sort by data quality, then carbon intensity (best first)
+	sort.SliceStable(result.Alternatives, func(i, j int) bool {
+		a, b := result.Alternatives[i], result.Alternatives[j]
+		if penalties[a.EdgeID] != penalties[b.EdgeID] {
+			return penalties[a.EdgeID] < penalties[b.EdgeID]
 		}
+		return a.CarbonIntensity < b.CarbonIntensity
+	})
+
+	// Limit to maxAlternatives
+	if len(result.Alternatives) > maxAlternatives {
+		result.Alternatives = result.Alternatives[:maxAlternatives]
+	}
+
+	if len(result.Excluded) > 0 {
+		c.logger.Info("Edges excluded from CDN alternatives",
+			"cdn_provider", cdnProvider,
+			"current_edge", currentEdgeLocation,
+			"excluded_count", len(result.Excluded))
 	}
 
-	// Sort by carbon intensity (best first)
-	for i := 0; i < len(alternatives)-1; i++ {
-		for j := 0; j < len(alternatives)-1-i; j++ {
-			if alternatives[j].CarbonIntensity > alternatives[j+1].CarbonIntensity {
-				alternatives[j], alternatives[j+1] = alternatives[j+1], alternatives[j]
+	return result, nil
+}
+
+// SetEdgeHealthSource sets the probe results used for edge availability; unhealthy edges are excluded
+func (c *ElectricityMapsClient) SetEdgeHealthSource(source carbon.EdgeHealthSource) {
+	c.mu.Lock()
+	c.healthSource = source
+	c.mu.Unlock()
+}
+
+// SetEdgeRankingPolicy sets how edges with stale or synthetic carbon data are ranked
+func (c *ElectricityMapsClient) SetEdgeRankingPolicy(policy carbon.EdgeRankingPolicy) {
+	c.mu.Lock()
+	c.rankingPolicy = policy
+	c.mu.Unlock()
+}
+
+// edgeRanking returns the current edge health source and ranking policy
+func (c *ElectricityMapsClient) edgeRanking() (carbon.EdgeHealthSource, carbon.EdgeRankingPolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.healthSource, c.rankingPolicy
+}
+
+// DataFreshness returns the source and age of the latest reading of every grid zone fetched so far
+func (c *ElectricityMapsClient) DataFreshness() []carbon.DataFreshness {
+	now := time.Now()
+
+	c.mu.Lock()
+	freshness := make([]carbon.DataFreshness, 0, len(c.readings))
+	for zone, reading := range c.readings {
+		freshness = append(freshness, c.rankingPolicy.Freshness(zone, reading.intensity, reading.fetchedAt, now))
+	}
+	c.mu.Unlock()
+
+	sort.Slice(freshness, func(i, j int) bool {
+		return freshness[i].Zone < freshness[j].Zone
+	})
+	return freshness
+}
+
+// maxRecordedZones bounds the readings kept for DataFreshness. It exceeds the number of
+// real grid zones, but in mock mode any zone-like location is recorded.
+const maxRecordedZones = 512
+
+// recordReading remembers the latest reading for the location's grid zone. When
+// maxRecordedZones are recorded, the least recently fetched zone makes room.
+func (c *ElectricityMapsClient) recordReading(location string, intensity *carbon.CarbonIntensity) {
+	zone := intensity.GridZone
+	if zone == "" {
+		zone = c.gridZone(location)
+	}
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.readings[zone]; !exists && len(c.readings) >= maxRecordedZones {
+		var oldestZone string
+		var oldest time.Time
+		for recorded, reading := range c.readings {
+			if oldestZone == "" || reading.fetchedAt.Before(oldest) {
+				oldestZone, oldest = recorded, reading.fetchedAt
 			}
 		}
+		delete(c.readings, oldestZone)
 	}
+	c.readings[zone] = zoneReading{intensity: intensity, fetchedAt: now}
+}
 
-	// Limit to maxAlternatives
-	if len(alternatives) > maxAlternatives {
-		alternatives = alternatives[:maxAlternatives]
+// zoneReadingLookup returns a memoized per-request lookup of grid zone readings; failed
+// lookups return nil
+func (c *ElectricityMapsClient) zoneReadingLookup(ctx context.Context) func(gridZone string) *carbon.CarbonIntensity {
+	readings := make(map[string]*carbon.CarbonIntensity)
+	return func(gridZone string) *carbon.CarbonIntensity {
+		if reading, exists := readings[gridZone]; exists {
+			return reading
+		}
+		reading, err := c.GetCarbonIntensity(ctx, gridZone)
+		if err != nil {
+			c.logger.Warn("Failed to get intensity for grid zone", "zone", gridZone, "error", err)
+			reading = nil
+		}
+		readings[gridZone] = reading
+		return reading
 	}
+}
+
+// annotateEdge sets the availability, data quality and ranking fields of an edge alternative
+// and returns the edge's quality under the ranking policy
+func (c *ElectricityMapsClient) annotateEdge(alternative *carbon.EdgeAlternative, cdnProvider, edgeID string, edge carbon.EdgeLocationInfo, reading *carbon.CarbonIntensity) carbon.EdgeQuality {
+	freshness, health, quality := c.evaluateEdge(cdnProvider, edgeID, edge, reading)
+
+	alternative.EdgeID = edgeID
+	alternative.AvailabilityScore, alternative.AvailabilitySource = carbon.EdgeAvailability(edge, health)
+	alternative.DataSource = freshness.Source
+	alternative.DataAgeMinutes = freshness.AgeMinutes
+	alternative.Excluded = quality.Excluded
+	alternative.Downranked = !quality.Excluded && quality.Penalty > 0
+	alternative.Reason = quality.Reason()
+	return quality
+}
 
-	return alternatives, nil
+// assessEdge returns an edge's quality under the ranking policy
+func (c *ElectricityMapsClient) assessEdge(cdnProvider, edgeID string, edge carbon.EdgeLocationInfo, reading *carbon.CarbonIntensity) carbon.EdgeQuality {
+	_, _, quality := c.evaluateEdge(cdnProvider, edgeID, edge, reading)
+	return quality
+}
+
+// evaluateEdge looks up an edge's carbon data freshness and probe health (nil when not probed)
+// and applies the ranking policy
+func (c *ElectricityMapsClient) evaluateEdge(cdnProvider, edgeID string, edge carbon.EdgeLocationInfo, reading *carbon.CarbonIntensity) (carbon.DataFreshness, *carbon.EdgeHealth, carbon.EdgeQuality) {
+	healthSource, policy := c.edgeRanking()
+
+	var health *carbon.EdgeHealth
+	if healthSource != nil {
+		if probed, exists := healthSource.EdgeHealth(cdnProvider, edgeID); exists {
+			health = &probed
+		}
+	}
+	freshness := policy.Freshness(edge.GridZone, reading, time.Time{}, time.Now())
+	return freshness, health, policy.Assess(freshness, health)
+}
+
+// SetEnergyModel sets the energy model used for absolute per-request emissions
+func (c *ElectricityMapsClient) SetEnergyModel(model carbon.EnergyModel) {
+	c.energyModel = model
+}
+
+// attachRequestEmissions adds the absolute emissions of one request of the dual-grid content type
+func (c *ElectricityMapsClient) attachRequestEmissions(dual *carbon.DualGridCarbonIntensity, userZone, edgeZone string) {
+	if c.energyModel == nil {
+		return
+	}
+	emissions := c.energyModel.EstimateRequestEmissions(carbon.ProfileForContentType(dual.ContentType),
+		userZone, edgeZone, dual.UserLocation.CarbonIntensity, dual.EdgeLocation.CarbonIntensity)
+	dual.Emissions = &emissions
+	dual.GramsPerRequest = emissions.TotalGrams
+}
+
+// SetRTTModel sets the RTT model used for edge latency, e.g. a measured RTT matrix.
+// SelectEdgeMultiObjective uses it when a request carries neither measured RTTs nor model parameters.
+func (c *ElectricityMapsClient) SetRTTModel(model carbon.RTTModel) {
+	c.rttModel = model
+}
+
+// SelectEdgeMultiObjective evaluates every edge of a CDN provider on RTT, egress price and
+// dual-grid weighted carbon intensity and returns the Pareto front with a weighted choice
+func (c *ElectricityMapsClient) SelectEdgeMultiObjective(ctx context.Context, req carbon.EdgeSelectionRequest) (*carbon.EdgeSelectionResult, error) {
+	provider, exists := carbon.GetCDNProvider(req.CDNProvider)
+	if !exists {
+		return nil, fmt.Errorf("CDN provider %s not supported", req.CDNProvider)
+	}
+
+	// Request-supplied measurements and model parameters take precedence over the configured model
+	rttModel := c.rttModel
+	if req.RTTModel != nil {
+		rttModel = *req.RTTModel
+	}
+	if len(req.MeasuredRTTMs) > 0 {
+		rttModel = carbon.RTTTable{Measurements: req.MeasuredRTTMs, Fallback: rttModel}
+	}
+
+	// Many edges share a grid zone; fetch each zone once
+	type zoneIntensity struct {
+		value     float64
+		available bool
+	}
+	intensities := make(map[string]zoneIntensity)
+	getIntensity := func(gridZone string) (float64, bool) {
+		if intensity, exists := intensities[gridZone]; exists {
+			return intensity.value, intensity.available
+		}
+		zone := zoneIntensity{value: 300} // Default high value
+		if intensity, err := c.GetCarbonIntensity(ctx, gridZone); err != nil {
+			c.logger.Warn("Failed to get intensity for grid zone", "zone", gridZone, "error", err)
+		} else {
+			zone = zoneIntensity{value: intensity.CarbonIntensity, available: true}
+		}
+		intensities[gridZone] = zone
+		return zone.value, zone.available
+	}
+
+	// The user's intensity weighs equally into every edge, so a default does not skew the ranking
+	userIntensity, _ := getIntensity(req.User.GridZone)
+	objectives := carbon.EvaluateEdgeObjectives(req, provider.EdgeLocations, rttModel, userIntensity, getIntensity)
+	result, err := carbon.SelectParetoEdge(objectives, req.Weights)
+	if err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Multi-objective edge selection completed",
+		"cdn_provider", req.CDNProvider,
+		"user_zone", req.User.GridZone,
+		"chosen", result.Chosen.EdgeID,
+		"pareto_front", len(result.ParetoFront),
+		"slo_satisfied", result.SLOSatisfied,
+		"excluded_no_carbon_data", len(result.ExcludedNoCarbonData))
+
+	return result, nil
+}
+
+// PlanTrafficShift builds a day-ahead hourly weight table for a CDN provider's edges from
+// green-hours forecasts of the user's and each edge's grid zone
+func (c *ElectricityMapsClient) PlanTrafficShift(ctx context.Context, req carbon.TrafficPlanRequest) (*carbon.TrafficPlan, error) {
+	provider, exists := carbon.GetCDNProvider(req.CDNProvider)
+	if !exists {
+		return nil, fmt.Errorf("CDN provider %s not supported", req.CDNProvider)
+	}
+
+	hours := req.Hours
+	if hours <= 0 {
+		hours = 24
+	}
+	if !req.Start.IsZero() {
+		// Forecasts start now; cover the gap to a later plan start
+		if lead := int(time.Until(req.Start).Hours()) + 1; lead > 0 {
+			hours += lead
+		}
+	}
+
+	intensityAt := c.forecastIntensityLookup(ctx, hours)
+
+	plan, err := carbon.PlanTrafficShift(req, provider, c.rttModel, intensityAt)
+	if err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Traffic shifting plan computed",
+		"cdn_provider", req.CDNProvider,
+		"user_zone", req.User.GridZone,
+		"hours", len(plan.Hours),
+		"reduction_percent", plan.ReductionPercent,
+		"slo_satisfied", plan.SLOSatisfied,
+		"estimated_hours", plan.EstimatedHours)
+
+	return plan, nil
+}
+
+// RecommendComputeRegion picks the cloud region and start time with the lowest expected
+// emissions for a deferrable compute workload
+func (c *ElectricityMapsClient) RecommendComputeRegion(ctx context.Context, workload carbon.ComputeWorkload) (*carbon.ComputePlacementResult, error) {
+	now := time.Now()
+	hours := int(math.Ceil(workload.Deadline.Sub(now).Hours()))
+	hours = max(1, min(hours, int(carbon.MaxComputeHorizon.Hours())))
+
+	result, err := carbon.RecommendComputePlacement(workload, now, c.forecastIntensityLookup(ctx, hours))
+	if err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Compute region recommended",
+		"best_region", result.Best.Provider+"/"+result.Best.Region,
+		"best_start", result.Best.Start,
+		"baseline_region", result.Baseline.Provider+"/"+result.Baseline.Region,
+		"savings_percent", result.SavingsPercent,
+		"best_estimated_hours", result.Best.EstimatedHours,
+		"regions_evaluated", result.RegionsEvaluated)
+
+	return result, nil
+}
+
+// forecastIntensityLookup returns a per-hour intensity lookup backed by green-hours forecasts.
+// Each zone's forecast and current intensity are fetched once; hours the forecast does not
+// list as green fall back to the current intensity and are reported as not forecast.
+func (c *ElectricityMapsClient) forecastIntensityLookup(ctx context.Context, hours int) carbon.HourlyIntensity {
+	type zoneForecast struct {
+		forecast *carbon.GreenHoursForecast
+		current  float64
+	}
+	zones := make(map[string]zoneForecast)
+
+	return func(gridZone string, hour time.Time) (float64, bool) {
+		zone, exists := zones[gridZone]
+		if !exists {
+			zone.current = 300 // Default high value
+			if intensity, err := c.GetCarbonIntensity(ctx, gridZone); err != nil {
+				c.logger.Warn("Failed to get intensity for grid zone", "zone", gridZone, "error", err)
+			} else {
+				zone.current = intensity.CarbonIntensity
+			}
+			if forecast, err := c.GetGreenHoursForecast(ctx, gridZone, hours); err != nil {
+				c.logger.Warn("Failed to get forecast for grid zone", "zone", gridZone, "error", err)
+			} else {
+				zone.forecast = forecast
+			}
+			zones[gridZone] = zone
+		}
+		return carbon.ForecastIntensityAt(zone.forecast, hour, zone.current)
+	}
 }
 
 // GetGreenHoursForecast generates a forecast of optimal low-carbon hours
@@ -535,6 +947,51 @@ func (c *ElectricityMapsClient) mapLocationToCountryCode(location string) string
 	return "DE"
 }
 
+// gridZonePattern matches grid zone codes such as "DE" or "US-CA"
+var gridZonePattern = regexp.MustCompile(`^[A-Z]{2}(-[A-Z0-9]+)*$`)
+
+// gridZone returns the grid zone for a location given as zone code or place name
+func (c *ElectricityMapsClient) gridZone(location string) string {
+	if location = strings.TrimSpace(location); gridZonePattern.MatchString(location) {
+		return location
+	}
+	return c.mapLocationToCountryCode(location)
+}
+
+// selectionUser returns the user set on ctx by carbon.WithEdgeSelectionUser, so latency
+// estimates use the requester's coordinates and network prefix. Without one, the user is
+// placed in Berlin within the grid zone of userLocation.
+func (c *ElectricityMapsClient) selectionUser(ctx context.Context, userLocation string) carbon.EdgeSelectionUser {
+	user, ok := carbon.EdgeSelectionUserFromContext(ctx)
+	if !ok {
+		user = carbon.EdgeSelectionUser{Latitude: 52.5200, Longitude: 13.4050} // Default to Berlin
+	}
+	if user.GridZone == "" {
+		user.GridZone = c.gridZone(userLocation)
+	}
+	return user
+}
+
+// findCatalogEdge finds a CDN catalog edge location by ID, or else by city name
+func findCatalogEdge(location string) (string, carbon.EdgeLocationInfo, bool) {
+	location = strings.ToLower(strings.TrimSpace(location))
+
+	var cityID string
+	var cityEdge carbon.EdgeLocationInfo
+	for _, name := range carbon.GetAllCDNProviders() {
+		provider, _ := carbon.GetCDNProvider(name)
+		if edge, exists := provider.EdgeLocations[location]; exists {
+			return location, edge, true
+		}
+		for edgeID, edge := range provider.EdgeLocations {
+			if cityID == "" && strings.ToLower(edge.City) == location {
+				cityID, cityEdge = edgeID, edge
+			}
+		}
+	}
+	return cityID, cityEdge, cityID != ""
+}
+
 // getMockCarbonIntensity provides fallback mock data
 func (c *ElectricityMapsClient) getMockCarbonIntensity(location string) *carbon.CarbonIntensity {
 	// Simulate different intensities based on time of day
@@ -574,7 +1031,7 @@ func (c *ElectricityMapsClient) getMockCarbonIntensity(location string) *carbon.
 		NextGreenWindow:         time.Now().Add(4 * time.Hour),
 		Timestamp:               time.Now(),
 		Source:                  "mock",
-		GridZone:                c.mapLocationToCountryCode(location),
+		GridZone:                c.gridZone(location),
 	}
 }
 
@@ -613,6 +1070,7 @@ func (c *ElectricityMapsClient) getMockDualGridIntensity(userLocation, edgeLocat
 		},
 	}
 
+	c.attachRequestEmissions(dual, c.gridZone(userLocation), c.gridZone(edgeLocation))
 	dual.Recommendation = carbon.GenerateDualGridRecommendation(dual, alternatives)
 
 	return dual
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+)
+
+// testEdgeCatalog adds a provider whose edges cover fresh, stale, synthetic and unhealthy data
+const testEdgeCatalog = `{"version": 1, "providers": {"testcdn": {"name": "TestCDN", "edge_locations": {
+	"fresh-1": {"city": "Stockholm", "country": "Sweden", "grid_zone": "SE", "latitude": 59.33, "longitude": 18.07, "tier": 1, "capacity": "high"},
+	"stale-1": {"city": "Oslo", "country": "Norway", "grid_zone": "NO-NO1", "latitude": 59.91, "longitude": 10.75, "tier": 1, "capacity": "high"},
+	"mock-1": {"city": "Reykjavik", "country": "Iceland", "grid_zone": "IS", "latitude": 64.15, "longitude": -21.94, "tier": 2, "capacity": "low"},
+	"down-1": {"city": "Helsinki", "country": "Finland", "grid_zone": "FI", "latitude": 60.17, "longitude": 24.94, "tier": 1, "capacity": "high"},
+	"dirty-1": {"city": "Frankfurt", "country": "Germany", "grid_zone": "DE", "latitude": 50.11, "longitude": 8.68, "tier": 1, "capacity": "high"}
+}}}}`
+
+// testHealthSource reports down-1 as failing and every other edge as healthy
+type testHealthSource struct{}
+
+func (testHealthSource) EdgeHealth(provider, edgeID string) (carbon.EdgeHealth, bool) {
+	if edgeID == "down-1" {
+		return carbon.EdgeHealth{Healthy: false, ConsecutiveFailures: 3, Checks: 10, Availability: 70}, true
+	}
+	return carbon.EdgeHealth{Healthy: true, Checks: 10, Availability: 100}, true
+}
+
+// newTestEdgeClient returns a client backed by a fake Electricity Maps API: SE and FI are
+// fresh, NO-NO1 is five hours old, IS fails over to mock data and everything else is dirty
+func newTestEdgeClient(t *testing.T) *ElectricityMapsClient {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	if err := os.WriteFile(path, []byte(testEdgeCatalog), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	carbon.DefaultCDNCatalog.SetFiles(path)
+	if _, err := carbon.DefaultCDNCatalog.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	t.Cleanup(func() {
+		carbon.DefaultCDNCatalog.SetFiles()
+		carbon.DefaultCDNCatalog.Reload()
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		zone := r.URL.Query().Get("countryCode")
+		intensity, age := 800.0, time.Duration(0)
+		switch zone {
+		case "IS":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		case "SE", "FI":
+			intensity = 30
+		case "NO-NO1":
+			intensity, age = 20, 5*time.Hour
+		}
+		fmt.Fprintf(w, `{"zone": %q, "status": "ok", "data": {"carbonIntensity": %v, "datetime": %q}}`,
+			zone, intensity, time.Now().Add(-age).UTC().Format(time.RFC3339))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewElectricityMapsClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	client.apiKey = "test"
+	client.baseURL = server.URL
+	client.SetEdgeHealthSource(testHealthSource{})
+	return client
+}
+
+func TestEvaluateCDNAlternatives(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       carbon.EdgeRankingPolicy
+		alternatives []string // In ranking order
+		downranked   []string
+		excluded     []string
+	}{
+		{"stale and synthetic data is downranked", carbon.DefaultEdgeRankingPolicy,
+			[]string{"fresh-1", "stale-1", "mock-1"}, []string{"stale-1", "mock-1"}, []string{"down-1"}},
+		{"stale and synthetic data is excluded",
+			carbon.EdgeRankingPolicy{StaleData: carbon.DataActionExclude, SyntheticData: carbon.DataActionExclude},
+			[]string{"fresh-1"}, nil, []string{"stale-1", "mock-1", "down-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestEdgeClient(t)
+			client.SetEdgeRankingPolicy(tt.policy)
+
+			result, err := client.EvaluateCDNAlternatives(context.Background(), "DE", "dirty-1", "testcdn", "mixed", 10)
+			if err != nil {
+				t.Fatalf("EvaluateCDNAlternatives() error = %v", err)
+			}
+
+			var ranked, downranked, excluded []string
+			for _, alternative := range result.Alternatives {
+				ranked = append(ranked, alternative.EdgeID)
+				if alternative.Downranked {
+					downranked = append(downranked, alternative.EdgeID)
+					if alternative.Reason == "" {
+						t.Errorf("Expected a reason for downranking %s", alternative.EdgeID)
+					}
+				}
+			}
+			for _, alternative := range result.Excluded {
+				excluded = append(excluded, alternative.EdgeID)
+				if !alternative.Excluded || alternative.Reason == "" {
+					t.Errorf("Expected %s marked excluded with a reason, got %+v", alternative.EdgeID, alternative)
+				}
+			}
+
+			if strings.Join(ranked, ",") != strings.Join(tt.alternatives, ",") {
+				t.Errorf("Expected alternatives %v, got %v", tt.alternatives, ranked)
+			}
+			if strings.Join(downranked, ",") != strings.Join(tt.downranked, ",") {
+				t.Errorf("Expected downranked %v, got %v", tt.downranked, downranked)
+			}
+			if !sameSet(excluded, tt.excluded) {
+				t.Errorf("Expected excluded %v, got %v", tt.excluded, excluded)
+			}
+		})
+	}
+}
+
+func TestDataFreshnessIsBounded(t *testing.T) {
+	client := NewElectricityMapsClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// In mock mode every zone-like location yields a reading
+	for i := 0; i < maxRecordedZones+50; i++ {
+		if _, err := client.GetCarbonIntensity(context.Background(), fmt.Sprintf("XX-%d", i)); err != nil {
+			t.Fatalf("GetCarbonIntensity() error = %v", err)
+		}
+	}
+
+	freshness := client.DataFreshness()
+	if len(freshness) != maxRecordedZones {
+		t.Fatalf("Expected %d recorded zones, got %d", maxRecordedZones, len(freshness))
+	}
+	zones := make(map[string]bool, len(freshness))
+	for _, zone := range freshness {
+		zones[zone.Zone] = true
+	}
+	if zones["XX-0"] || !zones[fmt.Sprintf("XX-%d", maxRecordedZones+49)] {
+		t.Error("Expected the least recently fetched zones to make room for new ones")
+	}
+}
+
+// sameSet reports whether a and b hold the same strings in any order
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[string]int)
+	for _, s := range a {
+		counts[s]++
+	}
+	for _, s := range b {
+		counts[s]--
+		if counts[s] < 0 {
+			return false
+		}
+	}
+	return true
+}
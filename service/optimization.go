@@ -19,6 +19,7 @@ type CarbonIntensityProvider interface {
 // OptimizationService handles website optimization recommendations
 type OptimizationService struct {
 	electricityMaps CarbonIntensityProvider
+	rules           OptimizationRuleStore // nil disables optimization rules
 	logger          *slog.Logger
 }
 
@@ -69,6 +70,16 @@ func (s *OptimizationService) GetOptimizationProfile(ctx context.Context, req op
 		s.applyURLSpecificOptimizations(profile, req.URL)
 	}
 
+	// Tighten the profile for the client's device and network if hints are provided
+	if req.ClientHints != nil {
+		s.applyClientHints(profile, req.ClientHints)
+	}
+
+	// Operator-defined rules override the generated profile
+	if s.rules != nil {
+		s.applyRules(ctx, profile, &req, intensity)
+	}
+
 	s.logger.Info("Generated optimization profile",
 		"location", req.Location,
 		"url", req.URL,
@@ -328,6 +339,132 @@ func (s *OptimizationService) applyURLSpecificOptimizations(profile *optimizatio
 		"disabled_features", len(profile.DisableFeatures))
 }
 
+// applyClientHints tightens a profile for constrained devices, slow networks and user preferences.
+// Hints only ever reduce quality or disable features; they never relax the carbon-based profile.
+func (s *OptimizationService) applyClientHints(profile *optimization.OptimizationProfile, hints *optimization.ClientHints) {
+	videoOpt := &profile.HighImpactOptimizations.VideoStreamingOptimization
+
+	// Save-Data is an explicit request from the user to minimise transfer
+	if hints.SaveData {
+		capImageQuality(profile, optimization.ImageQualityLow)
+		capVideoQuality(profile, optimization.VideoQuality480p)
+		addDisabledFeatures(profile, "video_autoplay", "background_videos", "preview_videos", "custom_fonts", "web_fonts")
+		profile.DeferAnalytics = true
+		profile.UIOptimizations.LazyLoadImages = true
+		profile.UIOptimizations.PreferSystemFonts = true
+		profile.ContentOptimizations.CompressImages = true
+		profile.ContentOptimizations.CompressVideos = true
+		videoOpt.AutoplayDisabled = true
+		videoOpt.PreloadStrategy = "none"
+		capResourceLimit(&profile.ResourceLimits.MaxImageSize, 200*1024)
+	}
+
+	// Network quality from ECT, Downlink and RTT
+	if hints.IsSlowNetwork() {
+		capImageQuality(profile, optimization.ImageQualityLow)
+		capVideoQuality(profile, optimization.VideoQuality360p)
+		addDisabledFeatures(profile, "video_autoplay", "background_videos", "preview_videos", "video_backgrounds",
+			"custom_fonts", "web_fonts", "embedded_maps", "social_widgets")
+		profile.DeferAnalytics = true
+		profile.CachingStrategy = optimization.CachingAggressive
+		profile.UIOptimizations.LazyLoadImages = true
+		profile.UIOptimizations.PreferSystemFonts = true
+		videoOpt.AutoplayDisabled = true
+		videoOpt.PreloadStrategy = "none"
+		capResourceLimit(&profile.ResourceLimits.MaxImageSize, 100*1024)
+		capResourceLimit(&profile.ResourceLimits.MaxScriptSize, 100*1024)
+		if profile.ResourceLimits.MaxConcurrentRequests == 0 || profile.ResourceLimits.MaxConcurrentRequests > 4 {
+			profile.ResourceLimits.MaxConcurrentRequests = 4
+		}
+	} else if hints.IsConstrainedNetwork() {
+		capImageQuality(profile, optimization.ImageQualityMedium)
+		capVideoQuality(profile, optimization.VideoQuality480p)
+		addDisabledFeatures(profile, "video_autoplay", "background_videos")
+		profile.UIOptimizations.LazyLoadImages = true
+		videoOpt.AutoplayDisabled = true
+		capResourceLimit(&profile.ResourceLimits.MaxImageSize, 300*1024)
+	}
+
+	// Low-memory devices struggle with heavy JavaScript and GPU work
+	if hints.IsLowEndDevice() {
+		addDisabledFeatures(profile, "3d_models", "webgl_effects", "parallax_effects", "heavy_scripts")
+		profile.UIOptimizations.MinimizeJavaScript = true
+		profile.UIOptimizations.ReduceAnimations = true
+		profile.ContentOptimizations.MinifyJavaScript = true
+	}
+
+	// Mobile screens gain nothing from video above 720p
+	if hints.Mobile {
+		capVideoQuality(profile, optimization.VideoQuality720p)
+	}
+
+	if hints.PrefersReducedMotion {
+		addDisabledFeatures(profile, "animations", "parallax_effects", "carousels", "video_autoplay")
+		profile.UIOptimizations.ReduceAnimations = true
+		profile.UIOptimizations.DisableTransitions = true
+		videoOpt.AutoplayDisabled = true
+	}
+
+	s.logger.Debug("Applied client hint optimizations",
+		"mobile", hints.Mobile,
+		"ect", hints.EffectiveConnectionType,
+		"save_data", hints.SaveData,
+		"device_memory", hints.DeviceMemory,
+		"video_quality", profile.VideoQuality,
+		"image_quality", profile.ImageQuality)
+}
+
+// videoQualityRank orders video qualities from highest to lowest
+var videoQualityRank = map[optimization.VideoQuality]int{
+	optimization.VideoQuality4K:    4,
+	optimization.VideoQuality1080p: 3,
+	optimization.VideoQuality720p:  2,
+	optimization.VideoQuality480p:  1,
+	optimization.VideoQuality360p:  0,
+}
+
+// imageQualityRank orders image qualities from highest to lowest
+var imageQualityRank = map[optimization.ImageQuality]int{
+	optimization.ImageQualityHigh:   2,
+	optimization.ImageQualityMedium: 1,
+	optimization.ImageQualityLow:    0,
+}
+
+// capVideoQuality lowers the profile's video quality to maxQuality if it is currently higher
+func capVideoQuality(profile *optimization.OptimizationProfile, maxQuality optimization.VideoQuality) {
+	if profile.VideoQuality == "" || videoQualityRank[profile.VideoQuality] > videoQualityRank[maxQuality] {
+		profile.VideoQuality = maxQuality
+	}
+	videoOpt := &profile.HighImpactOptimizations.VideoStreamingOptimization
+	if videoOpt.MaxQuality == "" || videoQualityRank[videoOpt.MaxQuality] > videoQualityRank[maxQuality] {
+		videoOpt.Enabled = true
+		videoOpt.MaxQuality = maxQuality
+	}
+}
+
+// capImageQuality lowers the profile's image quality to maxQuality if it is currently higher
+func capImageQuality(profile *optimization.OptimizationProfile, maxQuality optimization.ImageQuality) {
+	if profile.ImageQuality == "" || imageQualityRank[profile.ImageQuality] > imageQualityRank[maxQuality] {
+		profile.ImageQuality = maxQuality
+	}
+}
+
+// capResourceLimit lowers a byte limit to max if it is unset or higher
+func capResourceLimit(limit *int64, max int64) {
+	if *limit == 0 || *limit > max {
+		*limit = max
+	}
+}
+
+// addDisabledFeatures disables features that are not already disabled
+func addDisabledFeatures(profile *optimization.OptimizationProfile, features ...string) {
+	for _, feature := range features {
+		if !profile.IsFeatureDisabled(feature) {
+			profile.DisableFeatures = append(profile.DisableFeatures, feature)
+		}
+	}
+}
+
 // isEcommerceSite detects if a URL belongs to an e-commerce site
 func (s *OptimizationService) isEcommerceSite(url string) bool {
 	ecommerceIndicators := []string{
@@ -553,6 +690,27 @@ func (s *OptimizationService) GetOptimizationRecommendations(ctx context.Context
 	return recommendations, nil
 }
 
+// ValidateOptimizationProfile checks that a profile is well-formed and still valid
+func (s *OptimizationService) ValidateOptimizationProfile(ctx context.Context, profile *optimization.OptimizationProfile) (bool, error) {
+	if profile == nil {
+		return false, fmt.Errorf("optimization profile is nil")
+	}
+	if !profile.Mode.IsValid() {
+		return false, fmt.Errorf("invalid optimization mode: %s", profile.Mode)
+	}
+	if !profile.ValidUntil.IsZero() && profile.IsExpired() {
+		return false, nil
+	}
+	return true, nil
+}
+
+// GetSupportedFeatures returns the features that can be disabled by optimization profiles
+func (s *OptimizationService) GetSupportedFeatures(ctx context.Context) ([]string, error) {
+	features := make([]string, len(optimization.DefaultOptimizationServiceConfig.SupportedFeatures))
+	copy(features, optimization.DefaultOptimizationServiceConfig.SupportedFeatures)
+	return features, nil
+}
+
 // calculateHighImpactOptimizations determines which high-impact features to optimize
 func (s *OptimizationService) calculateHighImpactOptimizations(intensity float64) optimization.HighImpactOptimizations {
 	highImpact := optimization.HighImpactOptimizations{}
@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+)
+
+// defaultApplicationDuration is the usage period assumed for a tracked profile when
+// the client does not report one. CO2SavingsBreakdown values are expressed per hour.
+const defaultApplicationDuration = time.Hour
+
+// maxApplicationDuration caps the usage period a client may report for a tracked profile
+const maxApplicationDuration = 24 * time.Hour
+
+// maxTopDisabledFeatures limits the number of features returned in OptimizationStats
+const maxTopDisabledFeatures = 10
+
+// Limits of MemoryOptimizationAnalyticsStore
+const (
+	defaultMaxApplications      = 100000
+	defaultApplicationRetention = 90 * 24 * time.Hour
+)
+
+// savingsMethodology describes how EnergySavingsReport figures are derived
+const savingsMethodology = "Carbon savings use the server's estimated CO2 savings per hour for the grid carbon " +
+	"intensity when the application was tracked, never more than the profile reports, multiplied by the " +
+	"reported usage duration (default 1 hour, at most 24 hours). Energy savings divide avoided emissions by " +
+	"that grid carbon intensity. Per-feature savings split each application evenly across its disabled features."
+
+// OptimizationApplication is a single recorded application of an optimization profile
+type OptimizationApplication struct {
+	ID                     string                        `json:"id"`
+	Mode                   optimization.OptimizationMode `json:"mode"`
+	DisabledFeatures       []string                      `json:"disabled_features"`
+	CarbonIntensity        float64                       `json:"carbon_intensity"`
+	Location               string                        `json:"location"`
+	EnergySaved            float64                       `json:"energy_saved"` // kWh
+	CarbonSaved            float64                       `json:"carbon_saved"` // kg CO2
+	EstimatedEnergySavings float64                       `json:"estimated_energy_savings"`
+	PerformanceImpact      float64                       `json:"performance_impact"`
+	AppliedAt              time.Time                     `json:"applied_at"`
+	Metadata               map[string]interface{}        `json:"metadata,omitempty"`
+}
+
+// OptimizationAnalyticsStore persists optimization applications for later aggregation
+type OptimizationAnalyticsStore interface {
+	SaveApplication(ctx context.Context, application *OptimizationApplication) error
+	// ListApplications returns applications within the time range; an empty location matches all
+	ListApplications(ctx context.Context, location string, timeRange optimization.TimeRange) ([]*OptimizationApplication, error)
+}
+
+// MemoryOptimizationAnalyticsStore is an in-memory OptimizationAnalyticsStore. It keeps at most
+// maxApplications entries and forgets applications older than the retention period.
+type MemoryOptimizationAnalyticsStore struct {
+	applications    []*OptimizationApplication
+	maxApplications int
+	retention       time.Duration
+	mutex           sync.RWMutex
+}
+
+// NewMemoryOptimizationAnalyticsStore creates a new in-memory analytics store
+func NewMemoryOptimizationAnalyticsStore() *MemoryOptimizationAnalyticsStore {
+	return &MemoryOptimizationAnalyticsStore{
+		applications:    make([]*OptimizationApplication, 0),
+		maxApplications: defaultMaxApplications,
+		retention:       defaultApplicationRetention,
+	}
+}
+
+// SaveApplication stores an optimization application. When the store is full, expired
+// applications are dropped first, then the oldest recorded ones.
+func (m *MemoryOptimizationAnalyticsStore) SaveApplication(ctx context.Context, application *OptimizationApplication) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.applications) >= m.maxApplications {
+		cutoff := time.Now().Add(-m.retention)
+		kept := m.applications[:0]
+		for _, stored := range m.applications {
+			if !stored.AppliedAt.Before(cutoff) {
+				kept = append(kept, stored)
+			}
+		}
+		clear(m.applications[len(kept):])
+		m.applications = kept
+	}
+	if len(m.applications) >= m.maxApplications {
+		// Drop a tenth at once so a full store does not copy on every save
+		drop := max(len(m.applications)-m.maxApplications+1, m.maxApplications/10)
+		m.applications = append(make([]*OptimizationApplication, 0, m.maxApplications), m.applications[drop:]...)
+	}
+
+	m.applications = append(m.applications, application)
+	return nil
+}
+
+// ListApplications returns the applications matching location and time range
+func (m *MemoryOptimizationAnalyticsStore) ListApplications(ctx context.Context, location string, timeRange optimization.TimeRange) ([]*OptimizationApplication, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	cutoff := time.Now().Add(-m.retention)
+	var results []*OptimizationApplication
+	for _, application := range m.applications {
+		if location != "" && !strings.EqualFold(application.Location, location) {
+			continue
+		}
+		if application.AppliedAt.Before(cutoff) {
+			continue
+		}
+		if application.AppliedAt.Before(timeRange.Start) || application.AppliedAt.After(timeRange.End) {
+			continue
+		}
+		results = append(results, application)
+	}
+
+	return results, nil
+}
+
+// OptimizationAnalyticsService adds application tracking and savings reporting
+// on top of OptimizationService. It implements optimization.OptimizationServiceWithAnalytics.
+type OptimizationAnalyticsService struct {
+	*OptimizationService
+	store  OptimizationAnalyticsStore
+	logger *slog.Logger
+}
+
+var _ optimization.OptimizationServiceWithAnalytics = (*OptimizationAnalyticsService)(nil)
+
+// NewOptimizationAnalyticsService creates a new analytics-enabled optimization service.
+// A nil store falls back to an in-memory store.
+func NewOptimizationAnalyticsService(optimizationService *OptimizationService, store OptimizationAnalyticsStore, logger *slog.Logger) *OptimizationAnalyticsService {
+	if store == nil {
+		store = NewMemoryOptimizationAnalyticsStore()
+	}
+	return &OptimizationAnalyticsService{
+		OptimizationService: optimizationService,
+		store:               store,
+		logger:              logger,
+	}
+}
+
+// TrackOptimizationApplication records that a profile was applied by a client.
+// Recognised metadata keys: "location" (overrides the profile location),
+// "duration_minutes" (usage duration, at most 24 hours) and "applied_at" (RFC3339
+// timestamp, not after now). Savings are recomputed from the location's current grid
+// intensity; the client's figures only ever lower them.
+func (s *OptimizationAnalyticsService) TrackOptimizationApplication(ctx context.Context, profile *optimization.OptimizationProfile, metadata map[string]interface{}) error {
+	if profile == nil {
+		return fmt.Errorf("optimization profile is required")
+	}
+	if !profile.Mode.IsValid() {
+		return fmt.Errorf("invalid optimization mode: %s", profile.Mode)
+	}
+
+	now := time.Now()
+	application := &OptimizationApplication{
+		ID:                     fmt.Sprintf("opt_%d", now.UnixNano()),
+		Mode:                   profile.Mode,
+		DisabledFeatures:       append([]string{}, profile.DisableFeatures...),
+		Location:               profile.Metadata.Location,
+		EstimatedEnergySavings: profile.Metadata.EstimatedEnergySavings,
+		PerformanceImpact:      profile.Metadata.PerformanceImpact,
+		AppliedAt:              now,
+		Metadata:               metadata,
+	}
+
+	duration := defaultApplicationDuration
+	if location, ok := metadata["location"].(string); ok && location != "" {
+		application.Location = location
+	}
+	if minutes, ok := metadata["duration_minutes"].(float64); ok && minutes > 0 {
+		duration = min(time.Duration(minutes*float64(time.Minute)), maxApplicationDuration)
+	}
+	if appliedAt, ok := metadata["applied_at"].(string); ok {
+		if parsed, err := time.Parse(time.RFC3339, appliedAt); err == nil && parsed.Before(now) {
+			application.AppliedAt = parsed
+		}
+	}
+
+	// The client's profile is untrusted; estimate savings as the server would for the grid now
+	savingsPerHour := 0.0
+	if intensity, err := s.electricityMaps.GetCarbonIntensity(ctx, application.Location); err != nil {
+		s.logger.Warn("Failed to get carbon intensity for tracked application, recording no savings",
+			"error", err, "location", application.Location)
+	} else {
+		application.CarbonIntensity = intensity.CarbonIntensity
+		reference := s.generateProfile(intensity.CarbonIntensity)
+		savingsPerHour = min(max(profile.EstimatedCO2Savings.TotalSavingsPerHour, 0), reference.EstimatedCO2Savings.TotalSavingsPerHour)
+	}
+
+	// g/h × hours → grams avoided; grams ÷ (g/kWh) → kWh avoided
+	carbonSavedGrams := savingsPerHour * duration.Hours()
+	application.CarbonSaved = carbonSavedGrams / 1000
+	if application.CarbonIntensity > 0 {
+		application.EnergySaved = carbonSavedGrams / application.CarbonIntensity
+	}
+
+	if err := s.store.SaveApplication(ctx, application); err != nil {
+		s.logger.Error("Failed to save optimization application", "error", err, "location", application.Location)
+		return fmt.Errorf("failed to track optimization application: %w", err)
+	}
+
+	s.logger.Info("Tracked optimization application",
+		"location", application.Location,
+		"mode", application.Mode,
+		"disabled_features", len(application.DisabledFeatures),
+		"carbon_saved_kg", application.CarbonSaved)
+
+	return nil
+}
+
+// GetOptimizationStats aggregates tracked applications into mode, feature and location statistics
+func (s *OptimizationAnalyticsService) GetOptimizationStats(ctx context.Context, location string, timeRange optimization.TimeRange) (*optimization.OptimizationStats, error) {
+	applications, err := s.store.ListApplications(ctx, location, timeRange)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list optimization applications: %w", err)
+	}
+
+	stats := &optimization.OptimizationStats{
+		TotalOptimizations:      int64(len(applications)),
+		OptimizationsByMode:     make(map[optimization.OptimizationMode]int64),
+		MostDisabledFeatures:    []optimization.FeatureDisableCount{},
+		OptimizationsByLocation: make(map[string]int64),
+		TimeRange:               timeRange,
+		GeneratedAt:             time.Now(),
+	}
+
+	featureCounts := make(map[string]int64)
+	var totalEnergySavings, totalPerformanceImpact float64
+	for _, application := range applications {
+		stats.OptimizationsByMode[application.Mode]++
+		stats.OptimizationsByLocation[application.Location]++
+		for _, feature := range application.DisabledFeatures {
+			featureCounts[feature]++
+		}
+		totalEnergySavings += application.EstimatedEnergySavings
+		totalPerformanceImpact += application.PerformanceImpact
+	}
+
+	if len(applications) > 0 {
+		stats.AverageEnergySavings = totalEnergySavings / float64(len(applications))
+		stats.AveragePerformanceImpact = totalPerformanceImpact / float64(len(applications))
+	}
+
+	for feature, count := range featureCounts {
+		stats.MostDisabledFeatures = append(stats.MostDisabledFeatures, optimization.FeatureDisableCount{
+			Feature: feature,
+			Count:   count,
+		})
+	}
+	sort.Slice(stats.MostDisabledFeatures, func(i, j int) bool {
+		if stats.MostDisabledFeatures[i].Count != stats.MostDisabledFeatures[j].Count {
+			return stats.MostDisabledFeatures[i].Count > stats.MostDisabledFeatures[j].Count
+		}
+		return stats.MostDisabledFeatures[i].Feature < stats.MostDisabledFeatures[j].Feature
+	})
+	if len(stats.MostDisabledFeatures) > maxTopDisabledFeatures {
+		stats.MostDisabledFeatures = stats.MostDisabledFeatures[:maxTopDisabledFeatures]
+	}
+
+	return stats, nil
+}
+
+// GetEnergySavingsReport aggregates tracked applications into total, per-mode, per-feature and daily savings
+func (s *OptimizationAnalyticsService) GetEnergySavingsReport(ctx context.Context, location string, timeRange optimization.TimeRange) (*optimization.EnergySavingsReport, error) {
+	applications, err := s.store.ListApplications(ctx, location, timeRange)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list optimization applications: %w", err)
+	}
+
+	report := &optimization.EnergySavingsReport{
+		SavingsByMode:    make(map[optimization.OptimizationMode]optimization.EnergySavingsData),
+		SavingsByFeature: make(map[string]optimization.EnergySavingsData),
+		DailySavings:     []optimization.DailySavings{},
+		TimeRange:        timeRange,
+		GeneratedAt:      time.Now(),
+		Methodology:      savingsMethodology,
+	}
+
+	daily := make(map[time.Time]*optimization.DailySavings)
+	for _, application := range applications {
+		report.TotalEnergySaved += application.EnergySaved
+		report.TotalCarbonSaved += application.CarbonSaved
+
+		modeSavings := report.SavingsByMode[application.Mode]
+		modeSavings.EnergySaved += application.EnergySaved
+		modeSavings.CarbonSaved += application.CarbonSaved
+		modeSavings.Count++
+		report.SavingsByMode[application.Mode] = modeSavings
+
+		if n := len(application.DisabledFeatures); n > 0 {
+			for _, feature := range application.DisabledFeatures {
+				featureSavings := report.SavingsByFeature[feature]
+				featureSavings.EnergySaved += application.EnergySaved / float64(n)
+				featureSavings.CarbonSaved += application.CarbonSaved / float64(n)
+				featureSavings.Count++
+				report.SavingsByFeature[feature] = featureSavings
+			}
+		}
+
+		appliedAt := application.AppliedAt.UTC()
+		day := time.Date(appliedAt.Year(), appliedAt.Month(), appliedAt.Day(), 0, 0, 0, 0, time.UTC)
+		entry, exists := daily[day]
+		if !exists {
+			entry = &optimization.DailySavings{Date: day}
+			daily[day] = entry
+		}
+		entry.EnergySaved += application.EnergySaved
+		entry.CarbonSaved += application.CarbonSaved
+		entry.OptimizationsApplied++
+	}
+
+	for _, entry := range daily {
+		report.DailySavings = append(report.DailySavings, *entry)
+	}
+	sort.Slice(report.DailySavings, func(i, j int) bool {
+		return report.DailySavings[i].Date.Before(report.DailySavings[j].Date)
+	})
+
+	return report, nil
+}
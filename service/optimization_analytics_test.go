@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+)
+
+func newTestAnalyticsService(intensity float64) (*OptimizationAnalyticsService, *MemoryOptimizationAnalyticsStore) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	provider := &MockElectricityMapsClient{}
+	provider.SetMockIntensity(intensity)
+	store := NewMemoryOptimizationAnalyticsStore()
+	return NewOptimizationAnalyticsService(NewOptimizationService(provider, logger), store, logger), store
+}
+
+func TestTrackOptimizationApplication(t *testing.T) {
+	service, store := newTestAnalyticsService(350)
+	serverSavings := service.generateProfile(350).EstimatedCO2Savings.TotalSavingsPerHour
+	future := time.Now().Add(48 * time.Hour).Format(time.RFC3339)
+	past := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+
+	tests := []struct {
+		name          string
+		savings       float64
+		metadata      map[string]interface{}
+		expectedGrams float64
+		appliedAt     *time.Time // nil means now
+	}{
+		{"inflated savings are capped", 1e9, nil, serverSavings, nil},
+		{"lower reported savings are kept", 1, nil, 1, nil},
+		{"negative savings count as none", -50, nil, 0, nil},
+		{"duration is capped at a day", serverSavings, map[string]interface{}{"duration_minutes": 1e6}, serverSavings * 24, nil},
+		{"future applied_at is ignored", serverSavings, map[string]interface{}{"applied_at": future}, serverSavings, nil},
+		{"past applied_at is kept", serverSavings, map[string]interface{}{"applied_at": past.Format(time.RFC3339)}, serverSavings, &past},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store.applications = nil
+			profile := &optimization.OptimizationProfile{
+				Mode:                optimization.ModeEco,
+				EstimatedCO2Savings: optimization.CO2SavingsBreakdown{TotalSavingsPerHour: tt.savings},
+				Metadata:            optimization.OptimizationMetadata{Location: "DE", CarbonIntensity: 1},
+			}
+			before := time.Now()
+			if err := service.TrackOptimizationApplication(context.Background(), profile, tt.metadata); err != nil {
+				t.Fatalf("TrackOptimizationApplication() error = %v", err)
+			}
+
+			if len(store.applications) != 1 {
+				t.Fatalf("Expected 1 stored application, got %d", len(store.applications))
+			}
+			application := store.applications[0]
+			if application.CarbonIntensity != 350 {
+				t.Errorf("Expected the server's intensity 350, got %v", application.CarbonIntensity)
+			}
+			if math.Abs(application.CarbonSaved*1000-tt.expectedGrams) > 1e-6 {
+				t.Errorf("Expected %v g saved, got %v g", tt.expectedGrams, application.CarbonSaved*1000)
+			}
+			if math.Abs(application.EnergySaved-tt.expectedGrams/350) > 1e-9 {
+				t.Errorf("Expected %v kWh saved, got %v", tt.expectedGrams/350, application.EnergySaved)
+			}
+			if tt.appliedAt != nil {
+				if !application.AppliedAt.Equal(*tt.appliedAt) {
+					t.Errorf("Expected applied at %s, got %s", tt.appliedAt, application.AppliedAt)
+				}
+			} else if application.AppliedAt.Before(before) || application.AppliedAt.After(time.Now()) {
+				t.Errorf("Expected applied at now, got %s", application.AppliedAt)
+			}
+		})
+	}
+}
+
+func TestMemoryOptimizationAnalyticsStoreLimits(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	everything := optimization.TimeRange{Start: now.Add(-365 * 24 * time.Hour), End: now.Add(time.Hour)}
+
+	store := NewMemoryOptimizationAnalyticsStore()
+	store.maxApplications = 10
+
+	// Expired applications are not listed and are dropped first when the store is full
+	expired := &OptimizationApplication{ID: "expired", AppliedAt: now.Add(-defaultApplicationRetention - time.Hour)}
+	if err := store.SaveApplication(ctx, expired); err != nil {
+		t.Fatalf("SaveApplication() error = %v", err)
+	}
+	for i := 0; i < 9; i++ {
+		store.SaveApplication(ctx, &OptimizationApplication{ID: "recent", AppliedAt: now})
+	}
+	if listed, _ := store.ListApplications(ctx, "", everything); len(listed) != 9 {
+		t.Errorf("Expected 9 unexpired applications, got %d", len(listed))
+	}
+
+	store.SaveApplication(ctx, &OptimizationApplication{ID: "new", AppliedAt: now})
+	if len(store.applications) != 10 || store.applications[0].ID != "recent" {
+		t.Errorf("Expected the expired application to make room, got %d starting with %s", len(store.applications), store.applications[0].ID)
+	}
+
+	// Without expired entries the oldest recorded ones go
+	store.SaveApplication(ctx, &OptimizationApplication{ID: "newest", AppliedAt: now})
+	if len(store.applications) > store.maxApplications {
+		t.Errorf("Expected at most %d applications, got %d", store.maxApplications, len(store.applications))
+	}
+	if last := store.applications[len(store.applications)-1]; last.ID != "newest" {
+		t.Errorf("Expected the newest application to be kept, got %s", last.ID)
+	}
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/perschulte/greenweb-api/pkg/carbon"
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+)
+
+// Optimization rule action types
+const (
+	RuleActionDisableFeature  = "disable_feature"
+	RuleActionEnableFeature   = "enable_feature"
+	RuleActionSetImageQuality = "set_image_quality"
+	RuleActionSetVideoQuality = "set_video_quality"
+)
+
+var (
+	// ErrRulesNotConfigured is returned by the rule methods when no rule store is set
+	ErrRulesNotConfigured = errors.New("optimization rules are not configured")
+
+	// ErrInvalidRule is returned when creating or updating a malformed rule
+	ErrInvalidRule = errors.New("invalid optimization rule")
+)
+
+// OptimizationRuleStore persists optimization rules; internal/store.RuleStore implements it
+type OptimizationRuleStore interface {
+	CreateRule(ctx context.Context, rule *optimization.OptimizationRule) (*optimization.OptimizationRule, error)
+	UpdateRule(ctx context.Context, ruleID string, rule *optimization.OptimizationRule) (*optimization.OptimizationRule, error)
+	DeleteRule(ctx context.Context, ruleID string) error
+	GetRule(ctx context.Context, ruleID string) (*optimization.OptimizationRule, error)
+	ListRules(ctx context.Context, tags []string) ([]*optimization.OptimizationRule, error)
+	EvaluateRules(ctx context.Context, evalContext *optimization.OptimizationContext) ([]*optimization.OptimizationRule, error)
+}
+
+var _ optimization.OptimizationServiceWithRules = (*OptimizationService)(nil)
+
+// SetRuleStore enables rule management and applies matching rules to generated profiles
+func (s *OptimizationService) SetRuleStore(rules OptimizationRuleStore) {
+	s.rules = rules
+}
+
+// CreateRule validates and stores a new optimization rule
+func (s *OptimizationService) CreateRule(ctx context.Context, rule *optimization.OptimizationRule) (*optimization.OptimizationRule, error) {
+	if s.rules == nil {
+		return nil, ErrRulesNotConfigured
+	}
+	if err := validateRule(rule); err != nil {
+		return nil, err
+	}
+	return s.rules.CreateRule(ctx, rule)
+}
+
+// UpdateRule validates and replaces an existing optimization rule
+func (s *OptimizationService) UpdateRule(ctx context.Context, ruleID string, rule *optimization.OptimizationRule) (*optimization.OptimizationRule, error) {
+	if s.rules == nil {
+		return nil, ErrRulesNotConfigured
+	}
+	if err := validateRule(rule); err != nil {
+		return nil, err
+	}
+	return s.rules.UpdateRule(ctx, ruleID, rule)
+}
+
+// DeleteRule removes an optimization rule
+func (s *OptimizationService) DeleteRule(ctx context.Context, ruleID string) error {
+	if s.rules == nil {
+		return ErrRulesNotConfigured
+	}
+	return s.rules.DeleteRule(ctx, ruleID)
+}
+
+// GetRule retrieves an optimization rule
+func (s *OptimizationService) GetRule(ctx context.Context, ruleID string) (*optimization.OptimizationRule, error) {
+	if s.rules == nil {
+		return nil, ErrRulesNotConfigured
+	}
+	return s.rules.GetRule(ctx, ruleID)
+}
+
+// ListRules returns the optimization rules carrying any of the tags, or all of them
+func (s *OptimizationService) ListRules(ctx context.Context, tags []string) ([]*optimization.OptimizationRule, error) {
+	if s.rules == nil {
+		return nil, ErrRulesNotConfigured
+	}
+	return s.rules.ListRules(ctx, tags)
+}
+
+// EvaluateRules returns the enabled rules matching the context, highest priority first
+func (s *OptimizationService) EvaluateRules(ctx context.Context, evalContext *optimization.OptimizationContext) ([]*optimization.OptimizationRule, error) {
+	if s.rules == nil {
+		return nil, ErrRulesNotConfigured
+	}
+	return s.rules.EvaluateRules(ctx, evalContext)
+}
+
+// applyRules applies the actions of the rules matching the request to a profile. Rules are
+// applied lowest priority first, so higher priority rules have the last word.
+func (s *OptimizationService) applyRules(ctx context.Context, profile *optimization.OptimizationProfile, req *optimization.OptimizationRequest, intensity *carbon.CarbonIntensity) {
+	matched, err := s.rules.EvaluateRules(ctx, &optimization.OptimizationContext{
+		CarbonIntensity: intensity,
+		Request:         req,
+		Timestamp:       time.Now(),
+	})
+	if err != nil {
+		s.logger.Warn("Failed to evaluate optimization rules", "error", err, "location", req.Location)
+		return
+	}
+
+	for i := len(matched) - 1; i >= 0; i-- {
+		for _, action := range matched[i].Actions {
+			applyRuleAction(profile, action)
+		}
+	}
+
+	if len(matched) > 0 {
+		s.logger.Debug("Applied optimization rules", "location", req.Location, "rules", len(matched))
+	}
+}
+
+// applyRuleAction applies a single validated rule action to a profile
+func applyRuleAction(profile *optimization.OptimizationProfile, action optimization.RuleAction) {
+	switch action.Type {
+	case RuleActionDisableFeature:
+		addDisabledFeatures(profile, action.Target)
+	case RuleActionEnableFeature:
+		enabled := profile.DisableFeatures[:0]
+		for _, feature := range profile.DisableFeatures {
+			if feature != action.Target {
+				enabled = append(enabled, feature)
+			}
+		}
+		profile.DisableFeatures = enabled
+	case RuleActionSetImageQuality:
+		profile.ImageQuality = optimization.ImageQuality(action.Target)
+	case RuleActionSetVideoQuality:
+		profile.VideoQuality = optimization.VideoQuality(action.Target)
+	}
+}
+
+// validateRule checks that a rule has a name, conditions and only supported actions
+func validateRule(rule *optimization.OptimizationRule) error {
+	if rule == nil {
+		return fmt.Errorf("%w: rule is required", ErrInvalidRule)
+	}
+	if rule.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRule)
+	}
+	if len(rule.Conditions) == 0 {
+		return fmt.Errorf("%w: at least one condition is required", ErrInvalidRule)
+	}
+	if len(rule.Actions) == 0 {
+		return fmt.Errorf("%w: at least one action is required", ErrInvalidRule)
+	}
+	if rule.Priority < 0 {
+		return fmt.Errorf("%w: priority must not be negative", ErrInvalidRule)
+	}
+
+	for i, condition := range rule.Conditions {
+		if condition.Type == "" || condition.Operator == "" {
+			return fmt.Errorf("%w: condition %d needs a type and an operator", ErrInvalidRule, i)
+		}
+	}
+	for i, action := range rule.Actions {
+		valid := false
+		switch action.Type {
+		case RuleActionDisableFeature, RuleActionEnableFeature:
+			valid = action.Target != ""
+		case RuleActionSetImageQuality:
+			_, valid = imageQualityRank[optimization.ImageQuality(action.Target)]
+		case RuleActionSetVideoQuality:
+			_, valid = videoQualityRank[optimization.VideoQuality(action.Target)]
+		default:
+			return fmt.Errorf("%w: action %d has unsupported type %q", ErrInvalidRule, i, action.Type)
+		}
+		if !valid {
+			return fmt.Errorf("%w: action %d has invalid target %q for %s", ErrInvalidRule, i, action.Target, action.Type)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/perschulte/greenweb-api/internal/store"
+	"github.com/perschulte/greenweb-api/pkg/optimization"
+)
+
+func TestApplyClientHints(t *testing.T) {
+	service := NewOptimizationService(&MockElectricityMapsClient{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		name             string
+		intensity        float64
+		hints            optimization.ClientHints
+		imageQuality     optimization.ImageQuality
+		videoQuality     optimization.VideoQuality
+		disabled         []string
+		maxImageSize     int64
+		deferAnalytics   bool
+		minimizeScripts  bool
+		autoplayDisabled bool
+	}{
+		{"2G with Save-Data", 100, optimization.ClientHints{EffectiveConnectionType: "2g", SaveData: true},
+			optimization.ImageQualityLow, optimization.VideoQuality360p, []string{"video_autoplay", "custom_fonts", "embedded_maps"},
+			100 * 1024, true, false, true},
+		{"Save-Data on a fast network", 100, optimization.ClientHints{EffectiveConnectionType: "4g", SaveData: true},
+			optimization.ImageQualityLow, optimization.VideoQuality480p, []string{"video_autoplay", "web_fonts"},
+			200 * 1024, true, false, true},
+		{"3G", 100, optimization.ClientHints{EffectiveConnectionType: "3g"},
+			optimization.ImageQualityMedium, optimization.VideoQuality480p, []string{"video_autoplay", "background_videos"},
+			300 * 1024, false, false, true},
+		{"slow RTT on 4G", 100, optimization.ClientHints{EffectiveConnectionType: "4g", RTT: 600},
+			optimization.ImageQualityMedium, optimization.VideoQuality480p, []string{"video_autoplay"},
+			300 * 1024, false, false, true},
+		{"fiber keeps the green profile", 100, optimization.ClientHints{EffectiveConnectionType: "4g", Downlink: 250, RTT: 5, DeviceMemory: 8},
+			optimization.ImageQualityHigh, optimization.VideoQuality1080p, nil,
+			0, false, false, false},
+		{"fiber does not loosen a dirty grid", 600, optimization.ClientHints{EffectiveConnectionType: "4g", Downlink: 250, DeviceMemory: 8},
+			optimization.ImageQualityLow, optimization.VideoQuality360p, nil,
+			-1, true, true, true},
+		{"mobile on fiber", 100, optimization.ClientHints{Mobile: true, EffectiveConnectionType: "4g"},
+			optimization.ImageQualityHigh, optimization.VideoQuality720p, nil,
+			0, false, false, false},
+		{"low-memory device", 100, optimization.ClientHints{DeviceMemory: 1},
+			optimization.ImageQualityHigh, optimization.VideoQuality1080p, []string{"webgl_effects", "heavy_scripts"},
+			0, false, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := service.generateProfile(tt.intensity)
+			baseImageSize := profile.ResourceLimits.MaxImageSize
+			hints := tt.hints
+			service.applyClientHints(profile, &hints)
+
+			if profile.ImageQuality != tt.imageQuality {
+				t.Errorf("Expected image quality %s, got %s", tt.imageQuality, profile.ImageQuality)
+			}
+			if profile.VideoQuality != tt.videoQuality {
+				t.Errorf("Expected video quality %s, got %s", tt.videoQuality, profile.VideoQuality)
+			}
+			for _, feature := range tt.disabled {
+				if !profile.IsFeatureDisabled(feature) {
+					t.Errorf("Expected %s disabled, got %v", feature, profile.DisableFeatures)
+				}
+			}
+			// -1 means the grid-based limit is left as generated
+			expectedImageSize := tt.maxImageSize
+			if expectedImageSize == -1 {
+				expectedImageSize = baseImageSize
+			}
+			if profile.ResourceLimits.MaxImageSize != expectedImageSize {
+				t.Errorf("Expected max image size %d, got %d", expectedImageSize, profile.ResourceLimits.MaxImageSize)
+			}
+			if profile.DeferAnalytics != tt.deferAnalytics {
+				t.Errorf("Expected defer analytics %v, got %v", tt.deferAnalytics, profile.DeferAnalytics)
+			}
+			if profile.UIOptimizations.MinimizeJavaScript != tt.minimizeScripts {
+				t.Errorf("Expected minimize JavaScript %v, got %v", tt.minimizeScripts, profile.UIOptimizations.MinimizeJavaScript)
+			}
+			if autoplay := profile.HighImpactOptimizations.VideoStreamingOptimization.AutoplayDisabled; autoplay != tt.autoplayDisabled {
+				t.Errorf("Expected autoplay disabled %v, got %v", tt.autoplayDisabled, autoplay)
+			}
+		})
+	}
+}
+
+func TestOptimizationRules(t *testing.T) {
+	ctx := context.Background()
+	provider := &MockElectricityMapsClient{}
+	provider.SetMockIntensity(100)
+	service := NewOptimizationService(provider, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	rule := &optimization.OptimizationRule{
+		ID:         "berlin_video",
+		Name:       "Limit video in Berlin",
+		Conditions: []optimization.RuleCondition{{Type: "location", Operator: "eq", Value: "Berlin"}},
+		Actions: []optimization.RuleAction{
+			{Type: RuleActionSetVideoQuality, Target: "480p"},
+			{Type: RuleActionDisableFeature, Target: "chat_widget"},
+		},
+		Enabled: true,
+	}
+
+	if _, err := service.CreateRule(ctx, rule); !errors.Is(err, ErrRulesNotConfigured) {
+		t.Errorf("Expected ErrRulesNotConfigured without a rule store, got %v", err)
+	}
+
+	rules, err := store.NewRuleStore(nil)
+	if err != nil {
+		t.Fatalf("NewRuleStore() error = %v", err)
+	}
+	service.SetRuleStore(rules)
+
+	invalid := *rule
+	invalid.Actions = []optimization.RuleAction{{Type: RuleActionSetImageQuality, Target: "ultra"}}
+	if _, err := service.CreateRule(ctx, &invalid); !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("Expected ErrInvalidRule for an unknown image quality, got %v", err)
+	}
+	if _, err := service.CreateRule(ctx, rule); err != nil {
+		t.Fatalf("CreateRule() error = %v", err)
+	}
+
+	tests := []struct {
+		location     string
+		videoQuality optimization.VideoQuality
+		chatDisabled bool
+	}{
+		{"Berlin", optimization.VideoQuality480p, true},
+		{"Paris", optimization.VideoQuality1080p, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			response, err := service.GetOptimizationProfile(ctx, optimization.OptimizationRequest{Location: tt.location})
+			if err != nil {
+				t.Fatalf("GetOptimizationProfile() error = %v", err)
+			}
+			profile := response.Optimization
+			if profile.VideoQuality != tt.videoQuality {
+				t.Errorf("Expected video quality %s, got %s", tt.videoQuality, profile.VideoQuality)
+			}
+			if profile.IsFeatureDisabled("chat_widget") != tt.chatDisabled {
+				t.Errorf("Expected chat_widget disabled %v, got %v", tt.chatDisabled, profile.DisableFeatures)
+			}
+		})
+	}
+}